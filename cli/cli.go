@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"github.com/docker/docker/client"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// The name of the CLI binary, as shown in usage messages
+	BINARY_NAME = "kurtosis"
+
+	SUCCESS_EXIT_CODE = 0
+
+	// The exit code when the command ran but reported failure (e.g. some tests failed)
+	FAILURE_EXIT_CODE = 1
+
+	// The exit code when the command couldn't run at all (e.g. bad arguments, or the Docker engine being unreachable)
+	ERROR_EXIT_CODE = 2
+)
+
+// The test suites that the "run" command can run, keyed by name
+var registeredSuites = &suiteRegistry{
+	mutex:  &sync.Mutex{},
+	suites: map[string]SuiteRegistration{},
+}
+
+/*
+A test suite that the "run" command can run, along with the controller image that runs its tests.
+ */
+type SuiteRegistration struct {
+	TestSuite testsuite.TestSuite
+
+	// The controller image to run the suite's tests with, unless overridden with the "run" command's flag
+	ControllerImageName string
+}
+
+/*
+Makes the given test suite runnable by name with the "run" command. Suites are expected to register themselves from their
+	package's init function, so that a binary only needs to import them & call Main.
+ */
+func RegisterTestSuite(name string, registration SuiteRegistration) error {
+	return registeredSuites.add(name, registration)
+}
+
+/*
+Runs the CLI with this process's arguments and exits with the resulting exit code.
+ */
+func Main() {
+	os.Exit(Run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+/*
+Runs the CLI command named by the first of the given arguments, with the rest of the arguments as the command's flags.
+	Flags that aren't given default to the values in the user configuration file & environment (see applyUserDefaults).
+
+Returns:
+	The exit code the process should exit with (SUCCESS_EXIT_CODE, FAILURE_EXIT_CODE, or ERROR_EXIT_CODE)
+ */
+func Run(args []string, stdout io.Writer, stderr io.Writer) int {
+	commands := getCommands()
+	if len(args) == 0 {
+		printUsage(stderr, commands)
+		return ERROR_EXIT_CODE
+	}
+	commandName := args[0]
+	cmd, found := commands[commandName]
+	if !found {
+		fmt.Fprintf(stderr, "Unknown command '%v'\n\n", commandName)
+		printUsage(stderr, commands)
+		return ERROR_EXIT_CODE
+	}
+
+	flags := flag.NewFlagSet(BINARY_NAME + " " + commandName, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	execute := cmd.configureFlags(flags)
+	configDefaults, err := loadUserConfig(getUserConfigFilepath(), getAllFlagNames(commands))
+	if err != nil {
+		fmt.Fprintf(stderr, "Couldn't load the user configuration:\n%v\n", err)
+		return ERROR_EXIT_CODE
+	}
+	if err := applyUserDefaults(flags, configDefaults, os.LookupEnv); err != nil {
+		fmt.Fprintf(stderr, "Couldn't apply the user configuration:\n%v\n", err)
+		return ERROR_EXIT_CODE
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		// The flag set has already printed the problem & the command's usage
+		return ERROR_EXIT_CODE
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "Command '%v' doesn't take positional arguments, but got %v\n", commandName, flags.Args())
+		return ERROR_EXIT_CODE
+	}
+
+	succeeded, err := execute(stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "Command '%v' failed:\n%v\n", commandName, err)
+		return ERROR_EXIT_CODE
+	}
+	if !succeeded {
+		return FAILURE_EXIT_CODE
+	}
+	return SUCCESS_EXIT_CODE
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+A CLI subcommand, which registers its flags on the given flag set and returns the function that executes the command
+	once the flags have been parsed. The execution function returns false if the command ran but reported failure.
+ */
+type command struct {
+	description string
+
+	configureFlags func(flags *flag.FlagSet) func(stdout io.Writer) (bool, error)
+}
+
+func getCommands() map[string]command {
+	return map[string]command{
+		initCommandName: {
+			description:    "Generates a runnable skeleton of a new test suite, with an example service & test",
+			configureFlags: configureInitFlags,
+		},
+		runCommandName: {
+			description:    "Runs the tests of a registered test suite",
+			configureFlags: configureRunFlags,
+		},
+		planCommandName: {
+			description:    "Validates the networks of a registered test suite's tests & prints their plans, without touching Docker",
+			configureFlags: configurePlanFlags,
+		},
+		exportKubernetesCommandName: {
+			description:    "Renders a test's (or a Docker Compose file's) network as Kubernetes manifests, for promoting it to a long-lived environment",
+			configureFlags: configureExportKubernetesFlags,
+		},
+		remoteCommandName: {
+			description:    "Runs a registered test suite on a remote Docker host over SSH (optionally provisioned just for the run)",
+			configureFlags: configureRemoteFlags,
+		},
+		preflightCommandName: {
+			description:    "Checks that the Docker engine is reachable & has the resources to run tests",
+			configureFlags: configurePreflightFlags,
+		},
+		listCommandName: {
+			description:    "Lists the Kurtosis-managed Docker networks, containers, & volumes on the Docker engine",
+			configureFlags: configureListFlags,
+		},
+		inspectCommandName: {
+			description:    "Shows the state of each service of a running test suite execution",
+			configureFlags: configureInspectFlags,
+		},
+		shellCommandName: {
+			description:    "Opens an interactive shell (or runs another command) in a service of a running test suite execution",
+			configureFlags: configureShellFlags,
+		},
+		logsCommandName: {
+			description:    "Shows (and optionally follows) the logs of some or all services of a running test suite execution",
+			configureFlags: configureLogsFlags,
+		},
+		replCommandName: {
+			description:    "Attaches an interactive prompt to a running test's network, to kill/pause/partition its services & call them",
+			configureFlags: configureReplFlags,
+		},
+		cleanCommandName: {
+			description:    "Removes the Kurtosis-managed Docker networks & volumes left on the Docker engine (e.g. by killed runs)",
+			configureFlags: configureCleanFlags,
+		},
+	}
+}
+
+func printUsage(output io.Writer, commands map[string]command) {
+	commandNames := make([]string, 0, len(commands))
+	for commandName, _ := range commands {
+		commandNames = append(commandNames, commandName)
+	}
+	sort.Strings(commandNames)
+
+	fmt.Fprintf(output, "Usage: %v <command> [flags]\n\nCommands:\n", BINARY_NAME)
+	for _, commandName := range commandNames {
+		fmt.Fprintf(output, "  %-8v %v\n", commandName, commands[commandName].description)
+	}
+	fmt.Fprintf(output, "\nRun '%v <command> -help' to see a command's flags\n", BINARY_NAME)
+}
+
+// Creates a Docker manager for the Docker engine that the environment (e.g. DOCKER_HOST) points to
+func newDockerManager() (*docker.DockerManager, error) {
+	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to initialize Docker client from environment.")
+	}
+	dockerManager, err := docker.NewDockerManager(logrus.NewEntry(logrus.StandardLogger()), dockerClient)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the Docker manager")
+	}
+	return dockerManager, nil
+}
+
+// Splits a comma-separated flag value into a "set", ignoring empty elements
+func splitCommaSeparatedFlag(value string) map[string]bool {
+	result := map[string]bool{}
+	for _, element := range strings.Split(value, ",") {
+		trimmedElement := strings.TrimSpace(element)
+		if trimmedElement != "" {
+			result[trimmedElement] = true
+		}
+	}
+	return result
+}
+
+// =========================== SUITE REGISTRY =========================================
+/*
+NOTE: This is thread-safe!
+ */
+type suiteRegistry struct {
+	mutex *sync.Mutex
+
+	suites map[string]SuiteRegistration
+}
+
+func (registry *suiteRegistry) add(name string, registration SuiteRegistration) error {
+	if name == "" {
+		return stacktrace.NewError("Test suites can't be registered with an empty name")
+	}
+	if registration.TestSuite == nil {
+		return stacktrace.NewError("Test suite '%v' can't be registered without a test suite", name)
+	}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if _, found := registry.suites[name]; found {
+		return stacktrace.NewError("A test suite is already registered with name '%v'", name)
+	}
+	registry.suites[name] = registration
+	return nil
+}
+
+func (registry *suiteRegistry) get(name string) (SuiteRegistration, bool) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registration, found := registry.suites[name]
+	return registration, found
+}
+
+// Gets the names of the registered suites, sorted
+func (registry *suiteRegistry) getNames() []string {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	result := make([]string, 0, len(registry.suites))
+	for name, _ := range registry.suites {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+	"gotest.tools/v3/assert"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type emptyTestSuite struct {}
+
+func (suite emptyTestSuite) GetTests() map[string]testsuite.Test {
+	return map[string]testsuite.Test{}
+}
+
+func TestUnknownCommandsPrintUsage(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	assert.Equal(t, ERROR_EXIT_CODE, Run([]string{"nonexistent"}, stdout, stderr))
+	assert.Assert(t, strings.Contains(stderr.String(), "Unknown command 'nonexistent'"))
+	for commandName, _ := range getCommands() {
+		assert.Assert(t, strings.Contains(stderr.String(), commandName))
+	}
+
+	stderr.Reset()
+	assert.Equal(t, ERROR_EXIT_CODE, Run([]string{}, stdout, stderr))
+	assert.Assert(t, strings.Contains(stderr.String(), "Usage"))
+
+	stderr.Reset()
+	assert.Equal(t, ERROR_EXIT_CODE, Run([]string{"clean", "-nonexistent-flag"}, stdout, stderr))
+	assert.Equal(t, 0, stdout.Len())
+}
+
+func TestRunRequiresRegisteredSuite(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	assert.Equal(t, ERROR_EXIT_CODE, Run([]string{"run"}, stdout, stderr))
+	assert.Assert(t, strings.Contains(stderr.String(), "No test suites are registered"))
+}
+
+func TestChoosingSuitesToRun(t *testing.T) {
+	originalSuites := registeredSuites
+	defer func() {
+		registeredSuites = originalSuites
+	}()
+	registeredSuites = &suiteRegistry{mutex: &sync.Mutex{}, suites: map[string]SuiteRegistration{}}
+
+	assert.NilError(t, RegisterTestSuite("first", SuiteRegistration{TestSuite: emptyTestSuite{}, ControllerImageName: "first-controller"}))
+	assert.ErrorContains(t, RegisterTestSuite("first", SuiteRegistration{TestSuite: emptyTestSuite{}}), "already registered")
+	assert.ErrorContains(t, RegisterTestSuite("no-suite", SuiteRegistration{}), "without a test suite")
+
+	// With only one suite registered, it doesn't need to be named
+	registration, err := getSuiteToRun("")
+	assert.NilError(t, err)
+	assert.Equal(t, "first-controller", registration.ControllerImageName)
+
+	assert.NilError(t, RegisterTestSuite("second", SuiteRegistration{TestSuite: emptyTestSuite{}, ControllerImageName: "second-controller"}))
+	_, err = getSuiteToRun("")
+	assert.ErrorContains(t, err, "first, second")
+	registration, err = getSuiteToRun("second")
+	assert.NilError(t, err)
+	assert.Equal(t, "second-controller", registration.ControllerImageName)
+	_, err = getSuiteToRun("third")
+	assert.ErrorContains(t, err, "No test suite is registered with name 'third'")
+}
+
+func TestSplittingCommaSeparatedFlags(t *testing.T) {
+	assert.DeepEqual(t, map[string]bool{}, splitCommaSeparatedFlag(""))
+	assert.DeepEqual(t, map[string]bool{"a": true, "b": true}, splitCommaSeparatedFlag(" a,,b ,a"))
+}
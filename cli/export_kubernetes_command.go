@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/compose"
+	"github.com/kurtosis-tech/kurtosis/commons/kubernetes"
+	"github.com/kurtosis-tech/kurtosis/commons/logging"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io"
+)
+
+const (
+	exportKubernetesCommandName = "export-kubernetes"
+)
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configureExportKubernetesFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	suiteName := flags.String("suite", "", "Name of the registered test suite with the test to export (may be omitted if only one suite is registered)")
+	testName := flags.String("test", "", "Name of the test whose network to export")
+	composeFilepath := flags.String("compose", "", "Docker Compose file to export the network of (see compose.ComposeNetworkLoader), instead of a test's")
+	namespace := flags.String("namespace", "", "Kubernetes namespace to put the exported objects in (none if empty)")
+	return func(stdout io.Writer) (bool, error) {
+		plan, err := getPlanToExport(*suiteName, *testName, *composeFilepath)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred planning the network to export")
+		}
+		manifests, err := kubernetes.RenderManifests(plan, *namespace)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred rendering the network's Kubernetes manifests")
+		}
+		fmt.Fprint(stdout, manifests)
+		return true, nil
+	}
+}
+
+// Plans the network of either the given compose file (if non-empty) or the given test
+func getPlanToExport(suiteName string, testName string, composeFilepath string) (*networks.NetworkPlan, error) {
+	if composeFilepath != "" {
+		composeFile, err := compose.ParseComposeFile(composeFilepath)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred parsing compose file %v", composeFilepath)
+		}
+		loader := compose.NewComposeNetworkLoader(composeFile)
+		return networks.Plan(logrus.WithField(logging.TEST_NAME_FIELD, composeFilepath), loader, defaultPlanSubnetMask)
+	}
+	if testName == "" {
+		return nil, stacktrace.NewError("Either a test or a compose file must be given")
+	}
+	registration, err := getSuiteToRun(suiteName)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the test suite with the test to export")
+	}
+	test, found := registration.TestSuite.GetTests()[testName]
+	if !found {
+		return nil, stacktrace.NewError("No test registered with name '%v'", testName)
+	}
+	return planTest(test, testName, defaultPlanSubnetMask)
+}
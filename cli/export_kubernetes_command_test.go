@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGettingPlanToExport(t *testing.T) {
+	tempDirpath, err := ioutil.TempDir("", "export-kubernetes")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tempDirpath)
+
+	composeFilepath := filepath.Join(tempDirpath, "docker-compose.yml")
+	assert.NilError(t, ioutil.WriteFile(composeFilepath, []byte("services:\n  web:\n    image: nginx\n"), 0644))
+	plan, err := getPlanToExport("", "", composeFilepath)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(plan.Services))
+
+	_, err = getPlanToExport("", "", "")
+	assert.ErrorContains(t, err, "Either a test or a compose file must be given")
+}
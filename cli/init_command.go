@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"text/template"
+)
+
+const (
+	initCommandName = "init"
+
+	scaffoldingDirPerms = 0755
+	scaffoldingFilePerms = 0644
+)
+
+// The values that the scaffolding templates are rendered with
+type scaffoldingParams struct {
+	// The Go module path of the new suite, e.g. "github.com/my-org/my-suite"
+	ModulePath string
+
+	// The name the suite is registered with the CLI under
+	SuiteName string
+
+	ControllerImageName string
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configureInitFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	dirpath := flags.String("dir", ".", "Directory to generate the new test suite in")
+	modulePath := flags.String("module", "", "Go module path of the new test suite, e.g. github.com/my-org/my-suite")
+	suiteName := flags.String("name", "", "Name of the new test suite (the last element of the module path if empty)")
+	controllerImageName := flags.String("controller-image", "", "Name of the suite's controller image (<name>-controller if empty)")
+	return func(stdout io.Writer) (bool, error) {
+		if *modulePath == "" {
+			return false, stacktrace.NewError("The new test suite's Go module path must be given with the -module flag")
+		}
+		params := scaffoldingParams{
+			ModulePath:          *modulePath,
+			SuiteName:           *suiteName,
+			ControllerImageName: *controllerImageName,
+		}
+		if params.SuiteName == "" {
+			params.SuiteName = path.Base(*modulePath)
+		}
+		if params.ControllerImageName == "" {
+			params.ControllerImageName = params.SuiteName + "-controller"
+		}
+		createdFilepaths, err := generateScaffolding(*dirpath, params)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred generating the test suite in %v", *dirpath)
+		}
+
+		fmt.Fprintf(stdout, "Generated test suite %v in %v:\n", params.SuiteName, *dirpath)
+		for _, createdFilepath := range createdFilepaths {
+			fmt.Fprintf(stdout, "  %v\n", createdFilepath)
+		}
+		fmt.Fprintln(stdout, "\nTo run it, from that directory:")
+		fmt.Fprintln(stdout, "  make deps   # Fetch the Kurtosis framework")
+		fmt.Fprintln(stdout, "  make run    # Build the controller image & run the example test")
+		return true, nil
+	}
+}
+
+/*
+Renders the scaffolding templates into the given directory, without overwriting any existing file.
+
+Returns:
+	The created filepaths, sorted
+ */
+func generateScaffolding(dirpath string, params scaffoldingParams) ([]string, error) {
+	relativeFilepaths := make([]string, 0, len(scaffoldingTemplates))
+	for relativeFilepath, _ := range scaffoldingTemplates {
+		relativeFilepaths = append(relativeFilepaths, relativeFilepath)
+	}
+	sort.Strings(relativeFilepaths)
+
+	// Everything is rendered & checked before anything is written, so a failure doesn't leave a half-generated suite
+	renderedFiles := map[string][]byte{}
+	for _, relativeFilepath := range relativeFilepaths {
+		fileTemplate, err := template.New(relativeFilepath).Parse(scaffoldingTemplates[relativeFilepath])
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred parsing the template of %v", relativeFilepath)
+		}
+		rendered := &bytes.Buffer{}
+		if err := fileTemplate.Execute(rendered, params); err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred rendering the template of %v", relativeFilepath)
+		}
+		absFilepath := filepath.Join(dirpath, filepath.FromSlash(relativeFilepath))
+		if _, err := os.Stat(absFilepath); err == nil {
+			return nil, stacktrace.NewError("Refusing to overwrite existing file %v", absFilepath)
+		}
+		renderedFiles[absFilepath] = rendered.Bytes()
+	}
+
+	result := []string{}
+	for absFilepath, contents := range renderedFiles {
+		if err := os.MkdirAll(filepath.Dir(absFilepath), scaffoldingDirPerms); err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred creating the directory of %v", absFilepath)
+		}
+		if err := ioutil.WriteFile(absFilepath, contents, scaffoldingFilePerms); err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred writing %v", absFilepath)
+		}
+		result = append(result, absFilepath)
+	}
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"go/parser"
+	"go/token"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGeneratingScaffolding(t *testing.T) {
+	dirpath, err := ioutil.TempDir("", "test-scaffolding")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dirpath)
+
+	params := scaffoldingParams{
+		ModulePath:          "github.com/my-org/my-suite",
+		SuiteName:           "my-suite",
+		ControllerImageName: "my-suite-controller",
+	}
+	createdFilepaths, err := generateScaffolding(dirpath, params)
+	assert.NilError(t, err)
+	assert.Equal(t, len(scaffoldingTemplates), len(createdFilepaths))
+
+	// The generated Go files must at least parse, since users' first experience is building them
+	fileSet := token.NewFileSet()
+	for _, createdFilepath := range createdFilepaths {
+		contents, err := ioutil.ReadFile(createdFilepath)
+		assert.NilError(t, err)
+		assert.Assert(t, !strings.Contains(string(contents), "{{"), "File %v has unrendered template actions", createdFilepath)
+		if filepath.Ext(createdFilepath) == ".go" {
+			_, err := parser.ParseFile(fileSet, createdFilepath, contents, parser.AllErrors)
+			assert.NilError(t, err)
+		}
+	}
+	goModContents, err := ioutil.ReadFile(filepath.Join(dirpath, "go.mod"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(string(goModContents), "module github.com/my-org/my-suite\n"))
+
+	// Existing files are never overwritten
+	_, err = generateScaffolding(dirpath, params)
+	assert.ErrorContains(t, err, "Refusing to overwrite")
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	inspectCommandName = "inspect"
+
+	tableInspectFormat = "table"
+	jsonInspectFormat = "json"
+
+	// What the table shows for empty values
+	emptyTableCell = "-"
+
+	// Docker's state for running containers
+	runningContainerState = "running"
+)
+
+/*
+The state of one service of a running test, as shown by the "inspect" command.
+ */
+type serviceInspection struct {
+	TestName string `json:"testName"`
+
+	ServiceId string `json:"serviceId"`
+
+	ContainerId string `json:"containerId"`
+
+	// The container's IP addresses (IPv4 & IPv6) across all its networks, sorted
+	IpAddrs []string `json:"ipAddrs"`
+
+	// Docker's state for the container (e.g. "running")
+	State string `json:"state"`
+
+	// The result of the image's Docker healthcheck, or docker.NO_HEALTHCHECK_STATUS
+	Health string `json:"health"`
+
+	// Mapping of container port (e.g. "8080/tcp") -> the host port it's published on
+	HostPorts map[string]int `json:"hostPorts"`
+
+	StartedAt time.Time `json:"startedAt"`
+
+	// How long the container has been running, or zero if it isn't running
+	Uptime time.Duration `json:"uptimeNanos"`
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configureInspectFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	runId := flags.String("run-id", "", "Execution ID of the run to inspect, as logged when the run starts")
+	format := flags.String("format", tableInspectFormat, "Output format: " + tableInspectFormat + " or " + jsonInspectFormat)
+	return func(stdout io.Writer) (bool, error) {
+		if *runId == "" {
+			return false, stacktrace.NewError("The run to inspect must be given with the -run-id flag")
+		}
+		if *format != tableInspectFormat && *format != jsonInspectFormat {
+			return false, stacktrace.NewError("Unrecognized output format '%v'", *format)
+		}
+		dockerManager, err := newDockerManager()
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred connecting to the Docker engine")
+		}
+		inspections, err := inspectRun(context.Background(), dockerManager, *runId, time.Now())
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred inspecting run %v", *runId)
+		}
+		if *format == jsonInspectFormat {
+			return true, writeInspectionsJson(inspections, stdout)
+		}
+		return true, writeInspectionsTable(inspections, stdout)
+	}
+}
+
+/*
+Gets the state of every service of every test of the given run, sorted by test & then service. A run's tests are found
+	through their test volumes (which are labelled with the run's execution ID), and each test's services through their
+	containers (which are labelled with the test volume & service ID).
+ */
+func inspectRun(ctx context.Context, dockerManager *docker.DockerManager, runId string, now time.Time) ([]serviceInspection, error) {
+	testVolumeNames, err := getRunTestVolumes(ctx, dockerManager, runId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the tests of run %v", runId)
+	}
+
+	result := []serviceInspection{}
+	for _, testVolumeName := range testVolumeNames {
+		testName := getTestName(runId, testVolumeName)
+		containerIds, err := dockerManager.ListContainers(ctx, map[string]string{docker.TEST_VOLUME_LABEL: testVolumeName})
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred listing the service containers of test %v", testName)
+		}
+		for _, containerId := range containerIds {
+			inspection, err := inspectServiceContainer(ctx, dockerManager, testName, containerId, now)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "An error occurred inspecting container %v of test %v", containerId, testName)
+			}
+			result = append(result, inspection)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TestName != result[j].TestName {
+			return result[i].TestName < result[j].TestName
+		}
+		return result[i].ServiceId < result[j].ServiceId
+	})
+	return result, nil
+}
+
+func inspectServiceContainer(
+			ctx context.Context,
+			dockerManager *docker.DockerManager,
+			testName string,
+			containerId string,
+			now time.Time) (serviceInspection, error) {
+	status, err := dockerManager.GetContainerStatus(ctx, containerId)
+	if err != nil {
+		return serviceInspection{}, stacktrace.Propagate(err, "An error occurred getting the container's status")
+	}
+	networkInfo, err := dockerManager.GetContainerNetworkInfo(ctx, containerId)
+	if err != nil {
+		return serviceInspection{}, stacktrace.Propagate(err, "An error occurred getting the container's network info")
+	}
+	ipAddrs := []string{}
+	for _, ipAddr := range networkInfo.IpAddrs {
+		ipAddrs = append(ipAddrs, ipAddr.String())
+	}
+	for _, ipv6Addr := range networkInfo.Ipv6Addrs {
+		ipAddrs = append(ipAddrs, ipv6Addr.String())
+	}
+	sort.Strings(ipAddrs)
+
+	hostPorts := map[string]int{}
+	for containerPort, hostPort := range networkInfo.HostPorts {
+		hostPorts[string(containerPort)] = hostPort
+	}
+
+	var uptime time.Duration = 0
+	if status.State == runningContainerState && !status.StartedAt.IsZero() {
+		uptime = now.Sub(status.StartedAt)
+	}
+	return serviceInspection{
+		TestName:    testName,
+		ServiceId:   status.Labels[docker.SERVICE_ID_LABEL],
+		ContainerId: containerId,
+		IpAddrs:     ipAddrs,
+		State:       status.State,
+		Health:      status.HealthStatus,
+		HostPorts:   hostPorts,
+		StartedAt:   status.StartedAt,
+		Uptime:      uptime,
+	}, nil
+}
+
+func writeInspectionsJson(inspections []serviceInspection, output io.Writer) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(inspections); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the services' state as JSON")
+	}
+	return nil
+}
+
+func writeInspectionsTable(inspections []serviceInspection, output io.Writer) error {
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TEST\tSERVICE\tCONTAINER\tIPS\tSTATE\tHEALTH\tHOST PORTS\tUPTIME")
+	for _, inspection := range inspections {
+		containerPorts := make([]string, 0, len(inspection.HostPorts))
+		for containerPort, _ := range inspection.HostPorts {
+			containerPorts = append(containerPorts, containerPort)
+		}
+		sort.Strings(containerPorts)
+		portMappings := make([]string, 0, len(containerPorts))
+		for _, containerPort := range containerPorts {
+			portMappings = append(portMappings, fmt.Sprintf("%v->%v", inspection.HostPorts[containerPort], containerPort))
+		}
+
+		uptime := emptyTableCell
+		if inspection.Uptime > 0 {
+			uptime = inspection.Uptime.Round(time.Second).String()
+		}
+		fmt.Fprintf(
+			writer,
+			"%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			getTableCell(inspection.TestName),
+			getTableCell(inspection.ServiceId),
+			getTableCell(inspection.ContainerId),
+			getTableCell(strings.Join(inspection.IpAddrs, ",")),
+			getTableCell(inspection.State),
+			getTableCell(inspection.Health),
+			getTableCell(strings.Join(portMappings, ",")),
+			uptime)
+	}
+	if err := writer.Flush(); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the services' state as a table")
+	}
+	return nil
+}
+
+func getTableCell(value string) string {
+	if value == "" {
+		return emptyTableCell
+	}
+	return value
+}
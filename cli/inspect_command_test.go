@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testInspections = []serviceInspection{
+	{
+		TestName:    "myTest",
+		ServiceId:   "node1",
+		ContainerId: "abc123",
+		IpAddrs:     []string{"172.23.0.5"},
+		State:       "running",
+		Health:      "healthy",
+		HostPorts:   map[string]int{"9650/tcp": 32769, "8080/tcp": 32768},
+		StartedAt:   time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC),
+		Uptime:      90*time.Second + 400*time.Millisecond,
+	},
+	{
+		TestName:    "myTest",
+		ServiceId:   "node2",
+		ContainerId: "def456",
+		IpAddrs:     []string{},
+		State:       "exited",
+		Health:      "none",
+		HostPorts:   map[string]int{},
+	},
+}
+
+func TestWritingInspectionsTable(t *testing.T) {
+	output := &bytes.Buffer{}
+	assert.NilError(t, writeInspectionsTable(testInspections, output))
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.DeepEqual(t, []string{"TEST", "SERVICE", "CONTAINER", "IPS", "STATE", "HEALTH", "HOST", "PORTS", "UPTIME"}, strings.Fields(lines[0]))
+	assert.DeepEqual(
+		t,
+		[]string{"myTest", "node1", "abc123", "172.23.0.5", "running", "healthy", "32768->8080/tcp,32769->9650/tcp", "1m30s"},
+		strings.Fields(lines[1]))
+	assert.DeepEqual(t, []string{"myTest", "node2", "def456", "-", "exited", "none", "-", "-"}, strings.Fields(lines[2]))
+}
+
+func TestWritingInspectionsJson(t *testing.T) {
+	output := &bytes.Buffer{}
+	assert.NilError(t, writeInspectionsJson(testInspections, output))
+	parsed := []serviceInspection{}
+	assert.NilError(t, json.Unmarshal(output.Bytes(), &parsed))
+	assert.DeepEqual(t, testInspections, parsed)
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	logsCommandName = "logs"
+
+	defaultLogTailLines = 100
+
+	ansiColorReset = "\x1b[0m"
+
+	// Long lines (e.g. JSON-formatted log entries) are common, so lines are allowed to be much longer than bufio's default
+	maxLogLineBytes = 1024 * 1024
+)
+
+// The ANSI colors that services' log prefixes cycle through, chosen to be readable on both dark & light backgrounds
+var logPrefixColors = []string{
+	"\x1b[36m", // Cyan
+	"\x1b[33m", // Yellow
+	"\x1b[32m", // Green
+	"\x1b[35m", // Magenta
+	"\x1b[34m", // Blue
+	"\x1b[31m", // Red
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configureLogsFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	runId := flags.String("run-id", "", "Execution ID of the run whose services' logs to show, as logged when the run starts")
+	testName := flags.String("test", "", "Test whose services' logs to show (all the run's tests if empty)")
+	serviceIds := flags.String("services", "", "Comma-separated IDs of the services whose logs to show (all services if empty)")
+	numTailLines := flags.Uint("tail", defaultLogTailLines, "How many of each service's existing log lines to show")
+	follow := flags.Bool("follow", false, "Keep showing the services' logs as they're produced, until the services stop")
+	noColor := flags.Bool("no-color", false, "Don't color the services' log prefixes")
+	return func(stdout io.Writer) (bool, error) {
+		if *runId == "" {
+			return false, stacktrace.NewError("The run whose logs to show must be given with the -run-id flag")
+		}
+		dockerManager, err := newDockerManager()
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred connecting to the Docker engine")
+		}
+		ctx := context.Background()
+		serviceContainers, err := getRunServiceContainers(ctx, dockerManager, *runId, *testName)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred getting the services of run %v", *runId)
+		}
+		requestedServiceIds := splitCommaSeparatedFlag(*serviceIds)
+		if len(requestedServiceIds) > 0 {
+			serviceContainers, err = filterServiceContainers(serviceContainers, requestedServiceIds)
+			if err != nil {
+				return false, stacktrace.Propagate(err, "An error occurred finding the requested services in run %v", *runId)
+			}
+		}
+		return tailServiceLogs(ctx, dockerManager, serviceContainers, *numTailLines, *follow, !*noColor, stdout), nil
+	}
+}
+
+/*
+Writes the logs of the given services to the given output, interleaved line by line as they're read, with each line
+	prefixed by its service.
+
+Returns:
+	True if every service's logs were read without error
+ */
+func tailServiceLogs(
+			ctx context.Context,
+			dockerManager *docker.DockerManager,
+			serviceContainers []serviceContainer,
+			numTailLines uint,
+			follow bool,
+			useColor bool,
+			output io.Writer) bool {
+	prefixes := getLogPrefixes(serviceContainers, useColor)
+	outputMutex := &sync.Mutex{}
+	waitGroup := &sync.WaitGroup{}
+	allSucceeded := true
+	for idx, container := range serviceContainers {
+		waitGroup.Add(1)
+		go func(container serviceContainer, prefix string) {
+			defer waitGroup.Done()
+			logs, err := dockerManager.TailContainerLogs(ctx, container.containerId, numTailLines, follow)
+			if err == nil {
+				err = writePrefixedLines(logs, prefix, output, outputMutex)
+				logs.Close()
+			}
+			if err != nil {
+				logrus.Errorf("An error occurred reading the logs of service %v in test %v: %v", container.serviceId, container.testName, err)
+				outputMutex.Lock()
+				allSucceeded = false
+				outputMutex.Unlock()
+			}
+		}(container, prefixes[idx])
+	}
+	waitGroup.Wait()
+	return allSucceeded
+}
+
+/*
+Gets the prefix for each of the given services' log lines, in the same order: the service ID (qualified by the test name
+	if the services are from several tests), padded so the services' lines line up, and colored if requested.
+ */
+func getLogPrefixes(serviceContainers []serviceContainer, useColor bool) []string {
+	isMultiTest := false
+	for _, container := range serviceContainers {
+		isMultiTest = isMultiTest || container.testName != serviceContainers[0].testName
+	}
+
+	labels := make([]string, 0, len(serviceContainers))
+	maxLabelLength := 0
+	for _, container := range serviceContainers {
+		label := container.serviceId
+		if isMultiTest {
+			label = container.testName + "/" + container.serviceId
+		}
+		labels = append(labels, label)
+		if len(label) > maxLabelLength {
+			maxLabelLength = len(label)
+		}
+	}
+
+	result := make([]string, 0, len(labels))
+	for idx, label := range labels {
+		prefix := fmt.Sprintf("%-*v | ", maxLabelLength, label)
+		if useColor {
+			prefix = logPrefixColors[idx % len(logPrefixColors)] + prefix + ansiColorReset
+		}
+		result = append(result, prefix)
+	}
+	return result
+}
+
+// Copies each line of the given logs to the given output with the given prefix, holding the mutex while writing each line
+func writePrefixedLines(logs io.Reader, prefix string, output io.Writer, outputMutex *sync.Mutex) error {
+	scanner := bufio.NewScanner(logs)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLogLineBytes)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		outputMutex.Lock()
+		_, err := fmt.Fprintln(output, prefix + line)
+		outputMutex.Unlock()
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred writing a log line")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stacktrace.Propagate(err, "An error occurred reading the logs")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"gotest.tools/v3/assert"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGettingLogPrefixes(t *testing.T) {
+	sameTestContainers := []serviceContainer{
+		{testName: "myTest", serviceId: "node1", containerId: "abc"},
+		{testName: "myTest", serviceId: "bootstrap", containerId: "def"},
+	}
+	assert.DeepEqual(t, []string{"node1     | ", "bootstrap | "}, getLogPrefixes(sameTestContainers, false))
+
+	// Services from several tests are qualified by their tests
+	multiTestContainers := []serviceContainer{
+		{testName: "firstTest", serviceId: "node1", containerId: "abc"},
+		{testName: "secondTest", serviceId: "node1", containerId: "def"},
+	}
+	assert.DeepEqual(t, []string{"firstTest/node1  | ", "secondTest/node1 | "}, getLogPrefixes(multiTestContainers, false))
+
+	coloredPrefixes := getLogPrefixes(sameTestContainers, true)
+	assert.Equal(t, logPrefixColors[0] + "node1     | " + ansiColorReset, coloredPrefixes[0])
+	assert.Equal(t, logPrefixColors[1] + "bootstrap | " + ansiColorReset, coloredPrefixes[1])
+}
+
+func TestWritingPrefixedLines(t *testing.T) {
+	output := &bytes.Buffer{}
+	logs := strings.NewReader("first line\r\nsecond line\nunterminated")
+	assert.NilError(t, writePrefixedLines(logs, "node1 | ", output, &sync.Mutex{}))
+	assert.Equal(t, "node1 | first line\nnode1 | second line\nnode1 | unterminated\n", output.String())
+}
+
+func TestFilteringServiceContainers(t *testing.T) {
+	serviceContainers := []serviceContainer{
+		{testName: "firstTest", serviceId: "node1", containerId: "abc"},
+		{testName: "firstTest", serviceId: "node2", containerId: "def"},
+		{testName: "secondTest", serviceId: "node1", containerId: "ghi"},
+	}
+	filtered, err := filterServiceContainers(serviceContainers, map[string]bool{"node1": true})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(filtered))
+	assert.Equal(t, "abc", filtered[0].containerId)
+	assert.Equal(t, "ghi", filtered[1].containerId)
+
+	_, err = filterServiceContainers(serviceContainers, map[string]bool{"node1": true, "node3": true, "node4": true})
+	assert.ErrorContains(t, err, "node3, node4")
+}
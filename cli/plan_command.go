@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/compose"
+	"github.com/kurtosis-tech/kurtosis/commons/logging"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io"
+	"sort"
+)
+
+const (
+	planCommandName = "plan"
+
+	// The subnet that plans' IPs are picked from, which is the first subnet a run would give a test network by default
+	defaultPlanSubnetMask = "172.23.0.0/24"
+)
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configurePlanFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	suiteName := flags.String("suite", "", "Name of the registered test suite to plan (may be omitted if only one suite is registered)")
+	testNames := flags.String("tests", "", "Comma-separated names of the tests to plan (all of the suite's tests if empty)")
+	subnetMask := flags.String("subnet", defaultPlanSubnetMask, "Subnet that the planned IPs are picked from")
+	composeFilepath := flags.String("compose", "", "Docker Compose file to plan the network of (see compose.ComposeNetworkLoader), instead of a suite's tests")
+	return func(stdout io.Writer) (bool, error) {
+		if *composeFilepath != "" {
+			return planComposeFile(*composeFilepath, *subnetMask, stdout)
+		}
+		registration, err := getSuiteToRun(*suiteName)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred getting the test suite to plan")
+		}
+		return planSuite(registration, splitCommaSeparatedFlag(*testNames), *subnetMask, stdout)
+	}
+}
+
+/*
+Validates & prints the network plan (see networks.Plan) of each of the given tests of the given suite, without touching
+	Docker, carrying on past invalid tests so that every problem is reported at once.
+
+Args:
+	testNames: A "set" of the names of the tests to plan, or empty to plan all of the suite's tests
+
+Returns:
+	True if every test's network was valid
+ */
+func planSuite(registration SuiteRegistration, testNames map[string]bool, subnetMask string, output io.Writer) (bool, error) {
+	allTests := registration.TestSuite.GetTests()
+	if len(testNames) == 0 {
+		testNames = map[string]bool{}
+		for testName, _ := range allTests {
+			testNames[testName] = true
+		}
+	}
+	sortedTestNames := make([]string, 0, len(testNames))
+	for testName, _ := range testNames {
+		if _, found := allTests[testName]; !found {
+			return false, stacktrace.NewError("No test registered with name '%v'", testName)
+		}
+		sortedTestNames = append(sortedTestNames, testName)
+	}
+	sort.Strings(sortedTestNames)
+
+	allValid := true
+	for _, testName := range sortedTestNames {
+		fmt.Fprintf(output, "=================== %v ===================\n", testName)
+		plan, err := planTest(allTests[testName], testName, subnetMask)
+		if err != nil {
+			fmt.Fprintf(output, "INVALID: %v\n\n", err)
+			allValid = false
+			continue
+		}
+		fmt.Fprintln(output, plan.String())
+	}
+	if allValid {
+		fmt.Fprintf(output, "All %v tests' networks are valid\n", len(sortedTestNames))
+	} else {
+		fmt.Fprintln(output, "One or more tests' networks are invalid")
+	}
+	return allValid, nil
+}
+
+/*
+Validates & prints the network plan of the given Docker Compose file, so that a compose-based setup can be checked before
+	tests are migrated onto it.
+
+Returns:
+	True if the compose file's network was valid
+ */
+func planComposeFile(composeFilepath string, subnetMask string, output io.Writer) (bool, error) {
+	composeFile, err := compose.ParseComposeFile(composeFilepath)
+	if err != nil {
+		fmt.Fprintf(output, "INVALID: %v\n", err)
+		return false, nil
+	}
+	plan, err := networks.Plan(logrus.WithField(logging.TEST_NAME_FIELD, composeFilepath), compose.NewComposeNetworkLoader(composeFile), subnetMask)
+	if err != nil {
+		fmt.Fprintf(output, "INVALID: %v\n", err)
+		return false, nil
+	}
+	fmt.Fprintln(output, plan.String())
+	return true, nil
+}
+
+func planTest(test testsuite.Test, testName string, subnetMask string) (*networks.NetworkPlan, error) {
+	networkLoader, err := test.GetNetworkLoader()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the network loader")
+	}
+	plan, err := networks.Plan(logrus.WithField(logging.TEST_NAME_FIELD, testName), networkLoader, subnetMask)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred planning the network")
+	}
+	return plan, nil
+}
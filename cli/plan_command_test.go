@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"bytes"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testPlanNetworkLoader struct {
+	configureErr error
+}
+
+func (loader testPlanNetworkLoader) ConfigureNetwork(builder *networks.ServiceNetworkBuilder) error {
+	return loader.configureErr
+}
+
+func (loader testPlanNetworkLoader) InitializeNetwork(network *networks.ServiceNetwork) (map[networks.ServiceID]services.ServiceAvailabilityChecker, error) {
+	return map[networks.ServiceID]services.ServiceAvailabilityChecker{}, nil
+}
+
+func (loader testPlanNetworkLoader) WrapNetwork(network *networks.ServiceNetwork) (networks.Network, error) {
+	return nil, nil
+}
+
+type testPlanTest struct {
+	configureErr error
+}
+
+func (test testPlanTest) Run(network networks.Network, context testsuite.TestContext) {}
+
+func (test testPlanTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	return testPlanNetworkLoader{configureErr: test.configureErr}, nil
+}
+
+func (test testPlanTest) GetExecutionTimeout() time.Duration {
+	return time.Minute
+}
+
+func (test testPlanTest) GetSetupBuffer() time.Duration {
+	return time.Minute
+}
+
+type testPlanTestSuite struct {
+	tests map[string]testsuite.Test
+}
+
+func (suite testPlanTestSuite) GetTests() map[string]testsuite.Test {
+	return suite.tests
+}
+
+func TestPlanningSuite(t *testing.T) {
+	registration := SuiteRegistration{
+		TestSuite: testPlanTestSuite{tests: map[string]testsuite.Test{
+			"validTest":   testPlanTest{},
+			"invalidTest": testPlanTest{configureErr: stacktrace.NewError("Unknown configuration")},
+		}},
+	}
+
+	output := &bytes.Buffer{}
+	allValid, err := planSuite(registration, map[string]bool{"validTest": true}, defaultPlanSubnetMask, output)
+	assert.NilError(t, err)
+	assert.Assert(t, allValid)
+	assert.Assert(t, strings.Contains(output.String(), "All 1 tests' networks are valid"))
+
+	// Invalid tests don't stop the other tests being planned
+	output.Reset()
+	allValid, err = planSuite(registration, map[string]bool{}, defaultPlanSubnetMask, output)
+	assert.NilError(t, err)
+	assert.Assert(t, !allValid)
+	assert.Assert(t, strings.Contains(output.String(), "INVALID"))
+	assert.Assert(t, strings.Contains(output.String(), "Unknown configuration"))
+	assert.Assert(t, strings.Contains(output.String(), "validTest"))
+
+	_, err = planSuite(registration, map[string]bool{"nonexistentTest": true}, defaultPlanSubnetMask, output)
+	assert.ErrorContains(t, err, "nonexistentTest")
+}
+
+func TestPlanningComposeFile(t *testing.T) {
+	tempDirpath, err := ioutil.TempDir("", "plan-compose")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tempDirpath)
+
+	validFilepath := filepath.Join(tempDirpath, "valid.yml")
+	assert.NilError(t, ioutil.WriteFile(validFilepath, []byte("services:\n  web:\n    image: nginx\n    ports: [\"80\"]\n"), 0644))
+	output := &bytes.Buffer{}
+	valid, err := planComposeFile(validFilepath, defaultPlanSubnetMask, output)
+	assert.NilError(t, err)
+	assert.Assert(t, valid)
+	assert.Assert(t, strings.Contains(output.String(), "1. web (configuration web)"))
+
+	invalidFilepath := filepath.Join(tempDirpath, "invalid.yml")
+	assert.NilError(t, ioutil.WriteFile(invalidFilepath, []byte("services:\n  web:\n    build: .\n"), 0644))
+	output.Reset()
+	valid, err = planComposeFile(invalidFilepath, defaultPlanSubnetMask, output)
+	assert.NilError(t, err)
+	assert.Assert(t, !valid)
+	assert.Assert(t, strings.Contains(output.String(), "INVALID"))
+}
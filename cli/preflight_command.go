@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"io"
+)
+
+const (
+	preflightCommandName = "preflight"
+
+	bytesPerGibibyte = 1024 * 1024 * 1024
+)
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configurePreflightFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	defaults := docker.DEFAULT_PREFLIGHT_REQUIREMENTS
+	minApiVersion := flags.String("min-api-version", defaults.MinApiVersion, "The oldest acceptable Docker engine API version (empty to not check)")
+	minMemoryGib := flags.Float64("min-memory-gib", float64(defaults.MinMemoryBytes) / bytesPerGibibyte, "The least memory, in GiB, that the Docker engine must have (0 to not check)")
+	minFreeDiskGib := flags.Float64("min-free-disk-gib", float64(defaults.MinFreeDiskBytes) / bytesPerGibibyte, "The least free disk space, in GiB, that Docker's data directory must have (0 to not check)")
+	minEphemeralPorts := flags.Int("min-ephemeral-ports", defaults.MinEphemeralPorts, "The fewest ports that the host's ephemeral port range must contain (0 to not check)")
+	return func(stdout io.Writer) (bool, error) {
+		requirements := docker.PreflightRequirements{
+			MinApiVersion:     *minApiVersion,
+			MinMemoryBytes:    int64(*minMemoryGib * bytesPerGibibyte),
+			MinFreeDiskBytes:  uint64(*minFreeDiskGib * bytesPerGibibyte),
+			MinEphemeralPorts: *minEphemeralPorts,
+		}
+		dockerManager, err := newDockerManager()
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred connecting to the Docker engine")
+		}
+		if err := dockerManager.RunPreflightChecks(context.Background(), requirements); err != nil {
+			// A failed check is the command's answer rather than an error running it
+			fmt.Fprintf(stdout, "%v\n", err)
+			return false, nil
+		}
+		fmt.Fprintln(stdout, "The Docker environment is ready to run tests")
+		return true, nil
+	}
+}
@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/docker/distribution/uuid"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	remoteCommandName = "remote"
+
+	// The env variable that the teardown command gets the SSH target that the provision command printed in
+	SSH_TARGET_ENV_VAR = "KURTOSIS_SSH_TARGET"
+
+	// The directory on the remote host that each remote run ships its files to, which is removed afterwards
+	remoteWorkDirpathPrefix = "/tmp/kurtosis-remote-"
+
+	remoteSuiteBinaryFilename = "suite"
+	remoteArtifactsDirname    = "artifacts"
+
+	defaultSshPort = 22
+
+	// The exit code that ssh exits with when it couldn't run the remote command (as opposed to the command failing)
+	sshErrorExitCode = 255
+
+	shellBinary = "sh"
+)
+
+// Mapping of Go architecture -> the architecture "uname -m" reports for it, for those where they differ
+var unameMachinesByGoArch = map[string]string{
+	"amd64": "x86_64",
+	"arm64": "aarch64",
+	"386":   "i686",
+}
+
+// The args of the "remote" command, filled in from its flags
+type remoteArgs struct {
+	sshTarget string
+	sshPort uint
+	sshIdentityFilepath string
+	provisionCommand string
+	teardownCommand string
+	suiteBinaryFilepath string
+	imagesToShip string
+	runArgs string
+	artifactsDirpath string
+}
+
+/*
+Runs an external command with the given streams (any of which may be nil), returning an *exec.ExitError if the command
+	ran but exited non-zero. Swapped out in tests, so that no SSH connections are made.
+ */
+type commandExecutor func(stdin io.Reader, stdout io.Writer, stderr io.Writer, name string, args ...string) error
+
+// Runs a remote suite run's steps against a single SSH target
+type remoteRunner struct {
+	args remoteArgs
+
+	sshTarget string
+
+	workDirpath string
+
+	executor commandExecutor
+
+	stderr io.Writer
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configureRemoteFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	args := &remoteArgs{}
+	flags.StringVar(&args.sshTarget, "ssh-target", "", "SSH destination (e.g. user@host) of a host with Docker to run the suite on")
+	flags.UintVar(&args.sshPort, "ssh-port", defaultSshPort, "Port of the SSH target's SSH server")
+	flags.StringVar(&args.sshIdentityFilepath, "ssh-identity", "", "Private key file to authenticate to the SSH target with (ssh's default if empty)")
+	flags.StringVar(&args.provisionCommand, "provision-command", "", "Shell command that provisions an ephemeral host with Docker & prints its SSH target as its last line of output, instead of -ssh-target")
+	flags.StringVar(&args.teardownCommand, "teardown-command", "", "Shell command that destroys the host that -provision-command provisioned, which gets the SSH target in " + SSH_TARGET_ENV_VAR)
+	flags.StringVar(&args.suiteBinaryFilepath, "suite-binary", "", "Suite binary to ship to the host, built for the host's platform (this binary if empty)")
+	flags.StringVar(&args.imagesToShip, "ship-images", "", "Comma-separated local Docker images (e.g. the controller image) to copy to the host, for images that aren't in a registry")
+	flags.StringVar(&args.runArgs, "run-args", "", "Flags (space-separated) for the 'run' command on the host")
+	flags.StringVar(&args.artifactsDirpath, "artifacts-dir", "", "Local directory to copy the run's service artifacts back to (none if empty)")
+	return func(stdout io.Writer) (bool, error) {
+		return executeRemoteRun(*args, executeCommand, stdout, os.Stderr)
+	}
+}
+
+/*
+Runs the suite on a remote host over SSH: ships the suite binary (and any local images) to the host, runs the 'run'
+	command there while streaming its output back, copies the artifacts back, and cleans up the host (or tears it down, if
+	it was provisioned for the run).
+
+Returns:
+	True if the remote run succeeded
+ */
+func executeRemoteRun(args remoteArgs, executor commandExecutor, stdout io.Writer, stderr io.Writer) (bool, error) {
+	if (args.sshTarget == "") == (args.provisionCommand == "") {
+		return false, stacktrace.NewError("Exactly one of an SSH target or a provision command must be given")
+	}
+	if args.teardownCommand != "" && args.provisionCommand == "" {
+		return false, stacktrace.NewError("A teardown command can only be given along with a provision command")
+	}
+
+	sshTarget := args.sshTarget
+	if args.provisionCommand != "" {
+		provisionedTarget, err := provisionHost(args.provisionCommand, executor, stderr)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred provisioning the host to run on")
+		}
+		sshTarget = provisionedTarget
+		if args.teardownCommand != "" {
+			defer func() {
+				if err := teardownHost(args.teardownCommand, sshTarget, executor, stderr); err != nil {
+					logrus.Errorf("An error occurred tearing down host %v, which will need to be torn down manually: %v", sshTarget, err)
+				}
+			}()
+		}
+	}
+
+	runner := remoteRunner{
+		args:        args,
+		sshTarget:   sshTarget,
+		workDirpath: remoteWorkDirpathPrefix + uuid.Generate().String(),
+		executor:    executor,
+		stderr:      stderr,
+	}
+	if err := runner.checkHost(); err != nil {
+		return false, stacktrace.Propagate(err, "Host %v can't run the suite", sshTarget)
+	}
+	if err := runner.ssh(nil, nil, "mkdir", "-p", runner.workDirpath); err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred creating directory %v on the host", runner.workDirpath)
+	}
+	defer func() {
+		if err := runner.ssh(nil, nil, "rm", "-rf", runner.workDirpath); err != nil {
+			logrus.Warnf("An error occurred removing directory %v on host %v: %v", runner.workDirpath, sshTarget, err)
+		}
+	}()
+	if err := runner.shipFiles(); err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred shipping the suite to the host")
+	}
+	return runner.run(stdout)
+}
+
+// Runs the provision command, returning the SSH target that it printed as its last line
+func provisionHost(provisionCommand string, executor commandExecutor, stderr io.Writer) (string, error) {
+	output := &bytes.Buffer{}
+	if err := executor(nil, output, stderr, shellBinary, "-c", provisionCommand); err != nil {
+		return "", stacktrace.Propagate(err, "The provision command failed")
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	sshTarget := strings.TrimSpace(lines[len(lines) - 1])
+	if sshTarget == "" {
+		return "", stacktrace.NewError("The provision command didn't print an SSH target")
+	}
+	logrus.Infof("Provisioned host %v", sshTarget)
+	return sshTarget, nil
+}
+
+func teardownHost(teardownCommand string, sshTarget string, executor commandExecutor, stderr io.Writer) error {
+	teardownCommandWithEnv := fmt.Sprintf("export %v=%v; %v", SSH_TARGET_ENV_VAR, shellQuote(sshTarget), teardownCommand)
+	if err := executor(nil, stderr, stderr, shellBinary, "-c", teardownCommandWithEnv); err != nil {
+		return stacktrace.Propagate(err, "The teardown command failed")
+	}
+	logrus.Infof("Tore down host %v", sshTarget)
+	return nil
+}
+
+// Checks that the host has a reachable Docker engine, and that it can run this binary if it's the one being shipped
+func (runner remoteRunner) checkHost() error {
+	if runner.args.suiteBinaryFilepath == "" {
+		platformOutput := &bytes.Buffer{}
+		if err := runner.ssh(nil, platformOutput, "uname", "-sm"); err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the host's platform")
+		}
+		expectedMachine, found := unameMachinesByGoArch[runtime.GOARCH]
+		if !found {
+			expectedMachine = runtime.GOARCH
+		}
+		expectedPlatform := strings.ToLower(runtime.GOOS + " " + expectedMachine)
+		if platform := strings.ToLower(strings.TrimSpace(platformOutput.String())); platform != expectedPlatform {
+			return stacktrace.NewError(
+				"The host's platform is '%v' but this binary was built for '%v'; build the suite for the host & pass it with -suite-binary",
+				platform,
+				expectedPlatform)
+		}
+	}
+	if err := runner.ssh(nil, ioutil.Discard, "docker", "version"); err != nil {
+		return stacktrace.Propagate(err, "The host's Docker engine isn't reachable")
+	}
+	return nil
+}
+
+func (runner remoteRunner) shipFiles() error {
+	suiteBinaryFilepath := runner.args.suiteBinaryFilepath
+	if suiteBinaryFilepath == "" {
+		executableFilepath, err := os.Executable()
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the filepath of this binary")
+		}
+		suiteBinaryFilepath = executableFilepath
+	}
+	remoteSuiteBinaryFilepath := path.Join(runner.workDirpath, remoteSuiteBinaryFilename)
+	if err := runner.scp(suiteBinaryFilepath, runner.sshTarget + ":" + remoteSuiteBinaryFilepath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred copying suite binary %v to the host", suiteBinaryFilepath)
+	}
+	if err := runner.ssh(nil, nil, "chmod", "+x", remoteSuiteBinaryFilepath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred making the suite binary executable on the host")
+	}
+
+	for imageName, _ := range splitCommaSeparatedFlag(runner.args.imagesToShip) {
+		if err := runner.shipImage(imageName); err != nil {
+			return stacktrace.Propagate(err, "An error occurred copying image %v to the host", imageName)
+		}
+	}
+	return nil
+}
+
+func (runner remoteRunner) shipImage(imageName string) error {
+	imageArchive, err := ioutil.TempFile("", "kurtosis-remote-image")
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating a temporary file to save the image to")
+	}
+	imageArchive.Close()
+	defer os.Remove(imageArchive.Name())
+
+	if err := runner.executor(nil, nil, runner.stderr, "docker", "save", "-o", imageArchive.Name(), imageName); err != nil {
+		return stacktrace.Propagate(err, "An error occurred saving the image")
+	}
+	imageArchiveReader, err := os.Open(imageArchive.Name())
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred opening the saved image")
+	}
+	defer imageArchiveReader.Close()
+	if err := runner.ssh(imageArchiveReader, ioutil.Discard, "docker", "load"); err != nil {
+		return stacktrace.Propagate(err, "An error occurred loading the image on the host")
+	}
+	logrus.Infof("Copied image %v to the host", imageName)
+	return nil
+}
+
+// Runs the suite on the host, streaming its output, and copies back the artifacts
+func (runner remoteRunner) run(stdout io.Writer) (bool, error) {
+	remoteRunArgs := []string{
+		"cd", runner.workDirpath, "&&",
+		"./" + remoteSuiteBinaryFilename, runCommandName,
+	}
+	remoteArtifactsDirpath := path.Join(runner.workDirpath, remoteArtifactsDirname)
+	for _, runArg := range strings.Fields(runner.args.runArgs) {
+		remoteRunArgs = append(remoteRunArgs, shellQuote(runArg))
+	}
+	if runner.args.artifactsDirpath != "" {
+		remoteRunArgs = append(remoteRunArgs, "-artifacts-dir", remoteArtifactsDirpath)
+	}
+
+	logrus.Infof("Running the suite on host %v...", runner.sshTarget)
+	runErr := runner.executor(nil, stdout, runner.stderr, "ssh", append(runner.getSshOptions(), runner.sshTarget, strings.Join(remoteRunArgs, " "))...)
+	succeeded := runErr == nil
+	if runErr != nil {
+		exitErr, ok := runErr.(*exec.ExitError)
+		if !ok || exitErr.ExitCode() == sshErrorExitCode {
+			return false, stacktrace.Propagate(runErr, "An error occurred running the suite on the host")
+		}
+		if exitErr.ExitCode() == ERROR_EXIT_CODE {
+			return false, stacktrace.NewError("The suite couldn't run on the host; see its output above")
+		}
+	}
+
+	if runner.args.artifactsDirpath != "" {
+		if err := os.MkdirAll(runner.args.artifactsDirpath, os.ModePerm); err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred creating artifacts directory %v", runner.args.artifactsDirpath)
+		}
+		// The trailing "/." copies the remote directory's contents, rather than the directory itself
+		if err := runner.scp("-r", runner.sshTarget + ":" + remoteArtifactsDirpath + "/.", filepath.Clean(runner.args.artifactsDirpath)); err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred copying the artifacts back from the host")
+		}
+	}
+	return succeeded, nil
+}
+
+// Runs the given command on the host, with its args quoted so that the remote shell doesn't interpret them
+func (runner remoteRunner) ssh(stdin io.Reader, stdout io.Writer, name string, args ...string) error {
+	remoteCommandFragments := []string{shellQuote(name)}
+	for _, arg := range args {
+		remoteCommandFragments = append(remoteCommandFragments, shellQuote(arg))
+	}
+	sshArgs := append(runner.getSshOptions(), runner.sshTarget, strings.Join(remoteCommandFragments, " "))
+	return runner.executor(stdin, stdout, runner.stderr, "ssh", sshArgs...)
+}
+
+func (runner remoteRunner) scp(args ...string) error {
+	scpArgs := []string{"-P", strconv.FormatUint(uint64(runner.args.sshPort), 10), "-o", "BatchMode=yes"}
+	if runner.args.sshIdentityFilepath != "" {
+		scpArgs = append(scpArgs, "-i", runner.args.sshIdentityFilepath)
+	}
+	return runner.executor(nil, nil, runner.stderr, "scp", append(scpArgs, args...)...)
+}
+
+func (runner remoteRunner) getSshOptions() []string {
+	result := []string{"-p", strconv.FormatUint(uint64(runner.args.sshPort), 10), "-o", "BatchMode=yes"}
+	if runner.args.sshIdentityFilepath != "" {
+		result = append(result, "-i", runner.args.sshIdentityFilepath)
+	}
+	return result
+}
+
+func executeCommand(stdin io.Reader, stdout io.Writer, stderr io.Writer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+// Quotes the given string for a POSIX shell
+func shellQuote(str string) string {
+	return "'" + strings.Replace(str, "'", `'"'"'`, -1) + "'"
+}
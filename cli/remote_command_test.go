@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"gotest.tools/v3/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Records the commands it's given & fakes their output, without running anything
+type testCommandRecorder struct {
+	commands []string
+
+	// Mapping of command substring -> the output of commands containing it
+	outputs map[string]string
+
+	// A command substring that, if found in a command, makes the command exit with the given code
+	failingCommand string
+	failingExitCode int
+}
+
+func (recorder *testCommandRecorder) execute(stdin io.Reader, stdout io.Writer, stderr io.Writer, name string, args ...string) error {
+	command := name + " " + strings.Join(args, " ")
+	recorder.commands = append(recorder.commands, command)
+	for substring, output := range recorder.outputs {
+		if strings.Contains(command, substring) && stdout != nil {
+			fmt.Fprint(stdout, output)
+		}
+	}
+	if recorder.failingCommand != "" && strings.Contains(command, recorder.failingCommand) {
+		// Running a real command is the only way to get an *exec.ExitError with a given code
+		return exec.Command("sh", "-c", fmt.Sprintf("exit %v", recorder.failingExitCode)).Run()
+	}
+	return nil
+}
+
+func (recorder *testCommandRecorder) findCommand(substring string) int {
+	for i, command := range recorder.commands {
+		if strings.Contains(command, substring) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRemoteRunWithProvisionedHost(t *testing.T) {
+	artifactsDirpath, err := ioutil.TempDir("", "remote-artifacts")
+	assert.NilError(t, err)
+	defer os.RemoveAll(artifactsDirpath)
+
+	recorder := &testCommandRecorder{
+		outputs: map[string]string{
+			"provision.sh": "Creating VM...\nuser@10.0.0.5\n",
+			"'uname'":      "Linux x86_64\n",
+		},
+	}
+	args := remoteArgs{
+		sshPort:             defaultSshPort,
+		provisionCommand:    "./provision.sh",
+		teardownCommand:     "./teardown.sh",
+		suiteBinaryFilepath: "/builds/suite-linux-amd64",
+		imagesToShip:        "my-controller:latest",
+		runArgs:             "-tests=test1,test2 -parallelism=2",
+		artifactsDirpath:    artifactsDirpath,
+	}
+	output := &bytes.Buffer{}
+	succeeded, err := executeRemoteRun(args, recorder.execute, output, &bytes.Buffer{})
+	assert.NilError(t, err)
+	assert.Assert(t, succeeded)
+
+	// The host's platform isn't checked when a suite binary is given
+	assert.Equal(t, -1, recorder.findCommand("uname"))
+	assert.Assert(t, recorder.findCommand("scp -P 22 -o BatchMode=yes /builds/suite-linux-amd64 user@10.0.0.5:/tmp/kurtosis-remote-") != -1)
+	assert.Assert(t, recorder.findCommand("docker save -o") != -1)
+	runIdx := recorder.findCommand("./suite run '-tests=test1,test2' '-parallelism=2' -artifacts-dir /tmp/kurtosis-remote-")
+	assert.Assert(t, runIdx != -1)
+	assert.Assert(t, recorder.findCommand("scp -P 22 -o BatchMode=yes -r user@10.0.0.5:/tmp/kurtosis-remote-") > runIdx)
+	assert.Assert(t, recorder.findCommand("'rm' '-rf'") > runIdx)
+	assert.Assert(t, strings.HasSuffix(recorder.commands[len(recorder.commands) - 1], "export KURTOSIS_SSH_TARGET='user@10.0.0.5'; ./teardown.sh"))
+}
+
+func TestRemoteRunWithFailingTests(t *testing.T) {
+	recorder := &testCommandRecorder{
+		outputs:         map[string]string{},
+		failingCommand:  "./suite run",
+		failingExitCode: FAILURE_EXIT_CODE,
+	}
+	args := remoteArgs{
+		sshTarget:           "user@host",
+		sshPort:             defaultSshPort,
+		suiteBinaryFilepath: "/builds/suite",
+	}
+	succeeded, err := executeRemoteRun(args, recorder.execute, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.NilError(t, err)
+	assert.Assert(t, !succeeded)
+
+	// Unreachable hosts are errors, rather than failed runs
+	recorder = &testCommandRecorder{
+		outputs:         map[string]string{},
+		failingCommand:  "'docker' 'version'",
+		failingExitCode: sshErrorExitCode,
+	}
+	_, err = executeRemoteRun(args, recorder.execute, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "Docker engine isn't reachable")
+}
+
+func TestRemoteRunOnMismatchedPlatform(t *testing.T) {
+	recorder := &testCommandRecorder{
+		outputs: map[string]string{"'uname'": "Plan9 mips\n"},
+	}
+	args := remoteArgs{sshTarget: "user@host", sshPort: defaultSshPort}
+	_, err := executeRemoteRun(args, recorder.execute, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "built for '" + runtime.GOOS)
+}
+
+func TestRemoteRunArgsValidation(t *testing.T) {
+	recorder := &testCommandRecorder{}
+	_, err := executeRemoteRun(remoteArgs{}, recorder.execute, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "Exactly one of")
+	_, err = executeRemoteRun(remoteArgs{sshTarget: "user@host", teardownCommand: "true"}, recorder.execute, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "along with a provision command")
+	assert.Equal(t, 0, len(recorder.commands))
+}
@@ -0,0 +1,467 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	replCommandName = "repl"
+
+	replPrompt = "kurtosis> "
+
+	// How long a request made with the REPL's "rpc" & "http" commands gets to be answered
+	replRequestTimeout = 30 * time.Second
+
+	// The version of JSON-RPC that the "rpc" command speaks
+	jsonRpcVersion = "2.0"
+
+	// Marks REPL commands whose last arg takes the rest of the line (e.g. a JSON body containing spaces)
+	unlimitedReplArgs = -1
+)
+
+// A service that the REPL cut off from its test network, with what's needed to reconnect it as it was
+type partitionedService struct {
+	containerId string
+
+	ipAddr net.IP
+
+	aliases []string
+}
+
+/*
+The state of a REPL attached to the network of one test of a running run.
+ */
+type replSession struct {
+	dockerManager *docker.DockerManager
+
+	runId string
+
+	testName string
+
+	// The ID of the test's Docker network
+	networkId string
+
+	httpClient *http.Client
+
+	// Mapping of service ID -> the service, for the services that were partitioned with the "partition" command
+	partitionedServices map[string]partitionedService
+}
+
+/*
+A command of the REPL, which is given the args that followed the command's name on the line.
+ */
+type replCommand struct {
+	// The command's args, as shown in the REPL's help
+	argsUsage string
+
+	description string
+
+	minArgs int
+
+	// The most args the command takes, or unlimitedReplArgs if the last arg takes the rest of the line
+	maxArgs int
+
+	execute func(session *replSession, ctx context.Context, args []string, output io.Writer) error
+}
+
+type jsonRpcRequest struct {
+	JsonRpc string `json:"jsonrpc"`
+
+	Id int `json:"id"`
+
+	Method string `json:"method"`
+
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configureReplFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	runId := flags.String("run-id", "", "Execution ID of the run, as logged when the run starts")
+	testName := flags.String("test", "", "Test whose network to attach to (may be omitted if the run has only one test)")
+	return func(stdout io.Writer) (bool, error) {
+		if *runId == "" {
+			return false, stacktrace.NewError("The run to attach to must be given with the -run-id flag")
+		}
+		dockerManager, err := newDockerManager()
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred connecting to the Docker engine")
+		}
+		session, err := newReplSession(context.Background(), dockerManager, *runId, *testName)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred attaching to run %v", *runId)
+		}
+		fmt.Fprintf(stdout, "Attached to the network of test %v of run %v; type 'help' for the commands\n", session.testName, *runId)
+		return true, runRepl(session, os.Stdin, stdout)
+	}
+}
+
+func newReplSession(ctx context.Context, dockerManager *docker.DockerManager, runId string, testName string) (*replSession, error) {
+	if testName == "" {
+		testVolumeNames, err := getRunTestVolumes(ctx, dockerManager, runId)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred getting the tests of run %v", runId)
+		}
+		if len(testVolumeNames) > 1 {
+			testNames := []string{}
+			for _, testVolumeName := range testVolumeNames {
+				testNames = append(testNames, getTestName(runId, testVolumeName))
+			}
+			sort.Strings(testNames)
+			return nil, stacktrace.NewError(
+				"Run %v has several tests, so the one to attach to must be chosen with the -test flag: %v",
+				runId,
+				strings.Join(testNames, ", "))
+		}
+		testName = getTestName(runId, testVolumeNames[0])
+	}
+
+	// Test networks are named <execution ID>-<test name>, like their test volumes
+	networkId, err := dockerManager.GetNetworkId(ctx, runId + "-" + testName)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred finding the network of test %v; has the test finished?", testName)
+	}
+	return &replSession{
+		dockerManager:       dockerManager,
+		runId:               runId,
+		testName:            testName,
+		networkId:           networkId,
+		httpClient:          &http.Client{Timeout: replRequestTimeout},
+		partitionedServices: map[string]partitionedService{},
+	}, nil
+}
+
+/*
+Reads commands from the given input, a line at a time, until "exit" or the end of the input. A command that fails has its
+	error written to the output, rather than ending the REPL.
+ */
+func runRepl(session *replSession, input io.Reader, output io.Writer) error {
+	commands := getReplCommands()
+	scanner := bufio.NewScanner(input)
+	for {
+		fmt.Fprint(output, replPrompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(output)
+			break
+		}
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		commandName, args := words[0], words[1:]
+		if commandName == "exit" || commandName == "quit" {
+			break
+		}
+		if commandName == "help" {
+			writeReplHelp(commands, output)
+			continue
+		}
+		cmd, found := commands[commandName]
+		if !found {
+			fmt.Fprintf(output, "Unknown command '%v'; type 'help' for the commands\n", commandName)
+			continue
+		}
+		if len(args) < cmd.minArgs || (cmd.maxArgs != unlimitedReplArgs && len(args) > cmd.maxArgs) {
+			fmt.Fprintf(output, "Usage: %v %v\n", commandName, cmd.argsUsage)
+			continue
+		}
+		if err := cmd.execute(session, context.Background(), args, output); err != nil {
+			fmt.Fprintf(output, "Command '%v' failed:\n%v\n", commandName, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stacktrace.Propagate(err, "An error occurred reading the REPL's input")
+	}
+
+	if len(session.partitionedServices) > 0 {
+		fmt.Fprintf(
+			output,
+			"NOTE: %v service(s) are still partitioned off from the network; attach again & run 'heal' to reconnect them\n",
+			len(session.partitionedServices))
+	}
+	return nil
+}
+
+func getReplCommands() map[string]replCommand {
+	return map[string]replCommand{
+		"services": {
+			argsUsage:   "",
+			description: "Lists the test's services, with their states & IPs on the test network",
+			minArgs:     0,
+			maxArgs:     0,
+			execute:     executeReplServices,
+		},
+		"kill": {
+			argsUsage:   "<service>",
+			description: "Kills a service's container",
+			minArgs:     1,
+			maxArgs:     1,
+			execute:     getReplContainerAction("Killed", docker.DockerManager.KillContainer),
+		},
+		"pause": {
+			argsUsage:   "<service>",
+			description: "Freezes a service's processes, without stopping its container",
+			minArgs:     1,
+			maxArgs:     1,
+			execute:     getReplContainerAction("Paused", docker.DockerManager.PauseContainer),
+		},
+		"unpause": {
+			argsUsage:   "<service>",
+			description: "Resumes a paused service",
+			minArgs:     1,
+			maxArgs:     1,
+			execute:     getReplContainerAction("Unpaused", docker.DockerManager.UnpauseContainer),
+		},
+		"partition": {
+			argsUsage:   "<service> [<service>...]",
+			description: "Cuts services off from the test network, so they can neither reach nor be reached by any other service",
+			minArgs:     1,
+			maxArgs:     unlimitedReplArgs,
+			execute:     executeReplPartition,
+		},
+		"heal": {
+			argsUsage:   "",
+			description: "Reconnects every partitioned service to the test network, with the IP & hostnames it had",
+			minArgs:     0,
+			maxArgs:     0,
+			execute:     executeReplHeal,
+		},
+		"rpc": {
+			argsUsage:   "<service> <port> <method> [<params JSON>]",
+			description: "Makes a JSON-RPC call to a service & prints the response",
+			minArgs:     3,
+			maxArgs:     unlimitedReplArgs,
+			execute:     executeReplRpc,
+		},
+		"http": {
+			argsUsage:   "<service> <port> <HTTP method> <path> [<body>]",
+			description: "Makes an HTTP request to a service & prints the response",
+			minArgs:     4,
+			maxArgs:     unlimitedReplArgs,
+			execute:     executeReplHttp,
+		},
+	}
+}
+
+func writeReplHelp(commands map[string]replCommand, output io.Writer) {
+	commandNames := make([]string, 0, len(commands))
+	for commandName, _ := range commands {
+		commandNames = append(commandNames, commandName)
+	}
+	sort.Strings(commandNames)
+
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	for _, commandName := range commandNames {
+		cmd := commands[commandName]
+		fmt.Fprintf(writer, "%v %v\t%v\n", commandName, cmd.argsUsage, cmd.description)
+	}
+	fmt.Fprintln(writer, "help\tShows this help")
+	fmt.Fprintln(writer, "exit\tLeaves the REPL, leaving the network as it is")
+	writer.Flush()
+}
+
+func executeReplServices(session *replSession, ctx context.Context, args []string, output io.Writer) error {
+	serviceContainers, err := getRunServiceContainers(ctx, session.dockerManager, session.runId, session.testName)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred getting the services of test %v", session.testName)
+	}
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SERVICE\tCONTAINER\tSTATE\tIP")
+	for _, serviceContainer := range serviceContainers {
+		status, err := session.dockerManager.GetContainerStatus(ctx, serviceContainer.containerId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the status of service %v", serviceContainer.serviceId)
+		}
+		ipAddr, err := session.dockerManager.GetContainerIpAddr(ctx, serviceContainer.containerId, session.networkId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the IP of service %v", serviceContainer.serviceId)
+		}
+		ipAddrStr := emptyTableCell
+		if ipAddr != nil {
+			ipAddrStr = ipAddr.String()
+		}
+		state := status.State
+		if _, found := session.partitionedServices[serviceContainer.serviceId]; found {
+			state += " (partitioned)"
+		}
+		fmt.Fprintf(writer, "%v\t%v\t%v\t%v\n", serviceContainer.serviceId, serviceContainer.containerId, state, ipAddrStr)
+	}
+	return writer.Flush()
+}
+
+// Gets the execution function of a REPL command that runs the given action on a service's container
+func getReplContainerAction(
+			pastTenseVerb string,
+			action func(docker.DockerManager, context.Context, string) error) func(*replSession, context.Context, []string, io.Writer) error {
+	return func(session *replSession, ctx context.Context, args []string, output io.Writer) error {
+		serviceId := args[0]
+		containerId, err := findServiceContainer(ctx, session.dockerManager, session.runId, session.testName, serviceId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred finding the container of service %v", serviceId)
+		}
+		if err := action(*session.dockerManager, ctx, containerId); err != nil {
+			return stacktrace.Propagate(err, "An error occurred on the container of service %v", serviceId)
+		}
+		fmt.Fprintf(output, "%v service %v\n", pastTenseVerb, serviceId)
+		return nil
+	}
+}
+
+func executeReplPartition(session *replSession, ctx context.Context, args []string, output io.Writer) error {
+	for _, serviceId := range args {
+		if _, found := session.partitionedServices[serviceId]; found {
+			continue
+		}
+		containerId, err := findServiceContainer(ctx, session.dockerManager, session.runId, session.testName, serviceId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred finding the container of service %v", serviceId)
+		}
+		ipAddr, err := session.dockerManager.GetContainerIpAddr(ctx, containerId, session.networkId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the IP of service %v", serviceId)
+		}
+		if ipAddr == nil {
+			return stacktrace.NewError("Service %v isn't on the test network", serviceId)
+		}
+		aliases, err := session.dockerManager.GetContainerNetworkAliases(ctx, containerId, session.networkId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the hostnames of service %v", serviceId)
+		}
+		if err := session.dockerManager.DisconnectContainerFromNetwork(ctx, session.networkId, containerId); err != nil {
+			return stacktrace.Propagate(err, "An error occurred partitioning service %v off from the network", serviceId)
+		}
+		session.partitionedServices[serviceId] = partitionedService{
+			containerId: containerId,
+			ipAddr:      ipAddr,
+			aliases:     aliases,
+		}
+		fmt.Fprintf(output, "Partitioned service %v off from the network\n", serviceId)
+	}
+	return nil
+}
+
+func executeReplHeal(session *replSession, ctx context.Context, args []string, output io.Writer) error {
+	serviceIds := make([]string, 0, len(session.partitionedServices))
+	for serviceId, _ := range session.partitionedServices {
+		serviceIds = append(serviceIds, serviceId)
+	}
+	sort.Strings(serviceIds)
+	for _, serviceId := range serviceIds {
+		service := session.partitionedServices[serviceId]
+		if err := session.dockerManager.ConnectContainerToNetwork(ctx, session.networkId, service.containerId, service.ipAddr, service.aliases); err != nil {
+			return stacktrace.Propagate(err, "An error occurred reconnecting service %v to the network", serviceId)
+		}
+		delete(session.partitionedServices, serviceId)
+		fmt.Fprintf(output, "Reconnected service %v to the network\n", serviceId)
+	}
+	return nil
+}
+
+func executeReplRpc(session *replSession, ctx context.Context, args []string, output io.Writer) error {
+	serviceId, portStr, method := args[0], args[1], args[2]
+	request := jsonRpcRequest{
+		JsonRpc: jsonRpcVersion,
+		Id:      1,
+		Method:  method,
+	}
+	if len(args) > 3 {
+		params := strings.Join(args[3:], " ")
+		if !json.Valid([]byte(params)) {
+			return stacktrace.NewError("The params '%v' aren't valid JSON", params)
+		}
+		request.Params = json.RawMessage(params)
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred serializing the JSON-RPC request")
+	}
+	return makeReplHttpRequest(session, ctx, serviceId, portStr, http.MethodPost, "/", body, output)
+}
+
+func executeReplHttp(session *replSession, ctx context.Context, args []string, output io.Writer) error {
+	serviceId, portStr, method, path := args[0], args[1], strings.ToUpper(args[2]), args[3]
+	if !strings.HasPrefix(path, "/") {
+		return stacktrace.NewError("Path '%v' must start with '/'", path)
+	}
+	var body []byte = nil
+	if len(args) > 4 {
+		body = []byte(strings.Join(args[4:], " "))
+	}
+	return makeReplHttpRequest(session, ctx, serviceId, portStr, method, path, body, output)
+}
+
+/*
+Makes an HTTP request to the given port of the given service, writing the response's status & body to the output. The
+	service is reached through its published host port if it has one, or else its IP on the test network (which is
+	only reachable from the Docker engine's host).
+ */
+func makeReplHttpRequest(
+			session *replSession,
+			ctx context.Context,
+			serviceId string,
+			portStr string,
+			method string,
+			path string,
+			body []byte,
+			output io.Writer) error {
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return stacktrace.NewError("'%v' isn't a valid port", portStr)
+	}
+	containerId, err := findServiceContainer(ctx, session.dockerManager, session.runId, session.testName, serviceId)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred finding the container of service %v", serviceId)
+	}
+	networkInfo, err := session.dockerManager.GetContainerNetworkInfo(ctx, containerId)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred getting the network info of service %v", serviceId)
+	}
+
+	var hostAndPort string
+	if hostPort, found := networkInfo.HostPorts[nat.Port(fmt.Sprintf("%v/tcp", port))]; found {
+		hostAndPort = net.JoinHostPort("localhost", strconv.Itoa(hostPort))
+	} else if ipAddr, found := networkInfo.IpAddrs[session.networkId]; found {
+		hostAndPort = net.JoinHostPort(ipAddr.String(), strconv.Itoa(port))
+	} else {
+		return stacktrace.NewError("Service %v has neither a published port %v nor an IP on the test network (is it partitioned?)", serviceId, port)
+	}
+
+	request, err := http.NewRequest(method, "http://" + hostAndPort + path, bytes.NewReader(body))
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred building the request to service %v", serviceId)
+	}
+	if body != nil && json.Valid(body) {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := session.httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred making the request to service %v at %v", serviceId, hostAndPort)
+	}
+	defer response.Body.Close()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred reading the response from service %v", serviceId)
+	}
+	fmt.Fprintln(output, response.Status)
+	if len(responseBody) > 0 {
+		fmt.Fprintln(output, strings.TrimRight(string(responseBody), "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"bytes"
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+)
+
+func TestReplValidatesCommandsBeforeRunningThem(t *testing.T) {
+	// No Docker manager is needed, since none of these commands get as far as touching Docker
+	session := &replSession{partitionedServices: map[string]partitionedService{}}
+	input := strings.Join([]string{
+		"",
+		"help",
+		"bogus",
+		"kill",
+		"kill service1 service2",
+		"rpc service1 notaport method",
+		"rpc service1 8080 method {\"unclosed\": ",
+		"http service1 8080 GET no-slash",
+		"exit",
+		"services",
+	}, "\n")
+	output := &bytes.Buffer{}
+	assert.NilError(t, runRepl(session, strings.NewReader(input), output))
+
+	outputStr := output.String()
+	for commandName, _ := range getReplCommands() {
+		assert.Assert(t, strings.Contains(outputStr, commandName + " "), "Help is missing command %v", commandName)
+	}
+	assert.Assert(t, strings.Contains(outputStr, "Unknown command 'bogus'"))
+	assert.Equal(t, 2, strings.Count(outputStr, "Usage: kill <service>"))
+	assert.Assert(t, strings.Contains(outputStr, "'notaport' isn't a valid port"))
+	assert.Assert(t, strings.Contains(outputStr, "aren't valid JSON"))
+	assert.Assert(t, strings.Contains(outputStr, "must start with '/'"))
+	// Nothing after "exit" is run
+	assert.Assert(t, !strings.Contains(outputStr, "SERVICE"))
+}
+
+func TestReplWarnsAboutRemainingPartitions(t *testing.T) {
+	session := &replSession{partitionedServices: map[string]partitionedService{"service1": {}}}
+	output := &bytes.Buffer{}
+	assert.NilError(t, runRepl(session, strings.NewReader(""), output))
+	assert.Assert(t, strings.Contains(output.String(), "1 service(s) are still partitioned"))
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	listCommandName = "list"
+	cleanCommandName = "clean"
+
+	defaultCleanContainerStopTimeout = 10 * time.Second
+)
+
+// The labels that every Kurtosis-managed network & volume has
+var managedResourceLabels = map[string]string{
+	docker.MANAGED_BY_LABEL: docker.MANAGED_BY_LABEL_VALUE,
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configureListFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	return func(stdout io.Writer) (bool, error) {
+		dockerManager, err := newDockerManager()
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred connecting to the Docker engine")
+		}
+		if err := listManagedResources(context.Background(), dockerManager, stdout); err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred listing the Kurtosis-managed resources")
+		}
+		return true, nil
+	}
+}
+
+func configureCleanFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	containerStopTimeout := flags.Duration("container-stop-timeout", defaultCleanContainerStopTimeout, "How long to wait for each running container to stop")
+	return func(stdout io.Writer) (bool, error) {
+		dockerManager, err := newDockerManager()
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred connecting to the Docker engine")
+		}
+		return cleanManagedResources(context.Background(), dockerManager, *containerStopTimeout, stdout)
+	}
+}
+
+// Writes the Kurtosis-managed networks (with the containers attached to them) & volumes to the given output
+func listManagedResources(ctx context.Context, dockerManager *docker.DockerManager, output io.Writer) error {
+	networkIds, err := dockerManager.ListNetworks(ctx, managedResourceLabels)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred listing the Kurtosis-managed networks")
+	}
+	volumeNames, err := dockerManager.ListVolumes(ctx, managedResourceLabels)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred listing the Kurtosis-managed volumes")
+	}
+
+	fmt.Fprintf(output, "Networks (%v):\n", len(networkIds))
+	for _, networkId := range networkIds {
+		fmt.Fprintf(output, "  %v\n", networkId)
+		containerIds, err := dockerManager.GetContainersOnNetwork(ctx, networkId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the containers on network %v", networkId)
+		}
+		sortedContainerIds := make([]string, 0, len(containerIds))
+		for containerId, _ := range containerIds {
+			sortedContainerIds = append(sortedContainerIds, containerId)
+		}
+		sort.Strings(sortedContainerIds)
+		for _, containerId := range sortedContainerIds {
+			fmt.Fprintf(output, "    container %v\n", containerId)
+		}
+	}
+	fmt.Fprintf(output, "Volumes (%v):\n", len(volumeNames))
+	for _, volumeName := range volumeNames {
+		fmt.Fprintf(output, "  %v\n", volumeName)
+	}
+	return nil
+}
+
+/*
+Removes every Kurtosis-managed network (stopping its containers first) and then every Kurtosis-managed volume (removing
+	the stopped containers that reference it first), carrying on past failures so as much as possible is cleaned up.
+
+Returns:
+	True if everything was removed
+ */
+func cleanManagedResources(
+			ctx context.Context,
+			dockerManager *docker.DockerManager,
+			containerStopTimeout time.Duration,
+			output io.Writer) (bool, error) {
+	networkIds, err := dockerManager.ListNetworks(ctx, managedResourceLabels)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred listing the Kurtosis-managed networks")
+	}
+	allRemoved := true
+	for _, networkId := range networkIds {
+		if err := dockerManager.RemoveNetwork(ctx, networkId, containerStopTimeout); err != nil {
+			logrus.Errorf("An error occurred removing Docker network %v: %v", networkId, err)
+			allRemoved = false
+			continue
+		}
+		fmt.Fprintf(output, "Removed network %v\n", networkId)
+	}
+
+	// Volumes are removed after networks, since the networks' containers must be stopped before the volumes can be removed
+	volumeNames, err := dockerManager.ListVolumes(ctx, managedResourceLabels)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred listing the Kurtosis-managed volumes")
+	}
+	for _, volumeName := range volumeNames {
+		if err := dockerManager.RemoveVolume(ctx, volumeName, true); err != nil {
+			logrus.Errorf("An error occurred removing Docker volume %v: %v", volumeName, err)
+			allRemoved = false
+			continue
+		}
+		fmt.Fprintf(output, "Removed volume %v\n", volumeName)
+	}
+	return allRemoved, nil
+}
@@ -0,0 +1,341 @@
+package cli
+
+import (
+	"flag"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+	"github.com/kurtosis-tech/kurtosis/initializer"
+	"github.com/kurtosis-tech/kurtosis/initializer/notifications"
+	"github.com/kurtosis-tech/kurtosis/initializer/parallelism"
+	"github.com/kurtosis-tech/kurtosis/initializer/timings"
+	"github.com/kurtosis-tech/kurtosis/initializer/uploads"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	runCommandName = "run"
+
+	defaultControllerLogLevel = "info"
+
+	// Gives each test network room for 256 IPs, which is enough for most tests' services
+	defaultNetworkWidthBits = 8
+
+	// The -output values: human-readable logs, or only newline-delimited JSON events on STDOUT
+	textOutputFormat = "text"
+	jsonOutputFormat = "json"
+
+	// The -notify-payload values that stand for the notifications package's payload templates
+	jsonNotifyPayload  = "json"
+	slackNotifyPayload = "slack"
+)
+
+// The args of the "run" command, filled in from its flags
+type runArgs struct {
+	suiteName string
+	controllerImageName string
+	testNames string
+	parallelism uint
+	controllerLogLevel string
+	networkWidthBits uint
+	supernetCidr string
+	ipv6SupernetCidr string
+	showDashboard bool
+	artifactsDirpath string
+	artifactVerbosity string
+	snapshotsDirpath string
+	swarmDockerHosts string
+	registryMirror string
+	isEgressBlocked bool
+	outputFormat string
+	ciLogFormat string
+	timingsFilepath string
+	shardIndex uint
+	shardCount uint
+	notifyWebhookUrl string
+	notifyPayload string
+	notifyOnlyOnFailure bool
+	uploadUrl string
+	uploadHeaders string
+	uploadCommand string
+	uploadUrlTemplate string
+	junitReportFilepath string
+	orphanMaxAge time.Duration
+	maxCapturedSystemLogs int
+	allowedSystemLogCallers string
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configureRunFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	args := &runArgs{}
+	flags.StringVar(&args.suiteName, "suite", "", "Name of the registered test suite to run (may be omitted if only one suite is registered)")
+	flags.StringVar(&args.controllerImageName, "controller-image", "", "Controller image to run the tests with, overriding the suite's")
+	flags.StringVar(&args.testNames, "tests", "", "Comma-separated names of the tests to run (all of the suite's tests if empty)")
+	flags.UintVar(&args.parallelism, "parallelism", uint(runtime.NumCPU()), "How many tests to run in parallel")
+	flags.StringVar(&args.controllerLogLevel, "controller-log-level", defaultControllerLogLevel, "Log level the controller runs with")
+	flags.UintVar(&args.networkWidthBits, "network-width-bits", defaultNetworkWidthBits, "Each test network gets 2 ^ this many IPs")
+	flags.StringVar(&args.supernetCidr, "supernet", initializer.DEFAULT_SUPERNET_CIDR, "CIDR that test networks' subnets are carved out of")
+	flags.StringVar(&args.ipv6SupernetCidr, "ipv6-supernet", "", "IPv6 CIDR that makes test networks dual-stack (single-stack if empty)")
+	flags.BoolVar(&args.showDashboard, "dashboard", false, "Draw a live dashboard of the running tests to STDERR")
+	flags.StringVar(&args.artifactsDirpath, "artifacts-dir", "", "Directory to export each test's service artifacts to (none if empty)")
+	flags.StringVar(&args.artifactVerbosity, "artifact-verbosity", string(networks.LOG_ARTIFACTS), "How much to export to the artifacts directory: none, logs, or all")
+	flags.StringVar(&args.snapshotsDirpath, "snapshots-dir", "", "Directory to keep service data snapshots in between runs (none if empty)")
+	flags.StringVar(&args.swarmDockerHosts, "swarm-hosts", "", "Comma-separated Docker host URLs of the other Swarm nodes to spread services across")
+	flags.StringVar(&args.registryMirror, "registry-mirror", "", "Host of a pull-through registry mirror to pull images through, or '" + initializer.LOCAL_REGISTRY_MIRROR + "'")
+	flags.BoolVar(&args.isEgressBlocked, "block-egress", false, "Create test networks without outbound connectivity")
+	flags.StringVar(&args.outputFormat, "output", textOutputFormat, "'" + textOutputFormat + "' for human-readable logs, or '" + jsonOutputFormat + "' for only a stream of JSON events (ending with the result) on STDOUT")
+	flags.StringVar(&args.ciLogFormat, "ci-log-format", "", "How to mark up each test's output for a CI system's UI: plain, github-actions, or buildkite (detected from the environment if empty)")
+	flags.StringVar(&args.timingsFilepath, "timings-file", "", "File that tests' durations are kept in between runs, for starting the longest tests first & balancing shards (none if empty)")
+	flags.UintVar(&args.shardIndex, "shard-index", 0, "Which of the -shard-count shards of the tests to run (from 0), e.g. the CI job's index")
+	flags.UintVar(&args.shardCount, "shard-count", 1, "How many shards of about equal duration (according to -timings-file) to split the tests into, e.g. across CI jobs")
+	flags.StringVar(&args.notifyWebhookUrl, "notify-webhook", "", "URL of a webhook (e.g. a Slack incoming webhook) to POST the run's summary to once it's finished (none if empty)")
+	flags.StringVar(&args.notifyPayload, "notify-payload", jsonNotifyPayload, "Payload of the -notify-webhook call: '" + jsonNotifyPayload + "' for the summary as JSON, '" + slackNotifyPayload + "' for a Slack message, or a Go template rendered against the summary")
+	flags.BoolVar(&args.notifyOnlyOnFailure, "notify-only-on-failure", false, "Only call the -notify-webhook for runs that didn't pass")
+	flags.StringVar(&args.uploadUrl, "upload-url", "", "URL (e.g. an Azure container URL with a SAS token) to PUT each test's log & artifacts under once it finishes, printing their URLs in the summary")
+	flags.StringVar(&args.uploadHeaders, "upload-headers", "", "Comma-separated 'Name=value' headers to send with each -upload-url upload (e.g. 'Authorization=Bearer <token>')")
+	flags.StringVar(&args.uploadCommand, "upload-command", "", "Shell command that uploads the artifact file in $" + uploads.FILEPATH_ENV_VARIABLE + " as key $" + uploads.KEY_ENV_VARIABLE + " (e.g. with 'aws s3 cp'), instead of -upload-url")
+	flags.StringVar(&args.uploadUrlTemplate, "upload-url-template", "", "Go template of the URL that -upload-command uploads each artifact to, e.g. 'https://my-bucket.s3.amazonaws.com/{{ .Key }}'")
+	flags.StringVar(&args.junitReportFilepath, "junit-report", "", "File to write a JUnit XML report of the run to once it's finished, for CI systems' test result views (none if empty)")
+	flags.DurationVar(&args.orphanMaxAge, "clean-up-orphans-older-than", 0, "Before running the tests, remove the Kurtosis-managed containers, networks, & volumes created longer ago than this (e.g. '6h'), which earlier runs left behind (none if zero)")
+	flags.IntVar(&args.maxCapturedSystemLogs, "max-captured-system-logs", parallelism.DEFAULT_MAX_CAPTURED_ERRONEOUS_MESSAGES, "Most system-level log messages to keep between tests finishing, after which the oldest are dropped")
+	flags.StringVar(&args.allowedSystemLogCallers, "allowed-system-log-callers", "", "Comma-separated package prefixes (e.g. 'github.com/docker/docker/') of noisy code whose system-level log messages are passed through rather than captured")
+	return func(stdout io.Writer) (bool, error) {
+		return executeRun(*args, stdout)
+	}
+}
+
+func executeRun(args runArgs, stdout io.Writer) (bool, error) {
+	registration, err := getSuiteToRun(args.suiteName)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred getting the test suite to run")
+	}
+	controllerImageName := registration.ControllerImageName
+	if args.controllerImageName != "" {
+		controllerImageName = args.controllerImageName
+	}
+	if controllerImageName == "" {
+		return false, stacktrace.NewError("No controller image was registered with the test suite or given with the -controller-image flag")
+	}
+
+	artifactVerbosity := networks.ArtifactVerbosity(args.artifactVerbosity)
+	switch artifactVerbosity {
+	case networks.NO_ARTIFACTS, networks.LOG_ARTIFACTS, networks.ALL_ARTIFACTS:
+	default:
+		return false, stacktrace.NewError("Unrecognized artifact verbosity '%v'", args.artifactVerbosity)
+	}
+
+	swarmDockerHosts := []string{}
+	for host, _ := range splitCommaSeparatedFlag(args.swarmDockerHosts) {
+		swarmDockerHosts = append(swarmDockerHosts, host)
+	}
+	sort.Strings(swarmDockerHosts)
+
+	if args.ciLogFormat != "" && !parallelism.IsValidCiLogFormat(args.ciLogFormat) {
+		return false, stacktrace.NewError("Unrecognized CI log format '%v'", args.ciLogFormat)
+	}
+
+	var eventsOutput io.Writer = nil
+	switch args.outputFormat {
+	case textOutputFormat:
+	case jsonOutputFormat:
+		eventsOutput = stdout
+	default:
+		return false, stacktrace.NewError("Unrecognized output format '%v'", args.outputFormat)
+	}
+
+	var timingStore timings.TimingStore = nil
+	if args.timingsFilepath != "" {
+		timingStore = timings.NewFileTimingStore(args.timingsFilepath)
+	}
+	notifiers, err := getNotifiers(args)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred getting the run's notifiers")
+	}
+	artifactUploader, err := getArtifactUploader(args)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred getting the artifact uploader")
+	}
+	testNames := splitCommaSeparatedFlag(args.testNames)
+	if args.shardCount != 1 {
+		testNames, err = getShardTestNames(registration.TestSuite, testNames, timingStore, args.shardIndex, args.shardCount)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred getting the tests of shard %v", args.shardIndex)
+		}
+		// Running no tests would otherwise mean running them all
+		if len(testNames) == 0 {
+			logrus.Infof("Shard %v of %v has no tests to run", args.shardIndex, args.shardCount)
+			return true, nil
+		}
+	}
+
+	allowedSystemLogCallers := []string{}
+	for callerPackage, _ := range splitCommaSeparatedFlag(args.allowedSystemLogCallers) {
+		allowedSystemLogCallers = append(allowedSystemLogCallers, callerPackage)
+	}
+	sort.Strings(allowedSystemLogCallers)
+	systemLogCaptureOptions := parallelism.SystemLogCaptureOptions{
+		MaxMessages:           args.maxCapturedSystemLogs,
+		AllowedCallerPackages: allowedSystemLogCallers,
+	}
+
+	runner := initializer.NewTestSuiteRunner(
+		registration.TestSuite,
+		controllerImageName,
+		args.controllerLogLevel,
+		map[string]string{},
+		uint32(args.networkWidthBits),
+		initializer.TestSuiteRunnerOptions{
+			SupernetCidr:            args.supernetCidr,
+			Ipv6SupernetCidr:        args.ipv6SupernetCidr,
+			ShowDashboard:           args.showDashboard,
+			ArtifactsDirpath:        args.artifactsDirpath,
+			ArtifactVerbosity:       artifactVerbosity,
+			SnapshotsDirpath:        args.snapshotsDirpath,
+			SwarmDockerHosts:        swarmDockerHosts,
+			RegistryMirror:          args.registryMirror,
+			IsEgressBlocked:         args.isEgressBlocked,
+			EventsOutput:            eventsOutput,
+			CiLogFormat:             parallelism.CiLogFormat(args.ciLogFormat),
+			TimingStore:             timingStore,
+			Notifiers:               notifiers,
+			ArtifactUploader:        artifactUploader,
+			JunitReportFilepath:     args.junitReportFilepath,
+			OrphanMaxAge:            args.orphanMaxAge,
+			SystemLogCaptureOptions: systemLogCaptureOptions,
+		})
+	allTestsPassed, err := runner.RunTests(testNames, args.parallelism)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred running the tests")
+	}
+	if !allTestsPassed && eventsOutput == nil {
+		logrus.Error("One or more tests failed")
+	}
+	return allTestsPassed, nil
+}
+
+// Gets the registered suite with the given name, or the only registered suite if the name is empty
+func getSuiteToRun(suiteName string) (SuiteRegistration, error) {
+	suiteNames := registeredSuites.getNames()
+	if len(suiteNames) == 0 {
+		return SuiteRegistration{}, stacktrace.NewError(
+			"No test suites are registered with this binary; build a binary that registers your suite with RegisterTestSuite & calls Main")
+	}
+	if suiteName == "" {
+		if len(suiteNames) > 1 {
+			return SuiteRegistration{}, stacktrace.NewError(
+				"Multiple test suites are registered, so one must be chosen with the -suite flag: %v",
+				strings.Join(suiteNames, ", "))
+		}
+		suiteName = suiteNames[0]
+	}
+	registration, found := registeredSuites.get(suiteName)
+	if !found {
+		return SuiteRegistration{}, stacktrace.NewError(
+			"No test suite is registered with name '%v'; registered suites: %v",
+			suiteName,
+			strings.Join(suiteNames, ", "))
+	}
+	return registration, nil
+}
+
+/*
+Gets the names of the tests in the given shard of the given tests (see timings.AssignShards), balanced by the durations
+	in the given timing store (if any).
+
+Args:
+	testNames: A "set" of the names of the tests to shard, or empty to shard all of the suite's tests
+	timingStore: The store of tests' durations from earlier runs, or nil to treat every test as taking equally long
+ */
+func getShardTestNames(
+			suite testsuite.TestSuite,
+			testNames map[string]bool,
+			timingStore timings.TimingStore,
+			shardIndex uint,
+			shardCount uint) (map[string]bool, error) {
+	if shardIndex >= shardCount {
+		return nil, stacktrace.NewError("Shard index %v is out of range for %v shards", shardIndex, shardCount)
+	}
+	allTests := suite.GetTests()
+	if len(testNames) == 0 {
+		testNames = map[string]bool{}
+		for testName, _ := range allTests {
+			testNames[testName] = true
+		}
+	}
+	for testName, _ := range testNames {
+		if _, found := allTests[testName]; !found {
+			return nil, stacktrace.NewError("No test registered with name '%v'", testName)
+		}
+	}
+
+	recordedDurations := map[string]time.Duration{}
+	if timingStore != nil {
+		durations, err := timingStore.GetDurations()
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred getting the tests' durations from earlier runs")
+		}
+		recordedDurations = durations
+	}
+	shards, err := timings.AssignShards(timings.EstimateDurations(testNames, recordedDurations), int(shardCount))
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred splitting the tests into shards")
+	}
+	return shards[shardIndex], nil
+}
+
+// Gets the notifiers that the run's summary is given to, according to the -notify-* flags
+func getNotifiers(args runArgs) ([]notifications.Notifier, error) {
+	if args.notifyWebhookUrl == "" {
+		return []notifications.Notifier{}, nil
+	}
+	payloadTemplate := args.notifyPayload
+	switch args.notifyPayload {
+	case jsonNotifyPayload:
+		payloadTemplate = notifications.DEFAULT_WEBHOOK_PAYLOAD_TEMPLATE
+	case slackNotifyPayload:
+		payloadTemplate = notifications.SLACK_WEBHOOK_PAYLOAD_TEMPLATE
+	}
+	webhookNotifier, err := notifications.NewWebhookNotifier(args.notifyWebhookUrl, payloadTemplate, args.notifyOnlyOnFailure)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the webhook notifier")
+	}
+	return []notifications.Notifier{webhookNotifier}, nil
+}
+
+// Gets the uploader of tests' artifacts according to the -upload-* flags, or nil if artifacts shouldn't be uploaded
+func getArtifactUploader(args runArgs) (uploads.ArtifactUploader, error) {
+	if args.uploadUrl != "" && args.uploadCommand != "" {
+		return nil, stacktrace.NewError("Only one of -upload-url & -upload-command can be given")
+	}
+	if args.uploadUrl != "" {
+		headers := make(map[string]string)
+		for header, _ := range splitCommaSeparatedFlag(args.uploadHeaders) {
+			headerComponents := strings.SplitN(header, "=", 2)
+			if len(headerComponents) != 2 {
+				return nil, stacktrace.NewError("Upload header '%v' isn't of the form 'Name=value'", header)
+			}
+			headers[strings.TrimSpace(headerComponents[0])] = strings.TrimSpace(headerComponents[1])
+		}
+		httpPutUploader, err := uploads.NewHttpPutUploader(args.uploadUrl, headers)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred creating the HTTP PUT uploader")
+		}
+		return httpPutUploader, nil
+	}
+	if args.uploadCommand != "" {
+		if args.uploadUrlTemplate == "" {
+			return nil, stacktrace.NewError("The -upload-url-template flag is required with -upload-command")
+		}
+		commandUploader, err := uploads.NewCommandUploader(args.uploadCommand, args.uploadUrlTemplate)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred creating the command uploader")
+		}
+		return commandUploader, nil
+	}
+	return nil, nil
+}
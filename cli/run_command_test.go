@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+	"github.com/kurtosis-tech/kurtosis/initializer/timings"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGettingShardTestNames(t *testing.T) {
+	suite := testPlanTestSuite{tests: map[string]testsuite.Test{
+		"slowTest":  testPlanTest{},
+		"fastTest1": testPlanTest{},
+		"fastTest2": testPlanTest{},
+	}}
+	tempDirpath, err := ioutil.TempDir("", "shards")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tempDirpath)
+	timingStore := timings.NewFileTimingStore(filepath.Join(tempDirpath, "timings.json"))
+	assert.NilError(t, timingStore.RecordDurations(map[string]time.Duration{
+		"slowTest":  time.Minute,
+		"fastTest1": time.Second,
+		"fastTest2": time.Second,
+	}))
+
+	shard0, err := getShardTestNames(suite, map[string]bool{}, timingStore, 0, 2)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]bool{"slowTest": true}, shard0)
+	shard1, err := getShardTestNames(suite, map[string]bool{}, timingStore, 1, 2)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]bool{"fastTest1": true, "fastTest2": true}, shard1)
+
+	_, err = getShardTestNames(suite, map[string]bool{}, timingStore, 2, 2)
+	assert.ErrorContains(t, err, "out of range")
+	_, err = getShardTestNames(suite, map[string]bool{"nonexistentTest": true}, nil, 0, 2)
+	assert.ErrorContains(t, err, "nonexistentTest")
+}
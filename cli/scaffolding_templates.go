@@ -0,0 +1,364 @@
+package cli
+
+/*
+The templates of the files that the "init" command generates, keyed by the files' paths relative to the new suite's
+	directory. They're rendered with Go's text/template against a scaffoldingParams, so any literal "{{" in them must be
+	escaped.
+ */
+var scaffoldingTemplates = map[string]string{
+	"go.mod": `module {{ .ModulePath }}
+
+go 1.13
+`,
+
+	"Makefile": `CONTROLLER_IMAGE ?= {{ .ControllerImageName }}
+
+.PHONY: deps controller-image plan run clean
+
+# Resolves the Kurtosis framework & the suite's other dependencies
+deps:
+	go get github.com/kurtosis-tech/kurtosis
+	go mod tidy
+
+# Builds the image of the controller that runs each test inside the test network
+controller-image:
+	docker build -f controller.Dockerfile -t $(CONTROLLER_IMAGE) .
+
+# Validates the tests' networks without touching Docker
+plan:
+	go run ./cmd/initializer plan
+
+# Runs every test of the suite
+run: controller-image
+	go run ./cmd/initializer run -controller-image $(CONTROLLER_IMAGE)
+
+# Removes anything that a killed run left behind
+clean:
+	go run ./cmd/initializer clean
+`,
+
+	"controller.Dockerfile": `FROM golang:1.13-alpine AS builder
+WORKDIR /build
+COPY go.mod go.sum ./
+RUN go mod download
+COPY . .
+RUN CGO_ENABLED=0 go build -o /controller ./cmd/controller
+
+FROM alpine:3.12
+COPY --from=builder /controller /controller
+
+# The initializer passes the test's settings to the controller as these environment variables
+CMD /controller \
+    -test="${TEST_NAME}" \
+    -subnet-mask="${SUBNET_MASK}" \
+    -network-id="${NETWORK_ID}" \
+    -gateway-ip="${GATEWAY_IP}" \
+    -log-level="${LOG_LEVEL}" \
+    -test-controller-ip="${TEST_CONTROLLER_IP}" \
+    -test-volume="${TEST_VOLUME}" \
+    -test-volume-mountpoint="${TEST_VOLUME_MOUNTPOINT}" \
+    -artifacts-dirpath="${ARTIFACTS_DIRPATH}" \
+    -artifact-verbosity="${ARTIFACT_VERBOSITY}" \
+    -snapshots-dirpath="${SNAPSHOTS_DIRPATH}" \
+    -swarm-docker-hosts="${SWARM_DOCKER_HOSTS}" \
+    -execution-id="${EXECUTION_ID}" \
+    -test-suite="${TEST_SUITE}" \
+    > "${LOG_FILEPATH}" 2>&1
+`,
+
+	"cmd/initializer/main.go": `package main
+
+import (
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/cli"
+	"{{ .ModulePath }}/testsuite"
+	"os"
+)
+
+const (
+	suiteName = "{{ .SuiteName }}"
+
+	controllerImageName = "{{ .ControllerImageName }}"
+)
+
+func main() {
+	registration := cli.SuiteRegistration{
+		TestSuite:           testsuite.NewTestSuite(),
+		ControllerImageName: controllerImageName,
+	}
+	if err := cli.RegisterTestSuite(suiteName, registration); err != nil {
+		fmt.Fprintf(os.Stderr, "An error occurred registering the test suite: %v\n", err)
+		os.Exit(cli.ERROR_EXIT_CODE)
+	}
+	cli.Main()
+}
+`,
+
+	"cmd/controller/main.go": `package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/controller"
+	"github.com/sirupsen/logrus"
+	"{{ .ModulePath }}/testsuite"
+	"os"
+	"strings"
+)
+
+const (
+	setupErrorExitCode = 1
+	testFailureExitCode = 2
+)
+
+func main() {
+	testName := flag.String("test", "", "Name of the test to run")
+	subnetMask := flag.String("subnet-mask", "", "Subnet of the test network")
+	networkId := flag.String("network-id", "", "ID of the test network")
+	gatewayIp := flag.String("gateway-ip", "", "Gateway IP of the test network")
+	logLevel := flag.String("log-level", "info", "Log level of the controller")
+	testControllerIp := flag.String("test-controller-ip", "", "IP of the controller in the test network")
+	testVolume := flag.String("test-volume", "", "Name of the test volume")
+	testVolumeMountpoint := flag.String("test-volume-mountpoint", "", "Where the test volume is mounted in the controller")
+	artifactsDirpath := flag.String("artifacts-dirpath", "", "Where to export the services' artifacts (none if empty)")
+	artifactVerbosity := flag.String("artifact-verbosity", string(networks.LOG_ARTIFACTS), "How much to export as artifacts")
+	snapshotsDirpath := flag.String("snapshots-dirpath", "", "Where service data snapshots are stored (none if empty)")
+	swarmDockerHosts := flag.String("swarm-docker-hosts", "", "Comma-separated Docker hosts of the other Swarm nodes")
+	executionId := flag.String("execution-id", "", "UUID of the test suite execution")
+	testSuiteName := flag.String("test-suite", "", "Name of the test suite")
+	flag.Parse()
+
+	level, err := logrus.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unrecognized log level '%v': %v\n", *logLevel, err)
+		os.Exit(setupErrorExitCode)
+	}
+	logrus.SetLevel(level)
+
+	swarmDockerHostsList := []string{}
+	if *swarmDockerHosts != "" {
+		swarmDockerHostsList = strings.Split(*swarmDockerHosts, ",")
+	}
+	testController := controller.NewTestController(
+		*testVolume,
+		*testVolumeMountpoint,
+		*networkId,
+		*subnetMask,
+		*gatewayIp,
+		*testControllerIp,
+		testsuite.NewTestSuite(),
+		*testName,
+		*artifactsDirpath,
+		networks.ArtifactVerbosity(*artifactVerbosity),
+		"",
+		*snapshotsDirpath,
+		swarmDockerHostsList,
+		nil,
+		*executionId,
+		*testSuiteName)
+	setupErr, testErr := testController.RunTest()
+	if setupErr != nil {
+		fmt.Printf("Test %v encountered an error during setup (test did not run):\n%v\n", *testName, setupErr)
+		os.Exit(setupErrorExitCode)
+	}
+	if testErr != nil {
+		fmt.Printf("Test %v failed:\n%v\n", *testName, testErr)
+		os.Exit(testFailureExitCode)
+	}
+	fmt.Printf("Test %v succeeded\n", *testName)
+}
+`,
+
+	"testsuite/test_suite.go": `package testsuite
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+)
+
+/*
+The suite's tests, keyed by name. Add new tests here.
+ */
+type TestSuite struct {}
+
+func NewTestSuite() *TestSuite {
+	return &TestSuite{}
+}
+
+func (suite TestSuite) GetTests() map[string]testsuite.Test {
+	return map[string]testsuite.Test{
+		"webServerServesPage": WebServerServesPageTest{},
+	}
+}
+`,
+
+	"testsuite/web_server_service.go": `package testsuite
+
+import (
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// An example service to test; replace it with the image of your own service
+	webServerImage = "nginx:1.19-alpine"
+
+	webServerPort = 80
+
+	webServerAvailabilityTimeout = 30 * time.Second
+
+	webServerRequestTimeout = 2 * time.Second
+)
+
+/*
+The test's view of a running web server, which tests use to talk to it.
+ */
+type WebServerService struct {
+	IpAddr string
+}
+
+func (service WebServerService) GetUrl() string {
+	return fmt.Sprintf("http://%v:%v/", service.IpAddr, webServerPort)
+}
+
+/*
+Tells Kurtosis how to start the web server's container.
+ */
+type WebServerInitializerCore struct {}
+
+func (core WebServerInitializerCore) GetUsedPorts() map[nat.Port]bool {
+	return map[nat.Port]bool{
+		nat.Port(fmt.Sprintf("%v/tcp", webServerPort)): true,
+	}
+}
+
+func (core WebServerInitializerCore) GetServiceFromIp(ipAddr string) services.Service {
+	return WebServerService{IpAddr: ipAddr}
+}
+
+func (core WebServerInitializerCore) GetFilesToMount() map[string]bool {
+	return map[string]bool{}
+}
+
+func (core WebServerInitializerCore) InitializeMountedFiles(mountedFiles map[string]*os.File, dependencies []services.Service) error {
+	return nil
+}
+
+func (core WebServerInitializerCore) GetTestVolumeMountpoint() string {
+	return "/test-volume"
+}
+
+func (core WebServerInitializerCore) GetStartCommand(startCommandContext services.StartCommandContext) ([]string, error) {
+	return []string{"nginx", "-g", "daemon off;"}, nil
+}
+
+/*
+Tells Kurtosis when the web server is ready for the test to use.
+ */
+type WebServerAvailabilityCheckerCore struct {}
+
+func (core WebServerAvailabilityCheckerCore) IsServiceUp(toCheck services.Service, dependencies []services.Service) bool {
+	client := http.Client{Timeout: webServerRequestTimeout}
+	resp, err := client.Get(toCheck.(WebServerService).GetUrl())
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func (core WebServerAvailabilityCheckerCore) GetTimeout() time.Duration {
+	return webServerAvailabilityTimeout
+}
+`,
+
+	"testsuite/web_server_network_loader.go": `package testsuite
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+)
+
+const (
+	webServerConfigurationId networks.ConfigurationID = "web-server"
+
+	webServerServiceId networks.ServiceID = "web-server"
+)
+
+/*
+Sets up the network of services that the tests run against: here, a single web server.
+ */
+type WebServerNetworkLoader struct {}
+
+func (loader WebServerNetworkLoader) ConfigureNetwork(builder *networks.ServiceNetworkBuilder) error {
+	return builder.AddConfiguration(
+		webServerConfigurationId,
+		webServerImage,
+		WebServerInitializerCore{},
+		WebServerAvailabilityCheckerCore{})
+}
+
+func (loader WebServerNetworkLoader) InitializeNetwork(network *networks.ServiceNetwork) (map[networks.ServiceID]services.ServiceAvailabilityChecker, error) {
+	checker, err := network.AddService(webServerConfigurationId, webServerServiceId, map[networks.ServiceID]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return map[networks.ServiceID]services.ServiceAvailabilityChecker{
+		webServerServiceId: *checker,
+	}, nil
+}
+
+func (loader WebServerNetworkLoader) WrapNetwork(network *networks.ServiceNetwork) (networks.Network, error) {
+	return network, nil
+}
+`,
+
+	"testsuite/web_server_serves_page_test_case.go": `package testsuite
+
+import (
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+	"net/http"
+	"time"
+)
+
+/*
+An example test, which checks that the web server serves its home page.
+ */
+type WebServerServesPageTest struct {}
+
+func (test WebServerServesPageTest) Run(network networks.Network, context testsuite.TestContext) {
+	serviceNetwork := network.(*networks.ServiceNetwork)
+	ipAddr, err := serviceNetwork.GetServiceIp(webServerServiceId)
+	if err != nil {
+		context.Fatal(err)
+	}
+	service := WebServerService{IpAddr: ipAddr.String()}
+
+	client := http.Client{Timeout: webServerRequestTimeout}
+	resp, err := client.Get(service.GetUrl())
+	if err != nil {
+		context.Fatal(err)
+	}
+	defer resp.Body.Close()
+	context.AssertTrue(resp.StatusCode == http.StatusOK, fmt.Errorf("expected status 200 but got %v", resp.StatusCode))
+}
+
+func (test WebServerServesPageTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	return WebServerNetworkLoader{}, nil
+}
+
+func (test WebServerServesPageTest) GetExecutionTimeout() time.Duration {
+	return 30 * time.Second
+}
+
+func (test WebServerServesPageTest) GetSetupBuffer() time.Duration {
+	return 60 * time.Second
+}
+`,
+}
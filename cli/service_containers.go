@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"sort"
+	"strings"
+)
+
+// The container of a service in one of a run's tests
+type serviceContainer struct {
+	testName string
+
+	serviceId string
+
+	containerId string
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets the test volumes of the given run (which are labelled with the run's execution ID), each of which identifies one of
+	the run's tests.
+ */
+func getRunTestVolumes(ctx context.Context, dockerManager *docker.DockerManager, runId string) ([]string, error) {
+	testVolumeNames, err := dockerManager.ListVolumes(ctx, map[string]string{docker.EXECUTION_ID_LABEL: runId})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred listing the test volumes of run %v", runId)
+	}
+	if len(testVolumeNames) == 0 {
+		return nil, stacktrace.NewError("No tests were found for run %v", runId)
+	}
+	return testVolumeNames, nil
+}
+
+// Gets the name of the test that the given test volume of the given run belongs to
+func getTestName(runId string, testVolumeName string) string {
+	// Test volumes are named <execution ID>-<test name>
+	return strings.TrimPrefix(testVolumeName, runId + "-")
+}
+
+/*
+Gets the container of each service of the given run, sorted by test & then service. Of a restarted service's containers,
+	the running one is returned.
+
+Args:
+	testName: The test to get the services of, or empty to get the services of all the run's tests
+ */
+func getRunServiceContainers(
+			ctx context.Context,
+			dockerManager *docker.DockerManager,
+			runId string,
+			testName string) ([]serviceContainer, error) {
+	testVolumeNames, err := getRunTestVolumes(ctx, dockerManager, runId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the tests of run %v", runId)
+	}
+
+	result := []serviceContainer{}
+	for _, testVolumeName := range testVolumeNames {
+		candidateTestName := getTestName(runId, testVolumeName)
+		if testName != "" && candidateTestName != testName {
+			continue
+		}
+		containerIds, err := dockerManager.ListContainers(ctx, map[string]string{docker.TEST_VOLUME_LABEL: testVolumeName})
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred listing the service containers of test %v", candidateTestName)
+		}
+
+		// Mapping of service ID -> the service's container
+		testServiceContainerIds := map[string]string{}
+		for _, containerId := range containerIds {
+			status, err := dockerManager.GetContainerStatus(ctx, containerId)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "An error occurred getting the status of container %v", containerId)
+			}
+			serviceId := status.Labels[docker.SERVICE_ID_LABEL]
+			if _, found := testServiceContainerIds[serviceId]; !found || status.State == runningContainerState {
+				testServiceContainerIds[serviceId] = containerId
+			}
+		}
+		for serviceId, containerId := range testServiceContainerIds {
+			result = append(result, serviceContainer{
+				testName:    candidateTestName,
+				serviceId:   serviceId,
+				containerId: containerId,
+			})
+		}
+	}
+	if testName != "" && len(result) == 0 {
+		return nil, stacktrace.NewError("No services were found for test %v of run %v", testName, runId)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].testName != result[j].testName {
+			return result[i].testName < result[j].testName
+		}
+		return result[i].serviceId < result[j].serviceId
+	})
+	return result, nil
+}
+
+/*
+Finds the container of the given service of the given run.
+
+Args:
+	testName: The test whose network the service is in, which may be empty if only one of the run's tests has the service
+ */
+func findServiceContainer(
+			ctx context.Context,
+			dockerManager *docker.DockerManager,
+			runId string,
+			testName string,
+			serviceId string) (string, error) {
+	serviceContainers, err := getRunServiceContainers(ctx, dockerManager, runId, testName)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the service containers of run %v", runId)
+	}
+	matches, err := filterServiceContainers(serviceContainers, map[string]bool{serviceId: true})
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Service %v wasn't found in run %v", serviceId, runId)
+	}
+	if len(matches) > 1 {
+		matchingTestNames := []string{}
+		for _, match := range matches {
+			matchingTestNames = append(matchingTestNames, match.testName)
+		}
+		return "", stacktrace.NewError(
+			"Service %v is in several tests of run %v, so one must be chosen with the -test flag: %v",
+			serviceId,
+			runId,
+			strings.Join(matchingTestNames, ", "))
+	}
+	return matches[0].containerId, nil
+}
+
+// Gets the given service containers that are of the services with the given IDs, erroring if any of the services is missing
+func filterServiceContainers(serviceContainers []serviceContainer, serviceIds map[string]bool) ([]serviceContainer, error) {
+	foundServiceIds := map[string]bool{}
+	result := []serviceContainer{}
+	for _, candidate := range serviceContainers {
+		if serviceIds[candidate.serviceId] {
+			result = append(result, candidate)
+			foundServiceIds[candidate.serviceId] = true
+		}
+	}
+	missingServiceIds := []string{}
+	for serviceId, _ := range serviceIds {
+		if !foundServiceIds[serviceId] {
+			missingServiceIds = append(missingServiceIds, serviceId)
+		}
+	}
+	if len(missingServiceIds) > 0 {
+		sort.Strings(missingServiceIds)
+		return nil, stacktrace.NewError("No containers were found for services: %v", strings.Join(missingServiceIds, ", "))
+	}
+	return result, nil
+}
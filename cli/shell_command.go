@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	shellCommandName = "shell"
+
+	defaultShellCmd = "/bin/sh"
+)
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func configureShellFlags(flags *flag.FlagSet) func(stdout io.Writer) (bool, error) {
+	runId := flags.String("run-id", "", "Execution ID of the run whose service to shell into, as logged when the run starts")
+	testName := flags.String("test", "", "Test whose network the service is in (may be omitted if only one of the run's tests has the service)")
+	serviceId := flags.String("service", "", "ID of the service to shell into")
+	cmd := flags.String("cmd", defaultShellCmd, "Command (with space-separated args) to run in the service's container")
+	return func(stdout io.Writer) (bool, error) {
+		if *runId == "" || *serviceId == "" {
+			return false, stacktrace.NewError("The service to shell into must be given with the -run-id & -service flags")
+		}
+		cmdArgs := strings.Fields(*cmd)
+		if len(cmdArgs) == 0 {
+			return false, stacktrace.NewError("The command to run can't be empty")
+		}
+		dockerManager, err := newDockerManager()
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred connecting to the Docker engine")
+		}
+		ctx := context.Background()
+		containerId, err := findServiceContainer(ctx, dockerManager, *runId, *testName, *serviceId)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred finding the container of service %v", *serviceId)
+		}
+
+		terminal, restoreTerminal, err := getRawTerminal(os.Stdin)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred setting up the terminal")
+		}
+		exitCode, err := dockerManager.ExecInteractive(ctx, containerId, cmdArgs, terminal, os.Stdin, stdout, os.Stderr)
+		restoreTerminal()
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred running %v in the container of service %v", cmdArgs, *serviceId)
+		}
+		if exitCode != 0 {
+			fmt.Fprintf(os.Stderr, "%v exited with code %v\n", strings.Join(cmdArgs, " "), exitCode)
+			return false, nil
+		}
+		return true, nil
+	}
+}
@@ -0,0 +1,10 @@
+package cli
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+const (
+	getTermiosRequest = unix.TCGETS
+	setTermiosRequest = unix.TCSETS
+)
@@ -0,0 +1,13 @@
+// +build !linux,!darwin
+
+package cli
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"os"
+)
+
+// On other platforms exec sessions run without a terminal, so input is line-buffered & echoed locally
+func getRawTerminal(file *os.File) (terminal *docker.ExecTerminal, restore func(), err error) {
+	return nil, func() {}, nil
+}
@@ -0,0 +1,53 @@
+// +build linux darwin
+
+package cli
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+/*
+Gets the terminal that the given file (e.g. STDIN) is, and puts it into raw mode so that keystrokes (including Ctrl-C)
+	go straight to an exec session rather than being handled by this process's terminal.
+
+Returns:
+	terminal: The terminal's size, or nil if the file isn't a terminal
+	restore: Restores the terminal to how it was, which must be called once the session ends
+ */
+func getRawTerminal(file *os.File) (terminal *docker.ExecTerminal, restore func(), err error) {
+	fd := int(file.Fd())
+	originalTermios, err := unix.IoctlGetTermios(fd, getTermiosRequest)
+	if err != nil {
+		// Not a terminal (e.g. input piped from another command)
+		return nil, func() {}, nil
+	}
+	windowSize, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return nil, nil, stacktrace.Propagate(err, "An error occurred getting the size of the terminal")
+	}
+
+	// The same settings as cfmakeraw(3)
+	rawTermios := *originalTermios
+	rawTermios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	rawTermios.Oflag &^= unix.OPOST
+	rawTermios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	rawTermios.Cflag &^= unix.CSIZE | unix.PARENB
+	rawTermios.Cflag |= unix.CS8
+	rawTermios.Cc[unix.VMIN] = 1
+	rawTermios.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, setTermiosRequest, &rawTermios); err != nil {
+		return nil, nil, stacktrace.Propagate(err, "An error occurred putting the terminal into raw mode")
+	}
+
+	restore = func() {
+		unix.IoctlSetTermios(fd, setTermiosRequest, originalTermios)
+	}
+	terminal = &docker.ExecTerminal{
+		Height: uint(windowSize.Row),
+		Width:  uint(windowSize.Col),
+	}
+	return terminal, restore, nil
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// The environment variable that overrides where the user configuration file is read from
+	USER_CONFIG_FILEPATH_ENV_VAR = "KURTOSIS_CONFIG"
+
+	// Environment variables named with this prefix followed by a flag's name (upper-cased, with dashes as underscores, e.g.
+	//  KURTOSIS_ARTIFACTS_DIR) override that flag's default from the user configuration file
+	USER_DEFAULT_ENV_VAR_PREFIX = "KURTOSIS_"
+
+	defaultUserConfigDirname = ".kurtosis"
+	userConfigFilename = "config"
+)
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets where the user configuration file is read from: USER_CONFIG_FILEPATH_ENV_VAR if set, else ~/.kurtosis/config.
+
+Returns:
+	The filepath, or empty if there's no home directory to find the file in
+ */
+func getUserConfigFilepath() string {
+	if configFilepath := os.Getenv(USER_CONFIG_FILEPATH_ENV_VAR); configFilepath != "" {
+		return configFilepath
+	}
+	homeDirpath, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDirpath, defaultUserConfigDirname, userConfigFilename)
+}
+
+/*
+Reads the user configuration file, a JSON object whose keys are flag names & whose values are those flags' defaults for
+	every command that has the flag, e.g.:
+
+	{
+		"parallelism": 4,
+		"artifacts-dir": "/tmp/kurtosis-artifacts",
+		"registry-mirror": "mirror.internal:5000",
+		"swarm-hosts": ["tcp://10.0.0.5:2376", "tcp://10.0.0.6:2376"]
+	}
+
+Values may be strings, numbers, booleans, or lists of strings (for comma-separated flags).
+
+Args:
+	configFilepath: The file to read, which may not exist
+	knownFlagNames: "Set" of the flags of every command, so that misspelled keys are caught rather than silently ignored
+
+Returns:
+	Mapping of flag name -> the flag's default, as it would be given on the command line (empty if there's no file)
+ */
+func loadUserConfig(configFilepath string, knownFlagNames map[string]bool) (map[string]string, error) {
+	result := map[string]string{}
+	if configFilepath == "" {
+		return result, nil
+	}
+	configBytes, err := ioutil.ReadFile(configFilepath)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading user configuration file %v", configFilepath)
+	}
+
+	rawValues := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(configBytes))
+	// Numbers are kept as they were written, rather than being turned into floats
+	decoder.UseNumber()
+	if err := decoder.Decode(&rawValues); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing user configuration file %v as a JSON object", configFilepath)
+	}
+
+	unknownFlagNames := []string{}
+	for flagName, rawValue := range rawValues {
+		if !knownFlagNames[flagName] {
+			unknownFlagNames = append(unknownFlagNames, flagName)
+			continue
+		}
+		value, err := getUserConfigValueString(rawValue)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "Invalid value for '%v' in user configuration file %v", flagName, configFilepath)
+		}
+		result[flagName] = value
+	}
+	if len(unknownFlagNames) > 0 {
+		sort.Strings(unknownFlagNames)
+		return nil, stacktrace.NewError(
+			"User configuration file %v has keys that aren't the flags of any command: %v",
+			configFilepath,
+			strings.Join(unknownFlagNames, ", "))
+	}
+	return result, nil
+}
+
+/*
+Sets the defaults of the given command's flags from the user configuration & the environment, in increasing precedence:
+	the flag's built-in default, the user configuration file, then the flag's USER_DEFAULT_ENV_VAR_PREFIX environment
+	variable. Flags given on the command line take precedence over all of these, so this must be called before parsing.
+
+Args:
+	flags: The command's flags
+	configDefaults: Mapping of flag name -> default, from loadUserConfig
+	lookupEnv: Looks up an environment variable (e.g. os.LookupEnv)
+ */
+func applyUserDefaults(flags *flag.FlagSet, configDefaults map[string]string, lookupEnv func(string) (string, bool)) error {
+	var resultErr error = nil
+	flags.VisitAll(func(currentFlag *flag.Flag) {
+		if resultErr != nil {
+			return
+		}
+		envVar := getUserDefaultEnvVar(currentFlag.Name)
+		value, source := "", ""
+		if envValue, found := lookupEnv(envVar); found {
+			value, source = envValue, "environment variable " + envVar
+		} else if configValue, found := configDefaults[currentFlag.Name]; found {
+			value, source = configValue, "the user configuration file"
+		} else {
+			return
+		}
+		if err := flags.Set(currentFlag.Name, value); err != nil {
+			resultErr = stacktrace.Propagate(err, "Invalid default '%v' for flag '%v' from %v", value, currentFlag.Name, source)
+			return
+		}
+		// The command's usage should show the default that will actually be used
+		currentFlag.DefValue = currentFlag.Value.String()
+	})
+	return resultErr
+}
+
+// Gets the environment variable that overrides the given flag's default, e.g. "artifacts-dir" -> "KURTOSIS_ARTIFACTS_DIR"
+func getUserDefaultEnvVar(flagName string) string {
+	return USER_DEFAULT_ENV_VAR_PREFIX + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// Gets the "set" of the flags of all the given commands
+func getAllFlagNames(commands map[string]command) map[string]bool {
+	result := map[string]bool{}
+	for commandName, cmd := range commands {
+		flags := flag.NewFlagSet(commandName, flag.ContinueOnError)
+		cmd.configureFlags(flags)
+		flags.VisitAll(func(currentFlag *flag.Flag) {
+			result[currentFlag.Name] = true
+		})
+	}
+	return result
+}
+
+// Converts a JSON value from the user configuration file to a flag value, as it would be given on the command line
+func getUserConfigValueString(rawValue interface{}) (string, error) {
+	switch typedValue := rawValue.(type) {
+	case string:
+		return typedValue, nil
+	case json.Number:
+		return typedValue.String(), nil
+	case bool:
+		return fmt.Sprint(typedValue), nil
+	case []interface{}:
+		elements := make([]string, 0, len(typedValue))
+		for _, rawElement := range typedValue {
+			element, ok := rawElement.(string)
+			if !ok {
+				return "", stacktrace.NewError("Lists may only contain strings, but got %v", rawElement)
+			}
+			elements = append(elements, element)
+		}
+		return strings.Join(elements, ","), nil
+	default:
+		return "", stacktrace.NewError("Expected a string, number, boolean, or list of strings, but got %v", rawValue)
+	}
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"flag"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLoadingUserConfig(t *testing.T) {
+	configDirpath, err := ioutil.TempDir("", "test-user-config")
+	assert.NilError(t, err)
+	defer os.RemoveAll(configDirpath)
+	configFilepath := path.Join(configDirpath, "config")
+	knownFlagNames := map[string]bool{"parallelism": true, "dashboard": true, "swarm-hosts": true, "artifacts-dir": true}
+
+	// A missing file means no defaults
+	configDefaults, err := loadUserConfig(configFilepath, knownFlagNames)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(configDefaults))
+
+	contents := `{"parallelism": 4, "dashboard": true, "swarm-hosts": ["tcp://a:2376", "tcp://b:2376"], "artifacts-dir": "/tmp/a"}`
+	assert.NilError(t, ioutil.WriteFile(configFilepath, []byte(contents), 0644))
+	configDefaults, err = loadUserConfig(configFilepath, knownFlagNames)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"parallelism":   "4",
+		"dashboard":     "true",
+		"swarm-hosts":   "tcp://a:2376,tcp://b:2376",
+		"artifacts-dir": "/tmp/a",
+	}, configDefaults)
+
+	assert.NilError(t, ioutil.WriteFile(configFilepath, []byte(`{"paralelism": 4, "artifact-dir": "/tmp/a"}`), 0644))
+	_, err = loadUserConfig(configFilepath, knownFlagNames)
+	assert.ErrorContains(t, err, "artifact-dir, paralelism")
+
+	assert.NilError(t, ioutil.WriteFile(configFilepath, []byte(`{"swarm-hosts": [1]}`), 0644))
+	_, err = loadUserConfig(configFilepath, knownFlagNames)
+	assert.ErrorContains(t, err, "Invalid value for 'swarm-hosts'")
+}
+
+func TestApplyingUserDefaults(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	parallelism := flags.Uint("parallelism", 1, "")
+	artifactsDirpath := flags.String("artifacts-dir", "", "")
+	registryMirror := flags.String("registry-mirror", "", "")
+	env := map[string]string{"KURTOSIS_ARTIFACTS_DIR": "/env/artifacts"}
+	lookupEnv := func(envVar string) (string, bool) {
+		value, found := env[envVar]
+		return value, found
+	}
+	configDefaults := map[string]string{"parallelism": "4", "artifacts-dir": "/config/artifacts", "registry-mirror": "mirror:5000"}
+
+	assert.NilError(t, applyUserDefaults(flags, configDefaults, lookupEnv))
+	assert.NilError(t, flags.Parse([]string{"-registry-mirror", "other-mirror:5000"}))
+	// The command line beats the environment, which beats the configuration file
+	assert.Equal(t, uint(4), *parallelism)
+	assert.Equal(t, "/env/artifacts", *artifactsDirpath)
+	assert.Equal(t, "other-mirror:5000", *registryMirror)
+	assert.Equal(t, "4", flags.Lookup("parallelism").DefValue)
+
+	env["KURTOSIS_PARALLELISM"] = "many"
+	badFlags := flag.NewFlagSet("test", flag.ContinueOnError)
+	badFlags.Uint("parallelism", 1, "")
+	err := applyUserDefaults(badFlags, configDefaults, lookupEnv)
+	assert.ErrorContains(t, err, "environment variable KURTOSIS_PARALLELISM")
+}
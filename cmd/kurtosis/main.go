@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/kurtosis-tech/kurtosis/cli"
+)
+
+/*
+The stock Kurtosis CLI, whose "list" & "clean" commands work against any Docker engine. To use its "run" command, build a
+	binary like this one that also imports the packages registering your test suites (see cli.RegisterTestSuite).
+ */
+func main() {
+	cli.Main()
+}
@@ -0,0 +1,122 @@
+package assertions
+
+import (
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/palantir/stacktrace"
+	"reflect"
+	"time"
+)
+
+const (
+	TIME_BETWEEN_RPC_ATTEMPTS = 1 * time.Second
+)
+
+/*
+A condition on the response to an RPC made against a service, along with a human-readable description used in failure
+	messages.
+ */
+type ResponseMatcher struct {
+	// Description of the condition, e.g. "== 5"
+	Description string
+
+	// Returns true if the given response satisfies the condition
+	Matches func(response interface{}) bool
+}
+
+// Matches responses deeply equal to the expected response
+func Equals(expected interface{}) ResponseMatcher {
+	return ResponseMatcher{
+		Description: fmt.Sprintf("== %v", expected),
+		Matches:     func(response interface{}) bool { return reflect.DeepEqual(expected, response) },
+	}
+}
+
+// Matches responses that satisfy the given user-defined condition, described by the given description
+func Satisfies(description string, condition func(response interface{}) bool) ResponseMatcher {
+	return ResponseMatcher{
+		Description: description,
+		Matches:     condition,
+	}
+}
+
+/*
+Checks that every service in the network that hasn't been removed is available & not partitioned (see
+	ServiceNetwork.GetHealth).
+
+Returns:
+	An error if the network isn't healthy, containing the network's availability diagnostics
+ */
+func AssertAllHealthy(network *networks.ServiceNetwork) error {
+	if network.GetHealth().Healthy {
+		return nil
+	}
+	diagnostics := network.GetAvailabilityDiagnostics(networks.DEFAULT_DIAGNOSTICS_LOG_LINES)
+	return stacktrace.NewError("Expected every service in the network to be healthy, but it wasn't:\n%v", diagnostics)
+}
+
+/*
+Checks that the network has exactly the given number of services (not counting removed ones).
+ */
+func AssertServiceCount(network *networks.ServiceNetwork, expected int) error {
+	serviceIds := network.GetServiceIds()
+	if len(serviceIds) != expected {
+		return stacktrace.NewError("Expected the network to have %v services, but it had %v: %v", expected, len(serviceIds), serviceIds)
+	}
+	return nil
+}
+
+/*
+Checks that the two given services can reach each other over the test network (i.e. no partition separates them).
+ */
+func AssertReachable(network *networks.ServiceNetwork, serviceIdA networks.ServiceID, serviceIdB networks.ServiceID) error {
+	reachable, err := network.IsReachable(serviceIdA, serviceIdB)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred checking reachability between services %v and %v", serviceIdA, serviceIdB)
+	}
+	if !reachable {
+		return stacktrace.NewError("Expected services %v and %v to be reachable from each other, but they weren't", serviceIdA, serviceIdB)
+	}
+	return nil
+}
+
+/*
+Repeatedly makes the given RPC against the given service until its response satisfies the matcher, or the timeout is hit
+	(e.g. "node1's getLastAcceptedBlock eventually returns block 5").
+
+Args:
+	network: The network the service is in
+	serviceId: The ID of the service to make the RPC against
+	rpc: The user-defined RPC, which gets the service to cast to its interface & returns the service's response
+	matcher: The condition the response should eventually satisfy
+	timeout: How long to keep retrying the RPC before giving up
+
+Returns:
+	An error if the response didn't satisfy the matcher within the timeout, describing the last response (or RPC error)
+ */
+func EventuallyRPC(
+			network *networks.ServiceNetwork,
+			serviceId networks.ServiceID,
+			rpc networks.ServiceProbe,
+			matcher ResponseMatcher,
+			timeout time.Duration) error {
+	nodeInfo, err := network.GetService(serviceId)
+	if err != nil {
+		return stacktrace.Propagate(err, "Cannot make RPC against service ID %v", serviceId)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		response, err := rpc(nodeInfo.Service)
+		if err == nil && matcher.Matches(response) {
+			return nil
+		}
+		if time.Now().Add(TIME_BETWEEN_RPC_ATTEMPTS).After(deadline) {
+			if err != nil {
+				return stacktrace.Propagate(err, "RPC against service %v didn't return a response that's %v within %v", serviceId, matcher.Description, timeout)
+			}
+			return stacktrace.NewError("RPC against service %v didn't return a response that's %v within %v; last response: %v", serviceId, matcher.Description, timeout, response)
+		}
+		time.Sleep(TIME_BETWEEN_RPC_ATTEMPTS)
+	}
+}
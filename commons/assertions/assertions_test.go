@@ -0,0 +1,80 @@
+package assertions
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/kurtosis-tech/kurtosis/commons/testcontainers"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNetworkAssertions(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	log := logrus.NewEntry(logrus.StandardLogger())
+	freeIpTracker, err := networks.NewFreeIpAddrTracker(log.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := networks.NewServiceNetworkBuilder(log, dockerManager, "test-network", freeIpTracker, "test", testVolumeControllerDirpath, "")
+	config, err := testcontainers.NewServiceConfig(testcontainers.ContainerRequest{Image: "nginx:latest"})
+	assert.NilError(t, err)
+	assert.NilError(t, builder.AddServiceConfig("nginx", config))
+	network := builder.Build()
+
+	for _, serviceId := range []networks.ServiceID{"node1", "node2"} {
+		checker, err := network.AddService("nginx", serviceId, map[networks.ServiceID]bool{})
+		assert.NilError(t, err)
+		// The network isn't healthy until its services have been checked to be available
+		assert.ErrorContains(t, AssertAllHealthy(network), "NOT AVAILABLE")
+		assert.NilError(t, checker.WaitForStartup())
+	}
+	assert.NilError(t, AssertAllHealthy(network))
+	assert.NilError(t, AssertServiceCount(network, 2))
+	assert.ErrorContains(t, AssertServiceCount(network, 3), "Expected the network to have 3 services, but it had 2")
+	assert.NilError(t, AssertReachable(network, "node1", "node2"))
+	assert.ErrorContains(t, AssertReachable(network, "node1", "nonexistent"), "checking reachability")
+}
+
+func TestEventuallyRPC(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	log := logrus.NewEntry(logrus.StandardLogger())
+	freeIpTracker, err := networks.NewFreeIpAddrTracker(log.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := networks.NewServiceNetworkBuilder(log, dockerManager, "test-network", freeIpTracker, "test", testVolumeControllerDirpath, "")
+	config, err := testcontainers.NewServiceConfig(testcontainers.ContainerRequest{Image: "nginx:latest"})
+	assert.NilError(t, err)
+	assert.NilError(t, builder.AddServiceConfig("nginx", config))
+	network := builder.Build()
+	_, err = network.AddService("nginx", "node1", map[networks.ServiceID]bool{})
+	assert.NilError(t, err)
+
+	numCalls := 0
+	countingRpc := func(service services.Service) (interface{}, error) {
+		numCalls++
+		if numCalls == 1 {
+			return nil, stacktrace.NewError("Test RPC failure")
+		}
+		return numCalls, nil
+	}
+	assert.NilError(t, EventuallyRPC(network, "node1", countingRpc, Equals(2), 5 * TIME_BETWEEN_RPC_ATTEMPTS))
+
+	err = EventuallyRPC(network, "node1", countingRpc, Satisfies("negative", func(response interface{}) bool {
+		return response.(int) < 0
+	}), 0)
+	assert.ErrorContains(t, err, "didn't return a response that's negative within 0s; last response: 3")
+
+	numCalls = 0
+	assert.ErrorContains(t, EventuallyRPC(network, "node1", countingRpc, Equals(2), 0), "Test RPC failure")
+	assert.ErrorContains(t, EventuallyRPC(network, "nonexistent", countingRpc, Equals(2), 0), "Cannot make RPC")
+}
@@ -0,0 +1,389 @@
+package compose
+
+import (
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"github.com/palantir/stacktrace"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// The access modes that a short-syntax volume may end with
+	readWriteVolumeMode = "rw"
+	readOnlyVolumeMode  = "ro"
+)
+
+/*
+The parts of a Docker Compose file that can be turned into a Kurtosis network (see ComposeNetworkLoader). Callers may
+	change the parsed services before building the loader (e.g. to swap in a locally-built image).
+ */
+type ComposeFile struct {
+	// Mapping of compose_service_name -> service
+	Services map[string]ComposeService
+}
+
+/*
+A service from a Docker Compose file.
+ */
+type ComposeService struct {
+	Image string
+
+	// The command to run the service with, or empty to run the image's default command
+	Command []string
+
+	// The entrypoint to run the service with, or empty to use the image's default entrypoint
+	Entrypoint []string
+
+	// Mapping of env_variable_name -> value
+	Environment map[string]string
+
+	// The ports from the service's "ports" & "expose" keys
+	UsedPorts map[nat.Port]bool
+
+	// True if the service had a "ports" key, in which case its ports are published to the Docker host (on ports that
+	//  Docker picks, rather than the ones in the compose file, so that concurrent tests don't clash)
+	PublishPorts bool
+
+	// The ports from the service's "ports" key, which are the ones published to the Docker host ("expose" ports stay
+	//  internal to the Docker network)
+	PublishedPorts map[nat.Port]bool
+
+	// A "set" of the names of the services that this service depends on
+	DependsOn map[string]bool
+
+	Volumes []ComposeVolume
+
+	// True if the service had "init: true", in which case it runs with an init process that reaps zombie processes
+	Init bool
+
+	// How many copies of the service to run, from its "deploy.replicas" (or legacy "scale") key; 1 if it has neither
+	Replicas int
+}
+
+/*
+A volume that a compose service mounts.
+ */
+type ComposeVolume struct {
+	// The name of the volume, which services share by using the same name, or empty for an anonymous volume that only the
+	//  service mounting it uses
+	Name string
+
+	ContainerDirpath string
+
+	// True if the volume had the "ro" access mode
+	ReadOnly bool
+}
+
+/*
+Parses the Docker Compose file at the given filepath.
+
+Args:
+	composeFilepath: The filepath of the compose file (e.g. docker-compose.yml)
+ */
+func ParseComposeFile(composeFilepath string) (*ComposeFile, error) {
+	contents, err := ioutil.ReadFile(composeFilepath)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading compose file %v", composeFilepath)
+	}
+	composeFile, err := ParseCompose(contents)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing compose file %v", composeFilepath)
+	}
+	return composeFile, nil
+}
+
+/*
+Parses the given Docker Compose file contents. Only the service keys in ComposeService are used; keys that don't affect
+	how a service runs in a test (e.g. "restart" or "container_name") are ignored, and a service that would have to be
+	built (i.e. has no "image") or that bind-mounts a host directory is an error.
+
+Args:
+	contents: The contents of a compose file
+
+Returns:
+	The parsed compose file
+ */
+func ParseCompose(contents []byte) (*ComposeFile, error) {
+	rawFile := rawComposeFile{}
+	if err := yaml.Unmarshal(contents, &rawFile); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred unmarshalling the compose file YAML")
+	}
+	if len(rawFile.Services) == 0 {
+		return nil, stacktrace.NewError("The compose file has no services")
+	}
+
+	result := &ComposeFile{
+		Services: make(map[string]ComposeService),
+	}
+	for serviceName, rawService := range rawFile.Services {
+		service, err := parseService(rawService)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred parsing service '%v'", serviceName)
+		}
+		result.Services[serviceName] = service
+	}
+	for serviceName, service := range result.Services {
+		for dependencyName, _ := range service.DependsOn {
+			if _, found := result.Services[dependencyName]; !found {
+				return nil, stacktrace.NewError("Service '%v' depends on service '%v', which isn't defined", serviceName, dependencyName)
+			}
+		}
+	}
+	return result, nil
+}
+
+/*
+Gets the names of the file's services in an order that they can be started in, with every service after the services it
+	depends on (and ties broken alphabetically, so the order is stable).
+ */
+func (composeFile ComposeFile) GetStartOrder() ([]string, error) {
+	serviceNames := make([]string, 0, len(composeFile.Services))
+	for serviceName, _ := range composeFile.Services {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+
+	result := make([]string, 0, len(serviceNames))
+	started := make(map[string]bool)
+	for len(result) < len(serviceNames) {
+		startedThisRound := false
+		for _, serviceName := range serviceNames {
+			if started[serviceName] {
+				continue
+			}
+			ready := true
+			for dependencyName, _ := range composeFile.Services[serviceName].DependsOn {
+				if !started[dependencyName] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				result = append(result, serviceName)
+				started[serviceName] = true
+				startedThisRound = true
+			}
+		}
+		if !startedThisRound {
+			unstartedNames := []string{}
+			for _, serviceName := range serviceNames {
+				if !started[serviceName] {
+					unstartedNames = append(unstartedNames, serviceName)
+				}
+			}
+			return nil, stacktrace.NewError("Services %v have a dependency cycle", strings.Join(unstartedNames, ", "))
+		}
+	}
+	return result, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+type rawComposeFile struct {
+	Services map[string]rawComposeService `yaml:"services"`
+}
+
+type rawComposeService struct {
+	Image       string           `yaml:"image"`
+	Build       interface{}      `yaml:"build"`
+	Command     interface{}      `yaml:"command"`
+	Entrypoint  interface{}      `yaml:"entrypoint"`
+	Environment interface{}      `yaml:"environment"`
+	Ports       []interface{}    `yaml:"ports"`
+	Expose      []interface{}    `yaml:"expose"`
+	DependsOn   interface{}      `yaml:"depends_on"`
+	Volumes     []interface{}    `yaml:"volumes"`
+	Init        bool             `yaml:"init"`
+	Scale       *int             `yaml:"scale"`
+	Deploy      rawComposeDeploy `yaml:"deploy"`
+}
+
+type rawComposeDeploy struct {
+	Replicas *int `yaml:"replicas"`
+}
+
+func parseService(rawService rawComposeService) (ComposeService, error) {
+	if rawService.Image == "" {
+		if rawService.Build != nil {
+			return ComposeService{}, stacktrace.NewError("Building images isn't supported; build the image beforehand & set the service's 'image' instead")
+		}
+		return ComposeService{}, stacktrace.NewError("Service has no image")
+	}
+	command, err := parseStringOrList(rawService.Command)
+	if err != nil {
+		return ComposeService{}, stacktrace.Propagate(err, "An error occurred parsing the command")
+	}
+	entrypoint, err := parseStringOrList(rawService.Entrypoint)
+	if err != nil {
+		return ComposeService{}, stacktrace.Propagate(err, "An error occurred parsing the entrypoint")
+	}
+	environment, err := parseEnvironment(rawService.Environment)
+	if err != nil {
+		return ComposeService{}, stacktrace.Propagate(err, "An error occurred parsing the environment")
+	}
+	usedPorts, err := parsePorts(append(append([]interface{}{}, rawService.Ports...), rawService.Expose...))
+	if err != nil {
+		return ComposeService{}, stacktrace.Propagate(err, "An error occurred parsing the ports")
+	}
+	publishedPorts, err := parsePorts(rawService.Ports)
+	if err != nil {
+		return ComposeService{}, stacktrace.Propagate(err, "An error occurred parsing the published ports")
+	}
+	dependsOn, err := parseDependsOn(rawService.DependsOn)
+	if err != nil {
+		return ComposeService{}, stacktrace.Propagate(err, "An error occurred parsing the dependencies")
+	}
+	volumes, err := parseVolumes(rawService.Volumes)
+	if err != nil {
+		return ComposeService{}, stacktrace.Propagate(err, "An error occurred parsing the volumes")
+	}
+	replicas := 1
+	if rawService.Deploy.Replicas != nil {
+		replicas = *rawService.Deploy.Replicas
+	} else if rawService.Scale != nil {
+		replicas = *rawService.Scale
+	}
+	if replicas < 1 {
+		return ComposeService{}, stacktrace.NewError("Service has %v replicas, but must have at least one", replicas)
+	}
+	return ComposeService{
+		Image:          rawService.Image,
+		Command:        command,
+		Entrypoint:     entrypoint,
+		Environment:    environment,
+		UsedPorts:      usedPorts,
+		PublishPorts:   len(rawService.Ports) > 0,
+		PublishedPorts: publishedPorts,
+		DependsOn:      dependsOn,
+		Volumes:        volumes,
+		Init:           rawService.Init,
+		Replicas:       replicas,
+	}, nil
+}
+
+// Parses a value that compose allows to be either a whitespace-separated string or a list of strings
+func parseStringOrList(rawValue interface{}) ([]string, error) {
+	switch value := rawValue.(type) {
+	case nil:
+		return []string{}, nil
+	case string:
+		return strings.Fields(value), nil
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, element := range value {
+			result = append(result, fmt.Sprint(element))
+		}
+		return result, nil
+	default:
+		return nil, stacktrace.NewError("Expected a string or a list, but got '%v'", rawValue)
+	}
+}
+
+// Parses an environment, which compose allows to be either a map or a list of "NAME=value" strings
+func parseEnvironment(rawEnvironment interface{}) (map[string]string, error) {
+	result := make(map[string]string)
+	switch environment := rawEnvironment.(type) {
+	case nil:
+	case map[interface{}]interface{}:
+		for name, value := range environment {
+			// Compose treats a variable with no value as one to take from the host, which tests shouldn't depend on
+			if value == nil {
+				return nil, stacktrace.NewError("Env variable '%v' has no value", name)
+			}
+			result[fmt.Sprint(name)] = fmt.Sprint(value)
+		}
+	case []interface{}:
+		for _, element := range environment {
+			assignment := fmt.Sprint(element)
+			equalsIdx := strings.Index(assignment, "=")
+			if equalsIdx == -1 {
+				return nil, stacktrace.NewError("Env variable '%v' has no value", assignment)
+			}
+			result[assignment[:equalsIdx]] = assignment[equalsIdx + 1:]
+		}
+	default:
+		return nil, stacktrace.NewError("Expected a map or a list, but got '%v'", rawEnvironment)
+	}
+	return result, nil
+}
+
+// Parses port specs in compose's short syntax (e.g. "80", "8080:80", "127.0.0.1:8080:80/udp", or "9000-9002")
+func parsePorts(rawPorts []interface{}) (map[nat.Port]bool, error) {
+	portSpecs := make([]string, 0, len(rawPorts))
+	for _, rawPort := range rawPorts {
+		if _, isMap := rawPort.(map[interface{}]interface{}); isMap {
+			return nil, stacktrace.NewError("Only the short port syntax is supported, but got '%v'", rawPort)
+		}
+		portSpecs = append(portSpecs, fmt.Sprint(rawPort))
+	}
+	exposedPorts, _, err := nat.ParsePortSpecs(portSpecs)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing port specs %v", portSpecs)
+	}
+	result := make(map[nat.Port]bool)
+	for port, _ := range exposedPorts {
+		result[port] = true
+	}
+	return result, nil
+}
+
+// Parses dependencies, which compose allows to be either a list of service names or a map keyed by service name
+func parseDependsOn(rawDependsOn interface{}) (map[string]bool, error) {
+	result := make(map[string]bool)
+	switch dependsOn := rawDependsOn.(type) {
+	case nil:
+	case []interface{}:
+		for _, dependencyName := range dependsOn {
+			result[fmt.Sprint(dependencyName)] = true
+		}
+	case map[interface{}]interface{}:
+		for dependencyName, _ := range dependsOn {
+			result[fmt.Sprint(dependencyName)] = true
+		}
+	default:
+		return nil, stacktrace.NewError("Expected a list or a map, but got '%v'", rawDependsOn)
+	}
+	return result, nil
+}
+
+// Parses volumes in compose's short syntax (e.g. "data:/var/lib/data" or "/var/lib/data")
+func parseVolumes(rawVolumes []interface{}) ([]ComposeVolume, error) {
+	result := make([]ComposeVolume, 0, len(rawVolumes))
+	for _, rawVolume := range rawVolumes {
+		volumeSpec, ok := rawVolume.(string)
+		if !ok {
+			return nil, stacktrace.NewError("Only the short volume syntax is supported, but got '%v'", rawVolume)
+		}
+		parts := strings.Split(volumeSpec, ":")
+		mode := readWriteVolumeMode
+		if len(parts) > 1 && isVolumeMode(parts[len(parts) - 1]) {
+			mode = parts[len(parts) - 1]
+			parts = parts[:len(parts) - 1]
+		}
+		var volume ComposeVolume
+		switch len(parts) {
+		case 1:
+			volume = ComposeVolume{ContainerDirpath: parts[0]}
+		case 2:
+			if strings.HasPrefix(parts[0], "/") || strings.HasPrefix(parts[0], ".") || strings.HasPrefix(parts[0], "~") {
+				return nil, stacktrace.NewError("Volume '%v' bind-mounts a host path, which isn't supported; use a named volume instead", volumeSpec)
+			}
+			volume = ComposeVolume{Name: parts[0], ContainerDirpath: parts[1]}
+		default:
+			return nil, stacktrace.NewError("Volume '%v' isn't a valid short-syntax volume", volumeSpec)
+		}
+		volume.ReadOnly = mode == readOnlyVolumeMode
+		if !path.IsAbs(volume.ContainerDirpath) {
+			return nil, stacktrace.NewError("Volume '%v' isn't mounted at an absolute path", volumeSpec)
+		}
+		result = append(result, volume)
+	}
+	return result, nil
+}
+
+func isVolumeMode(str string) bool {
+	return str == readWriteVolumeMode || str == readOnlyVolumeMode
+}
@@ -0,0 +1,254 @@
+package compose
+
+import (
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Where compose services get the test volume mounted, which compose files don't know about
+	testVolumeMountpoint = "/kurtosis-test-volume"
+
+	// How long a compose service's ports have to start accepting connections before it's considered failed
+	serviceAvailabilityTimeout = 2 * time.Minute
+
+	// How long each attempt to connect to a compose service's port waits
+	portDialTimeout = 1 * time.Second
+
+	tcpProtocol = "tcp"
+)
+
+/*
+The service that the network returns for compose services (see ServiceNetwork.GetService).
+ */
+type ComposeNode struct {
+	// The name of the service in the compose file, which other services can also reach it by
+	ServiceName string
+
+	IpAddr string
+}
+
+/*
+A network loader that starts the services of a Docker Compose file, so that tests written against an existing compose
+	setup can be run by Kurtosis. Each compose service becomes a service configuration & a service with its compose name
+	as both IDs, and is reachable on the test network by that name just like under compose. Services with several
+	replicas become one service per replica, with IDs like "name-1", "name-2", etc., which all share the compose name as
+	a hostname. Services are started after the services they depend on (& all their replicas), and are considered
+	available once all their TCP ports accept connections.
+
+To migrate incrementally, embed this loader in a loader of your own that adds Kurtosis-native services (or swaps compose
+	services out, by changing the ComposeFile before building this loader) and wraps the network in a custom struct.
+ */
+type ComposeNetworkLoader struct {
+	composeFile *ComposeFile
+}
+
+/*
+Creates a loader for the services of the given compose file (see ParseComposeFile).
+ */
+func NewComposeNetworkLoader(composeFile *ComposeFile) *ComposeNetworkLoader {
+	return &ComposeNetworkLoader{composeFile: composeFile}
+}
+
+func (loader ComposeNetworkLoader) ConfigureNetwork(builder *networks.ServiceNetworkBuilder) error {
+	// Checked here, so a cycle is reported before any services are configured
+	if _, err := loader.composeFile.GetStartOrder(); err != nil {
+		return stacktrace.Propagate(err, "The compose services can't be started")
+	}
+
+	serviceNames := make([]string, 0, len(loader.composeFile.Services))
+	for serviceName, _ := range loader.composeFile.Services {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+
+	// Named volumes are shared between the services that mount them, so each is only created once
+	dockerVolumeNames := make(map[string]string)
+	for _, serviceName := range serviceNames {
+		service := loader.composeFile.Services[serviceName]
+		volumeMounts := make([]docker.VolumeMount, 0, len(service.Volumes))
+		for i, volume := range service.Volumes {
+			volumeName := volume.Name
+			if volumeName == "" {
+				volumeName = fmt.Sprintf("%v-anonymous-%v", serviceName, i)
+			}
+			dockerVolumeName, found := dockerVolumeNames[volumeName]
+			if !found {
+				createdVolumeName, err := builder.CreateVolume(volumeName)
+				if err != nil {
+					return stacktrace.Propagate(err, "An error occurred creating volume '%v' for service '%v'", volumeName, serviceName)
+				}
+				dockerVolumeName = createdVolumeName
+				dockerVolumeNames[volumeName] = dockerVolumeName
+			}
+			volumeMounts = append(volumeMounts, docker.VolumeMount{
+				VolumeName:       dockerVolumeName,
+				ContainerDirpath: volume.ContainerDirpath,
+				ReadOnly:         volume.ReadOnly,
+			})
+		}
+		// Replicas' service IDs aren't the compose name, so they need it as an alias to be reachable by it
+		networkAliases := []string{}
+		if service.Replicas > 1 {
+			networkAliases = append(networkAliases, serviceName)
+		}
+
+		containerOptions := docker.ContainerOptions{
+			Entrypoint:        service.Entrypoint,
+			ExtraVolumeMounts: volumeMounts,
+			NetworkAliases:    networkAliases,
+			PublishPorts:      service.PublishPorts,
+			PublishedPorts:    service.PublishedPorts,
+			UseInit:           service.Init,
+		}
+		initializerCore := composeInitializerCore{
+			serviceName: serviceName,
+			service:     service,
+		}
+		availabilityCheckerCore := composeAvailabilityCheckerCore{
+			usedPorts: service.UsedPorts,
+		}
+		err := builder.AddConfigurationWithOptions(
+			networks.ConfigurationID(serviceName),
+			service.Image,
+			initializerCore,
+			availabilityCheckerCore,
+			containerOptions)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred adding the configuration for service '%v'", serviceName)
+		}
+	}
+	return nil
+}
+
+func (loader ComposeNetworkLoader) InitializeNetwork(network *networks.ServiceNetwork) (map[networks.ServiceID]services.ServiceAvailabilityChecker, error) {
+	startOrder, err := loader.composeFile.GetStartOrder()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "The compose services can't be started")
+	}
+	availabilityCheckers := make(map[networks.ServiceID]services.ServiceAvailabilityChecker)
+	for _, serviceName := range startOrder {
+		dependencies := make(map[networks.ServiceID]bool)
+		for dependencyName, _ := range loader.composeFile.Services[serviceName].DependsOn {
+			for _, dependencyId := range loader.getServiceIds(dependencyName) {
+				dependencies[dependencyId] = true
+			}
+		}
+		for _, serviceId := range loader.getServiceIds(serviceName) {
+			checker, err := network.AddService(networks.ConfigurationID(serviceName), serviceId, dependencies)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "An error occurred adding service '%v'", serviceId)
+			}
+			availabilityCheckers[serviceId] = *checker
+		}
+	}
+	return availabilityCheckers, nil
+}
+
+func (loader ComposeNetworkLoader) WrapNetwork(network *networks.ServiceNetwork) (networks.Network, error) {
+	return network, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Gets the IDs of the services that the replicas of the given compose service run as
+func (loader ComposeNetworkLoader) getServiceIds(serviceName string) []networks.ServiceID {
+	replicas := loader.composeFile.Services[serviceName].Replicas
+	if replicas <= 1 {
+		return []networks.ServiceID{networks.ServiceID(serviceName)}
+	}
+	result := make([]networks.ServiceID, 0, replicas)
+	for i := 1; i <= replicas; i++ {
+		result = append(result, networks.ServiceID(fmt.Sprintf("%v-%v", serviceName, i)))
+	}
+	return result
+}
+
+type composeInitializerCore struct {
+	serviceName string
+
+	service ComposeService
+}
+
+func (core composeInitializerCore) GetUsedPorts() map[nat.Port]bool {
+	return core.service.UsedPorts
+}
+
+func (core composeInitializerCore) GetServiceFromIp(ipAddr string) services.Service {
+	return ComposeNode{
+		ServiceName: core.serviceName,
+		IpAddr:      ipAddr,
+	}
+}
+
+func (core composeInitializerCore) GetFilesToMount() map[string]bool {
+	return map[string]bool{}
+}
+
+func (core composeInitializerCore) InitializeMountedFiles(mountedFiles map[string]*os.File, dependencies []services.Service) error {
+	return nil
+}
+
+func (core composeInitializerCore) GetTestVolumeMountpoint() string {
+	return testVolumeMountpoint
+}
+
+func (core composeInitializerCore) GetStartCommand(startCommandContext services.StartCommandContext) ([]string, error) {
+	result := make([]string, 0, len(core.service.Command))
+	for _, fragment := range core.service.Command {
+		// Start command fragments are rendered as templates, but compose commands aren't templates so must come out as-is
+		if strings.Contains(fragment, "{{") {
+			fragment = "{{ " + strconv.Quote(fragment) + " }}"
+		}
+		result = append(result, fragment)
+	}
+	return result, nil
+}
+
+func (core composeInitializerCore) GetEnvVariables() map[string]string {
+	result := make(map[string]string)
+	for name, value := range core.service.Environment {
+		// Env variable values are rendered as templates too, but compose env variables aren't templates so must come out as-is
+		if strings.Contains(value, "{{") {
+			value = "{{ " + strconv.Quote(value) + " }}"
+		}
+		result[name] = value
+	}
+	return result
+}
+
+type composeAvailabilityCheckerCore struct {
+	usedPorts map[nat.Port]bool
+}
+
+func (core composeAvailabilityCheckerCore) IsServiceUp(toCheck services.Service, dependencies []services.Service) bool {
+	return core.ProbeService(toCheck, dependencies) == nil
+}
+
+func (core composeAvailabilityCheckerCore) ProbeService(toCheck services.Service, dependencies []services.Service) error {
+	node := toCheck.(ComposeNode)
+	for port, _ := range core.usedPorts {
+		if port.Proto() != tcpProtocol {
+			continue
+		}
+		conn, err := net.DialTimeout(tcpProtocol, net.JoinHostPort(node.IpAddr, port.Port()), portDialTimeout)
+		if err != nil {
+			return stacktrace.Propagate(err, "Port %v isn't accepting connections", port)
+		}
+		conn.Close()
+	}
+	return nil
+}
+
+func (core composeAvailabilityCheckerCore) GetTimeout() time.Duration {
+	return serviceAvailabilityTimeout
+}
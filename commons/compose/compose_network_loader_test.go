@@ -0,0 +1,199 @@
+package compose
+
+import (
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+const (
+	testSubnetMask = "172.23.0.0/24"
+	testNetworkId  = "test-network"
+	testVolume     = "test-volume"
+
+	testComposeFileContents = `
+version: "3.8"
+services:
+  db:
+    image: postgres:13
+    environment:
+      POSTGRES_PASSWORD: secret
+    expose:
+      - 5432
+    volumes:
+      - pgdata:/var/lib/postgresql/data
+  api:
+    image: my-api:1.0
+    command: ["--db", "db:5432", "--greeting={{ .Name }}"]
+    environment:
+      - LOG_LEVEL=debug
+    ports:
+      - "8080:80"
+    expose:
+      - 9090
+    depends_on:
+      db:
+        condition: service_started
+    restart: always
+  worker:
+    image: my-worker:1.0
+    entrypoint: /bin/worker --verbose
+    init: true
+    depends_on:
+      - api
+      - db
+    volumes:
+      - pgdata:/data:ro
+      - /scratch
+`
+)
+
+var testLog = logrus.NewEntry(logrus.StandardLogger())
+
+func TestParsingCompose(t *testing.T) {
+	composeFile, err := ParseCompose([]byte(testComposeFileContents))
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(composeFile.Services))
+
+	api := composeFile.Services["api"]
+	assert.DeepEqual(t, []string{"--db", "db:5432", "--greeting={{ .Name }}"}, api.Command)
+	assert.DeepEqual(t, map[string]string{"LOG_LEVEL": "debug"}, api.Environment)
+	assert.DeepEqual(t, map[nat.Port]bool{"80/tcp": true, "9090/tcp": true}, api.UsedPorts)
+	assert.Assert(t, api.PublishPorts)
+	assert.DeepEqual(t, map[nat.Port]bool{"80/tcp": true}, api.PublishedPorts)
+	assert.DeepEqual(t, map[string]bool{"db": true}, api.DependsOn)
+
+	db := composeFile.Services["db"]
+	assert.Assert(t, !db.PublishPorts)
+	assert.DeepEqual(t, map[nat.Port]bool{"5432/tcp": true}, db.UsedPorts)
+
+	worker := composeFile.Services["worker"]
+	assert.DeepEqual(t, []string{"/bin/worker", "--verbose"}, worker.Entrypoint)
+	assert.Assert(t, worker.Init)
+	assert.Assert(t, !api.Init)
+	assert.DeepEqual(t, []ComposeVolume{{Name: "pgdata", ContainerDirpath: "/data", ReadOnly: true}, {ContainerDirpath: "/scratch"}}, worker.Volumes)
+	assert.Equal(t, 1, worker.Replicas)
+
+	startOrder, err := composeFile.GetStartOrder()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"db", "api", "worker"}, startOrder)
+}
+
+func TestParsingInvalidCompose(t *testing.T) {
+	_, err := ParseCompose([]byte("services:\n  app:\n    build: .\n"))
+	assert.ErrorContains(t, err, "Building images isn't supported")
+
+	_, err = ParseCompose([]byte("services:\n  app:\n    image: app\n    volumes:\n      - ./data:/data\n"))
+	assert.ErrorContains(t, err, "bind-mounts a host path")
+
+	_, err = ParseCompose([]byte("services:\n  app:\n    image: app\n    depends_on: [db]\n"))
+	assert.ErrorContains(t, err, "which isn't defined")
+
+	_, err = ParseCompose([]byte("services:\n  app:\n    image: app\n    environment: [TOKEN]\n"))
+	assert.ErrorContains(t, err, "has no value")
+
+	_, err = ParseCompose([]byte("services:\n  app:\n    image: app\n    deploy:\n      replicas: 0\n"))
+	assert.ErrorContains(t, err, "must have at least one")
+
+	composeFile, err := ParseCompose([]byte("services:\n  a:\n    image: a\n    depends_on: [b]\n  b:\n    image: b\n    depends_on: [a]\n"))
+	assert.NilError(t, err)
+	_, err = composeFile.GetStartOrder()
+	assert.ErrorContains(t, err, "dependency cycle")
+}
+
+func TestPlanningComposeNetwork(t *testing.T) {
+	composeFile, err := ParseCompose([]byte(testComposeFileContents))
+	assert.NilError(t, err)
+
+	plan, err := networks.Plan(testLog, NewComposeNetworkLoader(composeFile), testSubnetMask)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"my-api:1.0", "my-worker:1.0", "postgres:13"}, plan.Images)
+	assert.Equal(t, 3, len(plan.Services))
+	assert.Equal(t, networks.ServiceID("db"), plan.Services[0].ServiceId)
+	assert.Equal(t, networks.ServiceID("api"), plan.Services[1].ServiceId)
+	// The command is passed through as-is, even though start commands are otherwise rendered as templates
+	assert.DeepEqual(t, []string{"--db", "db:5432", "--greeting={{ .Name }}"}, plan.Services[1].StartCmd)
+	assert.Assert(t, plan.Services[1].PublishPorts)
+	// Only the "ports" port is published; the "expose" one stays internal to the Docker network
+	assert.DeepEqual(t, []string{"80/tcp"}, plan.Services[1].PublishedPorts)
+	assert.Equal(t, networks.ServiceID("worker"), plan.Services[2].ServiceId)
+	assert.DeepEqual(t, []networks.ServiceID{"api", "db"}, plan.Services[2].DependencyIds)
+}
+
+func TestComposeServiceContainers(t *testing.T) {
+	composeFile, err := ParseCompose([]byte(testComposeFileContents))
+	assert.NilError(t, err)
+
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "compose-test")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+	freeIpTracker, err := networks.NewFreeIpAddrTracker(testLog.Logger, testSubnetMask, map[string]bool{})
+	assert.NilError(t, err)
+	dockerManager := docker.NewFakeDockerManager()
+	builder := networks.NewServiceNetworkBuilder(testLog, dockerManager, testNetworkId, freeIpTracker, testVolume, testVolumeControllerDirpath, "")
+
+	loader := NewComposeNetworkLoader(composeFile)
+	assert.NilError(t, loader.ConfigureNetwork(builder))
+	network := builder.Build()
+	availabilityCheckers, err := loader.InitializeNetwork(network)
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(availabilityCheckers))
+
+	dbNode, err := network.GetService("db")
+	assert.NilError(t, err)
+	assert.Equal(t, "db", dbNode.Service.(ComposeNode).ServiceName)
+	dbContainer, found := dockerManager.GetContainer(dbNode.ContainerId)
+	assert.Assert(t, found)
+	assert.Equal(t, "secret", dbContainer.EnvVariables["POSTGRES_PASSWORD"])
+	assert.DeepEqual(t, []string{"db"}, dbContainer.Options.NetworkAliases)
+
+	workerNode, err := network.GetService("worker")
+	assert.NilError(t, err)
+	workerContainer, found := dockerManager.GetContainer(workerNode.ContainerId)
+	assert.Assert(t, found)
+	// The named volume is shared with the db service, while the anonymous one is the worker's own
+	assert.Equal(t, dbContainer.Options.ExtraVolumeMounts[0].VolumeName, workerContainer.Options.ExtraVolumeMounts[0].VolumeName)
+	assert.Equal(t, testVolume + "-worker-anonymous-1", workerContainer.Options.ExtraVolumeMounts[1].VolumeName)
+	assert.Assert(t, workerContainer.Options.ExtraVolumeMounts[0].ReadOnly)
+	assert.DeepEqual(t, []string{"/bin/worker", "--verbose"}, workerContainer.Options.Entrypoint)
+}
+
+func TestReplicatedComposeServices(t *testing.T) {
+	composeFile, err := ParseCompose([]byte(`
+services:
+  seed:
+    image: node:1.0
+    scale: 1
+  node:
+    image: node:1.0
+    deploy:
+      replicas: 3
+    depends_on: [seed]
+  explorer:
+    image: explorer:1.0
+    depends_on: [node]
+`))
+	assert.NilError(t, err)
+	assert.Equal(t, 3, composeFile.Services["node"].Replicas)
+
+	plan, err := networks.Plan(testLog, NewComposeNetworkLoader(composeFile), testSubnetMask)
+	assert.NilError(t, err)
+	serviceIds := []networks.ServiceID{}
+	for _, service := range plan.Services {
+		serviceIds = append(serviceIds, service.ServiceId)
+	}
+	assert.DeepEqual(t, []networks.ServiceID{"seed", "node-1", "node-2", "node-3", "explorer"}, serviceIds)
+	assert.DeepEqual(t, []networks.ServiceID{"node-1", "node-2", "node-3"}, plan.Services[4].DependencyIds)
+
+	// Every replica can be reached by the compose name
+	builder := networks.NewServiceNetworkBuilder(testLog, nil, testNetworkId, nil, testVolume, "/foo/bar", "")
+	assert.NilError(t, NewComposeNetworkLoader(composeFile).ConfigureNetwork(builder))
+	nodeConfig, err := builder.GetConfiguration("node")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"node"}, nodeConfig.GetContainerOptions().NetworkAliases)
+}
@@ -0,0 +1,305 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/palantir/stacktrace"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const extractedDirPerms = 0755
+
+// The bytes that every gzip-compressed file starts with
+var gzipMagicBytes = []byte{0x1f, 0x8b}
+
+/*
+Data that will be loaded into a container's filesystem after the container is created but before it's started, so the data
+	is already in place when the container's process launches (e.g. a prepared database or chain state).
+ */
+type ContainerArchive struct {
+	// Path, on the machine running this code, of the data to load, which can be either:
+	//  - a tar archive (optionally gzip-compressed) whose entries are relative paths, e.g. as written by ArchiveContainerDirectory
+	//  - a directory, whose contents will be archived on the fly
+	ArchiveFilepath string
+
+	// Absolute path of the directory in the container that the archive's contents will be extracted into, which will be
+	//  created if it doesn't exist
+	ContainerDirpath string
+}
+
+/*
+Writes the contents of a directory in a container to the given output as a tar archive, with entries relative to the
+	directory (so the archive can be extracted anywhere). This works on data in volumes mounted in the container too.
+
+Args:
+	context: The context that the copy runs in (useful for cancellation)
+	containerId: ID of the Docker container to copy the directory from
+	containerDirpath: Absolute path of the directory in the container to archive
+	output: Where the tar archive will be written
+ */
+func (manager DockerManager) ArchiveContainerDirectory(context context.Context, containerId string, containerDirpath string, output io.Writer) error {
+	// Docker's archive will have the directory's own name as the first component of every entry, which we strip off
+	dockerArchive, _, err := manager.dockerClient.CopyFromContainer(context, containerId, containerDirpath)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to copy directory %v from container with ID %v", containerDirpath, containerId)
+	}
+	defer dockerArchive.Close()
+
+	stripFirstComponent := func(name string) (string, bool) {
+		components := strings.SplitN(strings.TrimPrefix(name, "/"), "/", 2)
+		if len(components) < 2 || components[1] == "" {
+			// This is the entry for the directory itself
+			return "", false
+		}
+		return components[1], true
+	}
+	if err := rebaseTarEntries(dockerArchive, output, stripFirstComponent); err != nil {
+		return stacktrace.Propagate(err, "An error occurred rewriting the archive of directory %v from container with ID %v", containerDirpath, containerId)
+	}
+	return nil
+}
+
+/*
+Extracts a tar archive into a directory in a container, creating the directory if it doesn't exist. If the container
+	isn't running yet, the data will be in place by the time it starts.
+
+Args:
+	context: The context that the copy runs in (useful for cancellation)
+	containerId: ID of the Docker container to extract the archive into
+	containerDirpath: Absolute path of the directory in the container that the archive will be extracted into
+	archive: The tar archive, whose entries should be relative paths
+ */
+func (manager DockerManager) ExtractArchiveToContainer(context context.Context, containerId string, containerDirpath string, archive io.Reader) error {
+	// Docker requires that the destination directory already exists, so we extract at the root and prefix each entry
+	//  with the destination directory (which Docker will create the parents of)
+	destinationPrefix := strings.Trim(containerDirpath, "/")
+	prefixWithDestination := func(name string) (string, bool) {
+		return path.Join(destinationPrefix, name), true
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(rebaseTarEntries(archive, pipeWriter, prefixWithDestination))
+	}()
+	defer pipeReader.Close()
+
+	if err := manager.dockerClient.CopyToContainer(context, containerId, "/", pipeReader, types.CopyToContainerOptions{}); err != nil {
+		return stacktrace.Propagate(err, "Failed to extract archive into directory %v of container with ID %v", containerDirpath, containerId)
+	}
+	return nil
+}
+
+/*
+Extracts a tar archive (e.g. as written by ArchiveContainerDirectory) into a directory on the machine running this code,
+	creating the directory if it doesn't exist. Only the archive's directories & regular files are extracted.
+
+Args:
+	archive: The tar archive, whose entries should be relative paths
+	dirpath: The directory that the archive will be extracted into
+ */
+func ExtractArchiveToDirectory(archive io.Reader, dirpath string) error {
+	if err := os.MkdirAll(dirpath, extractedDirPerms); err != nil {
+		return stacktrace.Propagate(err, "Failed to create directory %v", dirpath)
+	}
+	tarReader := tar.NewReader(archive)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred reading the next archive entry")
+		}
+
+		relativePath := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(relativePath) || relativePath == ".." || strings.HasPrefix(relativePath, ".." + string(filepath.Separator)) {
+			return stacktrace.NewError("Archive entry %v would be extracted outside of directory %v", header.Name, dirpath)
+		}
+		entryPath := filepath.Join(dirpath, relativePath)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(entryPath, extractedDirPerms); err != nil {
+				return stacktrace.Propagate(err, "Failed to create directory %v", entryPath)
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := extractArchiveFile(tarReader, entryPath, os.FileMode(header.Mode).Perm()); err != nil {
+				return stacktrace.Propagate(err, "Failed to extract archive entry %v", header.Name)
+			}
+		}
+	}
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (manager DockerManager) extractArchivesToContainer(context context.Context, containerId string, archives []ContainerArchive) error {
+	for _, archive := range archives {
+		archiveReader, err := openArchive(archive.ArchiveFilepath)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to open archive %v", archive.ArchiveFilepath)
+		}
+		err = manager.ExtractArchiveToContainer(context, containerId, archive.ContainerDirpath, archiveReader)
+		archiveReader.Close()
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to extract archive %v into the container", archive.ArchiveFilepath)
+		}
+	}
+	return nil
+}
+
+/*
+Opens the data at the given path as an uncompressed tar stream, archiving it first if it's a directory and decompressing
+	it if it's gzipped.
+ */
+func openArchive(archiveFilepath string) (io.ReadCloser, error) {
+	fileInfo, err := os.Stat(archiveFilepath)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to stat %v", archiveFilepath)
+	}
+	if fileInfo.IsDir() {
+		pipeReader, pipeWriter := io.Pipe()
+		go func() {
+			pipeWriter.CloseWithError(archiveDirectory(archiveFilepath, pipeWriter))
+		}()
+		return pipeReader, nil
+	}
+
+	archiveFp, err := os.Open(archiveFilepath)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to open %v", archiveFilepath)
+	}
+	bufferedReader := bufio.NewReader(archiveFp)
+	magicBytes, err := bufferedReader.Peek(len(gzipMagicBytes))
+	if err != nil || !bytes.Equal(magicBytes, gzipMagicBytes) {
+		// Files too short to have the magic bytes can't be gzipped, so we let the tar reader deal with them
+		return &wrappedReadCloser{Reader: bufferedReader, closer: archiveFp}, nil
+	}
+	gzipReader, err := gzip.NewReader(bufferedReader)
+	if err != nil {
+		archiveFp.Close()
+		return nil, stacktrace.Propagate(err, "Failed to decompress %v", archiveFilepath)
+	}
+	return &wrappedReadCloser{Reader: gzipReader, closer: archiveFp}, nil
+}
+
+/*
+Writes the contents of the given directory to the output as a tar archive, with entries relative to the directory.
+ */
+func archiveDirectory(dirpath string, output io.Writer) error {
+	tarWriter := tar.NewWriter(output)
+	err := filepath.Walk(dirpath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(dirpath, filePath)
+		if err != nil {
+			return err
+		}
+		if relativePath == "." {
+			return nil
+		}
+
+		linkTarget := ""
+		if fileInfo.Mode() & os.ModeSymlink != 0 {
+			if linkTarget, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(fileInfo, linkTarget)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relativePath)
+		if fileInfo.IsDir() {
+			header.Name += "/"
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if !fileInfo.Mode().IsRegular() {
+			return nil
+		}
+		fp, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+		_, err = io.Copy(tarWriter, fp)
+		return err
+	})
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred archiving directory %v", dirpath)
+	}
+	if err := tarWriter.Close(); err != nil {
+		return stacktrace.Propagate(err, "An error occurred finishing the archive of directory %v", dirpath)
+	}
+	return nil
+}
+
+// Writes the contents of the current entry of an archive to the given file, creating its parent directories
+func extractArchiveFile(tarReader *tar.Reader, destFilepath string, perms os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(destFilepath), extractedDirPerms); err != nil {
+		return stacktrace.Propagate(err, "Failed to create the parent directory of %v", destFilepath)
+	}
+	fp, err := os.OpenFile(destFilepath, os.O_WRONLY | os.O_CREATE | os.O_TRUNC, perms)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to create file %v", destFilepath)
+	}
+	defer fp.Close()
+	if _, err := io.Copy(fp, tarReader); err != nil {
+		return stacktrace.Propagate(err, "Failed to write file %v", destFilepath)
+	}
+	return nil
+}
+
+/*
+Reader that closes a different underlying resource than the one being read from (e.g. the file beneath a gzip reader).
+ */
+type wrappedReadCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (wrapped *wrappedReadCloser) Close() error {
+	return wrapped.closer.Close()
+}
+
+/*
+Copies a tar archive from input to output, renaming each entry with the given function (which returns false if the
+	entry should be dropped).
+ */
+func rebaseTarEntries(input io.Reader, output io.Writer, rename func(name string) (string, bool)) error {
+	tarReader := tar.NewReader(input)
+	tarWriter := tar.NewWriter(output)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred reading the next archive entry")
+		}
+
+		newName, keep := rename(header.Name)
+		if !keep {
+			continue
+		}
+		header.Name = newName
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return stacktrace.Propagate(err, "An error occurred writing the header of archive entry %v", newName)
+		}
+		if _, err := io.Copy(tarWriter, tarReader); err != nil {
+			return stacktrace.Propagate(err, "An error occurred writing the contents of archive entry %v", newName)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return stacktrace.Propagate(err, "An error occurred finishing the archive")
+	}
+	return nil
+}
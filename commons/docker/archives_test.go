@@ -0,0 +1,123 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"gotest.tools/v3/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRebaseTarEntries(t *testing.T) {
+	input := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(input)
+	writeTestTarEntry(t, tarWriter, "data/", tar.TypeDir, "")
+	writeTestTarEntry(t, tarWriter, "data/db/state.json", tar.TypeReg, `{"height": 12}`)
+	assert.NilError(t, tarWriter.Close())
+
+	output := &bytes.Buffer{}
+	dropDirs := func(name string) (string, bool) {
+		if name == "data/" {
+			return "", false
+		}
+		return "restored/" + name, true
+	}
+	assert.NilError(t, rebaseTarEntries(input, output, dropDirs))
+
+	tarReader := tar.NewReader(output)
+	header, err := tarReader.Next()
+	assert.NilError(t, err)
+	assert.Equal(t, "restored/data/db/state.json", header.Name)
+	contents, err := ioutil.ReadAll(tarReader)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"height": 12}`, string(contents))
+
+	_, err = tarReader.Next()
+	assert.Assert(t, err != nil)
+}
+
+func writeTestTarEntry(t *testing.T, tarWriter *tar.Writer, name string, typeflag byte, contents string) {
+	header := &tar.Header{
+		Name:     name,
+		Typeflag: typeflag,
+		Mode:     0644,
+		Size:     int64(len(contents)),
+	}
+	assert.NilError(t, tarWriter.WriteHeader(header))
+	_, err := tarWriter.Write([]byte(contents))
+	assert.NilError(t, err)
+}
+
+func TestOpeningDirectoryAsArchive(t *testing.T) {
+	dirpath, err := ioutil.TempDir("", "seed")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dirpath)
+	assert.NilError(t, os.Mkdir(path.Join(dirpath, "db"), 0755))
+	assert.NilError(t, ioutil.WriteFile(path.Join(dirpath, "db", "state.json"), []byte(`{"height": 12}`), 0644))
+
+	archive, err := openArchive(dirpath)
+	assert.NilError(t, err)
+	defer archive.Close()
+
+	assert.DeepEqual(t, map[string]string{"db/": "", "db/state.json": `{"height": 12}`}, readTestTarEntries(t, archive))
+}
+
+func TestOpeningGzippedArchive(t *testing.T) {
+	archiveFp, err := ioutil.TempFile("", "seed")
+	assert.NilError(t, err)
+	defer os.Remove(archiveFp.Name())
+	gzipWriter := gzip.NewWriter(archiveFp)
+	tarWriter := tar.NewWriter(gzipWriter)
+	writeTestTarEntry(t, tarWriter, "state.json", tar.TypeReg, `{"height": 12}`)
+	assert.NilError(t, tarWriter.Close())
+	assert.NilError(t, gzipWriter.Close())
+	assert.NilError(t, archiveFp.Close())
+
+	archive, err := openArchive(archiveFp.Name())
+	assert.NilError(t, err)
+	defer archive.Close()
+
+	assert.DeepEqual(t, map[string]string{"state.json": `{"height": 12}`}, readTestTarEntries(t, archive))
+}
+
+func readTestTarEntries(t *testing.T, archive io.Reader) map[string]string {
+	result := make(map[string]string)
+	tarReader := tar.NewReader(archive)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return result
+		}
+		assert.NilError(t, err)
+		contents, err := ioutil.ReadAll(tarReader)
+		assert.NilError(t, err)
+		result[header.Name] = string(contents)
+	}
+}
+
+func TestExtractingArchiveToDirectory(t *testing.T) {
+	dirpath, err := ioutil.TempDir("", "extracted")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dirpath)
+
+	archive := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(archive)
+	writeTestTarEntry(t, tarWriter, "covmeta.1234", tar.TypeReg, "meta")
+	writeTestTarEntry(t, tarWriter, "nested/covcounters.1234", tar.TypeReg, "counters")
+	assert.NilError(t, tarWriter.Close())
+	assert.NilError(t, ExtractArchiveToDirectory(archive, path.Join(dirpath, "coverage")))
+
+	contents, err := ioutil.ReadFile(path.Join(dirpath, "coverage", "nested", "covcounters.1234"))
+	assert.NilError(t, err)
+	assert.Equal(t, "counters", string(contents))
+
+	escapingArchive := &bytes.Buffer{}
+	tarWriter = tar.NewWriter(escapingArchive)
+	writeTestTarEntry(t, tarWriter, "../escaped", tar.TypeReg, "oops")
+	assert.NilError(t, tarWriter.Close())
+	assert.ErrorContains(t, ExtractArchiveToDirectory(escapingArchive, dirpath), "outside of directory")
+}
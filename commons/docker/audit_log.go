@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/docker/docker/client"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// Request bodies longer than this will be truncated in the audit log
+	MAX_AUDITED_REQUEST_BODY_BYTES = 4096
+
+	// Suffix added to request bodies that were truncated
+	truncatedBodySuffix = "...(truncated)"
+)
+
+/*
+A single call made to the Docker API.
+ */
+type AuditLogEntry struct {
+	// When the call was made
+	Timestamp time.Time `json:"timestamp"`
+
+	// The HTTP method of the call
+	Method string `json:"method"`
+
+	// The Docker API path called, including the query string (which is where many args are passed)
+	Path string `json:"path"`
+
+	// The request body (which is where the remaining args are passed), possibly truncated
+	RequestBody string `json:"requestBody,omitempty"`
+
+	// How long the Docker engine took to respond
+	DurationMillis int64 `json:"durationMillis"`
+
+	// The HTTP status the Docker engine responded with (0 if the call didn't get a response)
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// The error that prevented the call from getting a response, if any
+	Error string `json:"error,omitempty"`
+}
+
+/*
+Records every call made by a Docker client to a writer as JSON lines, which is invaluable for debugging daemon-level
+	flakes and for attaching the framework's exact behaviour to bug reports.
+
+NOTE: This is thread-safe!
+ */
+type AuditLog struct {
+	// Mutex ensuring that entries written from parallel calls don't get jumbled
+	mutex *sync.Mutex
+
+	output io.Writer
+}
+
+/*
+Creates a new audit log that will write one JSON object per Docker API call to the given writer.
+ */
+func NewAuditLog(output io.Writer) *AuditLog {
+	return &AuditLog{
+		mutex:  &sync.Mutex{},
+		output: output,
+	}
+}
+
+/*
+Returns a Docker client option that records every call the client makes to the given audit log.
+
+NOTE: This must be the LAST option passed to client.NewClientWithOpts, because options that configure the client's
+	connection (e.g. client.FromEnv) need to see the client's original HTTP transport.
+ */
+func WithAuditLog(auditLog *AuditLog) client.Opt {
+	return func(dockerClient *client.Client) error {
+		httpClient := dockerClient.HTTPClient()
+		underlying := httpClient.Transport
+		if underlying == nil {
+			underlying = http.DefaultTransport
+		}
+		httpClient.Transport = &auditingTransport{
+			auditLog:   auditLog,
+			underlying: underlying,
+		}
+		return client.WithHTTPClient(httpClient)(dockerClient)
+	}
+}
+
+func (auditLog *AuditLog) record(entry AuditLogEntry) {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		// This should never happen, since the entry is made entirely of marshallable types
+		return
+	}
+
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+	auditLog.output.Write(append(entryBytes, '\n'))
+}
+
+// =========================== AUDITING TRANSPORT =========================================
+/*
+HTTP transport which passes requests through to the Docker engine, recording each one to an audit log.
+ */
+type auditingTransport struct {
+	auditLog *AuditLog
+
+	underlying http.RoundTripper
+}
+
+func (transport *auditingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	requestBody := ""
+	if request.Body != nil {
+		bodyBytes, err := ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// The body can only be read once, so we give the request a fresh copy to send
+		request.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+		// Secrets are redacted before truncating, so that a secret cut in half by the truncation is still redacted
+		requestBody = truncateRequestBody([]byte(RedactSecrets(string(bodyBytes))))
+	}
+
+	startTime := time.Now()
+	response, err := transport.underlying.RoundTrip(request)
+	entry := AuditLogEntry{
+		Timestamp:      startTime,
+		Method:         request.Method,
+		Path:           RedactSecrets(request.URL.RequestURI()),
+		RequestBody:    requestBody,
+		DurationMillis: time.Since(startTime).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = RedactSecrets(err.Error())
+	} else {
+		entry.StatusCode = response.StatusCode
+	}
+	transport.auditLog.record(entry)
+	return response, err
+}
+
+func truncateRequestBody(bodyBytes []byte) string {
+	if len(bodyBytes) <= MAX_AUDITED_REQUEST_BODY_BYTES {
+		return string(bodyBytes)
+	}
+	return string(bodyBytes[:MAX_AUDITED_REQUEST_BODY_BYTES]) + truncatedBodySuffix
+}
@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/docker/docker/client"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogRecordsCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte(`{"message": "no such container"}`))
+	}))
+	defer server.Close()
+
+	auditOutput := &bytes.Buffer{}
+	dockerClient, err := client.NewClientWithOpts(
+		client.WithHost("tcp://" + strings.TrimPrefix(server.URL, "http://")),
+		client.WithVersion("1.40"),
+		WithAuditLog(NewAuditLog(auditOutput)))
+	assert.NilError(t, err)
+
+	_, err = dockerClient.ContainerInspect(context.Background(), "nonexistent")
+	assert.Assert(t, err != nil)
+
+	lines := strings.Split(strings.TrimSpace(auditOutput.String()), "\n")
+	assert.Equal(t, 1, len(lines))
+	var entry AuditLogEntry
+	assert.NilError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, http.MethodGet, entry.Method)
+	assert.Equal(t, "/v1.40/containers/nonexistent/json", entry.Path)
+	assert.Equal(t, http.StatusNotFound, entry.StatusCode)
+}
+
+func TestLongRequestBodiesTruncated(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), MAX_AUDITED_REQUEST_BODY_BYTES + 1)
+	truncated := truncateRequestBody(body)
+	assert.Assert(t, strings.HasSuffix(truncated, truncatedBodySuffix))
+	assert.Equal(t, MAX_AUDITED_REQUEST_BODY_BYTES + len(truncatedBodySuffix), len(truncated))
+}
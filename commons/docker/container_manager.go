@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/go-connections/nat"
+	"io"
+	"net"
+	"time"
+)
+
+// Compile-time checks that the managers implement the interface
+var _ ContainerManager = &DockerManager{}
+var _ ContainerManager = &FakeDockerManager{}
+var _ ContainerManager = &faultInjectingContainerManager{}
+
+/*
+The operations on a Docker engine that test networks & service initializers use, which DockerManager implements. Code
+	that builds test networks can be unit-tested without a Docker daemon by passing a FakeDockerManager instead.
+
+See DockerManager for the documentation of each method.
+ */
+type ContainerManager interface {
+	// ------------------------------------------ Networks ----------------------------------------------------------
+	CreateDynamicSubnetNetwork(context context.Context, name string, labels map[string]string) (string, error)
+	GetNetworkGatewayIp(context context.Context, networkId string) (net.IP, error)
+	GetContainersOnNetwork(context context.Context, networkId string) (map[string]bool, error)
+	ConnectContainerToNetwork(
+			context context.Context,
+			networkId string,
+			containerId string,
+			staticIp net.IP,
+			aliases []string) error
+	DisconnectContainerFromNetwork(context context.Context, networkId string, containerId string) error
+
+	// ------------------------------------------ Images ------------------------------------------------------------
+	PullImageIfMissing(context context.Context, dockerImage string) error
+
+	// ------------------------------------------ Volumes -----------------------------------------------------------
+	CreateVolume(context context.Context, volumeName string, labels map[string]string) error
+	RemoveVolume(context context.Context, volumeName string, removeStoppedContainers bool) error
+
+	// ------------------------------------------ Container lifecycle -----------------------------------------------
+	CreateContainer(
+			context context.Context,
+			dockerImage string,
+			networkId string,
+			staticIp net.IP,
+			usedPorts map[nat.Port]bool,
+			startCmdArgs []string,
+			envVariables map[string]string,
+			bindMounts map[string]string,
+			volumeMounts map[string]string,
+			options ContainerOptions) (containerId string, err error)
+	CreateAndStartContainer(
+			context context.Context,
+			dockerImage string,
+			networkId string,
+			staticIp net.IP,
+			usedPorts map[nat.Port]bool,
+			startCmdArgs []string,
+			envVariables map[string]string,
+			bindMounts map[string]string,
+			volumeMounts map[string]string,
+			options ContainerOptions) (containerId string, err error)
+	StartContainer(context context.Context, containerId string) error
+	StopContainer(context context.Context, containerId string, timeout *time.Duration) error
+	KillContainer(context context.Context, containerId string) error
+	SignalContainer(context context.Context, containerId string, signal string) error
+	PauseContainer(context context.Context, containerId string) error
+	UnpauseContainer(context context.Context, containerId string) error
+	RecreateContainer(context context.Context, containerId string, stopTimeout time.Duration) (newContainerId string, err error)
+	RemoveContainer(context context.Context, containerId string) error
+	LimitContainerCpu(context context.Context, containerId string, cpuPercent uint) error
+	UnlimitContainerCpu(context context.Context, containerId string) error
+	GetContainerCpuLimit(context context.Context, containerId string) (uint, error)
+
+	// ------------------------------------------ Container inspection ----------------------------------------------
+	GetContainerStatus(context context.Context, containerId string) (ContainerStatus, error)
+	GetContainerIpAddr(context context.Context, containerId string, networkId string) (net.IP, error)
+	GetContainerIpv6Addr(context context.Context, containerId string, networkId string) (net.IP, error)
+	GetContainerHostPort(context context.Context, containerId string, port nat.Port) (int, error)
+	GetContainerNetworkInfo(context context.Context, containerId string) (ContainerNetworkInfo, error)
+	GetContainerResourceUsage(context context.Context, containerId string) (ContainerResourceUsage, error)
+	GetContainerInspectJson(context context.Context, containerId string) ([]byte, error)
+	GetContainerLogs(context context.Context, containerId string, follow bool) (io.ReadCloser, error)
+	GetContainerStreamLogs(context context.Context, containerId string, stream LogStream, follow bool) (io.ReadCloser, error)
+	WriteContainerLogs(context context.Context, containerId string, stdout io.Writer, stderr io.Writer) error
+	ArchiveContainerDirectory(context context.Context, containerId string, containerDirpath string, output io.Writer) error
+
+	// ------------------------------------------ Exec --------------------------------------------------------------
+	RunExecCommand(context context.Context, containerId string, cmd []string, timeout time.Duration) (exitCode int, logs string, err error)
+}
@@ -0,0 +1,33 @@
+package docker
+
+import (
+	"github.com/palantir/stacktrace"
+	"regexp"
+)
+
+// The names that the Docker engine accepts for containers
+var validContainerNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Matches the runs of characters that aren't allowed in container names
+var invalidContainerNameCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+/*
+Turns the given string (e.g. one built from a test & service name) into a valid container name, by replacing each run of
+	characters that Docker doesn't allow in container names with a "-".
+ */
+func SanitizeContainerName(name string) string {
+	result := invalidContainerNameCharsRegex.ReplaceAllString(name, "-")
+	// Names must start with an alphanumeric character
+	if result != "" && !validContainerNameRegex.MatchString(result[:1]) {
+		result = "x" + result
+	}
+	return result
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func validateContainerName(name string) error {
+	if !validContainerNameRegex.MatchString(name) {
+		return stacktrace.NewError("Container name '%v' isn't valid; names must match %v (see SanitizeContainerName)", name, validContainerNameRegex)
+	}
+	return nil
+}
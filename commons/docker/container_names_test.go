@@ -0,0 +1,26 @@
+package docker
+
+import (
+	"context"
+	"gotest.tools/v3/assert"
+	"net"
+	"testing"
+)
+
+func TestSanitizeContainerName(t *testing.T) {
+	assert.Equal(t, "1234-myTest--node-1", SanitizeContainerName("1234-myTest--node 1"))
+	assert.Equal(t, "x-node", SanitizeContainerName("/node"))
+	assert.Equal(t, "a-b_c.d", SanitizeContainerName("a:/b_c.d"))
+	assert.NilError(t, validateContainerName(SanitizeContainerName("~weird name!")))
+}
+
+func TestFakeRejectsDuplicateContainerNames(t *testing.T) {
+	fake := NewFakeDockerManager()
+	options := ContainerOptions{Name: "my-container"}
+	_, err := fake.CreateContainer(context.Background(), "alpine:3.12", "network", net.ParseIP("172.23.0.2"), nil, nil, nil, nil, nil, options)
+	assert.NilError(t, err)
+	_, err = fake.CreateContainer(context.Background(), "alpine:3.12", "network", net.ParseIP("172.23.0.3"), nil, nil, nil, nil, nil, options)
+	assert.ErrorContains(t, err, "already in use")
+	_, err = fake.CreateContainer(context.Background(), "alpine:3.12", "network", net.ParseIP("172.23.0.3"), nil, nil, nil, nil, nil, ContainerOptions{Name: "bad name"})
+	assert.ErrorContains(t, err, "isn't valid")
+}
@@ -0,0 +1,317 @@
+package docker
+
+import (
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/ports"
+	"github.com/palantir/stacktrace"
+)
+
+// Log drivers that Docker ships with, for use in ContainerOptions.LogDriver
+const (
+	// Writes logs to JSON files on the host; supports rotation with the "max-size" & "max-file" options
+	JSON_FILE_LOG_DRIVER = "json-file"
+
+	// Ships logs to a syslog server, configured with the "syslog-address" option
+	SYSLOG_LOG_DRIVER = "syslog"
+
+	// Ships logs to a Fluentd collector, configured with the "fluentd-address" option
+	FLUENTD_LOG_DRIVER = "fluentd"
+
+	// Discards all logs
+	NONE_LOG_DRIVER = "none"
+)
+
+/*
+Optional settings for containers started with CreateAndStartContainer. The zero value gives a container Docker's defaults.
+ */
+type ContainerOptions struct {
+	// The name the container is given (which must be unique on the Docker host, and valid; see SanitizeContainerName),
+	//  or empty for a Docker-generated one. Service networks name their services' containers themselves (see
+	//  networks.ServiceNetworkBuilder.UseContainerNamer).
+	Name string
+
+	// The Docker log driver that the container's output will be sent to (e.g. JSON_FILE_LOG_DRIVER), or empty to use
+	//  the Docker daemon's default.
+	// NOTE: Framework features that read container logs (e.g. log assertions & artifact export) only work with log drivers
+	//  that Docker can read back, like "json-file" & "journald" (or with any driver on Docker engines that support dual logging)
+	LogDriver string
+
+	// Options for the log driver, e.g. {"max-size": "10m", "max-file": "3"} for a rotating "json-file" log
+	LogDriverOptions map[string]string
+
+	// Overrides the image's ENTRYPOINT if non-empty (e.g. to run a shell in an image whose entrypoint is a single binary)
+	Entrypoint []string
+
+	// Archives that will be extracted into the container's filesystem before it starts, in order (e.g. to restore a
+	//  service's data from a snapshot)
+	Archives []ContainerArchive
+
+	// Files that will be placed in the container's filesystem before it starts (after the archives are extracted)
+	Fixtures []FileFixture
+
+	// Credentials that will be injected into the container as environment variables and/or files, whose values are
+	//  redacted from the Docker audit log, exported artifacts, & errors about the container
+	Secrets []Secret
+
+	// Volumes to mount in addition to the volume mounts passed to CreateAndStartContainer (which, being keyed by volume
+	//  name, can't mount the same volume at multiple paths)
+	ExtraVolumeMounts []VolumeMount
+
+	// Files or directories on the Docker host to mount in addition to the bind mounts passed to CreateAndStartContainer
+	//  (e.g. genesis files or keystores that can be mounted read-only)
+	ExtraBindMounts []BindMount
+
+	// DNS names that other containers on the network can reach the container by, via Docker's embedded DNS server
+	NetworkAliases []string
+
+	// IDs of Docker networks that the container will be attached to in addition to the network passed to
+	//  CreateAndStartContainer (e.g. to model "public" & "private" networks), with Docker-assigned IPs. The container
+	//  gets the same network aliases on every network.
+	AdditionalNetworkIds []string
+
+	// The MAC address the container will have on the network passed to CreateAndStartContainer (e.g. "02:42:ac:11:00:02"),
+	//  or empty for a Docker-generated one, for services whose licensing or peer identity is tied to their MAC
+	MacAddress string
+
+	// Extra "hostname:IP" entries for the container's /etc/hosts, where the IP may be HOST_GATEWAY_IP to resolve the
+	//  hostname to the host (e.g. for reaching mock servers running on the host)
+	ExtraHosts []string
+
+	// If true, each of the container's ports is published on a random free port of the host, so that the container can be
+	//  reached from the host (e.g. to poke at a running test's services); see DockerManager.GetContainerHostPort
+	PublishPorts bool
+
+	// If non-empty, only these of the container's ports are published on random free ports of the host (whether or not
+	//  PublishPorts is set), so that services whose traffic mostly stays on the Docker network don't use up the host's
+	//  port range. Each must be one of the ports the container uses.
+	PublishedPorts map[nat.Port]bool
+
+	// If true, the container runs in the host's network namespace (network mode HOST_NETWORK_MODE) rather than being
+	//  attached to a Docker network, bypassing Docker NAT. The container's ports are bound directly on the host, and the
+	//  static IP, network aliases, & additional networks passed for it are ignored.
+	UseHostNetwork bool
+
+	// If true, the container is attached to the Docker engine's default bridge network (which has outbound connectivity)
+	//  even if the network passed to CreateAndStartContainer is internal, e.g. for gateways that forward traffic out of an
+	//  internal network. Containers on non-internal networks are always attached to the default bridge network.
+	UseDefaultBridgeNetwork bool
+
+	// If true, creating the container fails if its image isn't pinned to a tag other than "latest" (or to a digest), or if
+	//  it would run as root or privileged, for teams enforcing supply-chain hygiene in their test infrastructure
+	EnforceStrictPolicy bool
+
+	// The seccomp profile that restricts the container's syscalls: the filepath, on the machine running this code, of a
+	//  JSON profile; UNCONFINED_SECURITY_PROFILE to disable seccomp; or empty for the Docker engine's default profile
+	SeccompProfile string
+
+	// The name of the AppArmor profile (which must be loaded on the Docker host) that the container runs under;
+	//  UNCONFINED_SECURITY_PROFILE to disable AppArmor; or empty for the Docker engine's default profile. Ignored by
+	//  Docker hosts without AppArmor.
+	AppArmorProfile string
+
+	// If true, the container's processes can't gain privileges they didn't start with (e.g. through setuid binaries)
+	NoNewPrivileges bool
+
+	// If true, the container's root filesystem is mounted read-only, so only its volume & tmpfs mounts can be written to
+	//  (e.g. to check that a service works under immutable-infrastructure constraints). Archives, fixtures, & secret
+	//  files can't be placed in containers with read-only root filesystems.
+	ReadOnlyRootFilesystem bool
+
+	// Mapping of (container dirpath) -> (tmpfs mount options, e.g. "size=64m,mode=1777"; empty for Docker's defaults) of
+	//  in-memory filesystems to mount in the container, typically to give a read-only container writable scratch space
+	TmpfsMounts map[string]string
+
+	// If true, the container runs a minimal init process (Docker's --init) as PID 1, which forwards signals to the
+	//  container's command & reaps its zombie processes, so that shell-wrapped start commands don't leave behind defunct
+	//  processes that skew resource monitoring in long-running tests
+	UseInit bool
+
+	// The container's CPU weight relative to other containers when the host's CPUs are contended (Docker's --cpu-shares,
+	//  where 1024 is the default weight), or 0 for the default weight
+	CpuShares int64
+
+	// The percentage of a single CPU core that the container can use at most (e.g. 150 = one & a half cores), or 0 for no
+	//  limit, so that one busy service can't starve the rest of a large network; see also DockerManager.LimitContainerCpu
+	CpuPercent uint
+
+	// The most memory, in bytes, that the container can use before it's OOM-killed, or 0 for no limit
+	MemoryLimitBytes int64
+
+	// What Docker does when the container exits (e.g. {Name: "on-failure", MaximumRetryCount: 3}), or empty to never
+	//  restart it. Containers that Docker restarts count as running again, so crashes that Docker recovers from won't
+	//  show up in the network's health.
+	RestartPolicy container.RestartPolicy
+
+	// The platform ("os/arch[/variant]", e.g. LINUX_ARM64_PLATFORM) whose variant of a multi-arch image the container
+	//  runs, or empty for the Docker engine's own platform. If the locally-available image is for a different platform,
+	//  the requested platform's variant is pulled, replacing the local image's tag, so containers started from the same
+	//  image with different platforms shouldn't be created concurrently.
+	Platform string
+
+	// Labels to put on the container (e.g. SERVICE_ID_LABEL), so it can later be found with DockerManager.ListContainers;
+	//  the container is always given the MANAGED_BY_LABEL & the manager's resource labels as well
+	Labels map[string]string
+
+	// If non-nil, gets the final say over the container's configuration just before the container is created (see
+	//  ContainerCustomizer)
+	Customizer ContainerCustomizer
+}
+
+/*
+Gets a deep copy of the options, so that changes to the copy's slices & maps (or to the original's) don't leak across.
+
+NOTE: The Customizer can't be copied, so the copy shares it with the original.
+ */
+func (options ContainerOptions) Copy() ContainerOptions {
+	result := options
+	if options.LogDriverOptions != nil {
+		result.LogDriverOptions = make(map[string]string)
+		for key, value := range options.LogDriverOptions {
+			result.LogDriverOptions[key] = value
+		}
+	}
+	if options.Labels != nil {
+		result.Labels = make(map[string]string)
+		for key, value := range options.Labels {
+			result.Labels[key] = value
+		}
+	}
+	if options.TmpfsMounts != nil {
+		result.TmpfsMounts = make(map[string]string)
+		for containerDirpath, mountOptions := range options.TmpfsMounts {
+			result.TmpfsMounts[containerDirpath] = mountOptions
+		}
+	}
+	result.Entrypoint = copyStrings(options.Entrypoint)
+	if options.Archives != nil {
+		result.Archives = append([]ContainerArchive{}, options.Archives...)
+	}
+	if options.Fixtures != nil {
+		result.Fixtures = make([]FileFixture, 0, len(options.Fixtures))
+		for _, fixture := range options.Fixtures {
+			if fixture.Contents != nil {
+				fixture.Contents = append([]byte{}, fixture.Contents...)
+			}
+			result.Fixtures = append(result.Fixtures, fixture)
+		}
+	}
+	if options.Secrets != nil {
+		result.Secrets = append([]Secret{}, options.Secrets...)
+	}
+	if options.ExtraVolumeMounts != nil {
+		result.ExtraVolumeMounts = append([]VolumeMount{}, options.ExtraVolumeMounts...)
+	}
+	if options.ExtraBindMounts != nil {
+		result.ExtraBindMounts = append([]BindMount{}, options.ExtraBindMounts...)
+	}
+	if options.PublishedPorts != nil {
+		result.PublishedPorts = make(map[nat.Port]bool)
+		for port, _ := range options.PublishedPorts {
+			result.PublishedPorts[port] = true
+		}
+	}
+	result.NetworkAliases = copyStrings(options.NetworkAliases)
+	result.AdditionalNetworkIds = copyStrings(options.AdditionalNetworkIds)
+	result.ExtraHosts = copyStrings(options.ExtraHosts)
+	return result
+}
+
+/*
+Gets the "set" of the container's ports that the options publish on the host (see PublishPorts & PublishedPorts), which
+	is empty for containers on the host's network since their ports are bound on the host directly.
+
+Args:
+	usedPorts: The "set" of ports that the container uses
+ */
+func (options ContainerOptions) GetPublishedPorts(usedPorts map[nat.Port]bool) map[nat.Port]bool {
+	result := make(map[nat.Port]bool)
+	if options.UseHostNetwork {
+		return result
+	}
+	if len(options.PublishedPorts) > 0 {
+		for port, _ := range options.PublishedPorts {
+			result[port] = true
+		}
+	} else if options.PublishPorts {
+		for port, _ := range usedPorts {
+			result[port] = true
+		}
+	}
+	return result
+}
+
+/*
+A hook for tweaking the Docker configuration that the framework generates for a container, just before the container is
+	created, so that Docker features the framework doesn't wrap (e.g. sysctls, ulimits, or devices) are still reachable.
+
+NOTE: Changes that contradict the framework's own settings (e.g. the network mode) may break framework features.
+ */
+type ContainerCustomizer interface {
+	/*
+	Modifies the given container & host configurations in place.
+
+	Args:
+		config: The container's configuration (image, command, environment, etc.)
+		hostConfig: The container's host configuration (mounts, resources, port bindings, etc.)
+	 */
+	CustomizeContainer(config *container.Config, hostConfig *container.HostConfig) error
+}
+
+/*
+Adapts an ordinary function into a ContainerCustomizer.
+ */
+type ContainerCustomizerFunc func(config *container.Config, hostConfig *container.HostConfig) error
+
+func (customizerFunc ContainerCustomizerFunc) CustomizeContainer(config *container.Config, hostConfig *container.HostConfig) error {
+	return customizerFunc(config, hostConfig)
+}
+
+/*
+A Docker volume mounted at a path in a container.
+ */
+type VolumeMount struct {
+	VolumeName string
+
+	ContainerDirpath string
+
+	// If true, the container can only read the volume
+	ReadOnly bool
+}
+
+/*
+A file or directory on the Docker host mounted at a path in a container.
+ */
+type BindMount struct {
+	// Absolute path, on the Docker host, of the file or directory to mount
+	HostPath string
+
+	ContainerPath string
+
+	// If true, the container can only read the mounted file or directory
+	ReadOnly bool
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Copies the given slice, keeping nil slices nil
+func copyStrings(strs []string) []string {
+	if strs == nil {
+		return nil
+	}
+	return append([]string{}, strs...)
+}
+
+// Checks that the ports the options publish are all ports that the container uses
+func validatePublishedPorts(usedPorts map[nat.Port]bool, options ContainerOptions) error {
+	// Normalized, so that e.g. "8080" & "8080/tcp" match but "8080/udp" doesn't
+	normalizedUsedPorts := make(map[string]bool)
+	for port, _ := range usedPorts {
+		normalizedUsedPorts[ports.Normalize(port)] = true
+	}
+	for port, _ := range options.PublishedPorts {
+		if !normalizedUsedPorts[ports.Normalize(port)] {
+			return stacktrace.NewError("Port %v can't be published because it isn't one of the ports the container uses", port)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestCopiedContainerOptionsDontAlias(t *testing.T) {
+	original := ContainerOptions{
+		LogDriverOptions: map[string]string{"max-size": "10m"},
+		Entrypoint:       []string{"/bin/sh"},
+		Fixtures: []FileFixture{
+			{Contents: []byte("foo"), ContainerFilepath: "/foo"},
+		},
+		NetworkAliases: []string{"alias"},
+		TmpfsMounts:    map[string]string{"/tmp": ""},
+		Labels:         map[string]string{SERVICE_ID_LABEL: "service"},
+	}
+	copied := original.Copy()
+
+	copied.LogDriverOptions["max-size"] = "20m"
+	copied.Entrypoint[0] = "/bin/bash"
+	copied.Fixtures[0].Contents[0] = 'b'
+	copied.NetworkAliases = append(copied.NetworkAliases[:0], "other-alias")
+	copied.TmpfsMounts["/run"] = ""
+	copied.Labels[SERVICE_ID_LABEL] = "other-service"
+
+	assert.Equal(t, "10m", original.LogDriverOptions["max-size"])
+	assert.Equal(t, "/bin/sh", original.Entrypoint[0])
+	assert.Equal(t, "foo", string(original.Fixtures[0].Contents))
+	assert.Equal(t, "alias", original.NetworkAliases[0])
+	assert.Equal(t, 1, len(original.TmpfsMounts))
+	assert.Equal(t, "service", original.Labels[SERVICE_ID_LABEL])
+
+	// Nil fields stay nil, so copies compare the same as the original
+	assert.Assert(t, ContainerOptions{}.Copy().ExtraHosts == nil)
+}
+
+func TestReadOnlyRootFilesystemInHostConfig(t *testing.T) {
+	manager := &DockerManager{log: logrus.NewEntry(logrus.StandardLogger())}
+	hostConfig, err := manager.getContainerHostConfig(nil, nil, nil, ContainerOptions{
+		ReadOnlyRootFilesystem: true,
+		TmpfsMounts:            map[string]string{"/tmp": "size=64m,mode=1777", "/run": ""},
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, hostConfig.ReadonlyRootfs)
+	assert.DeepEqual(t, map[string]string{"/tmp": "size=64m,mode=1777", "/run": ""}, hostConfig.Tmpfs)
+	// The Docker engine's default applies unless the init process is requested
+	assert.Assert(t, hostConfig.Init == nil)
+}
+
+func TestMountsInHostConfig(t *testing.T) {
+	manager := &DockerManager{log: logrus.NewEntry(logrus.StandardLogger())}
+	hostConfig, err := manager.getContainerHostConfig(nil, nil, nil, ContainerOptions{
+		ExtraVolumeMounts: []VolumeMount{
+			{VolumeName: "test-data", ContainerDirpath: "/data"},
+			{VolumeName: "test-data", ContainerDirpath: "/backup", ReadOnly: true},
+		},
+		ExtraBindMounts: []BindMount{
+			{HostPath: "/tmp/genesis.json", ContainerPath: "/config/genesis.json", ReadOnly: true},
+			{HostPath: "/tmp/keystore", ContainerPath: "/keystore"},
+		},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{
+		"test-data:/data",
+		"test-data:/backup:ro",
+		"/tmp/genesis.json:/config/genesis.json:ro",
+		"/tmp/keystore:/keystore",
+	}, hostConfig.Binds)
+}
+
+func TestInitProcessInHostConfig(t *testing.T) {
+	manager := &DockerManager{log: logrus.NewEntry(logrus.StandardLogger())}
+	hostConfig, err := manager.getContainerHostConfig(nil, nil, nil, ContainerOptions{UseInit: true})
+	assert.NilError(t, err)
+	assert.Assert(t, hostConfig.Init != nil && *hostConfig.Init)
+}
+
+func TestResourceLimitsInHostConfig(t *testing.T) {
+	manager := &DockerManager{log: logrus.NewEntry(logrus.StandardLogger())}
+	hostConfig, err := manager.getContainerHostConfig(nil, nil, nil, ContainerOptions{
+		CpuShares:        512,
+		CpuPercent:       150,
+		MemoryLimitBytes: 256 * 1024 * 1024,
+		RestartPolicy:    container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, int64(512), hostConfig.CPUShares)
+	assert.Equal(t, int64(150000), hostConfig.CPUQuota)
+	assert.Equal(t, int64(CPU_CFS_PERIOD_MICROSECONDS), hostConfig.CPUPeriod)
+	assert.Equal(t, int64(256 * 1024 * 1024), hostConfig.Memory)
+	assert.Equal(t, 3, hostConfig.RestartPolicy.MaximumRetryCount)
+
+	// Without limits, the Docker engine's defaults apply
+	hostConfig, err = manager.getContainerHostConfig(nil, nil, nil, ContainerOptions{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, container.Resources{}, hostConfig.Resources)
+
+	_, err = manager.getContainerHostConfig(nil, nil, nil, ContainerOptions{MemoryLimitBytes: -1})
+	assert.ErrorContains(t, err, "must not be negative")
+}
+
+func TestGetCpuPercentLimit(t *testing.T) {
+	assert.Equal(t, uint(0), getCpuPercentLimit(container.Resources{}))
+	assert.Equal(t, uint(0), getCpuPercentLimit(container.Resources{CPUQuota: UNLIMITED_CPU_CFS_QUOTA}))
+	assert.Equal(t, uint(150), getCpuPercentLimit(container.Resources{CPUPeriod: CPU_CFS_PERIOD_MICROSECONDS, CPUQuota: 150000}))
+	// Docker's default period applies to containers that don't set one
+	assert.Equal(t, uint(50), getCpuPercentLimit(container.Resources{CPUQuota: 50000}))
+	// Fractions of a percent round up, so limited containers are never reported as unlimited
+	assert.Equal(t, uint(1), getCpuPercentLimit(container.Resources{CPUPeriod: CPU_CFS_PERIOD_MICROSECONDS, CPUQuota: 10}))
+	assert.Equal(t, uint(250), getCpuPercentLimit(container.Resources{NanoCPUs: 2500000000}))
+}
+
+func TestGetPublishedPorts(t *testing.T) {
+	usedPorts := map[nat.Port]bool{"80/tcp": true, "9090/tcp": true}
+
+	assert.Equal(t, 0, len(ContainerOptions{}.GetPublishedPorts(usedPorts)))
+	assert.DeepEqual(t, usedPorts, ContainerOptions{PublishPorts: true}.GetPublishedPorts(usedPorts))
+	onlyHttp := ContainerOptions{PublishPorts: true, PublishedPorts: map[nat.Port]bool{"80/tcp": true}}
+	assert.DeepEqual(t, map[nat.Port]bool{"80/tcp": true}, onlyHttp.GetPublishedPorts(usedPorts))
+	assert.Equal(t, 0, len(ContainerOptions{PublishPorts: true, UseHostNetwork: true}.GetPublishedPorts(usedPorts)))
+
+	assert.NilError(t, validatePublishedPorts(usedPorts, onlyHttp))
+	unusedPort := ContainerOptions{PublishedPorts: map[nat.Port]bool{"443/tcp": true}}
+	assert.ErrorContains(t, validatePublishedPorts(usedPorts, unusedPort), "isn't one of the ports")
+}
+
+func TestUdpAndTcpPorts(t *testing.T) {
+	// A gossip port that's used over both protocols, plus a port declared without a protocol (which defaults to TCP)
+	usedPorts := map[nat.Port]bool{"30303/tcp": true, "30303/udp": true, "8545": true}
+	options := ContainerOptions{PublishPorts: true}
+	manager := &DockerManager{log: logrus.NewEntry(logrus.StandardLogger())}
+	hostConfig, err := manager.getContainerHostConfig(usedPorts, nil, nil, options)
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(hostConfig.PortBindings))
+	_, found := hostConfig.PortBindings["30303/udp"]
+	assert.Assert(t, found)
+
+	assert.NilError(t, validatePublishedPorts(usedPorts, ContainerOptions{PublishedPorts: map[nat.Port]bool{"8545/tcp": true}}))
+	wrongProtocol := ContainerOptions{PublishedPorts: map[nat.Port]bool{"8545/udp": true}}
+	assert.ErrorContains(t, validatePublishedPorts(usedPorts, wrongProtocol), "isn't one of the ports")
+
+	fake := NewFakeDockerManager()
+	containerId, err := fake.CreateAndStartContainer(context.Background(), "geth:v1.9.0", "", nil, usedPorts, nil, nil, nil, nil, options)
+	assert.NilError(t, err)
+	tcpHostPort, err := fake.GetContainerHostPort(context.Background(), containerId, "30303/tcp")
+	assert.NilError(t, err)
+	udpHostPort, err := fake.GetContainerHostPort(context.Background(), containerId, "30303/udp")
+	assert.NilError(t, err)
+	assert.Assert(t, tcpHostPort != udpHostPort)
+	_, err = fake.GetContainerHostPort(context.Background(), containerId, "8545/tcp")
+	assert.NilError(t, err)
+}
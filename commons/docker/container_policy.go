@@ -0,0 +1,75 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types/container"
+	"github.com/palantir/stacktrace"
+	"strings"
+)
+
+const (
+	// The tag that images without an explicit tag get, which strict policy rejects because it can point at different
+	//  images from one run to the next
+	latestImageTag = "latest"
+
+	rootUsername = "root"
+	rootUid = "0"
+)
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Checks that the given image is pinned to a tag other than "latest" (or to a digest), for containers with
+	ContainerOptions.EnforceStrictPolicy set.
+ */
+func checkStrictPolicyImage(dockerImage string) error {
+	named, err := reference.ParseNormalizedNamed(dockerImage)
+	if err != nil {
+		return stacktrace.Propagate(err, "Couldn't parse image name %v", dockerImage)
+	}
+	if _, isDigested := named.(reference.Digested); isDigested {
+		return nil
+	}
+	tagged, isTagged := named.(reference.Tagged)
+	if !isTagged || tagged.Tag() == latestImageTag {
+		return stacktrace.NewError("Strict policy forbids image %v, because it isn't pinned to a tag other than '%v'", dockerImage, latestImageTag)
+	}
+	return nil
+}
+
+/*
+Checks that a container with the given (final) configuration won't run privileged or as root, for containers with
+	ContainerOptions.EnforceStrictPolicy set.
+ */
+func (manager DockerManager) checkStrictPolicyContainer(
+			context context.Context,
+			config *container.Config,
+			hostConfig *container.HostConfig) error {
+	if hostConfig.Privileged {
+		return stacktrace.NewError("Strict policy forbids container from image %v from running privileged", config.Image)
+	}
+
+	user := config.User
+	if user == "" {
+		// Containers run as their image's user unless told otherwise
+		imageInfo, _, err := manager.dockerClient.ImageInspectWithRaw(context, config.Image)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred inspecting image %v to get the user it runs as", config.Image)
+		}
+		if imageInfo.Config != nil {
+			user = imageInfo.Config.User
+		}
+	}
+	if isRootUser(user) {
+		return stacktrace.NewError(
+			"Strict policy forbids container from image %v from running as root; set a non-root USER in the image, or a user with a ContainerCustomizer",
+			config.Image)
+	}
+	return nil
+}
+
+// Returns true if the given Docker user ("user", "uid", "user:group", or "uid:gid") is root; no user means root
+func isRootUser(user string) bool {
+	username := strings.SplitN(user, ":", 2)[0]
+	return username == "" || username == rootUsername || username == rootUid
+}
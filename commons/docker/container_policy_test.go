@@ -0,0 +1,47 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/docker/api/types/container"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestStrictPolicyImageTags(t *testing.T) {
+	assert.NilError(t, checkStrictPolicyImage("alpine:3.12"))
+	assert.NilError(t, checkStrictPolicyImage("registry.internal:5000/team/service:1.4.2"))
+	assert.NilError(t, checkStrictPolicyImage("alpine@sha256:" + "a15790640a6690aa1730c38cf0a440e2aa44aaca9b0e8931a9f2b0d7cc90fd65"))
+	assert.ErrorContains(t, checkStrictPolicyImage("alpine"), "isn't pinned")
+	assert.ErrorContains(t, checkStrictPolicyImage("alpine:latest"), "isn't pinned")
+	assert.ErrorContains(t, checkStrictPolicyImage("registry.internal:5000/team/service"), "isn't pinned")
+}
+
+func TestStrictPolicyContainers(t *testing.T) {
+	// Containers with an explicit user never need their image inspected, so no Docker client is needed
+	manager := DockerManager{log: logrus.NewEntry(logrus.StandardLogger())}
+	assert.NilError(t, manager.checkStrictPolicyContainer(
+		context.Background(),
+		&container.Config{Image: "alpine:3.12", User: "1000:1000"},
+		&container.HostConfig{}))
+	assert.ErrorContains(
+		t,
+		manager.checkStrictPolicyContainer(
+			context.Background(),
+			&container.Config{Image: "alpine:3.12", User: "1000"},
+			&container.HostConfig{Privileged: true}),
+		"privileged")
+	assert.ErrorContains(
+		t,
+		manager.checkStrictPolicyContainer(
+			context.Background(),
+			&container.Config{Image: "alpine:3.12", User: "0:1000"},
+			&container.HostConfig{}),
+		"as root")
+
+	assert.Assert(t, isRootUser(""))
+	assert.Assert(t, isRootUser("root"))
+	assert.Assert(t, isRootUser("root:staff"))
+	assert.Assert(t, !isRootUser("nobody"))
+	assert.Assert(t, !isRootUser("65534:65534"))
+}
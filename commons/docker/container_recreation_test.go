@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"context"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFailedRecreationRestoresOldContainer(t *testing.T) {
+	calls := []string{}
+	server := newTestRecreationServer("DELETE /v1.40/containers/old", &calls)
+	defer server.Close()
+	manager := newTestServerDockerManager(t, server)
+
+	_, err := manager.RecreateContainer(context.Background(), "old", time.Second)
+	assert.ErrorContains(t, err, "removing container with ID old")
+	assert.DeepEqual(t, []string{
+		"POST /v1.40/containers/old/stop?t=1",
+		"POST /v1.40/containers/old/rename?name=service-replaced",
+		"POST /v1.40/containers/create?name=service",
+		"DELETE /v1.40/containers/old",
+		"DELETE /v1.40/containers/new?force=1",
+		"POST /v1.40/containers/old/rename?name=service",
+	}, calls)
+}
+
+func TestFailedRecreationRemovesReplacementContainer(t *testing.T) {
+	calls := []string{}
+	server := newTestRecreationServer("POST /v1.40/networks/test-network/connect", &calls)
+	defer server.Close()
+	manager := newTestServerDockerManager(t, server)
+
+	_, err := manager.RecreateContainer(context.Background(), "old", time.Second)
+	assert.ErrorContains(t, err, "reconnecting the replacement container")
+	// The old container is already gone, so there's nothing to rename back
+	assert.DeepEqual(t, []string{
+		"POST /v1.40/containers/old/stop?t=1",
+		"POST /v1.40/containers/old/rename?name=service-replaced",
+		"POST /v1.40/containers/create?name=service",
+		"DELETE /v1.40/containers/old",
+		"POST /v1.40/networks/test-network/connect",
+		"DELETE /v1.40/containers/new?force=1",
+	}, calls)
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Serves a Docker engine with a container "old", named "service" & attached to "test-network", whose replacement gets the
+	ID "new". The given call fails, and every call apart from inspections is recorded.
+ */
+func newTestRecreationServer(failingCall string, calls *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		call := request.Method + " " + request.URL.Path
+		if request.Method == http.MethodGet {
+			switch request.URL.Path {
+			case "/v1.40/containers/old/json":
+				writer.Write([]byte(`{"Id": "old", "Name": "/service", "Config": {"Image": "test"}, "HostConfig": {}, "Mounts": [],
+					"NetworkSettings": {"Networks": {"test-network": {"NetworkID": "test-network"}}}}`))
+			case "/v1.40/containers/new/json":
+				writer.Write([]byte(`{"Id": "new", "Name": "/service", "Config": {"Image": "test"}, "HostConfig": {}, "NetworkSettings": {"Networks": {}}}`))
+			default:
+				writer.WriteHeader(http.StatusNotFound)
+			}
+			return
+		}
+		if request.URL.RawQuery != "" {
+			*calls = append(*calls, call + "?" + request.URL.RawQuery)
+		} else {
+			*calls = append(*calls, call)
+		}
+		if call == failingCall {
+			writer.WriteHeader(http.StatusInternalServerError)
+			writer.Write([]byte(`{"message": "simulated failure"}`))
+			return
+		}
+		if call == "POST /v1.40/containers/create" {
+			writer.WriteHeader(http.StatusCreated)
+			writer.Write([]byte(`{"Id": "new"}`))
+			return
+		}
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+}
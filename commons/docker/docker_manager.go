@@ -1,20 +1,30 @@
 package docker
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	mounttypes "github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/metrics"
+	"github.com/kurtosis-tech/kurtosis/commons/ports"
+	"github.com/kurtosis-tech/kurtosis/commons/tracing"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
 	"io"
-	"io/ioutil"
 	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,7 +33,7 @@ WARNING WARNING WARNING WARNING WARNING WARNING WARNING WARNING WARNING WARNING
 
 This manager is used on a per-test basis. Because tests can run in parallel but we need to pretty-print
 each test's logs in a single block, we need to have a seprate logger per test. As such, this class takes in a
-logrus.Logger, and *all log messages should be sent through this logger rather than the systemwide logger!!!*
+logrus.Entry, and *all log messages should be sent through this entry rather than the systemwide logger!!!*
 
 No logrus.Info, logrus.Debug, etc. calls should happen in this file - only manager.log.Info, manager.log.Debug, etc.!
 
@@ -34,27 +44,161 @@ const (
 	// We use a bridge network because, as of 2020-08-01, we're only running locally; however, this may need to change
 	//  at some point in the future
 	DOCKER_NETWORK_DRIVER = "bridge"
+
+	// The network driver for networks spanning every host in a Docker Swarm, which lets a test's containers be spread
+	//  across multiple machines
+	OVERLAY_NETWORK_DRIVER = "overlay"
+
+	// The length of a CFS scheduling period, which CPU quotas are expressed relative to
+	CPU_CFS_PERIOD_MICROSECONDS = 100000
+
+	// The CFS quota value that the kernel interprets as "no limit"
+	UNLIMITED_CPU_CFS_QUOTA = -1
+
+	// The number of "nano CPUs" (Docker's --cpus unit) in a single CPU core
+	nanoCpusPerCpu = 1000000000
+
+	// Appended to the name of a container that's being recreated, while its replacement is created with its name
+	replacedContainerNameSuffix = "-replaced"
+
+	// The signal sent to containers that are killed (rather than gracefully stopped)
+	CONTAINER_KILL_SIGNAL = "SIGKILL"
+
+	// The health status reported for containers whose image doesn't define a Docker healthcheck
+	NO_HEALTHCHECK_STATUS = "none"
+
+	// Label put on every volume, network, & container that Kurtosis creates, so that Kurtosis-managed resources can be found
+	//  & cleaned up
+	MANAGED_BY_LABEL = "com.kurtosistech.managed-by"
+	MANAGED_BY_LABEL_VALUE = "kurtosis"
+
+	// Label identifying the test suite execution that a volume, network, or container was created for
+	EXECUTION_ID_LABEL = "com.kurtosistech.execution-id"
+
+	// Label identifying the test suite (by its controller image) that a volume, network, or container was created for
+	TEST_SUITE_LABEL = "com.kurtosistech.test-suite"
+
+	// Label identifying the test that a volume, network, or container was created for
+	TEST_NAME_LABEL = "com.kurtosistech.test-name"
+
+	// Label identifying the test volume of the test that a volume, network, or service container was created for
+	TEST_VOLUME_LABEL = "com.kurtosistech.test-volume"
+
+	// Label identifying the service that a container runs, within its test network
+	SERVICE_ID_LABEL = "com.kurtosistech.service-id"
+
+	// Label holding the directory in a service's container that its instrumented binary writes coverage to (see
+	//  services.CoverageProvider)
+	COVERAGE_DIRPATH_LABEL = "com.kurtosistech.coverage-dirpath"
+
+	// Label marking the containers of services that deliberately misbehave (see networks.ServiceNetworkBuilder.AddAdversarialConfiguration)
+	ADVERSARIAL_LABEL = "com.kurtosistech.adversarial"
+	ADVERSARIAL_LABEL_VALUE = "true"
+
+	// The network mode that runs a container in the host's network namespace, bypassing Docker NAT
+	HOST_NETWORK_MODE = "host"
+
+	// The special IP for ContainerOptions.ExtraHosts entries that Docker resolves to the host (e.g.
+	//  "host.docker.internal:host-gateway"), for reaching mock servers running on the host
+	HOST_GATEWAY_IP = "host-gateway"
+)
+
+/*
+One of the output streams of a container, whose logs can be retrieved separately with GetContainerStreamLogs (e.g. for
+	services that put structured output on STDOUT & diagnostics on STDERR).
+ */
+type LogStream string
+const (
+	STDOUT_LOG_STREAM LogStream = "stdout"
+	STDERR_LOG_STREAM LogStream = "stderr"
 )
 
+/*
+A snapshot of how much of the host's resources a container is using.
+ */
+type ContainerResourceUsage struct {
+	// CPU usage, where 100 means one full CPU core
+	CpuPercent float64
+
+	// Memory usage, in bytes
+	MemoryBytes uint64
+}
+
+/*
+A summary of the current state of a container.
+ */
+type ContainerStatus struct {
+	// The image the container was created from
+	Image string
+
+	// Docker's state for the container (e.g. "running", "exited")
+	State string
+
+	// The result of the image's Docker healthcheck, or NO_HEALTHCHECK_STATUS if the image doesn't define one
+	HealthStatus string
+
+	// The number of times Docker has restarted the container
+	RestartCount int
+
+	// The exit code of the container (only meaningful if the container has exited)
+	ExitCode int
+
+	// When the container was last started (the zero time if it's never been started)
+	StartedAt time.Time
+
+	// The container's labels (e.g. SERVICE_ID_LABEL)
+	Labels map[string]string
+}
+
+/*
+Everything about how a container can be reached, as of when it was inspected, so that callers needing several of these
+	details get them all from a single inspect round-trip (see GetContainerNetworkInfo).
+ */
+type ContainerNetworkInfo struct {
+	// Mapping of container port -> the port on the host it was published on, for published ports
+	HostPorts map[nat.Port]int
+
+	// Mapping of network ID -> the container's IPv4 address on the network, for networks it has one on
+	IpAddrs map[string]net.IP
+
+	// Mapping of network ID -> the container's IPv6 address on the network, for networks it has one on
+	Ipv6Addrs map[string]net.IP
+}
+
 /*
 A handle to interacting with the Docker environment running a test.
  */
 type DockerManager struct {
-	// The logger that all log messages will be written to
-	log *logrus.Logger // NOTE: This log should be used for all log statements - the system-wide logger should NOT be used!
+	// The log entry that all log messages will be written to, tagged with fields identifying the test
+	log *logrus.Entry // NOTE: This log should be used for all log statements - the system-wide logger should NOT be used!
 
 	// The underlying Docker client that will be used to modify the Docker environment
 	dockerClient        *client.Client
+
+	// The host of the pull-through registry mirror that Docker Hub images are pulled through (empty to pull them from
+	//  Docker Hub directly)
+	registryMirror string
+
+	// True if the manager creates Windows containers rather than Linux ones (see UseWindowsContainers)
+	windowsContainers bool
+
+	// A "set" of the images that the manager has already pulled if it always pulls images (see AlwaysPullImages), or nil
+	//  if it only pulls missing images
+	alwaysPulledImages *sync.Map
+
+	// Labels put on every volume, network, & container that the manager creates, on top of MANAGED_BY_LABEL (see
+	//  UseResourceLabels)
+	resourceLabels map[string]string
 }
 
 /*
 Creates a new Docker manager for manipulating the Docker engine using the given client.
 
 Args:
-	log: The logger that this Docker manager will write all its log messages to.
+	log: The log entry that this Docker manager will write all its log messages to (which should carry fields identifying the test).
 	dockerClient: The Docker client that will be used when interacting with the underlying Docker engine the Docker engine.
 */
-func NewDockerManager(log *logrus.Logger, dockerClient *client.Client) (dockerManager *DockerManager, err error) {
+func NewDockerManager(log *logrus.Entry, dockerClient *client.Client) (dockerManager *DockerManager, err error) {
 	return &DockerManager{
 		log: log,
 		dockerClient:        dockerClient,
@@ -67,36 +211,95 @@ Creates a new Docker network with the given parameters; does nothing if a networ
 Args:
 	context: The Context that this request is running in (useful for cancellation)
 	name: The name to give the new Docker network
-	subnetMask: The subnet mask defining allowed IPs for the Docker network
+	subnetMask: The subnet mask defining allowed IPs for the Docker network, which may be IPv4 or IPv6
 	gatewayIP: The IP to give the network gateway
+	isInternal: True if the network's containers shouldn't be able to reach anything outside the network (e.g. the
+		internet), unless they're also attached to another network
 
 Returns:
 	id: The Docker-managed ID of the network
  */
-func (manager DockerManager) CreateNetwork(context context.Context, name string, subnetMask string, gatewayIP net.IP) (id string, err error)  {
-	found, err := manager.networkExists(name)
-	if err != nil {
-		return "", stacktrace.Propagate(err, "An error occurred checking for existence of network with name %v", name)
-	}
-	if found {
-		// We throw an error if the network already exists because we don't know what settings that network was created
-		//  with - likely a completely different subnetMask and gatewayIP
-		return "", stacktrace.NewError("Network with name %v cannot be created because it already exists", name)
-	}
+func (manager DockerManager) CreateNetwork(context context.Context, name string, subnetMask string, gatewayIP net.IP, isInternal bool) (id string, err error)  {
 	ipamConfig := []network.IPAMConfig{{
 		Subnet: subnetMask,
 		Gateway: gatewayIP.String(),
 	}}
-	resp, err := manager.dockerClient.NetworkCreate(context, name, types.NetworkCreate{
-		Driver: DOCKER_NETWORK_DRIVER,
-		IPAM: &network.IPAM{
-			Config: ipamConfig,
+	return manager.createNetwork(context, name, manager.getNetworkDriver(), ipamConfig, map[string]string{}, isInternal)
+}
+
+/*
+Creates a new attachable overlay Docker network, spanning every host in the Docker Swarm that this manager's Docker engine
+	is a manager node of, so that standalone containers started on any of the Swarm's hosts can join it.
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	name: The name to give the new Docker network
+	subnetMask: The subnet mask defining allowed IPs for the Docker network
+	gatewayIP: The IP to give the network gateway
+	isInternal: True if the network's containers shouldn't be able to reach anything outside the network (see CreateNetwork)
+
+Returns:
+	The Docker-managed ID of the network
+ */
+func (manager DockerManager) CreateOverlayNetwork(context context.Context, name string, subnetMask string, gatewayIP net.IP, isInternal bool) (string, error) {
+	ipamConfig := []network.IPAMConfig{{
+		Subnet: subnetMask,
+		Gateway: gatewayIP.String(),
+	}}
+	return manager.createNetwork(context, name, OVERLAY_NETWORK_DRIVER, ipamConfig, map[string]string{}, isInternal)
+}
+
+/*
+Creates a new dual-stack Docker network, whose containers get both an IPv4 and an IPv6 address.
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	name: The name to give the new Docker network
+	ipv4SubnetMask: The IPv4 subnet mask defining allowed IPv4 IPs for the Docker network
+	ipv4GatewayIp: The IPv4 IP to give the network gateway
+	ipv6SubnetMask: The IPv6 subnet mask defining allowed IPv6 IPs for the Docker network
+	ipv6GatewayIp: The IPv6 IP to give the network gateway
+	isInternal: True if the network's containers shouldn't be able to reach anything outside the network (see CreateNetwork)
+
+Returns:
+	The Docker-managed ID of the network
+ */
+func (manager DockerManager) CreateDualStackNetwork(
+			context context.Context,
+			name string,
+			ipv4SubnetMask string,
+			ipv4GatewayIp net.IP,
+			ipv6SubnetMask string,
+			ipv6GatewayIp net.IP,
+			isInternal bool) (string, error) {
+	ipamConfig := []network.IPAMConfig{
+		{
+			Subnet:  ipv4SubnetMask,
+			Gateway: ipv4GatewayIp.String(),
+		},
+		{
+			Subnet:  ipv6SubnetMask,
+			Gateway: ipv6GatewayIp.String(),
 		},
-	})
-	if err != nil {
-		return "", stacktrace.Propagate( err, "Failed to create network %s with subnet %s", name, subnetMask)
 	}
-	return resp.ID, nil
+	return manager.createNetwork(context, name, manager.getNetworkDriver(), ipamConfig, map[string]string{}, isInternal)
+}
+
+/*
+Creates a new Docker network whose subnet Docker picks from its default address pools, for networks whose containers
+	don't need static IPs.
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	name: The name to give the new Docker network
+	labels: Labels to put on the network (e.g. TEST_VOLUME_LABEL), so it can later be found with ListNetworks; the network
+		is always given the MANAGED_BY_LABEL & the manager's resource labels as well
+
+Returns:
+	The Docker-managed ID of the network
+ */
+func (manager DockerManager) CreateDynamicSubnetNetwork(context context.Context, name string, labels map[string]string) (string, error) {
+	return manager.createNetwork(context, name, manager.getNetworkDriver(), []network.IPAMConfig{}, labels, false)
 }
 
 /*
@@ -127,6 +330,152 @@ func (manager DockerManager) RemoveNetwork(context context.Context, networkId st
 	return nil
 }
 
+/*
+Lists the IDs of the Docker networks that have all of the given labels, sorted.
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	labels: The labels that returned networks must have
+ */
+func (manager DockerManager) ListNetworks(context context.Context, labels map[string]string) ([]string, error) {
+	labelFilters := filters.NewArgs()
+	for key, value := range labels {
+		labelFilters.Add("label", key + "=" + value)
+	}
+	dockerNetworks, err := manager.dockerClient.NetworkList(context, types.NetworkListOptions{Filters: labelFilters})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to list Docker networks with labels %v", labels)
+	}
+
+	result := []string{}
+	for _, dockerNetwork := range dockerNetworks {
+		result = append(result, dockerNetwork.ID)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+/*
+Gets the ID of the Docker network with the given name (or ID), e.g. to look a test's network up by the name it was
+	created with.
+ */
+func (manager DockerManager) GetNetworkId(context context.Context, networkName string) (string, error) {
+	dockerNetwork, err := manager.dockerClient.NetworkInspect(context, networkName, types.NetworkInspectOptions{})
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Failed to inspect Docker network %v", networkName)
+	}
+	return dockerNetwork.ID, nil
+}
+
+/*
+Gets the IPv4 gateway of the given Docker network (or its IPv6 gateway, for IPv6-only networks), which is the address
+	that containers on the network can reach the host at.
+ */
+func (manager DockerManager) GetNetworkGatewayIp(context context.Context, networkId string) (net.IP, error) {
+	dockerNetwork, err := manager.dockerClient.NetworkInspect(context, networkId, types.NetworkInspectOptions{})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to inspect Docker network with ID %v", networkId)
+	}
+	var result net.IP = nil
+	for _, ipamConfig := range dockerNetwork.IPAM.Config {
+		gatewayIp := net.ParseIP(ipamConfig.Gateway)
+		if gatewayIp == nil {
+			continue
+		}
+		if gatewayIp.To4() != nil {
+			return gatewayIp, nil
+		}
+		if result == nil {
+			result = gatewayIp
+		}
+	}
+	if result == nil {
+		return nil, stacktrace.NewError("Docker network with ID %v has no gateway", networkId)
+	}
+	return result, nil
+}
+
+/*
+Gets the subnets of every Docker network on the host, so that new networks can avoid colliding with them.
+ */
+func (manager DockerManager) GetNetworkSubnets(context context.Context) ([]*net.IPNet, error) {
+	dockerNetworks, err := manager.dockerClient.NetworkList(context, types.NetworkListOptions{})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to list Docker networks")
+	}
+	result := []*net.IPNet{}
+	for _, dockerNetwork := range dockerNetworks {
+		for _, ipamConfig := range dockerNetwork.IPAM.Config {
+			if ipamConfig.Subnet == "" {
+				continue
+			}
+			_, subnet, err := net.ParseCIDR(ipamConfig.Subnet)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "Docker network %v has unparseable subnet %v", dockerNetwork.Name, ipamConfig.Subnet)
+			}
+			result = append(result, subnet)
+		}
+	}
+	return result, nil
+}
+
+/*
+Pulls the given Docker image from its remote image repository, unless it's already available locally (or, if the manager
+	always pulls images, unless the manager has already pulled it; see AlwaysPullImages).
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	dockerImage: The image to pull (e.g. "alpine:3.12")
+ */
+func (manager DockerManager) PullImageIfMissing(context context.Context, dockerImage string) error {
+	if manager.alwaysPulledImages != nil {
+		return manager.pullImageOnce(context, dockerImage)
+	}
+	imageExistsLocally, err := manager.isImageAvailableLocally(dockerImage)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred checking for local availability of Docker image %v", dockerImage)
+	}
+	if imageExistsLocally {
+		return nil
+	}
+	if err := manager.pullImage(context, dockerImage, ""); err != nil {
+		return stacktrace.Propagate(err, "Failed to pull Docker image %v from remote image repository", dockerImage)
+	}
+	return nil
+}
+
+/*
+Creates a Docker volume, identified by the given name, whose data is the given directory on the Docker engine's host, so
+	that files written to the directory from the host (e.g. by a test running outside of any container) are visible in
+	containers that mount the volume & vice versa.
+
+NOTE: The directory must exist, and must be on the Docker engine's machine (i.e. this only works with a local engine).
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	volumeName: The unique identifier used by Docker to identify this volume
+	hostDirpath: The absolute path of the directory on the host
+	labels: Labels to put on the volume, as with CreateVolume
+ */
+func (manager DockerManager) CreateHostDirectoryVolume(context context.Context, volumeName string, hostDirpath string, labels map[string]string) error {
+	volumeLabels := manager.getResourceLabels(labels)
+	volumeConfig := volume.VolumeCreateBody{
+		Name:       volumeName,
+		Driver:     "local",
+		// A local volume of type "none" is a bind mount of its device
+		DriverOpts: map[string]string{
+			"type":   "none",
+			"o":      "bind",
+			"device": hostDirpath,
+		},
+		Labels:     volumeLabels,
+	}
+	if _, err := manager.dockerClient.VolumeCreate(context, volumeConfig); err != nil {
+		return stacktrace.Propagate(err, "Could not create Docker volume %v of host directory %v", volumeName, hostDirpath)
+	}
+	return nil
+}
+
 /*
 Creates a Docker volume identified by the given name.
 
@@ -134,10 +483,14 @@ Args:
 	context: The Context that this request is running in (useful for cancellation)
 	volumeName: The unique identifier used by Docker to identify this volume (NOTE: at time of writing, Docker doesn't
 		even give volumes IDs - this name is all there is)
+	labels: Labels to put on the volume (e.g. EXECUTION_ID_LABEL), so it can later be found with ListVolumes; the volume
+		is always given the MANAGED_BY_LABEL & the manager's resource labels as well
  */
-func (manager DockerManager) CreateVolume(context context.Context, volumeName string) error {
+func (manager DockerManager) CreateVolume(context context.Context, volumeName string, labels map[string]string) error {
+	volumeLabels := manager.getResourceLabels(labels)
 	volumeConfig := volume.VolumeCreateBody{
 		Name:       volumeName,
+		Labels:     volumeLabels,
 	}
 
 	/*
@@ -149,15 +502,119 @@ func (manager DockerManager) CreateVolume(context context.Context, volumeName st
 	 */
 	_, err := manager.dockerClient.VolumeCreate(context, volumeConfig)
 	if err != nil {
-		return stacktrace.Propagate(err, "Could not create Docker volume for test controller")
+		return stacktrace.Propagate(err, "Could not create Docker volume %v", volumeName)
+	}
+
+	return nil
+}
+
+/*
+Removes the Docker volume with the given name.
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	volumeName: The name of the volume to remove
+	removeStoppedContainers: Docker won't remove a volume that any container - even a stopped one - references, so if this
+		is true then stopped containers referencing the volume are removed first. Running containers are never removed.
+ */
+func (manager DockerManager) RemoveVolume(context context.Context, volumeName string, removeStoppedContainers bool) error {
+	if removeStoppedContainers {
+		referencingContainers, err := manager.dockerClient.ContainerList(context, types.ContainerListOptions{
+			All:     true,
+			Filters: filters.NewArgs(filters.Arg("volume", volumeName)),
+		})
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to list the containers referencing volume %v", volumeName)
+		}
+		for _, referencingContainer := range referencingContainers {
+			if referencingContainer.State == "running" {
+				return stacktrace.NewError("Can't remove volume %v because running container %v uses it", volumeName, referencingContainer.ID)
+			}
+			if err := manager.dockerClient.ContainerRemove(context, referencingContainer.ID, types.ContainerRemoveOptions{}); err != nil {
+				return stacktrace.Propagate(err, "Failed to remove stopped container %v, which references volume %v", referencingContainer.ID, volumeName)
+			}
+		}
 	}
 
+	if err := manager.dockerClient.VolumeRemove(context, volumeName, false); err != nil {
+		return stacktrace.Propagate(err, "Failed to remove Docker volume %v", volumeName)
+	}
 	return nil
 }
 
+/*
+Lists the names of the Docker volumes that have all of the given labels, sorted.
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	labels: The labels that returned volumes must have, e.g. {MANAGED_BY_LABEL: MANAGED_BY_LABEL_VALUE} to get every
+		Kurtosis-managed volume
+ */
+func (manager DockerManager) ListVolumes(context context.Context, labels map[string]string) ([]string, error) {
+	labelFilters := filters.NewArgs()
+	for key, value := range labels {
+		labelFilters.Add("label", key + "=" + value)
+	}
+	listResponse, err := manager.dockerClient.VolumeList(context, labelFilters)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to list Docker volumes with labels %v", labels)
+	}
+
+	result := []string{}
+	for _, dockerVolume := range listResponse.Volumes {
+		result = append(result, dockerVolume.Name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+
+/*
+Creates a Docker container with the given args and starts it. If the container is created but can't be started, it's
+	removed.
+
+Args:
+	See CreateContainer
+
+Returns:
+	The Docker container ID of the newly-created container
+ */
+func (manager DockerManager) CreateAndStartContainer(
+			context context.Context,
+			dockerImage string,
+			networkId string,
+			staticIp net.IP,
+			usedPorts map[nat.Port]bool,
+			startCmdArgs []string,
+			envVariables map[string]string,
+			bindMounts map[string]string,
+			volumeMounts map[string]string,
+			options ContainerOptions) (containerId string, err error) {
+	containerId, err = manager.CreateContainer(
+			context,
+			dockerImage,
+			networkId,
+			staticIp,
+			usedPorts,
+			startCmdArgs,
+			envVariables,
+			bindMounts,
+			volumeMounts,
+			options)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Could not create Docker container from image %v.", dockerImage)
+	}
+	if err := manager.StartContainer(context, containerId); err != nil {
+		manager.removeFailedContainer(containerId)
+		return "", stacktrace.Propagate(err, "Could not start Docker container from image %v.", dockerImage)
+	}
+	return containerId, nil
+}
 
 /*
-Creates a Docker container with the given args and starts it.
+Creates a Docker container with the given args without starting it, attaching it to its networks and populating its
+	filesystem, so that it can be started later with StartContainer. If any step after the container is created fails,
+	the container is removed.
 
 Args:
 	context: The Context that this request is running in (useful for cancellation)
@@ -169,11 +626,12 @@ Args:
 	envVariables: A key-value mapping of Docker environment variables which will be passed to the container during startup
 	bindMounts: Mapping of (host file) -> (mountpoint on container) that will be mounted on container startup
 	volumeMounts: Mapping of (volume name) -> (mountpoint on container) to mount during container launch
+	options: Optional container settings (pass the zero value for Docker's defaults)
 
 Returns:
 	The Docker container ID of the newly-created container
  */
-func (manager DockerManager) CreateAndStartContainer(
+func (manager DockerManager) CreateContainer(
 			context context.Context,
 			dockerImage string,
 			networkId string,
@@ -182,52 +640,144 @@ func (manager DockerManager) CreateAndStartContainer(
 			startCmdArgs []string,
 			envVariables map[string]string,
 			bindMounts map[string]string,
-			volumeMounts map[string]string) (containerId string, err error) {
-
-	imageExistsLocally, err := manager.isImageAvailableLocally(dockerImage)
-	if err != nil {
-		return "", stacktrace.Propagate(err, "An error occurred checking for local availability of Docker image %v", dockerImage)
+			volumeMounts map[string]string,
+			options ContainerOptions) (containerId string, err error) {
+	context, span := tracing.StartSpan(context, "CreateContainer")
+	span.SetAttribute("image", dockerImage)
+	for _, secret := range options.Secrets {
+		knownSecretValues.add(string(secret.Value))
 	}
+	defer func() {
+		// The Docker engine's errors may echo the container's configuration, which includes its secrets
+		err = RedactSecretsFromError(err)
+		span.RecordError(err)
+		span.End()
+	}()
 
-	if !imageExistsLocally {
-		err = manager.pullImage(context, dockerImage)
-		if err != nil {
-			return "", stacktrace.Propagate(err, "Failed to pull Docker image %v from remote image repository", dockerImage)
+	if options.EnforceStrictPolicy {
+		if err := checkStrictPolicyImage(dockerImage); err != nil {
+			return "", stacktrace.Propagate(err, "Image %v doesn't meet strict policy", dockerImage)
+		}
+	}
+	if options.Platform != "" {
+		if err := manager.pullImageForPlatformIfMissing(context, dockerImage, options.Platform); err != nil {
+			return "", stacktrace.Propagate(err, "An error occurred getting the %v variant of Docker image %v", options.Platform, dockerImage)
 		}
+	} else if err := manager.PullImageIfMissing(context, dockerImage); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting Docker image %v", dockerImage)
 	}
 
-	networkExistsLocally, err := manager.networkExists(networkId)
+	dockerNetwork, networkExistsLocally, err := manager.findNetwork(networkId)
 	if err != nil {
 		return "", stacktrace.Propagate(err, "An error occurred checking for the existence of network with ID %v", networkId)
 	}
 	if !networkExistsLocally {
 		return "", stacktrace.NewError("Kurtosis Docker network with ID %v was never created before trying to launch containers. Please call DockerManager.CreateNetwork first.", networkId)
 	}
+	// Containers are otherwise created on the default bridge network, whose outbound connectivity would defeat the point
+	//  of an internal network, so they're created on the internal network itself instead
+	isOnlyOnNetwork := dockerNetwork.Internal && !options.UseHostNetwork && !options.UseDefaultBridgeNetwork
 
-	containerConfigPtr, err := manager.getContainerCfg(dockerImage, usedPorts, startCmdArgs, envVariables)
+	// The Docker engine refuses to copy files into containers whose root filesystems are read-only
+	if options.ReadOnlyRootFilesystem && (len(options.Archives) > 0 || len(options.Fixtures) > 0 || len(getSecretFixtures(options.Secrets)) > 0) {
+		return "", stacktrace.NewError("Archives, fixtures, & secret files can't be placed in container from image %v, because its root filesystem is read-only", dockerImage)
+	}
+	if err := validatePublishedPorts(usedPorts, options); err != nil {
+		return "", stacktrace.Propagate(err, "Invalid published ports for container from image %v", dockerImage)
+	}
+	if options.Name != "" {
+		if err := validateContainerName(options.Name); err != nil {
+			return "", stacktrace.Propagate(err, "Invalid name for container from image %v", dockerImage)
+		}
+	}
+	if options.MacAddress != "" {
+		if _, err := net.ParseMAC(options.MacAddress); err != nil {
+			return "", stacktrace.Propagate(err, "Invalid MAC address %v for container from image %v", options.MacAddress, dockerImage)
+		}
+	}
+	containerConfigPtr, err := manager.getContainerCfg(dockerImage, usedPorts, startCmdArgs, envVariables, options)
 	if err != nil {
 		return "", stacktrace.Propagate(err, "Failed to configure container from service.")
 	}
-	containerHostConfigPtr, err := manager.getContainerHostConfig(bindMounts, volumeMounts)
+	containerHostConfigPtr, err := manager.getContainerHostConfig(usedPorts, bindMounts, volumeMounts, options)
 	if err != nil {
 		return "", stacktrace.Propagate(err, "Failed to configure host to container mappings from service.")
 	}
-	resp, err := manager.dockerClient.ContainerCreate(context, containerConfigPtr, containerHostConfigPtr, nil, "")
+	var networkingConfigPtr *network.NetworkingConfig
+	if isOnlyOnNetwork {
+		containerHostConfigPtr.NetworkMode = container.NetworkMode(networkId)
+		networkingConfigPtr = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				networkId: getStaticIpEndpointSettings(staticIp, options.NetworkAliases),
+			},
+		}
+	}
+	if options.Customizer != nil {
+		if err := options.Customizer.CustomizeContainer(containerConfigPtr, containerHostConfigPtr); err != nil {
+			return "", stacktrace.Propagate(err, "The container customizer failed for container from image %v", dockerImage)
+		}
+	}
+	if options.EnforceStrictPolicy {
+		if err := manager.checkStrictPolicyContainer(context, containerConfigPtr, containerHostConfigPtr); err != nil {
+			return "", stacktrace.Propagate(err, "Container from image %v doesn't meet strict policy", dockerImage)
+		}
+	}
+	resp, err := manager.dockerClient.ContainerCreate(context, containerConfigPtr, containerHostConfigPtr, networkingConfigPtr, options.Name)
 	if err != nil {
 		return "", stacktrace.Propagate(err, "Could not create Docker container from image %v.", dockerImage)
 	}
 	containerId = resp.ID
+	defer func() {
+		if err != nil {
+			manager.removeFailedContainer(containerId)
+		}
+	}()
 
-	err = manager.connectToNetwork(networkId, containerId, staticIp)
-	if err != nil {
-		return "", stacktrace.Propagate(err, "Failed to connect container %s to network.", containerId)
+	// Containers using the host's network can't also be attached to a Docker network
+	if !options.UseHostNetwork {
+		if !isOnlyOnNetwork {
+			err = manager.connectToNetwork(networkId, containerId, staticIp, options.NetworkAliases, options.MacAddress)
+			if err != nil {
+				return "", stacktrace.Propagate(err, "Failed to connect container %s to network.", containerId)
+			}
+		}
+		for _, additionalNetworkId := range options.AdditionalNetworkIds {
+			err = manager.connectToNetwork(additionalNetworkId, containerId, nil, options.NetworkAliases, "")
+			if err != nil {
+				return "", stacktrace.Propagate(err, "Failed to connect container %s to additional network %s.", containerId, additionalNetworkId)
+			}
+		}
 	}
-	if err := manager.dockerClient.ContainerStart(context, containerId, types.ContainerStartOptions{}); err != nil {
-		return "", stacktrace.Propagate(err, "Could not start Docker container from image %v.", dockerImage)
+	if err := manager.extractArchivesToContainer(context, containerId, options.Archives); err != nil {
+		return "", stacktrace.Propagate(err, "Failed to extract archives into container %s before starting it.", containerId)
+	}
+	if err := manager.injectFixturesToContainer(context, containerId, options.Fixtures); err != nil {
+		return "", stacktrace.Propagate(err, "Failed to inject file fixtures into container %s before starting it.", containerId)
+	}
+	if err := manager.injectFixturesToContainer(context, containerId, getSecretFixtures(options.Secrets)); err != nil {
+		return "", stacktrace.Propagate(err, "Failed to inject secret files into container %s before starting it.", containerId)
 	}
 	return containerId, nil
 }
 
+/*
+Removes the container with the given ID (killing it first if it's running), along with its anonymous volumes.
+
+Args:
+	context: The context that the removal runs in (useful for cancellation)
+	containerId: ID of the Docker container to remove
+ */
+func (manager DockerManager) RemoveContainer(context context.Context, containerId string) error {
+	removeOpts := types.ContainerRemoveOptions{
+		RemoveVolumes: true,
+		Force:         true,
+	}
+	if err := manager.dockerClient.ContainerRemove(context, containerId, removeOpts); err != nil {
+		return stacktrace.Propagate(err, "An error occurred removing container with ID %v", containerId)
+	}
+	return nil
+}
+
 /*
 Stops the container with the given container ID, waiting for the provided timeout before forcefully terminating the container
 
@@ -245,10 +795,287 @@ func (manager DockerManager) StopContainer(context context.Context, containerId
 }
 
 /*
-Blocks until the given container exits or the context is cancelled.
+Immediately kills the container with the given ID via SIGKILL, without giving it a chance to shut down gracefully. The
+	container is not removed, so it can later be brought back with StartContainer.
 
 Args:
-	context: Context the waiting will run in (useful for cancellation)
+	context: The context that the killing runs in (useful for cancellation)
+	containerId: ID of Docker container to kill
+ */
+func (manager DockerManager) KillContainer(context context.Context, containerId string) error {
+	if err := manager.dockerClient.ContainerKill(context, containerId, CONTAINER_KILL_SIGNAL); err != nil {
+		return stacktrace.Propagate(err, "An error occurred killing container with ID '%v'", containerId)
+	}
+	return nil
+}
+
+/*
+Sends the given signal to the main process of the container with the given ID (e.g. to make it flush its state), leaving
+	it up to the process how to react.
+
+Args:
+	context: The context that the signalling runs in (useful for cancellation)
+	containerId: ID of the Docker container to signal
+	signal: The name of the signal to send (e.g. "SIGTERM" or "SIGUSR1")
+ */
+func (manager DockerManager) SignalContainer(context context.Context, containerId string, signal string) error {
+	if err := manager.dockerClient.ContainerKill(context, containerId, signal); err != nil {
+		return stacktrace.Propagate(err, "An error occurred sending signal %v to container with ID '%v'", signal, containerId)
+	}
+	return nil
+}
+
+/*
+Freezes all the processes in a running container, without stopping it.
+
+Args:
+	context: The context that the pause runs in (useful for cancellation)
+	containerId: ID of the Docker container to pause
+ */
+func (manager DockerManager) PauseContainer(context context.Context, containerId string) error {
+	if err := manager.dockerClient.ContainerPause(context, containerId); err != nil {
+		return stacktrace.Propagate(err, "An error occurred pausing container with ID %v", containerId)
+	}
+	return nil
+}
+
+/*
+Resumes the processes in a container that was paused with PauseContainer.
+
+Args:
+	context: The context that the unpause runs in (useful for cancellation)
+	containerId: ID of the Docker container to unpause
+ */
+func (manager DockerManager) UnpauseContainer(context context.Context, containerId string) error {
+	if err := manager.dockerClient.ContainerUnpause(context, containerId); err != nil {
+		return stacktrace.Propagate(err, "An error occurred unpausing container with ID %v", containerId)
+	}
+	return nil
+}
+
+/*
+Starts an already-created container that isn't running (e.g. because it was stopped or killed). Because the container is
+	reused, it keeps its IP address, hostname, and volumes.
+
+Args:
+	context: The context that the starting runs in (useful for cancellation)
+	containerId: ID of Docker container to start
+ */
+func (manager DockerManager) StartContainer(context context.Context, containerId string) error {
+	if err := manager.dockerClient.ContainerStart(context, containerId, types.ContainerStartOptions{}); err != nil {
+		return stacktrace.Propagate(err, "An error occurred starting container with ID '%v'", containerId)
+	}
+	metrics.ContainersStarted.Inc()
+	return nil
+}
+
+/*
+Replaces the container with the given ID with a brand-new container created from the same configuration, preserving
+	the old container's identity: hostname, static IP on every network it was attached to, bind mounts, and volumes
+	(including anonymous volumes, which would otherwise be orphaned). The old container is stopped and removed. If the
+	recreation fails partway through, the replacement container is removed and, unless it had already been removed, the old
+	container gets its name back.
+
+Args:
+	context: The context that the recreation runs in (useful for cancellation)
+	containerId: ID of the Docker container to recreate
+	stopTimeout: How long to wait for the old container to stop before forcefully terminating it
+
+Returns:
+	The Docker container ID of the new container
+ */
+func (manager DockerManager) RecreateContainer(context context.Context, containerId string, stopTimeout time.Duration) (newContainerId string, err error) {
+	oldContainer, err := manager.dockerClient.ContainerInspect(context, containerId)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred inspecting container with ID %v", containerId)
+	}
+
+	hostConfig := *oldContainer.HostConfig
+	existingBinds := make(map[string]bool)
+	for _, bind := range hostConfig.Binds {
+		existingBinds[bind] = true
+	}
+	bindsList := append([]string{}, hostConfig.Binds...)
+	for _, mount := range oldContainer.Mounts {
+		if mount.Type != mounttypes.TypeVolume {
+			continue
+		}
+		bind := mount.Name + ":" + mount.Destination
+		if !existingBinds[bind] {
+			bindsList = append(bindsList, bind)
+		}
+	}
+	hostConfig.Binds = bindsList
+
+	if err := manager.dockerClient.ContainerStop(context, containerId, &stopTimeout); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred stopping container with ID %v before recreating it", containerId)
+	}
+	// Docker reports names with a leading "/"; the old container is renamed out of the way so the new one can take its name
+	containerName := strings.TrimPrefix(oldContainer.Name, "/")
+	if containerName != "" {
+		if err := manager.dockerClient.ContainerRename(context, containerId, containerName + replacedContainerNameSuffix); err != nil {
+			return "", stacktrace.Propagate(err, "An error occurred renaming container with ID %v so its replacement can take its name", containerId)
+		}
+	}
+	// Containers created on their network (rather than connected to it afterwards, e.g. on internal networks) get their
+	//  static IP when they're created
+	var networkingConfigPtr *network.NetworkingConfig
+	for networkName, endpointSettings := range oldContainer.NetworkSettings.Networks {
+		if string(hostConfig.NetworkMode) == endpointSettings.NetworkID || string(hostConfig.NetworkMode) == networkName {
+			networkingConfigPtr = &network.NetworkingConfig{
+				EndpointsConfig: map[string]*network.EndpointSettings{
+					networkName: getStaticIpEndpointSettings(getRequestedStaticIp(endpointSettings), getRequestedAliases(containerId, endpointSettings)),
+				},
+			}
+		}
+	}
+	resp, err := manager.dockerClient.ContainerCreate(context, oldContainer.Config, &hostConfig, networkingConfigPtr, containerName)
+	if err != nil {
+		if containerName != "" {
+			// Best-effort, so the old container is left as it was found (apart from being stopped)
+			manager.dockerClient.ContainerRename(context, containerId, containerName)
+		}
+		return "", stacktrace.Propagate(err, "An error occurred creating the container to replace container with ID %v", containerId)
+	}
+	replacementContainerId := resp.ID
+	isOldContainerRemoved := false
+	defer func() {
+		if err != nil {
+			manager.cleanUpFailedRecreation(context, containerId, containerName, replacementContainerId, isOldContainerRemoved)
+		}
+	}()
+	newContainerId = replacementContainerId
+
+	// The old container has to be removed before the new container is connected, else the static IPs would collide
+	if err := manager.dockerClient.ContainerRemove(context, containerId, types.ContainerRemoveOptions{}); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred removing container with ID %v so it could be replaced", containerId)
+	}
+	isOldContainerRemoved = true
+	newContainer, err := manager.dockerClient.ContainerInspect(context, newContainerId)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred inspecting the replacement container with ID %v", newContainerId)
+	}
+	for networkName, endpointSettings := range oldContainer.NetworkSettings.Networks {
+		// Networks implied by the network mode (e.g. the default bridge) will already have been attached by Docker
+		if _, alreadyConnected := newContainer.NetworkSettings.Networks[networkName]; alreadyConnected {
+			continue
+		}
+		aliases := getRequestedAliases(containerId, endpointSettings)
+		// Docker generates MAC addresses itself unless they're configured, so only a configured one is carried over
+		macAddress := ""
+		if endpointSettings.MacAddress == oldContainer.Config.MacAddress {
+			macAddress = oldContainer.Config.MacAddress
+		}
+		if err := manager.connectToNetwork(endpointSettings.NetworkID, newContainerId, getRequestedStaticIp(endpointSettings), aliases, macAddress); err != nil {
+			return "", stacktrace.Propagate(err, "An error occurred reconnecting the replacement container to network %v", networkName)
+		}
+	}
+	if err := manager.dockerClient.ContainerStart(context, newContainerId, types.ContainerStartOptions{}); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred starting the container replacing container with ID %v", containerId)
+	}
+	metrics.ContainersStarted.Inc()
+	return newContainerId, nil
+}
+
+/*
+Connects the given container to the given Docker network with the given static IP.
+
+Args:
+	context: The context that the connection runs in (useful for cancellation)
+	networkId: ID of the Docker network to connect the container to
+	containerId: ID of the Docker container to connect
+	staticIp: The IP that the container will have on the network
+	aliases: DNS names that other containers on the network will be able to reach the container by
+ */
+func (manager DockerManager) ConnectContainerToNetwork(
+			context context.Context,
+			networkId string,
+			containerId string,
+			staticIp net.IP,
+			aliases []string) error {
+	err := manager.dockerClient.NetworkConnect(
+		context,
+		networkId,
+		containerId,
+		getStaticIpEndpointSettings(staticIp, aliases))
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to connect container %s to network with ID %s.", containerId, networkId)
+	}
+	return nil
+}
+
+/*
+Disconnects the given container from the given Docker network, so that it can no longer talk to (or be reached by) any
+	other container on that network.
+
+Args:
+	context: The context that the disconnection runs in (useful for cancellation)
+	networkId: ID of the Docker network to disconnect the container from
+	containerId: ID of the Docker container to disconnect
+ */
+func (manager DockerManager) DisconnectContainerFromNetwork(context context.Context, networkId string, containerId string) error {
+	if err := manager.dockerClient.NetworkDisconnect(context, networkId, containerId, true); err != nil {
+		return stacktrace.Propagate(err, "Failed to disconnect container %s from network with ID %s.", containerId, networkId)
+	}
+	return nil
+}
+
+/*
+Gets the IDs of the containers currently attached to the given Docker network.
+
+Args:
+	context: The context that the lookup runs in (useful for cancellation)
+	networkId: ID of the Docker network to inspect
+
+Returns:
+	A "set" of the IDs of containers attached to the network
+ */
+func (manager DockerManager) GetContainersOnNetwork(context context.Context, networkId string) (map[string]bool, error) {
+	inspectResponse, err := manager.dockerClient.NetworkInspect(context, networkId, types.NetworkInspectOptions{})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to get network information for network with ID %v", networkId)
+	}
+	result := make(map[string]bool)
+	for containerId, _ := range inspectResponse.Containers {
+		result[containerId] = true
+	}
+	return result, nil
+}
+
+/*
+Lists the IDs of the containers (running or not) that have all of the given labels, sorted.
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	labels: The labels that returned containers must have, e.g. {TEST_VOLUME_LABEL: <test volume>} to get every service
+		container of a test
+ */
+func (manager DockerManager) ListContainers(context context.Context, labels map[string]string) ([]string, error) {
+	labelFilters := filters.NewArgs()
+	for key, value := range labels {
+		labelFilters.Add("label", key + "=" + value)
+	}
+	dockerContainers, err := manager.dockerClient.ContainerList(context, types.ContainerListOptions{
+		All:     true,
+		Filters: labelFilters,
+	})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to list Docker containers with labels %v", labels)
+	}
+
+	result := []string{}
+	for _, dockerContainer := range dockerContainers {
+		result = append(result, dockerContainer.ID)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+/*
+Blocks until the given container exits or the context is cancelled.
+
+Args:
+	context: Context the waiting will run in (useful for cancellation)
 	containerId: The ID of the Docker container that should be waited on
 
 Returns:
@@ -271,10 +1098,489 @@ func (manager DockerManager) WaitForExit(context context.Context, containerId st
 }
 
 
+/*
+Tightens the CPU cgroup of a running container so that it can use at most the given percentage of a single CPU core,
+	which is useful for simulating resource starvation.
+
+Args:
+	context: The context that the update runs in (useful for cancellation)
+	containerId: ID of the Docker container whose CPU should be limited
+	cpuPercent: The percentage of a single CPU core that the container will be allowed to use (e.g. 50 = half a core)
+ */
+func (manager DockerManager) LimitContainerCpu(context context.Context, containerId string, cpuPercent uint) error {
+	if cpuPercent == 0 {
+		return stacktrace.NewError("CPU percentage must be greater than zero, or the container will never be scheduled")
+	}
+	resources := container.Resources{
+		CPUPeriod: CPU_CFS_PERIOD_MICROSECONDS,
+		CPUQuota: int64(cpuPercent) * CPU_CFS_PERIOD_MICROSECONDS / 100,
+	}
+	if err := manager.updateContainerResources(context, containerId, resources); err != nil {
+		return stacktrace.Propagate(err, "An error occurred limiting the CPU of container with ID %v to %v%%", containerId, cpuPercent)
+	}
+	return nil
+}
+
+/*
+Removes any CPU limit previously placed on the container with LimitContainerCpu.
+
+Args:
+	context: The context that the update runs in (useful for cancellation)
+	containerId: ID of the Docker container whose CPU limit should be removed
+ */
+func (manager DockerManager) UnlimitContainerCpu(context context.Context, containerId string) error {
+	resources := container.Resources{
+		// Docker ignores a quota of 0 on update, so we pass the cgroup's "unlimited" value instead
+		CPUQuota: UNLIMITED_CPU_CFS_QUOTA,
+	}
+	if err := manager.updateContainerResources(context, containerId, resources); err != nil {
+		return stacktrace.Propagate(err, "An error occurred removing the CPU limit of container with ID %v", containerId)
+	}
+	return nil
+}
+
+/*
+Gets the percentage of a single CPU core that the container with the given ID is limited to (whether by
+	ContainerOptions.CpuPercent, LimitContainerCpu, or a container customizer), rounded up to a whole percent so that a
+	limited container is never reported as unlimited.
+
+Args:
+	context: The context that the inspection runs in (useful for cancellation)
+	containerId: ID of the Docker container whose CPU limit should be gotten
+
+Returns:
+	The container's CPU limit as a percentage of a core, or 0 if it isn't limited
+ */
+func (manager DockerManager) GetContainerCpuLimit(context context.Context, containerId string) (uint, error) {
+	containerJson, err := manager.dockerClient.ContainerInspect(context, containerId)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "An error occurred inspecting container with ID %v to get its CPU limit", containerId)
+	}
+	return getCpuPercentLimit(containerJson.HostConfig.Resources), nil
+}
+
+/*
+Takes a single snapshot of the CPU & memory usage of a running container.
+
+Args:
+	context: The context that the stats request runs in (useful for cancellation)
+	containerId: ID of the Docker container whose resource usage should be sampled
+ */
+func (manager DockerManager) GetContainerResourceUsage(context context.Context, containerId string) (ContainerResourceUsage, error) {
+	statsResponse, err := manager.dockerClient.ContainerStats(context, containerId, false)
+	if err != nil {
+		return ContainerResourceUsage{}, stacktrace.Propagate(err, "Failed to get stats for container with ID %v", containerId)
+	}
+	defer statsResponse.Body.Close()
+
+	var stats types.StatsJSON
+	if err := json.NewDecoder(statsResponse.Body).Decode(&stats); err != nil {
+		return ContainerResourceUsage{}, stacktrace.Propagate(err, "Failed to decode stats for container with ID %v", containerId)
+	}
+
+	// This is the same calculation that "docker stats" uses, where 100% means one full CPU core
+	cpuPercent := 0.0
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	numCpus := float64(stats.CPUStats.OnlineCPUs)
+	if numCpus == 0 {
+		numCpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		cpuPercent = (cpuDelta / systemDelta) * numCpus * 100
+	}
+
+	return ContainerResourceUsage{
+		CpuPercent:  cpuPercent,
+		MemoryBytes: stats.MemoryStats.Usage,
+	}, nil
+}
+
+/*
+Gets the combined STDOUT & STDERR output of a container, demultiplexed into plain text.
+
+Args:
+	context: The context that the log request runs in; when following, cancelling this will end the stream
+	containerId: ID of the Docker container whose logs should be retrieved
+	follow: If true, the returned reader will keep streaming new output until the container stops or the context is
+		cancelled; if false, the reader will end after the output produced so far
+
+Returns:
+	A reader of the container's output, which the caller is responsible for closing
+ */
+func (manager DockerManager) GetContainerLogs(context context.Context, containerId string, follow bool) (io.ReadCloser, error) {
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     follow,
+	}
+	logs, err := manager.getDemultiplexedLogs(context, containerId, options)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to get logs for container with ID %v", containerId)
+	}
+	return logs, nil
+}
+
+/*
+Gets just the STDOUT or just the STDERR output of a container, demultiplexed into plain text.
+
+Args:
+	context: The context that the log request runs in; when following, cancelling this will end the stream
+	containerId: ID of the Docker container whose logs should be retrieved
+	stream: The output stream to get
+	follow: If true, the returned reader will keep streaming new output until the container stops or the context is
+		cancelled; if false, the reader will end after the output produced so far
+
+Returns:
+	A reader of the container's output on the stream, which the caller is responsible for closing
+ */
+func (manager DockerManager) GetContainerStreamLogs(context context.Context, containerId string, stream LogStream, follow bool) (io.ReadCloser, error) {
+	if stream != STDOUT_LOG_STREAM && stream != STDERR_LOG_STREAM {
+		return nil, stacktrace.NewError("Unrecognized log stream '%v'", stream)
+	}
+	options := types.ContainerLogsOptions{
+		ShowStdout: stream == STDOUT_LOG_STREAM,
+		ShowStderr: stream == STDERR_LOG_STREAM,
+		Follow:     follow,
+	}
+	logs, err := manager.getDemultiplexedLogs(context, containerId, options)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to get the %v logs for container with ID %v", stream, containerId)
+	}
+	return logs, nil
+}
+
+/*
+Gets a stream of a container's combined STDOUT & STDERR output, starting from its last lines.
+
+Args:
+	context: The context that the log request runs in (useful for cancellation)
+	containerId: ID of the Docker container whose logs should be retrieved
+	numLines: The maximum number of existing lines to start from, counting back from the most recent line
+	follow: If true, the stream stays open and returns the container's output as it's produced, until the container
+		stops or the context is cancelled; if false, the stream ends after the existing lines
+
+Returns:
+	A stream of the logs, which the caller must close
+ */
+func (manager DockerManager) TailContainerLogs(context context.Context, containerId string, numLines uint, follow bool) (io.ReadCloser, error) {
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     follow,
+		Tail:       strconv.FormatUint(uint64(numLines), 10),
+	}
+	logs, err := manager.getDemultiplexedLogs(context, containerId, options)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to tail the last %v log lines of container with ID %v", numLines, containerId)
+	}
+	return logs, nil
+}
+
+/*
+Gets the last lines of a container's combined STDOUT & STDERR output.
+
+Args:
+	context: The context that the log request runs in (useful for cancellation)
+	containerId: ID of the Docker container whose logs should be retrieved
+	numLines: The maximum number of lines to return, counting back from the most recent line
+ */
+func (manager DockerManager) GetContainerLogTail(context context.Context, containerId string, numLines uint) ([]string, error) {
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.FormatUint(uint64(numLines), 10),
+	}
+	logs, err := manager.getDemultiplexedLogs(context, containerId, options)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to get the last %v log lines of container with ID %v", numLines, containerId)
+	}
+	defer logs.Close()
+
+	result := []string{}
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		result = append(result, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading the logs of container with ID %v", containerId)
+	}
+	return result, nil
+}
+
+/*
+Gets a summary of the current state of a container.
+
+Args:
+	context: The context that the inspect request runs in (useful for cancellation)
+	containerId: ID of the Docker container to get the status of
+ */
+func (manager DockerManager) GetContainerStatus(context context.Context, containerId string) (ContainerStatus, error) {
+	inspectResponse, err := manager.dockerClient.ContainerInspect(context, containerId)
+	if err != nil {
+		return ContainerStatus{}, stacktrace.Propagate(err, "Failed to inspect container with ID %v", containerId)
+	}
+
+	healthStatus := NO_HEALTHCHECK_STATUS
+	if inspectResponse.State.Health != nil {
+		healthStatus = inspectResponse.State.Health.Status
+	}
+	// Docker reports never-started containers as having started at the zero time, so this parses to the zero time too
+	startedAt, err := time.Parse(time.RFC3339Nano, inspectResponse.State.StartedAt)
+	if err != nil {
+		return ContainerStatus{}, stacktrace.Propagate(err, "Container with ID %v has unparseable start time %v", containerId, inspectResponse.State.StartedAt)
+	}
+	return ContainerStatus{
+		Image:        inspectResponse.Config.Image,
+		State:        inspectResponse.State.Status,
+		HealthStatus: healthStatus,
+		RestartCount: inspectResponse.RestartCount,
+		ExitCode:     inspectResponse.State.ExitCode,
+		StartedAt:    startedAt,
+		Labels:       inspectResponse.Config.Labels,
+	}, nil
+}
+
+/*
+Gets the port on the host that the given container port was published on (see ContainerOptions.GetPublishedPorts).
+
+Returns:
+	The host port, or an error if the container port wasn't published
+ */
+func (manager DockerManager) GetContainerHostPort(context context.Context, containerId string, port nat.Port) (int, error) {
+	inspectResponse, err := manager.dockerClient.ContainerInspect(context, containerId)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "Failed to inspect container with ID %v", containerId)
+	}
+	if inspectResponse.NetworkSettings == nil {
+		return 0, stacktrace.NewError("Container %v has no network settings", containerId)
+	}
+	// Docker reports ports in their "number/protocol" form, even if they were declared without a protocol
+	for _, portBinding := range inspectResponse.NetworkSettings.Ports[nat.Port(ports.Normalize(port))] {
+		if portBinding.HostPort == "" {
+			continue
+		}
+		hostPort, err := strconv.Atoi(portBinding.HostPort)
+		if err != nil {
+			return 0, stacktrace.Propagate(err, "Container %v has unparseable host port %v", containerId, portBinding.HostPort)
+		}
+		return hostPort, nil
+	}
+	return 0, stacktrace.NewError("Port %v of container %v isn't published on the host", port, containerId)
+}
+
+/*
+Gets the container's published host ports & its addresses on every network it's connected to, using a single inspect
+	(rather than the one apiece that GetContainerHostPort, GetContainerIpAddr, & GetContainerIpv6Addr take).
+ */
+func (manager DockerManager) GetContainerNetworkInfo(context context.Context, containerId string) (ContainerNetworkInfo, error) {
+	inspectResponse, err := manager.dockerClient.ContainerInspect(context, containerId)
+	if err != nil {
+		return ContainerNetworkInfo{}, stacktrace.Propagate(err, "Failed to inspect container with ID %v", containerId)
+	}
+	result := ContainerNetworkInfo{
+		HostPorts: make(map[nat.Port]int),
+		IpAddrs:   make(map[string]net.IP),
+		Ipv6Addrs: make(map[string]net.IP),
+	}
+	if inspectResponse.NetworkSettings == nil {
+		return result, nil
+	}
+	for port, portBindings := range inspectResponse.NetworkSettings.Ports {
+		for _, portBinding := range portBindings {
+			if portBinding.HostPort == "" {
+				continue
+			}
+			hostPort, err := strconv.Atoi(portBinding.HostPort)
+			if err != nil {
+				return ContainerNetworkInfo{}, stacktrace.Propagate(err, "Container %v has unparseable host port %v", containerId, portBinding.HostPort)
+			}
+			result.HostPorts[port] = hostPort
+			break
+		}
+	}
+	for _, endpointSettings := range inspectResponse.NetworkSettings.Networks {
+		if endpointSettings.IPAddress != "" {
+			ipAddr := net.ParseIP(endpointSettings.IPAddress)
+			if ipAddr == nil {
+				return ContainerNetworkInfo{}, stacktrace.NewError("Container %v has unparseable IP address %v", containerId, endpointSettings.IPAddress)
+			}
+			result.IpAddrs[endpointSettings.NetworkID] = ipAddr
+		}
+		if endpointSettings.GlobalIPv6Address != "" {
+			ipv6Addr := net.ParseIP(endpointSettings.GlobalIPv6Address)
+			if ipv6Addr == nil {
+				return ContainerNetworkInfo{}, stacktrace.NewError("Container %v has unparseable IPv6 address %v", containerId, endpointSettings.GlobalIPv6Address)
+			}
+			result.Ipv6Addrs[endpointSettings.NetworkID] = ipv6Addr
+		}
+	}
+	return result, nil
+}
+
+/*
+Gets the IPv4 address that a container has on the given network, e.g. the address Docker assigned it on a network it was
+	connected to without a static IP.
+
+Returns:
+	The container's IPv4 address, or nil if it has none on the network
+ */
+func (manager DockerManager) GetContainerIpAddr(context context.Context, containerId string, networkId string) (net.IP, error) {
+	inspectResponse, err := manager.dockerClient.ContainerInspect(context, containerId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to inspect container with ID %v", containerId)
+	}
+	if inspectResponse.NetworkSettings == nil {
+		return nil, nil
+	}
+	for _, endpointSettings := range inspectResponse.NetworkSettings.Networks {
+		if endpointSettings.NetworkID != networkId || endpointSettings.IPAddress == "" {
+			continue
+		}
+		ipAddr := net.ParseIP(endpointSettings.IPAddress)
+		if ipAddr == nil {
+			return nil, stacktrace.NewError("Container %v has unparseable IP address %v", containerId, endpointSettings.IPAddress)
+		}
+		return ipAddr, nil
+	}
+	return nil, nil
+}
+
+/*
+Gets the network aliases (i.e. the hostnames that other containers on the network can reach it by) that a container has on
+	the given network, without the alias Docker adds for the container's own short ID.
+ */
+func (manager DockerManager) GetContainerNetworkAliases(context context.Context, containerId string, networkId string) ([]string, error) {
+	inspectResponse, err := manager.dockerClient.ContainerInspect(context, containerId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to inspect container with ID %v", containerId)
+	}
+	result := []string{}
+	if inspectResponse.NetworkSettings == nil {
+		return result, nil
+	}
+	for _, endpointSettings := range inspectResponse.NetworkSettings.Networks {
+		if endpointSettings.NetworkID != networkId {
+			continue
+		}
+		for _, alias := range endpointSettings.Aliases {
+			if !strings.HasPrefix(inspectResponse.ID, alias) {
+				result = append(result, alias)
+			}
+		}
+	}
+	return result, nil
+}
+
+/*
+Gets the IPv6 address that a container has on the given network, e.g. the address Docker assigned it on a dual-stack
+	network.
+
+Returns:
+	The container's IPv6 address, or nil if it has none on the network
+ */
+func (manager DockerManager) GetContainerIpv6Addr(context context.Context, containerId string, networkId string) (net.IP, error) {
+	inspectResponse, err := manager.dockerClient.ContainerInspect(context, containerId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to inspect container with ID %v", containerId)
+	}
+	if inspectResponse.NetworkSettings == nil {
+		return nil, nil
+	}
+	for _, endpointSettings := range inspectResponse.NetworkSettings.Networks {
+		if endpointSettings.NetworkID != networkId || endpointSettings.GlobalIPv6Address == "" {
+			continue
+		}
+		ipv6Addr := net.ParseIP(endpointSettings.GlobalIPv6Address)
+		if ipv6Addr == nil {
+			return nil, stacktrace.NewError("Container %v has unparseable IPv6 address %v", containerId, endpointSettings.GlobalIPv6Address)
+		}
+		return ipv6Addr, nil
+	}
+	return nil, nil
+}
+
+/*
+Writes everything a container has output so far to the given writers, keeping STDOUT & STDERR separate.
+
+Args:
+	context: The context that the log request runs in (useful for cancellation)
+	containerId: ID of the Docker container whose logs should be written
+	stdout: The writer that the container's STDOUT will be written to
+	stderr: The writer that the container's STDERR will be written to
+ */
+func (manager DockerManager) WriteContainerLogs(context context.Context, containerId string, stdout io.Writer, stderr io.Writer) error {
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+	}
+	multiplexedLogs, err := manager.dockerClient.ContainerLogs(context, containerId, options)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get logs for container with ID %v", containerId)
+	}
+	defer multiplexedLogs.Close()
+
+	if _, err := stdcopy.StdCopy(stdout, stderr, multiplexedLogs); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the logs of container with ID %v", containerId)
+	}
+	return nil
+}
+
+/*
+Gets the raw JSON that Docker returns when inspecting a container (the same as "docker inspect" prints).
+
+Args:
+	context: The context that the inspect request runs in (useful for cancellation)
+	containerId: ID of the Docker container to inspect
+ */
+func (manager DockerManager) GetContainerInspectJson(context context.Context, containerId string) ([]byte, error) {
+	_, rawJson, err := manager.dockerClient.ContainerInspectWithRaw(context, containerId, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to inspect container with ID %v", containerId)
+	}
+	// The container's configuration includes the secrets injected as environment variables
+	return []byte(RedactSecrets(string(rawJson))), nil
+}
+
 
 // =================================================================================================================
 //                                          INSTANCE HELPER FUNCTIONS
 // =================================================================================================================
+/*
+Makes a best-effort attempt to remove a container that failed partway through being created or started, so it doesn't
+	linger holding its static IP. A fresh context is used because the failure may have been the creation context's cancellation.
+ */
+/*
+Best-effort undoes a RecreateContainer call that failed after the replacement container was created: the replacement is
+	removed so it doesn't hold the old container's name, and the old container (if it's still around) is renamed back.
+ */
+func (manager DockerManager) cleanUpFailedRecreation(
+			context context.Context,
+			oldContainerId string,
+			containerName string,
+			replacementContainerId string,
+			isOldContainerRemoved bool) {
+	// Volumes aren't removed, as they're the old container's
+	if err := manager.dockerClient.ContainerRemove(context, replacementContainerId, types.ContainerRemoveOptions{Force: true}); err != nil {
+		manager.log.Errorf("The following error occurred cleaning up container %v after it failed to replace container %v:", replacementContainerId, oldContainerId)
+		fmt.Fprintln(manager.log.Logger.Out, err)
+	}
+	if isOldContainerRemoved || containerName == "" {
+		return
+	}
+	if err := manager.dockerClient.ContainerRename(context, oldContainerId, containerName); err != nil {
+		manager.log.Errorf("The following error occurred renaming container %v back to %v after it failed to be replaced:", oldContainerId, containerName)
+		fmt.Fprintln(manager.log.Logger.Out, err)
+	}
+}
+
+func (manager DockerManager) removeFailedContainer(containerId string) {
+	if err := manager.RemoveContainer(context.Background(), containerId); err != nil {
+		manager.log.Errorf("The following error occurred cleaning up container %v after it failed to be created or started:", containerId)
+		fmt.Fprintln(manager.log.Logger.Out, err)
+	}
+}
+
 func (manager DockerManager) isImageAvailableLocally(imageName string) (isAvailable bool, err error) {
 	referenceArg := filters.Arg("reference", imageName)
 	filters := filters.NewArgs(referenceArg)
@@ -291,6 +1597,12 @@ func (manager DockerManager) isImageAvailableLocally(imageName string) (isAvaila
 }
 
 func (manager DockerManager) networkExists(networkId string) (found bool, err error) {
+	_, found, err = manager.findNetwork(networkId)
+	return found, err
+}
+
+// Gets the network with the given ID, returning false if no such network exists
+func (manager DockerManager) findNetwork(networkId string) (dockerNetwork types.NetworkResource, found bool, err error) {
 	referenceArg := filters.Arg("id", networkId)
 	filters := filters.NewArgs(referenceArg)
 	networks, err := manager.dockerClient.NetworkList(
@@ -299,36 +1611,141 @@ func (manager DockerManager) networkExists(networkId string) (found bool, err er
 			Filters: filters,
 		})
 	if err != nil {
-		return false, stacktrace.Propagate(err, "Failed to list networks.")
+		return types.NetworkResource{}, false, stacktrace.Propagate(err, "Failed to list networks.")
 	}
 	if len(networks) == 0 {
-		return false, nil
+		return types.NetworkResource{}, false, nil
 	}
-	return true, nil
+	return networks[0], true, nil
 }
 
-func (manager DockerManager) connectToNetwork(networkId string, containerId string, staticIpAddr net.IP) (err error) {
+/*
+Gets the logs of a container, stripping the headers that Docker uses to multiplex STDOUT & STDERR into a single stream
+	(which it does because our containers don't use a TTY).
+ */
+func (manager DockerManager) getDemultiplexedLogs(context context.Context, containerId string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	multiplexedLogs, err := manager.dockerClient.ContainerLogs(context, containerId, options)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to get logs for container with ID %v", containerId)
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		defer multiplexedLogs.Close()
+		_, copyErr := stdcopy.StdCopy(pipeWriter, pipeWriter, multiplexedLogs)
+		pipeWriter.CloseWithError(copyErr)
+	}()
+	return pipeReader, nil
+}
+
+func (manager DockerManager) updateContainerResources(context context.Context, containerId string, resources container.Resources) error {
+	_, err := manager.dockerClient.ContainerUpdate(context, containerId, container.UpdateConfig{
+		Resources: resources,
+	})
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to update resources of container with ID %v", containerId)
+	}
+	return nil
+}
+
+func (manager DockerManager) createNetwork(
+			context context.Context,
+			name string,
+			driver string,
+			ipamConfig []network.IPAMConfig,
+			labels map[string]string,
+			isInternal bool) (string, error) {
+	found, err := manager.networkExists(name)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred checking for existence of network with name %v", name)
+	}
+	if found {
+		// We throw an error if the network already exists because we don't know what settings that network was created
+		//  with - likely a completely different subnetMask and gatewayIP
+		return "", stacktrace.NewError("Network with name %v cannot be created because it already exists", name)
+	}
+
+	enableIpv6 := false
+	subnetMasks := []string{}
+	for _, config := range ipamConfig {
+		_, subnet, err := net.ParseCIDR(config.Subnet)
+		if err != nil {
+			return "", stacktrace.Propagate(err, "Failed to parse subnet %v of network %v", config.Subnet, name)
+		}
+		enableIpv6 = enableIpv6 || subnet.IP.To4() == nil
+		subnetMasks = append(subnetMasks, config.Subnet)
+	}
+	labelsCopy := manager.getResourceLabels(labels)
+	resp, err := manager.dockerClient.NetworkCreate(context, name, types.NetworkCreate{
+		Driver: driver,
+		// Overlay networks only accept standalone containers (rather than Swarm services) if they're attachable
+		Attachable: driver == OVERLAY_NETWORK_DRIVER,
+		Internal: isInternal,
+		EnableIPv6: enableIpv6,
+		IPAM: &network.IPAM{
+			Config: ipamConfig,
+		},
+		Labels: labelsCopy,
+	})
+	if err != nil {
+		return "", stacktrace.Propagate( err, "Failed to create network %s with subnets %v", name, subnetMasks)
+	}
+	return resp.ID, nil
+}
+
+func (manager DockerManager) connectToNetwork(
+			networkId string,
+			containerId string,
+			staticIpAddr net.IP,
+			aliases []string,
+			macAddress string) (err error) {
+	endpointSettings := getStaticIpEndpointSettings(staticIpAddr, aliases)
+	endpointSettings.MacAddress = macAddress
 	err = manager.dockerClient.NetworkConnect(
 		context.Background(),
 		networkId,
 		containerId,
-		&network.EndpointSettings{
-			IPAddress: staticIpAddr.String(),
-		})
+		endpointSettings)
 	if err != nil {
 		return stacktrace.Propagate(err, "Failed to connect container %s to network with ID %s.", containerId, networkId)
 	}
 	return nil
 }
 
-func (manager DockerManager) pullImage(context context.Context, imageName string) (err error) {
+// Pulls the given image, for the given platform ("os/arch[/variant]") or for the Docker engine's own platform if empty
+func (manager DockerManager) pullImage(context context.Context, imageName string, platform string) (err error) {
+	context, span := tracing.StartSpan(context, "PullImage")
+	span.SetAttribute("image", imageName)
+	if platform != "" {
+		span.SetAttribute("platform", platform)
+	}
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
 	manager.log.Infof("Pulling image %s...", imageName)
-	out, err := manager.dockerClient.ImagePull(context, imageName, types.ImagePullOptions{})
+	if manager.registryMirror != "" {
+		wasPulled, err := manager.pullImageThroughMirror(context, imageName, platform)
+		if err != nil {
+			manager.log.Warnf("Couldn't pull image %v through registry mirror %v, so pulling it directly instead: %v", imageName, manager.registryMirror, err)
+		} else if wasPulled {
+			return nil
+		}
+	}
+	registryAuth, err := getRegistryAuth(imageName)
 	if err != nil {
-		return stacktrace.Propagate(err, "Failed to pull image %s", imageName)
+		// The image may well be public, so it's worth trying to pull without credentials
+		manager.log.Warnf("Couldn't get the registry credentials for image %v, so pulling it without any: %v", imageName, err)
+	}
+	out, err := manager.dockerClient.ImagePull(context, imageName, types.ImagePullOptions{RegistryAuth: registryAuth, Platform: platform})
+	if err != nil {
+		return stacktrace.Propagate(wrapImagePullFailure(imageName, err), "Failed to pull image %s", imageName)
 	}
 	defer out.Close()
-	io.Copy(ioutil.Discard, out)
+	if err := readPullProgress(manager.log, imageName, out); err != nil {
+		return stacktrace.Propagate(wrapImagePullFailure(imageName, err), "Failed to pull image %s", imageName)
+	}
 	return nil
 }
 
@@ -337,29 +1754,87 @@ Creates a Docker-Container-To-Host Port mapping, defining how a Container's JSON
 mapped to the host ports.
 
 Args:
-	usedPorts: A "set" of ports that the container will listen on (and which need to be mapped to host ports)
+	usedPorts: A "set" of ports that the container will listen on (which will be mapped to random host ports if the
+		options say the ports should be published)
 	bindMounts: Mapping of (host file) -> (mountpoint on container) that will be mounted at container startup (used when
 		sharing data between the host filesystem - in our case, the test initializer - and a Docker container)
 	volumeMounts: Mapping of (volume name) -> (mountpoint on container) that will be mounted at container startup (used
 		when sharing data between containers). This is distinct from a bind mount because the host filesystem can't easily
 		read from a Docker volume - you need to be inside a Docker container to do so.
  */
-func (manager *DockerManager) getContainerHostConfig(bindMounts map[string]string, volumeMounts map[string]string) (hostConfig *container.HostConfig, err error) {
+func (manager *DockerManager) getContainerHostConfig(
+			usedPorts map[nat.Port]bool,
+			bindMounts map[string]string,
+			volumeMounts map[string]string,
+			options ContainerOptions) (hostConfig *container.HostConfig, err error) {
 	bindsList := make([]string, 0, len(bindMounts))
 	for hostFilepath, containerFilepath := range bindMounts {
-		bindsList = append(bindsList, hostFilepath + ":" + containerFilepath)
+		bindsList = append(bindsList, hostFilepath + ":" + manager.getMountDestination(containerFilepath))
 	}
 	for volumeName, containerFilepath := range volumeMounts {
 		// Yes, it's SUPER confusing that "volumes" need to be put into the "binds" section because there's
 		//  a separate thing called a "bind mount".... blame the Docker API
-		bindsList = append(bindsList, volumeName + ":" + containerFilepath)
+		bindsList = append(bindsList, volumeName + ":" + manager.getMountDestination(containerFilepath))
+	}
+	for _, volumeMount := range options.ExtraVolumeMounts {
+		bindsList = append(bindsList, getBindSpec(volumeMount.VolumeName, manager.getMountDestination(volumeMount.ContainerDirpath), volumeMount.ReadOnly))
+	}
+	for _, bindMount := range options.ExtraBindMounts {
+		bindsList = append(bindsList, getBindSpec(bindMount.HostPath, manager.getMountDestination(bindMount.ContainerPath), bindMount.ReadOnly))
 	}
 
 	manager.log.Debugf("Binds: %v", bindsList)
 
+	logDriverOptions := make(map[string]string)
+	for key, value := range options.LogDriverOptions {
+		logDriverOptions[key] = value
+	}
+
+	networkMode := container.NetworkMode("default")
+	if options.UseHostNetwork {
+		networkMode = container.NetworkMode(HOST_NETWORK_MODE)
+	}
+
+	portBindings := nat.PortMap{}
+	for port, _ := range options.GetPublishedPorts(usedPorts) {
+		// An empty host port makes Docker pick a free one
+		portBindings[port] = []nat.PortBinding{{HostIP: "", HostPort: ""}}
+	}
+
+	tmpfsMounts := make(map[string]string)
+	for containerDirpath, mountOptions := range options.TmpfsMounts {
+		tmpfsMounts[containerDirpath] = mountOptions
+	}
+
+	securityOpts, err := getSecurityOpts(options)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the container's security options")
+	}
+
+	resources, err := getContainerResources(options)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the container's resource limits")
+	}
+
 	containerHostConfigPtr := &container.HostConfig{
 		Binds: bindsList,
-		NetworkMode: container.NetworkMode("default"),
+		NetworkMode: networkMode,
+		PortBindings: portBindings,
+		ExtraHosts: append([]string{}, options.ExtraHosts...),
+		SecurityOpt: securityOpts,
+		ReadonlyRootfs: options.ReadOnlyRootFilesystem,
+		Tmpfs: tmpfsMounts,
+		Resources: resources,
+		RestartPolicy: options.RestartPolicy,
+		LogConfig: container.LogConfig{
+			Type:   options.LogDriver,
+			Config: logDriverOptions,
+		},
+	}
+	if options.UseInit {
+		// Left nil otherwise, so that the Docker engine's default applies
+		useInit := true
+		containerHostConfigPtr.Init = &useInit
 	}
 	return containerHostConfigPtr, nil
 }
@@ -369,16 +1844,24 @@ func (manager *DockerManager) getContainerCfg(
 			dockerImage string,
 			usedPorts map[nat.Port]bool,
 			startCmdArgs []string,
-			envVariables map[string]string) (config *container.Config, err error) {
+			envVariables map[string]string,
+			options ContainerOptions) (config *container.Config, err error) {
 	portSet := nat.PortSet{}
 	for port, _ := range usedPorts {
 		portSet[port] = struct{}{}
 	}
 
-	envVariablesSlice := make([]string, 0, len(envVariables))
+	envVariablesSlice := make([]string, 0, len(envVariables) + len(options.Secrets))
 	for key, val := range envVariables {
 		envVariablesSlice = append(envVariablesSlice, fmt.Sprintf("%v=%v", key, val))
 	}
+	for _, secret := range options.Secrets {
+		if secret.EnvVariable != "" {
+			envVariablesSlice = append(envVariablesSlice, secret.EnvVariable + "=" + string(secret.Value))
+		}
+	}
+
+	labels := manager.getResourceLabels(options.Labels)
 
 	nodeConfigPtr := &container.Config{
 		Tty: false,
@@ -386,6 +1869,119 @@ func (manager *DockerManager) getContainerCfg(
 		ExposedPorts: portSet,
 		Cmd: startCmdArgs,
 		Env: envVariablesSlice,
+		MacAddress: options.MacAddress,
+		Labels: labels,
+	}
+	if len(options.Entrypoint) > 0 {
+		nodeConfigPtr.Entrypoint = append([]string{}, options.Entrypoint...)
 	}
 	return nodeConfigPtr, nil
 }
+
+/*
+Gets the static IP that a container was connected to a network with, or nil if Docker assigned the container's IP (in
+	which case it can't be requested again, because Docker only allows static IPs on networks with user-specified subnets)
+ */
+func getRequestedStaticIp(endpointSettings *network.EndpointSettings) net.IP {
+	if endpointSettings.IPAMConfig == nil {
+		return nil
+	}
+	if endpointSettings.IPAMConfig.IPv4Address != "" {
+		return net.ParseIP(endpointSettings.IPAMConfig.IPv4Address)
+	}
+	if endpointSettings.IPAMConfig.IPv6Address != "" {
+		return net.ParseIP(endpointSettings.IPAMConfig.IPv6Address)
+	}
+	return nil
+}
+
+/*
+Gets the percentage of a single CPU core that the given resources limit a container to, rounded up, or 0 if they don't
+	limit its CPU
+ */
+func getCpuPercentLimit(resources container.Resources) uint {
+	if resources.NanoCPUs > 0 {
+		return uint((resources.NanoCPUs * 100 + nanoCpusPerCpu - 1) / nanoCpusPerCpu)
+	}
+	if resources.CPUQuota <= 0 {
+		return 0
+	}
+	// Docker uses the CFS default period for containers that don't set one
+	cpuPeriod := resources.CPUPeriod
+	if cpuPeriod <= 0 {
+		cpuPeriod = CPU_CFS_PERIOD_MICROSECONDS
+	}
+	return uint((resources.CPUQuota * 100 + cpuPeriod - 1) / cpuPeriod)
+}
+
+/*
+Gets the DNS aliases that the container with the given ID was connected to a network with, leaving out the container's
+	short ID, which Docker adds as an alias itself
+ */
+func getRequestedAliases(containerId string, endpointSettings *network.EndpointSettings) []string {
+	result := []string{}
+	for _, alias := range endpointSettings.Aliases {
+		if !strings.HasPrefix(containerId, alias) {
+			result = append(result, alias)
+		}
+	}
+	return result
+}
+
+/*
+Gets the settings for connecting a container to a network with the given static IP, which may be IPv4 or IPv6 (or nil
+	to let Docker assign the IP), and DNS aliases
+ */
+func getStaticIpEndpointSettings(staticIp net.IP, aliases []string) *network.EndpointSettings {
+	if staticIp == nil {
+		return &network.EndpointSettings{
+			Aliases: aliases,
+		}
+	}
+	if staticIp.To4() != nil {
+		return &network.EndpointSettings{
+			IPAMConfig: &network.EndpointIPAMConfig{
+				IPv4Address: staticIp.String(),
+			},
+			IPAddress: staticIp.String(),
+			Aliases:   aliases,
+		}
+	}
+	return &network.EndpointSettings{
+		IPAMConfig: &network.EndpointIPAMConfig{
+			IPv6Address: staticIp.String(),
+		},
+		GlobalIPv6Address: staticIp.String(),
+		Aliases:           aliases,
+	}
+}
+
+/*
+Gets the Docker "source:destination[:ro]" spec of a bind mount or volume mount
+ */
+func getBindSpec(source string, destination string, readOnly bool) string {
+	result := source + ":" + destination
+	if readOnly {
+		result += ":ro"
+	}
+	return result
+}
+
+// Gets the CPU & memory limits that the given options put on a container
+func getContainerResources(options ContainerOptions) (container.Resources, error) {
+	if options.CpuShares < 0 {
+		return container.Resources{}, stacktrace.NewError("CPU shares must not be negative, but were %v", options.CpuShares)
+	}
+	if options.MemoryLimitBytes < 0 {
+		return container.Resources{}, stacktrace.NewError("Memory limit must not be negative, but was %v bytes", options.MemoryLimitBytes)
+	}
+	resources := container.Resources{
+		CPUShares: options.CpuShares,
+		Memory: options.MemoryLimitBytes,
+	}
+	if options.CpuPercent > 0 {
+		resources.CPUPeriod = CPU_CFS_PERIOD_MICROSECONDS
+		resources.CPUQuota = int64(options.CpuPercent) * CPU_CFS_PERIOD_MICROSECONDS / 100
+	}
+	return resources, nil
+}
@@ -0,0 +1,19 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+The root cause of errors returned when a Docker image can't be pulled (e.g. because it doesn't exist or the registry is
+	unreachable). Errors in this codebase are wrapped with stacktrace, so check for it with:
+
+	errors.Is(stacktrace.RootCause(err), docker.ErrImagePullFailed)
+ */
+var ErrImagePullFailed = errors.New("failed to pull Docker image")
+
+// Wraps the given cause so that ErrImagePullFailed can be found in it with errors.Is
+func wrapImagePullFailure(imageName string, cause error) error {
+	return fmt.Errorf("%w %v: %v", ErrImagePullFailed, imageName, cause)
+}
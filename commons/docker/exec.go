@@ -0,0 +1,144 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/palantir/stacktrace"
+	"io"
+	"time"
+)
+
+/*
+The terminal that an exec session's command runs attached to (see DockerManager.ExecInteractive), e.g. to run an
+	interactive shell.
+ */
+type ExecTerminal struct {
+	// The terminal's size in rows & columns
+	Height uint
+	Width uint
+}
+
+/*
+Runs the given command in the given running container, streaming the given input to the command and its output back,
+	until the command exits or the context is cancelled.
+
+Args:
+	context: The context that the session runs in (useful for cancellation)
+	containerId: ID of the Docker container to run the command in
+	cmd: The command & its args (e.g. ["/bin/sh"])
+	terminal: The terminal to attach the command to, or nil to run it without one (in which case its STDOUT & STDERR
+		are kept separate, whereas a terminal merges them into STDOUT)
+	stdin: Input for the command (nil for none)
+	stdout: Where the command's STDOUT goes
+	stderr: Where the command's STDERR goes
+
+Returns:
+	The command's exit code
+ */
+func (manager DockerManager) ExecInteractive(
+			context context.Context,
+			containerId string,
+			cmd []string,
+			terminal *ExecTerminal,
+			stdin io.Reader,
+			stdout io.Writer,
+			stderr io.Writer) (exitCode int, err error) {
+	useTty := terminal != nil
+	createResponse, err := manager.dockerClient.ContainerExecCreate(context, containerId, types.ExecConfig{
+		Cmd:          cmd,
+		Tty:          useTty,
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "Failed to create exec of command %v in container with ID %v", cmd, containerId)
+	}
+	execId := createResponse.ID
+
+	attachResponse, err := manager.dockerClient.ContainerExecAttach(context, execId, types.ExecStartCheck{Tty: useTty})
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "Failed to start exec of command %v in container with ID %v", cmd, containerId)
+	}
+	defer attachResponse.Close()
+
+	if useTty {
+		if err := manager.dockerClient.ContainerExecResize(context, execId, types.ResizeOptions{
+			Height: terminal.Height,
+			Width:  terminal.Width,
+		}); err != nil {
+			// The session still works with the default size, just with wrapping in the wrong places
+			manager.log.Warnf("Couldn't set the size of the terminal of exec %v in container %v: %v", execId, containerId, err)
+		}
+	}
+
+	if stdin != nil {
+		go func() {
+			io.Copy(attachResponse.Conn, stdin)
+			// Closing our end of the connection tells the command its input has ended
+			attachResponse.CloseWrite()
+		}()
+	}
+
+	outputDone := make(chan error, 1)
+	go func() {
+		var copyErr error
+		if useTty {
+			_, copyErr = io.Copy(stdout, attachResponse.Reader)
+		} else {
+			_, copyErr = stdcopy.StdCopy(stdout, stderr, attachResponse.Reader)
+		}
+		outputDone <- copyErr
+	}()
+	select {
+	case copyErr := <-outputDone:
+		if copyErr != nil {
+			return 0, stacktrace.Propagate(copyErr, "An error occurred streaming the output of command %v in container with ID %v", cmd, containerId)
+		}
+	case <-context.Done():
+		return 0, stacktrace.Propagate(context.Err(), "The context was cancelled while running command %v in container with ID %v", cmd, containerId)
+	}
+
+	inspectResponse, err := manager.dockerClient.ContainerExecInspect(context, execId)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "Failed to get the exit code of command %v in container with ID %v", cmd, containerId)
+	}
+	return inspectResponse.ExitCode, nil
+}
+
+/*
+Runs the given command in the given running container to completion & captures its output, e.g. to trigger a service's
+	admin CLI or inspect its data directory.
+
+NOTE: Docker can't kill exec'd commands, so a command that times out keeps running in the container.
+
+Args:
+	ctx: The context that the command runs in (useful for cancellation)
+	containerId: ID of the Docker container to run the command in
+	cmd: The command & its args (e.g. ["ls", "/data"])
+	timeout: How long to wait for the command to exit
+
+Returns:
+	exitCode: The command's exit code (where a nonzero exit code isn't an error)
+	logs: The command's STDOUT & STDERR, interleaved in the order they were written
+ */
+func (manager DockerManager) RunExecCommand(ctx context.Context, containerId string, cmd []string, timeout time.Duration) (exitCode int, logs string, err error) {
+	if len(cmd) == 0 {
+		return 0, "", stacktrace.NewError("Can't run an empty command in container with ID %v", containerId)
+	}
+	timeoutCtx, cancelFunc := context.WithTimeout(ctx, timeout)
+	defer cancelFunc()
+
+	// Without a terminal, STDOUT & STDERR are demultiplexed one frame at a time, so the buffer is never written concurrently
+	output := &bytes.Buffer{}
+	exitCode, err = manager.ExecInteractive(timeoutCtx, containerId, cmd, nil, nil, output, output)
+	if err != nil {
+		if timeoutCtx.Err() == context.DeadlineExceeded {
+			return 0, output.String(), stacktrace.Propagate(err, "Command %v in container with ID %v didn't exit within %v", cmd, containerId, timeout)
+		}
+		return 0, output.String(), stacktrace.Propagate(err, "An error occurred running command %v in container with ID %v", cmd, containerId)
+	}
+	return exitCode, output.String(), nil
+}
@@ -0,0 +1,889 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/ports"
+	"github.com/palantir/stacktrace"
+	"io"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Docker's states for containers, as reported by the fake in ContainerStatus.State
+const (
+	FAKE_CREATED_STATE = "created"
+	FAKE_RUNNING_STATE = "running"
+	FAKE_PAUSED_STATE  = "paused"
+	FAKE_EXITED_STATE  = "exited"
+
+	// The first host port that the fake hands out to containers that publish their ports, as Docker does
+	fakeFirstHostPort = 32768
+)
+
+/*
+A call that was made against a FakeDockerManager.
+ */
+type FakeDockerCall struct {
+	// The name of the ContainerManager method that was called (e.g. "StartContainer")
+	Method string
+
+	// The ID or name of the container, network, or volume that the call acted on
+	Target string
+}
+
+/*
+The state of a container in a FakeDockerManager.
+ */
+type FakeContainer struct {
+	Id string
+
+	Image string
+
+	// One of the FAKE_*_STATE constants
+	State string
+
+	StartCmdArgs []string
+
+	EnvVariables map[string]string
+
+	Options ContainerOptions
+
+	// Mapping of network ID -> the container's IP on the network (nil if it was given a dynamic IP)
+	NetworkIps map[string]net.IP
+
+	// Mapping of container port (in "number/protocol" form) -> host port, for containers whose options publish their ports
+	HostPorts map[nat.Port]int
+
+	// The percentage of a CPU that the container is limited to, or 0 if it isn't limited
+	CpuPercentLimit uint
+
+	// The output that the container's logs report (empty unless set with SetContainerOutput)
+	Stdout string
+	Stderr string
+
+	// The exit code & output of every command exec'd in the container (0 & empty unless set with SetExecResult)
+	ExecExitCode int
+	ExecOutput string
+
+	// Every command exec'd in the container, in order
+	ExecCmds [][]string
+}
+
+/*
+An in-memory ContainerManager that doesn't touch Docker, for unit-testing code that builds test networks. The fake keeps
+	just enough state (containers, networks, & volumes) to answer queries consistently, and records every call so tests
+	can assert on what their code did. Failures can be injected with FailNext.
+
+NOTE: This is thread-safe!
+ */
+type FakeDockerManager struct {
+	mutex *sync.Mutex
+
+	// Every call made so far, in order
+	calls []FakeDockerCall
+
+	// Mapping of container ID -> container
+	containers map[string]*FakeContainer
+
+	// A "set" of the IDs of networks created through the fake
+	networks map[string]bool
+
+	// Mapping of network ID -> gateway IP, for networks whose gateway has been set with SetNetworkGatewayIp
+	gatewayIps map[string]net.IP
+
+	// A "set" of the names of existing volumes
+	volumes map[string]bool
+
+	// Mapping of method name -> error that the next call of the method will return
+	injectedErrs map[string]error
+
+	// Used for generating unique IDs
+	nextId int
+
+	// The next host port that will be handed out to a published port
+	nextHostPort int
+}
+
+/*
+Creates a new fake with no containers, networks, or volumes.
+ */
+func NewFakeDockerManager() *FakeDockerManager {
+	return &FakeDockerManager{
+		mutex:        &sync.Mutex{},
+		calls:        []FakeDockerCall{},
+		containers:   make(map[string]*FakeContainer),
+		networks:     make(map[string]bool),
+		gatewayIps:   make(map[string]net.IP),
+		volumes:      make(map[string]bool),
+		injectedErrs: make(map[string]error),
+		nextId:       0,
+		nextHostPort: fakeFirstHostPort,
+	}
+}
+
+// ================================================ Test helpers ===============================================
+/*
+Makes the next call of the given method (e.g. "CreateContainer") return the given error, without having any effect.
+ */
+func (fake *FakeDockerManager) FailNext(method string, err error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.injectedErrs[method] = err
+}
+
+/*
+Sets the gateway IP that will be reported for the given network.
+ */
+func (fake *FakeDockerManager) SetNetworkGatewayIp(networkId string, gatewayIp net.IP) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.gatewayIps[networkId] = gatewayIp
+}
+
+/*
+Sets the output that the given container's logs will report, replacing any set before.
+ */
+func (fake *FakeDockerManager) SetContainerOutput(containerId string, stdout string, stderr string) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return err
+	}
+	container.Stdout = stdout
+	container.Stderr = stderr
+	return nil
+}
+
+/*
+Sets the exit code & output that commands exec'd in the given container will give, replacing any set before.
+ */
+func (fake *FakeDockerManager) SetExecResult(containerId string, exitCode int, output string) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return err
+	}
+	container.ExecExitCode = exitCode
+	container.ExecOutput = output
+	return nil
+}
+
+/*
+Gets a copy of every call made against the fake so far, in order.
+ */
+func (fake *FakeDockerManager) GetCalls() []FakeDockerCall {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return append([]FakeDockerCall{}, fake.calls...)
+}
+
+/*
+Gets a copy of the container with the given ID, returning false if no such container exists.
+ */
+func (fake *FakeDockerManager) GetContainer(containerId string) (FakeContainer, bool) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	container, found := fake.containers[containerId]
+	if !found {
+		return FakeContainer{}, false
+	}
+	return *container, true
+}
+
+/*
+Gets the IDs of all the fake's containers, sorted.
+ */
+func (fake *FakeDockerManager) GetContainerIds() []string {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	result := []string{}
+	for containerId, _ := range fake.containers {
+		result = append(result, containerId)
+	}
+	sort.Strings(result)
+	return result
+}
+
+/*
+Gets whether a volume with the given name exists.
+ */
+func (fake *FakeDockerManager) HasVolume(volumeName string) bool {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return fake.volumes[volumeName]
+}
+
+// ================================================ Networks ===============================================
+func (fake *FakeDockerManager) CreateDynamicSubnetNetwork(context context.Context, name string, labels map[string]string) (string, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("CreateDynamicSubnetNetwork", name); err != nil {
+		return "", err
+	}
+	networkId := fake.generateId("network")
+	fake.networks[networkId] = true
+	return networkId, nil
+}
+
+func (fake *FakeDockerManager) GetNetworkGatewayIp(context context.Context, networkId string) (net.IP, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetNetworkGatewayIp", networkId); err != nil {
+		return nil, err
+	}
+	gatewayIp, found := fake.gatewayIps[networkId]
+	if !found {
+		return nil, stacktrace.NewError("No gateway IP has been set for network %v", networkId)
+	}
+	return gatewayIp, nil
+}
+
+func (fake *FakeDockerManager) GetContainersOnNetwork(context context.Context, networkId string) (map[string]bool, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainersOnNetwork", networkId); err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool)
+	for containerId, container := range fake.containers {
+		if _, found := container.NetworkIps[networkId]; found {
+			result[containerId] = true
+		}
+	}
+	return result, nil
+}
+
+func (fake *FakeDockerManager) ConnectContainerToNetwork(
+			context context.Context,
+			networkId string,
+			containerId string,
+			staticIp net.IP,
+			aliases []string) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("ConnectContainerToNetwork", containerId); err != nil {
+		return err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return err
+	}
+	if _, found := container.NetworkIps[networkId]; found {
+		return stacktrace.NewError("Container %v is already connected to network %v", containerId, networkId)
+	}
+	container.NetworkIps[networkId] = staticIp
+	return nil
+}
+
+func (fake *FakeDockerManager) DisconnectContainerFromNetwork(context context.Context, networkId string, containerId string) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("DisconnectContainerFromNetwork", containerId); err != nil {
+		return err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return err
+	}
+	if _, found := container.NetworkIps[networkId]; !found {
+		return stacktrace.NewError("Container %v isn't connected to network %v", containerId, networkId)
+	}
+	delete(container.NetworkIps, networkId)
+	return nil
+}
+
+// ================================================ Images ===============================================
+// The fake has every image, so this only records the call
+func (fake *FakeDockerManager) PullImageIfMissing(context context.Context, dockerImage string) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return fake.recordCall("PullImageIfMissing", dockerImage)
+}
+
+// ================================================ Volumes ===============================================
+func (fake *FakeDockerManager) CreateVolume(context context.Context, volumeName string, labels map[string]string) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("CreateVolume", volumeName); err != nil {
+		return err
+	}
+	fake.volumes[volumeName] = true
+	return nil
+}
+
+func (fake *FakeDockerManager) RemoveVolume(context context.Context, volumeName string, removeStoppedContainers bool) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("RemoveVolume", volumeName); err != nil {
+		return err
+	}
+	delete(fake.volumes, volumeName)
+	return nil
+}
+
+// ================================================ Container lifecycle ===============================================
+func (fake *FakeDockerManager) CreateContainer(
+			context context.Context,
+			dockerImage string,
+			networkId string,
+			staticIp net.IP,
+			usedPorts map[nat.Port]bool,
+			startCmdArgs []string,
+			envVariables map[string]string,
+			bindMounts map[string]string,
+			volumeMounts map[string]string,
+			options ContainerOptions) (string, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("CreateContainer", dockerImage); err != nil {
+		return "", err
+	}
+	if err := fake.checkStrictPolicy(dockerImage, options); err != nil {
+		return "", err
+	}
+	if err := fake.checkContainerName(options.Name); err != nil {
+		return "", err
+	}
+	if err := validatePublishedPorts(usedPorts, options); err != nil {
+		return "", err
+	}
+	startCmdArgs, envVariables, err := runFakeCustomizer(dockerImage, startCmdArgs, envVariables, options)
+	if err != nil {
+		return "", err
+	}
+	return fake.createContainer(dockerImage, networkId, staticIp, usedPorts, startCmdArgs, envVariables, volumeMounts, options), nil
+}
+
+func (fake *FakeDockerManager) CreateAndStartContainer(
+			context context.Context,
+			dockerImage string,
+			networkId string,
+			staticIp net.IP,
+			usedPorts map[nat.Port]bool,
+			startCmdArgs []string,
+			envVariables map[string]string,
+			bindMounts map[string]string,
+			volumeMounts map[string]string,
+			options ContainerOptions) (string, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("CreateAndStartContainer", dockerImage); err != nil {
+		return "", err
+	}
+	if err := fake.checkStrictPolicy(dockerImage, options); err != nil {
+		return "", err
+	}
+	if err := fake.checkContainerName(options.Name); err != nil {
+		return "", err
+	}
+	if err := validatePublishedPorts(usedPorts, options); err != nil {
+		return "", err
+	}
+	startCmdArgs, envVariables, err := runFakeCustomizer(dockerImage, startCmdArgs, envVariables, options)
+	if err != nil {
+		return "", err
+	}
+	containerId := fake.createContainer(dockerImage, networkId, staticIp, usedPorts, startCmdArgs, envVariables, volumeMounts, options)
+	fake.containers[containerId].State = FAKE_RUNNING_STATE
+	return containerId, nil
+}
+
+func (fake *FakeDockerManager) StartContainer(context context.Context, containerId string) error {
+	return fake.setContainerState("StartContainer", containerId, FAKE_RUNNING_STATE)
+}
+
+func (fake *FakeDockerManager) StopContainer(context context.Context, containerId string, timeout *time.Duration) error {
+	return fake.setContainerState("StopContainer", containerId, FAKE_EXITED_STATE)
+}
+
+func (fake *FakeDockerManager) KillContainer(context context.Context, containerId string) error {
+	return fake.setContainerState("KillContainer", containerId, FAKE_EXITED_STATE)
+}
+
+// Fake containers have no processes to handle signals, so they exit on any signal as processes do by default
+func (fake *FakeDockerManager) SignalContainer(context context.Context, containerId string, signal string) error {
+	return fake.setContainerState("SignalContainer", containerId, FAKE_EXITED_STATE)
+}
+
+func (fake *FakeDockerManager) PauseContainer(context context.Context, containerId string) error {
+	return fake.setContainerState("PauseContainer", containerId, FAKE_PAUSED_STATE)
+}
+
+func (fake *FakeDockerManager) UnpauseContainer(context context.Context, containerId string) error {
+	return fake.setContainerState("UnpauseContainer", containerId, FAKE_RUNNING_STATE)
+}
+
+func (fake *FakeDockerManager) RecreateContainer(context context.Context, containerId string, stopTimeout time.Duration) (string, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("RecreateContainer", containerId); err != nil {
+		return "", err
+	}
+	oldContainer, err := fake.getContainer(containerId)
+	if err != nil {
+		return "", err
+	}
+	newContainer := *oldContainer
+	newContainer.Id = fake.generateId("container")
+	newContainer.State = FAKE_RUNNING_STATE
+	newContainer.NetworkIps = make(map[string]net.IP)
+	for networkId, ipAddr := range oldContainer.NetworkIps {
+		newContainer.NetworkIps[networkId] = ipAddr
+	}
+	delete(fake.containers, containerId)
+	fake.containers[newContainer.Id] = &newContainer
+	return newContainer.Id, nil
+}
+
+func (fake *FakeDockerManager) RemoveContainer(context context.Context, containerId string) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("RemoveContainer", containerId); err != nil {
+		return err
+	}
+	if _, err := fake.getContainer(containerId); err != nil {
+		return err
+	}
+	delete(fake.containers, containerId)
+	return nil
+}
+
+func (fake *FakeDockerManager) LimitContainerCpu(context context.Context, containerId string, cpuPercent uint) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("LimitContainerCpu", containerId); err != nil {
+		return err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return err
+	}
+	container.CpuPercentLimit = cpuPercent
+	return nil
+}
+
+func (fake *FakeDockerManager) UnlimitContainerCpu(context context.Context, containerId string) error {
+	return fake.LimitContainerCpu(context, containerId, 0)
+}
+
+func (fake *FakeDockerManager) GetContainerCpuLimit(context context.Context, containerId string) (uint, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainerCpuLimit", containerId); err != nil {
+		return 0, err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return 0, err
+	}
+	return container.CpuPercentLimit, nil
+}
+
+// ================================================ Container inspection ===============================================
+func (fake *FakeDockerManager) GetContainerStatus(context context.Context, containerId string) (ContainerStatus, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainerStatus", containerId); err != nil {
+		return ContainerStatus{}, err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return ContainerStatus{}, err
+	}
+	labels := map[string]string{
+		MANAGED_BY_LABEL: MANAGED_BY_LABEL_VALUE,
+	}
+	for key, value := range container.Options.Labels {
+		labels[key] = value
+	}
+	return ContainerStatus{
+		Image:        container.Image,
+		State:        container.State,
+		HealthStatus: NO_HEALTHCHECK_STATUS,
+		Labels:       labels,
+	}, nil
+}
+
+func (fake *FakeDockerManager) GetContainerIpAddr(context context.Context, containerId string, networkId string) (net.IP, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainerIpAddr", containerId); err != nil {
+		return nil, err
+	}
+	return fake.getContainerIp(containerId, networkId)
+}
+
+func (fake *FakeDockerManager) GetContainerIpv6Addr(context context.Context, containerId string, networkId string) (net.IP, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainerIpv6Addr", containerId); err != nil {
+		return nil, err
+	}
+	ipAddr, err := fake.getContainerIp(containerId, networkId)
+	if err != nil {
+		return nil, err
+	}
+	if ipAddr.To4() != nil {
+		return nil, stacktrace.NewError("Container %v has no IPv6 address on network %v", containerId, networkId)
+	}
+	return ipAddr, nil
+}
+
+func (fake *FakeDockerManager) GetContainerHostPort(context context.Context, containerId string, port nat.Port) (int, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainerHostPort", containerId); err != nil {
+		return 0, err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return 0, err
+	}
+	hostPort, found := container.HostPorts[nat.Port(ports.Normalize(port))]
+	if !found {
+		return 0, stacktrace.NewError("Port %v of container %v isn't published", port, containerId)
+	}
+	return hostPort, nil
+}
+
+func (fake *FakeDockerManager) GetContainerNetworkInfo(context context.Context, containerId string) (ContainerNetworkInfo, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainerNetworkInfo", containerId); err != nil {
+		return ContainerNetworkInfo{}, err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return ContainerNetworkInfo{}, err
+	}
+	result := ContainerNetworkInfo{
+		HostPorts: make(map[nat.Port]int),
+		IpAddrs:   make(map[string]net.IP),
+		Ipv6Addrs: make(map[string]net.IP),
+	}
+	for port, hostPort := range container.HostPorts {
+		result.HostPorts[port] = hostPort
+	}
+	for networkId, ipAddr := range container.NetworkIps {
+		if ipAddr == nil {
+			continue
+		}
+		if ipAddr.To4() != nil {
+			result.IpAddrs[networkId] = ipAddr
+		} else {
+			result.Ipv6Addrs[networkId] = ipAddr
+		}
+	}
+	return result, nil
+}
+
+func (fake *FakeDockerManager) GetContainerResourceUsage(context context.Context, containerId string) (ContainerResourceUsage, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainerResourceUsage", containerId); err != nil {
+		return ContainerResourceUsage{}, err
+	}
+	if _, err := fake.getContainer(containerId); err != nil {
+		return ContainerResourceUsage{}, err
+	}
+	return ContainerResourceUsage{}, nil
+}
+
+func (fake *FakeDockerManager) GetContainerInspectJson(context context.Context, containerId string) ([]byte, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainerInspectJson", containerId); err != nil {
+		return nil, err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return nil, err
+	}
+	inspectJson, err := json.Marshal(container)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred serializing container %v", containerId)
+	}
+	return []byte(RedactSecrets(string(inspectJson))), nil
+}
+
+// Fake containers only produce the output set with SetContainerOutput, which is never followed
+func (fake *FakeDockerManager) GetContainerLogs(context context.Context, containerId string, follow bool) (io.ReadCloser, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainerLogs", containerId); err != nil {
+		return nil, err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewBufferString(container.Stdout + container.Stderr)), nil
+}
+
+// Fake containers only produce the output set with SetContainerOutput, which is never followed
+func (fake *FakeDockerManager) GetContainerStreamLogs(context context.Context, containerId string, stream LogStream, follow bool) (io.ReadCloser, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("GetContainerStreamLogs", containerId); err != nil {
+		return nil, err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return nil, err
+	}
+	switch stream {
+	case STDOUT_LOG_STREAM:
+		return ioutil.NopCloser(bytes.NewBufferString(container.Stdout)), nil
+	case STDERR_LOG_STREAM:
+		return ioutil.NopCloser(bytes.NewBufferString(container.Stderr)), nil
+	default:
+		return nil, stacktrace.NewError("Unrecognized log stream '%v'", stream)
+	}
+}
+
+// Fake containers only produce the output set with SetContainerOutput
+func (fake *FakeDockerManager) WriteContainerLogs(context context.Context, containerId string, stdout io.Writer, stderr io.Writer) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("WriteContainerLogs", containerId); err != nil {
+		return err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(stdout, container.Stdout); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the STDOUT of container %v", containerId)
+	}
+	if _, err := io.WriteString(stderr, container.Stderr); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the STDERR of container %v", containerId)
+	}
+	return nil
+}
+
+// Fake containers have empty filesystems, so this writes an empty tar archive
+func (fake *FakeDockerManager) ArchiveContainerDirectory(context context.Context, containerId string, containerDirpath string, output io.Writer) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("ArchiveContainerDirectory", containerId); err != nil {
+		return err
+	}
+	if _, err := fake.getContainer(containerId); err != nil {
+		return err
+	}
+	if err := tar.NewWriter(output).Close(); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the archive of container %v", containerId)
+	}
+	return nil
+}
+
+// Commands can only be exec'd in running containers, & give the result set with SetExecResult
+func (fake *FakeDockerManager) RunExecCommand(context context.Context, containerId string, cmd []string, timeout time.Duration) (int, string, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall("RunExecCommand", containerId); err != nil {
+		return 0, "", err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return 0, "", err
+	}
+	if container.State != FAKE_RUNNING_STATE {
+		return 0, "", stacktrace.NewError("Container %v isn't running, so commands can't be exec'd in it", containerId)
+	}
+	container.ExecCmds = append(container.ExecCmds, append([]string{}, cmd...))
+	return container.ExecExitCode, container.ExecOutput, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Records a call, returning the error injected for the method (if any). Must be called with the mutex held.
+ */
+func (fake *FakeDockerManager) recordCall(method string, target string) error {
+	fake.calls = append(fake.calls, FakeDockerCall{
+		Method: method,
+		Target: target,
+	})
+	if err, found := fake.injectedErrs[method]; found {
+		delete(fake.injectedErrs, method)
+		return stacktrace.Propagate(err, "Injected failure of %v on %v", method, target)
+	}
+	return nil
+}
+
+// Must be called with the mutex held
+func (fake *FakeDockerManager) generateId(prefix string) string {
+	fake.nextId++
+	return fmt.Sprintf("fake-%v-%v", prefix, fake.nextId)
+}
+
+// Must be called with the mutex held
+func (fake *FakeDockerManager) getContainer(containerId string) (*FakeContainer, error) {
+	container, found := fake.containers[containerId]
+	if !found {
+		return nil, stacktrace.NewError("No container with ID %v exists", containerId)
+	}
+	return container, nil
+}
+
+// Checks that the given container name is valid & not taken, as the Docker engine does; must be called with the mutex held
+func (fake *FakeDockerManager) checkContainerName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if err := validateContainerName(name); err != nil {
+		return err
+	}
+	for _, container := range fake.containers {
+		if container.Options.Name == name {
+			return stacktrace.NewError("Container name '%v' is already in use by container %v", name, container.Id)
+		}
+	}
+	return nil
+}
+
+// Must be called with the mutex held
+func (fake *FakeDockerManager) getContainerIp(containerId string, networkId string) (net.IP, error) {
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return nil, err
+	}
+	ipAddr, found := container.NetworkIps[networkId]
+	if !found || ipAddr == nil {
+		return nil, stacktrace.NewError("Container %v has no IP on network %v", containerId, networkId)
+	}
+	return ipAddr, nil
+}
+
+/*
+Enforces the image tag part of strict policy (see ContainerOptions.EnforceStrictPolicy); the fake has no real images, so
+	can't know whether containers would run as root or privileged.
+ */
+func (fake *FakeDockerManager) checkStrictPolicy(dockerImage string, options ContainerOptions) error {
+	if !options.EnforceStrictPolicy {
+		return nil
+	}
+	if err := checkStrictPolicyImage(dockerImage); err != nil {
+		return stacktrace.Propagate(err, "Image %v doesn't meet strict policy", dockerImage)
+	}
+	return nil
+}
+
+/*
+Runs the options' Customizer (if any) on a configuration holding the container's image, start command, environment, &
+	labels, as the fake has no real Docker configuration to customize; changes to the start command & environment are kept.
+ */
+func runFakeCustomizer(
+			dockerImage string,
+			startCmdArgs []string,
+			envVariables map[string]string,
+			options ContainerOptions) ([]string, map[string]string, error) {
+	if options.Customizer == nil {
+		return startCmdArgs, envVariables, nil
+	}
+	envVariablesSlice := make([]string, 0, len(envVariables))
+	for key, value := range envVariables {
+		envVariablesSlice = append(envVariablesSlice, key + "=" + value)
+	}
+	sort.Strings(envVariablesSlice)
+	labels := make(map[string]string)
+	for key, value := range options.Labels {
+		labels[key] = value
+	}
+	config := &container.Config{
+		Image:  dockerImage,
+		Cmd:    append([]string{}, startCmdArgs...),
+		Env:    envVariablesSlice,
+		Labels: labels,
+	}
+	if err := options.Customizer.CustomizeContainer(config, &container.HostConfig{}); err != nil {
+		return nil, nil, stacktrace.Propagate(err, "An error occurred customizing the container's configuration")
+	}
+	customizedEnvVariables := make(map[string]string, len(config.Env))
+	for _, envVariable := range config.Env {
+		keyAndValue := strings.SplitN(envVariable, "=", 2)
+		if len(keyAndValue) < 2 {
+			keyAndValue = append(keyAndValue, "")
+		}
+		customizedEnvVariables[keyAndValue[0]] = keyAndValue[1]
+	}
+	return config.Cmd, customizedEnvVariables, nil
+}
+
+// Must be called with the mutex held
+func (fake *FakeDockerManager) createContainer(
+			dockerImage string,
+			networkId string,
+			staticIp net.IP,
+			usedPorts map[nat.Port]bool,
+			startCmdArgs []string,
+			envVariables map[string]string,
+			volumeMounts map[string]string,
+			options ContainerOptions) string {
+	container := &FakeContainer{
+		Id:           fake.generateId("container"),
+		Image:        dockerImage,
+		State:        FAKE_CREATED_STATE,
+		StartCmdArgs: append([]string{}, startCmdArgs...),
+		EnvVariables: make(map[string]string),
+		Options:      options,
+		NetworkIps:   make(map[string]net.IP),
+		HostPorts:    make(map[nat.Port]int),
+		CpuPercentLimit: options.CpuPercent,
+	}
+	for key, value := range envVariables {
+		container.EnvVariables[key] = value
+	}
+	for _, secret := range options.Secrets {
+		knownSecretValues.add(string(secret.Value))
+		if secret.EnvVariable != "" {
+			container.EnvVariables[secret.EnvVariable] = string(secret.Value)
+		}
+	}
+	if !options.UseHostNetwork {
+		container.NetworkIps[networkId] = staticIp
+		for _, additionalNetworkId := range options.AdditionalNetworkIds {
+			container.NetworkIps[additionalNetworkId] = nil
+		}
+	}
+	for port, _ := range options.GetPublishedPorts(usedPorts) {
+		// Keyed by the "number/protocol" form that Docker reports ports in
+		container.HostPorts[nat.Port(ports.Normalize(port))] = fake.nextHostPort
+		fake.nextHostPort++
+	}
+	// Docker creates volumes that containers mount if they don't already exist
+	for volumeName, _ := range volumeMounts {
+		fake.volumes[volumeName] = true
+	}
+	for _, volumeMount := range options.ExtraVolumeMounts {
+		fake.volumes[volumeMount.VolumeName] = true
+	}
+	fake.containers[container.Id] = container
+	return container.Id
+}
+
+func (fake *FakeDockerManager) setContainerState(method string, containerId string, state string) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.recordCall(method, containerId); err != nil {
+		return err
+	}
+	container, err := fake.getContainer(containerId)
+	if err != nil {
+		return err
+	}
+	container.State = state
+	return nil
+}
@@ -0,0 +1,430 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/go-connections/nat"
+	"github.com/palantir/stacktrace"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+/*
+The failures that a FaultInjector simulates in the Docker engine, for proving that code which drives Docker (the
+	framework's own, or a test suite's) cleans up & reports errors properly when the engine misbehaves. The zero value
+	injects no faults.
+ */
+type FaultInjection struct {
+	// How long every call waits before reaching the engine, simulating a slow or overloaded daemon
+	Latency time.Duration
+
+	// If > 0, the Nth container creation (counting from 1) fails without creating anything
+	FailNthContainerCreate int
+
+	// The probability (from 0 to 1) that a call inspecting a container (e.g. GetContainerStatus) fails as if the engine
+	//  dropped the request
+	InspectDropProbability float64
+}
+
+/*
+Injects the configured faults into the calls made through the ContainerManagers that it wraps (see Wrap). Managers wrapped
+	by the same injector share its state, so e.g. "the Nth container creation" counts creations across all of them.
+
+NOTE: This is thread-safe!
+ */
+type FaultInjector struct {
+	mutex *sync.Mutex
+
+	faults FaultInjection
+
+	// Decides which inspections are dropped; seeded so that a failing run can be reproduced
+	random *rand.Rand
+
+	// The number of container creations attempted so far
+	numContainerCreates int
+
+	// The number of faults injected so far (not counting latency)
+	numInjectedFaults int
+}
+
+/*
+Creates a new injector of the given faults.
+
+Args:
+	faults: The faults to inject
+	seed: The seed for deciding which calls the probabilistic faults hit, so that runs with the same seed hit the same calls
+ */
+func NewFaultInjector(faults FaultInjection, seed int64) *FaultInjector {
+	return &FaultInjector{
+		mutex:               &sync.Mutex{},
+		faults:              faults,
+		random:              rand.New(rand.NewSource(seed)),
+		numContainerCreates: 0,
+		numInjectedFaults:   0,
+	}
+}
+
+/*
+Gets a ContainerManager that passes calls through to the given manager, injecting this injector's faults into them.
+ */
+func (injector *FaultInjector) Wrap(delegate ContainerManager) ContainerManager {
+	return &faultInjectingContainerManager{
+		injector: injector,
+		delegate: delegate,
+	}
+}
+
+/*
+Gets the number of faults that have been injected so far (not counting latency).
+ */
+func (injector *FaultInjector) GetNumInjectedFaults() int {
+	injector.mutex.Lock()
+	defer injector.mutex.Unlock()
+	return injector.numInjectedFaults
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Waits out the configured latency, returning early with an error if the given context is cancelled first
+func (injector *FaultInjector) delay(context context.Context) error {
+	if injector.faults.Latency <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(injector.faults.Latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-context.Done():
+		return stacktrace.Propagate(context.Err(), "The context was cancelled while waiting out the injected latency")
+	}
+}
+
+// Counts a container creation, returning an error if it's the one that should fail
+func (injector *FaultInjector) onContainerCreate(context context.Context, dockerImage string) error {
+	if err := injector.delay(context); err != nil {
+		return err
+	}
+	injector.mutex.Lock()
+	defer injector.mutex.Unlock()
+	injector.numContainerCreates++
+	if injector.numContainerCreates != injector.faults.FailNthContainerCreate {
+		return nil
+	}
+	injector.numInjectedFaults++
+	return stacktrace.NewError(
+		"Injected fault: failed container creation #%v (image %v)",
+		injector.numContainerCreates,
+		dockerImage)
+}
+
+// Returns an error if the given container inspection should be dropped
+func (injector *FaultInjector) onContainerInspect(context context.Context, method string, containerId string) error {
+	if err := injector.delay(context); err != nil {
+		return err
+	}
+	injector.mutex.Lock()
+	defer injector.mutex.Unlock()
+	if injector.faults.InspectDropProbability <= 0 || injector.random.Float64() >= injector.faults.InspectDropProbability {
+		return nil
+	}
+	injector.numInjectedFaults++
+	return stacktrace.NewError("Injected fault: dropped %v call for container %v", method, containerId)
+}
+
+// =========================== FAULT-INJECTING CONTAINER MANAGER =========================================
+/*
+ContainerManager which passes calls through to another manager, injecting the faults of a FaultInjector into them.
+ */
+type faultInjectingContainerManager struct {
+	injector *FaultInjector
+
+	delegate ContainerManager
+}
+
+// ------------------------------------------ Networks ----------------------------------------------------------
+func (manager *faultInjectingContainerManager) CreateDynamicSubnetNetwork(context context.Context, name string, labels map[string]string) (string, error) {
+	if err := manager.injector.delay(context); err != nil {
+		return "", err
+	}
+	return manager.delegate.CreateDynamicSubnetNetwork(context, name, labels)
+}
+
+func (manager *faultInjectingContainerManager) GetNetworkGatewayIp(context context.Context, networkId string) (net.IP, error) {
+	if err := manager.injector.delay(context); err != nil {
+		return nil, err
+	}
+	return manager.delegate.GetNetworkGatewayIp(context, networkId)
+}
+
+func (manager *faultInjectingContainerManager) GetContainersOnNetwork(context context.Context, networkId string) (map[string]bool, error) {
+	if err := manager.injector.delay(context); err != nil {
+		return nil, err
+	}
+	return manager.delegate.GetContainersOnNetwork(context, networkId)
+}
+
+func (manager *faultInjectingContainerManager) ConnectContainerToNetwork(
+			context context.Context,
+			networkId string,
+			containerId string,
+			staticIp net.IP,
+			aliases []string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.ConnectContainerToNetwork(context, networkId, containerId, staticIp, aliases)
+}
+
+func (manager *faultInjectingContainerManager) DisconnectContainerFromNetwork(context context.Context, networkId string, containerId string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.DisconnectContainerFromNetwork(context, networkId, containerId)
+}
+
+// ------------------------------------------ Images ------------------------------------------------------------
+func (manager *faultInjectingContainerManager) PullImageIfMissing(context context.Context, dockerImage string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.PullImageIfMissing(context, dockerImage)
+}
+
+// ------------------------------------------ Volumes -----------------------------------------------------------
+func (manager *faultInjectingContainerManager) CreateVolume(context context.Context, volumeName string, labels map[string]string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.CreateVolume(context, volumeName, labels)
+}
+
+func (manager *faultInjectingContainerManager) RemoveVolume(context context.Context, volumeName string, removeStoppedContainers bool) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.RemoveVolume(context, volumeName, removeStoppedContainers)
+}
+
+// ------------------------------------------ Container lifecycle -----------------------------------------------
+func (manager *faultInjectingContainerManager) CreateContainer(
+			context context.Context,
+			dockerImage string,
+			networkId string,
+			staticIp net.IP,
+			usedPorts map[nat.Port]bool,
+			startCmdArgs []string,
+			envVariables map[string]string,
+			bindMounts map[string]string,
+			volumeMounts map[string]string,
+			options ContainerOptions) (string, error) {
+	if err := manager.injector.onContainerCreate(context, dockerImage); err != nil {
+		return "", err
+	}
+	return manager.delegate.CreateContainer(
+			context,
+			dockerImage,
+			networkId,
+			staticIp,
+			usedPorts,
+			startCmdArgs,
+			envVariables,
+			bindMounts,
+			volumeMounts,
+			options)
+}
+
+func (manager *faultInjectingContainerManager) CreateAndStartContainer(
+			context context.Context,
+			dockerImage string,
+			networkId string,
+			staticIp net.IP,
+			usedPorts map[nat.Port]bool,
+			startCmdArgs []string,
+			envVariables map[string]string,
+			bindMounts map[string]string,
+			volumeMounts map[string]string,
+			options ContainerOptions) (string, error) {
+	if err := manager.injector.onContainerCreate(context, dockerImage); err != nil {
+		return "", err
+	}
+	return manager.delegate.CreateAndStartContainer(
+			context,
+			dockerImage,
+			networkId,
+			staticIp,
+			usedPorts,
+			startCmdArgs,
+			envVariables,
+			bindMounts,
+			volumeMounts,
+			options)
+}
+
+func (manager *faultInjectingContainerManager) StartContainer(context context.Context, containerId string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.StartContainer(context, containerId)
+}
+
+func (manager *faultInjectingContainerManager) StopContainer(context context.Context, containerId string, timeout *time.Duration) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.StopContainer(context, containerId, timeout)
+}
+
+func (manager *faultInjectingContainerManager) KillContainer(context context.Context, containerId string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.KillContainer(context, containerId)
+}
+
+func (manager *faultInjectingContainerManager) SignalContainer(context context.Context, containerId string, signal string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.SignalContainer(context, containerId, signal)
+}
+
+func (manager *faultInjectingContainerManager) PauseContainer(context context.Context, containerId string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.PauseContainer(context, containerId)
+}
+
+func (manager *faultInjectingContainerManager) UnpauseContainer(context context.Context, containerId string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.UnpauseContainer(context, containerId)
+}
+
+func (manager *faultInjectingContainerManager) RecreateContainer(context context.Context, containerId string, stopTimeout time.Duration) (string, error) {
+	if err := manager.injector.delay(context); err != nil {
+		return "", err
+	}
+	return manager.delegate.RecreateContainer(context, containerId, stopTimeout)
+}
+
+func (manager *faultInjectingContainerManager) RemoveContainer(context context.Context, containerId string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.RemoveContainer(context, containerId)
+}
+
+func (manager *faultInjectingContainerManager) LimitContainerCpu(context context.Context, containerId string, cpuPercent uint) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.LimitContainerCpu(context, containerId, cpuPercent)
+}
+
+func (manager *faultInjectingContainerManager) UnlimitContainerCpu(context context.Context, containerId string) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.UnlimitContainerCpu(context, containerId)
+}
+
+func (manager *faultInjectingContainerManager) GetContainerCpuLimit(context context.Context, containerId string) (uint, error) {
+	if err := manager.injector.delay(context); err != nil {
+		return 0, err
+	}
+	return manager.delegate.GetContainerCpuLimit(context, containerId)
+}
+
+// ------------------------------------------ Container inspection ----------------------------------------------
+func (manager *faultInjectingContainerManager) GetContainerStatus(context context.Context, containerId string) (ContainerStatus, error) {
+	if err := manager.injector.onContainerInspect(context, "GetContainerStatus", containerId); err != nil {
+		return ContainerStatus{}, err
+	}
+	return manager.delegate.GetContainerStatus(context, containerId)
+}
+
+func (manager *faultInjectingContainerManager) GetContainerIpAddr(context context.Context, containerId string, networkId string) (net.IP, error) {
+	if err := manager.injector.onContainerInspect(context, "GetContainerIpAddr", containerId); err != nil {
+		return nil, err
+	}
+	return manager.delegate.GetContainerIpAddr(context, containerId, networkId)
+}
+
+func (manager *faultInjectingContainerManager) GetContainerIpv6Addr(context context.Context, containerId string, networkId string) (net.IP, error) {
+	if err := manager.injector.onContainerInspect(context, "GetContainerIpv6Addr", containerId); err != nil {
+		return nil, err
+	}
+	return manager.delegate.GetContainerIpv6Addr(context, containerId, networkId)
+}
+
+func (manager *faultInjectingContainerManager) GetContainerHostPort(context context.Context, containerId string, port nat.Port) (int, error) {
+	if err := manager.injector.onContainerInspect(context, "GetContainerHostPort", containerId); err != nil {
+		return 0, err
+	}
+	return manager.delegate.GetContainerHostPort(context, containerId, port)
+}
+
+func (manager *faultInjectingContainerManager) GetContainerNetworkInfo(context context.Context, containerId string) (ContainerNetworkInfo, error) {
+	if err := manager.injector.onContainerInspect(context, "GetContainerNetworkInfo", containerId); err != nil {
+		return ContainerNetworkInfo{}, err
+	}
+	return manager.delegate.GetContainerNetworkInfo(context, containerId)
+}
+
+func (manager *faultInjectingContainerManager) GetContainerResourceUsage(context context.Context, containerId string) (ContainerResourceUsage, error) {
+	if err := manager.injector.delay(context); err != nil {
+		return ContainerResourceUsage{}, err
+	}
+	return manager.delegate.GetContainerResourceUsage(context, containerId)
+}
+
+func (manager *faultInjectingContainerManager) GetContainerInspectJson(context context.Context, containerId string) ([]byte, error) {
+	if err := manager.injector.onContainerInspect(context, "GetContainerInspectJson", containerId); err != nil {
+		return nil, err
+	}
+	return manager.delegate.GetContainerInspectJson(context, containerId)
+}
+
+func (manager *faultInjectingContainerManager) GetContainerLogs(context context.Context, containerId string, follow bool) (io.ReadCloser, error) {
+	if err := manager.injector.delay(context); err != nil {
+		return nil, err
+	}
+	return manager.delegate.GetContainerLogs(context, containerId, follow)
+}
+
+func (manager *faultInjectingContainerManager) GetContainerStreamLogs(context context.Context, containerId string, stream LogStream, follow bool) (io.ReadCloser, error) {
+	if err := manager.injector.delay(context); err != nil {
+		return nil, err
+	}
+	return manager.delegate.GetContainerStreamLogs(context, containerId, stream, follow)
+}
+
+func (manager *faultInjectingContainerManager) WriteContainerLogs(context context.Context, containerId string, stdout io.Writer, stderr io.Writer) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.WriteContainerLogs(context, containerId, stdout, stderr)
+}
+
+func (manager *faultInjectingContainerManager) ArchiveContainerDirectory(context context.Context, containerId string, containerDirpath string, output io.Writer) error {
+	if err := manager.injector.delay(context); err != nil {
+		return err
+	}
+	return manager.delegate.ArchiveContainerDirectory(context, containerId, containerDirpath, output)
+}
+
+// ------------------------------------------ Exec --------------------------------------------------------------
+func (manager *faultInjectingContainerManager) RunExecCommand(
+			context context.Context,
+			containerId string,
+			cmd []string,
+			timeout time.Duration) (exitCode int, logs string, err error) {
+	if err := manager.injector.delay(context); err != nil {
+		return 0, "", err
+	}
+	return manager.delegate.RunExecCommand(context, containerId, cmd, timeout)
+}
@@ -0,0 +1,56 @@
+package docker
+
+import (
+	"context"
+	"gotest.tools/v3/assert"
+	"testing"
+	"time"
+)
+
+func TestFailingNthContainerCreate(t *testing.T) {
+	fake := NewFakeDockerManager()
+	injector := NewFaultInjector(FaultInjection{FailNthContainerCreate: 2}, 0)
+	manager := injector.Wrap(fake)
+	parentCtx := context.Background()
+
+	_, err := manager.CreateContainer(parentCtx, "node:latest", "", nil, nil, nil, nil, nil, nil, ContainerOptions{})
+	assert.NilError(t, err)
+	_, err = manager.CreateAndStartContainer(parentCtx, "node:latest", "", nil, nil, nil, nil, nil, nil, ContainerOptions{})
+	assert.ErrorContains(t, err, "Injected fault: failed container creation #2")
+	_, err = manager.CreateContainer(parentCtx, "node:latest", "", nil, nil, nil, nil, nil, nil, ContainerOptions{})
+	assert.NilError(t, err)
+
+	// The failed creation never reaches the engine
+	assert.Equal(t, 2, len(fake.GetContainerIds()))
+	assert.Equal(t, 1, injector.GetNumInjectedFaults())
+}
+
+func TestDroppingInspects(t *testing.T) {
+	fake := NewFakeDockerManager()
+	parentCtx := context.Background()
+	containerId, err := fake.CreateAndStartContainer(parentCtx, "node:latest", "", nil, nil, nil, nil, nil, nil, ContainerOptions{})
+	assert.NilError(t, err)
+
+	droppingManager := NewFaultInjector(FaultInjection{InspectDropProbability: 1}, 0).Wrap(fake)
+	_, err = droppingManager.GetContainerStatus(parentCtx, containerId)
+	assert.ErrorContains(t, err, "Injected fault: dropped GetContainerStatus call")
+	// Calls that don't inspect the container aren't dropped
+	assert.NilError(t, droppingManager.StopContainer(parentCtx, containerId, nil))
+
+	status, err := NewFaultInjector(FaultInjection{}, 0).Wrap(fake).GetContainerStatus(parentCtx, containerId)
+	assert.NilError(t, err)
+	assert.Equal(t, FAKE_EXITED_STATE, status.State)
+}
+
+func TestInjectingLatency(t *testing.T) {
+	latency := 50 * time.Millisecond
+	manager := NewFaultInjector(FaultInjection{Latency: latency}, 0).Wrap(NewFakeDockerManager())
+
+	startTime := time.Now()
+	assert.NilError(t, manager.CreateVolume(context.Background(), "test-volume", map[string]string{}))
+	assert.Assert(t, time.Since(startTime) >= latency)
+
+	cancelledCtx, cancelFunc := context.WithCancel(context.Background())
+	cancelFunc()
+	assert.ErrorContains(t, manager.CreateVolume(cancelledCtx, "other-volume", map[string]string{}), "cancelled")
+}
@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/palantir/stacktrace"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// The permissions that a fixture file gets if its mode isn't set
+	DEFAULT_FIXTURE_MODE os.FileMode = 0644
+)
+
+/*
+A file that will be placed in a container's filesystem after the container is created but before it's started (e.g. a
+	config file), so that it's in place without rebuilding the image or bind-mounting anything.
+ */
+type FileFixture struct {
+	// Path, on the machine running this code, of the file whose contents will be placed in the container; if empty,
+	//  Contents is used instead
+	SourceFilepath string
+
+	// The contents of the file, used if SourceFilepath is empty
+	Contents []byte
+
+	// Absolute path in the container that the file will be placed at; missing parent directories will be created
+	ContainerFilepath string
+
+	// The permissions of the file in the container (DEFAULT_FIXTURE_MODE if 0)
+	Mode os.FileMode
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (manager DockerManager) injectFixturesToContainer(context context.Context, containerId string, fixtures []FileFixture) error {
+	if len(fixtures) == 0 {
+		return nil
+	}
+
+	archive := &bytes.Buffer{}
+	if err := writeFixturesArchive(fixtures, archive); err != nil {
+		return stacktrace.Propagate(err, "An error occurred archiving the file fixtures")
+	}
+	if err := manager.dockerClient.CopyToContainer(context, containerId, "/", archive, types.CopyToContainerOptions{}); err != nil {
+		return stacktrace.Propagate(err, "Failed to copy file fixtures into container with ID %v", containerId)
+	}
+	return nil
+}
+
+/*
+Writes the given fixtures to a tar archive whose entries are relative to the container's root directory.
+ */
+func writeFixturesArchive(fixtures []FileFixture, output io.Writer) error {
+	tarWriter := tar.NewWriter(output)
+	for _, fixture := range fixtures {
+		if !path.IsAbs(fixture.ContainerFilepath) || strings.HasSuffix(fixture.ContainerFilepath, "/") {
+			return stacktrace.NewError("Fixture container filepath '%v' must be an absolute path to a file", fixture.ContainerFilepath)
+		}
+
+		contents := fixture.Contents
+		if fixture.SourceFilepath != "" {
+			sourceContents, err := ioutil.ReadFile(fixture.SourceFilepath)
+			if err != nil {
+				return stacktrace.Propagate(err, "An error occurred reading fixture file %v", fixture.SourceFilepath)
+			}
+			contents = sourceContents
+		}
+		mode := fixture.Mode
+		if mode == 0 {
+			mode = DEFAULT_FIXTURE_MODE
+		}
+
+		header := &tar.Header{
+			Name:     strings.TrimPrefix(path.Clean(fixture.ContainerFilepath), "/"),
+			Mode:     int64(mode.Perm()),
+			Size:     int64(len(contents)),
+			ModTime:  time.Now(),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return stacktrace.Propagate(err, "An error occurred writing the archive header for fixture %v", fixture.ContainerFilepath)
+		}
+		if _, err := tarWriter.Write(contents); err != nil {
+			return stacktrace.Propagate(err, "An error occurred writing the contents of fixture %v", fixture.ContainerFilepath)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return stacktrace.Propagate(err, "An error occurred closing the fixtures archive")
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestWritingFixturesArchive(t *testing.T) {
+	sourceDirpath, err := ioutil.TempDir("", "fixtures")
+	assert.NilError(t, err)
+	defer os.RemoveAll(sourceDirpath)
+	sourceFilepath := path.Join(sourceDirpath, "genesis.json")
+	assert.NilError(t, ioutil.WriteFile(sourceFilepath, []byte("{}"), 0600))
+
+	fixtures := []FileFixture{
+		{SourceFilepath: sourceFilepath, ContainerFilepath: "/etc/node/genesis.json"},
+		{Contents: []byte("#!/bin/sh"), ContainerFilepath: "/usr/local/bin/start.sh", Mode: 0755},
+	}
+	archive := &bytes.Buffer{}
+	assert.NilError(t, writeFixturesArchive(fixtures, archive))
+
+	tarReader := tar.NewReader(archive)
+	header, err := tarReader.Next()
+	assert.NilError(t, err)
+	assert.Equal(t, "etc/node/genesis.json", header.Name)
+	assert.Equal(t, int64(DEFAULT_FIXTURE_MODE), header.Mode)
+	contents, err := ioutil.ReadAll(tarReader)
+	assert.NilError(t, err)
+	assert.Equal(t, "{}", string(contents))
+
+	header, err = tarReader.Next()
+	assert.NilError(t, err)
+	assert.Equal(t, "usr/local/bin/start.sh", header.Name)
+	assert.Equal(t, int64(0755), header.Mode)
+	contents, err = ioutil.ReadAll(tarReader)
+	assert.NilError(t, err)
+	assert.Equal(t, "#!/bin/sh", string(contents))
+}
+
+func TestFixturesMustHaveAbsoluteFilepaths(t *testing.T) {
+	fixtures := []FileFixture{{Contents: []byte{}, ContainerFilepath: "relative/path"}}
+	assert.Assert(t, writeFixturesArchive(fixtures, &bytes.Buffer{}) != nil)
+}
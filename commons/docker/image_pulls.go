@@ -0,0 +1,120 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io"
+	"sort"
+	"sync"
+)
+
+/*
+Makes the manager pull each image the first time it's used, even if it's already available locally, so that tests run
+	against the latest pushed version of mutable tags (e.g. "latest" or "main"). Each image is only pulled once per
+	manager, rather than once per container.
+ */
+func (manager *DockerManager) AlwaysPullImages() {
+	manager.alwaysPulledImages = &sync.Map{}
+}
+
+/*
+Pulls each of the given images that isn't yet available onto each of the given Docker hosts, all in parallel, which is
+	much faster on a cold image cache than pulling them one at a time.
+
+NOTE: The pulls are best-effort parallel: each Docker engine limits how many layers it downloads at once.
+
+Args:
+	context: The Context that the pulls run in (useful for cancellation)
+	dockerManagers: The managers of the Docker hosts to pull the images onto
+	images: A "set" of the images to pull
+
+Returns:
+	An error naming every image that couldn't be pulled, whose root cause is the first such image's pull error
+ */
+func PullImagesInParallel(context context.Context, dockerManagers []ContainerManager, images map[string]bool) error {
+	resultsMutex := &sync.Mutex{}
+	pullErrs := make(map[string]error)
+	waitGroup := &sync.WaitGroup{}
+	for image, _ := range images {
+		for _, dockerManager := range dockerManagers {
+			waitGroup.Add(1)
+			go func(image string, dockerManager ContainerManager) {
+				defer waitGroup.Done()
+				if err := dockerManager.PullImageIfMissing(context, image); err != nil {
+					resultsMutex.Lock()
+					defer resultsMutex.Unlock()
+					if _, found := pullErrs[image]; !found {
+						pullErrs[image] = err
+					}
+				}
+			}(image, dockerManager)
+		}
+	}
+	waitGroup.Wait()
+
+	if len(pullErrs) == 0 {
+		return nil
+	}
+	failedImages := make([]string, 0, len(pullErrs))
+	for image, _ := range pullErrs {
+		failedImages = append(failedImages, image)
+	}
+	sort.Strings(failedImages)
+	return stacktrace.Propagate(pullErrs[failedImages[0]], "An error occurred pulling images %v", failedImages)
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// A message from the progress stream of an image pull
+type pullProgressMessage struct {
+	// The ID of the layer the message is about, if any
+	Id string `json:"id"`
+
+	// E.g. "Pulling fs layer", "Downloading", or "Download complete"
+	Status string `json:"status"`
+
+	// The error that the pull failed with, if any
+	Error string `json:"error"`
+}
+
+// Pulls the given image if the manager, which always pulls images, hasn't already pulled it
+func (manager DockerManager) pullImageOnce(context context.Context, dockerImage string) error {
+	if _, alreadyPulled := manager.alwaysPulledImages.LoadOrStore(dockerImage, true); alreadyPulled {
+		return nil
+	}
+	if err := manager.pullImage(context, dockerImage, ""); err != nil {
+		// Let a later use retry the pull
+		manager.alwaysPulledImages.Delete(dockerImage)
+		return stacktrace.Propagate(err, "Failed to pull Docker image %v from remote image repository", dockerImage)
+	}
+	return nil
+}
+
+/*
+Reads the progress stream of an image pull until the pull finishes, logging each layer's progress, and returns the error
+	the pull failed with if any; the Docker engine reports failures (e.g. a registry denying access partway through)
+	in the stream, rather than failing the pull request.
+ */
+func readPullProgress(log *logrus.Entry, imageName string, progress io.Reader) error {
+	decoder := json.NewDecoder(progress)
+	// Layers report their download progress many times, so only the changes in status are worth logging
+	layerStatuses := make(map[string]string)
+	for {
+		var message pullProgressMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return stacktrace.Propagate(err, "An error occurred reading the progress of the pull of image %v", imageName)
+		}
+		if message.Error != "" {
+			return stacktrace.NewError("%v", message.Error)
+		}
+		if message.Id == "" {
+			log.Debugf("Pulling image %v: %v", imageName, message.Status)
+		} else if layerStatuses[message.Id] != message.Status {
+			layerStatuses[message.Id] = message.Status
+			log.Debugf("Pulling image %v: layer %v: %v", imageName, message.Id, message.Status)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/client"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAlwaysPullingImages(t *testing.T) {
+	numPulls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		switch request.URL.Path {
+		case "/v1.40/images/json":
+			// The image is always available locally
+			writer.Write([]byte(`[{"Id": "sha256:abc", "RepoTags": ["node:latest"]}]`))
+		case "/v1.40/images/create":
+			numPulls++
+			writer.Write([]byte(`{"status": "Pulling from library/node", "id": "latest"}` + "\n"))
+			writer.Write([]byte(`{"status": "Downloading", "id": "layer1", "progressDetail": {"current": 1, "total": 2}}` + "\n"))
+			writer.Write([]byte(`{"status": "Download complete", "id": "layer1"}` + "\n"))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	manager := newTestServerDockerManager(t, server)
+	parentCtx := context.Background()
+
+	assert.NilError(t, manager.PullImageIfMissing(parentCtx, "node:latest"))
+	assert.Equal(t, 0, numPulls)
+
+	manager.AlwaysPullImages()
+	assert.NilError(t, manager.PullImageIfMissing(parentCtx, "node:latest"))
+	assert.NilError(t, manager.PullImageIfMissing(parentCtx, "node:latest"))
+	// Each image is only pulled once per manager
+	assert.Equal(t, 1, numPulls)
+}
+
+func TestPullErrorsInProgressStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		if request.URL.Path == "/v1.40/images/json" {
+			writer.Write([]byte(`[]`))
+			return
+		}
+		writer.Write([]byte(`{"status": "Pulling from private/node", "id": "latest"}` + "\n"))
+		writer.Write([]byte(`{"errorDetail": {"message": "denied: requested access to the resource is denied"}, "error": "denied: requested access to the resource is denied"}` + "\n"))
+	}))
+	defer server.Close()
+	manager := newTestServerDockerManager(t, server)
+
+	err := manager.PullImageIfMissing(context.Background(), "private/node:latest")
+	assert.ErrorContains(t, err, "requested access to the resource is denied")
+	assert.Assert(t, errors.Is(stacktrace.RootCause(err), ErrImagePullFailed))
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func newTestServerDockerManager(t *testing.T, server *httptest.Server) *DockerManager {
+	dockerClient, err := client.NewClientWithOpts(
+		client.WithHost("tcp://" + strings.TrimPrefix(server.URL, "http://")),
+		client.WithVersion("1.40"))
+	assert.NilError(t, err)
+	manager, err := NewDockerManager(logrus.NewEntry(logrus.StandardLogger()), dockerClient)
+	assert.NilError(t, err)
+	return manager
+}
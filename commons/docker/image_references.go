@@ -0,0 +1,22 @@
+package docker
+
+import (
+	"github.com/docker/distribution/reference"
+	"github.com/palantir/stacktrace"
+)
+
+/*
+Gets the given image with its tag (or digest) replaced by the given tag, e.g. "avaplatform/gecko:v0.5.7" & "v0.6.0" ->
+	"avaplatform/gecko:v0.6.0", for running different versions of a service's image.
+ */
+func WithImageTag(imageName string, tag string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Couldn't parse image name %v", imageName)
+	}
+	tagged, err := reference.WithTag(reference.TrimNamed(named), tag)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Couldn't tag image %v with tag '%v'", imageName, tag)
+	}
+	return reference.FamiliarString(tagged), nil
+}
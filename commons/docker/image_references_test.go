@@ -0,0 +1,19 @@
+package docker
+
+import (
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestWithImageTag(t *testing.T) {
+	versionedImage, err := WithImageTag("avaplatform/gecko:v0.5.7", "v0.6.0")
+	assert.NilError(t, err)
+	assert.Equal(t, "avaplatform/gecko:v0.6.0", versionedImage)
+
+	versionedImage, err = WithImageTag("localhost:5000/node@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", "v2")
+	assert.NilError(t, err)
+	assert.Equal(t, "localhost:5000/node:v2", versionedImage)
+
+	_, err = WithImageTag("alpine", "not a tag")
+	assert.ErrorContains(t, err, "Couldn't tag image")
+}
@@ -0,0 +1,198 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/palantir/stacktrace"
+	"sort"
+	"time"
+)
+
+/*
+A tag of a local image, in one of the repositories whose images the framework pulls (see ListManagedImages).
+ */
+type ManagedImage struct {
+	// The image's repository, in Docker's short form (e.g. "postgres" or "avaplatform/gecko")
+	Repository string
+
+	Tag string
+
+	// The ID of the image that the tag points to, which several tags may share
+	ImageId string
+
+	Created time.Time
+
+	SizeBytes int64
+
+	// True if a container (running or not) was created from the image, in which case the image can't be removed
+	InUse bool
+}
+
+/*
+Gets the image reference of the tag, e.g. "postgres:13".
+ */
+func (image ManagedImage) GetReference() string {
+	return image.Repository + ":" + image.Tag
+}
+
+/*
+Which local images to remove when cleaning up, so that hosts running many test suites (e.g. CI hosts) don't slowly fill
+	their disks with pulled images. An image is removed if either limit says so, unless a container uses it. Zero values
+	disable the corresponding limits.
+ */
+type ImageRetentionPolicy struct {
+	// How many of each repository's tags to keep, most recently created first
+	KeepLastTags int
+
+	// How long after being created images are kept
+	MaxAge time.Duration
+}
+
+/*
+Lists the tags of the local images in the given repositories, sorted by repository & then most recently created first.
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	repositories: A "set" of the repositories whose images are managed, as repository names or image references (e.g. a
+		NetworkPlan's Images), since images can't be labelled with the MANAGED_BY_LABEL when they're pulled
+ */
+func (manager DockerManager) ListManagedImages(context context.Context, repositories map[string]bool) ([]ManagedImage, error) {
+	normalizedRepositories := make(map[string]bool)
+	for repository, _ := range repositories {
+		normalizedRepository, err := normalizeRepository(repository)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "Invalid repository '%v'", repository)
+		}
+		normalizedRepositories[normalizedRepository] = true
+	}
+
+	imageSummaries, err := manager.dockerClient.ImageList(context, types.ImageListOptions{})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred listing the local Docker images")
+	}
+	containers, err := manager.dockerClient.ContainerList(context, types.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred listing the Docker containers using the local images")
+	}
+	imageIdsInUse := make(map[string]bool)
+	for _, dockerContainer := range containers {
+		imageIdsInUse[dockerContainer.ImageID] = true
+	}
+
+	result := []ManagedImage{}
+	for _, imageSummary := range imageSummaries {
+		for _, repoTag := range imageSummary.RepoTags {
+			named, err := reference.ParseNormalizedNamed(repoTag)
+			if err != nil {
+				// E.g. the "<none>:<none>" tag of dangling images
+				continue
+			}
+			tagged, isTagged := named.(reference.Tagged)
+			repository := reference.FamiliarName(named)
+			if !isTagged || !normalizedRepositories[repository] {
+				continue
+			}
+			result = append(result, ManagedImage{
+				Repository: repository,
+				Tag:        tagged.Tag(),
+				ImageId:    imageSummary.ID,
+				Created:    time.Unix(imageSummary.Created, 0),
+				SizeBytes:  imageSummary.Size,
+				InUse:      imageIdsInUse[imageSummary.ID],
+			})
+		}
+	}
+	sortManagedImages(result)
+	return result, nil
+}
+
+/*
+Removes the tags of the local images in the given repositories that the given policy doesn't retain (along with the
+	images themselves, once their last tag is removed), carrying on past failures so as much as possible is cleaned up.
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	repositories: A "set" of the repositories whose images are managed (see ListManagedImages)
+	policy: Which images to keep
+
+Returns:
+	The images that were removed, and an error naming every image that couldn't be, whose root cause is the first such
+		image's removal error
+ */
+func (manager DockerManager) ApplyImageRetentionPolicy(
+			context context.Context,
+			repositories map[string]bool,
+			policy ImageRetentionPolicy) ([]ManagedImage, error) {
+	images, err := manager.ListManagedImages(context, repositories)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred listing the managed images")
+	}
+
+	removed := []ManagedImage{}
+	var firstRemovalErr error
+	failedReferences := []string{}
+	for _, image := range getExpiredImages(images, policy, time.Now()) {
+		// Removing by reference rather than ID only untags images that other tags still point to
+		if _, err := manager.dockerClient.ImageRemove(context, image.GetReference(), types.ImageRemoveOptions{PruneChildren: true}); err != nil {
+			if firstRemovalErr == nil {
+				firstRemovalErr = err
+			}
+			failedReferences = append(failedReferences, image.GetReference())
+			continue
+		}
+		manager.log.Debugf("Removed image %v, which the image retention policy doesn't retain", image.GetReference())
+		removed = append(removed, image)
+	}
+	if firstRemovalErr != nil {
+		return removed, stacktrace.Propagate(firstRemovalErr, "An error occurred removing images %v", failedReferences)
+	}
+	return removed, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets the given images, sorted as ListManagedImages returns them, that the given policy doesn't retain as of the given
+	time, skipping the ones in use.
+ */
+func getExpiredImages(images []ManagedImage, policy ImageRetentionPolicy, now time.Time) []ManagedImage {
+	sortedImages := append([]ManagedImage{}, images...)
+	sortManagedImages(sortedImages)
+
+	result := []ManagedImage{}
+	numTagsSeen := make(map[string]int)
+	for _, image := range sortedImages {
+		numTagsSeen[image.Repository]++
+		if image.InUse {
+			continue
+		}
+		isBeyondKeptTags := policy.KeepLastTags > 0 && numTagsSeen[image.Repository] > policy.KeepLastTags
+		isTooOld := policy.MaxAge > 0 && now.Sub(image.Created) > policy.MaxAge
+		if isBeyondKeptTags || isTooOld {
+			result = append(result, image)
+		}
+	}
+	return result
+}
+
+// Sorts the given images by repository, then most recently created first, then by tag
+func sortManagedImages(images []ManagedImage) {
+	sort.SliceStable(images, func(i, j int) bool {
+		if images[i].Repository != images[j].Repository {
+			return images[i].Repository < images[j].Repository
+		}
+		if !images[i].Created.Equal(images[j].Created) {
+			return images[i].Created.After(images[j].Created)
+		}
+		return images[i].Tag < images[j].Tag
+	})
+}
+
+// Gets the short form of the repository of the given repository name or image reference, e.g. "docker.io/library/postgres:13" -> "postgres"
+func normalizeRepository(repository string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(repository)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Couldn't parse repository '%v'", repository)
+	}
+	return reference.FamiliarName(named), nil
+}
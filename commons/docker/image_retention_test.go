@@ -0,0 +1,47 @@
+package docker
+
+import (
+	"gotest.tools/v3/assert"
+	"testing"
+	"time"
+)
+
+func TestGetExpiredImages(t *testing.T) {
+	now := time.Now()
+	images := []ManagedImage{
+		{Repository: "postgres", Tag: "11", Created: now.Add(-3 * time.Hour)},
+		{Repository: "postgres", Tag: "13", Created: now.Add(-1 * time.Hour)},
+		{Repository: "postgres", Tag: "12", Created: now.Add(-2 * time.Hour), InUse: true},
+		{Repository: "avaplatform/gecko", Tag: "v0.5.7", Created: now.Add(-48 * time.Hour)},
+	}
+
+	// The in-use tag counts towards the kept tags, but is never removed
+	keepOne := getExpiredImages(images, ImageRetentionPolicy{KeepLastTags: 1}, now)
+	assert.DeepEqual(t, []string{"postgres:11"}, getReferences(keepOne))
+
+	maxAge := getExpiredImages(images, ImageRetentionPolicy{MaxAge: 24 * time.Hour}, now)
+	assert.DeepEqual(t, []string{"avaplatform/gecko:v0.5.7"}, getReferences(maxAge))
+
+	both := getExpiredImages(images, ImageRetentionPolicy{KeepLastTags: 2, MaxAge: 150 * time.Minute}, now)
+	assert.DeepEqual(t, []string{"avaplatform/gecko:v0.5.7", "postgres:11"}, getReferences(both))
+
+	assert.Equal(t, 0, len(getExpiredImages(images, ImageRetentionPolicy{}, now)))
+}
+
+func TestNormalizeRepository(t *testing.T) {
+	for _, repository := range []string{"postgres", "postgres:13", "docker.io/library/postgres"} {
+		normalized, err := normalizeRepository(repository)
+		assert.NilError(t, err)
+		assert.Equal(t, "postgres", normalized)
+	}
+	_, err := normalizeRepository("Not A Repository")
+	assert.ErrorContains(t, err, "Couldn't parse")
+}
+
+func getReferences(images []ManagedImage) []string {
+	result := []string{}
+	for _, image := range images {
+		result = append(result, image.GetReference())
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"gotest.tools/v3/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContainersOnInternalNetworksArentOnTheDefaultBridge(t *testing.T) {
+	networkMode, endpointsConfig, connectedNetworkIds := createTestContainerOnInternalNetwork(t, ContainerOptions{})
+	// Containers on the default bridge network could reach anything outside the internal network
+	assert.Equal(t, container.NetworkMode("internal-network"), networkMode)
+	assert.Equal(t, 1, len(endpointsConfig))
+	assert.Equal(t, "172.23.0.2", endpointsConfig["internal-network"].IPAMConfig.IPv4Address)
+	assert.DeepEqual(t, []string{"service"}, endpointsConfig["internal-network"].Aliases)
+	assert.Equal(t, 0, len(connectedNetworkIds))
+}
+
+func TestContainersCanAskForTheDefaultBridge(t *testing.T) {
+	networkMode, endpointsConfig, connectedNetworkIds := createTestContainerOnInternalNetwork(t, ContainerOptions{UseDefaultBridgeNetwork: true})
+	assert.Equal(t, container.NetworkMode("default"), networkMode)
+	assert.Equal(t, 0, len(endpointsConfig))
+	assert.DeepEqual(t, []string{"internal-network"}, connectedNetworkIds)
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Creates a container on an internal network, returning the network mode & endpoints that it was created with, and the IDs
+	of the networks that it was connected to after it was created
+ */
+func createTestContainerOnInternalNetwork(
+			t *testing.T,
+			options ContainerOptions) (container.NetworkMode, map[string]*network.EndpointSettings, []string) {
+	createRequest := struct {
+		HostConfig       container.HostConfig
+		NetworkingConfig network.NetworkingConfig
+	}{}
+	connectedNetworkIds := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		switch request.URL.Path {
+		case "/v1.40/images/json":
+			writer.Write([]byte(`[{"Id": "sha256:abc", "RepoTags": ["test:1.0"]}]`))
+		case "/v1.40/networks":
+			writer.Write([]byte(`[{"Id": "internal-network", "Internal": true}]`))
+		case "/v1.40/containers/create":
+			assert.NilError(t, json.NewDecoder(request.Body).Decode(&createRequest))
+			writer.WriteHeader(http.StatusCreated)
+			writer.Write([]byte(`{"Id": "container"}`))
+		case "/v1.40/networks/internal-network/connect":
+			connectedNetworkIds = append(connectedNetworkIds, "internal-network")
+			writer.WriteHeader(http.StatusOK)
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	manager := newTestServerDockerManager(t, server)
+
+	options.NetworkAliases = []string{"service"}
+	_, err := manager.CreateContainer(
+		context.Background(),
+		"test:1.0",
+		"internal-network",
+		net.ParseIP("172.23.0.2"),
+		nil,
+		nil,
+		map[string]string{},
+		map[string]string{},
+		map[string]string{},
+		options)
+	assert.NilError(t, err)
+	return createRequest.HostConfig.NetworkMode, createRequest.NetworkingConfig.EndpointsConfig, connectedNetworkIds
+}
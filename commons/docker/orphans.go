@@ -0,0 +1,163 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/palantir/stacktrace"
+	"sort"
+	"time"
+)
+
+/*
+The Kurtosis-managed resources left behind by earlier runs (e.g. because the initializer crashed midway through a run),
+	as found by CleanUpOrphans.
+ */
+type OrphanedResources struct {
+	// Sorted IDs of the containers (running or not)
+	ContainerIds []string
+
+	// Sorted IDs of the networks
+	NetworkIds []string
+
+	// Sorted names of the volumes
+	VolumeNames []string
+}
+
+/*
+Makes the manager put the given labels (e.g. EXECUTION_ID_LABEL, TEST_SUITE_LABEL, & TEST_NAME_LABEL) on every volume,
+	network, & container it creates from now on, so that leaked resources can be traced back to the run & test that
+	created them. Labels passed when creating a resource take precedence over these.
+ */
+func (manager *DockerManager) UseResourceLabels(labels map[string]string) {
+	labelsCopy := map[string]string{}
+	for key, value := range labels {
+		labelsCopy[key] = value
+	}
+	manager.resourceLabels = labelsCopy
+}
+
+/*
+Removes the Kurtosis-managed containers, networks, & volumes that were created more than the given duration ago, which
+	are left over from earlier runs that didn't tear down after themselves (e.g. because the initializer crashed). Running
+	containers are removed too, and everything is removed in that order so that networks & volumes are no longer in use
+	when they're removed, carrying on past failures so as much as possible is cleaned up.
+
+Args:
+	context: The Context that this request is running in (useful for cancellation)
+	olderThan: How long ago resources must have been created to be considered orphans, which should be longer than any
+		run that might be going on concurrently on the same Docker engine takes
+
+Returns:
+	The resources that were removed, and an error naming every resource that couldn't be, whose root cause is the first
+		such resource's removal error
+ */
+func (manager DockerManager) CleanUpOrphans(context context.Context, olderThan time.Duration) (OrphanedResources, error) {
+	cutoff := time.Now().Add(-olderThan)
+	managedFilters := filters.NewArgs(filters.Arg("label", MANAGED_BY_LABEL + "=" + MANAGED_BY_LABEL_VALUE))
+
+	dockerContainers, err := manager.dockerClient.ContainerList(context, types.ContainerListOptions{All: true, Filters: managedFilters})
+	if err != nil {
+		return OrphanedResources{}, stacktrace.Propagate(err, "An error occurred listing the Kurtosis-managed containers")
+	}
+	containerCreationTimes := map[string]time.Time{}
+	for _, dockerContainer := range dockerContainers {
+		containerCreationTimes[dockerContainer.ID] = time.Unix(dockerContainer.Created, 0)
+	}
+
+	dockerNetworks, err := manager.dockerClient.NetworkList(context, types.NetworkListOptions{Filters: managedFilters})
+	if err != nil {
+		return OrphanedResources{}, stacktrace.Propagate(err, "An error occurred listing the Kurtosis-managed networks")
+	}
+	networkCreationTimes := map[string]time.Time{}
+	for _, dockerNetwork := range dockerNetworks {
+		networkCreationTimes[dockerNetwork.ID] = dockerNetwork.Created
+	}
+
+	listVolumesResponse, err := manager.dockerClient.VolumeList(context, managedFilters)
+	if err != nil {
+		return OrphanedResources{}, stacktrace.Propagate(err, "An error occurred listing the Kurtosis-managed volumes")
+	}
+	volumeCreationTimes := map[string]time.Time{}
+	for _, dockerVolume := range listVolumesResponse.Volumes {
+		created, err := time.Parse(time.RFC3339, dockerVolume.CreatedAt)
+		if err != nil {
+			// Volumes whose age can't be told might belong to a run that's still going on, so they're left alone
+			manager.log.Debugf("Not cleaning up volume %v, whose creation time '%v' couldn't be parsed", dockerVolume.Name, dockerVolume.CreatedAt)
+			continue
+		}
+		volumeCreationTimes[dockerVolume.Name] = created
+	}
+
+	removed := OrphanedResources{
+		ContainerIds: []string{},
+		NetworkIds:   []string{},
+		VolumeNames:  []string{},
+	}
+	var firstRemovalErr error
+	failedResources := []string{}
+	recordFailure := func(resourceDescription string, err error) {
+		if firstRemovalErr == nil {
+			firstRemovalErr = err
+		}
+		failedResources = append(failedResources, resourceDescription)
+	}
+	for _, containerId := range getOrphans(containerCreationTimes, cutoff) {
+		if err := manager.dockerClient.ContainerRemove(context, containerId, types.ContainerRemoveOptions{Force: true}); err != nil {
+			recordFailure("container " + containerId, err)
+			continue
+		}
+		manager.log.Debugf("Removed orphaned container %v", containerId)
+		removed.ContainerIds = append(removed.ContainerIds, containerId)
+	}
+	for _, networkId := range getOrphans(networkCreationTimes, cutoff) {
+		if err := manager.dockerClient.NetworkRemove(context, networkId); err != nil {
+			recordFailure("network " + networkId, err)
+			continue
+		}
+		manager.log.Debugf("Removed orphaned network %v", networkId)
+		removed.NetworkIds = append(removed.NetworkIds, networkId)
+	}
+	for _, volumeName := range getOrphans(volumeCreationTimes, cutoff) {
+		if err := manager.dockerClient.VolumeRemove(context, volumeName, false); err != nil {
+			recordFailure("volume " + volumeName, err)
+			continue
+		}
+		manager.log.Debugf("Removed orphaned volume %v", volumeName)
+		removed.VolumeNames = append(removed.VolumeNames, volumeName)
+	}
+	if firstRemovalErr != nil {
+		return removed, stacktrace.Propagate(firstRemovalErr, "An error occurred removing orphaned resources %v", failedResources)
+	}
+	return removed, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Gets the labels to create a resource with, given the labels it was requested with
+func (manager DockerManager) getResourceLabels(labels map[string]string) map[string]string {
+	result := map[string]string{
+		MANAGED_BY_LABEL: MANAGED_BY_LABEL_VALUE,
+	}
+	for key, value := range manager.resourceLabels {
+		result[key] = value
+	}
+	for key, value := range labels {
+		result[key] = value
+	}
+	return result
+}
+
+/*
+Gets the sorted IDs, out of the given mapping of resource ID -> creation time, of the resources created before the given
+	cutoff.
+ */
+func getOrphans(creationTimes map[string]time.Time, cutoff time.Time) []string {
+	result := []string{}
+	for id, created := range creationTimes {
+		if created.Before(cutoff) {
+			result = append(result, id)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"github.com/docker/go-connections/nat"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"testing"
+	"time"
+)
+
+func TestGetOrphans(t *testing.T) {
+	now := time.Now()
+	creationTimes := map[string]time.Time{
+		"old-b":  now.Add(-3 * time.Hour),
+		"old-a":  now.Add(-2 * time.Hour),
+		"recent": now.Add(-1 * time.Minute),
+	}
+	assert.DeepEqual(t, []string{"old-a", "old-b"}, getOrphans(creationTimes, now.Add(-1 * time.Hour)))
+	assert.Equal(t, 0, len(getOrphans(creationTimes, now.Add(-24 * time.Hour))))
+}
+
+func TestResourceLabelsOnContainers(t *testing.T) {
+	manager := &DockerManager{log: logrus.NewEntry(logrus.StandardLogger())}
+	resourceLabels := map[string]string{
+		EXECUTION_ID_LABEL: "execution",
+		TEST_NAME_LABEL:    "test",
+	}
+	manager.UseResourceLabels(resourceLabels)
+	// The manager keeps its own copy of the labels
+	resourceLabels[TEST_NAME_LABEL] = "other-test"
+
+	config, err := manager.getContainerCfg("alpine", map[nat.Port]bool{}, nil, map[string]string{}, ContainerOptions{
+		Labels: map[string]string{SERVICE_ID_LABEL: "service", EXECUTION_ID_LABEL: "overridden"},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		MANAGED_BY_LABEL:   MANAGED_BY_LABEL_VALUE,
+		EXECUTION_ID_LABEL: "overridden",
+		TEST_NAME_LABEL:    "test",
+		SERVICE_ID_LABEL:   "service",
+	}, config.Labels)
+}
@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/docker/client"
+	"github.com/palantir/stacktrace"
+	"strings"
+)
+
+// Platforms that multi-arch images are commonly published for, for use in ContainerOptions.Platform
+const (
+	LINUX_AMD64_PLATFORM = "linux/amd64"
+	LINUX_ARM64_PLATFORM = "linux/arm64"
+
+	platformComponentSeparator = "/"
+)
+
+// Mapping of architecture name -> the name that Docker uses for it, for architectures that go by several names
+var architectureAliases = map[string]string{
+	"x86_64":  "amd64",
+	"x86-64":  "amd64",
+	"aarch64": "arm64",
+}
+
+/*
+Checks that the given platform is of the form "os/arch[/variant]" (e.g. "linux/amd64" or "linux/arm/v7").
+ */
+func ValidatePlatform(platform string) error {
+	components := strings.Split(platform, platformComponentSeparator)
+	if len(components) < 2 || len(components) > 3 {
+		return stacktrace.NewError("Platform '%v' isn't of the form os/arch[/variant]", platform)
+	}
+	for _, component := range components {
+		if component == "" {
+			return stacktrace.NewError("Platform '%v' has an empty component", platform)
+		}
+	}
+	return nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Pulls the given platform's variant of the given image unless the locally-available image is already for that platform,
+	since the Docker engine creates containers from whichever variant of the image it has locally.
+ */
+func (manager DockerManager) pullImageForPlatformIfMissing(context context.Context, dockerImage string, platform string) error {
+	if err := ValidatePlatform(platform); err != nil {
+		return stacktrace.Propagate(err, "Invalid platform for image %v", dockerImage)
+	}
+	imageInfo, _, err := manager.dockerClient.ImageInspectWithRaw(context, dockerImage)
+	if err == nil && imageMatchesPlatform(imageInfo.Os, imageInfo.Architecture, imageInfo.Variant, platform) {
+		return nil
+	}
+	if err != nil && !client.IsErrNotFound(err) {
+		return stacktrace.Propagate(err, "An error occurred inspecting the platform of local Docker image %v", dockerImage)
+	}
+	if err := manager.pullImage(context, dockerImage, platform); err != nil {
+		return stacktrace.Propagate(err, "Failed to pull the %v variant of Docker image %v from remote image repository", platform, dockerImage)
+	}
+	return nil
+}
+
+/*
+Returns true if an image with the given OS, architecture, & variant is for the given (valid) platform. A platform without a
+	variant matches images with any variant of its architecture.
+ */
+func imageMatchesPlatform(imageOs string, imageArchitecture string, imageVariant string, platform string) bool {
+	components := strings.Split(strings.ToLower(platform), platformComponentSeparator)
+	if components[0] != strings.ToLower(imageOs) {
+		return false
+	}
+	if normalizeArchitecture(components[1]) != normalizeArchitecture(imageArchitecture) {
+		return false
+	}
+	return len(components) < 3 || components[2] == strings.ToLower(imageVariant)
+}
+
+func normalizeArchitecture(architecture string) string {
+	architecture = strings.ToLower(architecture)
+	if alias, found := architectureAliases[architecture]; found {
+		return alias
+	}
+	return architecture
+}
@@ -0,0 +1,23 @@
+package docker
+
+import (
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestValidatePlatform(t *testing.T) {
+	assert.NilError(t, ValidatePlatform(LINUX_AMD64_PLATFORM))
+	assert.NilError(t, ValidatePlatform("linux/arm/v7"))
+	assert.ErrorContains(t, ValidatePlatform("arm64"), "os/arch[/variant]")
+	assert.ErrorContains(t, ValidatePlatform("linux/arm/v7/extra"), "os/arch[/variant]")
+	assert.ErrorContains(t, ValidatePlatform("linux/"), "empty component")
+}
+
+func TestImageMatchesPlatform(t *testing.T) {
+	assert.Assert(t, imageMatchesPlatform("linux", "amd64", "", LINUX_AMD64_PLATFORM))
+	assert.Assert(t, imageMatchesPlatform("linux", "aarch64", "v8", LINUX_ARM64_PLATFORM))
+	assert.Assert(t, imageMatchesPlatform("linux", "arm", "v7", "linux/arm/v7"))
+	assert.Assert(t, !imageMatchesPlatform("linux", "arm", "v6", "linux/arm/v7"))
+	assert.Assert(t, !imageMatchesPlatform("linux", "amd64", "", LINUX_ARM64_PLATFORM))
+	assert.Assert(t, !imageMatchesPlatform("windows", "amd64", "", LINUX_AMD64_PLATFORM))
+}
@@ -0,0 +1,176 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/palantir/stacktrace"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// The oldest Docker engine API that the framework is known to work with (Docker 1.13), which added attachable overlay
+	//  networks & the other features the framework relies on
+	MIN_DOCKER_API_VERSION = "1.25"
+
+	// How long the engine gets to answer each preflight check before it's deemed unreachable
+	preflightCheckTimeout = 10 * time.Second
+
+	// The file that a Linux host's ephemeral port range, which Docker picks published ports from, is read from
+	ephemeralPortRangeFilepath = "/proc/sys/net/ipv4/ip_local_port_range"
+
+	localDaemonHostScheme = "unix://"
+
+	bytesPerGibibyte = 1024 * 1024 * 1024
+)
+
+/*
+What the Docker environment must provide for tests to run, as checked by DockerManager.RunPreflightChecks. Zero values
+	disable the corresponding checks.
+ */
+type PreflightRequirements struct {
+	// The oldest Docker engine API version that's acceptable, e.g. MIN_DOCKER_API_VERSION
+	MinApiVersion string
+
+	// The least total memory that the Docker engine's host must have
+	MinMemoryBytes int64
+
+	// The least free disk space that the Docker engine's data directory must have
+	// NOTE: This can only be checked when the engine runs on this machine (not, e.g., in Docker Desktop's VM)
+	MinFreeDiskBytes uint64
+
+	// The fewest ports that the host's ephemeral port range (which published ports are picked from) must contain
+	// NOTE: This can only be checked when the engine runs on this machine & it's Linux
+	MinEphemeralPorts int
+}
+
+/*
+Preflight requirements that any machine that can run a handful of test networks should meet.
+ */
+var DEFAULT_PREFLIGHT_REQUIREMENTS = PreflightRequirements{
+	MinApiVersion:     MIN_DOCKER_API_VERSION,
+	MinMemoryBytes:    1 * bytesPerGibibyte,
+	MinFreeDiskBytes:  2 * bytesPerGibibyte,
+	MinEphemeralPorts: 1000,
+}
+
+/*
+Checks that the Docker engine is reachable and that it & its host meet the given requirements, so that an unsuitable
+	environment fails fast with an actionable message rather than with Docker errors midway through the tests.
+
+Returns:
+	An error describing every requirement that isn't met, and how to fix it
+ */
+func (manager DockerManager) RunPreflightChecks(ctx context.Context, requirements PreflightRequirements) error {
+	daemonHost := manager.dockerClient.DaemonHost()
+	pingCtx, cancelPing := context.WithTimeout(ctx, preflightCheckTimeout)
+	defer cancelPing()
+	if _, err := manager.dockerClient.Ping(pingCtx); err != nil {
+		return stacktrace.Propagate(
+			err,
+			"Couldn't reach the Docker engine at %v; check that the engine is running and that DOCKER_HOST (and, for TLS, " +
+				"DOCKER_CERT_PATH & DOCKER_TLS_VERIFY) point to it",
+			daemonHost)
+	}
+
+	infoCtx, cancelInfo := context.WithTimeout(ctx, preflightCheckTimeout)
+	defer cancelInfo()
+	version, err := manager.dockerClient.ServerVersion(infoCtx)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred getting the version of the Docker engine at %v", daemonHost)
+	}
+	info, err := manager.dockerClient.Info(infoCtx)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred getting the system info of the Docker engine at %v", daemonHost)
+	}
+
+	failures := []string{}
+	if requirements.MinApiVersion != "" && versions.LessThan(version.APIVersion, requirements.MinApiVersion) {
+		failures = append(failures, fmt.Sprintf(
+			"The Docker engine (version %v) supports API version %v, but at least %v is needed; upgrade the Docker engine",
+			version.Version,
+			version.APIVersion,
+			requirements.MinApiVersion))
+	}
+	if requirements.MinMemoryBytes > 0 && info.MemTotal < requirements.MinMemoryBytes {
+		failures = append(failures, fmt.Sprintf(
+			"The Docker engine has %v of memory, but at least %v is needed; give it more memory (e.g. in Docker Desktop's " +
+				"resource settings) or run fewer tests in parallel",
+			formatGibibytes(uint64(info.MemTotal)),
+			formatGibibytes(uint64(requirements.MinMemoryBytes))))
+	}
+
+	// The engine's filesystem & network settings can only be seen from here if the engine runs on this machine
+	isDaemonLocal := strings.HasPrefix(daemonHost, localDaemonHostScheme)
+	if requirements.MinFreeDiskBytes > 0 && isDaemonLocal {
+		freeDiskBytes, isKnown, err := getFreeDiskBytes(info.DockerRootDir)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the free disk space of Docker's data directory %v", info.DockerRootDir)
+		}
+		if isKnown && freeDiskBytes < requirements.MinFreeDiskBytes {
+			failures = append(failures, fmt.Sprintf(
+				"Docker's data directory %v has %v free, but at least %v is needed; free up space (e.g. with " +
+					"'docker system prune') or move Docker's data directory",
+				info.DockerRootDir,
+				formatGibibytes(freeDiskBytes),
+				formatGibibytes(requirements.MinFreeDiskBytes)))
+		}
+	}
+	if requirements.MinEphemeralPorts > 0 && isDaemonLocal {
+		rangeBytes, err := ioutil.ReadFile(ephemeralPortRangeFilepath)
+		if err != nil && !os.IsNotExist(err) {
+			return stacktrace.Propagate(err, "An error occurred reading the ephemeral port range from %v", ephemeralPortRangeFilepath)
+		}
+		if err == nil {
+			numEphemeralPorts, err := getNumEphemeralPorts(string(rangeBytes))
+			if err != nil {
+				return stacktrace.Propagate(err, "An error occurred parsing the ephemeral port range in %v", ephemeralPortRangeFilepath)
+			}
+			if numEphemeralPorts < requirements.MinEphemeralPorts {
+				failures = append(failures, fmt.Sprintf(
+					"The host's ephemeral port range (%v) has %v ports, but at least %v are needed for published ports; " +
+						"widen it with 'sysctl net.ipv4.ip_local_port_range'",
+					strings.Join(strings.Fields(string(rangeBytes)), "-"),
+					numEphemeralPorts,
+					requirements.MinEphemeralPorts))
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return stacktrace.NewError(
+			"The Docker environment at %v doesn't meet the requirements for running tests:\n - %v",
+			daemonHost,
+			strings.Join(failures, "\n - "))
+	}
+	return nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Gets the number of ports in a port range of the form "<first port> <last port>", as in ephemeralPortRangeFilepath
+func getNumEphemeralPorts(portRange string) (int, error) {
+	bounds := strings.Fields(portRange)
+	if len(bounds) != 2 {
+		return 0, stacktrace.NewError("Port range '%v' isn't of the form '<first port> <last port>'", strings.TrimSpace(portRange))
+	}
+	firstPort, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "Port range '%v' has an invalid first port", strings.TrimSpace(portRange))
+	}
+	lastPort, err := strconv.Atoi(bounds[1])
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "Port range '%v' has an invalid last port", strings.TrimSpace(portRange))
+	}
+	if lastPort < firstPort {
+		return 0, nil
+	}
+	return lastPort - firstPort + 1, nil
+}
+
+func formatGibibytes(numBytes uint64) string {
+	return fmt.Sprintf("%.1fGiB", float64(numBytes) / bytesPerGibibyte)
+}
@@ -0,0 +1,86 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPreflightChecksPassOnSuitableEngine(t *testing.T) {
+	server := newTestPreflightServer("1.40", 4 * bytesPerGibibyte)
+	defer server.Close()
+
+	err := newTestPreflightManager(t, server).RunPreflightChecks(context.Background(), DEFAULT_PREFLIGHT_REQUIREMENTS)
+	assert.NilError(t, err)
+}
+
+func TestPreflightChecksReportEveryFailure(t *testing.T) {
+	server := newTestPreflightServer("1.24", bytesPerGibibyte / 2)
+	defer server.Close()
+
+	err := newTestPreflightManager(t, server).RunPreflightChecks(context.Background(), DEFAULT_PREFLIGHT_REQUIREMENTS)
+	assert.ErrorContains(t, err, "supports API version 1.24, but at least " + MIN_DOCKER_API_VERSION)
+	assert.ErrorContains(t, err, "has 0.5GiB of memory, but at least 1.0GiB")
+
+	// Zero requirements disable the checks
+	err = newTestPreflightManager(t, server).RunPreflightChecks(context.Background(), PreflightRequirements{})
+	assert.NilError(t, err)
+}
+
+func TestPreflightChecksReportUnreachableEngine(t *testing.T) {
+	server := newTestPreflightServer("1.40", 4 * bytesPerGibibyte)
+	server.Close()
+
+	err := newTestPreflightManager(t, server).RunPreflightChecks(context.Background(), DEFAULT_PREFLIGHT_REQUIREMENTS)
+	assert.ErrorContains(t, err, "Couldn't reach the Docker engine")
+}
+
+func TestGetNumEphemeralPorts(t *testing.T) {
+	numPorts, err := getNumEphemeralPorts("32768\t60999\n")
+	assert.NilError(t, err)
+	assert.Equal(t, numPorts, 28232)
+
+	numPorts, err = getNumEphemeralPorts("60000 59999")
+	assert.NilError(t, err)
+	assert.Equal(t, numPorts, 0)
+
+	_, err = getNumEphemeralPorts("32768")
+	assert.ErrorContains(t, err, "isn't of the form")
+	_, err = getNumEphemeralPorts("32768 high")
+	assert.ErrorContains(t, err, "invalid last port")
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Serves just enough of the Docker engine API for the preflight checks
+func newTestPreflightServer(apiVersion string, memTotal int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("API-Version", apiVersion)
+		switch {
+		case strings.HasSuffix(request.URL.Path, "/_ping"):
+			writer.Write([]byte("OK"))
+		case strings.HasSuffix(request.URL.Path, "/version"):
+			json.NewEncoder(writer).Encode(types.Version{Version: "test", APIVersion: apiVersion})
+		case strings.HasSuffix(request.URL.Path, "/info"):
+			json.NewEncoder(writer).Encode(types.Info{MemTotal: memTotal, DockerRootDir: "/var/lib/docker"})
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestPreflightManager(t *testing.T, server *httptest.Server) *DockerManager {
+	dockerClient, err := client.NewClientWithOpts(
+		client.WithHost("tcp://" + strings.TrimPrefix(server.URL, "http://")),
+		client.WithVersion("1.40"))
+	assert.NilError(t, err)
+	manager, err := NewDockerManager(logrus.NewEntry(logrus.StandardLogger()), dockerClient)
+	assert.NilError(t, err)
+	return manager
+}
@@ -0,0 +1,27 @@
+// +build linux darwin
+
+package docker
+
+import (
+	"github.com/palantir/stacktrace"
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+/*
+Gets the free disk space (available to unprivileged users) of the filesystem holding the given directory.
+
+Returns:
+	freeBytes: The free space
+	isKnown: False if the directory doesn't exist on this machine, so its free space can't be known
+ */
+func getFreeDiskBytes(dirpath string) (freeBytes uint64, isKnown bool, err error) {
+	if _, err := os.Stat(dirpath); os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	stat := unix.Statfs_t{}
+	if err := unix.Statfs(dirpath, &stat); err != nil {
+		return 0, false, stacktrace.Propagate(err, "An error occurred getting the filesystem stats of %v", dirpath)
+	}
+	return stat.Bavail * uint64(stat.Bsize), true, nil
+}
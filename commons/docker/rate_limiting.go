@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"errors"
+	"github.com/docker/docker/client"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	// The default cap on how many calls a Docker client has in flight at once (see WithRateLimiting)
+	DEFAULT_MAX_CONCURRENT_DOCKER_REQUESTS = 16
+
+	// The default number of times that a call failing with a transient error is retried (see WithRateLimiting)
+	DEFAULT_MAX_DOCKER_REQUEST_RETRIES = 3
+
+	// How long to wait before the first retry of a call; the wait doubles with each further retry
+	initialDockerRequestRetryDelay = 200 * time.Millisecond
+)
+
+/*
+Returns a Docker client option that:
+
+	1) Caps how many calls the client has in flight at once, with further calls waiting their turn, so that e.g. 50
+		containers being created in parallel don't overwhelm the Docker engine
+	2) Keeps enough idle connections to the Docker engine open for all those calls to reuse (by default, the client only
+		keeps 2, so bursts of parallel calls open & close a socket apiece, which can exhaust the host's sockets)
+	3) Retries calls that fail with transient errors (a 5xx status, or the connection dropping), waiting a little longer
+		before each retry. Only calls that are safe to repeat (GETs, HEADs, & DELETEs without a request body) are retried,
+		since e.g. retrying a container creation whose response was lost would create a second container.
+
+NOTE: Like WithAuditLog, this must come after any options that configure the client's connection (e.g. client.FromEnv).
+	If both are used, this should come before WithAuditLog so that the audit log records each call once, with its final
+	result.
+
+Args:
+	maxConcurrentRequests: The most calls that the client can have in flight at once
+	maxRetries: The most times that a call failing with a transient error is retried
+ */
+func WithRateLimiting(maxConcurrentRequests int, maxRetries int) client.Opt {
+	return func(dockerClient *client.Client) error {
+		httpClient := dockerClient.HTTPClient()
+		underlying := httpClient.Transport
+		if underlying == nil {
+			underlying = http.DefaultTransport
+		}
+		if httpTransport, ok := underlying.(*http.Transport); ok {
+			httpTransport.MaxIdleConnsPerHost = maxConcurrentRequests
+		}
+		httpClient.Transport = &rateLimitingTransport{
+			requestSlots: make(chan bool, maxConcurrentRequests),
+			maxRetries:   maxRetries,
+			underlying:   underlying,
+		}
+		return client.WithHTTPClient(httpClient)(dockerClient)
+	}
+}
+
+// =========================== RATE-LIMITING TRANSPORT =========================================
+/*
+HTTP transport which passes requests through to the Docker engine, limiting how many are in flight at once & retrying
+	those that fail with transient errors.
+
+NOTE: This is thread-safe!
+ */
+type rateLimitingTransport struct {
+	// Holds a value for each request in flight, so that sending to it blocks once the limit is hit
+	requestSlots chan bool
+
+	maxRetries int
+
+	underlying http.RoundTripper
+}
+
+func (transport *rateLimitingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	select {
+	case transport.requestSlots <- true:
+	case <-request.Context().Done():
+		return nil, request.Context().Err()
+	}
+	// NOTE: The slot is freed when the response headers arrive, rather than when the body is closed, because some calls
+	//  (e.g. following logs, or waiting on a container) stream their bodies for as long as the test runs
+	defer func() { <-transport.requestSlots }()
+
+	retryDelay := initialDockerRequestRetryDelay
+	for numRetries := 0; ; numRetries++ {
+		response, err := transport.underlying.RoundTrip(request)
+		if numRetries >= transport.maxRetries || !isRetryableRequest(request) || !isTransientFailure(response, err) {
+			return response, err
+		}
+		if response != nil {
+			// The connection can only be reused once the failed response's body has been read to the end
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+		select {
+		case <-time.After(retryDelay):
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		}
+		retryDelay *= 2
+	}
+}
+
+// Returns true if the request can be sent again without changing what it does
+func isRetryableRequest(request *http.Request) bool {
+	if request.Body != nil && request.Body != http.NoBody {
+		return false
+	}
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns true if the given result of a request is a failure that may not happen again if the request is retried
+func isTransientFailure(response *http.Response, err error) bool {
+	if err != nil {
+		return errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF) ||
+			errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED)
+	}
+	return response.StatusCode >= http.StatusInternalServerError && response.StatusCode != http.StatusNotImplemented
+}
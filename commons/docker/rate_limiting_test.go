@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimitingCapsConcurrentRequests(t *testing.T) {
+	maxConcurrentRequests := 3
+	mutex := &sync.Mutex{}
+	numInFlight := 0
+	maxNumInFlight := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		numInFlight++
+		if numInFlight > maxNumInFlight {
+			maxNumInFlight = numInFlight
+		}
+		mutex.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mutex.Lock()
+		numInFlight--
+		mutex.Unlock()
+		writer.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	dockerClient := newTestRateLimitedClient(t, server, maxConcurrentRequests, 0)
+
+	waitGroup := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			dockerClient.ContainerInspect(context.Background(), "nonexistent")
+		}()
+	}
+	waitGroup.Wait()
+	assert.Equal(t, maxConcurrentRequests, maxNumInFlight)
+}
+
+func TestRateLimitingRetriesTransientFailures(t *testing.T) {
+	mutex := &sync.Mutex{}
+	numRequestsByMethod := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		numRequestsByMethod[request.Method]++
+		numRequests := numRequestsByMethod[request.Method]
+		mutex.Unlock()
+		if numRequests < 3 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	dockerClient := newTestRateLimitedClient(t, server, 1, 5)
+
+	// The GET is retried until it gets past the transient failures...
+	_, err := dockerClient.ContainerInspect(context.Background(), "nonexistent")
+	assert.Assert(t, client.IsErrNotFound(err))
+	assert.Equal(t, 3, numRequestsByMethod[http.MethodGet])
+
+	// ...but the POST isn't retried at all, since it might not be safe to repeat
+	err = dockerClient.ContainerStart(context.Background(), "nonexistent", types.ContainerStartOptions{})
+	assert.Assert(t, err != nil && !client.IsErrNotFound(err))
+	assert.Equal(t, 1, numRequestsByMethod[http.MethodPost])
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func newTestRateLimitedClient(t *testing.T, server *httptest.Server, maxConcurrentRequests int, maxRetries int) *client.Client {
+	dockerClient, err := client.NewClientWithOpts(
+		client.WithHost("tcp://" + strings.TrimPrefix(server.URL, "http://")),
+		client.WithVersion("1.40"),
+		WithRateLimiting(maxConcurrentRequests, maxRetries))
+	assert.NilError(t, err)
+	return dockerClient
+}
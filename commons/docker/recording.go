@@ -0,0 +1,324 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/docker/docker/client"
+	"github.com/palantir/stacktrace"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+const (
+	// The longest recorded call that LoadRecording can load
+	maxRecordingLineBytes = 64 * 1024 * 1024
+)
+
+/*
+A single call made to the Docker API, along with the response the Docker engine gave it.
+ */
+type RecordedInteraction struct {
+	// The HTTP method of the call
+	Method string `json:"method"`
+
+	// The Docker API path called, including the query string
+	Path string `json:"path"`
+
+	// The request body, if any
+	RequestBody string `json:"requestBody,omitempty"`
+
+	// The HTTP status & headers the Docker engine responded with (0 & empty if the call didn't get a response)
+	StatusCode      int         `json:"statusCode,omitempty"`
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+
+	// The response body, as far as the caller read it (e.g. up to where it stopped following a container's logs)
+	ResponseBody []byte `json:"responseBody,omitempty"`
+
+	// The error that prevented the call from getting a response, if any
+	Error string `json:"error,omitempty"`
+}
+
+/*
+The calls that a Docker client made during a run & the responses it got (see WithRecording), which can be saved & later
+	served back to a client in place of a Docker engine (see WithReplay) so that code driving Docker can be unit-tested
+	hermetically against a real engine's behaviour.
+
+Calls are recorded in the order they were made. Secrets are redacted from them (see RedactSecrets), but anything else
+	the engine returned (e.g. container environments) is recorded as-is.
+
+NOTE: This is thread-safe!
+ */
+type Recording struct {
+	mutex *sync.Mutex
+
+	// The recorded calls, some of which may still be awaiting their response bodies being closed
+	interactions []*pendingInteraction
+}
+
+/*
+Creates a new, empty recording.
+ */
+func NewRecording() *Recording {
+	return &Recording{
+		mutex:        &sync.Mutex{},
+		interactions: []*pendingInteraction{},
+	}
+}
+
+/*
+Loads a recording saved with Save.
+ */
+func LoadRecording(input io.Reader) (*Recording, error) {
+	recording := NewRecording()
+	scanner := bufio.NewScanner(input)
+	// Recorded responses (e.g. container logs) can be far longer than the scanner's default line limit
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxRecordingLineBytes)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		var interaction RecordedInteraction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred parsing line %v of the recording", lineNum)
+		}
+		recording.interactions = append(recording.interactions, &pendingInteraction{
+			interaction: interaction,
+			isComplete:  true,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading the recording")
+	}
+	return recording, nil
+}
+
+/*
+Gets the calls recorded so far whose responses have been fully read, in the order they were made.
+ */
+func (recording *Recording) GetInteractions() []RecordedInteraction {
+	recording.mutex.Lock()
+	defer recording.mutex.Unlock()
+	result := []RecordedInteraction{}
+	for _, pending := range recording.interactions {
+		if pending.isComplete {
+			result = append(result, pending.interaction)
+		}
+	}
+	return result
+}
+
+/*
+Writes the calls recorded so far whose responses have been fully read to the given writer as JSON lines, for loading
+	with LoadRecording.
+ */
+func (recording *Recording) Save(output io.Writer) error {
+	for _, interaction := range recording.GetInteractions() {
+		interactionBytes, err := json.Marshal(interaction)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred serializing the recorded %v call to %v", interaction.Method, interaction.Path)
+		}
+		if _, err := output.Write(append(interactionBytes, '\n')); err != nil {
+			return stacktrace.Propagate(err, "An error occurred writing the recording")
+		}
+	}
+	return nil
+}
+
+/*
+Returns a Docker client option that records every call the client makes, along with its response, to the given
+	recording. Calls that hijack the connection (e.g. attaching to an exec) bypass the client's transport, so aren't
+	recorded.
+
+NOTE: Like WithAuditLog, this must come after any options that configure the client's connection (e.g. client.FromEnv).
+ */
+func WithRecording(recording *Recording) client.Opt {
+	return func(dockerClient *client.Client) error {
+		httpClient := dockerClient.HTTPClient()
+		underlying := httpClient.Transport
+		if underlying == nil {
+			underlying = http.DefaultTransport
+		}
+		httpClient.Transport = &recordingTransport{
+			recording:  recording,
+			underlying: underlying,
+		}
+		return client.WithHTTPClient(httpClient)(dockerClient)
+	}
+}
+
+/*
+Returns a Docker client option that serves the client's calls from the given recording rather than a Docker engine. Each
+	call gets the response of the first recorded call with the same method & path (ignoring the query string) that
+	hasn't already been served, so the client must use the same API version as the recorded one did (e.g. with
+	client.WithVersion). Calls with no such recorded call fail.
+ */
+func WithReplay(recording *Recording) client.Opt {
+	return func(dockerClient *client.Client) error {
+		httpClient := dockerClient.HTTPClient()
+		httpClient.Transport = &replayingTransport{
+			mutex:              &sync.Mutex{},
+			interactions:       recording.GetInteractions(),
+			servedInteractions: map[int]bool{},
+		}
+		return client.WithHTTPClient(httpClient)(dockerClient)
+	}
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// A recorded call, which is only complete once its response body has been closed
+type pendingInteraction struct {
+	interaction RecordedInteraction
+
+	isComplete bool
+}
+
+// Adds a call that's about to be made to the recording, so that calls are recorded in the order they're made
+func (recording *Recording) reserve() *pendingInteraction {
+	recording.mutex.Lock()
+	defer recording.mutex.Unlock()
+	pending := &pendingInteraction{isComplete: false}
+	recording.interactions = append(recording.interactions, pending)
+	return pending
+}
+
+func (recording *Recording) complete(pending *pendingInteraction, interaction RecordedInteraction) {
+	recording.mutex.Lock()
+	defer recording.mutex.Unlock()
+	pending.interaction = interaction
+	pending.isComplete = true
+}
+
+// =========================== RECORDING TRANSPORT =========================================
+/*
+HTTP transport which passes requests through to the Docker engine, recording each one along with its response.
+ */
+type recordingTransport struct {
+	recording *Recording
+
+	underlying http.RoundTripper
+}
+
+func (transport *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	interaction := RecordedInteraction{
+		Method: request.Method,
+		Path:   RedactSecrets(request.URL.RequestURI()),
+	}
+	if request.Body != nil {
+		bodyBytes, err := ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// The body can only be read once, so we give the request a fresh copy to send
+		request.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+		interaction.RequestBody = RedactSecrets(string(bodyBytes))
+	}
+
+	pending := transport.recording.reserve()
+	response, err := transport.underlying.RoundTrip(request)
+	if err != nil {
+		interaction.Error = RedactSecrets(err.Error())
+		transport.recording.complete(pending, interaction)
+		return response, err
+	}
+	interaction.StatusCode = response.StatusCode
+	interaction.ResponseHeaders = response.Header.Clone()
+	// Some responses (e.g. followed logs) stream for as long as the caller reads them, so the body is recorded as it's
+	//  read rather than up front
+	response.Body = &recordingBody{
+		underlying: response.Body,
+		buffer:     &bytes.Buffer{},
+		onClose: func(bodyBytes []byte) {
+			interaction.ResponseBody = []byte(RedactSecrets(string(bodyBytes)))
+			transport.recording.complete(pending, interaction)
+		},
+		closeOnce: &sync.Once{},
+	}
+	return response, nil
+}
+
+// Response body which keeps a copy of everything read from it, handing the copy over when it's closed
+type recordingBody struct {
+	underlying io.ReadCloser
+
+	buffer *bytes.Buffer
+
+	onClose func(bodyBytes []byte)
+
+	closeOnce *sync.Once
+}
+
+func (body *recordingBody) Read(bytesToRead []byte) (int, error) {
+	numRead, err := body.underlying.Read(bytesToRead)
+	body.buffer.Write(bytesToRead[:numRead])
+	return numRead, err
+}
+
+func (body *recordingBody) Close() error {
+	err := body.underlying.Close()
+	body.closeOnce.Do(func() { body.onClose(body.buffer.Bytes()) })
+	return err
+}
+
+// =========================== REPLAYING TRANSPORT =========================================
+/*
+HTTP transport which answers requests with the responses from a recording, without sending them anywhere.
+
+NOTE: This is thread-safe!
+ */
+type replayingTransport struct {
+	mutex *sync.Mutex
+
+	interactions []RecordedInteraction
+
+	// A "set" of the indices of the interactions whose responses have already been served
+	servedInteractions map[int]bool
+}
+
+func (transport *replayingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		request.Body.Close()
+	}
+	interaction, found := transport.takeInteraction(request.Method, request.URL.Path)
+	if !found {
+		return nil, stacktrace.NewError("No unserved call to %v %v was recorded", request.Method, request.URL.Path)
+	}
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+	header := interaction.ResponseHeaders.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        http.StatusText(interaction.StatusCode),
+		StatusCode:    interaction.StatusCode,
+		Proto:         request.Proto,
+		ProtoMajor:    request.ProtoMajor,
+		ProtoMinor:    request.ProtoMinor,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(interaction.ResponseBody)),
+		ContentLength: int64(len(interaction.ResponseBody)),
+		Request:       request,
+	}, nil
+}
+
+// Gets the first recorded call with the given method & path that hasn't been served yet, marking it as served
+func (transport *replayingTransport) takeInteraction(method string, path string) (RecordedInteraction, bool) {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	for idx, interaction := range transport.interactions {
+		if transport.servedInteractions[idx] || interaction.Method != method {
+			continue
+		}
+		recordedUrl, err := url.ParseRequestURI(interaction.Path)
+		if err != nil || recordedUrl.Path != path {
+			continue
+		}
+		transport.servedInteractions[idx] = true
+		return interaction, true
+	}
+	return RecordedInteraction{}, false
+}
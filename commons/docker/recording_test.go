@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"github.com/docker/docker/client"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecordingAndReplaying(t *testing.T) {
+	numInspects := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/v1.40/containers/test-container/json" {
+			writer.WriteHeader(http.StatusNotFound)
+			writer.Write([]byte(`{"message": "not found"}`))
+			return
+		}
+		// The container exits between the two inspections, which the replay should reproduce in order
+		numInspects++
+		state := `{"Status": "running", "StartedAt": "2020-06-01T12:00:00Z"}`
+		if numInspects > 1 {
+			state = `{"Status": "exited", "ExitCode": 3, "StartedAt": "2020-06-01T12:00:00Z"}`
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write([]byte(`{"Id": "test-container", "Config": {"Image": "node:latest"}, "State": ` + state + `}`))
+	}))
+	recording := NewRecording()
+	recordingClient, err := client.NewClientWithOpts(
+		client.WithHost("tcp://" + strings.TrimPrefix(server.URL, "http://")),
+		client.WithVersion("1.40"),
+		WithRecording(recording))
+	assert.NilError(t, err)
+	recordedStatuses := getTestContainerStatuses(t, recordingClient)
+	server.Close()
+	assert.Equal(t, FAKE_RUNNING_STATE, recordedStatuses[0].State)
+	assert.Equal(t, 3, recordedStatuses[1].ExitCode)
+	assert.Equal(t, 2, len(recording.GetInteractions()))
+
+	// Replaying a saved & reloaded recording gives the same results, even though there's no engine to talk to
+	saved := &bytes.Buffer{}
+	assert.NilError(t, recording.Save(saved))
+	loaded, err := LoadRecording(saved)
+	assert.NilError(t, err)
+	replayingClient, err := client.NewClientWithOpts(client.WithVersion("1.40"), WithReplay(loaded))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, recordedStatuses, getTestContainerStatuses(t, replayingClient))
+
+	// Every recorded call has been served, so further calls fail
+	_, err = replayingClient.ContainerInspect(context.Background(), "test-container")
+	assert.ErrorContains(t, err, "No unserved call")
+}
+
+func TestLoadingInvalidRecording(t *testing.T) {
+	_, err := LoadRecording(strings.NewReader("{\"method\": \"GET\"}\nnot json\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func getTestContainerStatuses(t *testing.T, dockerClient *client.Client) []ContainerStatus {
+	manager, err := NewDockerManager(logrus.NewEntry(logrus.StandardLogger()), dockerClient)
+	assert.NilError(t, err)
+	result := []ContainerStatus{}
+	for i := 0; i < 2; i++ {
+		status, err := manager.GetContainerStatus(context.Background(), "test-container")
+		assert.NilError(t, err)
+		result = append(result, status)
+	}
+	return result
+}
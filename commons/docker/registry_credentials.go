@@ -0,0 +1,194 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/palantir/stacktrace"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// The environment variable that, like for the Docker CLI, overrides the directory holding the Docker config file
+	DOCKER_CONFIG_DIRPATH_ENV_VAR = "DOCKER_CONFIG"
+
+	dockerConfigFilename = "config.json"
+	defaultDockerConfigDirname = ".docker"
+
+	// The key that the Docker CLI stores Docker Hub's credentials under
+	dockerHubCredentialsKey = "https://index.docker.io/v1/"
+
+	// Credential helpers are binaries named with this prefix, followed by the helper's name (e.g. "docker-credential-ecr-login")
+	credentialHelperBinaryPrefix = "docker-credential-"
+
+	// The username that credential helpers return for identity tokens (e.g. from "docker login" with OAuth), rather than passwords
+	identityTokenUsername = "<token>"
+)
+
+// The parts of the Docker CLI's config file that say where registry credentials come from
+type dockerConfigFile struct {
+	// Mapping of registry -> credentials stored in the config file itself
+	Auths map[string]dockerConfigAuth `json:"auths"`
+
+	// The credential helper used for registries without their own helper in CredHelpers
+	CredsStore string `json:"credsStore"`
+
+	// Mapping of registry -> the credential helper for it (e.g. "ecr-login" for an ECR registry)
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+type dockerConfigAuth struct {
+	// Base64 of "username:password"
+	Auth string `json:"auth"`
+
+	IdentityToken string `json:"identitytoken"`
+}
+
+// What credential helpers write to STDOUT when asked for a registry's credentials
+type credentialHelperResponse struct {
+	Username string `json:"Username"`
+
+	Secret string `json:"Secret"`
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets the encoded credentials (for types.ImagePullOptions.RegistryAuth) for pulling the given image, from the Docker CLI's
+	config file in the same way as "docker pull": from the registry's credential helper, else the default credential
+	store, else the credentials stored in the config file itself. Credential helpers (e.g. for ECR or GCR) are run as
+	"docker-credential-<helper> get", so must be on the PATH.
+
+Returns:
+	The encoded credentials, or empty if there are none for the image's registry
+ */
+func getRegistryAuth(imageName string) (string, error) {
+	configDirpath := os.Getenv(DOCKER_CONFIG_DIRPATH_ENV_VAR)
+	if configDirpath == "" {
+		homeDirpath, err := os.UserHomeDir()
+		if err != nil {
+			// With no home directory there can't be a config file
+			return "", nil
+		}
+		configDirpath = filepath.Join(homeDirpath, defaultDockerConfigDirname)
+	}
+	authConfig, err := getRegistryAuthConfig(imageName, filepath.Join(configDirpath, dockerConfigFilename), runCredentialHelper)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the registry credentials for image %v", imageName)
+	}
+	if authConfig == nil {
+		return "", nil
+	}
+	// The credentials mustn't show up in logs or the Docker audit log
+	knownSecretValues.add(authConfig.Password)
+	knownSecretValues.add(authConfig.IdentityToken)
+	authConfigBytes, err := json.Marshal(authConfig)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred serializing the registry credentials for image %v", imageName)
+	}
+	encodedAuth := base64.URLEncoding.EncodeToString(authConfigBytes)
+	knownSecretValues.add(encodedAuth)
+	return encodedAuth, nil
+}
+
+/*
+Gets the credentials for pulling the given image according to the given Docker config file (see getRegistryAuth).
+
+Args:
+	imageName: The image to get credentials for
+	configFilepath: The Docker CLI config file, which may not exist
+	runHelper: Runs the credential helper with the given name to get the credentials of the given server, returning its output
+
+Returns:
+	The credentials, or nil if there are none for the image's registry
+ */
+func getRegistryAuthConfig(
+			imageName string,
+			configFilepath string,
+			runHelper func(helperName string, serverUrl string) ([]byte, error)) (*types.AuthConfig, error) {
+	configBytes, err := ioutil.ReadFile(configFilepath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading Docker config file %v", configFilepath)
+	}
+	config := dockerConfigFile{}
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing Docker config file %v", configFilepath)
+	}
+
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Couldn't parse image name %v", imageName)
+	}
+	registry := reference.Domain(named)
+	credentialsKey := registry
+	if registry == dockerHubDomain {
+		credentialsKey = dockerHubCredentialsKey
+	}
+
+	helperName, found := config.CredHelpers[registry]
+	if !found {
+		helperName = config.CredsStore
+	}
+	if helperName != "" {
+		output, err := runHelper(helperName, credentialsKey)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "Credential helper %v failed to get the credentials for %v", helperName, registry)
+		}
+		// Credential stores answer for every registry, so an empty answer means the store has nothing for this one
+		if len(bytes.TrimSpace(output)) == 0 {
+			return nil, nil
+		}
+		response := credentialHelperResponse{}
+		if err := json.Unmarshal(output, &response); err != nil {
+			return nil, stacktrace.Propagate(err, "Credential helper %v returned unparseable credentials for %v", helperName, registry)
+		}
+		if response.Username == identityTokenUsername {
+			return &types.AuthConfig{IdentityToken: response.Secret, ServerAddress: credentialsKey}, nil
+		}
+		return &types.AuthConfig{Username: response.Username, Password: response.Secret, ServerAddress: credentialsKey}, nil
+	}
+
+	storedAuth, found := config.Auths[credentialsKey]
+	if !found {
+		return nil, nil
+	}
+	result := &types.AuthConfig{IdentityToken: storedAuth.IdentityToken, ServerAddress: credentialsKey}
+	if storedAuth.Auth != "" {
+		decodedAuth, err := base64.StdEncoding.DecodeString(storedAuth.Auth)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "The stored credentials for %v in Docker config file %v aren't valid base64", registry, configFilepath)
+		}
+		usernameAndPassword := strings.SplitN(string(decodedAuth), ":", 2)
+		if len(usernameAndPassword) != 2 {
+			return nil, stacktrace.NewError("The stored credentials for %v in Docker config file %v aren't of the form username:password", registry, configFilepath)
+		}
+		result.Username = usernameAndPassword[0]
+		result.Password = usernameAndPassword[1]
+	}
+	return result, nil
+}
+
+// Runs the given credential helper's "get" command for the given server, per the credential helper protocol
+func runCredentialHelper(helperName string, serverUrl string) ([]byte, error) {
+	command := exec.Command(credentialHelperBinaryPrefix + helperName, "get")
+	command.Stdin = strings.NewReader(serverUrl)
+	stderr := &bytes.Buffer{}
+	command.Stderr = stderr
+	output, err := command.Output()
+	if err != nil {
+		// Helpers report missing credentials with a non-zero exit, which isn't an error for our purposes
+		if strings.Contains(string(output) + stderr.String(), "credentials not found") {
+			return []byte{}, nil
+		}
+		return nil, stacktrace.Propagate(err, "Running credential helper %v failed with output: %v", helperName, strings.TrimSpace(stderr.String() + string(output)))
+	}
+	return output, nil
+}
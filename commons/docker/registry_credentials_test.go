@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+const testDockerConfig = `{
+	"auths": {
+		"https://index.docker.io/v1/": {"auth": "aHViLXVzZXI6aHViLXBhc3N3b3Jk"}
+	},
+	"credHelpers": {
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com": "ecr-login"
+	}
+}`
+
+func TestGettingRegistryAuthConfigs(t *testing.T) {
+	configDirpath, err := ioutil.TempDir("", "test-docker-config")
+	assert.NilError(t, err)
+	defer os.RemoveAll(configDirpath)
+	configFilepath := path.Join(configDirpath, "config.json")
+	assert.NilError(t, ioutil.WriteFile(configFilepath, []byte(testDockerConfig), 0644))
+
+	helperCalls := map[string]string{}
+	runHelper := func(helperName string, serverUrl string) ([]byte, error) {
+		helperCalls[helperName] = serverUrl
+		if helperName != "ecr-login" {
+			return nil, stacktrace.NewError("Unknown credential helper %v", helperName)
+		}
+		return []byte(`{"ServerURL": "123456789012.dkr.ecr.us-east-1.amazonaws.com", "Username": "AWS", "Secret": "ecr-token"}`), nil
+	}
+
+	// Credentials stored in the config file are used for registries without credential helpers
+	authConfig, err := getRegistryAuthConfig("kurtosistech/controller:1.0", configFilepath, runHelper)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &types.AuthConfig{
+		Username:      "hub-user",
+		Password:      "hub-password",
+		ServerAddress: "https://index.docker.io/v1/",
+	}, authConfig)
+
+	authConfig, err = getRegistryAuthConfig("123456789012.dkr.ecr.us-east-1.amazonaws.com/service:1.0", configFilepath, runHelper)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &types.AuthConfig{
+		Username:      "AWS",
+		Password:      "ecr-token",
+		ServerAddress: "123456789012.dkr.ecr.us-east-1.amazonaws.com",
+	}, authConfig)
+	assert.Equal(t, "123456789012.dkr.ecr.us-east-1.amazonaws.com", helperCalls["ecr-login"])
+
+	// Registries the config file doesn't know about get no credentials
+	authConfig, err = getRegistryAuthConfig("gcr.io/project/service:1.0", configFilepath, runHelper)
+	assert.NilError(t, err)
+	assert.Assert(t, authConfig == nil)
+
+	authConfig, err = getRegistryAuthConfig("alpine:3.12", path.Join(configDirpath, "nonexistent.json"), runHelper)
+	assert.NilError(t, err)
+	assert.Assert(t, authConfig == nil)
+}
+
+func TestGettingRegistryAuthConfigsFromCredentialStore(t *testing.T) {
+	configDirpath, err := ioutil.TempDir("", "test-docker-config")
+	assert.NilError(t, err)
+	defer os.RemoveAll(configDirpath)
+	configFilepath := path.Join(configDirpath, "config.json")
+	assert.NilError(t, ioutil.WriteFile(configFilepath, []byte(`{"credsStore": "desktop"}`), 0644))
+
+	runHelper := func(helperName string, serverUrl string) ([]byte, error) {
+		if serverUrl == "gcr.io" {
+			return []byte(`{"Username": "<token>", "Secret": "gcr-identity-token"}`), nil
+		}
+		return []byte{}, nil
+	}
+
+	authConfig, err := getRegistryAuthConfig("gcr.io/project/service:1.0", configFilepath, runHelper)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &types.AuthConfig{IdentityToken: "gcr-identity-token", ServerAddress: "gcr.io"}, authConfig)
+
+	// The store having nothing for a registry isn't an error
+	authConfig, err = getRegistryAuthConfig("alpine:3.12", configFilepath, runHelper)
+	assert.NilError(t, err)
+	assert.Assert(t, authConfig == nil)
+}
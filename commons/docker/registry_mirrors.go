@@ -0,0 +1,160 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/go-connections/nat"
+	"github.com/palantir/stacktrace"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	// The image run by StartLocalRegistryMirror, which is Docker's own registry in pull-through cache mode
+	REGISTRY_MIRROR_IMAGE = "registry:2"
+
+	// The name of the container (& the volume holding its cache) started by StartLocalRegistryMirror, which are kept
+	//  between runs so that the cache stays warm
+	LOCAL_REGISTRY_MIRROR_CONTAINER_NAME = "kurtosis-registry-mirror"
+	localRegistryMirrorVolumeName        = "kurtosis-registry-mirror-cache"
+
+	// The port on the host that the local mirror is published on; the Docker engine trusts plain HTTP registries on
+	//  localhost, so no TLS setup is needed
+	LOCAL_REGISTRY_MIRROR_HOST_PORT = 5000
+
+	// The registry that images without an explicit registry are pulled from, which is the registry that mirrors cache
+	dockerHubDomain = "docker.io"
+
+	registryMirrorPort         = nat.Port("5000/tcp")
+	registryMirrorDataDirpath  = "/var/lib/registry"
+	registryMirrorRemoteUrlEnv = "REGISTRY_PROXY_REMOTEURL=https://registry-1.docker.io"
+)
+
+/*
+Makes the manager pull Docker Hub images through the given pull-through registry mirror (e.g. one on the local network,
+	for CI machines behind slow links or rate-limited by Docker Hub). Images are pulled from the mirror & then tagged with
+	their usual names, so nothing else needs to know about the mirror. If pulling an image through the mirror fails, it's
+	pulled from Docker Hub as usual. Images from other registries are always pulled from their registry.
+
+Args:
+	mirrorHost: The host (& port) of the mirror, e.g. "localhost:5000" or "mirror.internal:5000"
+ */
+func (manager *DockerManager) UseRegistryMirror(mirrorHost string) {
+	manager.registryMirror = mirrorHost
+}
+
+/*
+Starts a pull-through registry mirror of Docker Hub on this Docker host, for use with UseRegistryMirror, or reuses the one
+	started by an earlier run. The mirror's container & cache are kept after the run, so later runs only need to pull
+	images that changed.
+
+Returns:
+	The host of the mirror, to pass to UseRegistryMirror
+ */
+func (manager DockerManager) StartLocalRegistryMirror(context context.Context) (string, error) {
+	mirrorHost := fmt.Sprintf("localhost:%v", LOCAL_REGISTRY_MIRROR_HOST_PORT)
+
+	nameFilter := filters.NewArgs(filters.Arg("name", "^/" + LOCAL_REGISTRY_MIRROR_CONTAINER_NAME + "$"))
+	existingContainers, err := manager.dockerClient.ContainerList(context, types.ContainerListOptions{All: true, Filters: nameFilter})
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred checking for an existing registry mirror container")
+	}
+	if len(existingContainers) > 0 {
+		existingContainer := existingContainers[0]
+		if existingContainer.State != "running" {
+			if err := manager.dockerClient.ContainerStart(context, existingContainer.ID, types.ContainerStartOptions{}); err != nil {
+				return "", stacktrace.Propagate(err, "An error occurred restarting the existing registry mirror container")
+			}
+		}
+		manager.log.Infof("Using the existing registry mirror at %v", mirrorHost)
+		return mirrorHost, nil
+	}
+
+	if err := manager.PullImageIfMissing(context, REGISTRY_MIRROR_IMAGE); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred pulling the registry mirror image")
+	}
+	containerConfig := &container.Config{
+		Image:        REGISTRY_MIRROR_IMAGE,
+		Env:          []string{registryMirrorRemoteUrlEnv},
+		ExposedPorts: nat.PortSet{registryMirrorPort: struct{}{}},
+	}
+	hostConfig := &container.HostConfig{
+		Binds: []string{localRegistryMirrorVolumeName + ":" + registryMirrorDataDirpath},
+		PortBindings: nat.PortMap{
+			registryMirrorPort: []nat.PortBinding{{
+				HostIP:   "127.0.0.1",
+				HostPort: fmt.Sprintf("%v", LOCAL_REGISTRY_MIRROR_HOST_PORT),
+			}},
+		},
+		RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+	}
+	resp, err := manager.dockerClient.ContainerCreate(context, containerConfig, hostConfig, nil, LOCAL_REGISTRY_MIRROR_CONTAINER_NAME)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred creating the registry mirror container")
+	}
+	if err := manager.dockerClient.ContainerStart(context, resp.ID, types.ContainerStartOptions{}); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred starting the registry mirror container")
+	}
+	manager.log.Infof("Started a registry mirror at %v", mirrorHost)
+	return mirrorHost, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Pulls the given image through the manager's registry mirror, tagging it with its usual name.
+
+Returns:
+	False (with no error) if the image can't come from the mirror (e.g. because it's not from Docker Hub)
+ */
+func (manager DockerManager) pullImageThroughMirror(context context.Context, imageName string, platform string) (bool, error) {
+	mirroredImageName, canBeMirrored := getMirroredImageName(manager.registryMirror, imageName)
+	if !canBeMirrored {
+		return false, nil
+	}
+
+	manager.log.Debugf("Pulling image %v through registry mirror %v...", imageName, manager.registryMirror)
+	out, err := manager.dockerClient.ImagePull(context, mirroredImageName, types.ImagePullOptions{Platform: platform})
+	if err != nil {
+		return false, stacktrace.Propagate(err, "Failed to pull image %v from registry mirror %v", imageName, manager.registryMirror)
+	}
+	defer out.Close()
+	if _, err := io.Copy(ioutil.Discard, out); err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred reading the progress of pulling image %v from registry mirror %v", imageName, manager.registryMirror)
+	}
+
+	if err := manager.dockerClient.ImageTag(context, mirroredImageName, imageName); err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred tagging mirrored image %v as %v", mirroredImageName, imageName)
+	}
+	// Removing the mirror's tag only untags the image, since it's still tagged with its usual name
+	if _, err := manager.dockerClient.ImageRemove(context, mirroredImageName, types.ImageRemoveOptions{}); err != nil {
+		manager.log.Debugf("Couldn't remove the mirror's tag %v from image %v: %v", mirroredImageName, imageName, err)
+	}
+	return true, nil
+}
+
+/*
+Gets the name that the given image has on the given mirror of Docker Hub (e.g. "alpine:3.12" ->
+	"localhost:5000/library/alpine:3.12"), returning false if the image isn't from Docker Hub, can't be parsed, or is
+	pinned to a digest (because Docker can't tag images with digest references).
+ */
+func getMirroredImageName(mirrorHost string, imageName string) (string, bool) {
+	if mirrorHost == "" {
+		return "", false
+	}
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil || reference.Domain(named) != dockerHubDomain {
+		return "", false
+	}
+	if _, isDigested := named.(reference.Digested); isDigested {
+		return "", false
+	}
+	tagged, ok := reference.TagNameOnly(named).(reference.Tagged)
+	if !ok {
+		return "", false
+	}
+	return mirrorHost + "/" + reference.Path(named) + ":" + tagged.Tag(), true
+}
@@ -0,0 +1,24 @@
+package docker
+
+import (
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestGettingMirroredImageNames(t *testing.T) {
+	mirroredImageName, canBeMirrored := getMirroredImageName("localhost:5000", "alpine:3.12")
+	assert.Assert(t, canBeMirrored)
+	assert.Equal(t, "localhost:5000/library/alpine:3.12", mirroredImageName)
+
+	mirroredImageName, canBeMirrored = getMirroredImageName("localhost:5000", "kurtosistech/controller")
+	assert.Assert(t, canBeMirrored)
+	assert.Equal(t, "localhost:5000/kurtosistech/controller:latest", mirroredImageName)
+
+	// Images from other registries, pinned to digests, or with no mirror configured aren't mirrored
+	_, canBeMirrored = getMirroredImageName("localhost:5000", "gcr.io/project/image:1.0")
+	assert.Assert(t, !canBeMirrored)
+	_, canBeMirrored = getMirroredImageName("localhost:5000", "alpine@sha256:a15790640a6690aa1730c38cf0a440e2aa44aaca9b0e8931a9f2b0d7cc90fd65")
+	assert.Assert(t, !canBeMirrored)
+	_, canBeMirrored = getMirroredImageName("", "alpine:3.12")
+	assert.Assert(t, !canBeMirrored)
+}
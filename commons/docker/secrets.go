@@ -0,0 +1,199 @@
+package docker
+
+import (
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// What secret values are replaced with wherever the framework would otherwise show them (logs, the Docker audit log,
+	//  exported artifacts, & error messages)
+	REDACTED_SECRET_PLACEHOLDER = "[REDACTED]"
+
+	// The permissions of the files that secrets are written to in containers, which are read-only but readable by every
+	//  user because injected files are owned by root, whereas many images run their service as another user
+	SECRET_FILE_MODE os.FileMode = 0444
+)
+
+// Every secret value known to this process, so that they can be redacted from any text the framework outputs
+var knownSecretValues = &secretValueRegistry{
+	mutex:  &sync.Mutex{},
+	values: map[string]bool{},
+}
+
+/*
+The value of a credential (e.g. an API key) to inject into a container. Printing the value with the fmt package shows
+	REDACTED_SECRET_PLACEHOLDER rather than the value, and once the value has been created with one of the functions
+	below (or has been injected into a container), RedactSecrets replaces it wherever it appears.
+ */
+type SecretValue string
+
+/*
+Creates a secret value from a literal, e.g. one the test suite got from its own configuration.
+ */
+func NewSecretValue(value string) SecretValue {
+	knownSecretValues.add(value)
+	return SecretValue(value)
+}
+
+/*
+Reads a secret value from the given file (e.g. one mounted into the controller container by CI), without any trailing
+	newline.
+ */
+func ReadSecretFromFile(filepath string) (SecretValue, error) {
+	valueBytes, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred reading secret file %v", filepath)
+	}
+	return NewSecretValue(strings.TrimRight(string(valueBytes), "\r\n")), nil
+}
+
+/*
+Reads a secret value from the given environment variable of this process (e.g. one passed to the controller container
+	with the initializer's custom controller environment variables), which must be set.
+ */
+func ReadSecretFromEnv(envVariable string) (SecretValue, error) {
+	value, found := os.LookupEnv(envVariable)
+	if !found {
+		return "", stacktrace.NewError("Secret environment variable %v isn't set", envVariable)
+	}
+	return NewSecretValue(value), nil
+}
+
+func (value SecretValue) String() string {
+	return REDACTED_SECRET_PLACEHOLDER
+}
+
+func (value SecretValue) GoString() string {
+	return REDACTED_SECRET_PLACEHOLDER
+}
+
+/*
+A credential to inject into a container (see ContainerOptions.Secrets), as an environment variable, a file, or both.
+ */
+type Secret struct {
+	Value SecretValue
+
+	// The environment variable that the container will get the secret as (empty to not inject it as one)
+	EnvVariable string
+
+	// Absolute path in the container of a file that the secret will be written to before the container starts (empty to
+	//  not inject it as a file)
+	ContainerFilepath string
+}
+
+/*
+Replaces every known secret value in the given text with REDACTED_SECRET_PLACEHOLDER.
+ */
+func RedactSecrets(text string) string {
+	return knownSecretValues.redact(text)
+}
+
+/*
+Returns an error like the given one, but with known secret values redacted from its message, or the given error itself
+	if its message doesn't contain any.
+ */
+func RedactSecretsFromError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	redactedMessage := RedactSecrets(message)
+	if redactedMessage == message {
+		return err
+	}
+	return stacktrace.NewError("%v", redactedMessage)
+}
+
+/*
+A logrus hook that redacts known secret values from log messages & string fields, for adding to loggers that may log
+	values derived from secrets (e.g. errors from the Docker engine that echo a container's configuration).
+ */
+type SecretRedactingHook struct {}
+
+func NewSecretRedactingHook() *SecretRedactingHook {
+	return &SecretRedactingHook{}
+}
+
+func (hook SecretRedactingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (hook SecretRedactingHook) Fire(entry *logrus.Entry) error {
+	entry.Message = RedactSecrets(entry.Message)
+	redactedData := logrus.Fields{}
+	for key, value := range entry.Data {
+		switch typedValue := value.(type) {
+		case string:
+			redactedData[key] = RedactSecrets(typedValue)
+		case error:
+			redactedData[key] = RedactSecrets(typedValue.Error())
+		default:
+			redactedData[key] = value
+		}
+	}
+	// The entry's data may be shared with the logger it was created from, so it's replaced rather than modified
+	entry.Data = redactedData
+	return nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Gets the fixtures that write the given secrets to files in a container
+func getSecretFixtures(secrets []Secret) []FileFixture {
+	result := []FileFixture{}
+	for _, secret := range secrets {
+		if secret.ContainerFilepath == "" {
+			continue
+		}
+		result = append(result, FileFixture{
+			Contents:          []byte(secret.Value),
+			ContainerFilepath: secret.ContainerFilepath,
+			Mode:              SECRET_FILE_MODE,
+		})
+	}
+	return result
+}
+
+// =========================== SECRET VALUE REGISTRY =========================================
+/*
+NOTE: This is thread-safe!
+ */
+type secretValueRegistry struct {
+	mutex *sync.Mutex
+
+	// "Set" of the secret values
+	values map[string]bool
+}
+
+func (registry *secretValueRegistry) add(value string) {
+	// Redacting the empty string would mangle everything
+	if value == "" {
+		return
+	}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.values[value] = true
+}
+
+func (registry *secretValueRegistry) redact(text string) string {
+	registry.mutex.Lock()
+	values := make([]string, 0, len(registry.values))
+	for value, _ := range registry.values {
+		values = append(values, value)
+	}
+	registry.mutex.Unlock()
+
+	// Longer values go first, so that a secret containing another secret is redacted whole
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for _, value := range values {
+		text = strings.Replace(text, value, REDACTED_SECRET_PLACEHOLDER, -1)
+	}
+	return text
+}
@@ -0,0 +1,122 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/docker/docker/client"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// NOTE: Secret values are known to the whole process once created, so every test uses distinct, unlikely values
+
+func TestSecretsAreRedacted(t *testing.T) {
+	shortSecret := NewSecretValue("test-secret-short")
+	NewSecretValue("test-secret-short-and-long")
+
+	assert.Equal(t, REDACTED_SECRET_PLACEHOLDER, fmt.Sprintf("%v", shortSecret))
+	assert.Equal(t, REDACTED_SECRET_PLACEHOLDER, fmt.Sprintf("%#v", shortSecret))
+	assert.Equal(
+		t,
+		"--key=[REDACTED] --other-key=[REDACTED]",
+		RedactSecrets("--key=test-secret-short --other-key=test-secret-short-and-long"))
+
+	err := stacktrace.Propagate(stacktrace.NewError("Bad key test-secret-short"), "An error occurred")
+	redactedErr := RedactSecretsFromError(err)
+	assert.Assert(t, !strings.Contains(redactedErr.Error(), "test-secret-short"))
+	assert.ErrorContains(t, redactedErr, "Bad key [REDACTED]")
+	unrelatedErr := stacktrace.NewError("Something else went wrong")
+	assert.Equal(t, unrelatedErr, RedactSecretsFromError(unrelatedErr))
+}
+
+func TestReadingSecrets(t *testing.T) {
+	secretsDirpath, err := ioutil.TempDir("", "test-secrets")
+	assert.NilError(t, err)
+	defer os.RemoveAll(secretsDirpath)
+	secretFilepath := path.Join(secretsDirpath, "api-key")
+	assert.NilError(t, ioutil.WriteFile(secretFilepath, []byte("test-secret-from-file\n"), 0600))
+
+	fileSecret, err := ReadSecretFromFile(secretFilepath)
+	assert.NilError(t, err)
+	assert.Equal(t, SecretValue("test-secret-from-file"), fileSecret)
+	assert.Equal(t, "[REDACTED]", RedactSecrets("test-secret-from-file"))
+
+	envVariable := "KURTOSIS_TEST_SECRET_FROM_ENV"
+	_, err = ReadSecretFromEnv(envVariable)
+	assert.ErrorContains(t, err, "isn't set")
+	os.Setenv(envVariable, "test-secret-from-env")
+	defer os.Unsetenv(envVariable)
+	envSecret, err := ReadSecretFromEnv(envVariable)
+	assert.NilError(t, err)
+	assert.Equal(t, SecretValue("test-secret-from-env"), envSecret)
+	assert.Equal(t, "[REDACTED]", RedactSecrets("test-secret-from-env"))
+}
+
+func TestSecretRedactingHook(t *testing.T) {
+	NewSecretValue("test-secret-logged")
+	output := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = output
+	logger.AddHook(NewSecretRedactingHook())
+
+	logger.WithField("token", "test-secret-logged").Errorf("Service rejected key %v", "test-secret-logged")
+	assert.Assert(t, !strings.Contains(output.String(), "test-secret-logged"))
+	assert.Assert(t, strings.Contains(output.String(), "Service rejected key [REDACTED]"))
+}
+
+func TestSecretsAreRedactedFromAuditLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	auditOutput := &bytes.Buffer{}
+	dockerClient, err := client.NewClientWithOpts(
+		client.WithHost("tcp://" + strings.TrimPrefix(server.URL, "http://")),
+		client.WithVersion("1.40"),
+		WithAuditLog(NewAuditLog(auditOutput)))
+	assert.NilError(t, err)
+
+	options := ContainerOptions{Secrets: []Secret{{Value: NewSecretValue("test-secret-audited"), EnvVariable: "API_KEY"}}}
+	manager := &DockerManager{log: logrus.NewEntry(logrus.StandardLogger()), dockerClient: dockerClient}
+	containerConfig, err := manager.getContainerCfg("test-image", nil, nil, map[string]string{}, options)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"API_KEY=test-secret-audited"}, containerConfig.Env)
+
+	dockerClient.ContainerCreate(context.Background(), containerConfig, nil, nil, "")
+	assert.Assert(t, strings.Contains(auditOutput.String(), "API_KEY=[REDACTED]"))
+	assert.Assert(t, !strings.Contains(auditOutput.String(), "test-secret-audited"))
+}
+
+func TestFakeInjectsSecrets(t *testing.T) {
+	fake := NewFakeDockerManager()
+	options := ContainerOptions{Secrets: []Secret{{Value: "test-secret-faked", EnvVariable: "API_KEY"}}}
+	containerId, err := fake.CreateContainer(
+		context.Background(),
+		"test-image",
+		"test-network",
+		net.ParseIP("172.23.0.2"),
+		nil,
+		nil,
+		map[string]string{},
+		map[string]string{},
+		map[string]string{},
+		options)
+	assert.NilError(t, err)
+
+	container, found := fake.GetContainer(containerId)
+	assert.Assert(t, found)
+	assert.Equal(t, "test-secret-faked", container.EnvVariables["API_KEY"])
+	inspectJson, err := fake.GetContainerInspectJson(context.Background(), containerId)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(string(inspectJson), "test-secret-faked"))
+}
@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/palantir/stacktrace"
+	"io/ioutil"
+)
+
+const (
+	// Disables a security profile for a container (see ContainerOptions.SeccompProfile & ContainerOptions.AppArmorProfile),
+	//  e.g. to test how a service behaves without the Docker engine's default restrictions
+	UNCONFINED_SECURITY_PROFILE = "unconfined"
+)
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets the Docker security options (e.g. "seccomp=<profile JSON>") for a container with the given options, loading its
+	seccomp profile if it has one.
+ */
+func getSecurityOpts(options ContainerOptions) ([]string, error) {
+	result := []string{}
+	if options.SeccompProfile != "" {
+		seccompProfile := options.SeccompProfile
+		if seccompProfile != UNCONFINED_SECURITY_PROFILE {
+			// Unlike the Docker CLI, the Docker API takes the profile itself rather than its filepath
+			profileBytes, err := ioutil.ReadFile(options.SeccompProfile)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "An error occurred reading seccomp profile %v", options.SeccompProfile)
+			}
+			compactedProfile := &bytes.Buffer{}
+			if err := json.Compact(compactedProfile, profileBytes); err != nil {
+				return nil, stacktrace.Propagate(err, "Seccomp profile %v isn't valid JSON", options.SeccompProfile)
+			}
+			seccompProfile = compactedProfile.String()
+		}
+		result = append(result, "seccomp=" + seccompProfile)
+	}
+	if options.AppArmorProfile != "" {
+		result = append(result, "apparmor=" + options.AppArmorProfile)
+	}
+	if options.NoNewPrivileges {
+		result = append(result, "no-new-privileges")
+	}
+	return result, nil
+}
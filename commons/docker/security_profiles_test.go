@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSecurityProfilesInHostConfig(t *testing.T) {
+	profilesDirpath, err := ioutil.TempDir("", "test-profiles")
+	assert.NilError(t, err)
+	defer os.RemoveAll(profilesDirpath)
+	seccompProfileFilepath := path.Join(profilesDirpath, "seccomp.json")
+	seccompProfile := "{\n  \"defaultAction\": \"SCMP_ACT_ERRNO\",\n  \"syscalls\": []\n}\n"
+	assert.NilError(t, ioutil.WriteFile(seccompProfileFilepath, []byte(seccompProfile), 0644))
+
+	manager := &DockerManager{log: logrus.NewEntry(logrus.StandardLogger())}
+	hostConfig, err := manager.getContainerHostConfig(nil, nil, nil, ContainerOptions{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{}, hostConfig.SecurityOpt)
+
+	hostConfig, err = manager.getContainerHostConfig(nil, nil, nil, ContainerOptions{
+		SeccompProfile:  seccompProfileFilepath,
+		AppArmorProfile: "docker-hardened",
+		NoNewPrivileges: true,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(
+		t,
+		[]string{
+			"seccomp={\"defaultAction\":\"SCMP_ACT_ERRNO\",\"syscalls\":[]}",
+			"apparmor=docker-hardened",
+			"no-new-privileges",
+		},
+		hostConfig.SecurityOpt)
+
+	hostConfig, err = manager.getContainerHostConfig(nil, nil, nil, ContainerOptions{
+		SeccompProfile:  UNCONFINED_SECURITY_PROFILE,
+		AppArmorProfile: UNCONFINED_SECURITY_PROFILE,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"seccomp=unconfined", "apparmor=unconfined"}, hostConfig.SecurityOpt)
+
+	assert.NilError(t, ioutil.WriteFile(seccompProfileFilepath, []byte("{not json"), 0644))
+	_, err = manager.getContainerHostConfig(nil, nil, nil, ContainerOptions{SeccompProfile: seccompProfileFilepath})
+	assert.ErrorContains(t, err, "isn't valid JSON")
+}
@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
+	"github.com/palantir/stacktrace"
+	"net/http"
+)
+
+/*
+The TLS material & verification settings for connecting to a Docker engine that's secured with TLS (e.g. a remote engine
+	listening on tcp://<host>:2376), for when it can't (or shouldn't) come from the DOCKER_CERT_PATH & DOCKER_TLS_VERIFY
+	environment variables.
+ */
+type TlsOptions struct {
+	// The CA certificate to verify the engine's certificate against; if empty, the system's CAs are used
+	CaCertFilepath string
+
+	// The client certificate & its key to present to the engine; if either is empty, no client certificate is presented
+	CertFilepath string
+	KeyFilepath string
+
+	// The name to verify the engine's certificate against, if it's not the engine's host (e.g. when connecting by IP)
+	ServerName string
+
+	// Whether to skip verifying the engine's certificate entirely
+	// NOTE: This leaves the connection open to man-in-the-middle attacks, so should only be used on trusted networks
+	InsecureSkipVerify bool
+}
+
+/*
+Returns a Docker client option that connects to the engine over TLS using the given options, overriding any TLS settings
+	from the environment.
+
+NOTE: This must come after any options that set the client's HTTP client (e.g. client.FromEnv) and before WithRateLimiting
+	& WithAuditLog, since it configures the client's original HTTP transport.
+ */
+func WithTls(options TlsOptions) client.Opt {
+	return func(dockerClient *client.Client) error {
+		tlsConfig, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:             options.CaCertFilepath,
+			CertFile:           options.CertFilepath,
+			KeyFile:            options.KeyFilepath,
+			InsecureSkipVerify: options.InsecureSkipVerify,
+			// A CA given explicitly should be the only one trusted, rather than being added to the system's
+			ExclusiveRootPools: true,
+		})
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred loading the Docker engine TLS material")
+		}
+		tlsConfig.ServerName = options.ServerName
+
+		httpClient := dockerClient.HTTPClient()
+		httpTransport, ok := httpClient.Transport.(*http.Transport)
+		if !ok {
+			return stacktrace.NewError(
+				"Couldn't apply TLS settings to the Docker client's transport of type %T; WithTls must come before options that wrap the transport",
+				httpClient.Transport)
+		}
+		httpTransport.TLSClientConfig = tlsConfig
+		if err := client.WithHTTPClient(httpClient)(dockerClient); err != nil {
+			return stacktrace.Propagate(err, "An error occurred setting the Docker client's HTTP client")
+		}
+		return client.WithScheme("https")(dockerClient)
+	}
+}
@@ -0,0 +1,60 @@
+package docker
+
+import (
+	"context"
+	"encoding/pem"
+	"github.com/docker/docker/client"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestTlsVerifiesEngineCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("API-Version", "1.40")
+		writer.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	certsDirpath, err := ioutil.TempDir("", "test-certs")
+	assert.NilError(t, err)
+	defer os.RemoveAll(certsDirpath)
+	caCertFilepath := path.Join(certsDirpath, "ca.pem")
+	caCertPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NilError(t, ioutil.WriteFile(caCertFilepath, caCertPem, 0644))
+
+	// The test server's certificate is for "example.com" & 127.0.0.1
+	_, err = newTestTlsClient(t, server, TlsOptions{CaCertFilepath: caCertFilepath}).Ping(context.Background())
+	assert.NilError(t, err)
+	_, err = newTestTlsClient(t, server, TlsOptions{CaCertFilepath: caCertFilepath, ServerName: "example.com"}).Ping(context.Background())
+	assert.NilError(t, err)
+	_, err = newTestTlsClient(t, server, TlsOptions{CaCertFilepath: caCertFilepath, ServerName: "other.com"}).Ping(context.Background())
+	assert.ErrorContains(t, err, "certificate")
+
+	// Without the CA, the server's certificate can't be verified...
+	_, err = newTestTlsClient(t, server, TlsOptions{}).Ping(context.Background())
+	assert.ErrorContains(t, err, "certificate")
+	// ...unless verification is skipped
+	_, err = newTestTlsClient(t, server, TlsOptions{InsecureSkipVerify: true}).Ping(context.Background())
+	assert.NilError(t, err)
+}
+
+func TestTlsRejectsMissingMaterial(t *testing.T) {
+	_, err := client.NewClientWithOpts(WithTls(TlsOptions{CaCertFilepath: "/nonexistent/ca.pem"}))
+	assert.ErrorContains(t, err, "TLS material")
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func newTestTlsClient(t *testing.T, server *httptest.Server, options TlsOptions) *client.Client {
+	dockerClient, err := client.NewClientWithOpts(
+		client.WithHost("tcp://" + strings.TrimPrefix(server.URL, "https://")),
+		client.WithVersion("1.40"),
+		WithTls(options))
+	assert.NilError(t, err)
+	return dockerClient
+}
@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"context"
+	"github.com/palantir/stacktrace"
+	"strings"
+)
+
+const (
+	// The network driver that Windows container hosts use in place of "bridge", NATing containers behind the host
+	WINDOWS_NETWORK_DRIVER = "nat"
+
+	// The named pipe that a Windows Docker engine listens on, as a DOCKER_HOST value & as a path on the host (e.g. for
+	//  bind-mounting into containers that need to reach the engine, in place of /var/run/docker.sock)
+	WINDOWS_DOCKER_ENGINE_HOST     = "npipe:////./pipe/docker_engine"
+	WINDOWS_DOCKER_ENGINE_PIPEPATH = `\\.\pipe\docker_engine`
+
+	// The OS type that Windows Docker engines report
+	windowsOsType = "windows"
+
+	// The drive that POSIX-style container paths are placed on in Windows containers
+	windowsSystemDrive = "C:"
+
+	windowsPathSeparator = `\`
+)
+
+/*
+Makes the manager create Windows containers: networks use the WINDOWS_NETWORK_DRIVER (as Windows hosts have no bridge
+	driver), and POSIX-style container paths that volumes & files are mounted at (e.g. "/shared") are translated to paths
+	on the containers' system drive (e.g. "C:\shared"). Programs in Windows containers resolve POSIX-style paths against
+	the current drive, so paths passed to them in start commands & env variables work unchanged.
+
+NOTE: Windows containers can only bind-mount directories & named pipes, not single files.
+ */
+func (manager *DockerManager) UseWindowsContainers() {
+	manager.windowsContainers = true
+}
+
+/*
+Returns true if the manager creates Windows containers (see UseWindowsContainers).
+ */
+func (manager DockerManager) IsUsingWindowsContainers() bool {
+	return manager.windowsContainers
+}
+
+/*
+Switches the manager to Windows containers (see UseWindowsContainers) if its Docker engine runs Windows containers, so that
+	the same test suite configuration works against Linux & Windows engines.
+
+Returns:
+	True if the engine runs Windows containers
+ */
+func (manager *DockerManager) DetectWindowsContainers(context context.Context) (bool, error) {
+	info, err := manager.dockerClient.Info(context)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred getting the OS type of the Docker engine")
+	}
+	if info.OSType != windowsOsType {
+		return false, nil
+	}
+	manager.UseWindowsContainers()
+	return true, nil
+}
+
+/*
+Translates the given POSIX-style container path to the equivalent path in a Windows container (e.g. "/shared/logs" ->
+	"C:\shared\logs"). Paths that are already Windows paths (with a drive letter, or a "\\" UNC or named pipe prefix) are
+	returned unchanged.
+ */
+func ToWindowsContainerPath(containerPath string) string {
+	if isWindowsPath(containerPath) {
+		return containerPath
+	}
+	return windowsSystemDrive + strings.ReplaceAll(containerPath, "/", windowsPathSeparator)
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Gets the driver of the networks that the manager creates on a single host
+func (manager DockerManager) getNetworkDriver() string {
+	if manager.windowsContainers {
+		return WINDOWS_NETWORK_DRIVER
+	}
+	return DOCKER_NETWORK_DRIVER
+}
+
+// Gets the path that a mount at the given container path is placed at in the manager's containers
+func (manager DockerManager) getMountDestination(containerPath string) string {
+	if manager.windowsContainers {
+		return ToWindowsContainerPath(containerPath)
+	}
+	return containerPath
+}
+
+func isWindowsPath(path string) bool {
+	if strings.HasPrefix(path, `\\`) {
+		return true
+	}
+	return len(path) >= 2 && path[1] == ':' && ((path[0] >= 'a' && path[0] <= 'z') || (path[0] >= 'A' && path[0] <= 'Z'))
+}
@@ -0,0 +1,24 @@
+package docker
+
+import (
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestToWindowsContainerPath(t *testing.T) {
+	assert.Equal(t, `C:\shared`, ToWindowsContainerPath("/shared"))
+	assert.Equal(t, `C:\shared\logs\test.log`, ToWindowsContainerPath("/shared/logs/test.log"))
+	assert.Equal(t, `D:\data`, ToWindowsContainerPath(`D:\data`))
+	assert.Equal(t, WINDOWS_DOCKER_ENGINE_PIPEPATH, ToWindowsContainerPath(WINDOWS_DOCKER_ENGINE_PIPEPATH))
+}
+
+func TestWindowsContainersMode(t *testing.T) {
+	manager := &DockerManager{}
+	assert.Equal(t, DOCKER_NETWORK_DRIVER, manager.getNetworkDriver())
+	assert.Equal(t, "/shared", manager.getMountDestination("/shared"))
+
+	manager.UseWindowsContainers()
+	assert.Assert(t, manager.IsUsingWindowsContainers())
+	assert.Equal(t, WINDOWS_NETWORK_DRIVER, manager.getNetworkDriver())
+	assert.Equal(t, `C:\shared`, manager.getMountDestination("/shared"))
+}
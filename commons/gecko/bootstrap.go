@@ -0,0 +1,189 @@
+package gecko
+
+import (
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// Bootstrap nodes get service IDs of BOOTSTRAP_SERVICE_ID_PREFIX followed by their index (e.g. "bootstrap-0")
+	BOOTSTRAP_SERVICE_ID_PREFIX = "bootstrap-"
+
+	bootstrapFlagsSeparator = ","
+)
+
+/*
+An interface that the service of a Gecko ServiceInitializerCore must implement for its nodes to be bootstrap nodes, as
+	Gecko nodes must be told their bootstrap nodes' IDs.
+ */
+type NodeIdProvider interface {
+	// Gets the node's ID (e.g. from its info.getNodeID API method), which is only called once the node is available
+	GetNodeId() (string, error)
+}
+
+/*
+A set of K bootstrap nodes of a Gecko network, which starts the bootstrap nodes before any other node & gives every node the
+	--bootstrap-ips & --bootstrap-ids flags for them, using the bootstrap nodes' actual IPs & node IDs. Each bootstrap node
+	bootstraps from the bootstrap nodes started before it, so the first bootstraps from no one.
+
+The set is shared between the test, which starts the nodes with Start & AddNode, and the Gecko ServiceInitializerCore, whose
+	GetStartCommand adds GetBootstrapFlags to its command.
+ */
+type BootstrapNodeSet struct {
+	mutex *sync.Mutex
+
+	configurationId networks.ConfigurationID
+
+	// The bootstrap nodes' service IDs, in the order they're started in
+	serviceIds []networks.ServiceID
+
+	stakingPort int
+
+	// Mapping of service ID -> node ID of the bootstrap nodes that have been started
+	nodeIds map[networks.ServiceID]string
+}
+
+/*
+Creates a set of bootstrap nodes, which aren't started until Start is called.
+
+Args:
+	configurationId: The configuration the bootstrap nodes are created with, whose core's service must implement NodeIdProvider
+	numNodes: The number of bootstrap nodes
+	stakingPort: The port the nodes listen for staking connections on, that other nodes connect to the bootstrap nodes at
+ */
+func NewBootstrapNodeSet(configurationId networks.ConfigurationID, numNodes int, stakingPort int) (*BootstrapNodeSet, error) {
+	if numNodes < 1 {
+		return nil, stacktrace.NewError("A bootstrap node set needs at least one node, but got %v", numNodes)
+	}
+	serviceIds := make([]networks.ServiceID, 0, numNodes)
+	for i := 0; i < numNodes; i++ {
+		serviceIds = append(serviceIds, networks.ServiceID(BOOTSTRAP_SERVICE_ID_PREFIX + strconv.Itoa(i)))
+	}
+	return &BootstrapNodeSet{
+		mutex:           &sync.Mutex{},
+		configurationId: configurationId,
+		serviceIds:      serviceIds,
+		stakingPort:     stakingPort,
+		nodeIds:         make(map[networks.ServiceID]string),
+	}, nil
+}
+
+/*
+Gets the service IDs of the bootstrap nodes, in the order they're started in.
+ */
+func (set *BootstrapNodeSet) GetServiceIds() []networks.ServiceID {
+	return append([]networks.ServiceID{}, set.serviceIds...)
+}
+
+/*
+Adds the bootstrap nodes to the given network one at a time, waiting for each to become available & getting its node ID
+	before adding the next.
+ */
+func (set *BootstrapNodeSet) Start(network *networks.ServiceNetwork) error {
+	dependencies := make(map[networks.ServiceID]bool)
+	for _, serviceId := range set.serviceIds {
+		availabilityChecker, err := network.AddService(set.configurationId, serviceId, copyServiceIdSet(dependencies))
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred adding bootstrap node %v", serviceId)
+		}
+		if err := availabilityChecker.WaitForStartup(); err != nil {
+			return stacktrace.Propagate(err, "An error occurred waiting for bootstrap node %v to become available", serviceId)
+		}
+		node, err := network.GetService(serviceId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting bootstrap node %v", serviceId)
+		}
+		nodeIdProvider, ok := node.Service.(NodeIdProvider)
+		if !ok {
+			return stacktrace.NewError("The service of bootstrap node %v, of type %T, doesn't implement NodeIdProvider", serviceId, node.Service)
+		}
+		nodeId, err := nodeIdProvider.GetNodeId()
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the node ID of bootstrap node %v", serviceId)
+		}
+		set.mutex.Lock()
+		set.nodeIds[serviceId] = nodeId
+		set.mutex.Unlock()
+		dependencies[serviceId] = true
+	}
+	return nil
+}
+
+/*
+Adds a non-bootstrap node to the given network, depending on every bootstrap node so that it gets all of them in its
+	bootstrap flags.
+
+Args:
+	network: The network to add the node to
+	configurationId: The configuration to create the node with, whose core adds GetBootstrapFlags to its start command
+	serviceId: The node's service ID
+
+Returns:
+	An AvailabilityChecker for checking when the node is available
+ */
+func (set *BootstrapNodeSet) AddNode(
+			network *networks.ServiceNetwork,
+			configurationId networks.ConfigurationID,
+			serviceId networks.ServiceID) (*services.ServiceAvailabilityChecker, error) {
+	set.mutex.Lock()
+	numStarted := len(set.nodeIds)
+	set.mutex.Unlock()
+	if numStarted < len(set.serviceIds) {
+		return nil, stacktrace.NewError("Node %v can't be added before all bootstrap nodes are started with Start", serviceId)
+	}
+	dependencies := make(map[networks.ServiceID]bool)
+	for _, bootstrapServiceId := range set.serviceIds {
+		dependencies[bootstrapServiceId] = true
+	}
+	availabilityChecker, err := network.AddService(configurationId, serviceId, dependencies)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred adding node %v", serviceId)
+	}
+	return availabilityChecker, nil
+}
+
+/*
+Gets the --bootstrap-ips & --bootstrap-ids flags for the node whose start command is being built, for a Gecko
+	ServiceInitializerCore's GetStartCommand. The flags list the bootstrap nodes among the node's dependencies, which are
+	empty for the first bootstrap node (so that it doesn't try to bootstrap from Gecko's default nodes).
+
+Args:
+	startCommandContext: The context that GetStartCommand was called with
+ */
+func (set *BootstrapNodeSet) GetBootstrapFlags(startCommandContext services.StartCommandContext) ([]string, error) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	bootstrapIps := []string{}
+	bootstrapIds := []string{}
+	for _, serviceId := range set.serviceIds {
+		ipAddr, found := startCommandContext.DependencyIpAddrs[string(serviceId)]
+		if !found {
+			continue
+		}
+		nodeId, found := set.nodeIds[serviceId]
+		if !found {
+			return nil, stacktrace.NewError("Bootstrap node %v is a dependency but hasn't been started with Start", serviceId)
+		}
+		bootstrapIps = append(bootstrapIps, net.JoinHostPort(ipAddr, strconv.Itoa(set.stakingPort)))
+		bootstrapIds = append(bootstrapIds, nodeId)
+	}
+	return []string{
+		fmt.Sprintf("--bootstrap-ips=%v", strings.Join(bootstrapIps, bootstrapFlagsSeparator)),
+		fmt.Sprintf("--bootstrap-ids=%v", strings.Join(bootstrapIds, bootstrapFlagsSeparator)),
+	}, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func copyServiceIdSet(serviceIds map[networks.ServiceID]bool) map[networks.ServiceID]bool {
+	result := make(map[networks.ServiceID]bool)
+	for serviceId, _ := range serviceIds {
+		result[serviceId] = true
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package gecko
+
+import (
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+const (
+	testStakingPort = 9651
+	testBootstrapConfiguration = "bootstrap"
+	testNodeConfiguration = "node"
+)
+
+type testGeckoNode struct {
+	ipAddr string
+}
+
+func (node testGeckoNode) GetNodeId() (string, error) {
+	return "NodeID-" + node.ipAddr, nil
+}
+
+type testGeckoInitializerCore struct {
+	bootstrapNodeSet *BootstrapNodeSet
+}
+
+func (core testGeckoInitializerCore) GetUsedPorts() map[nat.Port]bool {
+	return map[nat.Port]bool{}
+}
+
+func (core testGeckoInitializerCore) GetServiceFromIp(ipAddr string) services.Service {
+	return testGeckoNode{ipAddr: ipAddr}
+}
+
+func (core testGeckoInitializerCore) GetFilesToMount() map[string]bool {
+	return map[string]bool{}
+}
+
+func (core testGeckoInitializerCore) InitializeMountedFiles(mountedFiles map[string]*os.File, dependencies []services.Service) error {
+	return nil
+}
+
+func (core testGeckoInitializerCore) GetTestVolumeMountpoint() string {
+	return "/volume"
+}
+
+func (core testGeckoInitializerCore) GetStartCommand(startCommandContext services.StartCommandContext) ([]string, error) {
+	return core.bootstrapNodeSet.GetBootstrapFlags(startCommandContext)
+}
+
+type testGeckoAvailabilityCheckerCore struct{}
+
+func (core testGeckoAvailabilityCheckerCore) IsServiceUp(toCheck services.Service, dependencies []services.Service) bool {
+	return true
+}
+
+func (core testGeckoAvailabilityCheckerCore) GetTimeout() time.Duration {
+	return time.Second
+}
+
+func TestBootstrapNodeSet(t *testing.T) {
+	testVolumeDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeDirpath)
+
+	bootstrapNodeSet, err := NewBootstrapNodeSet(testBootstrapConfiguration, 2, testStakingPort)
+	assert.NilError(t, err)
+	core := testGeckoInitializerCore{bootstrapNodeSet: bootstrapNodeSet}
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := networks.NewFreeIpAddrTracker(logrus.StandardLogger(), "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := networks.NewServiceNetworkBuilder(logrus.NewEntry(logrus.StandardLogger()), dockerManager, "test-network", freeIpTracker, "test", testVolumeDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testBootstrapConfiguration, "gecko", core, testGeckoAvailabilityCheckerCore{}))
+	assert.NilError(t, builder.AddConfiguration(testNodeConfiguration, "gecko", core, testGeckoAvailabilityCheckerCore{}))
+	network := builder.Build()
+
+	_, err = bootstrapNodeSet.AddNode(network, testNodeConfiguration, "node-0")
+	assert.ErrorContains(t, err, "before all bootstrap nodes are started")
+
+	assert.NilError(t, bootstrapNodeSet.Start(network))
+	_, err = bootstrapNodeSet.AddNode(network, testNodeConfiguration, "node-0")
+	assert.NilError(t, err)
+
+	startCmds := map[string][]string{}
+	for _, containerId := range dockerManager.GetContainerIds() {
+		container, found := dockerManager.GetContainer(containerId)
+		assert.Assert(t, found)
+		startCmds[container.Options.Labels[docker.SERVICE_ID_LABEL]] = container.StartCmdArgs
+	}
+	bootstrap0Ip, err := network.GetServiceIp("bootstrap-0")
+	assert.NilError(t, err)
+	bootstrap1Ip, err := network.GetServiceIp("bootstrap-1")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"--bootstrap-ips=", "--bootstrap-ids="}, startCmds["bootstrap-0"])
+	assert.DeepEqual(
+		t,
+		[]string{"--bootstrap-ips=" + bootstrap0Ip.String() + ":9651", "--bootstrap-ids=NodeID-" + bootstrap0Ip.String()},
+		startCmds["bootstrap-1"])
+	assert.DeepEqual(
+		t,
+		[]string{
+			"--bootstrap-ips=" + bootstrap0Ip.String() + ":9651," + bootstrap1Ip.String() + ":9651",
+			"--bootstrap-ids=NodeID-" + bootstrap0Ip.String() + ",NodeID-" + bootstrap1Ip.String(),
+		},
+		startCmds["node-0"])
+}
@@ -0,0 +1,289 @@
+package gecko
+
+import (
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// The number of validators in Gecko's "local" network genesis, which DefaultAvaNetwork starts one node for each of
+	DEFAULT_AVA_NETWORK_SIZE = 5
+
+	// Non-bootstrap nodes of DefaultAvaNetwork get service IDs of NODE_SERVICE_ID_PREFIX followed by their index (e.g. "node-1")
+	NODE_SERVICE_ID_PREFIX = "node-"
+
+	// The directory in the nodes' containers that their chain state database is kept in
+	GECKO_DB_DIRPATH = "/gecko-db"
+
+	geckoImageRepository = "avaplatform/gecko"
+	geckoBinaryFilepath  = "/gecko/build/ava"
+	localNetworkId       = "local"
+
+	// The staking certs & keys of the validators in the "local" network genesis, which come with the Gecko image
+	localStakerCertFilepathFormat = "/gecko/staking/local/staker%v.crt"
+	localStakerKeyFilepathFormat  = "/gecko/staking/local/staker%v.key"
+
+	// Gecko's default consensus parameters need more nodes than the network has
+	snowSampleSize = 3
+	snowQuorumSize = 2
+
+	stakerConfigurationIdPrefix = "staker-"
+	geckoTestVolumeMountpoint   = "/shared"
+	geckoStartupTimeout         = 90 * time.Second
+)
+
+/*
+A network loader for the standard Avalanche test network: DEFAULT_AVA_NETWORK_SIZE Gecko nodes on the "local" network,
+	each staking as one of the genesis validators, with the first being the bootstrap node of the others (see
+	BootstrapNodeSet). Nodes are available once their health API reports them live.
+
+For tests that need existing chain history, the nodes can be started from pre-synced chain state (see WithChainState &
+	WithChainStateSnapshot), so that they skip bootstrapping the history from each other.
+ */
+type AvaNetworkLoader struct {
+	imageTag string
+
+	// Path of a tar archive or directory of chain state to preload into every node's database, or empty for none
+	chainStateFilepath string
+
+	// Name of a snapshot (see AvaNetwork.SnapshotChainState) to preload into every node's database, or empty for none
+	chainStateSnapshotName string
+
+	// Created when the network is configured
+	bootstrapNodeSet *BootstrapNodeSet
+}
+
+/*
+Creates a loader for the standard five-validator Avalanche test network.
+
+Args:
+	imageTag: The tag of the avaplatform/gecko image to run the nodes with
+ */
+func DefaultAvaNetwork(imageTag string) *AvaNetworkLoader {
+	return &AvaNetworkLoader{imageTag: imageTag}
+}
+
+/*
+Preloads every node's database with the given chain state, e.g. a copy of the database directory of a pre-synced node
+	(such as the mountpoint of a Docker volume it ran with) or a tar archive of one.
+
+Args:
+	chainStateFilepath: Path, on the machine running the tests, of the chain state's tar archive (optionally
+		gzip-compressed) or directory, whose contents are the contents of a Gecko database directory
+ */
+func (loader *AvaNetworkLoader) WithChainState(chainStateFilepath string) *AvaNetworkLoader {
+	loader.chainStateFilepath = chainStateFilepath
+	return loader
+}
+
+/*
+Preloads every node's database with the chain state saved under the given snapshot name by AvaNetwork.SnapshotChainState
+	(possibly in an earlier test run). If there's no such snapshot yet, the nodes start without any chain state, so a
+	test can bootstrap the history the slow way & save the snapshot for next time.
+ */
+func (loader *AvaNetworkLoader) WithChainStateSnapshot(snapshotName string) *AvaNetworkLoader {
+	loader.chainStateSnapshotName = snapshotName
+	return loader
+}
+
+func (loader *AvaNetworkLoader) ConfigureNetwork(builder *networks.ServiceNetworkBuilder) error {
+	bootstrapNodeSet, err := NewBootstrapNodeSet(getStakerConfigurationId(1), 1, STAKING_PORT)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating the bootstrap node set")
+	}
+	loader.bootstrapNodeSet = bootstrapNodeSet
+
+	if loader.chainStateFilepath != "" && loader.chainStateSnapshotName != "" {
+		return stacktrace.NewError("Only one of the chain state & chain state snapshot can be preloaded")
+	}
+	containerOptions := docker.ContainerOptions{}
+	if loader.chainStateFilepath != "" {
+		containerOptions.Archives = []docker.ContainerArchive{
+			{
+				ArchiveFilepath:  loader.chainStateFilepath,
+				ContainerDirpath: GECKO_DB_DIRPATH,
+			},
+		}
+	}
+	if loader.chainStateSnapshotName != "" {
+		if snapshotArchive, found := builder.GetSnapshotArchive(loader.chainStateSnapshotName, GECKO_DB_DIRPATH); found {
+			containerOptions.Archives = []docker.ContainerArchive{snapshotArchive}
+		}
+	}
+
+	dockerImage := fmt.Sprintf("%v:%v", geckoImageRepository, loader.imageTag)
+	for stakerIdx := 1; stakerIdx <= DEFAULT_AVA_NETWORK_SIZE; stakerIdx++ {
+		initializerCore := geckoInitializerCore{
+			stakerIdx:        stakerIdx,
+			bootstrapNodeSet: bootstrapNodeSet,
+		}
+		err := builder.AddConfigurationWithOptions(
+			getStakerConfigurationId(stakerIdx),
+			dockerImage,
+			initializerCore,
+			geckoAvailabilityCheckerCore{},
+			containerOptions)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred adding the configuration of staker %v", stakerIdx)
+		}
+	}
+	return nil
+}
+
+func (loader *AvaNetworkLoader) InitializeNetwork(network *networks.ServiceNetwork) (map[networks.ServiceID]services.ServiceAvailabilityChecker, error) {
+	if err := loader.bootstrapNodeSet.Start(network); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred starting the bootstrap node")
+	}
+	availabilityCheckers := make(map[networks.ServiceID]services.ServiceAvailabilityChecker)
+	for stakerIdx := 2; stakerIdx <= DEFAULT_AVA_NETWORK_SIZE; stakerIdx++ {
+		serviceId := getNodeServiceId(stakerIdx)
+		checker, err := loader.bootstrapNodeSet.AddNode(network, getStakerConfigurationId(stakerIdx), serviceId)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred adding node %v", serviceId)
+		}
+		availabilityCheckers[serviceId] = *checker
+	}
+	return availabilityCheckers, nil
+}
+
+func (loader *AvaNetworkLoader) WrapNetwork(network *networks.ServiceNetwork) (networks.Network, error) {
+	serviceIds := loader.bootstrapNodeSet.GetServiceIds()
+	for stakerIdx := 2; stakerIdx <= DEFAULT_AVA_NETWORK_SIZE; stakerIdx++ {
+		serviceIds = append(serviceIds, getNodeServiceId(stakerIdx))
+	}
+	return AvaNetwork{
+		network:    network,
+		serviceIds: serviceIds,
+	}, nil
+}
+
+/*
+The network given to tests whose network loader is DefaultAvaNetwork.
+ */
+type AvaNetwork struct {
+	network *networks.ServiceNetwork
+
+	serviceIds []networks.ServiceID
+}
+
+/*
+Gets the service IDs of the network's nodes, with the bootstrap node first.
+ */
+func (network AvaNetwork) GetServiceIds() []networks.ServiceID {
+	return append([]networks.ServiceID{}, network.serviceIds...)
+}
+
+/*
+Gets the node with the given service ID.
+ */
+func (network AvaNetwork) GetNode(serviceId networks.ServiceID) (GeckoNode, error) {
+	node, err := network.network.GetService(serviceId)
+	if err != nil {
+		return GeckoNode{}, stacktrace.Propagate(err, "An error occurred getting node %v", serviceId)
+	}
+	return node.Service.(GeckoNode), nil
+}
+
+/*
+Gets a client for the APIs of the node with the given service ID, at the node's address on the test network (which tests
+	can reach, as they run on the test network).
+ */
+func (network AvaNetwork) GetGeckoClient(serviceId networks.ServiceID) (*GeckoClient, error) {
+	node, err := network.GetNode(serviceId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting node %v", serviceId)
+	}
+	return node.GetClient(), nil
+}
+
+/*
+Saves the bootstrap node's chain state as a named snapshot, for later networks to be preloaded with via
+	AvaNetworkLoader.WithChainStateSnapshot. The node is paused while its database is copied.
+ */
+func (network AvaNetwork) SnapshotChainState(snapshotName string) error {
+	if err := network.network.SnapshotServiceData(network.serviceIds[0], GECKO_DB_DIRPATH, snapshotName); err != nil {
+		return stacktrace.Propagate(err, "An error occurred snapshotting the chain state")
+	}
+	return nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func getStakerConfigurationId(stakerIdx int) networks.ConfigurationID {
+	return networks.ConfigurationID(stakerConfigurationIdPrefix + strconv.Itoa(stakerIdx))
+}
+
+func getNodeServiceId(stakerIdx int) networks.ServiceID {
+	return networks.ServiceID(NODE_SERVICE_ID_PREFIX + strconv.Itoa(stakerIdx - 1))
+}
+
+type geckoInitializerCore struct {
+	// The index of the genesis validator (from 1) that the node stakes as
+	stakerIdx int
+
+	bootstrapNodeSet *BootstrapNodeSet
+}
+
+func (core geckoInitializerCore) GetUsedPorts() map[nat.Port]bool {
+	return map[nat.Port]bool{
+		nat.Port(fmt.Sprintf("%v/tcp", HTTP_PORT)):    true,
+		nat.Port(fmt.Sprintf("%v/tcp", STAKING_PORT)): true,
+	}
+}
+
+func (core geckoInitializerCore) GetServiceFromIp(ipAddr string) services.Service {
+	return GeckoNode{
+		IpAddr:   ipAddr,
+		HttpPort: HTTP_PORT,
+	}
+}
+
+func (core geckoInitializerCore) GetFilesToMount() map[string]bool {
+	return map[string]bool{}
+}
+
+func (core geckoInitializerCore) InitializeMountedFiles(mountedFiles map[string]*os.File, dependencies []services.Service) error {
+	return nil
+}
+
+func (core geckoInitializerCore) GetTestVolumeMountpoint() string {
+	return geckoTestVolumeMountpoint
+}
+
+func (core geckoInitializerCore) GetStartCommand(startCommandContext services.StartCommandContext) ([]string, error) {
+	bootstrapFlags, err := core.bootstrapNodeSet.GetBootstrapFlags(startCommandContext)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the node's bootstrap flags")
+	}
+	result := []string{
+		geckoBinaryFilepath,
+		"--public-ip={{ .IpAddr }}",
+		"--network-id=" + localNetworkId,
+		"--db-dir=" + GECKO_DB_DIRPATH,
+		fmt.Sprintf("--http-port=%v", HTTP_PORT),
+		fmt.Sprintf("--staking-port=%v", STAKING_PORT),
+		fmt.Sprintf("--snow-sample-size=%v", snowSampleSize),
+		fmt.Sprintf("--snow-quorum-size=%v", snowQuorumSize),
+		"--staking-tls-enabled=true",
+		"--staking-tls-cert-file=" + fmt.Sprintf(localStakerCertFilepathFormat, core.stakerIdx),
+		"--staking-tls-key-file=" + fmt.Sprintf(localStakerKeyFilepathFormat, core.stakerIdx),
+	}
+	return append(result, bootstrapFlags...), nil
+}
+
+type geckoAvailabilityCheckerCore struct{}
+
+func (core geckoAvailabilityCheckerCore) IsServiceUp(toCheck services.Service, dependencies []services.Service) bool {
+	isLive, err := toCheck.(GeckoNode).GetClient().GetLiveness()
+	return err == nil && isLive
+}
+
+func (core geckoAvailabilityCheckerCore) GetTimeout() time.Duration {
+	return geckoStartupTimeout
+}
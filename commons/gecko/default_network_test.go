@@ -0,0 +1,74 @@
+package gecko
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestDefaultAvaNetwork(t *testing.T) {
+	loader := DefaultAvaNetwork("v0.5.7")
+	builder := networks.NewServiceNetworkBuilder(logrus.NewEntry(logrus.StandardLogger()), nil, "test-network", nil, "test", "/foo/bar", "")
+	assert.NilError(t, loader.ConfigureNetwork(builder))
+	assert.Equal(t, DEFAULT_AVA_NETWORK_SIZE, len(builder.GetConfigurationIds()))
+
+	config, err := builder.GetConfiguration("staker-2")
+	assert.NilError(t, err)
+	assert.Equal(t, "avaplatform/gecko:v0.5.7", config.GetDockerImage())
+	_, err = config.GetInitializerCore().GetStartCommand(services.StartCommandContext{
+		DependencyIpAddrs: map[string]string{"bootstrap-0": "172.23.0.2"},
+	})
+	assert.ErrorContains(t, err, "hasn't been started")
+
+	config, err = builder.GetConfiguration("staker-1")
+	assert.NilError(t, err)
+	startCmd, err := config.GetInitializerCore().GetStartCommand(services.StartCommandContext{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{
+		"/gecko/build/ava",
+		"--public-ip={{ .IpAddr }}",
+		"--network-id=local",
+		"--db-dir=/gecko-db",
+		"--http-port=9650",
+		"--staking-port=9651",
+		"--snow-sample-size=3",
+		"--snow-quorum-size=2",
+		"--staking-tls-enabled=true",
+		"--staking-tls-cert-file=/gecko/staking/local/staker1.crt",
+		"--staking-tls-key-file=/gecko/staking/local/staker1.key",
+		"--bootstrap-ips=",
+		"--bootstrap-ids=",
+	}, startCmd)
+
+	wrapped, err := loader.WrapNetwork(nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(
+		t,
+		[]networks.ServiceID{"bootstrap-0", "node-1", "node-2", "node-3", "node-4"},
+		wrapped.(AvaNetwork).GetServiceIds())
+}
+
+func TestDefaultAvaNetworkChainState(t *testing.T) {
+	builder := networks.NewServiceNetworkBuilder(logrus.NewEntry(logrus.StandardLogger()), nil, "test-network", nil, "test", "/foo/bar", "")
+	assert.NilError(t, DefaultAvaNetwork("v0.5.7").WithChainState("/synced/db.tar.gz").ConfigureNetwork(builder))
+	config, err := builder.GetConfiguration("staker-3")
+	assert.NilError(t, err)
+	assert.DeepEqual(
+		t,
+		[]docker.ContainerArchive{{ArchiveFilepath: "/synced/db.tar.gz", ContainerDirpath: GECKO_DB_DIRPATH}},
+		config.GetContainerOptions().Archives)
+
+	// Without a saved snapshot, nodes start without chain state
+	builder = networks.NewServiceNetworkBuilder(logrus.NewEntry(logrus.StandardLogger()), nil, "test-network", nil, "test", "/foo/bar", "")
+	assert.NilError(t, DefaultAvaNetwork("v0.5.7").WithChainStateSnapshot("synced").ConfigureNetwork(builder))
+	config, err = builder.GetConfiguration("staker-3")
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(config.GetContainerOptions().Archives))
+
+	builder = networks.NewServiceNetworkBuilder(logrus.NewEntry(logrus.StandardLogger()), nil, "test-network", nil, "test", "/foo/bar", "")
+	err = DefaultAvaNetwork("v0.5.7").WithChainState("/synced/db.tar.gz").WithChainStateSnapshot("synced").ConfigureNetwork(builder)
+	assert.ErrorContains(t, err, "Only one")
+}
@@ -0,0 +1,489 @@
+package gecko
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/palantir/stacktrace"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	infoApiPath     = "/ext/info"
+	healthApiPath   = "/ext/health"
+	platformApiPath = "/ext/P"
+	xChainApiPath   = "/ext/bc/X"
+	keystoreApiPath = "/ext/keystore"
+	cChainApiPath   = "/ext/bc/C/rpc"
+
+	jsonRpcVersion     = "2.0"
+	jsonRpcContentType = "application/json"
+
+	apiCallTimeout = 5 * time.Second
+
+	hexPrefix = "0x"
+)
+
+/*
+A peer of a Gecko node, as reported by its info API.
+ */
+type Peer struct {
+	Ip string `json:"ip"`
+
+	PublicIp string `json:"publicIP"`
+
+	NodeId string `json:"nodeID"`
+
+	Version string `json:"version"`
+}
+
+/*
+A validator of a subnet, as reported by the P-chain API. Amounts & times are left as the API's decimal strings.
+ */
+type Validator struct {
+	NodeId string `json:"nodeID"`
+
+	StartTime string `json:"startTime"`
+
+	EndTime string `json:"endTime"`
+
+	// The validator's stake, for validators of the primary network
+	StakeAmount string `json:"stakeAmount"`
+
+	// The validator's weight, for validators of other subnets
+	Weight string `json:"weight"`
+}
+
+/*
+The credentials of a user of a Gecko node's keystore, which the node signs transactions issued through its API with.
+ */
+type KeystoreUser struct {
+	Username string `json:"username"`
+
+	Password string `json:"password"`
+}
+
+/*
+A client for the APIs of a Gecko node, with typed wrappers for the endpoints that tests commonly use so that tests don't
+	need to hand-roll JSON-RPC calls. Endpoints without a wrapper can be called with CallApi.
+ */
+type GeckoClient struct {
+	// The node's API URL, without a trailing slash (e.g. "http://172.23.0.2:9650")
+	baseUrl string
+
+	httpClient *http.Client
+}
+
+/*
+Creates a client for the node whose APIs are served at the given URL (e.g. "http://172.23.0.2:9650").
+ */
+func NewGeckoClient(baseUrl string) *GeckoClient {
+	return &GeckoClient{
+		baseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		httpClient: &http.Client{Timeout: apiCallTimeout},
+	}
+}
+
+/*
+Calls a JSON-RPC method of the node.
+
+Args:
+	apiPath: The path of the API the method belongs to (e.g. "/ext/bc/X")
+	method: The method to call (e.g. "avm.getBalance")
+	params: The method's params, which will be serialized to JSON
+	result: Pointer to the value that the method's result will be deserialized into
+ */
+func (client *GeckoClient) CallApi(apiPath string, method string, params interface{}, result interface{}) error {
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": jsonRpcVersion,
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred serializing the request for %v", method)
+	}
+	resp, err := client.httpClient.Post(client.baseUrl + apiPath, jsonRpcContentType, bytes.NewReader(requestBody))
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred calling %v", method)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return stacktrace.NewError("Calling %v failed with status %v", method, resp.Status)
+	}
+	response := struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return stacktrace.Propagate(err, "An error occurred parsing the response to %v", method)
+	}
+	if response.Error != nil {
+		return stacktrace.NewError("Calling %v returned an error: %v", method, response.Error.Message)
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return stacktrace.Propagate(err, "An error occurred parsing the result of %v", method)
+	}
+	return nil
+}
+
+// ================================ Health API ==================================
+/*
+Gets whether the node reports itself as healthy from its liveness check.
+ */
+func (client *GeckoClient) GetLiveness() (bool, error) {
+	result := struct {
+		Healthy bool `json:"healthy"`
+	}{}
+	if err := client.CallApi(healthApiPath, "health.getLiveness", struct{}{}, &result); err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred getting the node's liveness")
+	}
+	return result.Healthy, nil
+}
+
+// ================================ Info API ==================================
+/*
+Gets the node's ID.
+ */
+func (client *GeckoClient) GetNodeId() (string, error) {
+	result := struct {
+		NodeId string `json:"nodeID"`
+	}{}
+	if err := client.CallApi(infoApiPath, "info.getNodeID", struct{}{}, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the node's ID")
+	}
+	return result.NodeId, nil
+}
+
+/*
+Gets the ID of the network that the node is part of.
+ */
+func (client *GeckoClient) GetNetworkId() (string, error) {
+	result := struct {
+		NetworkId string `json:"networkID"`
+	}{}
+	if err := client.CallApi(infoApiPath, "info.getNetworkID", struct{}{}, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the node's network ID")
+	}
+	return result.NetworkId, nil
+}
+
+/*
+Gets the version of Gecko that the node runs.
+ */
+func (client *GeckoClient) GetNodeVersion() (string, error) {
+	result := struct {
+		Version string `json:"version"`
+	}{}
+	if err := client.CallApi(infoApiPath, "info.getNodeVersion", struct{}{}, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the node's version")
+	}
+	return result.Version, nil
+}
+
+/*
+Gets the peers that the node is connected to.
+ */
+func (client *GeckoClient) GetPeers() ([]Peer, error) {
+	result := struct {
+		Peers []Peer `json:"peers"`
+	}{}
+	if err := client.CallApi(infoApiPath, "info.peers", struct{}{}, &result); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the node's peers")
+	}
+	return result.Peers, nil
+}
+
+/*
+Gets whether the node has finished bootstrapping the given chain.
+
+Args:
+	chain: The ID or alias of the chain (e.g. "X")
+ */
+func (client *GeckoClient) IsBootstrapped(chain string) (bool, error) {
+	params := struct {
+		Chain string `json:"chain"`
+	}{Chain: chain}
+	result := struct {
+		IsBootstrapped bool `json:"isBootstrapped"`
+	}{}
+	if err := client.CallApi(infoApiPath, "info.isBootstrapped", params, &result); err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred getting whether the node has bootstrapped chain %v", chain)
+	}
+	return result.IsBootstrapped, nil
+}
+
+// ================================ P-chain API ==================================
+/*
+Gets the current validators of the primary network.
+ */
+func (client *GeckoClient) GetCurrentValidators() ([]Validator, error) {
+	result := struct {
+		Validators []Validator `json:"validators"`
+	}{}
+	if err := client.CallApi(platformApiPath, "platform.getCurrentValidators", struct{}{}, &result); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the current validators")
+	}
+	return result.Validators, nil
+}
+
+/*
+Gets the current validators of the given subnet.
+ */
+func (client *GeckoClient) GetSubnetValidators(subnetId string) ([]Validator, error) {
+	params := struct {
+		SubnetId string `json:"subnetID"`
+	}{SubnetId: subnetId}
+	result := struct {
+		Validators []Validator `json:"validators"`
+	}{}
+	if err := client.CallApi(platformApiPath, "platform.getCurrentValidators", params, &result); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the current validators of subnet %v", subnetId)
+	}
+	return result.Validators, nil
+}
+
+/*
+Issues a transaction creating a subnet, signed by the given keystore user.
+
+Args:
+	user: The keystore user that pays the transaction's fee
+	controlKeys: The addresses whose keys can add validators to the subnet
+	threshold: How many of the control keys' signatures are needed to add a validator
+
+Returns:
+	The ID of the transaction, which is also the ID of the subnet
+ */
+func (client *GeckoClient) CreateSubnet(user KeystoreUser, controlKeys []string, threshold int) (string, error) {
+	params := struct {
+		KeystoreUser
+		ControlKeys []string `json:"controlKeys"`
+		Threshold   int      `json:"threshold"`
+	}{KeystoreUser: user, ControlKeys: controlKeys, Threshold: threshold}
+	result := struct {
+		TxId string `json:"txID"`
+	}{}
+	if err := client.CallApi(platformApiPath, "platform.createSubnet", params, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred creating a subnet")
+	}
+	return result.TxId, nil
+}
+
+/*
+Issues a transaction adding a validator to a subnet, signed by the given keystore user (which must hold the subnet's control
+	keys). The node must also validate the primary network throughout the validation period.
+
+Args:
+	user: The keystore user holding the subnet's control keys
+	subnetId: The subnet to add the validator to
+	nodeId: The ID of the node to add
+	startTime: When the node starts validating the subnet, which must be in the future
+	endTime: When the node stops validating the subnet
+	weight: The validator's weight in the subnet's consensus
+
+Returns:
+	The ID of the transaction
+ */
+func (client *GeckoClient) AddSubnetValidator(
+			user KeystoreUser,
+			subnetId string,
+			nodeId string,
+			startTime time.Time,
+			endTime time.Time,
+			weight uint64) (string, error) {
+	params := struct {
+		KeystoreUser
+		SubnetId  string `json:"subnetID"`
+		NodeId    string `json:"nodeID"`
+		StartTime string `json:"startTime"`
+		EndTime   string `json:"endTime"`
+		Weight    string `json:"weight"`
+	}{
+		KeystoreUser: user,
+		SubnetId:     subnetId,
+		NodeId:       nodeId,
+		StartTime:    strconv.FormatInt(startTime.Unix(), 10),
+		EndTime:      strconv.FormatInt(endTime.Unix(), 10),
+		Weight:       strconv.FormatUint(weight, 10),
+	}
+	result := struct {
+		TxId string `json:"txID"`
+	}{}
+	if err := client.CallApi(platformApiPath, "platform.addSubnetValidator", params, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred adding node %v as a validator of subnet %v", nodeId, subnetId)
+	}
+	return result.TxId, nil
+}
+
+/*
+Issues a transaction creating a blockchain in a subnet, signed by the given keystore user (which must hold the subnet's
+	control keys).
+
+Args:
+	user: The keystore user holding the subnet's control keys
+	subnetId: The subnet whose validators will validate the blockchain
+	vmId: The ID or alias of the virtual machine that the blockchain runs
+	name: A human-readable name for the blockchain
+	genesisData: The blockchain's genesis, encoded as the virtual machine expects (e.g. CB58)
+
+Returns:
+	The ID of the transaction, which is also the ID of the blockchain
+ */
+func (client *GeckoClient) CreateBlockchain(user KeystoreUser, subnetId string, vmId string, name string, genesisData string) (string, error) {
+	params := struct {
+		KeystoreUser
+		SubnetId    string `json:"subnetID"`
+		VmId        string `json:"vmID"`
+		Name        string `json:"name"`
+		GenesisData string `json:"genesisData"`
+	}{KeystoreUser: user, SubnetId: subnetId, VmId: vmId, Name: name, GenesisData: genesisData}
+	result := struct {
+		TxId string `json:"txID"`
+	}{}
+	if err := client.CallApi(platformApiPath, "platform.createBlockchain", params, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred creating blockchain '%v' in subnet %v", name, subnetId)
+	}
+	return result.TxId, nil
+}
+
+/*
+Gets the status of a P-chain transaction (e.g. "Committed", "Processing", or "Dropped").
+ */
+func (client *GeckoClient) GetPlatformTxStatus(txId string) (string, error) {
+	params := struct {
+		TxId string `json:"txID"`
+	}{TxId: txId}
+	result := struct {
+		Status string `json:"status"`
+	}{}
+	if err := client.CallApi(platformApiPath, "platform.getTxStatus", params, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the status of P-chain transaction %v", txId)
+	}
+	return result.Status, nil
+}
+
+/*
+Gets the status of a blockchain from the node's point of view (e.g. "Validating" if the node validates it, or "Created").
+ */
+func (client *GeckoClient) GetBlockchainStatus(blockchainId string) (string, error) {
+	params := struct {
+		BlockchainId string `json:"blockchainID"`
+	}{BlockchainId: blockchainId}
+	result := struct {
+		Status string `json:"status"`
+	}{}
+	if err := client.CallApi(platformApiPath, "platform.getBlockchainStatus", params, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the status of blockchain %v", blockchainId)
+	}
+	return result.Status, nil
+}
+
+// ================================ Keystore API ==================================
+/*
+Creates a user in the node's keystore, whose password must be strong enough for the node to accept it.
+ */
+func (client *GeckoClient) CreateKeystoreUser(user KeystoreUser) error {
+	result := struct {
+		Success bool `json:"success"`
+	}{}
+	if err := client.CallApi(keystoreApiPath, "keystore.createUser", user, &result); err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating keystore user '%v'", user.Username)
+	}
+	if !result.Success {
+		return stacktrace.NewError("The node didn't create keystore user '%v'", user.Username)
+	}
+	return nil
+}
+
+// ================================ X-chain API ==================================
+/*
+Creates a new X-chain address controlled by the given keystore user.
+ */
+func (client *GeckoClient) CreateXChainAddress(user KeystoreUser) (string, error) {
+	result := struct {
+		Address string `json:"address"`
+	}{}
+	if err := client.CallApi(xChainApiPath, "avm.createAddress", user, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred creating an X-chain address for keystore user '%v'", user.Username)
+	}
+	return result.Address, nil
+}
+
+/*
+Issues an X-chain transaction sending an amount of an asset held by the given keystore user to an address.
+
+Returns:
+	The ID of the transaction
+ */
+func (client *GeckoClient) SendXChain(user KeystoreUser, assetId string, amount uint64, toAddress string) (string, error) {
+	params := struct {
+		KeystoreUser
+		AssetId string `json:"assetID"`
+		Amount  uint64 `json:"amount"`
+		To      string `json:"to"`
+	}{KeystoreUser: user, AssetId: assetId, Amount: amount, To: toAddress}
+	result := struct {
+		TxId string `json:"txID"`
+	}{}
+	if err := client.CallApi(xChainApiPath, "avm.send", params, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred sending %v %v to address %v", amount, assetId, toAddress)
+	}
+	return result.TxId, nil
+}
+
+/*
+Gets the balance of an asset held by an X-chain address, as the API's decimal string.
+
+Args:
+	address: The address (e.g. "X-local1...")
+	assetId: The asset's ID or alias (e.g. "AVAX")
+ */
+func (client *GeckoClient) GetXChainBalance(address string, assetId string) (string, error) {
+	params := struct {
+		Address string `json:"address"`
+		AssetId string `json:"assetID"`
+	}{Address: address, AssetId: assetId}
+	result := struct {
+		Balance string `json:"balance"`
+	}{}
+	if err := client.CallApi(xChainApiPath, "avm.getBalance", params, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the %v balance of address %v", assetId, address)
+	}
+	return result.Balance, nil
+}
+
+/*
+Gets the status of an X-chain transaction (e.g. "Accepted" or "Processing").
+ */
+func (client *GeckoClient) GetXChainTxStatus(txId string) (string, error) {
+	params := struct {
+		TxId string `json:"txID"`
+	}{TxId: txId}
+	result := struct {
+		Status string `json:"status"`
+	}{}
+	if err := client.CallApi(xChainApiPath, "avm.getTxStatus", params, &result); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the status of transaction %v", txId)
+	}
+	return result.Status, nil
+}
+
+// ================================ C-chain API ==================================
+/*
+Gets the number of the C-chain's latest block.
+ */
+func (client *GeckoClient) GetCChainBlockNumber() (uint64, error) {
+	var result string
+	if err := client.CallApi(cChainApiPath, "eth_blockNumber", []interface{}{}, &result); err != nil {
+		return 0, stacktrace.Propagate(err, "An error occurred getting the C-chain's block number")
+	}
+	blockNumber, err := strconv.ParseUint(strings.TrimPrefix(result, hexPrefix), 16, 64)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "An error occurred parsing C-chain block number '%v'", result)
+	}
+	return blockNumber, nil
+}
@@ -0,0 +1,77 @@
+package gecko
+
+import (
+	"encoding/json"
+	"gotest.tools/v3/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// Mapping of "<API path> <method>" -> the JSON-RPC response body that the test server returns
+var testApiResponses = map[string]string{
+	"/ext/info info.getNodeID":             `{"result":{"nodeID":"NodeID-abc"}}`,
+	"/ext/info info.peers":                 `{"result":{"peers":[{"ip":"172.23.0.3:9651","publicIP":"172.23.0.3:9651","nodeID":"NodeID-def","version":"avalanche/0.6.0"}]}}`,
+	"/ext/info info.isBootstrapped":        `{"result":{"isBootstrapped":true}}`,
+	"/ext/health health.getLiveness":       `{"error":{"code":-32000,"message":"not bootstrapped"}}`,
+	"/ext/P platform.getCurrentValidators": `{"result":{"validators":[{"nodeID":"NodeID-abc","startTime":"1","endTime":"2","stakeAmount":"20000"}]}}`,
+	"/ext/bc/X avm.getBalance":             `{"result":{"balance":"1000"}}`,
+	"/ext/bc/C/rpc eth_blockNumber":        `{"result":"0x1f"}`,
+}
+
+func TestGeckoClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		jsonRpcRequest := struct {
+			Method string `json:"method"`
+		}{}
+		assert.NilError(t, json.NewDecoder(request.Body).Decode(&jsonRpcRequest))
+		response, found := testApiResponses[request.URL.Path + " " + jsonRpcRequest.Method]
+		if !found {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writer.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NilError(t, err)
+	port, err := strconv.Atoi(portStr)
+	assert.NilError(t, err)
+	node := GeckoNode{IpAddr: host, HttpPort: port}
+	client := node.GetClient()
+
+	nodeId, err := node.GetNodeId()
+	assert.NilError(t, err)
+	assert.Equal(t, "NodeID-abc", nodeId)
+
+	peers, err := client.GetPeers()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []Peer{{Ip: "172.23.0.3:9651", PublicIp: "172.23.0.3:9651", NodeId: "NodeID-def", Version: "avalanche/0.6.0"}}, peers)
+
+	isBootstrapped, err := client.IsBootstrapped("X")
+	assert.NilError(t, err)
+	assert.Assert(t, isBootstrapped)
+
+	validators, err := client.GetCurrentValidators()
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(validators))
+	assert.Equal(t, "20000", validators[0].StakeAmount)
+
+	balance, err := client.GetXChainBalance("X-local1abc", "AVAX")
+	assert.NilError(t, err)
+	assert.Equal(t, "1000", balance)
+
+	blockNumber, err := client.GetCChainBlockNumber()
+	assert.NilError(t, err)
+	assert.Equal(t, uint64(31), blockNumber)
+
+	_, err = client.GetLiveness()
+	assert.ErrorContains(t, err, "not bootstrapped")
+	assert.Assert(t, !geckoAvailabilityCheckerCore{}.IsServiceUp(node, nil))
+
+	_, err = client.GetNodeVersion()
+	assert.ErrorContains(t, err, "404")
+}
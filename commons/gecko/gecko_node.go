@@ -0,0 +1,44 @@
+package gecko
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const (
+	// The ports that Gecko nodes listen on by default
+	HTTP_PORT    = 9650
+	STAKING_PORT = 9651
+)
+
+/*
+A Gecko node in a test network, as returned by the network for nodes of the gecko package's configurations (see
+	ServiceNetwork.GetService).
+ */
+type GeckoNode struct {
+	IpAddr string
+
+	HttpPort int
+}
+
+/*
+Gets the URL of the node's API at the given path (e.g. "/ext/bc/X").
+ */
+func (node GeckoNode) GetApiUrl(apiPath string) string {
+	return fmt.Sprintf("http://%v%v", net.JoinHostPort(node.IpAddr, strconv.Itoa(node.HttpPort)), apiPath)
+}
+
+/*
+Gets a client for the node's APIs.
+ */
+func (node GeckoNode) GetClient() *GeckoClient {
+	return NewGeckoClient(node.GetApiUrl(""))
+}
+
+/*
+Gets the node's ID from its info API.
+ */
+func (node GeckoNode) GetNodeId() (string, error) {
+	return node.GetClient().GetNodeId()
+}
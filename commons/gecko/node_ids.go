@@ -0,0 +1,65 @@
+package gecko
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/palantir/stacktrace"
+	"golang.org/x/crypto/ripemd160"
+	"math/big"
+)
+
+const (
+	nodeIdPrefix = "NodeID-"
+
+	cb58ChecksumLength = 4
+	base58Alphabet     = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+/*
+Gets the ID of the Gecko node that stakes with the given credentials, which Gecko derives from the node's staking
+	certificate, so that node IDs (e.g. for a genesis or --bootstrap-ids) can be known before the nodes are started.
+ */
+func GetNodeId(credentials *StakingCredentials) (string, error) {
+	certBlock, _ := pem.Decode(credentials.CertPem)
+	if certBlock == nil {
+		return "", stacktrace.NewError("The staking certificate isn't PEM-encoded")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred parsing the staking certificate")
+	}
+	certHash := sha256.Sum256(cert.Raw)
+	hasher := ripemd160.New()
+	hasher.Write(certHash[:])
+	return nodeIdPrefix + encodeCb58(hasher.Sum(nil)), nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Encodes the given bytes in the CB58 format that Avalanche IDs are written in: base 58 with a 4-byte SHA-256 checksum
+func encodeCb58(payload []byte) string {
+	checksum := sha256.Sum256(payload)
+	toEncode := append(append([]byte{}, payload...), checksum[len(checksum) - cb58ChecksumLength:]...)
+
+	encodedReversed := []byte{}
+	value := new(big.Int).SetBytes(toEncode)
+	base := big.NewInt(int64(len(base58Alphabet)))
+	remainder := new(big.Int)
+	for value.Sign() > 0 {
+		value.DivMod(value, base, remainder)
+		encodedReversed = append(encodedReversed, base58Alphabet[remainder.Int64()])
+	}
+	// Leading zero bytes are encoded as the alphabet's zero digit, as the number loses them
+	for _, b := range toEncode {
+		if b != 0 {
+			break
+		}
+		encodedReversed = append(encodedReversed, base58Alphabet[0])
+	}
+
+	result := make([]byte, len(encodedReversed))
+	for i, b := range encodedReversed {
+		result[len(encodedReversed) - 1 - i] = b
+	}
+	return string(result)
+}
@@ -0,0 +1,129 @@
+package gecko
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"math/big"
+	"os"
+	"time"
+)
+
+const (
+	// The keys of the staking files in GetFilesToMount & InitializeMountedFiles (see AddStakingFilesToMount)
+	STAKING_CERT_FILE_KEY = "stakingCert"
+	STAKING_KEY_FILE_KEY  = "stakingKey"
+
+	// The same key size & validity as Gecko's own staking certificate generation
+	stakingKeyBits           = 4096
+	stakingCertValidityYears = 100
+
+	certPemBlockType = "CERTIFICATE"
+	keyPemBlockType  = "PRIVATE KEY"
+)
+
+/*
+The TLS certificate & key that a Gecko node stakes with, from which the node's ID is derived.
+ */
+type StakingCredentials struct {
+	// The PEM-encoded self-signed certificate
+	CertPem []byte
+
+	// The PEM-encoded PKCS #8 private key
+	KeyPem []byte
+}
+
+/*
+Generates a new staking certificate & key, the same way Gecko does for nodes that aren't given any. Each node of a
+	network must have its own credentials, since the node ID is derived from them.
+ */
+func GenerateStakingCredentials() (*StakingCredentials, error) {
+	key, err := rsa.GenerateKey(rand.Reader, stakingKeyBits)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred generating the staking key")
+	}
+	certTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(0),
+		NotBefore:             time.Date(2000, time.January, 0, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Now().AddDate(stakingCertValidityYears, 0, 0),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageDataEncipherment,
+		BasicConstraintsValid: true,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, certTemplate, certTemplate, &key.PublicKey, key)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the staking certificate")
+	}
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred marshalling the staking key")
+	}
+	return &StakingCredentials{
+		CertPem: pem.EncodeToMemory(&pem.Block{Type: certPemBlockType, Bytes: certBytes}),
+		KeyPem:  pem.EncodeToMemory(&pem.Block{Type: keyPemBlockType, Bytes: keyBytes}),
+	}, nil
+}
+
+/*
+Adds the staking certificate & key files to the given "set" of files to mount, for a Gecko ServiceInitializerCore's
+	GetFilesToMount.
+
+Args:
+	filesToMount: The "set" of the core's other files to mount, which is modified
+
+Returns:
+	The given "set", for convenience
+ */
+func AddStakingFilesToMount(filesToMount map[string]bool) map[string]bool {
+	filesToMount[STAKING_CERT_FILE_KEY] = true
+	filesToMount[STAKING_KEY_FILE_KEY] = true
+	return filesToMount
+}
+
+/*
+Writes staking credentials into the staking files, for a Gecko ServiceInitializerCore's InitializeMountedFiles. As the core's
+	InitializeMountedFiles is called once per node, passing nil credentials gives every node its own.
+
+Args:
+	mountedFiles: The files passed to the core's InitializeMountedFiles, which must include the files added by
+		AddStakingFilesToMount
+	credentials: The credentials to write (e.g. pre-generated ones for a bootstrap node whose ID other nodes need to
+		know), or nil to generate new ones
+ */
+func InitializeStakingFiles(mountedFiles map[string]*os.File, credentials *StakingCredentials) error {
+	if credentials == nil {
+		generatedCredentials, err := GenerateStakingCredentials()
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred generating the staking credentials")
+		}
+		credentials = generatedCredentials
+	}
+	filesToWrite := map[string][]byte{
+		STAKING_CERT_FILE_KEY: credentials.CertPem,
+		STAKING_KEY_FILE_KEY:  credentials.KeyPem,
+	}
+	for fileKey, contents := range filesToWrite {
+		fp, found := mountedFiles[fileKey]
+		if !found {
+			return stacktrace.NewError("No mounted file with key '%v' was found; the core's GetFilesToMount must use AddStakingFilesToMount", fileKey)
+		}
+		if _, err := fp.Write(contents); err != nil {
+			return stacktrace.Propagate(err, "An error occurred writing staking file '%v'", fileKey)
+		}
+	}
+	return nil
+}
+
+/*
+Gets the Gecko flags that enable staking with the files written by InitializeStakingFiles, as start command fragments
+	for a Gecko ServiceInitializerCore's GetStartCommand.
+ */
+func GetStakingFlags() []string {
+	return []string{
+		"--staking-tls-enabled=true",
+		fmt.Sprintf("--staking-tls-cert-file={{ .MountedFilepaths.%v }}", STAKING_CERT_FILE_KEY),
+		fmt.Sprintf("--staking-tls-key-file={{ .MountedFilepaths.%v }}", STAKING_KEY_FILE_KEY),
+	}
+}
@@ -0,0 +1,51 @@
+package gecko
+
+import (
+	"crypto/tls"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestInitializeStakingFiles(t *testing.T) {
+	dirpath, err := ioutil.TempDir("", "staking")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dirpath)
+
+	filesToMount := AddStakingFilesToMount(map[string]bool{"config": true})
+	mountedFiles := map[string]*os.File{}
+	for fileKey, _ := range filesToMount {
+		fp, err := os.Create(path.Join(dirpath, fileKey))
+		assert.NilError(t, err)
+		defer fp.Close()
+		mountedFiles[fileKey] = fp
+	}
+	assert.NilError(t, InitializeStakingFiles(mountedFiles, nil))
+
+	certPem, err := ioutil.ReadFile(path.Join(dirpath, STAKING_CERT_FILE_KEY))
+	assert.NilError(t, err)
+	keyPem, err := ioutil.ReadFile(path.Join(dirpath, STAKING_KEY_FILE_KEY))
+	assert.NilError(t, err)
+	_, err = tls.X509KeyPair(certPem, keyPem)
+	assert.NilError(t, err)
+
+	err = InitializeStakingFiles(map[string]*os.File{}, &StakingCredentials{})
+	assert.ErrorContains(t, err, "No mounted file")
+}
+
+func TestGetStakingFlags(t *testing.T) {
+	rendered, err := services.RenderStartCommand(GetStakingFlags(), services.StartCommandContext{
+		MountedFilepaths: map[string]string{
+			STAKING_CERT_FILE_KEY: "/volume/node-1/cert",
+			STAKING_KEY_FILE_KEY:  "/volume/node-1/key",
+		},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(
+		t,
+		[]string{"--staking-tls-enabled=true", "--staking-tls-cert-file=/volume/node-1/cert", "--staking-tls-key-file=/volume/node-1/key"},
+		rendered)
+}
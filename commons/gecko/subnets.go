@@ -0,0 +1,181 @@
+package gecko
+
+import (
+	"github.com/palantir/stacktrace"
+	"time"
+)
+
+const (
+	// P-chain transaction statuses
+	COMMITTED_TX_STATUS = "Committed"
+	DROPPED_TX_STATUS   = "Dropped"
+	ABORTED_TX_STATUS   = "Aborted"
+
+	// The status of a blockchain on the nodes that validate it
+	VALIDATING_BLOCKCHAIN_STATUS = "Validating"
+
+	subnetPollInterval = 500 * time.Millisecond
+
+	// How far in the future subnet validators' validation starts, as the P-chain rejects start times in the past
+	subnetValidationStartDelay = 20 * time.Second
+)
+
+/*
+The definition of a subnet for CreateSubnet.
+ */
+type SubnetSpec struct {
+	// The addresses whose keys can add validators & blockchains to the subnet, which the creating keystore user must hold
+	ControlKeys []string
+
+	// How many of the control keys' signatures are needed to change the subnet
+	Threshold int
+
+	// Mapping of node ID -> weight of the subnet's validators, which must be validators of the primary network
+	ValidatorWeights map[string]uint64
+
+	// How long the validators validate the subnet for
+	ValidationDuration time.Duration
+}
+
+/*
+Creates a subnet & adds its validators through the given node, waiting until every validator is validating the subnet.
+	Note that a node only syncs a subnet's blockchains if it's launched with the subnet in its --whitelisted-subnets flag,
+	so the validators of a subnet that will run blockchains must be (re)started with the subnet ID.
+
+Args:
+	client: The client of the node to issue the transactions through
+	user: The keystore user on that node that holds the subnet's control keys & pays the fees
+	spec: The subnet's definition
+	timeout: How long to wait for the subnet to be created & validated
+
+Returns:
+	The ID of the subnet
+ */
+func CreateSubnet(client *GeckoClient, user KeystoreUser, spec SubnetSpec, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	subnetId, err := client.CreateSubnet(user, spec.ControlKeys, spec.Threshold)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred issuing the subnet creation")
+	}
+	if err := waitForPlatformTx(client, subnetId, deadline); err != nil {
+		return "", stacktrace.Propagate(err, "The subnet creation wasn't committed")
+	}
+
+	startTime := time.Now().Add(subnetValidationStartDelay)
+	endTime := startTime.Add(spec.ValidationDuration)
+	for nodeId, weight := range spec.ValidatorWeights {
+		txId, err := client.AddSubnetValidator(user, subnetId, nodeId, startTime, endTime, weight)
+		if err != nil {
+			return "", stacktrace.Propagate(err, "An error occurred issuing the addition of validator %v to subnet %v", nodeId, subnetId)
+		}
+		if err := waitForPlatformTx(client, txId, deadline); err != nil {
+			return "", stacktrace.Propagate(err, "The addition of validator %v to subnet %v wasn't committed", nodeId, subnetId)
+		}
+	}
+
+	err = pollUntil(deadline, func() (bool, error) {
+		validators, err := client.GetSubnetValidators(subnetId)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred getting the validators of subnet %v", subnetId)
+		}
+		currentValidatorIds := make(map[string]bool)
+		for _, validator := range validators {
+			currentValidatorIds[validator.NodeId] = true
+		}
+		for nodeId, _ := range spec.ValidatorWeights {
+			if !currentValidatorIds[nodeId] {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return "", stacktrace.Propagate(err, "The validators of subnet %v didn't start validating it", subnetId)
+	}
+	return subnetId, nil
+}
+
+/*
+Creates a blockchain in a subnet through the given node, waiting until every one of the given validators of the subnet
+	is validating the blockchain.
+
+Args:
+	client: The client of the node to issue the transaction through
+	user: The keystore user on that node that holds the subnet's control keys & pays the fee
+	subnetId: The subnet to create the blockchain in (e.g. as created by CreateSubnet)
+	vmId: The ID or alias of the virtual machine that the blockchain runs
+	name: A human-readable name for the blockchain
+	genesisData: The blockchain's genesis, encoded as the virtual machine expects
+	validatorClients: The clients of the subnet's validators, which must each report the blockchain as validated
+	timeout: How long to wait for the blockchain to be created & validated
+
+Returns:
+	The ID of the blockchain
+ */
+func CreateBlockchain(
+			client *GeckoClient,
+			user KeystoreUser,
+			subnetId string,
+			vmId string,
+			name string,
+			genesisData string,
+			validatorClients []*GeckoClient,
+			timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	blockchainId, err := client.CreateBlockchain(user, subnetId, vmId, name, genesisData)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred issuing the creation of blockchain '%v'", name)
+	}
+	if err := waitForPlatformTx(client, blockchainId, deadline); err != nil {
+		return "", stacktrace.Propagate(err, "The creation of blockchain '%v' wasn't committed", name)
+	}
+
+	for _, validatorClient := range validatorClients {
+		err := pollUntil(deadline, func() (bool, error) {
+			status, err := validatorClient.GetBlockchainStatus(blockchainId)
+			if err != nil {
+				return false, stacktrace.Propagate(err, "An error occurred getting the status of blockchain %v", blockchainId)
+			}
+			return status == VALIDATING_BLOCKCHAIN_STATUS, nil
+		})
+		if err != nil {
+			return "", stacktrace.Propagate(err, "Node at %v didn't start validating blockchain %v", validatorClient.baseUrl, blockchainId)
+		}
+	}
+	return blockchainId, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Waits for the given P-chain transaction to be committed, returning an error if it's rejected or the deadline passes
+func waitForPlatformTx(client *GeckoClient, txId string, deadline time.Time) error {
+	return pollUntil(deadline, func() (bool, error) {
+		status, err := client.GetPlatformTxStatus(txId)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred getting the status of transaction %v", txId)
+		}
+		if status == DROPPED_TX_STATUS || status == ABORTED_TX_STATUS {
+			return false, stacktrace.NewError("Transaction %v was rejected with status %v", txId, status)
+		}
+		return status == COMMITTED_TX_STATUS, nil
+	})
+}
+
+/*
+Calls the given condition until it returns true, returning an error if it returns one or the deadline passes. Errors from
+	the condition are returned immediately, as the APIs it calls are expected to be up.
+ */
+func pollUntil(deadline time.Time, condition func() (bool, error)) error {
+	for {
+		isMet, err := condition()
+		if err != nil {
+			return err
+		}
+		if isMet {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return stacktrace.NewError("Timed out waiting for the condition to be met")
+		}
+		time.Sleep(subnetPollInterval)
+	}
+}
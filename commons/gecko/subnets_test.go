@@ -0,0 +1,105 @@
+package gecko
+
+import (
+	"encoding/json"
+	"fmt"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A fake P-chain, whose transactions are committed the second time their status is checked
+type fakePlatformChain struct {
+	mutex *sync.Mutex
+
+	// Mapping of tx ID -> number of times its status was checked
+	txStatusChecks map[string]int
+
+	// Mapping of subnet ID -> node IDs of its validators
+	subnetValidators map[string][]string
+
+	// Transactions issued by the test that the chain rejects
+	droppedTxIds map[string]bool
+}
+
+func (chain *fakePlatformChain) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	chain.mutex.Lock()
+	defer chain.mutex.Unlock()
+	jsonRpcRequest := struct {
+		Method string                 `json:"method"`
+		Params map[string]interface{} `json:"params"`
+	}{}
+	if err := json.NewDecoder(request.Body).Decode(&jsonRpcRequest); err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	params := map[string]string{}
+	for name, value := range jsonRpcRequest.Params {
+		params[name] = fmt.Sprint(value)
+	}
+	var result interface{}
+	switch jsonRpcRequest.Method {
+	case "platform.createSubnet":
+		result = map[string]string{"txID": "subnet1"}
+	case "platform.addSubnetValidator":
+		chain.subnetValidators[params["subnetID"]] = append(chain.subnetValidators[params["subnetID"]], params["nodeID"])
+		result = map[string]string{"txID": "add-" + params["nodeID"]}
+	case "platform.createBlockchain":
+		result = map[string]string{"txID": "chain-" + params["name"]}
+	case "platform.getTxStatus":
+		chain.txStatusChecks[params["txID"]]++
+		status := "Processing"
+		if chain.droppedTxIds[params["txID"]] {
+			status = DROPPED_TX_STATUS
+		} else if chain.txStatusChecks[params["txID"]] > 1 {
+			status = COMMITTED_TX_STATUS
+		}
+		result = map[string]string{"status": status}
+	case "platform.getCurrentValidators":
+		validators := []map[string]string{}
+		for _, nodeId := range chain.subnetValidators[params["subnetID"]] {
+			validators = append(validators, map[string]string{"nodeID": nodeId, "weight": "10"})
+		}
+		result = map[string]interface{}{"validators": validators}
+	case "platform.getBlockchainStatus":
+		result = map[string]string{"status": VALIDATING_BLOCKCHAIN_STATUS}
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	resultBytes, _ := json.Marshal(result)
+	fmt.Fprintf(writer, `{"jsonrpc":"2.0","id":1,"result":%s}`, resultBytes)
+}
+
+func TestCreateSubnetAndBlockchain(t *testing.T) {
+	chain := &fakePlatformChain{
+		mutex:            &sync.Mutex{},
+		txStatusChecks:   map[string]int{},
+		subnetValidators: map[string][]string{},
+		droppedTxIds:     map[string]bool{"chain-dropped": true},
+	}
+	server := httptest.NewServer(chain)
+	defer server.Close()
+	client := NewGeckoClient(server.URL)
+	user := KeystoreUser{Username: "user", Password: "password"}
+
+	subnetId, err := CreateSubnet(client, user, SubnetSpec{
+		ControlKeys:        []string{"P-local1abc"},
+		Threshold:          1,
+		ValidatorWeights:   map[string]uint64{"NodeID-a": 10, "NodeID-b": 10},
+		ValidationDuration: time.Hour,
+	}, 10 * time.Second)
+	assert.NilError(t, err)
+	assert.Equal(t, "subnet1", subnetId)
+	assert.Equal(t, 2, len(chain.subnetValidators["subnet1"]))
+
+	blockchainId, err := CreateBlockchain(client, user, subnetId, "timestampvm", "timestamps", "", []*GeckoClient{client}, 10 * time.Second)
+	assert.NilError(t, err)
+	assert.Equal(t, "chain-timestamps", blockchainId)
+
+	_, err = CreateBlockchain(client, user, subnetId, "timestampvm", "dropped", "", []*GeckoClient{client}, 10 * time.Second)
+	assert.ErrorContains(t, err, "rejected with status Dropped")
+}
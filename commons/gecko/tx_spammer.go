@@ -0,0 +1,392 @@
+package gecko
+
+import (
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// The statuses of finalized X-chain transactions
+	ACCEPTED_TX_STATUS = "Accepted"
+	REJECTED_TX_STATUS = "Rejected"
+
+	// How long an issued transaction may take to be accepted before it's counted as failed, if the config doesn't say otherwise
+	DEFAULT_TX_ACCEPTANCE_TIMEOUT = 30 * time.Second
+
+	// How often the status of an issued transaction is checked, which bounds the precision of acceptance latencies
+	txStatusPollInterval = 50 * time.Millisecond
+
+	// The keystore credentials of the users that the spammer creates on each target node
+	spammerUsernamePrefix = "kurtosis-tx-spammer-"
+	spammerPassword       = "Kurt0sis-Tx-Spamm3r-P@ssword"
+)
+
+/*
+Configuration for a TxSpammer. Transactions are issued round-robin across the target nodes, each sending funds from a key
+	held by the node's keystore back to that same key, so the spammer's funds are never used up (only fees are spent).
+ */
+type TxSpammerConfig struct {
+	// The nodes that transactions will be issued through
+	Targets []*GeckoClient
+
+	// A keystore user on the first target's node, holding the funds that the spammer's keys are funded from
+	FundingUser KeystoreUser
+
+	// The ID of the asset that the spammer sends (e.g. "AVA")
+	AssetId string
+
+	// How much of the asset each target's key is funded with, which must cover the fees of the transactions issued through it
+	FundingAmount uint64
+
+	// How much of the asset each transaction sends
+	TxAmount uint64
+
+	// How many transactions will be issued per second
+	TxsPerSecond uint
+
+	// How long transactions will be issued for before the spammer stops of its own accord (0 to run until Stop is called)
+	Duration time.Duration
+
+	// How long an issued transaction may take to be accepted before it's counted as failed (DEFAULT_TX_ACCEPTANCE_TIMEOUT if 0)
+	AcceptanceTimeout time.Duration
+}
+
+/*
+Acceptance & latency results of a transaction spamming run. Latencies are measured from when a transaction was issued to
+	when it was seen to be accepted, over accepted transactions only.
+ */
+type TxSpamResults struct {
+	// How long transactions were issued for
+	Elapsed time.Duration
+
+	// The number of transactions that the target nodes accepted for issuance
+	Issued int
+
+	// The number of issued transactions that were accepted
+	Accepted int
+
+	// The number of issued transactions that were rejected (e.g. as conflicting with other transactions)
+	Rejected int
+
+	// The number of transactions that couldn't be issued, or whose status couldn't be determined in time
+	Failed int
+
+	// Accepted transactions per second
+	Throughput float64
+
+	LatencyP50 time.Duration
+	LatencyP90 time.Duration
+	LatencyP99 time.Duration
+	LatencyMax time.Duration
+}
+
+func (results TxSpamResults) String() string {
+	return fmt.Sprintf(
+		"%v issued, %v accepted, %v rejected, %v failed in %v (%.1f tx/s); acceptance latency p50=%v p90=%v p99=%v max=%v",
+		results.Issued,
+		results.Accepted,
+		results.Rejected,
+		results.Failed,
+		results.Elapsed,
+		results.Throughput,
+		results.LatencyP50,
+		results.LatencyP90,
+		results.LatencyP99,
+		results.LatencyMax)
+}
+
+/*
+Issues X-chain transactions to nodes of an Avalanche network at a fixed rate, recording how many are accepted & how long
+	acceptance takes, so tests can check the network's throughput & stability under transaction load (the equivalent of
+	the generic networks.LoadGenerator, for transactions).
+ */
+type TxSpammer struct {
+	// The configuration dictating where & how often to issue transactions
+	config TxSpammerConfig
+
+	// The keystore users that hold the spammer's funds, parallel to config.Targets
+	users []KeystoreUser
+
+	// The addresses of the spammer's funded keys, parallel to config.Targets
+	addresses []string
+
+	// Mutex guarding the results fields below
+	mutex *sync.Mutex
+
+	// When transaction issuance started & stopped
+	startTime time.Time
+	stopTime time.Time
+
+	// The number of issued, rejected, & failed transactions
+	issued int
+	rejected int
+	failed int
+
+	// Acceptance latencies of accepted transactions
+	latencies []time.Duration
+
+	// Closed to tell the spammer goroutine to stop
+	stopChan chan struct{}
+
+	// Closed by the spammer goroutine once it has stopped and all issued transactions have been finalized or timed out
+	doneChan chan struct{}
+}
+
+/*
+Creates a new transaction spammer.
+
+Args:
+	config: The configuration dictating where & how often to issue transactions
+ */
+func NewTxSpammer(config TxSpammerConfig) *TxSpammer {
+	// Defensive copy
+	config.Targets = append([]*GeckoClient{}, config.Targets...)
+	if config.AcceptanceTimeout == 0 {
+		config.AcceptanceTimeout = DEFAULT_TX_ACCEPTANCE_TIMEOUT
+	}
+
+	return &TxSpammer{
+		config:    config,
+		users:     nil,
+		addresses: nil,
+		mutex:     &sync.Mutex{},
+		latencies: []time.Duration{},
+		stopChan:  nil,
+		doneChan:  nil,
+	}
+}
+
+/*
+Funds a key on each of the target nodes from the funding user, blocking until the funding transactions are accepted, then
+	starts issuing transactions in a background goroutine. A spammer can only be started once.
+ */
+func (spammer *TxSpammer) Start() error {
+	if spammer.stopChan != nil {
+		return stacktrace.NewError("Transaction spammer has already been started")
+	}
+	if spammer.config.TxsPerSecond == 0 {
+		return stacktrace.NewError("Transaction spammer transactions per second must be positive")
+	}
+	if len(spammer.config.Targets) == 0 {
+		return stacktrace.NewError("Transaction spammer must have at least one target node")
+	}
+	if spammer.config.AssetId == "" {
+		return stacktrace.NewError("Transaction spammer must have an asset to send")
+	}
+
+	if err := spammer.fundKeys(); err != nil {
+		return stacktrace.Propagate(err, "An error occurred funding the transaction spammer's keys")
+	}
+
+	spammer.mutex.Lock()
+	spammer.startTime = time.Now()
+	spammer.mutex.Unlock()
+
+	spammer.stopChan = make(chan struct{})
+	spammer.doneChan = make(chan struct{})
+	go spammer.run()
+	return nil
+}
+
+/*
+Stops issuing transactions and blocks until all issued transactions have been finalized or timed out.
+ */
+func (spammer *TxSpammer) Stop() {
+	if spammer.stopChan == nil {
+		return
+	}
+	select {
+	case <-spammer.stopChan:
+	default:
+		close(spammer.stopChan)
+	}
+	<-spammer.doneChan
+}
+
+/*
+Blocks until the spammer has run for its configured duration and all issued transactions have been finalized or timed out.
+ */
+func (spammer *TxSpammer) Wait() error {
+	if spammer.doneChan == nil {
+		return stacktrace.NewError("Transaction spammer hasn't been started")
+	}
+	if spammer.config.Duration == 0 {
+		return stacktrace.NewError("Transaction spammer has no duration, so it won't stop until Stop is called")
+	}
+	<-spammer.doneChan
+	return nil
+}
+
+/*
+Gets the results of the transactions issued so far (which can be called while the spammer is still running, in which case
+	transactions that haven't been finalized yet are only counted as issued).
+ */
+func (spammer *TxSpammer) GetResults() TxSpamResults {
+	spammer.mutex.Lock()
+	defer spammer.mutex.Unlock()
+
+	var elapsed time.Duration
+	if !spammer.stopTime.IsZero() {
+		elapsed = spammer.stopTime.Sub(spammer.startTime)
+	} else if !spammer.startTime.IsZero() {
+		elapsed = time.Since(spammer.startTime)
+	}
+
+	sortedLatencies := append([]time.Duration{}, spammer.latencies...)
+	sort.Slice(sortedLatencies, func(i, j int) bool {
+		return sortedLatencies[i] < sortedLatencies[j]
+	})
+
+	results := TxSpamResults{
+		Elapsed:  elapsed,
+		Issued:   spammer.issued,
+		Accepted: len(sortedLatencies),
+		Rejected: spammer.rejected,
+		Failed:   spammer.failed,
+	}
+	if elapsed > 0 {
+		results.Throughput = float64(results.Accepted) / elapsed.Seconds()
+	}
+	if len(sortedLatencies) > 0 {
+		results.LatencyP50 = getPercentile(sortedLatencies, 50)
+		results.LatencyP90 = getPercentile(sortedLatencies, 90)
+		results.LatencyP99 = getPercentile(sortedLatencies, 99)
+		results.LatencyMax = sortedLatencies[len(sortedLatencies) - 1]
+	}
+	return results
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (spammer *TxSpammer) fundKeys() error {
+	fundingClient := spammer.config.Targets[0]
+	// Distinguishes the keystore users of spammers run against the same network
+	spammerNonce := time.Now().UnixNano()
+
+	users := []KeystoreUser{}
+	addresses := []string{}
+	for i, target := range spammer.config.Targets {
+		user := KeystoreUser{
+			Username: fmt.Sprintf("%v%v-%v", spammerUsernamePrefix, spammerNonce, i),
+			Password: spammerPassword,
+		}
+		if err := target.CreateKeystoreUser(user); err != nil {
+			return stacktrace.Propagate(err, "An error occurred creating the spammer's keystore user on target #%v", i)
+		}
+		address, err := target.CreateXChainAddress(user)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred creating the spammer's address on target #%v", i)
+		}
+		txId, err := fundingClient.SendXChain(spammer.config.FundingUser, spammer.config.AssetId, spammer.config.FundingAmount, address)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred funding the spammer's address on target #%v", i)
+		}
+		status, err := waitForXChainTx(fundingClient, txId, time.Now().Add(spammer.config.AcceptanceTimeout))
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred waiting for funding transaction %v to be accepted", txId)
+		}
+		if status != ACCEPTED_TX_STATUS {
+			return stacktrace.NewError("Funding transaction %v was rejected", txId)
+		}
+		users = append(users, user)
+		addresses = append(addresses, address)
+	}
+	spammer.users = users
+	spammer.addresses = addresses
+	return nil
+}
+
+func (spammer *TxSpammer) run() {
+	defer close(spammer.doneChan)
+
+	inFlightTxs := &sync.WaitGroup{}
+	defer func() {
+		inFlightTxs.Wait()
+		spammer.mutex.Lock()
+		spammer.stopTime = time.Now()
+		spammer.mutex.Unlock()
+	}()
+
+	var durationChan <-chan time.Time
+	if spammer.config.Duration > 0 {
+		durationChan = time.After(spammer.config.Duration)
+	}
+	ticker := time.NewTicker(time.Second / time.Duration(spammer.config.TxsPerSecond))
+	defer ticker.Stop()
+
+	for txNumber := 0; ; txNumber++ {
+		select {
+		case <-spammer.stopChan:
+			return
+		case <-durationChan:
+			return
+		case <-ticker.C:
+		}
+
+		targetIdx := txNumber % len(spammer.config.Targets)
+		inFlightTxs.Add(1)
+		go func() {
+			defer inFlightTxs.Done()
+			spammer.issueTx(targetIdx)
+		}()
+	}
+}
+
+func (spammer *TxSpammer) issueTx(targetIdx int) {
+	target := spammer.config.Targets[targetIdx]
+	issueTime := time.Now()
+	txId, err := target.SendXChain(spammer.users[targetIdx], spammer.config.AssetId, spammer.config.TxAmount, spammer.addresses[targetIdx])
+	spammer.mutex.Lock()
+	if err != nil {
+		spammer.failed++
+	} else {
+		spammer.issued++
+	}
+	spammer.mutex.Unlock()
+	if err != nil {
+		return
+	}
+
+	status, err := waitForXChainTx(target, txId, issueTime.Add(spammer.config.AcceptanceTimeout))
+	latency := time.Since(issueTime)
+
+	spammer.mutex.Lock()
+	defer spammer.mutex.Unlock()
+	if err != nil {
+		spammer.failed++
+	} else if status == REJECTED_TX_STATUS {
+		spammer.rejected++
+	} else {
+		spammer.latencies = append(spammer.latencies, latency)
+	}
+}
+
+/*
+Polls the status of the given X-chain transaction until it's accepted or rejected, returning the final status.
+ */
+func waitForXChainTx(client *GeckoClient, txId string, deadline time.Time) (string, error) {
+	for {
+		status, err := client.GetXChainTxStatus(txId)
+		if err != nil {
+			return "", stacktrace.Propagate(err, "An error occurred getting the status of transaction %v", txId)
+		}
+		if status == ACCEPTED_TX_STATUS || status == REJECTED_TX_STATUS {
+			return status, nil
+		}
+		if time.Now().After(deadline) {
+			return "", stacktrace.NewError("Timed out waiting for transaction %v to be finalized; last status was %v", txId, status)
+		}
+		time.Sleep(txStatusPollInterval)
+	}
+}
+
+// Gets the given percentile of the given latencies, which must be sorted and non-empty
+func getPercentile(sortedLatencies []time.Duration, percentile int) time.Duration {
+	idx := (len(sortedLatencies) * percentile + 99) / 100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sortedLatencies[idx]
+}
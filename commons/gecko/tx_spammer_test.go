@@ -0,0 +1,157 @@
+package gecko
+
+import (
+	"encoding/json"
+	"fmt"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A fake node with a keystore & X-chain, which accepts transactions the second time their status is checked
+type fakeExchangeChain struct {
+	mutex *sync.Mutex
+
+	// The "set" of keystore usernames
+	users map[string]bool
+
+	// Mapping of address -> amount sent to it
+	received map[string]uint64
+
+	// Mapping of tx ID -> number of times its status was checked
+	txStatusChecks map[string]int
+
+	// The amount that, when sent, the chain rejects the sending transaction
+	rejectedAmount uint64
+}
+
+func (chain *fakeExchangeChain) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	chain.mutex.Lock()
+	defer chain.mutex.Unlock()
+	jsonRpcRequest := struct {
+		Method string                 `json:"method"`
+		Params map[string]interface{} `json:"params"`
+	}{}
+	if err := json.NewDecoder(request.Body).Decode(&jsonRpcRequest); err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	params := map[string]string{}
+	for name, value := range jsonRpcRequest.Params {
+		params[name] = fmt.Sprint(value)
+	}
+	var result interface{}
+	switch jsonRpcRequest.Method {
+	case "keystore.createUser":
+		chain.users[params["username"]] = true
+		result = map[string]bool{"success": true}
+	case "avm.createAddress":
+		result = map[string]string{"address": "X-" + params["username"]}
+	case "avm.send":
+		var amount uint64
+		fmt.Sscan(params["amount"], &amount)
+		chain.received[params["to"]] += amount
+		txId := fmt.Sprintf("tx%v", len(chain.txStatusChecks))
+		if amount == chain.rejectedAmount {
+			txId = "rejected-" + txId
+		}
+		chain.txStatusChecks[txId] = 0
+		result = map[string]string{"txID": txId}
+	case "avm.getTxStatus":
+		txId := params["txID"]
+		chain.txStatusChecks[txId]++
+		status := "Processing"
+		if chain.txStatusChecks[txId] > 1 {
+			status = ACCEPTED_TX_STATUS
+			if txId[0] == 'r' {
+				status = REJECTED_TX_STATUS
+			}
+		}
+		result = map[string]string{"status": status}
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	resultBytes, _ := json.Marshal(result)
+	fmt.Fprintf(writer, `{"jsonrpc":"2.0","id":1,"result":%s}`, resultBytes)
+}
+
+func newFakeExchangeChain(rejectedAmount uint64) *fakeExchangeChain {
+	return &fakeExchangeChain{
+		mutex:          &sync.Mutex{},
+		users:          map[string]bool{},
+		received:       map[string]uint64{},
+		txStatusChecks: map[string]int{},
+		rejectedAmount: rejectedAmount,
+	}
+}
+
+func TestTxSpammer(t *testing.T) {
+	chains := []*fakeExchangeChain{newFakeExchangeChain(0), newFakeExchangeChain(0)}
+	targets := []*GeckoClient{}
+	for _, chain := range chains {
+		server := httptest.NewServer(chain)
+		defer server.Close()
+		targets = append(targets, NewGeckoClient(server.URL))
+	}
+
+	spammer := NewTxSpammer(TxSpammerConfig{
+		Targets:       targets,
+		FundingUser:   KeystoreUser{Username: "funder", Password: "password"},
+		AssetId:       "AVA",
+		FundingAmount: 1000,
+		TxAmount:      1,
+		TxsPerSecond:  50,
+		Duration:      200 * time.Millisecond,
+	})
+	assert.NilError(t, spammer.Start())
+	assert.NilError(t, spammer.Wait())
+
+	// Both targets' keys are funded through the first target
+	assert.Equal(t, 2, len(chains[0].users) + len(chains[1].users))
+	fundedAddresses := 0
+	for _, amount := range chains[0].received {
+		if amount >= 1000 {
+			fundedAddresses++
+		}
+	}
+	assert.Equal(t, 2, fundedAddresses)
+
+	results := spammer.GetResults()
+	assert.Assert(t, results.Issued > 0)
+	assert.Equal(t, results.Issued, results.Accepted)
+	assert.Equal(t, 0, results.Rejected)
+	assert.Equal(t, 0, results.Failed)
+	assert.Assert(t, results.LatencyP50 >= txStatusPollInterval)
+	assert.Assert(t, results.LatencyMax >= results.LatencyP99)
+}
+
+func TestTxSpammerRejectedTxs(t *testing.T) {
+	chain := newFakeExchangeChain(1)
+	server := httptest.NewServer(chain)
+	defer server.Close()
+
+	spammer := NewTxSpammer(TxSpammerConfig{
+		Targets:       []*GeckoClient{NewGeckoClient(server.URL)},
+		AssetId:       "AVA",
+		FundingAmount: 1000,
+		TxAmount:      1,
+		TxsPerSecond:  50,
+	})
+	assert.NilError(t, spammer.Start())
+	time.Sleep(100 * time.Millisecond)
+	spammer.Stop()
+
+	results := spammer.GetResults()
+	assert.Assert(t, results.Rejected > 0)
+	assert.Equal(t, 0, results.Accepted)
+	assert.Assert(t, spammer.Wait() != nil)
+}
+
+func TestTxSpammerValidation(t *testing.T) {
+	spammer := NewTxSpammer(TxSpammerConfig{AssetId: "AVA", TxsPerSecond: 1})
+	assert.ErrorContains(t, spammer.Start(), "at least one target node")
+}
@@ -0,0 +1,214 @@
+package gecko
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/palantir/stacktrace"
+	"os"
+	"text/template"
+)
+
+const (
+	// The key of the genesis file in GetFilesToMount & InitializeMountedFiles (see AddNodeFilesToMount)
+	GENESIS_FILE_KEY = "genesis"
+)
+
+/*
+A member of a ValidatorSet.
+ */
+type GenesisValidator struct {
+	// The name the validator was added under (e.g. the service ID of the node that will be the validator)
+	Name string
+
+	// The ID of the node, derived from its staking credentials
+	NodeId string
+
+	// The validator's staking weight
+	Weight uint64
+
+	credentials *StakingCredentials
+}
+
+/*
+The values that a ValidatorSet's genesis template is rendered with.
+ */
+type GenesisData struct {
+	// The validators, in the order they were added
+	Validators []GenesisValidator
+
+	// The sum of the validators' weights
+	TotalWeight uint64
+}
+
+/*
+The validators of a Gecko network & their staking weights, declared when the network is defined. Each validator gets its
+	own staking credentials, so its node ID is known up front, and the network's genesis is rendered from a template
+	listing the validators. Every node (validator or not) is given the same genesis & its own credentials through its
+	ServiceInitializerCore, with AddNodeFilesToMount, InitializeNodeFiles, & GetNodeFlags.
+
+As the genesis format differs between Gecko versions, the genesis is given as a Go text/template rendered against a
+	GenesisData, with a "json" function for quoting values, e.g.:
+
+		"initialStakers": [{{ range $i, $v := .Validators }}{{ if $i }},{{ end }}
+			{"nodeID": {{ json $v.NodeId }}, "weight": {{ $v.Weight }}}{{ end }}
+		]
+ */
+type ValidatorSet struct {
+	genesisTemplate *template.Template
+
+	validators []GenesisValidator
+}
+
+/*
+Creates an empty validator set.
+
+Args:
+	genesisTemplate: The Go text/template of the network's genesis, rendered against a GenesisData
+ */
+func NewValidatorSet(genesisTemplate string) (*ValidatorSet, error) {
+	parsedTemplate, err := template.New("genesis").Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			jsonBytes, err := json.Marshal(value)
+			return string(jsonBytes), err
+		},
+	}).Parse(genesisTemplate)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing the genesis template")
+	}
+	return &ValidatorSet{
+		genesisTemplate: parsedTemplate,
+		validators:      []GenesisValidator{},
+	}, nil
+}
+
+/*
+Adds a validator to the set, generating its staking credentials.
+
+Args:
+	name: The name of the validator, unique within the set, which its node's core gives InitializeNodeFiles
+	weight: The validator's staking weight, which must be positive
+ */
+func (set *ValidatorSet) AddValidator(name string, weight uint64) error {
+	if _, found := set.getValidator(name); found {
+		return stacktrace.NewError("Validator '%v' is already in the set", name)
+	}
+	if weight == 0 {
+		return stacktrace.NewError("Validator '%v' must have a positive weight", name)
+	}
+	credentials, err := GenerateStakingCredentials()
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred generating the staking credentials of validator '%v'", name)
+	}
+	nodeId, err := GetNodeId(credentials)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred getting the node ID of validator '%v'", name)
+	}
+	set.validators = append(set.validators, GenesisValidator{
+		Name:        name,
+		NodeId:      nodeId,
+		Weight:      weight,
+		credentials: credentials,
+	})
+	return nil
+}
+
+/*
+Gets the validators, in the order they were added.
+ */
+func (set *ValidatorSet) GetValidators() []GenesisValidator {
+	return append([]GenesisValidator{}, set.validators...)
+}
+
+/*
+Gets the sum of the validators' weights.
+ */
+func (set *ValidatorSet) GetTotalWeight() uint64 {
+	result := uint64(0)
+	for _, validator := range set.validators {
+		result += validator.Weight
+	}
+	return result
+}
+
+/*
+Renders the network's genesis from the set's template, which is the same for every node.
+ */
+func (set *ValidatorSet) GetGenesis() ([]byte, error) {
+	genesisData := GenesisData{
+		Validators:  set.GetValidators(),
+		TotalWeight: set.GetTotalWeight(),
+	}
+	buffer := &bytes.Buffer{}
+	if err := set.genesisTemplate.Execute(buffer, genesisData); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred rendering the genesis template")
+	}
+	return buffer.Bytes(), nil
+}
+
+/*
+Adds the genesis & staking files to the given "set" of files to mount, for a Gecko ServiceInitializerCore's
+	GetFilesToMount.
+
+Args:
+	filesToMount: The "set" of the core's other files to mount, which is modified
+
+Returns:
+	The given "set", for convenience
+ */
+func (set *ValidatorSet) AddNodeFilesToMount(filesToMount map[string]bool) map[string]bool {
+	filesToMount[GENESIS_FILE_KEY] = true
+	return AddStakingFilesToMount(filesToMount)
+}
+
+/*
+Writes the genesis & a node's staking credentials into its files, for a Gecko ServiceInitializerCore's
+	InitializeMountedFiles.
+
+Args:
+	name: The name of the validator that the node is, or a name that isn't in the set for nodes that aren't validators
+		(which get newly-generated credentials)
+	mountedFiles: The files passed to the core's InitializeMountedFiles, which must include the files added by
+		AddNodeFilesToMount
+ */
+func (set *ValidatorSet) InitializeNodeFiles(name string, mountedFiles map[string]*os.File) error {
+	genesis, err := set.GetGenesis()
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred getting the genesis")
+	}
+	genesisFp, found := mountedFiles[GENESIS_FILE_KEY]
+	if !found {
+		return stacktrace.NewError("No mounted file with key '%v' was found; the core's GetFilesToMount must use AddNodeFilesToMount", GENESIS_FILE_KEY)
+	}
+	if _, err := genesisFp.Write(genesis); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the genesis file")
+	}
+
+	var credentials *StakingCredentials = nil
+	if validator, found := set.getValidator(name); found {
+		credentials = validator.credentials
+	}
+	if err := InitializeStakingFiles(mountedFiles, credentials); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the staking files of node '%v'", name)
+	}
+	return nil
+}
+
+/*
+Gets the Gecko flags that launch a node with the files written by InitializeNodeFiles, as start command fragments for a
+	Gecko ServiceInitializerCore's GetStartCommand.
+ */
+func (set *ValidatorSet) GetNodeFlags() []string {
+	return append(
+		[]string{"--genesis={{ .MountedFilepaths." + GENESIS_FILE_KEY + " }}"},
+		GetStakingFlags()...)
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (set *ValidatorSet) getValidator(name string) (GenesisValidator, bool) {
+	for _, validator := range set.validators {
+		if validator.Name == name {
+			return validator, true
+		}
+	}
+	return GenesisValidator{}, false
+}
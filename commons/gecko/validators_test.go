@@ -0,0 +1,64 @@
+package gecko
+
+import (
+	"encoding/json"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+const testGenesisTemplate = `{"totalWeight": {{ .TotalWeight }}, "initialStakers": [{{ range $i, $v := .Validators }}{{ if $i }},{{ end }}{"nodeID": {{ json $v.NodeId }}, "weight": {{ $v.Weight }}}{{ end }}]}`
+
+func TestValidatorSet(t *testing.T) {
+	validatorSet, err := NewValidatorSet(testGenesisTemplate)
+	assert.NilError(t, err)
+	assert.NilError(t, validatorSet.AddValidator("validator-0", 100))
+	assert.NilError(t, validatorSet.AddValidator("validator-1", 50))
+	assert.ErrorContains(t, validatorSet.AddValidator("validator-1", 50), "already in the set")
+	assert.ErrorContains(t, validatorSet.AddValidator("validator-2", 0), "positive weight")
+
+	validators := validatorSet.GetValidators()
+	assert.Equal(t, 2, len(validators))
+	assert.Assert(t, strings.HasPrefix(validators[0].NodeId, "NodeID-"))
+	assert.Assert(t, validators[0].NodeId != validators[1].NodeId)
+
+	genesis, err := validatorSet.GetGenesis()
+	assert.NilError(t, err)
+	parsedGenesis := struct {
+		TotalWeight    uint64 `json:"totalWeight"`
+		InitialStakers []struct {
+			NodeId string `json:"nodeID"`
+			Weight uint64 `json:"weight"`
+		} `json:"initialStakers"`
+	}{}
+	assert.NilError(t, json.Unmarshal(genesis, &parsedGenesis))
+	assert.Equal(t, uint64(150), parsedGenesis.TotalWeight)
+	assert.Equal(t, validators[1].NodeId, parsedGenesis.InitialStakers[1].NodeId)
+	assert.Equal(t, uint64(50), parsedGenesis.InitialStakers[1].Weight)
+
+	// A validator's node is launched with its own credentials, whose node ID is the one in the genesis
+	dirpath, err := ioutil.TempDir("", "validator")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dirpath)
+	mountedFiles := map[string]*os.File{}
+	for fileKey, _ := range validatorSet.AddNodeFilesToMount(map[string]bool{}) {
+		fp, err := os.Create(path.Join(dirpath, fileKey))
+		assert.NilError(t, err)
+		defer fp.Close()
+		mountedFiles[fileKey] = fp
+	}
+	assert.NilError(t, validatorSet.InitializeNodeFiles("validator-1", mountedFiles))
+	certPem, err := ioutil.ReadFile(path.Join(dirpath, STAKING_CERT_FILE_KEY))
+	assert.NilError(t, err)
+	nodeId, err := GetNodeId(&StakingCredentials{CertPem: certPem})
+	assert.NilError(t, err)
+	assert.Equal(t, validators[1].NodeId, nodeId)
+	writtenGenesis, err := ioutil.ReadFile(path.Join(dirpath, GENESIS_FILE_KEY))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, genesis, writtenGenesis)
+
+	assert.Equal(t, "--genesis={{ .MountedFilepaths.genesis }}", validatorSet.GetNodeFlags()[0])
+}
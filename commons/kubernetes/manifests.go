@@ -0,0 +1,337 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/palantir/stacktrace"
+	"gopkg.in/yaml.v2"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// The label that ties a service's Deployment, pods, & Service together
+	NAME_LABEL = "app.kubernetes.io/name"
+
+	MANAGED_BY_LABEL       = "app.kubernetes.io/managed-by"
+	MANAGED_BY_LABEL_VALUE = "kurtosis"
+
+	/*
+	The env variable that every exported container gets its pod's IP in, which start commands & env variables that
+		referred to the service's own IP are rewritten to use (as "$(POD_IP)"), because pods' IPs aren't known up front
+	 */
+	POD_IP_ENV_VARIABLE = "POD_IP"
+
+	// The suffix of the name of the Kubernetes Secret that a service's secret env variables are read from, which must be
+	//  created separately (e.g. "node1-secrets" for service "node1")
+	SECRET_NAME_SUFFIX = "-secrets"
+
+	manifestSeparator = "---\n"
+
+	// Kubernetes object names must be DNS labels, which are at most this long
+	maxObjectNameLength = 63
+
+	podIpFieldPath      = "status.podIP"
+	nodePortServiceType = "NodePort"
+)
+
+var invalidObjectNameCharsRegex = regexp.MustCompile("[^a-z0-9-]+")
+
+// Matches whole IPv4 addresses, so that e.g. 172.23.0.3 isn't found at the start of 172.23.0.30
+var ipAddrRegex = regexp.MustCompile(`[0-9]+(\.[0-9]+){3}`)
+
+/*
+Renders the given network plan (see networks.Plan) as Kubernetes manifests, so that a topology validated in tests can be
+	deployed to a long-lived environment (e.g. with "kubectl apply -f"). Each service becomes a Deployment, plus a Service
+	named the same (if it uses any ports) that the other services reach it through. Because pods don't have the IPs that the
+	plan gave services, every IP of a service that appears in a start command, entrypoint, or env variable is replaced by
+	that service's name, or by "$(POD_IP)" in the service's own container.
+
+The manifests don't carry over services' mounted files, shared volumes, or dependency ordering (Kubernetes restarts pods
+	whose dependencies aren't up yet instead), and services that the plan left unstarted get zero replicas.
+
+Args:
+	plan: The plan of the network to export
+	namespace: The Kubernetes namespace to put the objects in, or empty to leave it up to kubectl
+
+Returns:
+	The manifests, as a multi-document YAML string
+ */
+func RenderManifests(plan *networks.NetworkPlan, namespace string) (string, error) {
+	objectNames := make(map[networks.ServiceID]string)
+	serviceIdsByObjectName := make(map[string]networks.ServiceID)
+	for _, service := range plan.Services {
+		objectName := getObjectName(service.ServiceId)
+		if objectName == "" {
+			return "", stacktrace.NewError("Service ID '%v' has no characters that are valid in a Kubernetes object name", service.ServiceId)
+		}
+		if otherServiceId, found := serviceIdsByObjectName[objectName]; found {
+			return "", stacktrace.NewError("Services '%v' and '%v' would both have Kubernetes object name '%v'", otherServiceId, service.ServiceId, objectName)
+		}
+		objectNames[service.ServiceId] = objectName
+		serviceIdsByObjectName[objectName] = service.ServiceId
+	}
+
+	builder := &strings.Builder{}
+	for _, service := range plan.Services {
+		ipReplacer := getIpReplacer(plan, objectNames, service.ServiceId)
+		documents := []interface{}{
+			getDeployment(service, objectNames[service.ServiceId], namespace, ipReplacer),
+		}
+		if len(service.Ports) > 0 {
+			serviceManifest, err := getService(service, objectNames[service.ServiceId], namespace)
+			if err != nil {
+				return "", stacktrace.Propagate(err, "An error occurred getting the Kubernetes Service for service '%v'", service.ServiceId)
+			}
+			documents = append(documents, serviceManifest)
+		}
+		for _, document := range documents {
+			documentBytes, err := yaml.Marshal(document)
+			if err != nil {
+				return "", stacktrace.Propagate(err, "An error occurred serializing a manifest for service '%v'", service.ServiceId)
+			}
+			builder.WriteString(manifestSeparator)
+			builder.Write(documentBytes)
+		}
+	}
+	return builder.String(), nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+type objectMeta struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+type deployment struct {
+	ApiVersion string         `yaml:"apiVersion"`
+	Kind       string         `yaml:"kind"`
+	Metadata   objectMeta     `yaml:"metadata"`
+	Spec       deploymentSpec `yaml:"spec"`
+}
+
+type deploymentSpec struct {
+	Replicas int             `yaml:"replicas"`
+	Selector labelSelector   `yaml:"selector"`
+	Template podTemplateSpec `yaml:"template"`
+}
+
+type labelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type podTemplateSpec struct {
+	Metadata podTemplateMeta `yaml:"metadata"`
+	Spec     podSpec         `yaml:"spec"`
+}
+
+type podTemplateMeta struct {
+	Labels map[string]string `yaml:"labels"`
+}
+
+type podSpec struct {
+	HostNetwork bool        `yaml:"hostNetwork,omitempty"`
+	Containers  []container `yaml:"containers"`
+}
+
+type container struct {
+	Name    string          `yaml:"name"`
+	Image   string          `yaml:"image"`
+	Command []string        `yaml:"command,omitempty"`
+	Args    []string        `yaml:"args,omitempty"`
+	Env     []envVariable   `yaml:"env"`
+	Ports   []containerPort `yaml:"ports,omitempty"`
+}
+
+type envVariable struct {
+	Name      string             `yaml:"name"`
+	Value     string             `yaml:"value,omitempty"`
+	ValueFrom *envVariableSource `yaml:"valueFrom,omitempty"`
+}
+
+type envVariableSource struct {
+	FieldRef     *fieldSelector     `yaml:"fieldRef,omitempty"`
+	SecretKeyRef *secretKeySelector `yaml:"secretKeyRef,omitempty"`
+}
+
+type fieldSelector struct {
+	FieldPath string `yaml:"fieldPath"`
+}
+
+type secretKeySelector struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+type containerPort struct {
+	ContainerPort int    `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol"`
+}
+
+type kubernetesService struct {
+	ApiVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   objectMeta  `yaml:"metadata"`
+	Spec       serviceSpec `yaml:"spec"`
+}
+
+type serviceSpec struct {
+	Type     string            `yaml:"type,omitempty"`
+	Selector map[string]string `yaml:"selector"`
+	Ports    []servicePort     `yaml:"ports"`
+}
+
+type servicePort struct {
+	Name       string `yaml:"name"`
+	Port       int    `yaml:"port"`
+	TargetPort int    `yaml:"targetPort"`
+	Protocol   string `yaml:"protocol"`
+}
+
+// Turns a service ID into a valid Kubernetes object name (a DNS label), e.g. "Node_1" -> "node-1"
+func getObjectName(serviceId networks.ServiceID) string {
+	name := invalidObjectNameCharsRegex.ReplaceAllString(strings.ToLower(string(serviceId)), "-")
+	if len(name) > maxObjectNameLength {
+		name = name[:maxObjectNameLength]
+	}
+	return strings.Trim(name, "-")
+}
+
+// Gets a replacer of the planned IPs of the plan's services, as seen from the container of the given service
+func getIpReplacer(plan *networks.NetworkPlan, objectNames map[networks.ServiceID]string, serviceId networks.ServiceID) func(string) string {
+	replacements := make(map[string]string)
+	for _, service := range plan.Services {
+		if service.IpAddr == nil || service.UseHostNetwork {
+			continue
+		}
+		replacement := objectNames[service.ServiceId]
+		if service.ServiceId == serviceId {
+			replacement = fmt.Sprintf("$(%v)", POD_IP_ENV_VARIABLE)
+		}
+		replacements[service.IpAddr.String()] = replacement
+	}
+	return func(str string) string {
+		return ipAddrRegex.ReplaceAllStringFunc(str, func(ipAddr string) string {
+			if replacement, found := replacements[ipAddr]; found {
+				return replacement
+			}
+			return ipAddr
+		})
+	}
+}
+
+func getDeployment(plannedService networks.PlannedService, objectName string, namespace string, ipReplacer func(string) string) deployment {
+	env := []envVariable{
+		{
+			Name:      POD_IP_ENV_VARIABLE,
+			ValueFrom: &envVariableSource{FieldRef: &fieldSelector{FieldPath: podIpFieldPath}},
+		},
+	}
+	for _, name := range getSortedKeys(plannedService.EnvVariables) {
+		env = append(env, envVariable{Name: name, Value: ipReplacer(plannedService.EnvVariables[name])})
+	}
+	for _, name := range plannedService.SecretEnvVariables {
+		env = append(env, envVariable{
+			Name:      name,
+			ValueFrom: &envVariableSource{SecretKeyRef: &secretKeySelector{Name: objectName + SECRET_NAME_SUFFIX, Key: name}},
+		})
+	}
+
+	ports := []containerPort{}
+	for _, portStr := range plannedService.Ports {
+		port := nat.Port(portStr)
+		ports = append(ports, containerPort{ContainerPort: port.Int(), Protocol: strings.ToUpper(port.Proto())})
+	}
+
+	replicas := 1
+	if !plannedService.Started {
+		replicas = 0
+	}
+	labels := getLabels(objectName)
+	return deployment{
+		ApiVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata:   objectMeta{Name: objectName, Namespace: namespace, Labels: labels},
+		Spec: deploymentSpec{
+			Replicas: replicas,
+			Selector: labelSelector{MatchLabels: map[string]string{NAME_LABEL: objectName}},
+			Template: podTemplateSpec{
+				Metadata: podTemplateMeta{Labels: labels},
+				Spec: podSpec{
+					HostNetwork: plannedService.UseHostNetwork,
+					Containers: []container{
+						{
+							Name:    objectName,
+							Image:   plannedService.DockerImage,
+							Command: replaceAll(plannedService.Entrypoint, ipReplacer),
+							Args:    replaceAll(plannedService.StartCmd, ipReplacer),
+							Env:     env,
+							Ports:   ports,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func getService(plannedService networks.PlannedService, objectName string, namespace string) (kubernetesService, error) {
+	ports := []servicePort{}
+	for _, portStr := range plannedService.Ports {
+		port := nat.Port(portStr)
+		portNum := port.Int()
+		if portNum == 0 {
+			return kubernetesService{}, stacktrace.NewError("Port '%v' isn't a single port", portStr)
+		}
+		ports = append(ports, servicePort{
+			Name:       fmt.Sprintf("%v-%v", port.Proto(), portNum),
+			Port:       portNum,
+			TargetPort: portNum,
+			Protocol:   strings.ToUpper(port.Proto()),
+		})
+	}
+	serviceType := ""
+	if plannedService.PublishPorts && !plannedService.UseHostNetwork {
+		serviceType = nodePortServiceType
+	}
+	return kubernetesService{
+		ApiVersion: "v1",
+		Kind:       "Service",
+		Metadata:   objectMeta{Name: objectName, Namespace: namespace, Labels: getLabels(objectName)},
+		Spec: serviceSpec{
+			Type:     serviceType,
+			Selector: map[string]string{NAME_LABEL: objectName},
+			Ports:    ports,
+		},
+	}, nil
+}
+
+func getLabels(objectName string) map[string]string {
+	return map[string]string{
+		NAME_LABEL:       objectName,
+		MANAGED_BY_LABEL: MANAGED_BY_LABEL_VALUE,
+	}
+}
+
+func getSortedKeys(strMap map[string]string) []string {
+	result := make([]string, 0, len(strMap))
+	for key, _ := range strMap {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func replaceAll(strs []string, replacer func(string) string) []string {
+	if len(strs) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(strs))
+	for _, str := range strs {
+		result = append(result, replacer(str))
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package kubernetes
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"gopkg.in/yaml.v2"
+	"gotest.tools/v3/assert"
+	"net"
+	"strings"
+	"testing"
+)
+
+var testPlan = &networks.NetworkPlan{
+	Images: []string{"my-node:1.0"},
+	Services: []networks.PlannedService{
+		{
+			ServiceId:   "Bootstrap_Node",
+			DockerImage: "my-node:1.0",
+			IpAddr:      net.ParseIP("172.23.0.3"),
+			Ports:       []string{"8545/tcp", "30303/udp"},
+			StartCmd:    []string{"--public-ip=172.23.0.3"},
+			Started:     true,
+		},
+		{
+			ServiceId:          "node1",
+			DockerImage:        "my-node:1.0",
+			IpAddr:             net.ParseIP("172.23.0.30"),
+			Ports:              []string{"8545/tcp"},
+			PublishPorts:       true,
+			StartCmd:           []string{"--bootstrap=172.23.0.3:8545", "--public-ip=172.23.0.30"},
+			EnvVariables:       map[string]string{"PEERS": "172.23.0.3,172.23.0.30"},
+			SecretEnvVariables: []string{"API_KEY"},
+			Started:            true,
+		},
+		{
+			ServiceId:   "standby",
+			DockerImage: "my-node:1.0",
+			IpAddr:      net.ParseIP("172.23.0.4"),
+			Ports:       []string{},
+			Entrypoint:  []string{"/bin/sh", "-c"},
+			Started:     false,
+		},
+	},
+}
+
+func TestRenderingManifests(t *testing.T) {
+	manifests, err := RenderManifests(testPlan, "staging")
+	assert.NilError(t, err)
+
+	documents := map[string]map[string]interface{}{}
+	for _, documentStr := range strings.Split(manifests, manifestSeparator)[1:] {
+		document := map[string]interface{}{}
+		assert.NilError(t, yaml.Unmarshal([]byte(documentStr), &document))
+		metadata := document["metadata"].(map[interface{}]interface{})
+		assert.Equal(t, "staging", metadata["namespace"])
+		documents[document["kind"].(string) + "/" + metadata["name"].(string)] = document
+	}
+	// The standby service has no ports, so gets no Kubernetes Service
+	assert.Equal(t, 5, len(documents))
+	for _, key := range []string{"Deployment/bootstrap-node", "Service/bootstrap-node", "Deployment/node1", "Service/node1", "Deployment/standby"} {
+		_, found := documents[key]
+		assert.Assert(t, found, "Missing manifest %v", key)
+	}
+
+	// Other services' IPs become their names, while the service's own IP comes from the pod
+	assert.Assert(t, strings.Contains(manifests, "--bootstrap=bootstrap-node:8545"))
+	assert.Assert(t, strings.Contains(manifests, "--public-ip=$(POD_IP)"))
+	assert.Assert(t, strings.Contains(manifests, "value: bootstrap-node,$(POD_IP)"))
+	assert.Assert(t, !strings.Contains(manifests, "172.23.0."))
+
+	assert.Assert(t, strings.Contains(manifests, "name: node1-secrets"))
+	assert.Assert(t, strings.Contains(manifests, "type: NodePort"))
+	assert.Assert(t, strings.Contains(manifests, "protocol: UDP"))
+	standbySpec := documents["Deployment/standby"]["spec"].(map[interface{}]interface{})
+	assert.Equal(t, 0, standbySpec["replicas"])
+}
+
+func TestRenderingManifestsWithCollidingNames(t *testing.T) {
+	plan := &networks.NetworkPlan{
+		Services: []networks.PlannedService{
+			{ServiceId: "node_1", DockerImage: "my-node:1.0"},
+			{ServiceId: "node.1", DockerImage: "my-node:1.0"},
+		},
+	}
+	_, err := RenderManifests(plan, "")
+	assert.ErrorContains(t, err, "would both have Kubernetes object name 'node-1'")
+}
@@ -0,0 +1,23 @@
+package logging
+
+import "github.com/sirupsen/logrus"
+
+/*
+The structured-logging fields that Kurtosis attaches to its log lines so that every line can be attributed to the run,
+	test, and service it came from (which matters when many tests run in parallel).
+ */
+const (
+	EXECUTION_ID_FIELD = "execution_id"
+	TEST_NAME_FIELD    = "test_name"
+	SERVICE_ID_FIELD   = "service_id"
+)
+
+/*
+Gets a log entry for the given logger that's tagged with the given test execution ID and test name.
+ */
+func NewTestLogEntry(log *logrus.Logger, executionId string, testName string) *logrus.Entry {
+	return log.WithFields(logrus.Fields{
+		EXECUTION_ID_FIELD: executionId,
+		TEST_NAME_FIELD:    testName,
+	})
+}
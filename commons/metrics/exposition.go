@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	METRICS_PATH = "/metrics"
+
+	// The content type of the Prometheus text exposition format
+	textExpositionContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+/*
+Gets an HTTP handler that serves the contents of the given registry in the Prometheus text exposition format.
+ */
+func NewHandler(registry *Registry) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		buffer := &bytes.Buffer{}
+		if err := registry.WriteText(buffer); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", textExpositionContentType)
+		writer.Write(buffer.Bytes())
+	})
+}
+
+/*
+Starts serving the given registry on the /metrics path of the given listen address (e.g. ":9090") in a background
+	goroutine, so that a Prometheus server can scrape it.
+
+Returns:
+	The server, which the caller should Close when it no longer wants to serve metrics
+ */
+func StartServer(registry *Registry, listenAddr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred listening on %v to serve metrics", listenAddr)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(METRICS_PATH, NewHandler(registry))
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	return server, nil
+}
+
+/*
+Pushes the contents of the given registry to a Prometheus Pushgateway, replacing any metrics previously pushed for the
+	given job. This is the better fit for short-lived processes (like a test controller) that won't be around to be scraped.
+
+Args:
+	registry: The registry whose metrics should be pushed
+	gatewayUrl: The base URL of the Pushgateway (e.g. "http://pushgateway:9091")
+	jobName: The Pushgateway job that the metrics will be grouped under
+ */
+func PushToGateway(registry *Registry, gatewayUrl string, jobName string) error {
+	buffer := &bytes.Buffer{}
+	if err := registry.WriteText(buffer); err != nil {
+		return stacktrace.Propagate(err, "An error occurred rendering the metrics to push")
+	}
+
+	pushUrl := fmt.Sprintf("%v/metrics/job/%v", strings.TrimRight(gatewayUrl, "/"), url.PathEscape(jobName))
+	request, err := http.NewRequest(http.MethodPut, pushUrl, buffer)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred building the request to push metrics to %v", pushUrl)
+	}
+	request.Header.Set("Content-Type", textExpositionContentType)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred pushing metrics to %v", pushUrl)
+	}
+	defer response.Body.Close()
+	if response.StatusCode / 100 != 2 {
+		return stacktrace.NewError("Pushing metrics to %v returned non-2xx status code %v", pushUrl, response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package metrics
+
+/*
+Metrics describing the health of the Kurtosis orchestration itself (as opposed to the services under test), all registered
+	on the DefaultRegistry. Expose them with StartServer(DefaultRegistry, ...) or PushToGateway(DefaultRegistry, ...).
+ */
+var (
+	// The registry that all of Kurtosis' own metrics are registered on
+	DefaultRegistry = NewRegistry()
+
+	ContainersStarted = DefaultRegistry.NewCounterVec(
+		"kurtosis_containers_started_total",
+		"Number of Docker containers started by Kurtosis")
+
+	ServiceStartupSeconds = DefaultRegistry.NewHistogramVec(
+		"kurtosis_service_startup_seconds",
+		"Time from a service's container being started to the service being reported as available",
+		[]float64{1, 2, 5, 10, 20, 30, 60, 120, 300},
+		"service_id")
+
+	AvailabilityProbes = DefaultRegistry.NewCounterVec(
+		"kurtosis_availability_probes_total",
+		"Number of availability probes made against services",
+		"service_id")
+
+	AvailabilityProbeFailures = DefaultRegistry.NewCounterVec(
+		"kurtosis_availability_probe_failures_total",
+		"Number of availability probes that reported a service as not yet available",
+		"service_id")
+
+	IpAddrsInUse = DefaultRegistry.NewGaugeVec(
+		"kurtosis_ip_addresses_in_use",
+		"Number of IP addresses currently handed out to containers in test networks")
+)
@@ -0,0 +1,259 @@
+package metrics
+
+import (
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// =============================== "enum" for metric types =========================================
+type metricType string
+const (
+	counterType   metricType = "counter"
+	gaugeType     metricType = "gauge"
+	histogramType metricType = "histogram"
+)
+
+/*
+A minimal, dependency-free registry of metrics that can render itself in the Prometheus text exposition format.
+
+NOTE: This is thread-safe!
+ */
+type Registry struct {
+	mutex *sync.Mutex
+
+	// The metric families in the order they were registered, so output is stable between scrapes
+	families []*metricFamily
+}
+
+/*
+Creates a new, empty registry.
+ */
+func NewRegistry() *Registry {
+	return &Registry{
+		mutex:    &sync.Mutex{},
+		families: []*metricFamily{},
+	}
+}
+
+/*
+Registers a new counter (a value that only ever goes up) with the given name, help text, and label names.
+ */
+func (registry *Registry) NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{family: registry.register(name, help, counterType, nil, labelNames)}
+}
+
+/*
+Registers a new gauge (a value that can go up and down) with the given name, help text, and label names.
+ */
+func (registry *Registry) NewGaugeVec(name string, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{family: registry.register(name, help, gaugeType, nil, labelNames)}
+}
+
+/*
+Registers a new histogram with the given name, help text, bucket upper bounds (which must be sorted ascending), and label names.
+ */
+func (registry *Registry) NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	bucketsCopy := make([]float64, len(buckets))
+	copy(bucketsCopy, buckets)
+	return &HistogramVec{family: registry.register(name, help, histogramType, bucketsCopy, labelNames)}
+}
+
+/*
+Writes every metric in the registry to the given writer in the Prometheus text exposition format.
+ */
+func (registry *Registry) WriteText(writer io.Writer) error {
+	registry.mutex.Lock()
+	families := make([]*metricFamily, len(registry.families))
+	copy(families, registry.families)
+	registry.mutex.Unlock()
+
+	for _, family := range families {
+		if _, err := io.WriteString(writer, family.render()); err != nil {
+			return stacktrace.Propagate(err, "An error occurred writing metric family %v", family.name)
+		}
+	}
+	return nil
+}
+
+func (registry *Registry) register(name string, help string, familyType metricType, buckets []float64, labelNames []string) *metricFamily {
+	family := &metricFamily{
+		name:       name,
+		help:       help,
+		familyType: familyType,
+		buckets:    buckets,
+		labelNames: append([]string{}, labelNames...),
+		mutex:      &sync.Mutex{},
+		series:     make(map[string]*series),
+	}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.families = append(registry.families, family)
+	return family
+}
+
+// =============================== Public metric types =========================================
+/*
+A counter, partitioned by label values.
+ */
+type CounterVec struct {
+	family *metricFamily
+}
+
+// Increments the counter with the given label values by one
+func (counter *CounterVec) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Increases the counter with the given label values by the given (non-negative) amount
+func (counter *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	counter.family.update(labelValues, func(s *series) { s.value += delta })
+}
+
+/*
+A gauge, partitioned by label values.
+ */
+type GaugeVec struct {
+	family *metricFamily
+}
+
+// Sets the gauge with the given label values to the given value
+func (gauge *GaugeVec) Set(value float64, labelValues ...string) {
+	gauge.family.update(labelValues, func(s *series) { s.value = value })
+}
+
+// Adds the given (possibly negative) amount to the gauge with the given label values
+func (gauge *GaugeVec) Add(delta float64, labelValues ...string) {
+	gauge.family.update(labelValues, func(s *series) { s.value += delta })
+}
+
+/*
+A histogram, partitioned by label values.
+ */
+type HistogramVec struct {
+	family *metricFamily
+}
+
+// Records an observation in the histogram with the given label values
+func (histogram *HistogramVec) Observe(value float64, labelValues ...string) {
+	buckets := histogram.family.buckets
+	histogram.family.update(labelValues, func(s *series) {
+		if s.bucketCounts == nil {
+			s.bucketCounts = make([]uint64, len(buckets))
+		}
+		for i, upperBound := range buckets {
+			if value <= upperBound {
+				s.bucketCounts[i]++
+			}
+		}
+		s.value += value
+		s.count++
+	})
+}
+
+// =============================== Private helpers =========================================
+type metricFamily struct {
+	name       string
+	help       string
+	familyType metricType
+	buckets    []float64
+	labelNames []string
+
+	mutex  *sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+
+	// The counter/gauge value, or the sum of observations for a histogram
+	value float64
+
+	// Only used by histograms
+	count        uint64
+	bucketCounts []uint64
+}
+
+func (family *metricFamily) update(labelValues []string, updateFunc func(s *series)) {
+	// Pad or truncate so that a mismatched number of label values can't break rendering
+	normalizedValues := make([]string, len(family.labelNames))
+	copy(normalizedValues, labelValues)
+
+	key := strings.Join(normalizedValues, "\xff")
+	family.mutex.Lock()
+	defer family.mutex.Unlock()
+	s, found := family.series[key]
+	if !found {
+		s = &series{labelValues: normalizedValues}
+		family.series[key] = s
+	}
+	updateFunc(s)
+}
+
+func (family *metricFamily) render() string {
+	family.mutex.Lock()
+	defer family.mutex.Unlock()
+
+	keys := make([]string, 0, len(family.series))
+	for key, _ := range family.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "# HELP %v %v\n", family.name, escapeHelp(family.help))
+	fmt.Fprintf(builder, "# TYPE %v %v\n", family.name, family.familyType)
+	for _, key := range keys {
+		s := family.series[key]
+		if family.familyType != histogramType {
+			fmt.Fprintf(builder, "%v%v %v\n", family.name, renderLabels(family.labelNames, s.labelValues, "", ""), formatValue(s.value))
+			continue
+		}
+		for i, upperBound := range family.buckets {
+			labels := renderLabels(family.labelNames, s.labelValues, "le", formatValue(upperBound))
+			fmt.Fprintf(builder, "%v_bucket%v %v\n", family.name, labels, s.bucketCounts[i])
+		}
+		infLabels := renderLabels(family.labelNames, s.labelValues, "le", "+Inf")
+		fmt.Fprintf(builder, "%v_bucket%v %v\n", family.name, infLabels, s.count)
+		plainLabels := renderLabels(family.labelNames, s.labelValues, "", "")
+		fmt.Fprintf(builder, "%v_sum%v %v\n", family.name, plainLabels, formatValue(s.value))
+		fmt.Fprintf(builder, "%v_count%v %v\n", family.name, plainLabels, s.count)
+	}
+	return builder.String()
+}
+
+func renderLabels(labelNames []string, labelValues []string, extraName string, extraValue string) string {
+	pairs := []string{}
+	for i, name := range labelNames {
+		pairs = append(pairs, fmt.Sprintf("%v=\"%v\"", name, escapeLabelValue(labelValues[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%v=\"%v\"", extraName, escapeLabelValue(extraValue)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"").Replace(value)
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"bytes"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestTextExposition(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_total", "A test counter", "service_id")
+	gauge := registry.NewGaugeVec("test_gauge", "A test gauge")
+	histogram := registry.NewHistogramVec("test_seconds", "A test histogram", []float64{1, 5})
+
+	counter.Inc("node\"1")
+	counter.Add(2, "node2")
+	gauge.Set(3)
+	gauge.Add(-1)
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+	histogram.Observe(10)
+
+	buffer := &bytes.Buffer{}
+	assert.NilError(t, registry.WriteText(buffer))
+
+	expected := `# HELP test_total A test counter
+# TYPE test_total counter
+test_total{service_id="node\"1"} 1
+test_total{service_id="node2"} 2
+# HELP test_gauge A test gauge
+# TYPE test_gauge gauge
+test_gauge 2
+# HELP test_seconds A test histogram
+# TYPE test_seconds histogram
+test_seconds_bucket{le="1"} 1
+test_seconds_bucket{le="5"} 2
+test_seconds_bucket{le="+Inf"} 3
+test_seconds_sum 13.5
+test_seconds_count 3
+`
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestCountersIgnoreNegativeDeltas(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_total", "A test counter")
+	counter.Add(-5)
+
+	buffer := &bytes.Buffer{}
+	assert.NilError(t, registry.WriteText(buffer))
+	assert.Equal(t, "# HELP test_total A test counter\n# TYPE test_total counter\n", buffer.String())
+}
@@ -0,0 +1,242 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TIME_BETWEEN_METRIC_SCRAPES = 1 * time.Second
+
+	scrapeTimeout = 10 * time.Second
+)
+
+/*
+A single sample scraped from a Prometheus endpoint exposed by a service under test.
+ */
+type Sample struct {
+	// The name of the metric (for histograms and summaries, including the _bucket/_sum/_count suffix)
+	Name string
+
+	// The labels on the sample
+	Labels map[string]string
+
+	// The value of the sample
+	Value float64
+}
+
+/*
+A condition on a metric value, along with a human-readable description used in failure messages.
+ */
+type ValueMatcher struct {
+	// Description of the condition, e.g. ">= 4"
+	Description string
+
+	// Returns true if the given value satisfies the condition
+	Matches func(value float64) bool
+}
+
+// Matches values equal to the expected value
+func Equals(expected float64) ValueMatcher {
+	return ValueMatcher{
+		Description: fmt.Sprintf("== %v", expected),
+		Matches:     func(value float64) bool { return value == expected },
+	}
+}
+
+// Matches values greater than or equal to the given minimum
+func AtLeast(min float64) ValueMatcher {
+	return ValueMatcher{
+		Description: fmt.Sprintf(">= %v", min),
+		Matches:     func(value float64) bool { return value >= min },
+	}
+}
+
+// Matches values less than or equal to the given maximum
+func AtMost(max float64) ValueMatcher {
+	return ValueMatcher{
+		Description: fmt.Sprintf("<= %v", max),
+		Matches:     func(value float64) bool { return value <= max },
+	}
+}
+
+/*
+Scrapes the Prometheus endpoint at the given URL (e.g. "http://172.23.0.5:9090/metrics") and parses all the samples it exposes.
+ */
+func Scrape(endpointUrl string) ([]Sample, error) {
+	client := http.Client{Timeout: scrapeTimeout}
+	response, err := client.Get(endpointUrl)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred scraping metrics from %v", endpointUrl)
+	}
+	defer response.Body.Close()
+	if response.StatusCode / 100 != 2 {
+		return nil, stacktrace.NewError("Scraping metrics from %v returned non-2xx status code %v", endpointUrl, response.StatusCode)
+	}
+	samples, err := ParseText(response.Body)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing the metrics scraped from %v", endpointUrl)
+	}
+	return samples, nil
+}
+
+/*
+Parses samples in the Prometheus text exposition format, ignoring comment lines.
+ */
+func ParseText(reader io.Reader) ([]Sample, error) {
+	samples := []Sample{}
+	scanner := bufio.NewScanner(reader)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sample, err := parseSampleLine(line)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred parsing metrics line %v: %v", lineNum, line)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading metrics")
+	}
+	return samples, nil
+}
+
+/*
+Finds the sample with the given name whose labels include all the given labels (extra labels on the sample are allowed).
+ */
+func FindSample(samples []Sample, name string, labels map[string]string) (Sample, bool) {
+	for _, sample := range samples {
+		if sample.Name != name {
+			continue
+		}
+		allLabelsMatch := true
+		for key, value := range labels {
+			if sample.Labels[key] != value {
+				allLabelsMatch = false
+				break
+			}
+		}
+		if allLabelsMatch {
+			return sample, true
+		}
+	}
+	return Sample{}, false
+}
+
+/*
+Scrapes the given endpoint once and checks that the given metric exists and its value satisfies the matcher.
+ */
+func CheckMetric(endpointUrl string, name string, labels map[string]string, matcher ValueMatcher) error {
+	samples, err := Scrape(endpointUrl)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred scraping metrics to check metric %v", name)
+	}
+	sample, found := FindSample(samples, name, labels)
+	if !found {
+		return stacktrace.NewError("No metric %v with labels %v was exposed at %v", name, labels, endpointUrl)
+	}
+	if !matcher.Matches(sample.Value) {
+		return stacktrace.NewError("Expected metric %v with labels %v at %v to be %v, but was %v", name, labels, endpointUrl, matcher.Description, sample.Value)
+	}
+	return nil
+}
+
+/*
+Repeatedly scrapes the given endpoint until the given metric's value satisfies the matcher, or the timeout is hit.
+ */
+func WaitForMetric(endpointUrl string, name string, labels map[string]string, matcher ValueMatcher, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := CheckMetric(endpointUrl, name, labels, matcher)
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(TIME_BETWEEN_METRIC_SCRAPES).After(deadline) {
+			return stacktrace.Propagate(err, "Metric %v didn't become %v within %v", name, matcher.Description, timeout)
+		}
+		time.Sleep(TIME_BETWEEN_METRIC_SCRAPES)
+	}
+}
+
+// =============================== Private helpers =========================================
+func parseSampleLine(line string) (Sample, error) {
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd == -1 {
+		return Sample{}, stacktrace.NewError("Line has no value")
+	}
+	name := line[:nameEnd]
+	rest := line[nameEnd:]
+
+	labels := make(map[string]string)
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		labels, rest, err = parseLabels(rest[1:])
+		if err != nil {
+			return Sample{}, stacktrace.Propagate(err, "An error occurred parsing the labels")
+		}
+	}
+
+	// The value may optionally be followed by a timestamp, which we don't need
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return Sample{}, stacktrace.NewError("Line has no value")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return Sample{}, stacktrace.Propagate(err, "Couldn't parse value '%v' as a float", fields[0])
+	}
+	return Sample{Name: name, Labels: labels, Value: value}, nil
+}
+
+/*
+Parses labels from the text immediately after the opening brace, returning the labels and the text after the closing brace.
+ */
+func parseLabels(text string) (map[string]string, string, error) {
+	labels := make(map[string]string)
+	for {
+		text = strings.TrimLeft(text, " \t,")
+		if strings.HasPrefix(text, "}") {
+			return labels, text[1:], nil
+		}
+		equalsIdx := strings.Index(text, "=")
+		if equalsIdx == -1 {
+			return nil, "", stacktrace.NewError("Label is missing '='")
+		}
+		key := strings.TrimSpace(text[:equalsIdx])
+		text = strings.TrimLeft(text[equalsIdx+1:], " \t")
+		if !strings.HasPrefix(text, "\"") {
+			return nil, "", stacktrace.NewError("Value of label %v isn't quoted", key)
+		}
+
+		valueBuilder := &strings.Builder{}
+		i := 1
+		for ; i < len(text) && text[i] != '"'; i++ {
+			if text[i] == '\\' && i + 1 < len(text) {
+				i++
+				switch text[i] {
+				case 'n':
+					valueBuilder.WriteByte('\n')
+				default:
+					valueBuilder.WriteByte(text[i])
+				}
+				continue
+			}
+			valueBuilder.WriteByte(text[i])
+		}
+		if i >= len(text) {
+			return nil, "", stacktrace.NewError("Value of label %v is missing its closing quote", key)
+		}
+		labels[key] = valueBuilder.String()
+		text = text[i+1:]
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"bytes"
+	"gotest.tools/v3/assert"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseText(t *testing.T) {
+	text := `# HELP peers Number of connected peers
+# TYPE peers gauge
+peers{chain="X",node="a \"quoted\" name"} 4 1595000000000
+uptime_seconds 12.5
+weird_values{kind="inf"} +Inf
+`
+	samples, err := ParseText(strings.NewReader(text))
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(samples))
+
+	peers, found := FindSample(samples, "peers", map[string]string{"chain": "X"})
+	assert.Assert(t, found)
+	assert.Equal(t, 4.0, peers.Value)
+	assert.Equal(t, "a \"quoted\" name", peers.Labels["node"])
+
+	uptime, found := FindSample(samples, "uptime_seconds", nil)
+	assert.Assert(t, found)
+	assert.Equal(t, 12.5, uptime.Value)
+
+	inf, found := FindSample(samples, "weird_values", nil)
+	assert.Assert(t, found)
+	assert.Assert(t, math.IsInf(inf.Value, 1))
+
+	_, found = FindSample(samples, "peers", map[string]string{"chain": "P"})
+	assert.Assert(t, !found)
+}
+
+func TestParseMalformedText(t *testing.T) {
+	_, err := ParseText(strings.NewReader(`peers{chain="X} 4`))
+	assert.Assert(t, err != nil)
+
+	_, err = ParseText(strings.NewReader(`peers notanumber`))
+	assert.Assert(t, err != nil)
+}
+
+func TestParsingRoundTripsOwnExposition(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounterVec("test_total", "A test counter", "service_id").Add(3, "node1")
+
+	buffer := &bytes.Buffer{}
+	assert.NilError(t, registry.WriteText(buffer))
+	samples, err := ParseText(buffer)
+	assert.NilError(t, err)
+
+	sample, found := FindSample(samples, "test_total", map[string]string{"service_id": "node1"})
+	assert.Assert(t, found)
+	assert.Assert(t, AtLeast(3).Matches(sample.Value))
+	assert.Assert(t, !Equals(2).Matches(sample.Value))
+}
@@ -0,0 +1,82 @@
+package networks
+
+import (
+	"github.com/palantir/stacktrace"
+)
+
+/*
+Defines a configuration for adversarial services, i.e. services that deliberately misbehave (e.g. by running a Byzantine
+	build of the node's image, or a config that makes it equivocate), so that safety properties can be tested against
+	misbehaving peers. The configuration is derived from an honest one, and services launched with it are first-class
+	members of the network: they're added, depended on, & started like any other service. The network reports which
+	services are adversarial (see GetHonestServiceIds), so tests can check that the honest services still agree.
+
+Args:
+	configurationId: The ID by which the adversarial configuration will be referenced later
+	baseConfigurationId: The ID of the honest configuration to derive the adversarial one from, which must already exist
+	override: The changes that make the base configuration adversarial (e.g. a different Docker image or initializer core)
+ */
+func (builder *ServiceNetworkBuilder) AddAdversarialConfiguration(
+			configurationId ConfigurationID,
+			baseConfigurationId ConfigurationID,
+			override ConfigurationOverride) error {
+	if _, found := builder.configurations[configurationId]; found {
+		return stacktrace.NewError("Configuration ID %v is already registered", configurationId)
+	}
+	baseConfig, found := builder.configurations[baseConfigurationId]
+	if !found {
+		return stacktrace.NewError("Cannot derive adversarial configuration ID %v from configuration ID %v because it doesn't exist", configurationId, baseConfigurationId)
+	}
+	config, err := applyConfigurationOverride(baseConfig, override)
+	if err != nil {
+		return stacktrace.Propagate(err, "The override for adversarial configuration ID %v is invalid", configurationId)
+	}
+	config.isAdversarial = true
+	builder.getWritableConfigurations()[configurationId] = config
+	return nil
+}
+
+/*
+Gets whether the service with the given ID was launched with an adversarial configuration (see
+	ServiceNetworkBuilder.AddAdversarialConfiguration).
+ */
+func (network *ServiceNetwork) IsAdversarial(serviceId ServiceID) (bool, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return false, stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	return network.configurations[nodeInfo.configurationId].isAdversarial, nil
+}
+
+/*
+Gets a "set" of the IDs of the services that weren't launched with an adversarial configuration, i.e. the services whose
+	behaviour safety properties should hold for.
+ */
+func (network *ServiceNetwork) GetHonestServiceIds() map[ServiceID]bool {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	result := make(map[ServiceID]bool)
+	for serviceId, nodeInfo := range network.serviceNodes {
+		if !network.configurations[nodeInfo.configurationId].isAdversarial {
+			result[serviceId] = true
+		}
+	}
+	return result
+}
+
+/*
+Gets a "set" of the IDs of the services that were launched with an adversarial configuration.
+ */
+func (network *ServiceNetwork) GetAdversarialServiceIds() map[ServiceID]bool {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	result := make(map[ServiceID]bool)
+	for serviceId, nodeInfo := range network.serviceNodes {
+		if network.configurations[nodeInfo.configurationId].isAdversarial {
+			result[serviceId] = true
+		}
+	}
+	return result
+}
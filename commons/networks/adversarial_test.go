@@ -0,0 +1,50 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestAdversarialServices(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:honest", getTestInitializerCore(), getTestCheckerCore()))
+	assert.NilError(t, builder.AddAdversarialConfiguration("byzantine", testConfiguration, ConfigurationOverride{DockerImage: "node:byzantine"}))
+	err = builder.AddAdversarialConfiguration("other", "nonexistent", ConfigurationOverride{})
+	assert.ErrorContains(t, err, "doesn't exist")
+	err = builder.AddAdversarialConfiguration("byzantine", testConfiguration, ConfigurationOverride{})
+	assert.ErrorContains(t, err, "already registered")
+	network := builder.Build()
+
+	_, err = network.AddService(testConfiguration, "honest", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	// Adversarial services can depend on & be depended on by honest ones
+	_, err = network.AddService("byzantine", "attacker", map[ServiceID]bool{"honest": true})
+	assert.NilError(t, err)
+	_, err = network.AddService(testConfiguration, "victim", map[ServiceID]bool{"attacker": true})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, map[ServiceID]bool{"honest": true, "victim": true}, network.GetHonestServiceIds())
+	assert.DeepEqual(t, map[ServiceID]bool{"attacker": true}, network.GetAdversarialServiceIds())
+	isAdversarial, err := network.IsAdversarial("attacker")
+	assert.NilError(t, err)
+	assert.Assert(t, isAdversarial)
+
+	info, err := network.GetServiceInfo("attacker")
+	assert.NilError(t, err)
+	assert.Assert(t, info.IsAdversarial)
+	for _, containerId := range dockerManager.GetContainerIds() {
+		container, _ := dockerManager.GetContainer(containerId)
+		_, isLabelled := container.Options.Labels[docker.ADVERSARIAL_LABEL]
+		assert.Equal(t, container.Image == "node:byzantine", isLabelled)
+	}
+}
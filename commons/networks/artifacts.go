@@ -0,0 +1,117 @@
+package networks
+
+import (
+	"context"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// =============================== "enum" for artifact verbosity =========================================
+/*
+How much information about each service should be exported at the end of a test.
+ */
+type ArtifactVerbosity string
+const (
+	// Nothing is exported
+	NO_ARTIFACTS ArtifactVerbosity = "none"
+
+	// Each service's STDOUT & STDERR are exported
+	LOG_ARTIFACTS ArtifactVerbosity = "logs"
+
+	// Each service's STDOUT & STDERR are exported, along with the JSON from inspecting its container and an audit log of
+	//  every Docker API call made during the test
+	ALL_ARTIFACTS ArtifactVerbosity = "all"
+)
+
+const (
+	STDOUT_ARTIFACT_FILENAME  = "stdout.log"
+	STDERR_ARTIFACT_FILENAME  = "stderr.log"
+	INSPECT_ARTIFACT_FILENAME = "inspect.json"
+	DOCKER_AUDIT_LOG_FILENAME = "docker-audit.jsonl"
+
+	artifactDirPerms  = 0755
+	artifactFilePerms = 0644
+)
+
+/*
+Writes artifacts for every service in the network under the given directory, with one subdirectory per service ID. This
+	is intended to be called at the end of a test, before the network is torn down, so that the artifacts can be examined
+	whether the test passed or failed.
+
+Args:
+	dirpath: The directory to write artifacts to, which will be created if it doesn't exist
+	verbosity: How much information about each service to export
+
+Returns:
+	An error if any service's artifacts couldn't be written; the export is best-effort, so the other services' artifacts
+		will still be written
+ */
+func (network *ServiceNetwork) ExportServiceArtifacts(dirpath string, verbosity ArtifactVerbosity) error {
+	switch verbosity {
+	case NO_ARTIFACTS:
+		return nil
+	case LOG_ARTIFACTS, ALL_ARTIFACTS:
+	default:
+		return stacktrace.NewError("Unrecognized artifact verbosity '%v'", verbosity)
+	}
+
+	// Copied so that we don't hold the network's lock while the (potentially slow) export runs
+	network.mutex.Lock()
+	serviceNodesCopy := make(map[ServiceID]ServiceNode)
+	for serviceId, nodeInfo := range network.serviceNodes {
+		serviceNodesCopy[serviceId] = nodeInfo
+	}
+	network.mutex.Unlock()
+
+	var resultErr error = nil
+	for serviceId, nodeInfo := range serviceNodesCopy {
+		serviceDirpath := path.Join(dirpath, string(serviceId))
+		if err := network.exportArtifactsForContainer(serviceDirpath, network.getDockerManager(nodeInfo), nodeInfo.ContainerId, verbosity); err != nil {
+			network.serviceLog(serviceId).Errorf("An error occurred exporting the artifacts of service ID %v: %v", serviceId, err)
+			resultErr = stacktrace.Propagate(err, "An error occurred exporting the artifacts of service ID %v", serviceId)
+		}
+	}
+	return resultErr
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (network *ServiceNetwork) exportArtifactsForContainer(
+			dirpath string,
+			dockerManager docker.ContainerManager,
+			containerId string,
+			verbosity ArtifactVerbosity) error {
+	ctx := context.Background()
+
+	if err := os.MkdirAll(dirpath, artifactDirPerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating artifact directory %v", dirpath)
+	}
+
+	stdoutFp, err := os.OpenFile(path.Join(dirpath, STDOUT_ARTIFACT_FILENAME), os.O_WRONLY | os.O_CREATE | os.O_TRUNC, artifactFilePerms)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred opening the STDOUT artifact file")
+	}
+	defer stdoutFp.Close()
+	stderrFp, err := os.OpenFile(path.Join(dirpath, STDERR_ARTIFACT_FILENAME), os.O_WRONLY | os.O_CREATE | os.O_TRUNC, artifactFilePerms)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred opening the STDERR artifact file")
+	}
+	defer stderrFp.Close()
+	if err := dockerManager.WriteContainerLogs(ctx, containerId, stdoutFp, stderrFp); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the container's logs")
+	}
+
+	if verbosity != ALL_ARTIFACTS {
+		return nil
+	}
+	inspectJson, err := dockerManager.GetContainerInspectJson(ctx, containerId)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred inspecting the container")
+	}
+	if err := ioutil.WriteFile(path.Join(dirpath, INSPECT_ARTIFACT_FILENAME), inspectJson, artifactFilePerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the container's inspect JSON")
+	}
+	return nil
+}
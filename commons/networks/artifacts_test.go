@@ -0,0 +1,28 @@
+package networks
+
+import (
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestExportingNoArtifactsWritesNothing(t *testing.T) {
+	dirpath, err := ioutil.TempDir("", "artifacts")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dirpath)
+
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+	assert.NilError(t, network.ExportServiceArtifacts(dirpath, NO_ARTIFACTS))
+
+	files, err := ioutil.ReadDir(dirpath)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(files))
+}
+
+func TestExportingUnknownVerbosityFails(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+	assert.Assert(t, network.ExportServiceArtifacts("/nonexistent", "loud") != nil)
+}
@@ -0,0 +1,206 @@
+package networks
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// How many of each unavailable service's last log lines diagnostics include, unless told otherwise
+	DEFAULT_DIAGNOSTICS_LOG_LINES = 20
+
+	// The container state reported for services whose containers couldn't be inspected
+	unknownContainerState = "unknown"
+)
+
+/*
+What was going on with a single service when its network was diagnosed (see GetAvailabilityDiagnostics).
+ */
+type ServiceDiagnostics struct {
+	ServiceId ServiceID
+
+	// True if the service has been reported available by its availability checker (see GetHealth)
+	Available bool
+
+	// Docker's state for the service's container (e.g. "running" or "exited"), & its exit code if it has exited
+	ContainerState string
+	ExitCode       int
+
+	// Why the last availability check of the service failed, or empty if it hasn't failed since the service last became
+	//  available
+	LastProbeError string
+
+	// The IDs of the service's dependencies that aren't available, which may be what's holding the service up, sorted
+	BlockingDependencyIds []ServiceID
+
+	// The last lines of the service's logs (unavailable services only)
+	LogTail []string
+}
+
+/*
+A snapshot of which of a network's services are up & why the others aren't, for printing when a network doesn't become
+	available in time in place of a bare timeout error.
+ */
+type AvailabilityDiagnostics struct {
+	// The network's services, unavailable ones first, then by service ID
+	Services []ServiceDiagnostics
+}
+
+/*
+Gathers which of the network's services are available, and for each that isn't: the state of its container, why its last
+	availability check failed, which of its dependencies aren't available, & the tail of its logs. Gathering is
+	best-effort, so that diagnosing a broken network doesn't itself fail: details that can't be gathered are left out.
+
+Args:
+	numLogLines: How many of each unavailable service's last log lines to include (e.g. DEFAULT_DIAGNOSTICS_LOG_LINES)
+ */
+func (network *ServiceNetwork) GetAvailabilityDiagnostics(numLogLines int) *AvailabilityDiagnostics {
+	parentCtx := context.Background()
+
+	availableServiceIds := make(map[ServiceID]bool)
+	for _, health := range network.GetHealth().Services {
+		if health.Available {
+			availableServiceIds[health.ServiceId] = true
+		}
+	}
+
+	// The Docker calls are made without the network's lock, so they don't hold up the rest of the network
+	network.mutex.Lock()
+	serviceNodes := make(map[ServiceID]ServiceNode, len(network.serviceNodes))
+	for serviceId, nodeInfo := range network.serviceNodes {
+		serviceNodes[serviceId] = nodeInfo
+	}
+	network.mutex.Unlock()
+
+	result := &AvailabilityDiagnostics{Services: []ServiceDiagnostics{}}
+	for serviceId, nodeInfo := range serviceNodes {
+		diagnostics := ServiceDiagnostics{
+			ServiceId:             serviceId,
+			Available:             availableServiceIds[serviceId],
+			ContainerState:        unknownContainerState,
+			LastProbeError:        "",
+			BlockingDependencyIds: []ServiceID{},
+			LogTail:               []string{},
+		}
+		dockerManager := network.getDockerManager(nodeInfo)
+		if status, err := dockerManager.GetContainerStatus(parentCtx, nodeInfo.ContainerId); err == nil {
+			diagnostics.ContainerState = status.State
+			diagnostics.ExitCode = status.ExitCode
+		}
+		if diagnostics.Available {
+			result.Services = append(result.Services, diagnostics)
+			continue
+		}
+
+		if probeErr := network.getLastProbeError(serviceId); probeErr != nil {
+			diagnostics.LastProbeError = probeErr.Error()
+		}
+		for _, dependencyId := range nodeInfo.dependencyIds {
+			if !availableServiceIds[dependencyId] {
+				diagnostics.BlockingDependencyIds = append(diagnostics.BlockingDependencyIds, dependencyId)
+			}
+		}
+		sort.Slice(diagnostics.BlockingDependencyIds, func(i, j int) bool {
+			return diagnostics.BlockingDependencyIds[i] < diagnostics.BlockingDependencyIds[j]
+		})
+		if logs, err := dockerManager.GetContainerLogs(parentCtx, nodeInfo.ContainerId, false); err == nil {
+			diagnostics.LogTail = getLastLines(bufio.NewScanner(logs), numLogLines)
+			logs.Close()
+		}
+		result.Services = append(result.Services, diagnostics)
+	}
+	sort.Slice(result.Services, func(i, j int) bool {
+		if result.Services[i].Available != result.Services[j].Available {
+			return !result.Services[i].Available
+		}
+		return result.Services[i].ServiceId < result.Services[j].ServiceId
+	})
+	return result
+}
+
+/*
+Renders the diagnostics for printing, e.g.:
+
+	2 of 3 services are available
+	node-2: NOT AVAILABLE (container running)
+	    Last check failed with: Port 9650/tcp isn't accepting connections: connection refused
+	    Waiting on unavailable dependencies: bootstrap
+	    Last 2 log lines:
+	        | Starting node...
+	        | Waiting for bootstrap
+	bootstrap: available (container running)
+ */
+func (diagnostics AvailabilityDiagnostics) String() string {
+	builder := &strings.Builder{}
+	numAvailable := 0
+	for _, service := range diagnostics.Services {
+		if service.Available {
+			numAvailable++
+		}
+	}
+	fmt.Fprintf(builder, "%v of %v services are available\n", numAvailable, len(diagnostics.Services))
+	for _, service := range diagnostics.Services {
+		containerState := service.ContainerState
+		if containerState == "exited" {
+			containerState = fmt.Sprintf("%v with code %v", containerState, service.ExitCode)
+		}
+		if service.Available {
+			fmt.Fprintf(builder, "%v: available (container %v)\n", service.ServiceId, containerState)
+			continue
+		}
+		fmt.Fprintf(builder, "%v: NOT AVAILABLE (container %v)\n", service.ServiceId, containerState)
+		if service.LastProbeError != "" {
+			fmt.Fprintf(builder, "    Last check failed with: %v\n", service.LastProbeError)
+		}
+		if len(service.BlockingDependencyIds) > 0 {
+			blockingDependencyStrs := make([]string, 0, len(service.BlockingDependencyIds))
+			for _, dependencyId := range service.BlockingDependencyIds {
+				blockingDependencyStrs = append(blockingDependencyStrs, string(dependencyId))
+			}
+			fmt.Fprintf(builder, "    Waiting on unavailable dependencies: %v\n", strings.Join(blockingDependencyStrs, ", "))
+		}
+		if len(service.LogTail) > 0 {
+			fmt.Fprintf(builder, "    Last %v log lines:\n", len(service.LogTail))
+			for _, line := range service.LogTail {
+				fmt.Fprintf(builder, "        | %v\n", line)
+			}
+		}
+	}
+	return builder.String()
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Records why the last availability check of the given service failed (nil if it succeeded)
+func (network *ServiceNetwork) setLastProbeError(serviceId ServiceID, probeErr error) {
+	network.probeErrorsMutex.Lock()
+	defer network.probeErrorsMutex.Unlock()
+	if probeErr == nil {
+		delete(network.lastProbeErrors, serviceId)
+		return
+	}
+	network.lastProbeErrors[serviceId] = probeErr
+}
+
+func (network *ServiceNetwork) getLastProbeError(serviceId ServiceID) error {
+	network.probeErrorsMutex.Lock()
+	defer network.probeErrorsMutex.Unlock()
+	return network.lastProbeErrors[serviceId]
+}
+
+// Gets the last (up to) the given number of lines that the given scanner reads, ignoring any error reading them
+func getLastLines(scanner *bufio.Scanner, numLines int) []string {
+	result := []string{}
+	if numLines <= 0 {
+		return result
+	}
+	for scanner.Scan() {
+		result = append(result, scanner.Text())
+		if len(result) > numLines {
+			result = result[1:]
+		}
+	}
+	return result
+}
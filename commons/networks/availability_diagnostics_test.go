@@ -0,0 +1,72 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A core for services that never come up, which says why
+type refusingCheckerCore struct {}
+func (core refusingCheckerCore) IsServiceUp(toCheck services.Service, dependencies []services.Service) bool {
+	return false
+}
+func (core refusingCheckerCore) ProbeService(toCheck services.Service, dependencies []services.Service) error {
+	return stacktrace.NewError("connection refused")
+}
+func (core refusingCheckerCore) GetTimeout() time.Duration {
+	return 10 * time.Millisecond
+}
+
+func TestAvailabilityDiagnostics(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:latest", getTestInitializerCore(), getTestCheckerCore()))
+	assert.NilError(t, builder.AddConfiguration("refusing", "node:latest", getTestInitializerCore(), refusingCheckerCore{}))
+	network := builder.Build()
+
+	availabilityCheckers := map[ServiceID]*services.ServiceAvailabilityChecker{}
+	availabilityCheckers["healthy"], err = network.AddService(testConfiguration, "healthy", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	availabilityCheckers["bootstrap"], err = network.AddService("refusing", "bootstrap", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	availabilityCheckers["node"], err = network.AddService(testConfiguration, "node", map[ServiceID]bool{"bootstrap": true, "healthy": true})
+	assert.NilError(t, err)
+	assert.NilError(t, availabilityCheckers["healthy"].WaitForStartup())
+	assert.Assert(t, availabilityCheckers["bootstrap"].WaitForStartup() != nil)
+
+	bootstrapNode, err := network.GetService("bootstrap")
+	assert.NilError(t, err)
+	assert.NilError(t, dockerManager.SetContainerOutput(bootstrapNode.ContainerId, "line 1\nline 2\nline 3\n", ""))
+
+	diagnostics := network.GetAvailabilityDiagnostics(2)
+	assert.Equal(t, 3, len(diagnostics.Services))
+	bootstrap := diagnostics.Services[0]
+	assert.Equal(t, ServiceID("bootstrap"), bootstrap.ServiceId)
+	assert.Assert(t, !bootstrap.Available)
+	assert.Equal(t, docker.FAKE_RUNNING_STATE, bootstrap.ContainerState)
+	assert.Assert(t, strings.Contains(bootstrap.LastProbeError, "connection refused"))
+	assert.DeepEqual(t, []string{"line 2", "line 3"}, bootstrap.LogTail)
+	node := diagnostics.Services[1]
+	assert.Equal(t, ServiceID("node"), node.ServiceId)
+	assert.DeepEqual(t, []ServiceID{"bootstrap"}, node.BlockingDependencyIds)
+	assert.Assert(t, diagnostics.Services[2].Available)
+
+	rendered := diagnostics.String()
+	assert.Assert(t, strings.HasPrefix(rendered, "1 of 3 services are available\n"))
+	assert.Assert(t, strings.Contains(rendered, "bootstrap: NOT AVAILABLE (container running)"))
+	assert.Assert(t, strings.Contains(rendered, "Waiting on unavailable dependencies: bootstrap"))
+	assert.Assert(t, strings.Contains(rendered, "        | line 3\n"))
+}
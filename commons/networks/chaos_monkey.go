@@ -0,0 +1,266 @@
+package networks
+
+import (
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// =============================== "enum" for chaos action types =========================================
+type ChaosActionType string
+const (
+	CHAOS_KILL ChaosActionType = "KILL"
+	CHAOS_HEAL ChaosActionType = "HEAL"
+)
+
+/*
+The policy dictating how a ChaosMonkey will wreak havoc on a test network.
+ */
+type ChaosPolicy struct {
+	// How often a random service will be killed
+	KillInterval time.Duration
+
+	// How long a killed service will stay down before it's brought back
+	HealAfter time.Duration
+
+	// A "set" of services that should never be killed (e.g. bootstrap nodes)
+	ProtectedServices map[ServiceID]bool
+}
+
+/*
+A record of a single action that the chaos monkey took against the network, kept for debugging.
+ */
+type ChaosAction struct {
+	// When the action was taken
+	Timestamp time.Time
+
+	// The type of action that was taken
+	ActionType ChaosActionType
+
+	// The service that the action was taken against
+	ServiceId ServiceID
+
+	// Non-nil if an error occurred taking the action
+	Err error
+}
+
+func (action ChaosAction) String() string {
+	result := fmt.Sprintf("%v %v %v", action.Timestamp.Format(time.RFC3339Nano), action.ActionType, action.ServiceId)
+	if action.Err != nil {
+		result += fmt.Sprintf(" (failed: %v)", action.Err)
+	}
+	return result
+}
+
+/*
+Applies chaos actions against a test network on a schedule: every KillInterval, a random unprotected service is killed
+	and, HealAfter later, that service is brought back. Every action is recorded in an action log.
+
+NOTE: The monkey acts against the network from a separate goroutine through the network's thread-safe methods, so services
+	can be added to or removed from the network while the monkey is running; a service removed between being picked and
+	being killed just shows up as a failed action in the log.
+ */
+type ChaosMonkey struct {
+	// The network to apply chaos actions against
+	network *ServiceNetwork
+
+	// The policy dictating what chaos actions to take
+	policy ChaosPolicy
+
+	// Source of randomness for choosing which service to kill
+	random *rand.Rand
+
+	// Mutex guarding the action log
+	mutex *sync.Mutex
+
+	// The log of all actions the monkey has taken so far
+	actionLog []ChaosAction
+
+	// Mutex guarding starting & stopping the monkey
+	lifecycleMutex *sync.Mutex
+
+	// Closed to tell the monkey goroutine to stop
+	stopChan chan struct{}
+
+	// Whether stopChan has been closed, so that the monkey can be stopped more than once
+	isStopped bool
+
+	// Closed by the monkey goroutine once it has fully stopped
+	doneChan chan struct{}
+}
+
+/*
+Creates a new chaos monkey that will act against the given network according to the given policy.
+
+Args:
+	network: The network to apply chaos actions against
+	policy: The policy dictating what actions to take, and how often
+	seed: The seed for choosing which services to kill, so that a run can be reproduced
+ */
+func NewChaosMonkey(network *ServiceNetwork, policy ChaosPolicy, seed int64) *ChaosMonkey {
+	// Defensive copy
+	protectedServicesCopy := make(map[ServiceID]bool)
+	for serviceId, isProtected := range policy.ProtectedServices {
+		protectedServicesCopy[serviceId] = isProtected
+	}
+	policy.ProtectedServices = protectedServicesCopy
+
+	return &ChaosMonkey{
+		network:        network,
+		policy:         policy,
+		random:         rand.New(rand.NewSource(seed)),
+		mutex:          &sync.Mutex{},
+		actionLog:      []ChaosAction{},
+		lifecycleMutex: &sync.Mutex{},
+		stopChan:       nil,
+		isStopped:      false,
+		doneChan:       nil,
+	}
+}
+
+/*
+Starts applying chaos actions against the network in a background goroutine. A monkey can only be started once.
+ */
+func (monkey *ChaosMonkey) Start() error {
+	if monkey.policy.KillInterval <= 0 {
+		return stacktrace.NewError("Chaos policy kill interval must be positive, but was %v", monkey.policy.KillInterval)
+	}
+
+	monkey.lifecycleMutex.Lock()
+	defer monkey.lifecycleMutex.Unlock()
+	if monkey.stopChan != nil {
+		return stacktrace.NewError("Chaos monkey has already been started")
+	}
+	monkey.stopChan = make(chan struct{})
+	monkey.doneChan = make(chan struct{})
+	go monkey.run()
+	return nil
+}
+
+/*
+Stops applying chaos actions, heals any services that are still killed, and blocks until this is done. Stopping a monkey
+	that's already been stopped does nothing.
+ */
+func (monkey *ChaosMonkey) Stop() {
+	monkey.lifecycleMutex.Lock()
+	defer monkey.lifecycleMutex.Unlock()
+	if monkey.stopChan == nil {
+		return
+	}
+	if !monkey.isStopped {
+		close(monkey.stopChan)
+		monkey.isStopped = true
+	}
+	<-monkey.doneChan
+}
+
+/*
+Gets a copy of all actions that the monkey has taken so far, in the order they were taken.
+ */
+func (monkey *ChaosMonkey) GetActionLog() []ChaosAction {
+	monkey.mutex.Lock()
+	defer monkey.mutex.Unlock()
+
+	result := make([]ChaosAction, len(monkey.actionLog))
+	copy(result, monkey.actionLog)
+	return result
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (monkey *ChaosMonkey) run() {
+	defer close(monkey.doneChan)
+
+	// Mapping of killed service -> time it should be healed at
+	killedServices := make(map[ServiceID]time.Time)
+	nextKillTime := time.Now().Add(monkey.policy.KillInterval)
+	for {
+		nextEventTime := nextKillTime
+		for _, healTime := range killedServices {
+			if healTime.Before(nextEventTime) {
+				nextEventTime = healTime
+			}
+		}
+
+		select {
+		case <-monkey.stopChan:
+			for serviceId, _ := range killedServices {
+				monkey.heal(serviceId)
+			}
+			return
+		case <-time.After(time.Until(nextEventTime)):
+		}
+
+		now := time.Now()
+		for serviceId, healTime := range killedServices {
+			if !healTime.After(now) {
+				monkey.heal(serviceId)
+				delete(killedServices, serviceId)
+			}
+		}
+		if !nextKillTime.After(now) {
+			if serviceId, found := monkey.pickServiceToKill(killedServices); found {
+				if monkey.kill(serviceId) {
+					killedServices[serviceId] = now.Add(monkey.policy.HealAfter)
+				}
+			}
+			nextKillTime = now.Add(monkey.policy.KillInterval)
+		}
+	}
+}
+
+/*
+Picks a random service that's neither protected nor already killed, returning false if no such service exists.
+ */
+func (monkey *ChaosMonkey) pickServiceToKill(killedServices map[ServiceID]time.Time) (ServiceID, bool) {
+	candidates := []ServiceID{}
+	for serviceId, _ := range monkey.network.GetServiceIds() {
+		if monkey.policy.ProtectedServices[serviceId] {
+			continue
+		}
+		if _, isKilled := killedServices[serviceId]; isKilled {
+			continue
+		}
+		candidates = append(candidates, serviceId)
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	// Map iteration order is random, so we sort to make the choice reproducible given the seed
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i] < candidates[j]
+	})
+	return candidates[monkey.random.Intn(len(candidates))], true
+}
+
+func (monkey *ChaosMonkey) kill(serviceId ServiceID) bool {
+	err := monkey.network.KillService(serviceId)
+	monkey.recordAction(CHAOS_KILL, serviceId, err)
+	return err == nil
+}
+
+func (monkey *ChaosMonkey) heal(serviceId ServiceID) {
+	err := monkey.network.ReviveService(serviceId)
+	monkey.recordAction(CHAOS_HEAL, serviceId, err)
+}
+
+func (monkey *ChaosMonkey) recordAction(actionType ChaosActionType, serviceId ServiceID, err error) {
+	action := ChaosAction{
+		Timestamp:  time.Now(),
+		ActionType: actionType,
+		ServiceId:  serviceId,
+		Err:        err,
+	}
+	if err != nil {
+		monkey.network.serviceLog(serviceId).Warnf("Chaos monkey action failed: %v", action)
+	} else {
+		monkey.network.serviceLog(serviceId).Infof("Chaos monkey action: %v", action)
+	}
+
+	monkey.mutex.Lock()
+	defer monkey.mutex.Unlock()
+	monkey.actionLog = append(monkey.actionLog, action)
+}
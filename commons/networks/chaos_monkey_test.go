@@ -0,0 +1,109 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestChaosMonkeyRespectsProtectedAndKilledServices(t *testing.T) {
+	network := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "").Build()
+	network.serviceNodes["bootstrap"] = ServiceNode{}
+	network.serviceNodes["killed"] = ServiceNode{}
+	network.serviceNodes["victim"] = ServiceNode{}
+
+	policy := ChaosPolicy{
+		KillInterval:      time.Second,
+		HealAfter:         time.Second,
+		ProtectedServices: map[ServiceID]bool{"bootstrap": true},
+	}
+	monkey := NewChaosMonkey(network, policy, 0)
+	killedServices := map[ServiceID]time.Time{"killed": time.Now()}
+	for i := 0; i < 10; i++ {
+		serviceId, found := monkey.pickServiceToKill(killedServices)
+		assert.Assert(t, found)
+		assert.Equal(t, ServiceID("victim"), serviceId)
+	}
+
+	killedServices["victim"] = time.Now()
+	_, found := monkey.pickServiceToKill(killedServices)
+	assert.Assert(t, !found)
+}
+
+func TestChaosMonkeyRejectsNonPositiveInterval(t *testing.T) {
+	network := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "").Build()
+	monkey := NewChaosMonkey(network, ChaosPolicy{}, 0)
+	assert.ErrorContains(t, monkey.Start(), "kill interval must be positive")
+}
+
+func TestChaosMonkeyKillsAndHealsServicesOnSchedule(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+	for _, serviceId := range []ServiceID{"bootstrap", "victim"} {
+		_, err = network.AddService(testConfiguration, serviceId, map[ServiceID]bool{})
+		assert.NilError(t, err)
+	}
+
+	policy := ChaosPolicy{
+		KillInterval:      10 * time.Millisecond,
+		HealAfter:         5 * time.Millisecond,
+		ProtectedServices: map[ServiceID]bool{"bootstrap": true},
+	}
+	monkey := NewChaosMonkey(network, policy, 0)
+	assert.NilError(t, monkey.Start())
+	deadline := time.Now().Add(5 * time.Second)
+	for countActions(monkey.GetActionLog(), CHAOS_HEAL) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	monkey.Stop()
+
+	actionLog := monkey.GetActionLog()
+	assert.Assert(t, countActions(actionLog, CHAOS_KILL) >= 2, "Expected the monkey to kill repeatedly, but its log was %v", actionLog)
+	for idx, action := range actionLog {
+		assert.NilError(t, action.Err)
+		assert.Equal(t, ServiceID("victim"), action.ServiceId)
+		// Kills & heals alternate, since the only unprotected service can't be killed again until it's healed
+		if idx % 2 == 0 {
+			assert.Equal(t, CHAOS_KILL, action.ActionType)
+		} else {
+			assert.Equal(t, CHAOS_HEAL, action.ActionType)
+		}
+	}
+	// Stopping heals any service that's still killed
+	assert.Equal(t, CHAOS_HEAL, actionLog[len(actionLog) - 1].ActionType)
+	for _, containerId := range dockerManager.GetContainerIds() {
+		container, _ := dockerManager.GetContainer(containerId)
+		assert.Equal(t, docker.FAKE_RUNNING_STATE, container.State)
+	}
+}
+
+func TestChaosMonkeyCanBeStoppedTwice(t *testing.T) {
+	network := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "").Build()
+	monkey := NewChaosMonkey(network, ChaosPolicy{KillInterval: time.Hour}, 0)
+	assert.NilError(t, monkey.Start())
+	monkey.Stop()
+	monkey.Stop()
+	assert.Equal(t, 0, len(monkey.GetActionLog()))
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func countActions(actionLog []ChaosAction, actionType ChaosActionType) int {
+	result := 0
+	for _, action := range actionLog {
+		if action.ActionType == actionType {
+			result++
+		}
+	}
+	return result
+}
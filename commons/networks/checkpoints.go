@@ -0,0 +1,185 @@
+package networks
+
+import (
+	"encoding/json"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+)
+
+const (
+	// The file in a checkpoint directory describing the checkpointed network
+	CHECKPOINT_MANIFEST_FILENAME = "checkpoint.json"
+
+	// The extension of the files in a checkpoint directory holding each service's data
+	CHECKPOINT_DATA_FILE_EXTENSION = ".tar"
+
+	checkpointDirPerms  = 0755
+	checkpointFilePerms = 0644
+)
+
+/*
+The description of a checkpointed network, stored alongside the archives of its services' data.
+ */
+type CheckpointManifest struct {
+	// The topology of the network at the time of the checkpoint
+	State NetworkState
+
+	// Mapping of service ID -> the directory in the service's container whose data was archived, for the services
+	//  whose data is part of the checkpoint
+	DataDirpaths map[ServiceID]string
+}
+
+/*
+Exports a checkpoint of the network - its topology plus the data of its services - to the given directory, so that a
+	colleague (or CI) can reconstruct the exact environment that produced a failure with ServiceNetworkBuilder.ImportState.
+
+Each service is paused while its data is archived, so the archive is consistent.
+
+Args:
+	checkpointDirpath: The directory to write the checkpoint to, which will be created if it doesn't exist
+	dataDirpaths: Mapping of service ID -> the absolute path of the directory in the service's container holding its data.
+		Services without an entry are checkpointed without data.
+ */
+func (network *ServiceNetwork) ExportState(checkpointDirpath string, dataDirpaths map[ServiceID]string) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	if err := os.MkdirAll(checkpointDirpath, checkpointDirPerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating checkpoint directory %v", checkpointDirpath)
+	}
+
+	// Defensive copy
+	dataDirpathsCopy := make(map[ServiceID]string)
+	for serviceId, containerDirpath := range dataDirpaths {
+		if _, found := network.serviceNodes[serviceId]; !found {
+			return stacktrace.NewError("Can't checkpoint the data of service %v because no service with that ID exists", serviceId)
+		}
+		dataDirpathsCopy[serviceId] = containerDirpath
+	}
+
+	for serviceId, containerDirpath := range dataDirpathsCopy {
+		dataFilepath := getCheckpointDataFilepath(checkpointDirpath, serviceId)
+		if err := network.archiveServiceData(serviceId, containerDirpath, dataFilepath); err != nil {
+			return stacktrace.Propagate(err, "An error occurred checkpointing the data of service %v", serviceId)
+		}
+	}
+
+	manifest := CheckpointManifest{
+		State:        network.getState(),
+		DataDirpaths: dataDirpathsCopy,
+	}
+	manifestJson, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred serializing the checkpoint manifest")
+	}
+	manifestFilepath := path.Join(checkpointDirpath, CHECKPOINT_MANIFEST_FILENAME)
+	if err := ioutil.WriteFile(manifestFilepath, manifestJson, checkpointFilePerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the checkpoint manifest to %v", manifestFilepath)
+	}
+	return nil
+}
+
+/*
+Reconstructs a network from a checkpoint written with ServiceNetwork.ExportState: every service is recreated from the
+	same configuration, with the same service ID and dependencies, and with its checkpointed data restored before it
+	starts. Services are created in dependency order.
+
+NOTE: Services get fresh containers and IPs, which may differ from those of the checkpointed network.
+
+Args:
+	checkpointDirpath: The directory the checkpoint was exported to
+
+Returns:
+	The reconstructed network
+	A mapping of service ID -> availability checker, for checking when each recreated service is available
+ */
+func (builder ServiceNetworkBuilder) ImportState(checkpointDirpath string) (*ServiceNetwork, map[ServiceID]*services.ServiceAvailabilityChecker, error) {
+	manifestFilepath := path.Join(checkpointDirpath, CHECKPOINT_MANIFEST_FILENAME)
+	manifestJson, err := ioutil.ReadFile(manifestFilepath)
+	if err != nil {
+		return nil, nil, stacktrace.Propagate(err, "An error occurred reading checkpoint manifest %v", manifestFilepath)
+	}
+	var manifest CheckpointManifest
+	if err := json.Unmarshal(manifestJson, &manifest); err != nil {
+		return nil, nil, stacktrace.Propagate(err, "An error occurred parsing checkpoint manifest %v", manifestFilepath)
+	}
+
+	creationOrder, err := getDependencyOrder(manifest.State.Services)
+	if err != nil {
+		return nil, nil, stacktrace.Propagate(err, "An error occurred ordering the checkpointed services")
+	}
+
+	network := builder.Build()
+	availabilityCheckers := make(map[ServiceID]*services.ServiceAvailabilityChecker)
+	for _, serviceId := range creationOrder {
+		serviceState := manifest.State.Services[serviceId]
+		dependencies := make(map[ServiceID]bool)
+		for _, dependencyId := range serviceState.DependencyIds {
+			dependencies[dependencyId] = true
+		}
+
+		archives := []docker.ContainerArchive{}
+		if containerDirpath, found := manifest.DataDirpaths[serviceId]; found {
+			archives = append(archives, docker.ContainerArchive{
+				ArchiveFilepath:  getCheckpointDataFilepath(checkpointDirpath, serviceId),
+				ContainerDirpath: containerDirpath,
+			})
+		}
+
+		availabilityChecker, err := network.addService(serviceState.ConfigurationId, serviceId, nil, dependencies, archives)
+		if err != nil {
+			return nil, nil, stacktrace.Propagate(err, "An error occurred recreating checkpointed service %v", serviceId)
+		}
+		availabilityCheckers[serviceId] = availabilityChecker
+	}
+	return network, availabilityCheckers, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func getCheckpointDataFilepath(checkpointDirpath string, serviceId ServiceID) string {
+	return path.Join(checkpointDirpath, string(serviceId) + CHECKPOINT_DATA_FILE_EXTENSION)
+}
+
+/*
+Orders the given services so that every service comes after its dependencies, breaking ties by service ID so that the
+	order is deterministic.
+ */
+func getDependencyOrder(servicesState map[ServiceID]PersistedServiceState) ([]ServiceID, error) {
+	remainingServiceIds := []ServiceID{}
+	for serviceId, _ := range servicesState {
+		remainingServiceIds = append(remainingServiceIds, serviceId)
+	}
+	sort.Slice(remainingServiceIds, func(i, j int) bool {
+		return remainingServiceIds[i] < remainingServiceIds[j]
+	})
+
+	result := []ServiceID{}
+	ordered := make(map[ServiceID]bool)
+	for len(remainingServiceIds) > 0 {
+		stillRemainingServiceIds := []ServiceID{}
+		for _, serviceId := range remainingServiceIds {
+			allDependenciesOrdered := true
+			for _, dependencyId := range servicesState[serviceId].DependencyIds {
+				if _, found := servicesState[dependencyId]; !found {
+					return nil, stacktrace.NewError("Service %v depends on service %v, which doesn't exist", serviceId, dependencyId)
+				}
+				allDependenciesOrdered = allDependenciesOrdered && ordered[dependencyId]
+			}
+			if allDependenciesOrdered {
+				result = append(result, serviceId)
+				ordered[serviceId] = true
+			} else {
+				stillRemainingServiceIds = append(stillRemainingServiceIds, serviceId)
+			}
+		}
+		if len(stillRemainingServiceIds) == len(remainingServiceIds) {
+			return nil, stacktrace.NewError("Services %v have a dependency cycle", stillRemainingServiceIds)
+		}
+		remainingServiceIds = stillRemainingServiceIds
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package networks
+
+import (
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDependencyOrder(t *testing.T) {
+	servicesState := map[ServiceID]PersistedServiceState{
+		"a-node":    {DependencyIds: []ServiceID{"bootstrap", "c-node"}},
+		"bootstrap": {DependencyIds: []ServiceID{}},
+		"c-node":    {DependencyIds: []ServiceID{"bootstrap"}},
+		"b-node":    {DependencyIds: []ServiceID{"bootstrap"}},
+	}
+	order, err := getDependencyOrder(servicesState)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []ServiceID{"bootstrap", "c-node", "a-node", "b-node"}, order)
+}
+
+func TestDependencyOrderWithCycle(t *testing.T) {
+	servicesState := map[ServiceID]PersistedServiceState{
+		"a": {DependencyIds: []ServiceID{"b"}},
+		"b": {DependencyIds: []ServiceID{"a"}},
+	}
+	_, err := getDependencyOrder(servicesState)
+	assert.Assert(t, err != nil)
+}
+
+func TestDependencyOrderWithMissingDependency(t *testing.T) {
+	servicesState := map[ServiceID]PersistedServiceState{
+		"a": {DependencyIds: []ServiceID{"missing"}},
+	}
+	_, err := getDependencyOrder(servicesState)
+	assert.Assert(t, err != nil)
+}
+
+func TestExportingDataOfNonexistentService(t *testing.T) {
+	checkpointDirpath, err := ioutil.TempDir("", "checkpoint")
+	assert.NilError(t, err)
+	defer os.RemoveAll(checkpointDirpath)
+
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	err = builder.Build().ExportState(checkpointDirpath, map[ServiceID]string{testServiceName: "/data"})
+	assert.Assert(t, err != nil)
+}
+
+func TestExportAndImportEmptyNetwork(t *testing.T) {
+	checkpointDirpath, err := ioutil.TempDir("", "checkpoint")
+	assert.NilError(t, err)
+	defer os.RemoveAll(checkpointDirpath)
+
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	assert.NilError(t, builder.Build().ExportState(checkpointDirpath, map[ServiceID]string{}))
+	network, availabilityCheckers, err := builder.ImportState(checkpointDirpath)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, network.GetSize())
+	assert.Equal(t, 0, len(availabilityCheckers))
+}
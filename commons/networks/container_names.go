@@ -0,0 +1,54 @@
+package networks
+
+import (
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+)
+
+const (
+	// Separates the test volume's name from the service ID in the names given by DefaultContainerNamer
+	containerNameSeparator = "--"
+)
+
+/*
+Gets the name of the container of a service, so that the containers of a running test can be told apart in `docker ps`.
+	The name is sanitized (see docker.SanitizeContainerName), and if the service ID has had a container before (e.g. the
+	service was removed & re-added), a "-<number>" suffix is added to keep the name unique.
+
+Args:
+	testVolume: The name of the test's Docker volume, which is made of the test suite execution's UUID & the test's name
+	serviceId: The ID of the service
+ */
+type ContainerNamer func(testVolume string, serviceId ServiceID) string
+
+/*
+The namer that service networks use unless told otherwise, which names containers "<execution UUID>-<test name>--<service ID>".
+ */
+func DefaultContainerNamer(testVolume string, serviceId ServiceID) string {
+	return testVolume + containerNameSeparator + string(serviceId)
+}
+
+/*
+Makes the built network name its services' containers with the given namer, in place of DefaultContainerNamer; nil leaves
+	the containers to be named by Docker.
+ */
+func (builder *ServiceNetworkBuilder) UseContainerNamer(namer ContainerNamer) {
+	builder.containerNamer = namer
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets the name that the next container of the given service should have (empty for a Docker-generated one), counting it as
+	used. Must be called with the network's lock held.
+ */
+func (network *ServiceNetwork) getNextContainerName(serviceId ServiceID) string {
+	if network.containerNamer == nil {
+		return ""
+	}
+	network.containerNameUses[serviceId]++
+	name := docker.SanitizeContainerName(network.containerNamer(network.testVolume, serviceId))
+	if uses := network.containerNameUses[serviceId]; uses > 1 {
+		name = fmt.Sprintf("%v-%v", name, uses)
+	}
+	return name
+}
@@ -0,0 +1,58 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestContainerNames(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "1234-myTest", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:latest", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+
+	_, err = network.AddService(testConfiguration, "node 1", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	assert.NilError(t, network.RemoveService("node 1", time.Second))
+	// The removed service's stopped container keeps its name, so the re-added service's container needs another
+	_, err = network.AddService(testConfiguration, "node 1", map[ServiceID]bool{})
+	assert.NilError(t, err)
+
+	names := map[string]bool{}
+	for _, containerId := range dockerManager.GetContainerIds() {
+		container, _ := dockerManager.GetContainer(containerId)
+		names[container.Options.Name] = true
+	}
+	assert.DeepEqual(t, map[string]bool{"1234-myTest--node-1": true, "1234-myTest--node-1-2": true}, names)
+}
+
+func TestDockerGeneratedContainerNames(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:latest", getTestInitializerCore(), getTestCheckerCore()))
+	builder.UseContainerNamer(nil)
+	network := builder.Build()
+
+	_, err = network.AddService(testConfiguration, testServiceName, map[ServiceID]bool{})
+	assert.NilError(t, err)
+	containerIds := dockerManager.GetContainerIds()
+	assert.Equal(t, 1, len(containerIds))
+	container, _ := dockerManager.GetContainer(containerIds[0])
+	assert.Equal(t, "", container.Options.Name)
+}
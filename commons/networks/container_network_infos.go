@@ -0,0 +1,90 @@
+package networks
+
+import (
+	"context"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"sync"
+)
+
+const (
+	// The most containers that are inspected at once when inspecting many services, so that large networks don't flood
+	//  the Docker engine with requests
+	maxParallelContainerInspects = 16
+)
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets the host ports & addresses of the given node's container, inspecting the container only if it hasn't been inspected
+	since it was last started. Must be called with the network's lock held.
+ */
+func (network *ServiceNetwork) getContainerNetworkInfo(nodeInfo ServiceNode) (docker.ContainerNetworkInfo, error) {
+	if containerNetworkInfo, found := network.containerNetworkInfos[nodeInfo.ContainerId]; found {
+		return containerNetworkInfo, nil
+	}
+	containerNetworkInfo, err := network.getDockerManager(nodeInfo).GetContainerNetworkInfo(context.Background(), nodeInfo.ContainerId)
+	if err != nil {
+		return docker.ContainerNetworkInfo{}, stacktrace.Propagate(err, "An error occurred inspecting container %v", nodeInfo.ContainerId)
+	}
+	network.containerNetworkInfos[nodeInfo.ContainerId] = containerNetworkInfo
+	return containerNetworkInfo, nil
+}
+
+/*
+Inspects the containers of the given services that haven't been inspected since they were last started, in parallel, so
+	that later calls to getContainerNetworkInfo for them don't each wait on a round-trip to the Docker engine. Services that
+	run on the host's network or don't publish their ports are skipped, because nothing needs to inspect them. Must be
+	called with the network's lock held.
+ */
+func (network *ServiceNetwork) prefetchContainerNetworkInfos(serviceIds []ServiceID) error {
+	nodesToInspect := []ServiceNode{}
+	for _, serviceId := range serviceIds {
+		nodeInfo, found := network.serviceNodes[serviceId]
+		if !found {
+			return stacktrace.NewError("No service with ID %v found", serviceId)
+		}
+		config := network.configurations[nodeInfo.configurationId]
+		if network.isHostNetworked(nodeInfo) || len(config.containerOptions.GetPublishedPorts(config.initializerCore.GetUsedPorts())) == 0 {
+			continue
+		}
+		if _, found := network.containerNetworkInfos[nodeInfo.ContainerId]; found {
+			continue
+		}
+		nodesToInspect = append(nodesToInspect, nodeInfo)
+	}
+
+	resultsMutex := &sync.Mutex{}
+	var resultErr error = nil
+	inspectSlots := make(chan bool, maxParallelContainerInspects)
+	waitGroup := &sync.WaitGroup{}
+	for _, nodeInfo := range nodesToInspect {
+		waitGroup.Add(1)
+		go func(nodeInfo ServiceNode) {
+			defer waitGroup.Done()
+			inspectSlots <- true
+			containerNetworkInfo, err := network.getDockerManager(nodeInfo).GetContainerNetworkInfo(context.Background(), nodeInfo.ContainerId)
+			<-inspectSlots
+
+			resultsMutex.Lock()
+			defer resultsMutex.Unlock()
+			if err != nil {
+				if resultErr == nil {
+					resultErr = stacktrace.Propagate(err, "An error occurred inspecting container %v", nodeInfo.ContainerId)
+				}
+				return
+			}
+			// The network's lock is held by our caller, which is waiting on us, so only the results mutex guards the cache
+			network.containerNetworkInfos[nodeInfo.ContainerId] = containerNetworkInfo
+		}(nodeInfo)
+	}
+	waitGroup.Wait()
+	return resultErr
+}
+
+/*
+Drops the cached inspection of the given container, which must be done whenever the container is started or stopped
+	because Docker may give it different host ports when it next starts. Must be called with the network's lock held.
+ */
+func (network *ServiceNetwork) forgetContainerNetworkInfo(containerId string) {
+	delete(network.containerNetworkInfos, containerId)
+}
@@ -0,0 +1,64 @@
+package networks
+
+import (
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestContainerInspectionsAreCachedUntilRestart(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	dockerManager := docker.NewFakeDockerManager()
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfigurationWithOptions(
+		testConfiguration,
+		"test",
+		testPortsInitializerCore{usedPorts: map[nat.Port]bool{"8080/tcp": true, "9090/tcp": true}},
+		getTestCheckerCore(),
+		docker.ContainerOptions{PublishPorts: true}))
+	network := builder.Build()
+
+	serviceIds := []ServiceID{"service0", "service1", "service2"}
+	for _, serviceId := range serviceIds {
+		_, err := network.AddService(testConfiguration, serviceId, map[ServiceID]bool{})
+		assert.NilError(t, err)
+	}
+
+	// One inspect per container, no matter how many ports or how many times they're asked for
+	_, err = network.GetServiceInfos()
+	assert.NilError(t, err)
+	for _, serviceId := range serviceIds {
+		_, err := network.GetServiceHostPort(serviceId, "8080/tcp")
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, len(serviceIds), countFakeCalls(dockerManager, "GetContainerNetworkInfo"))
+
+	// Restarting a container may change its host ports, so it must be inspected again
+	assert.NilError(t, network.KillService("service0"))
+	assert.NilError(t, network.ReviveService("service0"))
+	_, err = network.GetServiceHostPort("service0", "8080/tcp")
+	assert.NilError(t, err)
+	assert.Equal(t, len(serviceIds) + 1, countFakeCalls(dockerManager, "GetContainerNetworkInfo"))
+
+	_, err = network.GetServiceHostPort("service0", "1234/tcp")
+	assert.ErrorContains(t, err, "isn't published")
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func countFakeCalls(dockerManager *docker.FakeDockerManager, method string) int {
+	result := 0
+	for _, call := range dockerManager.GetCalls() {
+		if call.Method == method {
+			result++
+		}
+	}
+	return result
+}
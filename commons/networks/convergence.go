@@ -0,0 +1,86 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"reflect"
+	"time"
+)
+
+const (
+	TIME_BETWEEN_CONVERGENCE_PROBES = 1 * time.Second
+)
+
+// GENERICS TOOD: When Go has generics, make the service argument and the return value parameterized
+/*
+A user-defined probe that extracts some piece of state from a service (e.g. the last accepted block), used to check whether
+	a set of services have converged on the same state. Because Go doesn't have generics, the user will need to cast the
+	service to the expected interface type.
+ */
+type ServiceProbe func(service services.Service) (interface{}, error)
+
+/*
+Repeatedly probes the given services until every probe succeeds and returns the same value, or the timeout is hit.
+
+Args:
+	serviceIds: A "set" of the IDs of the services that should converge
+	probe: The probe used to extract the state that should converge
+	timeout: How long to wait for convergence before giving up
+
+Returns:
+	An error if the services didn't converge within the timeout, describing the last observed state of each service
+ */
+func (network *ServiceNetwork) WaitForConvergence(serviceIds map[ServiceID]bool, probe ServiceProbe, timeout time.Duration) error {
+	// The probes are run without the network's lock, so that they can take as long as they need
+	servicesToProbe := make(map[ServiceID]services.Service)
+	for serviceId, _ := range serviceIds {
+		nodeInfo, err := network.GetService(serviceId)
+		if err != nil {
+			return stacktrace.Propagate(err, "Cannot check convergence of service ID %v", serviceId)
+		}
+		servicesToProbe[serviceId] = nodeInfo.Service
+	}
+
+	deadline := time.Now().Add(timeout)
+	var lastObservations map[ServiceID]interface{}
+	for {
+		var converged bool
+		converged, lastObservations = probeForConvergence(servicesToProbe, probe)
+		if converged {
+			return nil
+		}
+		if time.Now().Add(TIME_BETWEEN_CONVERGENCE_PROBES).After(deadline) {
+			break
+		}
+		network.log.Tracef("Services haven't converged yet; sleeping for %v before re-probing...", TIME_BETWEEN_CONVERGENCE_PROBES)
+		time.Sleep(TIME_BETWEEN_CONVERGENCE_PROBES)
+	}
+	return stacktrace.NewError("Services didn't converge within %v; last observed states: %v", timeout, lastObservations)
+}
+
+/*
+Probes each service once, returning whether all probes succeeded with the same value along with what was observed for
+	each service (either the probed value or the probe error).
+ */
+func probeForConvergence(servicesToProbe map[ServiceID]services.Service, probe ServiceProbe) (bool, map[ServiceID]interface{}) {
+	observations := make(map[ServiceID]interface{})
+	converged := true
+	var firstValue interface{}
+	isFirst := true
+	for serviceId, service := range servicesToProbe {
+		value, err := probe(service)
+		if err != nil {
+			observations[serviceId] = err
+			converged = false
+			continue
+		}
+		observations[serviceId] = value
+		if isFirst {
+			firstValue = value
+			isFirst = false
+		} else if !reflect.DeepEqual(firstValue, value) {
+			converged = false
+		}
+	}
+	return converged, observations
+}
@@ -0,0 +1,52 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"testing"
+	"time"
+)
+
+type testProbedService struct {
+	state int
+}
+
+func probeTestState(service services.Service) (interface{}, error) {
+	probedService, ok := service.(testProbedService)
+	if !ok {
+		return nil, stacktrace.NewError("Service wasn't a probeable test service")
+	}
+	return probedService.state, nil
+}
+
+func TestConvergedServices(t *testing.T) {
+	network := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "").Build()
+	network.serviceNodes["node1"] = ServiceNode{Service: testProbedService{state: 5}}
+	network.serviceNodes["node2"] = ServiceNode{Service: testProbedService{state: 5}}
+
+	serviceIds := map[ServiceID]bool{"node1": true, "node2": true}
+	if err := network.WaitForConvergence(serviceIds, probeTestState, time.Second); err != nil {
+		t.Fatalf("Expected services with identical state to be converged, but got error: %v", err)
+	}
+}
+
+func TestDivergedServices(t *testing.T) {
+	network := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "").Build()
+	network.serviceNodes["node1"] = ServiceNode{Service: testProbedService{state: 5}}
+	network.serviceNodes["node2"] = ServiceNode{Service: testProbedService{state: 6}}
+	network.serviceNodes["node3"] = ServiceNode{Service: TestService{}}
+
+	if err := network.WaitForConvergence(map[ServiceID]bool{"node1": true, "node2": true}, probeTestState, 0); err == nil {
+		t.Fatal("Expected services with different state not to be converged")
+	}
+	if err := network.WaitForConvergence(map[ServiceID]bool{"node1": true, "node3": true}, probeTestState, 0); err == nil {
+		t.Fatal("Expected services with failing probes not to be converged")
+	}
+}
+
+func TestConvergenceOfNonexistentService(t *testing.T) {
+	network := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "").Build()
+	if err := network.WaitForConvergence(map[ServiceID]bool{"nonexistent": true}, probeTestState, 0); err == nil {
+		t.Fatal("Expected an error checking convergence of a nonexistent service")
+	}
+}
@@ -0,0 +1,134 @@
+package networks
+
+import (
+	"context"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"io"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	// The name of the directory, in each service's artifacts directory, that the service's coverage files are collected into
+	COVERAGE_ARTIFACT_DIRNAME = "coverage"
+
+	coverageFlushPollInterval = 250 * time.Millisecond
+)
+
+/*
+Collects the coverage of every service whose initializer core implements services.CoverageProvider: each such service is
+	sent its coverage flush signal, given up to the flush timeout to write out its coverage & exit, and then the files in
+	its coverage directory are copied to <dirpath>/<service ID>/COVERAGE_ARTIFACT_DIRNAME (alongside its other artifacts,
+	see ExportServiceArtifacts). The files are left as the instrumented binaries wrote them, to be merged by the coverage
+	tooling of the services' language (e.g. "go tool covdata").
+
+This is intended to be called at the end of a test, before the network is torn down, as the services are stopped by it.
+
+Args:
+	dirpath: The directory to collect coverage into, which will be created if it doesn't exist
+	flushTimeout: How long to give each service to exit after being signalled, after which whatever coverage it has
+		written so far is collected
+
+Returns:
+	An error if any service's coverage couldn't be collected; the collection is best-effort, so the other services'
+		coverage will still be collected
+ */
+func (network *ServiceNetwork) CollectCoverage(dirpath string, flushTimeout time.Duration) error {
+	// Copied so that we don't hold the network's lock while waiting for the services to flush
+	network.mutex.Lock()
+	coverageNodes := make(map[ServiceID]ServiceNode)
+	flushSignals := make(map[ServiceID]string)
+	for serviceId, nodeInfo := range network.serviceNodes {
+		coverageProvider, ok := network.configurations[nodeInfo.configurationId].initializerCore.(services.CoverageProvider)
+		if !ok {
+			continue
+		}
+		coverageNodes[serviceId] = nodeInfo
+		flushSignals[serviceId] = coverageProvider.GetCoverageFlushSignal()
+	}
+	network.mutex.Unlock()
+
+	// The services are flushed in parallel, since each can take up to the flush timeout
+	resultMutex := &sync.Mutex{}
+	var resultErr error = nil
+	waitGroup := &sync.WaitGroup{}
+	for serviceId, nodeInfo := range coverageNodes {
+		waitGroup.Add(1)
+		go func(serviceId ServiceID, nodeInfo ServiceNode) {
+			defer waitGroup.Done()
+			serviceDirpath := path.Join(dirpath, string(serviceId), COVERAGE_ARTIFACT_DIRNAME)
+			err := network.collectServiceCoverage(serviceId, nodeInfo, flushSignals[serviceId], flushTimeout, serviceDirpath)
+			if err != nil {
+				network.serviceLog(serviceId).Errorf("An error occurred collecting the coverage of service ID %v: %v", serviceId, err)
+				resultMutex.Lock()
+				resultErr = stacktrace.Propagate(err, "An error occurred collecting the coverage of service ID %v", serviceId)
+				resultMutex.Unlock()
+			}
+		}(serviceId, nodeInfo)
+	}
+	waitGroup.Wait()
+	return resultErr
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (network *ServiceNetwork) collectServiceCoverage(
+			serviceId ServiceID,
+			nodeInfo ServiceNode,
+			flushSignal string,
+			flushTimeout time.Duration,
+			dirpath string) error {
+	ctx := context.Background()
+	dockerManager := network.getDockerManager(nodeInfo)
+
+	status, err := dockerManager.GetContainerStatus(ctx, nodeInfo.ContainerId)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred getting the status of the service's container")
+	}
+	coverageDirpath, found := status.Labels[docker.COVERAGE_DIRPATH_LABEL]
+	if !found {
+		return stacktrace.NewError("The service's container has no coverage directory label")
+	}
+
+	if status.State == RUNNING_CONTAINER_STATE {
+		network.serviceLog(serviceId).Debugf("Sending %v to service ID %v to flush its coverage...", flushSignal, serviceId)
+		if err := dockerManager.SignalContainer(ctx, nodeInfo.ContainerId, flushSignal); err != nil {
+			return stacktrace.Propagate(err, "An error occurred signalling the service to flush its coverage")
+		}
+		if err := waitForContainerExit(ctx, dockerManager, nodeInfo.ContainerId, flushTimeout); err != nil {
+			// The service may still have flushed some of its coverage, so we collect whatever is there
+			network.serviceLog(serviceId).Warnf("Service ID %v didn't finish flushing its coverage; its coverage may be incomplete: %v", serviceId, err)
+		}
+	}
+
+	// Docker can copy out of stopped containers, and the coverage directory is on the test volume so it outlives the process
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(dockerManager.ArchiveContainerDirectory(ctx, nodeInfo.ContainerId, coverageDirpath, pipeWriter))
+	}()
+	defer pipeReader.Close()
+	if err := docker.ExtractArchiveToDirectory(pipeReader, dirpath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred copying coverage directory %v out of the service's container", coverageDirpath)
+	}
+	return nil
+}
+
+// Waits until the given container is no longer running, returning an error if it's still running after the timeout
+func waitForContainerExit(ctx context.Context, dockerManager docker.ContainerManager, containerId string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := dockerManager.GetContainerStatus(ctx, containerId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the status of container %v", containerId)
+		}
+		if status.State != RUNNING_CONTAINER_STATE {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return stacktrace.NewError("Container %v was still running after %v", containerId, timeout)
+		}
+		time.Sleep(coverageFlushPollInterval)
+	}
+}
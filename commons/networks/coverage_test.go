@@ -0,0 +1,66 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+type testCoverageInitializerCore struct {
+	TestInitializerCore
+}
+
+func (core testCoverageInitializerCore) GetCoverageDirEnvVariable() string {
+	return "GOCOVERDIR"
+}
+
+func (core testCoverageInitializerCore) GetCoverageFlushSignal() string {
+	return "SIGTERM"
+}
+
+func TestCollectingCoverage(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+	artifactsDirpath, err := ioutil.TempDir("", "artifacts")
+	assert.NilError(t, err)
+	defer os.RemoveAll(artifactsDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration("instrumented", "test", testCoverageInitializerCore{}, getTestCheckerCore()))
+	assert.NilError(t, builder.AddConfiguration("plain", "test", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+	_, err = network.AddService("instrumented", "node1", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	_, err = network.AddService("plain", "node2", map[ServiceID]bool{})
+	assert.NilError(t, err)
+
+	instrumentedNode, err := network.GetService("node1")
+	assert.NilError(t, err)
+	container, found := dockerManager.GetContainer(instrumentedNode.ContainerId)
+	assert.Assert(t, found)
+	coverageDirpath := container.EnvVariables["GOCOVERDIR"]
+	assert.Equal(t, "/foo/bar", path.Dir(path.Dir(coverageDirpath)))
+	assert.Equal(t, coverageDirpath, container.Options.Labels[docker.COVERAGE_DIRPATH_LABEL])
+
+	assert.NilError(t, network.CollectCoverage(artifactsDirpath, time.Second))
+	container, _ = dockerManager.GetContainer(instrumentedNode.ContainerId)
+	assert.Equal(t, docker.FAKE_EXITED_STATE, container.State)
+	_, err = os.Stat(path.Join(artifactsDirpath, "node1", COVERAGE_ARTIFACT_DIRNAME))
+	assert.NilError(t, err)
+
+	// Services that aren't instrumented are left alone
+	_, err = os.Stat(path.Join(artifactsDirpath, "node2"))
+	assert.Assert(t, os.IsNotExist(err))
+	plainNode, err := network.GetService("node2")
+	assert.NilError(t, err)
+	container, _ = dockerManager.GetContainer(plainNode.ContainerId)
+	assert.Equal(t, docker.FAKE_RUNNING_STATE, container.State)
+}
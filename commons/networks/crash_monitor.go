@@ -0,0 +1,209 @@
+package networks
+
+import (
+	"bufio"
+	"context"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The Docker states of containers that have stopped running, which is a crash for services the test didn't stop
+var stoppedContainerStates = map[string]bool{
+	"exited": true,
+	"dead":   true,
+}
+
+// The types of events that start or stop services' containers
+var runStateEventTypes = map[LifecycleEventType]bool{
+	SERVICE_STARTED:    true,
+	SERVICE_RESTARTED:  true,
+	SERVICE_REVIVED:    true,
+	SERVICE_REATTACHED: true,
+	SERVICE_KILLED:     true,
+	SERVICE_CRASHED:    true,
+}
+
+/*
+Periodically checks a test network's services for crashes (see ServiceNetwork.DetectCrashedServices), so that crashes
+	show up as SERVICE_CRASHED events (e.g. for EventSubscriptions) shortly after they happen.
+ */
+type CrashMonitor struct {
+	// The network whose services will be checked
+	network *ServiceNetwork
+
+	// How often the services will be checked
+	pollInterval time.Duration
+
+	// Mutex guarding the error
+	mutex *sync.Mutex
+
+	// The last error that occurred checking the services, if any
+	lastErr error
+
+	// Closed to tell the monitor goroutine to stop
+	stopChan chan struct{}
+
+	// Closed by the monitor goroutine once it has fully stopped
+	doneChan chan struct{}
+}
+
+/*
+Creates a new monitor that will check the network's services for crashes every poll interval.
+ */
+func NewCrashMonitor(network *ServiceNetwork, pollInterval time.Duration) *CrashMonitor {
+	return &CrashMonitor{
+		network:      network,
+		pollInterval: pollInterval,
+		mutex:        &sync.Mutex{},
+		lastErr:      nil,
+		stopChan:     nil,
+		doneChan:     nil,
+	}
+}
+
+/*
+Starts checking the network's services in a background goroutine. A monitor can only be started once.
+ */
+func (monitor *CrashMonitor) Start() error {
+	if monitor.pollInterval <= 0 {
+		return stacktrace.NewError("Crash monitor poll interval must be positive, but was %v", monitor.pollInterval)
+	}
+	if monitor.stopChan != nil {
+		return stacktrace.NewError("Crash monitor has already been started")
+	}
+	monitor.stopChan = make(chan struct{})
+	monitor.doneChan = make(chan struct{})
+	go monitor.run()
+	return nil
+}
+
+/*
+Stops checking the network's services, blocking until the background goroutine has exited.
+
+Returns:
+	The last error that occurred checking the services, if any
+ */
+func (monitor *CrashMonitor) Stop() error {
+	if monitor.stopChan != nil {
+		close(monitor.stopChan)
+		<-monitor.doneChan
+	}
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	return monitor.lastErr
+}
+
+/*
+Checks the container of every service that has been started for whether it has exited without the test killing or
+	removing the service, recording a SERVICE_CRASHED event (once per crash) for each one that has. This can be called
+	periodically (e.g. by a CrashMonitor) to turn crashes into events that subscribers can react to.
+
+Returns:
+	A "set" of the services that were newly found to have crashed
+ */
+func (network *ServiceNetwork) DetectCrashedServices() (map[ServiceID]bool, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	parentCtx := context.Background()
+
+	// Mapping of service ID -> the last event that started or stopped the service's container
+	lastRunStateEventTypes := make(map[ServiceID]LifecycleEventType)
+	for _, event := range network.timeline.GetEvents() {
+		if runStateEventTypes[event.EventType] {
+			lastRunStateEventTypes[event.ServiceId] = event.EventType
+		}
+	}
+
+	// Sorted so that crashes found in a single check are recorded in a stable order
+	serviceIds := make([]ServiceID, 0, len(network.serviceNodes))
+	for serviceId, _ := range network.serviceNodes {
+		serviceIds = append(serviceIds, serviceId)
+	}
+	sort.Slice(serviceIds, func(i, j int) bool {
+		return serviceIds[i] < serviceIds[j]
+	})
+
+	result := make(map[ServiceID]bool)
+	for _, serviceId := range serviceIds {
+		nodeInfo := network.serviceNodes[serviceId]
+		lastRunStateEventType := lastRunStateEventTypes[serviceId]
+		if nodeInfo.awaitingStart || lastRunStateEventType == SERVICE_KILLED || lastRunStateEventType == SERVICE_CRASHED {
+			continue
+		}
+		status, err := network.getDockerManager(nodeInfo).GetContainerStatus(parentCtx, nodeInfo.ContainerId)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred getting the status of service ID %v", serviceId)
+		}
+		if !stoppedContainerStates[status.State] {
+			continue
+		}
+		network.serviceLog(serviceId).Warnf("Service ID %v has crashed; its container is %v with exit code %v", serviceId, status.State, status.ExitCode)
+		network.forgetContainerNetworkInfo(nodeInfo.ContainerId)
+		network.timeline.record(SERVICE_CRASHED, serviceId)
+		result[serviceId] = true
+	}
+	return result, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Checks whether the container of the given service, which is being waited on to start, has stopped running; if so,
+	records a SERVICE_CRASHED event (unless the test killed the service, or the crash was already recorded) & returns an
+	ErrServiceCrashed with the tail of the service's logs. Errors inspecting the container aren't crashes, so are ignored.
+
+NOTE: This doesn't take the network's lock, as it's called while the lock is held when waiting for dependencies.
+ */
+func (network *ServiceNetwork) checkForStartupCrash(serviceId ServiceID, nodeInfo ServiceNode) error {
+	parentCtx := context.Background()
+	dockerManager := network.getDockerManager(nodeInfo)
+	status, err := dockerManager.GetContainerStatus(parentCtx, nodeInfo.ContainerId)
+	if err != nil || !stoppedContainerStates[status.State] {
+		return nil
+	}
+
+	crashedErr := &services.ErrServiceCrashed{
+		ServiceId:      string(serviceId),
+		ContainerState: status.State,
+		ExitCode:       status.ExitCode,
+		LogTail:        []string{},
+	}
+	if logs, err := dockerManager.GetContainerLogs(parentCtx, nodeInfo.ContainerId, false); err == nil {
+		crashedErr.LogTail = getLastLines(bufio.NewScanner(logs), DEFAULT_DIAGNOSTICS_LOG_LINES)
+		logs.Close()
+	}
+
+	var lastRunStateEventType LifecycleEventType
+	for _, event := range network.timeline.GetEvents() {
+		if event.ServiceId == serviceId && runStateEventTypes[event.EventType] {
+			lastRunStateEventType = event.EventType
+		}
+	}
+	if lastRunStateEventType != SERVICE_KILLED && lastRunStateEventType != SERVICE_CRASHED {
+		network.serviceLog(serviceId).Warnf("Service ID %v crashed while starting up; its container is %v with exit code %v", serviceId, status.State, status.ExitCode)
+		network.timeline.record(SERVICE_CRASHED, serviceId)
+	}
+	return crashedErr
+}
+
+func (monitor *CrashMonitor) run() {
+	defer close(monitor.doneChan)
+
+	for {
+		select {
+		case <-monitor.stopChan:
+			return
+		case <-time.After(monitor.pollInterval):
+		}
+
+		if _, err := monitor.network.DetectCrashedServices(); err != nil {
+			// Services can legitimately disappear mid-check (e.g. removed by the test), so we carry on checking
+			monitor.network.log.Debugf("Crash monitor couldn't check the network's services: %v", err)
+			monitor.mutex.Lock()
+			monitor.lastErr = err
+			monitor.mutex.Unlock()
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package networks
+
+import (
+	"context"
+	"errors"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDetectCrashedServices(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:latest", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+	for _, serviceId := range []ServiceID{"crashed", "killed", "healthy"} {
+		_, err = network.AddService(testConfiguration, serviceId, map[ServiceID]bool{})
+		assert.NilError(t, err)
+	}
+	crashes := network.SubscribeToEvents(10, SERVICE_CRASHED)
+
+	crashedNode, err := network.GetService("crashed")
+	assert.NilError(t, err)
+	assert.NilError(t, dockerManager.StopContainer(context.Background(), crashedNode.ContainerId, nil))
+	// Services that the test kills aren't crashes
+	assert.NilError(t, network.KillService("killed"))
+
+	crashed, err := network.DetectCrashedServices()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[ServiceID]bool{"crashed": true}, crashed)
+	assert.Equal(t, ServiceID("crashed"), (<-crashes.Events()).ServiceId)
+	assert.Assert(t, !network.GetHealth().Healthy)
+
+	// Each crash is only reported once
+	crashed, err = network.DetectCrashedServices()
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(crashed))
+}
+
+func TestFailingFastOnStartupCrash(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	numChecks := 0
+	neverUpCore := countingCheckerCore{numChecks: &numChecks, numChecksUntilUp: 0}
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:latest", getTestInitializerCore(), neverUpCore))
+	network := builder.Build()
+	availabilityChecker, err := network.AddService(testConfiguration, testServiceName, map[ServiceID]bool{})
+	assert.NilError(t, err)
+
+	// The service exits right after starting, as it would with bad flags
+	node, err := network.GetService(testServiceName)
+	assert.NilError(t, err)
+	assert.NilError(t, dockerManager.SetContainerOutput(node.ContainerId, "Starting node...\nunknown flag: --bootstrap-ips\n", ""))
+	assert.NilError(t, dockerManager.StopContainer(context.Background(), node.ContainerId, nil))
+
+	// Rather than waiting out the checker core's timeout
+	startTime := time.Now()
+	err = availabilityChecker.WaitForStartup()
+	assert.Assert(t, time.Since(startTime) < 10 * time.Second)
+	var crashedErr *services.ErrServiceCrashed
+	assert.Assert(t, errors.As(stacktrace.RootCause(err), &crashedErr))
+	assert.DeepEqual(t, []string{"Starting node...", "unknown flag: --bootstrap-ips"}, crashedErr.LogTail)
+	assert.ErrorContains(t, err, "unknown flag: --bootstrap-ips")
+	assert.Assert(t, !network.GetHealth().Healthy)
+
+	// The crash was already recorded, so isn't reported again
+	crashed, err := network.DetectCrashedServices()
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(crashed))
+}
+
+func TestCrashMonitorNonPositivePollIntervalRejected(t *testing.T) {
+	monitor := NewCrashMonitor(nil, 0)
+	assert.ErrorContains(t, monitor.Start(), "must be positive")
+	assert.NilError(t, monitor.Stop())
+}
@@ -0,0 +1,92 @@
+package networks
+
+import (
+	"context"
+	"github.com/palantir/stacktrace"
+	"time"
+)
+
+/*
+How a network checks that a service's dependencies are available before starting the service (see
+	ServiceNetworkBuilder.UseDependencyAvailabilityPolicy), so that a dependent doesn't race a dependency that hasn't come
+	up yet. Each dependency is checked with its configuration's availability checker core, until it's available or the
+	policy's limits are hit.
+ */
+type DependencyAvailabilityPolicy struct {
+	// How long to keep checking each dependency before failing the dependent's start
+	Timeout time.Duration
+
+	// How long to wait between checks of a dependency
+	RetryInterval time.Duration
+
+	// The most times that a dependency's check is retried after failing, or 0 for no limit other than the timeout
+	MaxRetries int
+}
+
+/*
+Makes the built network check that each service's dependencies are available before starting the service, failing the
+	start (with which dependency never became available, & why) if one isn't. Dependencies already reported available
+	(e.g. by waiting on their availability checkers) aren't checked again.
+
+NOTE: The network's lock is held while the dependencies are checked, so other calls on the network wait until the
+	checks finish.
+ */
+func (builder *ServiceNetworkBuilder) UseDependencyAvailabilityPolicy(policy DependencyAvailabilityPolicy) {
+	builder.dependencyAvailabilityPolicy = &policy
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Checks that the dependencies of the given service are available per the network's dependency availability policy (if
+	it has one); must be called with the network's lock held.
+ */
+func (network *ServiceNetwork) waitForDependencies(serviceId ServiceID, nodeInfo ServiceNode) error {
+	if network.dependencyAvailabilityPolicy == nil || len(nodeInfo.dependencyIds) == 0 {
+		return nil
+	}
+	policy := *network.dependencyAvailabilityPolicy
+
+	availableServiceIds := make(map[ServiceID]bool)
+	for _, health := range network.GetHealth().Services {
+		if health.Available {
+			availableServiceIds[health.ServiceId] = true
+		}
+	}
+	for _, dependencyId := range nodeInfo.dependencyIds {
+		if availableServiceIds[dependencyId] {
+			continue
+		}
+		dependencyNode, found := network.serviceNodes[dependencyId]
+		if !found {
+			return stacktrace.NewError("Dependency %v of service %v is no longer in the network", dependencyId, serviceId)
+		}
+		if err := network.waitForDependency(dependencyId, dependencyNode, policy); err != nil {
+			return stacktrace.Propagate(err, "Dependency %v of service %v never became available", dependencyId, serviceId)
+		}
+	}
+	return nil
+}
+
+// Checks the given dependency until it's available or the given policy's limits are hit
+func (network *ServiceNetwork) waitForDependency(dependencyId ServiceID, dependencyNode ServiceNode, policy DependencyAvailabilityPolicy) error {
+	config := network.configurations[dependencyNode.configurationId]
+	checker := network.newAvailabilityChecker(context.Background(), dependencyId, config, dependencyNode)
+
+	deadline := time.Now().Add(policy.Timeout)
+	numChecks := 0
+	for {
+		numChecks++
+		checkErr := checker.CheckAvailability()
+		if checkErr == nil {
+			return nil
+		}
+		if policy.MaxRetries > 0 && numChecks > policy.MaxRetries {
+			return stacktrace.Propagate(checkErr, "Service %v still wasn't available after %v checks", dependencyId, numChecks)
+		}
+		if time.Now().Add(policy.RetryInterval).After(deadline) {
+			return stacktrace.Propagate(checkErr, "Service %v still wasn't available after %v checks over %v", dependencyId, numChecks, policy.Timeout)
+		}
+		network.serviceLog(dependencyId).Tracef("Service %v isn't available yet; sleeping for %v before re-checking...", dependencyId, policy.RetryInterval)
+		time.Sleep(policy.RetryInterval)
+	}
+}
@@ -0,0 +1,81 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+const (
+	testDependencyConfiguration = "test-dependency-configuration"
+)
+
+// Reports the service as up once it has been checked the given number of times (or never, if 0)
+type countingCheckerCore struct {
+	numChecks *int
+
+	numChecksUntilUp int
+}
+
+func (core countingCheckerCore) IsServiceUp(toCheck services.Service, dependencies []services.Service) bool {
+	*core.numChecks++
+	return core.numChecksUntilUp > 0 && *core.numChecks >= core.numChecksUntilUp
+}
+
+func (core countingCheckerCore) GetTimeout() time.Duration {
+	return 30 * time.Second
+}
+
+func TestWaitingForDependencies(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	numChecks := 0
+	network := buildDependencyTestNetwork(t, testVolumeControllerDirpath, countingCheckerCore{numChecks: &numChecks, numChecksUntilUp: 3})
+	_, err = network.AddService(testDependencyConfiguration, "bootstrap", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	_, err = network.AddService(testConfiguration, "node1", map[ServiceID]bool{"bootstrap": true})
+	assert.NilError(t, err)
+	assert.Equal(t, 3, numChecks)
+	assert.Assert(t, network.GetHealth().Services[0].Available)
+
+	// Dependencies that are already available aren't checked again
+	_, err = network.AddService(testConfiguration, "node2", map[ServiceID]bool{"bootstrap": true})
+	assert.NilError(t, err)
+	assert.Equal(t, 3, numChecks)
+}
+
+func TestDependencyNeverAvailable(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	numChecks := 0
+	network := buildDependencyTestNetwork(t, testVolumeControllerDirpath, countingCheckerCore{numChecks: &numChecks, numChecksUntilUp: 0})
+	_, err = network.AddService(testDependencyConfiguration, "bootstrap", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	_, err = network.AddService(testConfiguration, "node1", map[ServiceID]bool{"bootstrap": true})
+	assert.ErrorContains(t, err, "Dependency bootstrap of service node1 never became available")
+	assert.ErrorContains(t, err, "still wasn't available after 3 checks")
+	assert.Equal(t, 3, numChecks)
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func buildDependencyTestNetwork(t *testing.T, testVolumeControllerDirpath string, dependencyCheckerCore services.ServiceAvailabilityCheckerCore) *ServiceNetwork {
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, docker.NewFakeDockerManager(), testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:latest", getTestInitializerCore(), getTestCheckerCore()))
+	assert.NilError(t, builder.AddConfiguration(testDependencyConfiguration, "node:latest", getTestInitializerCore(), dependencyCheckerCore))
+	builder.UseDependencyAvailabilityPolicy(DependencyAvailabilityPolicy{
+		Timeout:       10 * time.Second,
+		RetryInterval: time.Millisecond,
+		MaxRetries:    2,
+	})
+	return builder.Build()
+}
@@ -0,0 +1,134 @@
+package networks
+
+import (
+	"context"
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// The image that egress gateways run, which must contain socat & a shell
+	EGRESS_GATEWAY_IMAGE = NAT_PROXY_IMAGE
+
+	egressGatewayShell = "/bin/sh"
+)
+
+/*
+Lets the built network's services reach the given external endpoint (e.g. "api.example.com:443") even when the test
+	network is internal (i.e. created without outbound connectivity, so that tests prove their services don't depend on
+	external endpoints). Services reach an allowed endpoint by its usual host name, which resolves to an egress gateway:
+	a container on the test network, with a leg on Docker's default bridge network, that forwards TCP connections made to
+	the endpoint's allowed ports to the real endpoint. Nothing else outside the network is reachable.
+
+NOTE: Only TCP is forwarded, and only on the allowed ports. Services on the host's network aren't affected, since they
+	aren't on the test network.
+
+Args:
+	hostAndPort: The endpoint's host name & port, e.g. "api.example.com:443"
+ */
+func (builder *ServiceNetworkBuilder) AllowEgressTo(hostAndPort string) error {
+	host, portStr, err := net.SplitHostPort(hostAndPort)
+	if err != nil {
+		return stacktrace.Propagate(err, "Egress endpoint '%v' isn't of the form host:port", hostAndPort)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return stacktrace.NewError("Egress endpoint '%v' doesn't have a valid port", hostAndPort)
+	}
+	if host == "" {
+		return stacktrace.NewError("Egress endpoint '%v' doesn't have a host", hostAndPort)
+	}
+	if _, found := builder.egressAllowances[host]; !found {
+		builder.egressAllowances[host] = map[int]bool{}
+	}
+	builder.egressAllowances[host][port] = true
+	return nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Starts an egress gateway for each of the network's allowed external hosts, if they haven't already been started; must be
+	called with the network's lock held.
+
+Returns:
+	The extra "hostname:IP" entries that services' /etc/hosts need to reach the allowed hosts through their gateways
+ */
+func (network *ServiceNetwork) ensureEgressGateways(ctx context.Context) ([]string, error) {
+	hosts := make([]string, 0, len(network.egressAllowances))
+	for host, _ := range network.egressAllowances {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	extraHosts := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		gatewayIp, found := network.egressGatewayIps[host]
+		if !found {
+			var err error
+			gatewayIp, err = network.startEgressGateway(ctx, host)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "An error occurred starting the egress gateway for %v", host)
+			}
+			network.egressGatewayIps[host] = gatewayIp
+		}
+		extraHosts = append(extraHosts, host + ":" + gatewayIp.String())
+	}
+	return extraHosts, nil
+}
+
+// Must be called with the network's lock held
+func (network *ServiceNetwork) startEgressGateway(ctx context.Context, host string) (net.IP, error) {
+	gatewayIp, err := network.freeIpTracker.GetFreeIpAddr()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to allocate static IP for the egress gateway")
+	}
+
+	network.log.Debugf("Starting egress gateway for %v at %v...", host, gatewayIp)
+	containerId, err := network.dockerManager.CreateAndStartContainer(
+			ctx,
+			EGRESS_GATEWAY_IMAGE,
+			network.dockerNetworkId,
+			gatewayIp,
+			nil,
+			[]string{"-c", getEgressGatewayScript(host, network.egressAllowances[host])},
+			map[string]string{},
+			map[string]string{},
+			map[string]string{},
+			docker.ContainerOptions{
+				Entrypoint:              []string{egressGatewayShell},
+				// The gateway reaches the outside world through the default bridge network
+				UseDefaultBridgeNetwork: true,
+			})
+	if err != nil {
+		network.freeIpTracker.ReleaseIpAddr(gatewayIp)
+		return nil, stacktrace.Propagate(err, "An error occurred starting the egress gateway container")
+	}
+	network.log.Debugf("Successfully started egress gateway for %v in container %v", host, containerId)
+	return gatewayIp, nil
+}
+
+/*
+Gets the shell script an egress gateway runs, which starts a socat forwarder to the host for each of the given ports (in
+	port order, so the script is deterministic)
+ */
+func getEgressGatewayScript(host string, ports map[int]bool) string {
+	sortedPorts := make([]int, 0, len(ports))
+	for port, _ := range ports {
+		sortedPorts = append(sortedPorts, port)
+	}
+	sort.Ints(sortedPorts)
+
+	forwarders := make([]string, 0, len(sortedPorts))
+	for _, port := range sortedPorts {
+		forwarders = append(forwarders, fmt.Sprintf(
+			"socat TCP-LISTEN:%v,fork,reuseaddr TCP:%v &",
+			port,
+			net.JoinHostPort(host, strconv.Itoa(port))))
+	}
+	return strings.Join(append(forwarders, "wait"), " ")
+}
@@ -0,0 +1,112 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEgressGatewaysForwardAllowedEndpoints(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	dockerManager := docker.NewFakeDockerManager()
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	assert.NilError(t, builder.AllowEgressTo("api.example.com:443"))
+	assert.NilError(t, builder.AllowEgressTo("api.example.com:80"))
+	assert.ErrorContains(t, builder.AllowEgressTo("api.example.com"), "host:port")
+	assert.ErrorContains(t, builder.AllowEgressTo("api.example.com:http"), "valid port")
+	assert.ErrorContains(t, builder.AllowEgressTo(":443"), "doesn't have a host")
+	network := builder.Build()
+
+	for _, serviceId := range []ServiceID{"service0", "service1"} {
+		_, err := network.AddService(testConfiguration, serviceId, map[ServiceID]bool{})
+		assert.NilError(t, err)
+	}
+
+	// One gateway per allowed host, started along with the first service
+	gatewayContainerIds := []string{}
+	for _, containerId := range dockerManager.GetContainerIds() {
+		container, _ := dockerManager.GetContainer(containerId)
+		if container.Image == EGRESS_GATEWAY_IMAGE {
+			gatewayContainerIds = append(gatewayContainerIds, containerId)
+		}
+	}
+	assert.Equal(t, 1, len(gatewayContainerIds))
+	gateway, _ := dockerManager.GetContainer(gatewayContainerIds[0])
+	assert.Assert(t, gateway.Options.UseDefaultBridgeNetwork)
+	assert.Equal(t, 0, len(gateway.Options.AdditionalNetworkIds))
+	assert.Equal(
+		t,
+		"socat TCP-LISTEN:80,fork,reuseaddr TCP:api.example.com:80 & socat TCP-LISTEN:443,fork,reuseaddr TCP:api.example.com:443 & wait",
+		gateway.StartCmdArgs[1])
+
+	// Every service reaches the allowed host through the gateway
+	gatewayIp := gateway.NetworkIps[testNetworkName]
+	for _, serviceId := range []ServiceID{"service0", "service1"} {
+		nodeInfo, err := network.GetService(serviceId)
+		assert.NilError(t, err)
+		container, _ := dockerManager.GetContainer(nodeInfo.ContainerId)
+		assert.Assert(t, strings.Contains(strings.Join(container.Options.ExtraHosts, ","), "api.example.com:" + gatewayIp.String()))
+	}
+}
+
+func TestServicesCantReachHostsThatArentAllowed(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	dockerManager := docker.NewFakeDockerManager()
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	assert.NilError(t, builder.AllowEgressTo("api.example.com:443"))
+	network := builder.Build()
+
+	_, err = network.AddService(testConfiguration, "service", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	nodeInfo, err := network.GetService("service")
+	assert.NilError(t, err)
+	container, _ := dockerManager.GetContainer(nodeInfo.ContainerId)
+	// The service is only on the (internal) test network, and only the allowed host resolves to a gateway
+	assert.Assert(t, !container.Options.UseDefaultBridgeNetwork)
+	assert.Equal(t, 0, len(container.Options.AdditionalNetworkIds))
+	assert.Equal(t, 1, len(container.NetworkIps))
+	assert.Equal(t, 1, len(container.Options.ExtraHosts))
+	assert.Assert(t, strings.HasPrefix(container.Options.ExtraHosts[0], "api.example.com:"))
+}
+
+func TestFailedEgressGatewaysFreeTheirIps(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	dockerManager := docker.NewFakeDockerManager()
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	assert.NilError(t, builder.AllowEgressTo("api.example.com:443"))
+	network := builder.Build()
+
+	dockerManager.FailNext("CreateAndStartContainer", stacktrace.NewError("Test failure"))
+	_, err = network.AddService(testConfiguration, "service", map[ServiceID]bool{})
+	assert.ErrorContains(t, err, "Test failure")
+
+	// Neither the service nor its gateway held on to their IPs, so they get the same IPs the second time around
+	_, err = network.AddService(testConfiguration, "service", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	serviceIp, err := network.GetServiceIp("service")
+	assert.NilError(t, err)
+	assert.Equal(t, "172.23.0.1", serviceIp.String())
+	assert.Equal(t, "172.23.0.2", network.egressGatewayIps["api.example.com"].String())
+}
@@ -0,0 +1,14 @@
+package networks
+
+import (
+	"errors"
+)
+
+/*
+The root cause of errors returned when there are no free addresses left to hand out: no free IPs in a test network's
+	subnet, or no free subnets in the supernet that test networks are allocated from. Errors in this codebase are wrapped
+	with stacktrace, so check for it with:
+
+	errors.Is(stacktrace.RootCause(err), networks.ErrAddressesExhausted)
+ */
+var ErrAddressesExhausted = errors.New("addresses exhausted")
@@ -0,0 +1,97 @@
+package networks
+
+
+/*
+A feed of the lifecycle events that happen in a test network from when the subscription is made (see Timeline.Subscribe),
+	so that tests & monitoring tooling can react to the network's changes as they happen rather than polling it.
+
+Events are delivered on a buffered channel without ever blocking the network, so subscribers that fall more than a
+	buffer's worth of events behind miss events; GetNumDropped says how many. The full history is always available from
+	Timeline.GetEvents.
+ */
+type EventSubscription struct {
+	timeline *Timeline
+
+	// The "set" of event types that are delivered, or empty for every type
+	eventTypes map[LifecycleEventType]bool
+
+	events chan LifecycleEvent
+
+	// The number of events that couldn't be delivered because the channel's buffer was full (guarded by the timeline's
+	//  mutex)
+	numDropped int
+}
+
+/*
+Subscribes to the lifecycle events that happen from now on.
+
+Args:
+	bufferSize: How many events can wait to be received before further events are dropped
+	eventTypes: The types of events to deliver, or none for every type
+ */
+func (timeline *Timeline) Subscribe(bufferSize int, eventTypes ...LifecycleEventType) *EventSubscription {
+	subscription := &EventSubscription{
+		timeline:   timeline,
+		eventTypes: map[LifecycleEventType]bool{},
+		events:     make(chan LifecycleEvent, bufferSize),
+		numDropped: 0,
+	}
+	for _, eventType := range eventTypes {
+		subscription.eventTypes[eventType] = true
+	}
+
+	timeline.mutex.Lock()
+	defer timeline.mutex.Unlock()
+	timeline.subscriptions[subscription] = true
+	return subscription
+}
+
+/*
+Subscribes to the lifecycle events that happen in the network from now on (see Timeline.Subscribe).
+ */
+func (network *ServiceNetwork) SubscribeToEvents(bufferSize int, eventTypes ...LifecycleEventType) *EventSubscription {
+	return network.timeline.Subscribe(bufferSize, eventTypes...)
+}
+
+/*
+Gets the channel that the subscription's events are delivered on, in the order they happened, which is closed once the
+	subscription is cancelled with Unsubscribe.
+ */
+func (subscription *EventSubscription) Events() <-chan LifecycleEvent {
+	return subscription.events
+}
+
+/*
+Gets the number of events that weren't delivered because the subscriber fell too far behind.
+ */
+func (subscription *EventSubscription) GetNumDropped() int {
+	subscription.timeline.mutex.Lock()
+	defer subscription.timeline.mutex.Unlock()
+	return subscription.numDropped
+}
+
+/*
+Stops delivering events to the subscription & closes its channel. Unsubscribing more than once has no effect.
+ */
+func (subscription *EventSubscription) Unsubscribe() {
+	subscription.timeline.mutex.Lock()
+	defer subscription.timeline.mutex.Unlock()
+	if !subscription.timeline.subscriptions[subscription] {
+		return
+	}
+	delete(subscription.timeline.subscriptions, subscription)
+	close(subscription.events)
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Delivers the given event to the subscription if it wants it; must be called with the timeline's mutex held
+func (subscription *EventSubscription) publish(event LifecycleEvent) {
+	if len(subscription.eventTypes) > 0 && !subscription.eventTypes[event.EventType] {
+		return
+	}
+	select {
+	case subscription.events <- event:
+	default:
+		subscription.numDropped++
+	}
+}
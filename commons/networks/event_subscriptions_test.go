@@ -0,0 +1,30 @@
+package networks
+
+import (
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestEventSubscriptions(t *testing.T) {
+	timeline := newTimeline()
+	timeline.record(SERVICE_STARTED, "before-subscribing")
+
+	allEvents := timeline.Subscribe(10)
+	crashes := timeline.Subscribe(10, SERVICE_CRASHED)
+	full := timeline.Subscribe(1)
+	timeline.record(SERVICE_STARTED, "node1")
+	timeline.record(SERVICE_CRASHED, "node1")
+
+	assert.Equal(t, ServiceID("node1"), (<-allEvents.Events()).ServiceId)
+	assert.Equal(t, SERVICE_CRASHED, (<-allEvents.Events()).EventType)
+	assert.Equal(t, SERVICE_CRASHED, (<-crashes.Events()).EventType)
+	assert.Equal(t, 0, len(crashes.Events()))
+	assert.Equal(t, 1, full.GetNumDropped())
+
+	allEvents.Unsubscribe()
+	allEvents.Unsubscribe()
+	timeline.record(SERVICE_REMOVED, "node1")
+	_, isOpen := <-allEvents.Events()
+	assert.Assert(t, !isOpen)
+	assert.Equal(t, SERVICE_REMOVED, timeline.GetEvents()[3].EventType)
+}
@@ -0,0 +1,141 @@
+package networks
+
+import (
+	"encoding/json"
+	"github.com/palantir/stacktrace"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	HEALTH_PATH = "/health"
+)
+
+/*
+The current health of a single service in the network.
+ */
+type ServiceHealth struct {
+	ServiceId ServiceID `json:"serviceId"`
+
+	// True if the service has been reported available by its availability checker, and hasn't since been killed,
+	//  restarted, or removed
+	Available bool `json:"available"`
+
+	// True if the service is currently cut off from the network by a partition
+	Partitioned bool `json:"partitioned"`
+
+	// True if the service's processes are currently frozen (see ServiceNetwork.PauseService)
+	Paused bool `json:"paused"`
+
+	// True if the service has been removed from the network
+	Removed bool `json:"removed"`
+
+	// The most recent lifecycle event that happened to the service, and when
+	LastEvent     LifecycleEventType `json:"lastEvent"`
+	LastEventTime time.Time          `json:"lastEventTime"`
+}
+
+/*
+The current health of every service that has been in the network.
+ */
+type NetworkHealth struct {
+	// True if every service that hasn't been removed is available, and neither partitioned nor paused
+	Healthy bool `json:"healthy"`
+
+	// The health of each service, sorted by service ID
+	Services []ServiceHealth `json:"services"`
+}
+
+/*
+Gets the current health of all the services in the network.
+
+NOTE: This is derived from the network's timeline, so it doesn't need the network's lock and won't wait on other network
+	calls (e.g. a slow service add) to finish.
+ */
+func (network *ServiceNetwork) GetHealth() NetworkHealth {
+	healthByService := make(map[ServiceID]*ServiceHealth)
+	for _, event := range network.timeline.GetEvents() {
+		health, found := healthByService[event.ServiceId]
+		if !found {
+			health = &ServiceHealth{ServiceId: event.ServiceId}
+			healthByService[event.ServiceId] = health
+		}
+		health.LastEvent = event.EventType
+		health.LastEventTime = event.Timestamp
+
+		switch event.EventType {
+		case SERVICE_STARTED, SERVICE_RESTARTED, SERVICE_REVIVED, SERVICE_REATTACHED, SERVICE_KILLED, SERVICE_CRASHED:
+			// Revived services don't get re-checked for availability, so we can't say they're available
+			health.Available = false
+		case SERVICE_AVAILABLE:
+			health.Available = true
+		case SERVICE_REMOVED:
+			health.Available = false
+			health.Removed = true
+		case SERVICE_PARTITIONED:
+			health.Partitioned = true
+		case SERVICE_RECONNECTED:
+			health.Partitioned = false
+		case SERVICE_PAUSED:
+			health.Paused = true
+		case SERVICE_UNPAUSED:
+			health.Paused = false
+		}
+	}
+
+	result := NetworkHealth{
+		Healthy:  true,
+		Services: []ServiceHealth{},
+	}
+	for _, health := range healthByService {
+		if !health.Removed && (!health.Available || health.Partitioned || health.Paused) {
+			result.Healthy = false
+		}
+		result.Services = append(result.Services, *health)
+	}
+	sort.Slice(result.Services, func(i, j int) bool {
+		return result.Services[i].ServiceId < result.Services[j].ServiceId
+	})
+	return result
+}
+
+/*
+Gets an HTTP handler that serves the current health of the network's services as JSON, responding with 200 if the network
+	is healthy and 503 if it isn't (so simple uptime checkers can watch it too).
+ */
+func NewHealthHandler(network *ServiceNetwork) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		health := network.GetHealth()
+		healthJson, err := json.Marshal(health)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		if !health.Healthy {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+		writer.Write(healthJson)
+	})
+}
+
+/*
+Starts serving the health of the network's services on the /health path of the given listen address (e.g. ":9091") in a
+	background goroutine, so that humans & external watchers can check on long-running networks.
+
+Returns:
+	The server, which the caller should Close when it no longer wants to serve health
+ */
+func StartHealthServer(network *ServiceNetwork, listenAddr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred listening on %v to serve network health", listenAddr)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(HEALTH_PATH, NewHealthHandler(network))
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	return server, nil
+}
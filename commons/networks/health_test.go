@@ -0,0 +1,66 @@
+package networks
+
+import (
+	"encoding/json"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthDerivedFromTimeline(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+	network.timeline.record(SERVICE_STARTED, "node1")
+	network.timeline.record(SERVICE_STARTED, "node2")
+	network.timeline.record(SERVICE_AVAILABLE, "node1")
+	network.timeline.record(SERVICE_AVAILABLE, "node2")
+
+	health := network.GetHealth()
+	assert.Assert(t, health.Healthy)
+	assert.Equal(t, 2, len(health.Services))
+	assert.Equal(t, ServiceID("node1"), health.Services[0].ServiceId)
+
+	network.timeline.record(SERVICE_PARTITIONED, "node2")
+	health = network.GetHealth()
+	assert.Assert(t, !health.Healthy)
+	assert.Assert(t, health.Services[1].Partitioned)
+
+	network.timeline.record(SERVICE_RECONNECTED, "node2")
+	network.timeline.record(SERVICE_PAUSED, "node2")
+	health = network.GetHealth()
+	assert.Assert(t, !health.Healthy)
+	assert.Assert(t, health.Services[1].Paused)
+
+	network.timeline.record(SERVICE_UNPAUSED, "node2")
+	assert.Assert(t, network.GetHealth().Healthy)
+	network.timeline.record(SERVICE_KILLED, "node1")
+	health = network.GetHealth()
+	assert.Assert(t, !health.Healthy)
+	assert.Assert(t, !health.Services[0].Available)
+	assert.Equal(t, SERVICE_KILLED, health.Services[0].LastEvent)
+
+	network.timeline.record(SERVICE_REMOVED, "node1")
+	assert.Assert(t, network.GetHealth().Healthy)
+}
+
+func TestHealthHandlerStatusCodes(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+	network.timeline.record(SERVICE_STARTED, "node1")
+	handler := NewHealthHandler(network)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, HEALTH_PATH, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	network.timeline.record(SERVICE_AVAILABLE, "node1")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, HEALTH_PATH, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var health NetworkHealth
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &health))
+	assert.Assert(t, health.Healthy)
+	assert.Equal(t, 1, len(health.Services))
+}
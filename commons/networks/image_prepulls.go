@@ -0,0 +1,39 @@
+package networks
+
+import (
+	"context"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/tracing"
+	"github.com/palantir/stacktrace"
+)
+
+/*
+Pulls the images of all the network's configurations that aren't yet available, in parallel, onto every Docker host the
+	network can schedule services onto. Otherwise each image is pulled when the first service using it is created, one
+	after another, which dominates network startup time on machines with a cold image cache (e.g. fresh CI machines).
+
+Returns:
+	An error naming every image that couldn't be pulled, whose root cause is the first such image's pull error
+ */
+func (network *ServiceNetwork) PrePullImages() (err error) {
+	ctx, span := tracing.StartSpan(context.Background(), "PrePullImages")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	network.mutex.Lock()
+	images := make(map[string]bool)
+	for _, config := range network.configurations {
+		images[config.dockerImage] = true
+	}
+	network.mutex.Unlock()
+	dockerManagers := append([]docker.ContainerManager{network.dockerManager}, network.remoteDockerManagers...)
+
+	network.log.Infof("Pre-pulling %v images onto %v Docker hosts...", len(images), len(dockerManagers))
+	if err := docker.PullImagesInParallel(ctx, dockerManagers, images); err != nil {
+		return stacktrace.Propagate(err, "An error occurred pre-pulling the network's images")
+	}
+	network.log.Info("Successfully pre-pulled images")
+	return nil
+}
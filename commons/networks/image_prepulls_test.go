@@ -0,0 +1,43 @@
+package networks
+
+import (
+	"errors"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestPrePullingImagesOntoEveryHost(t *testing.T) {
+	localDockerManager := docker.NewFakeDockerManager()
+	remoteDockerManager := docker.NewFakeDockerManager()
+	builder := NewServiceNetworkBuilder(testLog, localDockerManager, testNetworkName, nil, "test", "/foo/bar", "")
+	builder.AddRemoteDockerHost(remoteDockerManager)
+	assert.NilError(t, builder.AddConfiguration(testConfigurationId0, "image-a", getTestInitializerCore(), getTestCheckerCore()))
+	assert.NilError(t, builder.AddConfiguration(testConfigurationId1, "image-b", getTestInitializerCore(), getTestCheckerCore()))
+	assert.NilError(t, builder.AddConfiguration("test-configuration-2", "image-a", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+
+	assert.NilError(t, network.PrePullImages())
+	for _, dockerManager := range []*docker.FakeDockerManager{localDockerManager, remoteDockerManager} {
+		pulledImages := make(map[string]int)
+		for _, call := range dockerManager.GetCalls() {
+			assert.Equal(t, "PullImageIfMissing", call.Method)
+			pulledImages[call.Target]++
+		}
+		assert.DeepEqual(t, map[string]int{"image-a": 1, "image-b": 1}, pulledImages)
+	}
+}
+
+func TestPrePullingImagesFailure(t *testing.T) {
+	dockerManager := docker.NewFakeDockerManager()
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, nil, "test", "/foo/bar", "")
+	assert.NilError(t, builder.AddConfiguration(testConfigurationId0, "image-a", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+
+	pullErr := errors.New("registry unreachable")
+	dockerManager.FailNext("PullImageIfMissing", pullErr)
+	err := network.PrePullImages()
+	assert.ErrorContains(t, err, "image-a")
+	assert.Equal(t, pullErr, stacktrace.RootCause(err))
+}
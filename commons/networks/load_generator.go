@@ -0,0 +1,398 @@
+package networks
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	// How long a single load request may take before it's counted as failed, if the config doesn't say otherwise
+	DEFAULT_LOAD_REQUEST_TIMEOUT = 10 * time.Second
+)
+
+/*
+A template for the HTTP requests that a LoadGenerator will send. The path and body are rendered as Go text/templates
+	against a LoadRequestContext, so requests can vary (e.g. "/tx/{{ .RequestNumber }}").
+ */
+type LoadRequestTemplate struct {
+	// The HTTP method of the request (e.g. "GET", "POST")
+	Method string
+
+	// The port on the target service that the request will be sent to
+	Port int
+
+	// The template for the request's path, starting with "/"
+	PathTemplate string
+
+	// The template for the request's body (empty for no body)
+	BodyTemplate string
+
+	// Headers to send with the request
+	Headers map[string]string
+}
+
+/*
+The values that a LoadRequestTemplate's path & body are rendered with.
+ */
+type LoadRequestContext struct {
+	// The service the request is being sent to
+	ServiceId ServiceID
+
+	// The IP address of the service the request is being sent to
+	IpAddr string
+
+	// The number of the request, counting up from 0 across all of the generator's requests
+	RequestNumber int
+}
+
+/*
+Configuration for a LoadGenerator. Requests are sent round-robin across the target services, cycling through the
+	request templates.
+ */
+type LoadGeneratorConfig struct {
+	// The services that requests will be sent to
+	Targets []ServiceID
+
+	// The requests that will be sent
+	Requests []LoadRequestTemplate
+
+	// How many requests will be started per second
+	RequestsPerSecond uint
+
+	// How long load will be generated for before the generator stops of its own accord (0 to run until Stop is called)
+	Duration time.Duration
+
+	// How long a single request may take before it's counted as failed (DEFAULT_LOAD_REQUEST_TIMEOUT if 0)
+	RequestTimeout time.Duration
+}
+
+/*
+Throughput & latency results of a load generation run. Latencies are measured over successful requests only.
+ */
+type LoadResults struct {
+	// How long load was generated for
+	Elapsed time.Duration
+
+	// The number of requests that completed with a non-error status code
+	Successes int
+
+	// The number of requests that errored, timed out, or completed with an error (>= 400) status code
+	Failures int
+
+	// Successful requests per second
+	Throughput float64
+
+	LatencyP50 time.Duration
+	LatencyP90 time.Duration
+	LatencyP99 time.Duration
+	LatencyMax time.Duration
+}
+
+func (results LoadResults) String() string {
+	return fmt.Sprintf(
+		"%v successes, %v failures in %v (%.1f req/s); latency p50=%v p90=%v p99=%v max=%v",
+		results.Successes,
+		results.Failures,
+		results.Elapsed,
+		results.Throughput,
+		results.LatencyP50,
+		results.LatencyP90,
+		results.LatencyP99,
+		results.LatencyMax)
+}
+
+/*
+Generates HTTP load against services in a test network at a fixed rate, recording the throughput & latency achieved, so
+	tests can check how the network performs under load (or combine load with a ChaosMonkey).
+
+NOTE: The target services' IPs are looked up when the generator starts, so the targets shouldn't be restarted or
+	removed while the generator is running.
+ */
+type LoadGenerator struct {
+	// The network containing the target services
+	network *ServiceNetwork
+
+	// The configuration dictating what requests to send, and how often
+	config LoadGeneratorConfig
+
+	// The HTTP client that requests are sent with
+	httpClient *http.Client
+
+	// The parsed path & body templates, parallel to config.Requests
+	pathTemplates []*template.Template
+	bodyTemplates []*template.Template
+
+	// Mutex guarding the results fields below
+	mutex *sync.Mutex
+
+	// When load generation started & stopped
+	startTime time.Time
+	stopTime time.Time
+
+	// Latencies of successful requests
+	latencies []time.Duration
+
+	// The number of failed requests
+	failures int
+
+	// Closed to tell the generator goroutine to stop
+	stopChan chan struct{}
+
+	// Closed by the generator goroutine once it has stopped and all in-flight requests have finished
+	doneChan chan struct{}
+}
+
+/*
+Creates a new load generator that will send requests to services in the given network.
+
+Args:
+	network: The network containing the target services
+	config: The configuration dictating what requests to send, and how often
+ */
+func NewLoadGenerator(network *ServiceNetwork, config LoadGeneratorConfig) *LoadGenerator {
+	// Defensive copies
+	config.Targets = append([]ServiceID{}, config.Targets...)
+	config.Requests = append([]LoadRequestTemplate{}, config.Requests...)
+	if config.RequestTimeout == 0 {
+		config.RequestTimeout = DEFAULT_LOAD_REQUEST_TIMEOUT
+	}
+
+	return &LoadGenerator{
+		network:       network,
+		config:        config,
+		httpClient:    &http.Client{Timeout: config.RequestTimeout},
+		pathTemplates: nil,
+		bodyTemplates: nil,
+		mutex:         &sync.Mutex{},
+		latencies:     []time.Duration{},
+		failures:      0,
+		stopChan:      nil,
+		doneChan:      nil,
+	}
+}
+
+/*
+Starts generating load in a background goroutine. A generator can only be started once.
+ */
+func (generator *LoadGenerator) Start() error {
+	if generator.stopChan != nil {
+		return stacktrace.NewError("Load generator has already been started")
+	}
+	if generator.config.RequestsPerSecond == 0 {
+		return stacktrace.NewError("Load generator requests per second must be positive")
+	}
+	if len(generator.config.Targets) == 0 {
+		return stacktrace.NewError("Load generator must have at least one target service")
+	}
+	if len(generator.config.Requests) == 0 {
+		return stacktrace.NewError("Load generator must have at least one request template")
+	}
+
+	targetIps := make([]string, 0, len(generator.config.Targets))
+	for _, serviceId := range generator.config.Targets {
+		ipAddr, err := generator.network.GetServiceIp(serviceId)
+		if err != nil {
+			return stacktrace.Propagate(err, "Load generator target service %v doesn't exist", serviceId)
+		}
+		targetIps = append(targetIps, ipAddr.String())
+	}
+
+	pathTemplates := []*template.Template{}
+	bodyTemplates := []*template.Template{}
+	for i, request := range generator.config.Requests {
+		pathTemplate, err := template.New("path").Option("missingkey=error").Parse(request.PathTemplate)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred parsing the path template of request #%v", i)
+		}
+		bodyTemplate, err := template.New("body").Option("missingkey=error").Parse(request.BodyTemplate)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred parsing the body template of request #%v", i)
+		}
+		pathTemplates = append(pathTemplates, pathTemplate)
+		bodyTemplates = append(bodyTemplates, bodyTemplate)
+	}
+	generator.pathTemplates = pathTemplates
+	generator.bodyTemplates = bodyTemplates
+
+	generator.mutex.Lock()
+	generator.startTime = time.Now()
+	generator.mutex.Unlock()
+
+	generator.stopChan = make(chan struct{})
+	generator.doneChan = make(chan struct{})
+	go generator.run(targetIps)
+	return nil
+}
+
+/*
+Stops generating load and blocks until all in-flight requests have finished.
+ */
+func (generator *LoadGenerator) Stop() {
+	if generator.stopChan == nil {
+		return
+	}
+	select {
+	case <-generator.stopChan:
+	default:
+		close(generator.stopChan)
+	}
+	<-generator.doneChan
+}
+
+/*
+Blocks until the generator has run for its configured duration and all in-flight requests have finished.
+ */
+func (generator *LoadGenerator) Wait() error {
+	if generator.doneChan == nil {
+		return stacktrace.NewError("Load generator hasn't been started")
+	}
+	if generator.config.Duration == 0 {
+		return stacktrace.NewError("Load generator has no duration, so it won't stop until Stop is called")
+	}
+	<-generator.doneChan
+	return nil
+}
+
+/*
+Gets the results of the load generated so far (which can be called while the generator is still running).
+ */
+func (generator *LoadGenerator) GetResults() LoadResults {
+	generator.mutex.Lock()
+	defer generator.mutex.Unlock()
+
+	var elapsed time.Duration
+	if !generator.stopTime.IsZero() {
+		elapsed = generator.stopTime.Sub(generator.startTime)
+	} else if !generator.startTime.IsZero() {
+		elapsed = time.Since(generator.startTime)
+	}
+
+	sortedLatencies := append([]time.Duration{}, generator.latencies...)
+	sort.Slice(sortedLatencies, func(i, j int) bool {
+		return sortedLatencies[i] < sortedLatencies[j]
+	})
+
+	results := LoadResults{
+		Elapsed:   elapsed,
+		Successes: len(sortedLatencies),
+		Failures:  generator.failures,
+	}
+	if elapsed > 0 {
+		results.Throughput = float64(results.Successes) / elapsed.Seconds()
+	}
+	if len(sortedLatencies) > 0 {
+		results.LatencyP50 = getPercentile(sortedLatencies, 50)
+		results.LatencyP90 = getPercentile(sortedLatencies, 90)
+		results.LatencyP99 = getPercentile(sortedLatencies, 99)
+		results.LatencyMax = sortedLatencies[len(sortedLatencies) - 1]
+	}
+	return results
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (generator *LoadGenerator) run(targetIps []string) {
+	defer close(generator.doneChan)
+
+	inFlightRequests := &sync.WaitGroup{}
+	defer func() {
+		inFlightRequests.Wait()
+		generator.mutex.Lock()
+		generator.stopTime = time.Now()
+		generator.mutex.Unlock()
+	}()
+
+	var durationChan <-chan time.Time
+	if generator.config.Duration > 0 {
+		durationChan = time.After(generator.config.Duration)
+	}
+	ticker := time.NewTicker(time.Second / time.Duration(generator.config.RequestsPerSecond))
+	defer ticker.Stop()
+
+	for requestNumber := 0; ; requestNumber++ {
+		select {
+		case <-generator.stopChan:
+			return
+		case <-durationChan:
+			return
+		case <-ticker.C:
+		}
+
+		targetIdx := requestNumber % len(targetIps)
+		requestIdx := requestNumber % len(generator.config.Requests)
+		requestContext := LoadRequestContext{
+			ServiceId:     generator.config.Targets[targetIdx],
+			IpAddr:        targetIps[targetIdx],
+			RequestNumber: requestNumber,
+		}
+		inFlightRequests.Add(1)
+		go func() {
+			defer inFlightRequests.Done()
+			latency, err := generator.sendRequest(requestIdx, requestContext)
+			generator.recordResult(latency, err)
+		}()
+	}
+}
+
+func (generator *LoadGenerator) sendRequest(requestIdx int, requestContext LoadRequestContext) (time.Duration, error) {
+	request := generator.config.Requests[requestIdx]
+
+	pathBuffer := &bytes.Buffer{}
+	if err := generator.pathTemplates[requestIdx].Execute(pathBuffer, requestContext); err != nil {
+		return 0, stacktrace.Propagate(err, "An error occurred rendering the path of request #%v", requestIdx)
+	}
+	bodyBuffer := &bytes.Buffer{}
+	if err := generator.bodyTemplates[requestIdx].Execute(bodyBuffer, requestContext); err != nil {
+		return 0, stacktrace.Propagate(err, "An error occurred rendering the body of request #%v", requestIdx)
+	}
+
+	// JoinHostPort brackets IPv6 addresses
+	hostPort := net.JoinHostPort(requestContext.IpAddr, strconv.Itoa(request.Port))
+	url := fmt.Sprintf("http://%v%v", hostPort, pathBuffer.String())
+	httpRequest, err := http.NewRequest(strings.ToUpper(request.Method), url, bodyBuffer)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "An error occurred creating request to %v", url)
+	}
+	for key, value := range request.Headers {
+		httpRequest.Header.Set(key, value)
+	}
+
+	startTime := time.Now()
+	response, err := generator.httpClient.Do(httpRequest)
+	latency := time.Since(startTime)
+	if err != nil {
+		return latency, stacktrace.Propagate(err, "An error occurred sending request to %v", url)
+	}
+	response.Body.Close()
+	if response.StatusCode >= http.StatusBadRequest {
+		return latency, stacktrace.NewError("Request to %v returned status %v", url, response.StatusCode)
+	}
+	return latency, nil
+}
+
+func (generator *LoadGenerator) recordResult(latency time.Duration, err error) {
+	generator.mutex.Lock()
+	defer generator.mutex.Unlock()
+	if err != nil {
+		generator.failures++
+		return
+	}
+	generator.latencies = append(generator.latencies, latency)
+}
+
+// Gets the given percentile of the given latencies, which must be sorted and non-empty
+func getPercentile(sortedLatencies []time.Duration, percentile int) time.Duration {
+	idx := (len(sortedLatencies) * percentile + 99) / 100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sortedLatencies[idx]
+}
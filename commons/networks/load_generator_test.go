@@ -0,0 +1,78 @@
+package networks
+
+import (
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadGeneratorSendsRenderedRequests(t *testing.T) {
+	mutex := &sync.Mutex{}
+	receivedPaths := map[string]bool{}
+	receivedBodies := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		mutex.Lock()
+		receivedPaths[request.URL.Path] = true
+		receivedBodies[string(body)] = true
+		mutex.Unlock()
+		if request.URL.Path == "/fail" {
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	serverAddr := server.Listener.Addr().(*net.TCPAddr)
+
+	network := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "").Build()
+	network.serviceNodes[testServiceName] = ServiceNode{IpAddr: serverAddr.IP}
+
+	generator := NewLoadGenerator(network, LoadGeneratorConfig{
+		Targets: []ServiceID{testServiceName},
+		Requests: []LoadRequestTemplate{
+			{Method: "post", Port: serverAddr.Port, PathTemplate: "/tx", BodyTemplate: "{{ .ServiceId }}-{{ .RequestNumber }}"},
+			{Method: "get", Port: serverAddr.Port, PathTemplate: "/fail"},
+		},
+		RequestsPerSecond: 100,
+		Duration:          200 * time.Millisecond,
+	})
+	assert.NilError(t, generator.Start())
+	assert.NilError(t, generator.Wait())
+
+	results := generator.GetResults()
+	assert.Assert(t, results.Successes > 0)
+	assert.Assert(t, results.Failures > 0)
+	assert.Assert(t, results.Throughput > 0)
+	assert.Assert(t, results.LatencyP50 <= results.LatencyMax)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Assert(t, receivedPaths["/tx"])
+	assert.Assert(t, receivedBodies[testServiceName + "-0"])
+	assert.Assert(t, receivedBodies[testServiceName + "-" + strconv.Itoa(2)])
+}
+
+func TestLoadGeneratorRejectsUnknownTargets(t *testing.T) {
+	network := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "").Build()
+	generator := NewLoadGenerator(network, LoadGeneratorConfig{
+		Targets:           []ServiceID{testServiceName},
+		Requests:          []LoadRequestTemplate{{Method: "GET", Port: 80, PathTemplate: "/"}},
+		RequestsPerSecond: 1,
+	})
+	assert.Assert(t, generator.Start() != nil)
+}
+
+func TestPercentiles(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i))
+	}
+	assert.Equal(t, time.Duration(50), getPercentile(latencies, 50))
+	assert.Equal(t, time.Duration(99), getPercentile(latencies, 99))
+	assert.Equal(t, time.Duration(1), getPercentile(latencies[:1], 99))
+}
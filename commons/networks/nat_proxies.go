@@ -0,0 +1,177 @@
+package networks
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// The image that NAT proxies run, which must contain socat & a shell
+	NAT_PROXY_IMAGE = "alpine/socat:1.7.4.4"
+
+	// The suffix of the hostname that a service's NAT proxy can be reached by (e.g. "node1-nat")
+	NAT_PROXY_HOSTNAME_SUFFIX = "-nat"
+
+	natProxyShell = "/bin/sh"
+)
+
+/*
+A package object containing the details of a NAT proxy that sits in front of a service.
+ */
+type natProxy struct {
+	// The proxy's IP address within the test's Docker network (i.e. the service's "external" address)
+	ipAddr net.IP
+
+	// The Docker container ID of the container running the proxy
+	containerId string
+}
+
+/*
+Puts a NAT proxy in front of the service with the given ID: a container at a new IP that forwards each of the service's
+	ports to the service, so that connections made through the proxy reach the service from the proxy's address. This
+	simulates a node behind a NAT, for testing NAT traversal & external address advertisement logic.
+
+Once a service has a NAT proxy, services added afterwards that depend on it are given the proxy's IP in place of the
+	service's own IP.
+
+NOTE: NAT proxies aren't recorded in a persisted network state, so they're lost if the network is rebuilt from one.
+
+Args:
+	serviceId: The ID of the service to put the proxy in front of
+
+Returns:
+	The proxy's IP, which is the service's external address
+ */
+func (network *ServiceNetwork) AddNatProxy(serviceId ServiceID) (net.IP, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	parentCtx := context.Background()
+
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return nil, stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	if network.isHostNetworked(nodeInfo) {
+		return nil, stacktrace.NewError("Cannot put a NAT proxy in front of service ID %v because it runs on the host's network", serviceId)
+	}
+	if _, found := network.natProxies[serviceId]; found {
+		return nil, stacktrace.NewError("Service ID %v already has a NAT proxy", serviceId)
+	}
+	usedPorts := network.configurations[nodeInfo.configurationId].initializerCore.GetUsedPorts()
+	if len(usedPorts) == 0 {
+		return nil, stacktrace.NewError("Cannot put a NAT proxy in front of service ID %v because it doesn't use any ports", serviceId)
+	}
+
+	proxyIp, err := network.freeIpTracker.GetFreeIpAddr()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to allocate static IP for the NAT proxy of service %v", serviceId)
+	}
+
+	network.serviceLog(serviceId).Debugf("Starting NAT proxy for service ID %v at %v...", serviceId, proxyIp)
+	containerId, err := network.dockerManager.CreateAndStartContainer(
+			parentCtx,
+			NAT_PROXY_IMAGE,
+			network.dockerNetworkId,
+			proxyIp,
+			usedPorts,
+			[]string{"-c", getNatProxyScript(nodeInfo.IpAddr, usedPorts)},
+			map[string]string{},
+			map[string]string{},
+			map[string]string{},
+			docker.ContainerOptions{
+				Entrypoint:     []string{natProxyShell},
+				NetworkAliases: []string{string(serviceId) + NAT_PROXY_HOSTNAME_SUFFIX},
+			})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred starting the NAT proxy for service ID %v", serviceId)
+	}
+	network.natProxies[serviceId] = natProxy{
+		ipAddr:      proxyIp,
+		containerId: containerId,
+	}
+	network.serviceLog(serviceId).Debugf("Successfully started NAT proxy for service ID %v in container %v", serviceId, containerId)
+	return proxyIp, nil
+}
+
+/*
+Stops the NAT proxy in front of the service with the given ID, so that the service is only reachable at its own IP.
+ */
+func (network *ServiceNetwork) RemoveNatProxy(serviceId ServiceID, containerStopTimeout time.Duration) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	return network.removeNatProxy(serviceId, containerStopTimeout)
+}
+
+/*
+Gets the address that the service with the given ID is reachable at from the rest of the network: the IP of its NAT
+	proxy if it has one, or the service's own IP otherwise.
+ */
+func (network *ServiceNetwork) GetServiceExternalIp(serviceId ServiceID) (net.IP, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return nil, stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	return network.getExternalIp(serviceId, nodeInfo), nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Stops the NAT proxy in front of the given service; must be called with the network's lock held
+func (network *ServiceNetwork) removeNatProxy(serviceId ServiceID, containerStopTimeout time.Duration) error {
+	proxy, found := network.natProxies[serviceId]
+	if !found {
+		return stacktrace.NewError("Service ID %v doesn't have a NAT proxy", serviceId)
+	}
+
+	network.serviceLog(serviceId).Debugf("Removing NAT proxy of service ID %v...", serviceId)
+	delete(network.natProxies, serviceId)
+	if err := network.dockerManager.StopContainer(context.Background(), proxy.containerId, &containerStopTimeout); err != nil {
+		return stacktrace.Propagate(err, "An error occurred stopping the NAT proxy of service ID %v", serviceId)
+	}
+	network.freeIpTracker.ReleaseIpAddr(proxy.ipAddr)
+	return nil
+}
+
+func (network *ServiceNetwork) getExternalIp(serviceId ServiceID, nodeInfo ServiceNode) net.IP {
+	if proxy, found := network.natProxies[serviceId]; found {
+		return proxy.ipAddr
+	}
+	return nodeInfo.IpAddr
+}
+
+/*
+Gets the shell script a NAT proxy runs, which starts a socat forwarder to the target for each of the given ports (in port
+	order, so the script is deterministic)
+ */
+func getNatProxyScript(targetIp net.IP, usedPorts map[nat.Port]bool) string {
+	sortedPorts := make([]nat.Port, 0, len(usedPorts))
+	for port, _ := range usedPorts {
+		sortedPorts = append(sortedPorts, port)
+	}
+	sort.Slice(sortedPorts, func(i, j int) bool {
+		if sortedPorts[i].Int() != sortedPorts[j].Int() {
+			return sortedPorts[i].Int() < sortedPorts[j].Int()
+		}
+		return sortedPorts[i].Proto() < sortedPorts[j].Proto()
+	})
+
+	forwarders := make([]string, 0, len(sortedPorts))
+	for _, port := range sortedPorts {
+		proto := strings.ToUpper(port.Proto())
+		forwarders = append(forwarders, fmt.Sprintf(
+			"socat %v-LISTEN:%v,fork,reuseaddr %v:%v &",
+			proto,
+			port.Int(),
+			proto,
+			net.JoinHostPort(targetIp.String(), port.Port())))
+	}
+	return strings.Join(append(forwarders, "wait"), " ")
+}
@@ -0,0 +1,46 @@
+package networks
+
+import (
+	"github.com/docker/go-connections/nat"
+	"gotest.tools/v3/assert"
+	"net"
+	"testing"
+)
+
+func TestNatProxyScriptForwardsEachPort(t *testing.T) {
+	usedPorts := map[nat.Port]bool{
+		"9000/udp": true,
+		"8545/tcp": true,
+		"30303/udp": true,
+		"30303/tcp": true,
+	}
+	script := getNatProxyScript(net.ParseIP("172.23.0.5"), usedPorts)
+	expected := "socat TCP-LISTEN:8545,fork,reuseaddr TCP:172.23.0.5:8545 & " +
+		"socat UDP-LISTEN:9000,fork,reuseaddr UDP:172.23.0.5:9000 & " +
+		"socat TCP-LISTEN:30303,fork,reuseaddr TCP:172.23.0.5:30303 & " +
+		"socat UDP-LISTEN:30303,fork,reuseaddr UDP:172.23.0.5:30303 & " +
+		"wait"
+	assert.Equal(t, expected, script)
+}
+
+func TestExternalIpUsesNatProxy(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+	serviceIp := net.ParseIP("172.23.0.2")
+	proxyIp := net.ParseIP("172.23.0.3")
+	network.serviceNodes[testServiceName] = ServiceNode{IpAddr: serviceIp}
+
+	externalIp, err := network.GetServiceExternalIp(testServiceName)
+	assert.NilError(t, err)
+	assert.Assert(t, externalIp.Equal(serviceIp))
+
+	network.natProxies[testServiceName] = natProxy{ipAddr: proxyIp, containerId: "abc"}
+	externalIp, err = network.GetServiceExternalIp(testServiceName)
+	assert.NilError(t, err)
+	assert.Assert(t, externalIp.Equal(proxyIp))
+
+	_, err = network.AddNatProxy(testServiceName)
+	assert.ErrorContains(t, err, "already has a NAT proxy")
+	_, err = network.GetServiceExternalIp("nonexistent")
+	assert.ErrorContains(t, err, "No service")
+}
@@ -0,0 +1,204 @@
+package networks
+
+import (
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"sync"
+	"time"
+)
+
+/*
+Boots a new network for a NetworkPool, e.g. by building it & adding (and waiting on) its initial services.
+ */
+type NetworkFactory func() (*ServiceNetwork, error)
+
+/*
+Returns a network leased from a NetworkPool to its initial state (e.g. reviving killed services, healing partitions, and
+	removing services the lessee added), so that the next lessee gets a network indistinguishable from a fresh one.
+ */
+type NetworkResetter func(network *ServiceNetwork) error
+
+/*
+A pool of pre-booted networks with the same topology, which are leased out & returned so that the cost of booting a
+	network is paid once per network rather than once per use.
+
+NOTE: Each of the suite's tests runs in its own controller container, with its own Docker network, so a pool only lives as
+	long as the process that created it: it amortizes startup across the scenarios run by a single process (e.g. a test that
+	runs many sub-scenarios against the same topology), not across the suite's tests.
+
+NOTE: This is thread-safe!
+ */
+type NetworkPool struct {
+	mutex *sync.Mutex
+
+	// Boots new networks
+	factory NetworkFactory
+
+	// Returns leased networks to their initial state
+	resetter NetworkResetter
+
+	// How long to wait for each container to stop when removing a network
+	containerStopTimeout time.Duration
+
+	// Networks that are ready to be leased
+	idleNetworks []*ServiceNetwork
+
+	// A "set" of the networks that are currently leased out
+	leasedNetworks map[*ServiceNetwork]bool
+
+	// True once the pool has been closed
+	isClosed bool
+}
+
+/*
+Creates a new, empty pool.
+
+Args:
+	factory: Boots the pool's networks
+	resetter: Returns networks to their initial state when they're returned to the pool
+	containerStopTimeout: How long to wait for each container to stop when removing a network from the pool
+ */
+func NewNetworkPool(factory NetworkFactory, resetter NetworkResetter, containerStopTimeout time.Duration) *NetworkPool {
+	return &NetworkPool{
+		mutex:                &sync.Mutex{},
+		factory:              factory,
+		resetter:             resetter,
+		containerStopTimeout: containerStopTimeout,
+		idleNetworks:         []*ServiceNetwork{},
+		leasedNetworks:       make(map[*ServiceNetwork]bool),
+		isClosed:             false,
+	}
+}
+
+/*
+Boots networks, in parallel, until the pool has the given number of idle networks, so that later leases don't have to wait
+	for networks to boot.
+ */
+func (pool *NetworkPool) Fill(numIdleNetworks int) error {
+	pool.mutex.Lock()
+	if pool.isClosed {
+		pool.mutex.Unlock()
+		return stacktrace.NewError("Cannot fill a closed network pool")
+	}
+	numToBoot := numIdleNetworks - len(pool.idleNetworks)
+	pool.mutex.Unlock()
+	if numToBoot <= 0 {
+		return nil
+	}
+
+	resultsMutex := &sync.Mutex{}
+	bootedNetworks := []*ServiceNetwork{}
+	var resultErr error = nil
+	waitGroup := &sync.WaitGroup{}
+	for i := 0; i < numToBoot; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			network, err := pool.factory()
+			resultsMutex.Lock()
+			defer resultsMutex.Unlock()
+			if err != nil {
+				resultErr = stacktrace.Propagate(err, "An error occurred booting a network for the pool")
+				return
+			}
+			bootedNetworks = append(bootedNetworks, network)
+		}()
+	}
+	waitGroup.Wait()
+
+	// We keep the networks that did boot, even if others failed
+	for _, network := range bootedNetworks {
+		pool.addIdleNetwork(network)
+	}
+	return resultErr
+}
+
+/*
+Leases a network from the pool, booting a new one if no idle networks are available. The network must be given back with
+	Return when the lessee is done with it.
+ */
+func (pool *NetworkPool) Lease() (*ServiceNetwork, error) {
+	pool.mutex.Lock()
+	if pool.isClosed {
+		pool.mutex.Unlock()
+		return nil, stacktrace.NewError("Cannot lease a network from a closed network pool")
+	}
+	if numIdleNetworks := len(pool.idleNetworks); numIdleNetworks > 0 {
+		network := pool.idleNetworks[numIdleNetworks - 1]
+		pool.idleNetworks = pool.idleNetworks[:numIdleNetworks - 1]
+		pool.leasedNetworks[network] = true
+		pool.mutex.Unlock()
+		return network, nil
+	}
+	pool.mutex.Unlock()
+
+	// Booting can take a while, so we don't hold the lock while doing so
+	network, err := pool.factory()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred booting a network to lease")
+	}
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pool.leasedNetworks[network] = true
+	return network, nil
+}
+
+/*
+Gives a leased network back to the pool, resetting it so it can be leased again. If the reset fails, the network is
+	removed rather than returned to the pool (so a broken network is never leased out), and the reset's error is returned.
+ */
+func (pool *NetworkPool) Return(network *ServiceNetwork) error {
+	pool.mutex.Lock()
+	if !pool.leasedNetworks[network] {
+		pool.mutex.Unlock()
+		return stacktrace.NewError("Cannot return a network that wasn't leased from this pool")
+	}
+	delete(pool.leasedNetworks, network)
+	pool.mutex.Unlock()
+
+	if err := pool.resetter(network); err != nil {
+		pool.removeNetwork(network)
+		return stacktrace.Propagate(err, "An error occurred resetting the returned network, so it was removed instead")
+	}
+	pool.addIdleNetwork(network)
+	return nil
+}
+
+/*
+Closes the pool, removing its idle networks. Networks that are still leased are removed when they're returned.
+ */
+func (pool *NetworkPool) Close() error {
+	pool.mutex.Lock()
+	pool.isClosed = true
+	idleNetworks := pool.idleNetworks
+	pool.idleNetworks = []*ServiceNetwork{}
+	pool.mutex.Unlock()
+
+	for _, network := range idleNetworks {
+		pool.removeNetwork(network)
+	}
+	return nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Adds the given network to the idle networks, unless the pool has been closed (in which case the network is removed).
+ */
+func (pool *NetworkPool) addIdleNetwork(network *ServiceNetwork) {
+	pool.mutex.Lock()
+	if !pool.isClosed {
+		pool.idleNetworks = append(pool.idleNetworks, network)
+		pool.mutex.Unlock()
+		return
+	}
+	pool.mutex.Unlock()
+	pool.removeNetwork(network)
+}
+
+// Makes a best-effort attempt to remove the given network's services
+func (pool *NetworkPool) removeNetwork(network *ServiceNetwork) {
+	if err := network.RemoveAll(pool.containerStopTimeout); err != nil {
+		network.log.Error("The following error occurred removing a network from the pool:")
+		fmt.Fprintln(network.log.Logger.Out, err)
+	}
+}
@@ -0,0 +1,91 @@
+package networks
+
+import (
+	"errors"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Gets a factory of empty networks, along with a counter of how many networks it has booted
+func getTestNetworkFactory() (NetworkFactory, func() int) {
+	mutex := &sync.Mutex{}
+	numBooted := 0
+	factory := func() (*ServiceNetwork, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		numBooted++
+		builder := NewServiceNetworkBuilder(testLog, docker.NewFakeDockerManager(), testNetworkName, nil, "test", "/foo/bar", "")
+		return builder.Build(), nil
+	}
+	getNumBooted := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return numBooted
+	}
+	return factory, getNumBooted
+}
+
+func TestLeasingAndReturningPooledNetworks(t *testing.T) {
+	factory, getNumBooted := getTestNetworkFactory()
+	numResets := 0
+	resetter := func(network *ServiceNetwork) error {
+		numResets++
+		return nil
+	}
+	pool := NewNetworkPool(factory, resetter, time.Second)
+	assert.NilError(t, pool.Fill(2))
+	assert.Equal(t, 2, getNumBooted())
+
+	network1, err := pool.Lease()
+	assert.NilError(t, err)
+	network2, err := pool.Lease()
+	assert.NilError(t, err)
+	assert.Assert(t, network1 != network2)
+	assert.Equal(t, 2, getNumBooted())
+
+	// The pool is empty, so a new network is booted
+	network3, err := pool.Lease()
+	assert.NilError(t, err)
+	assert.Equal(t, 3, getNumBooted())
+
+	// Returned networks are reset & reused
+	assert.NilError(t, pool.Return(network1))
+	assert.Equal(t, 1, numResets)
+	reusedNetwork, err := pool.Lease()
+	assert.NilError(t, err)
+	assert.Assert(t, reusedNetwork == network1)
+	assert.Equal(t, 3, getNumBooted())
+
+	foreignNetwork, err := factory()
+	assert.NilError(t, err)
+	assert.ErrorContains(t, pool.Return(foreignNetwork), "wasn't leased")
+	assert.NilError(t, pool.Return(network1))
+	assert.ErrorContains(t, pool.Return(network1), "wasn't leased")
+
+	assert.NilError(t, pool.Close())
+	_, err = pool.Lease()
+	assert.ErrorContains(t, err, "closed")
+	assert.NilError(t, pool.Return(network2))
+	assert.NilError(t, pool.Return(network3))
+}
+
+func TestFailedResetsDiscardNetworks(t *testing.T) {
+	factory, getNumBooted := getTestNetworkFactory()
+	resetter := func(network *ServiceNetwork) error {
+		return errors.New("reset failed")
+	}
+	pool := NewNetworkPool(factory, resetter, time.Second)
+
+	network, err := pool.Lease()
+	assert.NilError(t, err)
+	assert.ErrorContains(t, pool.Return(network), "reset")
+
+	// The broken network isn't leased out again
+	newNetwork, err := pool.Lease()
+	assert.NilError(t, err)
+	assert.Assert(t, newNetwork != network)
+	assert.Equal(t, 2, getNumBooted())
+}
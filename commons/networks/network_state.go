@@ -0,0 +1,248 @@
+package networks
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+)
+
+const (
+	// The Docker state of a container that's running
+	RUNNING_CONTAINER_STATE = "running"
+
+	networkStateFilePerms = 0644
+)
+
+/*
+The state of a single service, as persisted to disk so that a later process can re-attach to it.
+ */
+type PersistedServiceState struct {
+	// The ID of the configuration the service was created from
+	ConfigurationId ConfigurationID
+
+	// The ID of the Docker container running the service
+	ContainerId string
+
+	// The service's IP address within the Docker network
+	IpAddr string
+
+	// The ports that the service's container listens on (e.g. "8080/tcp")
+	Ports []string
+
+	// The IDs of the services that this service depends on
+	DependencyIds []ServiceID
+
+	// The Docker host the service runs on: 0 for the controller's host, or i for the i-th remote host added to the builder
+	DockerHostIdx int
+}
+
+/*
+The state of a test network, as persisted to disk so that a later process can re-attach to the still-running network
+	rather than recreating it (e.g. for iterating locally against a long-lived topology).
+ */
+type NetworkState struct {
+	// The ID of the Docker network that the network's services run in
+	DockerNetworkId string
+
+	// The name of the Docker volume mounted on every service
+	TestVolume string
+
+	// Mapping of service ID -> persisted service state
+	Services map[ServiceID]PersistedServiceState
+}
+
+/*
+Gets the current state of the network, which can be used to re-attach to the network from another process.
+ */
+func (network *ServiceNetwork) GetState() NetworkState {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	return network.getState()
+}
+
+// Gets the current state of the network; must be called with the network's lock held
+func (network *ServiceNetwork) getState() NetworkState {
+	servicesState := make(map[ServiceID]PersistedServiceState)
+	for serviceId, node := range network.serviceNodes {
+		ports := []string{}
+		if config, found := network.configurations[node.configurationId]; found {
+			for port, _ := range config.initializerCore.GetUsedPorts() {
+				ports = append(ports, string(port))
+			}
+		}
+		sort.Strings(ports)
+
+		dependencyIds := make([]ServiceID, len(node.dependencyIds))
+		copy(dependencyIds, node.dependencyIds)
+
+		servicesState[serviceId] = PersistedServiceState{
+			ConfigurationId: node.configurationId,
+			ContainerId:     node.ContainerId,
+			IpAddr:          node.IpAddr.String(),
+			Ports:           ports,
+			DependencyIds:   dependencyIds,
+			DockerHostIdx:   node.dockerHostIdx,
+		}
+	}
+	return NetworkState{
+		DockerNetworkId: network.dockerNetworkId,
+		TestVolume:      network.testVolume,
+		Services:        servicesState,
+	}
+}
+
+/*
+Writes the current state of the network to the given file as JSON, replacing the file atomically so that a crash midway
+	through never leaves a corrupt state file behind.
+ */
+func (network *ServiceNetwork) SaveState(filepath string) error {
+	stateJson, err := json.MarshalIndent(network.GetState(), "", "  ")
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred serializing the network state")
+	}
+	tempFilepath := filepath + ".tmp"
+	if err := ioutil.WriteFile(tempFilepath, stateJson, networkStateFilePerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the network state to temporary file %v", tempFilepath)
+	}
+	if err := os.Rename(tempFilepath, filepath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred moving the network state file into place at %v", filepath)
+	}
+	return nil
+}
+
+/*
+Reads network state previously written with ServiceNetwork.SaveState.
+ */
+func LoadNetworkState(filepath string) (NetworkState, error) {
+	stateJson, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return NetworkState{}, stacktrace.Propagate(err, "An error occurred reading network state file %v", filepath)
+	}
+	var state NetworkState
+	if err := json.Unmarshal(stateJson, &state); err != nil {
+		return NetworkState{}, stacktrace.Propagate(err, "An error occurred parsing network state file %v", filepath)
+	}
+	if state.Services == nil {
+		state.Services = make(map[ServiceID]PersistedServiceState)
+	}
+	return state, nil
+}
+
+/*
+Constructs a ServiceNetwork that re-attaches to the still-running services described by the given state, rather than
+	creating new ones. The builder must have been created with the same Docker network and test volume as the network
+	whose state was saved, and must have all the configurations that the state's services were created from.
+
+Args:
+	state: The state of the network to re-attach to, as saved by ServiceNetwork.SaveState
+
+Returns:
+	The re-attached network
+	A mapping of service ID -> availability checker, for checking when each re-attached service is available
+ */
+func (builder ServiceNetworkBuilder) BuildFromState(state NetworkState) (*ServiceNetwork, map[ServiceID]*services.ServiceAvailabilityChecker, error) {
+	if state.DockerNetworkId != builder.dockerNetworkId {
+		return nil, nil, stacktrace.NewError(
+			"Network state is for Docker network %v, but the builder is for Docker network %v",
+			state.DockerNetworkId,
+			builder.dockerNetworkId)
+	}
+	if state.TestVolume != builder.testVolume {
+		return nil, nil, stacktrace.NewError(
+			"Network state is for test volume %v, but the builder is for test volume %v",
+			state.TestVolume,
+			builder.testVolume)
+	}
+
+	network := builder.Build()
+	parentCtx := context.Background()
+
+	// Sorted so that errors & timeline events are deterministic
+	serviceIds := []ServiceID{}
+	for serviceId, _ := range state.Services {
+		serviceIds = append(serviceIds, serviceId)
+	}
+	sort.Slice(serviceIds, func(i, j int) bool {
+		return serviceIds[i] < serviceIds[j]
+	})
+
+	for _, serviceId := range serviceIds {
+		serviceState := state.Services[serviceId]
+		config, found := network.configurations[serviceState.ConfigurationId]
+		if !found {
+			return nil, nil, stacktrace.NewError(
+				"Service %v was created from configuration %v, which the builder doesn't have",
+				serviceId,
+				serviceState.ConfigurationId)
+		}
+		ipAddr := net.ParseIP(serviceState.IpAddr)
+		if ipAddr == nil {
+			return nil, nil, stacktrace.NewError("Service %v has invalid IP address '%v'", serviceId, serviceState.IpAddr)
+		}
+
+		if serviceState.DockerHostIdx < 0 || serviceState.DockerHostIdx > len(network.remoteDockerManagers) {
+			return nil, nil, stacktrace.NewError(
+				"Service %v runs on Docker host #%v, but the builder only has %v remote Docker hosts",
+				serviceId,
+				serviceState.DockerHostIdx,
+				len(network.remoteDockerManagers))
+		}
+		dockerManager := network.getDockerManager(ServiceNode{dockerHostIdx: serviceState.DockerHostIdx})
+		containerStatus, err := dockerManager.GetContainerStatus(parentCtx, serviceState.ContainerId)
+		if err != nil {
+			return nil, nil, stacktrace.Propagate(err, "An error occurred getting the status of service %v's container", serviceId)
+		}
+		if containerStatus.State != RUNNING_CONTAINER_STATE {
+			return nil, nil, stacktrace.NewError(
+				"Can't re-attach to service %v because its container %v is %v rather than %v",
+				serviceId,
+				serviceState.ContainerId,
+				containerStatus.State,
+				RUNNING_CONTAINER_STATE)
+		}
+
+		// Host-networked services use the gateway's IP, which was never the tracker's to give out
+		if !config.containerOptions.UseHostNetwork {
+			if err := network.freeIpTracker.TakeIpAddr(ipAddr); err != nil {
+				return nil, nil, stacktrace.Propagate(err, "An error occurred reserving IP %v for service %v", ipAddr, serviceId)
+			}
+		}
+
+		network.serviceNodes[serviceId] = ServiceNode{
+			IpAddr:          ipAddr,
+			Service:         config.initializerCore.GetServiceFromIp(ipAddr.String()),
+			ContainerId:     serviceState.ContainerId,
+			configurationId: serviceState.ConfigurationId,
+			dependencies:    nil,
+			dependencyIds:   serviceState.DependencyIds,
+			dockerHostIdx:   serviceState.DockerHostIdx,
+		}
+	}
+
+	// Dependencies can only be resolved once every service has been re-attached
+	availabilityCheckers := make(map[ServiceID]*services.ServiceAvailabilityChecker)
+	for _, serviceId := range serviceIds {
+		node := network.serviceNodes[serviceId]
+		dependencyServices := make([]services.Service, 0, len(node.dependencyIds))
+		for _, dependencyId := range node.dependencyIds {
+			dependencyNode, found := network.serviceNodes[dependencyId]
+			if !found {
+				return nil, nil, stacktrace.NewError("Service %v depends on service %v, which isn't in the network state", serviceId, dependencyId)
+			}
+			dependencyServices = append(dependencyServices, dependencyNode.Service)
+		}
+		node.dependencies = dependencyServices
+		network.serviceNodes[serviceId] = node
+		network.timeline.record(SERVICE_REATTACHED, serviceId)
+		network.serviceLog(serviceId).Debugf("Re-attached to service ID %v in container %v", serviceId, node.ContainerId)
+
+		config := network.configurations[node.configurationId]
+		availabilityCheckers[serviceId] = network.newAvailabilityChecker(parentCtx, serviceId, config, node)
+	}
+	return network, availabilityCheckers, nil
+}
@@ -0,0 +1,63 @@
+package networks
+
+import (
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestNetworkStateRoundTrip(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+	network.serviceNodes["bootstrap"] = ServiceNode{
+		IpAddr:          net.ParseIP("172.23.0.2"),
+		ContainerId:     "container1",
+		configurationId: testConfiguration,
+		dependencyIds:   []ServiceID{},
+	}
+	network.serviceNodes["node"] = ServiceNode{
+		IpAddr:          net.ParseIP("172.23.0.3"),
+		ContainerId:     "container2",
+		configurationId: testConfiguration,
+		dependencyIds:   []ServiceID{"bootstrap"},
+	}
+
+	tempDirpath, err := ioutil.TempDir("", "network-state")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tempDirpath)
+	stateFilepath := path.Join(tempDirpath, "network.json")
+
+	assert.NilError(t, network.SaveState(stateFilepath))
+	state, err := LoadNetworkState(stateFilepath)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, network.GetState(), state)
+	assert.Equal(t, testNetworkName, state.DockerNetworkId)
+	assert.Equal(t, "container2", state.Services["node"].ContainerId)
+	assert.Equal(t, "172.23.0.3", state.Services["node"].IpAddr)
+	assert.DeepEqual(t, []ServiceID{"bootstrap"}, state.Services["node"].DependencyIds)
+}
+
+func TestBuildingFromMismatchedState(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	_, _, err := builder.BuildFromState(NetworkState{DockerNetworkId: "other-network", TestVolume: "test"})
+	assert.Assert(t, err != nil)
+	_, _, err = builder.BuildFromState(NetworkState{DockerNetworkId: testNetworkName, TestVolume: "other-volume"})
+	assert.Assert(t, err != nil)
+}
+
+func TestBuildingFromStateWithUnknownConfiguration(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	state := NetworkState{
+		DockerNetworkId: testNetworkName,
+		TestVolume:      "test",
+		Services: map[ServiceID]PersistedServiceState{
+			testServiceName: {ConfigurationId: "nonexistent", IpAddr: "172.23.0.2"},
+		},
+	}
+	_, _, err := builder.BuildFromState(state)
+	assert.Assert(t, err != nil)
+}
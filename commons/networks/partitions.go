@@ -0,0 +1,114 @@
+package networks
+
+import (
+	"context"
+	"github.com/palantir/stacktrace"
+)
+
+/*
+Cuts the given services off from the rest of the test network by disconnecting their containers from the Docker network,
+	simulating a network partition. Isolated services can neither reach nor be reached by any other service (including
+	each other) until HealPartition is called.
+
+Args:
+	serviceIds: A "set" of the IDs of the services to isolate
+ */
+func (network *ServiceNetwork) PartitionServices(serviceIds map[ServiceID]bool) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	parentCtx := context.Background()
+
+	for serviceId, _ := range serviceIds {
+		nodeInfo, found := network.serviceNodes[serviceId]
+		if !found {
+			return stacktrace.NewError("Cannot partition service ID %v because no service with this ID exists", serviceId)
+		}
+		if network.isHostNetworked(nodeInfo) {
+			return stacktrace.NewError("Cannot partition service ID %v because it runs on the host's network", serviceId)
+		}
+	}
+
+	for serviceId, _ := range serviceIds {
+		if network.partitionedServices[serviceId] {
+			continue
+		}
+		nodeInfo := network.serviceNodes[serviceId]
+		network.serviceLog(serviceId).Debugf("Partitioning service ID %v off from the network...", serviceId)
+		if err := network.getDockerManager(nodeInfo).DisconnectContainerFromNetwork(parentCtx, network.dockerNetworkId, nodeInfo.ContainerId); err != nil {
+			return stacktrace.Propagate(err, "An error occurred partitioning service ID %v off from the network", serviceId)
+		}
+		network.partitionedServices[serviceId] = true
+		network.timeline.record(SERVICE_PARTITIONED, serviceId)
+	}
+	return nil
+}
+
+/*
+Heals any partition created with PartitionServices, reconnecting every isolated service to the test network with the
+	same IP & hostnames it had before.
+ */
+func (network *ServiceNetwork) HealPartition() error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	parentCtx := context.Background()
+
+	for serviceId, _ := range network.partitionedServices {
+		nodeInfo, found := network.serviceNodes[serviceId]
+		if !found {
+			// The service was removed while partitioned, so there's nothing to reconnect
+			delete(network.partitionedServices, serviceId)
+			continue
+		}
+		network.serviceLog(serviceId).Debugf("Reconnecting partitioned service ID %v to the network...", serviceId)
+		aliases := getNetworkAliases(serviceId, network.configurations[nodeInfo.configurationId])
+		if err := network.getDockerManager(nodeInfo).ConnectContainerToNetwork(parentCtx, network.dockerNetworkId, nodeInfo.ContainerId, nodeInfo.IpAddr, aliases); err != nil {
+			return stacktrace.Propagate(err, "An error occurred reconnecting partitioned service ID %v to the network", serviceId)
+		}
+		delete(network.partitionedServices, serviceId)
+		network.timeline.record(SERVICE_RECONNECTED, serviceId)
+	}
+	return nil
+}
+
+/*
+Checks, by asking Docker which containers are attached to the test network, whether the two given services can reach each
+	other over the network.
+ */
+func (network *ServiceNetwork) IsReachable(serviceIdA ServiceID, serviceIdB ServiceID) (bool, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	parentCtx := context.Background()
+
+	nodeA, found := network.serviceNodes[serviceIdA]
+	if !found {
+		return false, stacktrace.NewError("No service with ID %v exists in the network", serviceIdA)
+	}
+	nodeB, found := network.serviceNodes[serviceIdB]
+	if !found {
+		return false, stacktrace.NewError("No service with ID %v exists in the network", serviceIdB)
+	}
+
+	isAReachable, err := network.isAttachedToNetwork(parentCtx, nodeA)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred checking whether service ID %v is attached to the network", serviceIdA)
+	}
+	isBReachable, err := network.isAttachedToNetwork(parentCtx, nodeB)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred checking whether service ID %v is attached to the network", serviceIdB)
+	}
+	return isAReachable && isBReachable, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (network *ServiceNetwork) isAttachedToNetwork(ctx context.Context, nodeInfo ServiceNode) (bool, error) {
+	// Host-networked services can't be partitioned, so they're always reachable through the gateway
+	if network.isHostNetworked(nodeInfo) {
+		return true, nil
+	}
+	// Docker only knows which of an overlay network's containers are on its own host, so we ask the node's host
+	attachedContainers, err := network.getDockerManager(nodeInfo).GetContainersOnNetwork(ctx, network.dockerNetworkId)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred getting the containers attached to the network")
+	}
+	return attachedContainers[nodeInfo.ContainerId], nil
+}
@@ -0,0 +1,287 @@
+package networks
+
+import (
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// The names that a plan's network & test volume are given, since no real ones exist
+	planNetworkName = "plan-network"
+	planTestVolume  = "plan-volume"
+)
+
+/*
+A service that a network loader would add to its network, as worked out by Plan.
+ */
+type PlannedService struct {
+	ServiceId ServiceID
+
+	ConfigurationId ConfigurationID
+
+	DockerImage string
+
+	// The IP the service would have in the test network (which depends on the subnet the plan was made with)
+	IpAddr net.IP
+
+	// The ports the service uses, sorted
+	Ports []string
+
+	// True if any of the service's ports would be published to the Docker host
+	PublishPorts bool
+
+	// The ports that would be published to the Docker host, sorted
+	PublishedPorts []string
+
+	// True if the service would run on the host's network
+	UseHostNetwork bool
+
+	// The IDs of the services that the service depends on
+	DependencyIds []ServiceID
+
+	// The start command the service's container would run
+	StartCmd []string
+
+	// The entrypoint the service's container would run its start command with, or empty for the image's default
+	Entrypoint []string
+
+	// Mapping of env_variable_name -> value, for the env variables the service's container would have set (other than
+	//  those holding secrets, so that plans can be shared)
+	EnvVariables map[string]string
+
+	// The names of the env variables that the service's container would get secrets as (see docker.Secret), sorted
+	SecretEnvVariables []string
+
+	// True if the loader would start the service, rather than leaving it created but unstarted (see CreateService)
+	Started bool
+}
+
+/*
+What a network loader would create when initializing its network, as worked out by Plan.
+ */
+type NetworkPlan struct {
+	// The Docker images that would be pulled, sorted
+	Images []string
+
+	// The services that would be added, in the order they'd be started (followed by any that would be left unstarted)
+	Services []PlannedService
+}
+
+/*
+Works out what the given network loader would create when initializing its network, without touching Docker, so that
+	generated configurations can be validated (e.g. in a CI gate) before anything runs. The loader's ConfigureNetwork &
+	InitializeNetwork are run against a docker.FakeDockerManager, so any error they'd return when setting up the network
+	(e.g. an invalid configuration or an unknown dependency) is returned here.
+
+NOTE: InitializeNetwork must not wait for services to become available (it should return their availability checkers for
+	the framework to wait on instead), because no services actually run.
+
+Args:
+	log: The log entry that the planned network will write its log messages to
+	networkLoader: The loader whose network should be planned
+	subnetMask: The subnet that the test network would use, which the planned IPs are picked from
+
+Returns:
+	The plan, which can be printed with its String method
+ */
+func Plan(log *logrus.Entry, networkLoader NetworkLoader, subnetMask string) (*NetworkPlan, error) {
+	freeIpTracker, err := NewFreeIpAddrTracker(log.Logger, subnetMask, map[string]bool{})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the IP tracker for subnet %v", subnetMask)
+	}
+	// Host-networked services are reached through the network's gateway, so we give the fake network one
+	gatewayIp, err := freeIpTracker.GetFreeIpAddr()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred picking the gateway IP of subnet %v", subnetMask)
+	}
+	dockerManager := docker.NewFakeDockerManager()
+	dockerManager.SetNetworkGatewayIp(planNetworkName, gatewayIp)
+
+	// Service initializers write the files that services mount, so they need somewhere to write them
+	testVolumeControllerDirpath, err := ioutil.TempDir("", planTestVolume)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating a temporary directory to stand in for the test volume")
+	}
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	builder := NewServiceNetworkBuilder(log, dockerManager, planNetworkName, freeIpTracker, planTestVolume, testVolumeControllerDirpath, "")
+	if err := networkLoader.ConfigureNetwork(builder); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred configuring the network")
+	}
+	network := builder.Build()
+	if _, err := networkLoader.InitializeNetwork(network); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred initializing the network")
+	}
+	return getNetworkPlan(network, dockerManager), nil
+}
+
+/*
+Renders the plan for printing, e.g.:
+
+	Images to pull:
+	  my-node:1.0
+	Services, in start order:
+	  1. bootstrap (configuration node) at 172.23.0.3
+	       Image:      my-node:1.0
+	       Ports:      8545/tcp
+	       Depends on: -
+	       Command:    --bootstrap
+ */
+func (plan NetworkPlan) String() string {
+	builder := &strings.Builder{}
+	fmt.Fprintln(builder, "Images to pull:")
+	for _, image := range plan.Images {
+		fmt.Fprintf(builder, "  %v\n", image)
+	}
+	fmt.Fprintln(builder, "Services, in start order:")
+	for i, service := range plan.Services {
+		location := fmt.Sprintf("at %v", service.IpAddr)
+		if service.UseHostNetwork {
+			location = "on the host's network"
+		}
+		fmt.Fprintf(builder, "  %v. %v (configuration %v) %v", i + 1, service.ServiceId, service.ConfigurationId, location)
+		if !service.Started {
+			fmt.Fprint(builder, " [created but not started]")
+		}
+		fmt.Fprintln(builder)
+
+		ports := strings.Join(service.Ports, ", ")
+		if len(service.Ports) == 0 {
+			ports = "-"
+		} else if service.PublishPorts && !service.UseHostNetwork {
+			if len(service.PublishedPorts) == len(service.Ports) {
+				ports += " (published to the host)"
+			} else {
+				ports += fmt.Sprintf(" (%v published to the host)", strings.Join(service.PublishedPorts, ", "))
+			}
+		}
+		dependencyIds := make([]string, 0, len(service.DependencyIds))
+		for _, dependencyId := range service.DependencyIds {
+			dependencyIds = append(dependencyIds, string(dependencyId))
+		}
+		dependencies := strings.Join(dependencyIds, ", ")
+		if len(dependencyIds) == 0 {
+			dependencies = "-"
+		}
+		fmt.Fprintf(builder, "       Image:      %v\n", service.DockerImage)
+		fmt.Fprintf(builder, "       Ports:      %v\n", ports)
+		fmt.Fprintf(builder, "       Depends on: %v\n", dependencies)
+		fmt.Fprintf(builder, "       Command:    %v\n", strings.Join(service.StartCmd, " "))
+	}
+	return builder.String()
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets the plan of a network that was initialized against the given fake, using the calls made against the fake to work
+	out which images would be pulled & which order services would be started in.
+ */
+func getNetworkPlan(network *ServiceNetwork, dockerManager *docker.FakeDockerManager) *NetworkPlan {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	serviceIdsByContainerId := make(map[string]ServiceID)
+	for serviceId, nodeInfo := range network.serviceNodes {
+		serviceIdsByContainerId[nodeInfo.ContainerId] = serviceId
+	}
+
+	images := make(map[string]bool)
+	startedServiceIds := []ServiceID{}
+	for _, call := range dockerManager.GetCalls() {
+		switch call.Method {
+		case "CreateContainer", "CreateAndStartContainer":
+			images[call.Target] = true
+		case "StartContainer":
+			if serviceId, found := serviceIdsByContainerId[call.Target]; found {
+				startedServiceIds = append(startedServiceIds, serviceId)
+			}
+		}
+	}
+
+	result := &NetworkPlan{
+		Images:   []string{},
+		Services: []PlannedService{},
+	}
+	for image, _ := range images {
+		result.Images = append(result.Images, image)
+	}
+	sort.Strings(result.Images)
+
+	isStarted := make(map[ServiceID]bool)
+	for _, serviceId := range startedServiceIds {
+		isStarted[serviceId] = true
+		result.Services = append(result.Services, network.getPlannedService(dockerManager, serviceId, true))
+	}
+	unstartedServiceIds := []ServiceID{}
+	for serviceId, _ := range network.serviceNodes {
+		if !isStarted[serviceId] {
+			unstartedServiceIds = append(unstartedServiceIds, serviceId)
+		}
+	}
+	sort.Slice(unstartedServiceIds, func(i, j int) bool {
+		return unstartedServiceIds[i] < unstartedServiceIds[j]
+	})
+	for _, serviceId := range unstartedServiceIds {
+		result.Services = append(result.Services, network.getPlannedService(dockerManager, serviceId, false))
+	}
+	return result
+}
+
+// Must be called with the network's lock held
+func (network *ServiceNetwork) getPlannedService(dockerManager *docker.FakeDockerManager, serviceId ServiceID, started bool) PlannedService {
+	nodeInfo := network.serviceNodes[serviceId]
+	config := network.configurations[nodeInfo.configurationId]
+
+	ports := []string{}
+	for port, _ := range config.initializerCore.GetUsedPorts() {
+		ports = append(ports, string(port))
+	}
+	sort.Strings(ports)
+	publishedPorts := []string{}
+	for port, _ := range config.containerOptions.GetPublishedPorts(config.initializerCore.GetUsedPorts()) {
+		publishedPorts = append(publishedPorts, string(port))
+	}
+	sort.Strings(publishedPorts)
+
+	dependencyIds := append([]ServiceID{}, nodeInfo.dependencyIds...)
+	sort.Slice(dependencyIds, func(i, j int) bool {
+		return dependencyIds[i] < dependencyIds[j]
+	})
+
+	container, _ := dockerManager.GetContainer(nodeInfo.ContainerId)
+	envVariables := make(map[string]string)
+	for name, value := range container.EnvVariables {
+		envVariables[name] = value
+	}
+	secretEnvVariables := []string{}
+	for _, secret := range container.Options.Secrets {
+		if secret.EnvVariable != "" {
+			delete(envVariables, secret.EnvVariable)
+			secretEnvVariables = append(secretEnvVariables, secret.EnvVariable)
+		}
+	}
+	sort.Strings(secretEnvVariables)
+	return PlannedService{
+		ServiceId:          serviceId,
+		ConfigurationId:    nodeInfo.configurationId,
+		DockerImage:        config.dockerImage,
+		IpAddr:             nodeInfo.IpAddr,
+		Ports:              ports,
+		PublishPorts:       len(publishedPorts) > 0,
+		PublishedPorts:     publishedPorts,
+		UseHostNetwork:     config.containerOptions.UseHostNetwork,
+		DependencyIds:      dependencyIds,
+		StartCmd:           container.StartCmdArgs,
+		Entrypoint:         container.Options.Entrypoint,
+		EnvVariables:       envVariables,
+		SecretEnvVariables: secretEnvVariables,
+		Started:            started,
+	}
+}
@@ -0,0 +1,54 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+)
+
+type testPlanNetworkLoader struct {}
+
+func (loader testPlanNetworkLoader) ConfigureNetwork(builder *ServiceNetworkBuilder) error {
+	if err := builder.AddConfiguration(testConfigurationId0, "test-bootstrap", getTestInitializerCore(), getTestCheckerCore()); err != nil {
+		return err
+	}
+	return builder.AddConfiguration(testConfigurationId1, "test-node", getTestInitializerCore(), getTestCheckerCore())
+}
+
+func (loader testPlanNetworkLoader) InitializeNetwork(network *ServiceNetwork) (map[ServiceID]services.ServiceAvailabilityChecker, error) {
+	if _, err := network.AddService(testConfigurationId0, "bootstrap", map[ServiceID]bool{}); err != nil {
+		return nil, err
+	}
+	if _, err := network.AddService(testConfigurationId1, "node1", map[ServiceID]bool{"bootstrap": true}); err != nil {
+		return nil, err
+	}
+	if err := network.CreateService(testConfigurationId1, "node2", map[ServiceID]bool{"bootstrap": true}); err != nil {
+		return nil, err
+	}
+	return map[ServiceID]services.ServiceAvailabilityChecker{}, nil
+}
+
+func (loader testPlanNetworkLoader) WrapNetwork(network *ServiceNetwork) (Network, error) {
+	return network, nil
+}
+
+func TestPlanningNetwork(t *testing.T) {
+	plan, err := Plan(testLog, testPlanNetworkLoader{}, "172.23.0.0/24")
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, []string{"test-bootstrap", "test-node"}, plan.Images)
+	assert.Equal(t, 3, len(plan.Services))
+	assert.Equal(t, ServiceID("bootstrap"), plan.Services[0].ServiceId)
+	assert.Assert(t, plan.Services[0].Started)
+	assert.Equal(t, ServiceID("node1"), plan.Services[1].ServiceId)
+	assert.DeepEqual(t, []ServiceID{"bootstrap"}, plan.Services[1].DependencyIds)
+	assert.Equal(t, ServiceID("node2"), plan.Services[2].ServiceId)
+	assert.Assert(t, !plan.Services[2].Started)
+	assert.Assert(t, strings.Contains(plan.String(), "3. node2 (configuration test-configuration-1)"))
+}
+
+func TestPlanningNetworkWithInvalidSubnet(t *testing.T) {
+	_, err := Plan(testLog, testPlanNetworkLoader{}, "not-a-subnet")
+	assert.ErrorContains(t, err, "subnet")
+}
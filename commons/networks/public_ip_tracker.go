@@ -1,19 +1,29 @@
 package networks
 
 import (
-	"encoding/binary"
+	"github.com/kurtosis-tech/kurtosis/commons/metrics"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
+	"math/big"
 	"net"
+	"sync"
 )
 
 /*
 Object which is intialized from a subnet and doles out IPs from the subnet, tracking which IPs are currently in use and
 	making sure not to return any IPs that are in use.
+
+NOTE: This is thread-safe! One tracker can be shared by networks that are built & torn down in parallel.
+
+NOTE: The tracker only knows about the IPs it has given out or been told are taken, so IPs in the subnet that are used by
+	anything else (e.g. the network's gateway) must be passed to it as already taken.
  */
 type FreeIpAddrTracker struct {
 	log *logrus.Logger
 	subnet *net.IPNet
+
+	// Guards takenIps
+	mutex *sync.Mutex
 	takenIps map[string]bool
 }
 
@@ -41,51 +51,98 @@ func NewFreeIpAddrTracker(log *logrus.Logger, subnetMask string, alreadyTakenIps
 	ipAddrTracker = &FreeIpAddrTracker{
 		log: log,
 		subnet: ipv4Net,
+		mutex: &sync.Mutex{},
 		takenIps: takenIps,
 	}
 	return ipAddrTracker, nil
 }
 
-// TODO Return IP objects (which are easily convertable to strings) rather than strings themselves
-// TODO rework this entire function to handle IPv6 as well (currently breaks on IPv6)
 /*
-Gets a free IP address from the subnet that the IP tracker was initializd with.
+Gets a free IP address from the subnet that the IP tracker was initializd with (which may be IPv4 or IPv6).
 
 Returns:
-	An IP from the subnet the tracker was initialized with that won't collide with any previously-given IP. The
-		actual IP returned is undefined.
+	The lowest IP in the subnet the tracker was initialized with that hasn't already been given out or taken, so that
+		the same sequence of calls always gets the same IPs. Neither the subnet's network address nor, for IPv4 subnets,
+		its broadcast address is ever given out.
  */
 func (networkManager FreeIpAddrTracker) GetFreeIpAddr() (ipAddr net.IP, err error){
-	// convert IPNet struct mask and address to uint32
-	// network is BigEndian
-	mask := binary.BigEndian.Uint32(networkManager.subnet.Mask)
-
-	subnetIp := networkManager.subnet.IP
-
-	// The IP can be either 4 bytes or 16 bytes long; we need to handle both!
-	// See https://gist.github.com/ammario/649d4c0da650162efd404af23e25b86b
-	var intIp uint32
-	if len(subnetIp) == 16 {
-		intIp = binary.BigEndian.Uint32(subnetIp[12:16])
-	} else {
-		intIp = binary.BigEndian.Uint32(subnetIp)
-	}
+	networkManager.mutex.Lock()
+	defer networkManager.mutex.Unlock()
+	maskBits, addrBits := networkManager.subnet.Mask.Size()
 
 	// We remove the zeroth IP because it's only used for specifying the network itself
-	start := intIp + 1
+	start := new(big.Int).Add(ipToInt(networkManager.subnet.IP), big.NewInt(1))
 
 	// find the final address
-	finish := (start & mask) | (mask ^ 0xffffffff)
-	// loop through addresses as uint32
-	for i := start; i <= finish; i++ {
-		// convert back to net.IP
-		ip := make(net.IP, 4)
-		binary.BigEndian.PutUint32(ip, i)
+	numIps := new(big.Int).Lsh(big.NewInt(1), uint(addrBits - maskBits))
+	finish := new(big.Int).Add(ipToInt(networkManager.subnet.IP), numIps)
+	finish.Sub(finish, big.NewInt(1))
+	// The last IP of an IPv4 subnet is its broadcast address (except in point-to-point /31 & single-address /32 subnets)
+	if addrBits == net.IPv4len * 8 && addrBits - maskBits > 1 {
+		finish.Sub(finish, big.NewInt(1))
+	}
+
+	// loop through addresses as integers
+	for i := start; i.Cmp(finish) <= 0; i.Add(i, big.NewInt(1)) {
+		ip := intToIp(i, addrBits / 8)
 		ipStr := ip.String()
 		if !networkManager.takenIps[ipStr] {
 			networkManager.takenIps[ipStr] = true
+			metrics.IpAddrsInUse.Add(1)
 			return ip, nil
 		}
 	}
-	return nil, stacktrace.NewError("Failed to allocate IpAddr on subnet %v - all taken.", networkManager.subnet)
-}
\ No newline at end of file
+	return nil, stacktrace.Propagate(ErrAddressesExhausted, "Failed to allocate IpAddr on subnet %v - all taken.", networkManager.subnet)
+}
+
+/*
+Marks the given IP address as taken, e.g. because it belongs to an already-running service that the network is
+	re-attaching to.
+ */
+func (networkManager FreeIpAddrTracker) TakeIpAddr(ipAddr net.IP) error {
+	if !networkManager.subnet.Contains(ipAddr) {
+		return stacktrace.NewError("IP %v isn't in subnet %v", ipAddr, networkManager.subnet)
+	}
+	networkManager.mutex.Lock()
+	defer networkManager.mutex.Unlock()
+	ipStr := ipAddr.String()
+	if networkManager.takenIps[ipStr] {
+		return stacktrace.NewError("IP %v is already taken", ipStr)
+	}
+	networkManager.takenIps[ipStr] = true
+	metrics.IpAddrsInUse.Add(1)
+	return nil
+}
+
+/*
+Marks the given IP address as free again (e.g. because the service it was given to has been removed), so that it can be
+	given out to a later service. Releasing an IP that isn't taken has no effect.
+ */
+func (networkManager FreeIpAddrTracker) ReleaseIpAddr(ipAddr net.IP) {
+	networkManager.mutex.Lock()
+	defer networkManager.mutex.Unlock()
+	networkManager.releaseIpAddr(ipAddr)
+}
+
+/*
+Marks all of the given IP addresses as free again at once, e.g. because the network they were given out for has been
+	torn down. Releasing an IP that isn't taken has no effect.
+ */
+func (networkManager FreeIpAddrTracker) ReleaseIpAddrs(ipAddrs []net.IP) {
+	networkManager.mutex.Lock()
+	defer networkManager.mutex.Unlock()
+	for _, ipAddr := range ipAddrs {
+		networkManager.releaseIpAddr(ipAddr)
+	}
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Marks the given IP address as free; must be called with the tracker's lock held
+func (networkManager FreeIpAddrTracker) releaseIpAddr(ipAddr net.IP) {
+	ipStr := ipAddr.String()
+	if !networkManager.takenIps[ipStr] {
+		return
+	}
+	delete(networkManager.takenIps, ipStr)
+	metrics.IpAddrsInUse.Add(-1)
+}
@@ -0,0 +1,100 @@
+package networks
+
+import (
+	"errors"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestIpv4Tracking(t *testing.T) {
+	tracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/29", map[string]bool{"172.23.0.2": true})
+	assert.NilError(t, err)
+
+	for _, expectedIp := range []string{"172.23.0.1", "172.23.0.3", "172.23.0.4", "172.23.0.5", "172.23.0.6"} {
+		ipAddr, err := tracker.GetFreeIpAddr()
+		assert.NilError(t, err)
+		assert.Equal(t, expectedIp, ipAddr.String())
+	}
+	// The broadcast address is never given out
+	_, err = tracker.GetFreeIpAddr()
+	assert.Assert(t, errors.Is(stacktrace.RootCause(err), ErrAddressesExhausted))
+}
+
+func TestReleasingIps(t *testing.T) {
+	tracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/30", map[string]bool{})
+	assert.NilError(t, err)
+
+	firstIp, err := tracker.GetFreeIpAddr()
+	assert.NilError(t, err)
+	_, err = tracker.GetFreeIpAddr()
+	assert.NilError(t, err)
+	tracker.ReleaseIpAddr(firstIp)
+	// Releasing an IP twice doesn't free up any other IP
+	tracker.ReleaseIpAddr(firstIp)
+
+	ipAddr, err := tracker.GetFreeIpAddr()
+	assert.NilError(t, err)
+	assert.Equal(t, firstIp.String(), ipAddr.String())
+	_, err = tracker.GetFreeIpAddr()
+	assert.Assert(t, errors.Is(stacktrace.RootCause(err), ErrAddressesExhausted))
+}
+
+func TestIpv6Tracking(t *testing.T) {
+	tracker, err := NewFreeIpAddrTracker(testLog.Logger, "fd00:6b75:7274:1::/64", map[string]bool{})
+	assert.NilError(t, err)
+
+	assert.NilError(t, tracker.TakeIpAddr(net.ParseIP("fd00:6b75:7274:1::1")))
+	ipAddr, err := tracker.GetFreeIpAddr()
+	assert.NilError(t, err)
+	assert.Equal(t, "fd00:6b75:7274:1::2", ipAddr.String())
+	assert.Assert(t, tracker.TakeIpAddr(net.ParseIP("fd00:6b75:7274:2::1")) != nil)
+}
+
+func BenchmarkAllocatingAllIpv4Addrs(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		tracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+		assert.NilError(b, err)
+		// A /24 has 254 usable addresses, less the gateway's
+		for j := 0; j < 253; j++ {
+			_, err := tracker.GetFreeIpAddr()
+			assert.NilError(b, err)
+		}
+	}
+}
+
+func TestConcurrentIpAllocation(t *testing.T) {
+	tracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+
+	numAllocators := 50
+	allocatedIps := make(chan net.IP, numAllocators)
+	waitGroup := &sync.WaitGroup{}
+	for i := 0; i < numAllocators; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			ipAddr, err := tracker.GetFreeIpAddr()
+			assert.Check(t, err)
+			allocatedIps <- ipAddr
+		}()
+	}
+	waitGroup.Wait()
+	close(allocatedIps)
+
+	uniqueIps := map[string]bool{}
+	toRelease := []net.IP{}
+	for ipAddr := range allocatedIps {
+		uniqueIps[ipAddr.String()] = true
+		toRelease = append(toRelease, ipAddr)
+	}
+	assert.Equal(t, numAllocators, len(uniqueIps))
+
+	// Releasing all of them at once (e.g. on a network's teardown) makes the lowest IP free again
+	tracker.ReleaseIpAddrs(toRelease)
+	ipAddr, err := tracker.GetFreeIpAddr()
+	assert.NilError(t, err)
+	assert.Equal(t, "172.23.0.1", ipAddr.String())
+}
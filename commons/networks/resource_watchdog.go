@@ -0,0 +1,200 @@
+package networks
+
+import (
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+The resource usage limits that a ResourceWatchdog will check services against. A zero value for a limit means that
+	resource won't be checked.
+ */
+type ResourceThresholds struct {
+	// The maximum CPU usage a service may have, where 100 means one full CPU core
+	MaxCpuPercent float64
+
+	// The maximum memory, in bytes, that a service may use
+	MaxMemoryBytes uint64
+}
+
+/*
+A record of a single time that a service was seen using more resources than the thresholds allow.
+ */
+type ResourceViolation struct {
+	// When the violation was observed
+	Timestamp time.Time
+
+	// The service that exceeded its thresholds
+	ServiceId ServiceID
+
+	// The resource usage that was observed
+	Usage docker.ContainerResourceUsage
+
+	// A human-readable description of which threshold was exceeded
+	Description string
+}
+
+func (violation ResourceViolation) String() string {
+	return fmt.Sprintf("%v %v %v", violation.Timestamp.Format(time.RFC3339Nano), violation.ServiceId, violation.Description)
+}
+
+/*
+Periodically samples the CPU & memory usage of every service in a test network via Docker stats, logging a warning and
+	recording a violation whenever a service exceeds the thresholds. This is useful for catching leaks and runaway nodes
+	during long-running soak tests.
+
+NOTE: The watchdog reads the network's services from a separate goroutine, so services shouldn't be added to or removed
+	from the network while the watchdog is running.
+ */
+type ResourceWatchdog struct {
+	// The network whose services will be monitored
+	network *ServiceNetwork
+
+	// The limits that services will be checked against
+	thresholds ResourceThresholds
+
+	// How often the services' resource usage will be sampled
+	pollInterval time.Duration
+
+	// Mutex guarding the violations
+	mutex *sync.Mutex
+
+	// All violations that have been observed so far
+	violations []ResourceViolation
+
+	// Closed to tell the watchdog goroutine to stop
+	stopChan chan struct{}
+
+	// Closed by the watchdog goroutine once it has fully stopped
+	doneChan chan struct{}
+}
+
+/*
+Creates a new watchdog that will check the resource usage of the network's services against the given thresholds.
+
+Args:
+	network: The network whose services will be monitored
+	thresholds: The resource usage limits that services will be checked against
+	pollInterval: How often each service's resource usage will be sampled
+ */
+func NewResourceWatchdog(network *ServiceNetwork, thresholds ResourceThresholds, pollInterval time.Duration) *ResourceWatchdog {
+	return &ResourceWatchdog{
+		network:      network,
+		thresholds:   thresholds,
+		pollInterval: pollInterval,
+		mutex:        &sync.Mutex{},
+		violations:   []ResourceViolation{},
+		stopChan:     nil,
+		doneChan:     nil,
+	}
+}
+
+/*
+Starts monitoring the network's services in a background goroutine. A watchdog can only be started once.
+ */
+func (watchdog *ResourceWatchdog) Start() error {
+	if watchdog.pollInterval <= 0 {
+		return stacktrace.NewError("Resource watchdog poll interval must be positive, but was %v", watchdog.pollInterval)
+	}
+	if watchdog.stopChan != nil {
+		return stacktrace.NewError("Resource watchdog has already been started")
+	}
+	watchdog.stopChan = make(chan struct{})
+	watchdog.doneChan = make(chan struct{})
+	go watchdog.run()
+	return nil
+}
+
+/*
+Stops monitoring the network's services, blocking until the background goroutine has exited.
+ */
+func (watchdog *ResourceWatchdog) Stop() {
+	if watchdog.stopChan == nil {
+		return
+	}
+	close(watchdog.stopChan)
+	<-watchdog.doneChan
+}
+
+/*
+Gets a copy of all violations that the watchdog has observed so far, in the order they were observed.
+ */
+func (watchdog *ResourceWatchdog) GetViolations() []ResourceViolation {
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+
+	result := make([]ResourceViolation, len(watchdog.violations))
+	copy(result, watchdog.violations)
+	return result
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (watchdog *ResourceWatchdog) run() {
+	defer close(watchdog.doneChan)
+
+	for {
+		select {
+		case <-watchdog.stopChan:
+			return
+		case <-time.After(watchdog.pollInterval):
+		}
+
+		// Sorted so that violations within a single poll are recorded in a stable order
+		serviceIds := []ServiceID{}
+		for serviceId, _ := range watchdog.network.GetServiceIds() {
+			serviceIds = append(serviceIds, serviceId)
+		}
+		sort.Slice(serviceIds, func(i, j int) bool {
+			return serviceIds[i] < serviceIds[j]
+		})
+
+		for _, serviceId := range serviceIds {
+			usage, err := watchdog.network.GetServiceResourceUsage(serviceId)
+			if err != nil {
+				// Services can legitimately be down (e.g. killed by the test), so we don't treat this as a violation
+				watchdog.network.serviceLog(serviceId).Debugf("Resource watchdog couldn't sample service ID %v: %v", serviceId, err)
+				continue
+			}
+			for _, violation := range watchdog.checkUsage(serviceId, usage) {
+				watchdog.recordViolation(violation)
+			}
+		}
+	}
+}
+
+/*
+Checks the given resource usage against the thresholds, returning a violation for each threshold that was exceeded.
+ */
+func (watchdog *ResourceWatchdog) checkUsage(serviceId ServiceID, usage docker.ContainerResourceUsage) []ResourceViolation {
+	now := time.Now()
+	result := []ResourceViolation{}
+	if watchdog.thresholds.MaxCpuPercent > 0 && usage.CpuPercent > watchdog.thresholds.MaxCpuPercent {
+		result = append(result, ResourceViolation{
+			Timestamp:   now,
+			ServiceId:   serviceId,
+			Usage:       usage,
+			Description: fmt.Sprintf("CPU usage %.1f%% exceeds threshold %.1f%%", usage.CpuPercent, watchdog.thresholds.MaxCpuPercent),
+		})
+	}
+	if watchdog.thresholds.MaxMemoryBytes > 0 && usage.MemoryBytes > watchdog.thresholds.MaxMemoryBytes {
+		result = append(result, ResourceViolation{
+			Timestamp:   now,
+			ServiceId:   serviceId,
+			Usage:       usage,
+			Description: fmt.Sprintf("Memory usage %d bytes exceeds threshold %d bytes", usage.MemoryBytes, watchdog.thresholds.MaxMemoryBytes),
+		})
+	}
+	return result
+}
+
+func (watchdog *ResourceWatchdog) recordViolation(violation ResourceViolation) {
+	watchdog.network.serviceLog(violation.ServiceId).Warnf("Resource threshold exceeded: %v", violation)
+
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+	watchdog.violations = append(watchdog.violations, violation)
+}
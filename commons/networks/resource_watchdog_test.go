@@ -0,0 +1,50 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"testing"
+	"time"
+)
+
+func TestResourceUsageWithinThresholds(t *testing.T) {
+	thresholds := ResourceThresholds{
+		MaxCpuPercent:  50,
+		MaxMemoryBytes: 1024,
+	}
+	watchdog := NewResourceWatchdog(nil, thresholds, time.Second)
+	usage := docker.ContainerResourceUsage{
+		CpuPercent:  50,
+		MemoryBytes: 1024,
+	}
+	assert.Equal(t, 0, len(watchdog.checkUsage("node1", usage)))
+}
+
+func TestResourceUsageExceedingThresholds(t *testing.T) {
+	thresholds := ResourceThresholds{
+		MaxCpuPercent:  50,
+		MaxMemoryBytes: 1024,
+	}
+	watchdog := NewResourceWatchdog(nil, thresholds, time.Second)
+	usage := docker.ContainerResourceUsage{
+		CpuPercent:  75,
+		MemoryBytes: 2048,
+	}
+	violations := watchdog.checkUsage("node1", usage)
+	assert.Equal(t, 2, len(violations))
+	assert.Equal(t, ServiceID("node1"), violations[0].ServiceId)
+}
+
+func TestZeroThresholdsAreUnchecked(t *testing.T) {
+	watchdog := NewResourceWatchdog(nil, ResourceThresholds{}, time.Second)
+	usage := docker.ContainerResourceUsage{
+		CpuPercent:  400,
+		MemoryBytes: 1 << 40,
+	}
+	assert.Equal(t, 0, len(watchdog.checkUsage("node1", usage)))
+}
+
+func TestNonPositivePollIntervalRejected(t *testing.T) {
+	watchdog := NewResourceWatchdog(nil, ResourceThresholds{}, 0)
+	assert.Assert(t, watchdog.Start() != nil)
+}
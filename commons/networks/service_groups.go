@@ -0,0 +1,99 @@
+package networks
+
+import (
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"sort"
+	"sync"
+)
+
+/*
+A group of identical services added together with ServiceNetwork.AddServiceGroup, e.g. a network's validators.
+ */
+type ServiceGroup struct {
+	// A "set" of the IDs of the group's services, which can be passed as (or merged into) the dependencies of services
+	//  that need the whole group
+	ServiceIds map[ServiceID]bool
+
+	// Mapping of service ID -> the checker for when that service of the group becomes available
+	AvailabilityCheckers map[ServiceID]*services.ServiceAvailabilityChecker
+}
+
+/*
+Adds the given number of services, all created from the given configuration & with the given dependencies, to the
+	network, for networks made of several identical nodes (e.g. "3 boot nodes + 10 validators"). The services get the IDs
+	"<namePrefix>-1", "<namePrefix>-2", etc. This doesn't wait for the services to become available; to only start
+	dependents once the whole group is live, call the group's WaitForStartup before adding them (or have the network
+	wait for dependencies; see ServiceNetworkBuilder.UseDependencyAvailabilityPolicy).
+
+Args:
+	configurationId: The ID of the configuration that the group's services will be created from
+	namePrefix: The prefix of the IDs of the group's services
+	count: How many services the group has
+	dependencies: A "set" of the IDs of the services that each of the group's services depends on
+
+Returns:
+	The group, or an error if any of its services couldn't be added (in which case the services added before it stay in
+		the network)
+ */
+func (network *ServiceNetwork) AddServiceGroup(
+			configurationId ConfigurationID,
+			namePrefix string,
+			count int,
+			dependencies map[ServiceID]bool) (*ServiceGroup, error) {
+	if count < 1 {
+		return nil, stacktrace.NewError("Service group %v must have at least one service, but was given %v", namePrefix, count)
+	}
+	group := &ServiceGroup{
+		ServiceIds:           make(map[ServiceID]bool),
+		AvailabilityCheckers: make(map[ServiceID]*services.ServiceAvailabilityChecker),
+	}
+	for i := 1; i <= count; i++ {
+		serviceId := ServiceID(fmt.Sprintf("%v-%v", namePrefix, i))
+		availabilityChecker, err := network.AddService(configurationId, serviceId, dependencies)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred adding service %v of service group %v", serviceId, namePrefix)
+		}
+		group.ServiceIds[serviceId] = true
+		group.AvailabilityCheckers[serviceId] = availabilityChecker
+	}
+	return group, nil
+}
+
+/*
+Waits for all of the group's services to become available, waiting on them in parallel.
+
+Returns:
+	An error naming every service that didn't become available, whose root cause is the first such service's error
+ */
+func (group ServiceGroup) WaitForStartup() error {
+	resultsMutex := &sync.Mutex{}
+	startupErrs := make(map[ServiceID]error)
+	waitGroup := &sync.WaitGroup{}
+	for serviceId, availabilityChecker := range group.AvailabilityCheckers {
+		waitGroup.Add(1)
+		go func(serviceId ServiceID, availabilityChecker *services.ServiceAvailabilityChecker) {
+			defer waitGroup.Done()
+			if err := availabilityChecker.WaitForStartup(); err != nil {
+				resultsMutex.Lock()
+				defer resultsMutex.Unlock()
+				startupErrs[serviceId] = err
+			}
+		}(serviceId, availabilityChecker)
+	}
+	waitGroup.Wait()
+
+	if len(startupErrs) == 0 {
+		return nil
+	}
+	failedServiceIds := make([]string, 0, len(startupErrs))
+	for serviceId, _ := range startupErrs {
+		failedServiceIds = append(failedServiceIds, string(serviceId))
+	}
+	sort.Strings(failedServiceIds)
+	return stacktrace.Propagate(
+		startupErrs[ServiceID(failedServiceIds[0])],
+		"Services %v of the group didn't become available",
+		failedServiceIds)
+}
@@ -0,0 +1,40 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestAddingServiceGroups(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, docker.NewFakeDockerManager(), testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:latest", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+
+	bootNodes, err := network.AddServiceGroup(testConfiguration, "boot", 2, map[ServiceID]bool{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[ServiceID]bool{"boot-1": true, "boot-2": true}, bootNodes.ServiceIds)
+	assert.NilError(t, bootNodes.WaitForStartup())
+
+	validators, err := network.AddServiceGroup(testConfiguration, "validator", 3, bootNodes.ServiceIds)
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(validators.AvailabilityCheckers))
+	validator, err := network.GetService("validator-3")
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(validator.dependencyIds))
+	assert.Equal(t, 5, network.GetSize())
+
+	_, err = network.AddServiceGroup(testConfiguration, "empty", 0, map[ServiceID]bool{})
+	assert.ErrorContains(t, err, "must have at least one service")
+	// Groups can't reuse the IDs of existing services
+	_, err = network.AddServiceGroup(testConfiguration, "boot", 1, map[ServiceID]bool{})
+	assert.ErrorContains(t, err, "already exists")
+}
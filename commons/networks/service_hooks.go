@@ -0,0 +1,77 @@
+package networks
+
+import (
+	"github.com/docker/docker/api/types/container"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"net"
+)
+
+/*
+Callbacks that a network invokes around the creation & start of each of its services, for custom wiring that the framework
+	doesn't natively support (e.g. registering services with an external discovery system, or adding Docker settings that
+	depend on the service ID). Hooks are called with the network's lock held, so mustn't call the network's methods.
+ */
+type ServiceHook interface {
+	/*
+	Called just before the container of the given service is created, with the container's fully-rendered configuration
+		(start command, environment, mounts, etc.), which may be modified in place. The configuration's own
+		docker.ContainerOptions.Customizer runs after the hooks, so still gets the final say.
+
+	Returns:
+		An error if the service shouldn't be created, which fails the service's creation
+	 */
+	BeforeServiceCreate(serviceId ServiceID, config *container.Config, hostConfig *container.HostConfig) error
+
+	/*
+	Called just after the container of the given service has started, including after RestartService replaces it.
+
+	Args:
+		serviceId: The ID of the service
+		containerId: The ID of the service's (new) container
+		ipAddr: The service's IP on the test network
+
+	Returns:
+		An error if the service shouldn't be used, which is returned from the call that started the service (though the
+			service is left running in the network, to be removed like any other)
+	 */
+	AfterServiceStart(serviceId ServiceID, containerId string, ipAddr net.IP) error
+}
+
+/*
+Makes the built network invoke the given hook around the creation & start of each of its services (see ServiceHook).
+	Hooks are invoked in the order they were added.
+ */
+func (builder *ServiceNetworkBuilder) AddServiceHook(hook ServiceHook) {
+	builder.serviceHooks = append(builder.serviceHooks, hook)
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets a customizer that runs the network's BeforeServiceCreate hooks for the given service, followed by the given
+	customizer (if non-nil).
+ */
+func (network *ServiceNetwork) getHookedCustomizer(serviceId ServiceID, customizer docker.ContainerCustomizer) docker.ContainerCustomizer {
+	hooks := append([]ServiceHook{}, network.serviceHooks...)
+	return docker.ContainerCustomizerFunc(func(config *container.Config, hostConfig *container.HostConfig) error {
+		for idx, hook := range hooks {
+			if err := hook.BeforeServiceCreate(serviceId, config, hostConfig); err != nil {
+				return stacktrace.Propagate(err, "Service hook #%v failed before creating service %v", idx, serviceId)
+			}
+		}
+		if customizer == nil {
+			return nil
+		}
+		return customizer.CustomizeContainer(config, hostConfig)
+	})
+}
+
+// Runs the network's AfterServiceStart hooks for the given service; must be called with the network's lock held
+func (network *ServiceNetwork) runAfterServiceStartHooks(serviceId ServiceID, nodeInfo ServiceNode) error {
+	for idx, hook := range network.serviceHooks {
+		if err := hook.AfterServiceStart(serviceId, nodeInfo.ContainerId, nodeInfo.IpAddr); err != nil {
+			return stacktrace.Propagate(err, "Service hook #%v failed after starting service %v", idx, serviceId)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package networks
+
+import (
+	"github.com/docker/docker/api/types/container"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+)
+
+// Records the calls it gets, tags each service's environment with its ID, & optionally fails after starting services
+type recordingServiceHook struct {
+	calls []string
+
+	startedContainerIds map[ServiceID]string
+
+	failAfterStart bool
+}
+
+func (hook *recordingServiceHook) BeforeServiceCreate(serviceId ServiceID, config *container.Config, hostConfig *container.HostConfig) error {
+	hook.calls = append(hook.calls, "before " + string(serviceId))
+	config.Env = append(config.Env, "SERVICE_ID=" + string(serviceId))
+	return nil
+}
+
+func (hook *recordingServiceHook) AfterServiceStart(serviceId ServiceID, containerId string, ipAddr net.IP) error {
+	hook.calls = append(hook.calls, "after " + string(serviceId) + " " + ipAddr.String())
+	hook.startedContainerIds[serviceId] = containerId
+	if hook.failAfterStart {
+		return stacktrace.NewError("Test failure")
+	}
+	return nil
+}
+
+func TestServiceHooks(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	customizedOptions := docker.ContainerOptions{
+		// The configuration's own customizer runs after the hooks
+		Customizer: docker.ContainerCustomizerFunc(func(config *container.Config, hostConfig *container.HostConfig) error {
+			config.Env = append(config.Env, "SERVICE_ID=overridden")
+			return nil
+		}),
+	}
+	assert.NilError(t, builder.AddConfigurationWithOptions(testConfiguration, "node:latest", getTestInitializerCore(), getTestCheckerCore(), customizedOptions))
+	hook := &recordingServiceHook{startedContainerIds: map[ServiceID]string{}}
+	builder.AddServiceHook(hook)
+	network := builder.Build()
+
+	_, err = network.AddService(testConfiguration, testServiceName, map[ServiceID]bool{})
+	assert.NilError(t, err)
+	node, err := network.GetService(testServiceName)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"before " + string(testServiceName), "after " + string(testServiceName) + " " + node.IpAddr.String()}, hook.calls)
+	assert.Equal(t, node.ContainerId, hook.startedContainerIds[testServiceName])
+	container, _ := dockerManager.GetContainer(node.ContainerId)
+	assert.Equal(t, "overridden", container.EnvVariables["SERVICE_ID"])
+
+	// Failing hooks fail the start, but leave the service in the network
+	hook.failAfterStart = true
+	_, err = network.AddService(testConfiguration, "other", map[ServiceID]bool{})
+	assert.ErrorContains(t, err, "Test failure")
+	_, err = network.GetService("other")
+	assert.NilError(t, err)
+}
@@ -0,0 +1,181 @@
+package networks
+
+import (
+	"github.com/docker/go-connections/nat"
+	"github.com/palantir/stacktrace"
+	"net"
+	"sort"
+	"time"
+)
+
+/*
+A report of a service in the network, covering where it runs & how long it took to start, so that tests (& the controller)
+	can log and assert on startup characteristics.
+ */
+type ServiceInfo struct {
+	ServiceId ServiceID
+
+	// The ID of the configuration the service was created from
+	ConfigurationId ConfigurationID
+
+	// True if the service deliberately misbehaves (see ServiceNetworkBuilder.AddAdversarialConfiguration)
+	IsAdversarial bool
+
+	// The Docker container ID of the container running the service
+	ContainerId string
+
+	// The service's IP within the test network
+	IpAddr net.IP
+
+	// Mapping of the service's ports -> the ports on the Docker host they can be reached at, for the ports that the
+	//  service's configuration publishes (or all its ports, if it uses the host's network)
+	HostPorts map[nat.Port]int
+
+	// When the service's container was last started (or restarted, revived, or re-attached to), or the zero time if the
+	//  service hasn't been started
+	StartTime time.Time
+
+	// When the service was last seen to become available after StartTime, or the zero time if it hasn't been (e.g.
+	//  because nothing waited on its availability checker yet)
+	AvailableTime time.Time
+
+	// How long it took to make the service's image available on its Docker host when the service was created (close to
+	//  zero if the image was already there, e.g. because it was pre-pulled), or zero if the network didn't create the
+	//  service (e.g. it was re-attached to from persisted state)
+	PullDuration time.Duration
+
+	// How long it took to create the service's container (including writing its files) when the service was created, or
+	//  zero if the network didn't create the service
+	CreateDuration time.Duration
+
+	// How long Docker took to start the service's container when the service was first started, or zero if the network
+	//  didn't start the service
+	StartDuration time.Duration
+}
+
+/*
+Gets how long the service took to become available after it was last started, returning false if it hasn't become
+	available since.
+ */
+func (info ServiceInfo) GetStartupDuration() (time.Duration, bool) {
+	if info.StartTime.IsZero() || info.AvailableTime.IsZero() {
+		return 0, false
+	}
+	return info.AvailableTime.Sub(info.StartTime), true
+}
+
+/*
+Gets how long the service took to boot in all, from pulling its image until it became available (or until it was started,
+	if it hasn't become available since).
+ */
+func (info ServiceInfo) GetTotalStartupDuration() time.Duration {
+	result := info.PullDuration + info.CreateDuration + info.StartDuration
+	if startupDuration, isAvailable := info.GetStartupDuration(); isAvailable {
+		result += startupDuration
+	}
+	return result
+}
+
+/*
+Gets a report of the service with the given ID.
+ */
+func (network *ServiceNetwork) GetServiceInfo(serviceId ServiceID) (ServiceInfo, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	if _, found := network.serviceNodes[serviceId]; !found {
+		return ServiceInfo{}, stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	return network.getServiceInfo(serviceId, network.timeline.GetEvents())
+}
+
+/*
+Gets a report of every service in the network, sorted by service ID.
+ */
+func (network *ServiceNetwork) GetServiceInfos() ([]ServiceInfo, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	serviceIds := make([]ServiceID, 0, len(network.serviceNodes))
+	for serviceId, _ := range network.serviceNodes {
+		serviceIds = append(serviceIds, serviceId)
+	}
+	sort.Slice(serviceIds, func(i, j int) bool {
+		return serviceIds[i] < serviceIds[j]
+	})
+
+	// Inspecting each container in turn would make large networks' reports take a round-trip per service
+	if err := network.prefetchContainerNetworkInfos(serviceIds); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred inspecting the network's containers")
+	}
+
+	events := network.timeline.GetEvents()
+	result := make([]ServiceInfo, 0, len(serviceIds))
+	for _, serviceId := range serviceIds {
+		info, err := network.getServiceInfo(serviceId, events)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred getting the info of service ID %v", serviceId)
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets a report of the service with the given ID (which must exist), with its times taken from the given timeline events.
+	Must be called with the network's lock held.
+ */
+func (network *ServiceNetwork) getServiceInfo(serviceId ServiceID, events []LifecycleEvent) (ServiceInfo, error) {
+	nodeInfo := network.serviceNodes[serviceId]
+	config := network.configurations[nodeInfo.configurationId]
+
+	hostPorts := make(map[nat.Port]int)
+	if network.isHostNetworked(nodeInfo) {
+		for port, _ := range config.initializerCore.GetUsedPorts() {
+			hostPorts[port] = port.Int()
+		}
+	} else if publishedPorts := config.containerOptions.GetPublishedPorts(config.initializerCore.GetUsedPorts()); len(publishedPorts) > 0 {
+		containerNetworkInfo, err := network.getContainerNetworkInfo(nodeInfo)
+		if err != nil {
+			return ServiceInfo{}, stacktrace.Propagate(err, "An error occurred getting the host ports of service ID %v", serviceId)
+		}
+		for port, _ := range publishedPorts {
+			hostPort, found := containerNetworkInfo.HostPorts[port]
+			if !found {
+				return ServiceInfo{}, stacktrace.NewError("Port %v of service ID %v isn't published on the host", port, serviceId)
+			}
+			hostPorts[port] = hostPort
+		}
+	}
+
+	var startTime time.Time
+	var availableTime time.Time
+	for _, event := range events {
+		if event.ServiceId != serviceId {
+			continue
+		}
+		switch event.EventType {
+		case SERVICE_STARTED, SERVICE_RESTARTED, SERVICE_REVIVED, SERVICE_REATTACHED:
+			startTime = event.Timestamp
+			availableTime = time.Time{}
+		case SERVICE_AVAILABLE:
+			if availableTime.IsZero() {
+				availableTime = event.Timestamp
+			}
+		}
+	}
+
+	return ServiceInfo{
+		ServiceId:       serviceId,
+		ConfigurationId: nodeInfo.configurationId,
+		IsAdversarial:   config.isAdversarial,
+		ContainerId:     nodeInfo.ContainerId,
+		IpAddr:          nodeInfo.IpAddr,
+		HostPorts:       hostPorts,
+		StartTime:       startTime,
+		AvailableTime:   availableTime,
+		PullDuration:    nodeInfo.pullDuration,
+		CreateDuration:  nodeInfo.createDuration,
+		StartDuration:   nodeInfo.startDuration,
+	}, nil
+}
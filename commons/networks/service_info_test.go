@@ -0,0 +1,49 @@
+package networks
+
+import (
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestGettingServiceInfo(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, docker.NewFakeDockerManager(), testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfigurationWithOptions(
+		testConfiguration,
+		"test",
+		testPortsInitializerCore{usedPorts: map[nat.Port]bool{"8080/tcp": true}},
+		getTestCheckerCore(),
+		docker.ContainerOptions{PublishPorts: true}))
+	network := builder.Build()
+
+	availabilityChecker, err := network.AddService(testConfiguration, testServiceName, map[ServiceID]bool{})
+	assert.NilError(t, err)
+	info, err := network.GetServiceInfo(testServiceName)
+	assert.NilError(t, err)
+	assert.Equal(t, ServiceID(testConfiguration), ServiceID(info.ConfigurationId))
+	assert.Equal(t, 1, len(info.HostPorts))
+	assert.Assert(t, info.HostPorts["8080/tcp"] > 0)
+	assert.Assert(t, !info.StartTime.IsZero())
+	_, isAvailable := info.GetStartupDuration()
+	assert.Assert(t, !isAvailable)
+
+	assert.NilError(t, availabilityChecker.WaitForStartup())
+	infos, err := network.GetServiceInfos()
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(infos))
+	startupDuration, isAvailable := infos[0].GetStartupDuration()
+	assert.Assert(t, isAvailable)
+	assert.Assert(t, startupDuration >= 0)
+
+	_, err = network.GetServiceInfo("nonexistent")
+	assert.ErrorContains(t, err, "No service")
+}
@@ -0,0 +1,250 @@
+package networks
+
+import (
+	"bufio"
+	"context"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sync"
+	"time"
+)
+
+/*
+Copies a service's output to writers as the service logs it (see ServiceNetwork.StreamServiceLogs), until stopped or the
+	service's container stops.
+ */
+type ServiceLogFollower struct {
+	cancelFunc context.CancelFunc
+
+	// Done once both of the service's output streams have finished being copied
+	waitGroup *sync.WaitGroup
+
+	mutex *sync.Mutex
+
+	// The first error that copying either output stream failed with, if any
+	copyErr error
+}
+
+/*
+Stops copying the service's output, waiting for the output logged so far to be written.
+
+Returns:
+	An error if copying the service's output failed before it was stopped
+ */
+func (follower *ServiceLogFollower) Stop() error {
+	follower.cancelFunc()
+	follower.waitGroup.Wait()
+	follower.mutex.Lock()
+	defer follower.mutex.Unlock()
+	return follower.copyErr
+}
+
+/*
+Gets every line of output that the service with the given ID has logged so far which matches the given regex.
+ */
+func (network *ServiceNetwork) GetLogsMatching(serviceId ServiceID, regex *regexp.Regexp) ([]string, error) {
+	nodeInfo, err := network.GetService(serviceId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Cannot get the logs of service ID %v", serviceId)
+	}
+
+	logs, err := network.getDockerManager(nodeInfo).GetContainerLogs(context.Background(), nodeInfo.ContainerId, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the logs of service ID %v", serviceId)
+	}
+	defer logs.Close()
+
+	matchingLines, err := findMatchingLines(logs, regex, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading the logs of service ID %v", serviceId)
+	}
+	return matchingLines, nil
+}
+
+/*
+Gets everything that the service with the given ID has output so far on just one of its output streams (e.g. a node's
+	structured output on STDOUT, without the diagnostics it writes to STDERR).
+ */
+func (network *ServiceNetwork) GetServiceOutput(serviceId ServiceID, stream docker.LogStream) (string, error) {
+	logs, err := network.getServiceStreamLogs(serviceId, stream)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the %v logs of service ID %v", stream, serviceId)
+	}
+	defer logs.Close()
+
+	output, err := ioutil.ReadAll(logs)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred reading the %v logs of service ID %v", stream, serviceId)
+	}
+	return string(output), nil
+}
+
+/*
+Gets every line that the service with the given ID has output so far on the given output stream which matches the given
+	regex, like GetLogsMatching but for just one of the service's output streams.
+ */
+func (network *ServiceNetwork) GetStreamLogsMatching(serviceId ServiceID, stream docker.LogStream, regex *regexp.Regexp) ([]string, error) {
+	logs, err := network.getServiceStreamLogs(serviceId, stream)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the %v logs of service ID %v", stream, serviceId)
+	}
+	defer logs.Close()
+
+	matchingLines, err := findMatchingLines(logs, regex, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading the %v logs of service ID %v", stream, serviceId)
+	}
+	return matchingLines, nil
+}
+
+/*
+Streams the output of the service with the given ID, blocking until a line matching the given regex is logged (including
+	lines logged before this was called) or the timeout is hit.
+
+Returns:
+	The first matching line, or an error if no line matched before the timeout
+ */
+func (network *ServiceNetwork) WaitForLogMatch(serviceId ServiceID, regex *regexp.Regexp, timeout time.Duration) (string, error) {
+	nodeInfo, err := network.GetService(serviceId)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Cannot wait for the logs of service ID %v", serviceId)
+	}
+
+	timeoutCtx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+	defer cancelFunc()
+
+	logs, err := network.getDockerManager(nodeInfo).GetContainerLogs(timeoutCtx, nodeInfo.ContainerId, true)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred streaming the logs of service ID %v", serviceId)
+	}
+	defer logs.Close()
+
+	matchingLines, err := findMatchingLines(logs, regex, true)
+	if len(matchingLines) > 0 {
+		return matchingLines[0], nil
+	}
+	if timeoutCtx.Err() != nil {
+		return "", stacktrace.NewError("No log line of service ID %v matched regex '%v' within %v", serviceId, regex, timeout)
+	}
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred reading the logs of service ID %v", serviceId)
+	}
+	return "", stacktrace.NewError("Service ID %v stopped logging without any line matching regex '%v'", serviceId, regex)
+}
+
+/*
+Starts copying the output of the service with the given ID to the given writers (including what it logged before this
+	was called) as the service logs it, e.g. to tee nodes' output to files or to the test's own output while the test
+	runs. Writes to the writers are serialized, so the same writer can be given for both streams.
+
+Args:
+	serviceId: The ID of the service whose output to copy
+	stdout: The writer that the service's STDOUT will be copied to
+	stderr: The writer that the service's STDERR will be copied to
+
+Returns:
+	A follower that keeps copying the output until it's stopped or the service's container stops
+ */
+func (network *ServiceNetwork) StreamServiceLogs(serviceId ServiceID, stdout io.Writer, stderr io.Writer) (*ServiceLogFollower, error) {
+	nodeInfo, err := network.GetService(serviceId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Cannot stream the logs of service ID %v", serviceId)
+	}
+	dockerManager := network.getDockerManager(nodeInfo)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	streamLogs := make(map[docker.LogStream]io.ReadCloser)
+	for _, stream := range []docker.LogStream{docker.STDOUT_LOG_STREAM, docker.STDERR_LOG_STREAM} {
+		logs, err := dockerManager.GetContainerStreamLogs(ctx, nodeInfo.ContainerId, stream, true)
+		if err != nil {
+			cancelFunc()
+			for _, openedLogs := range streamLogs {
+				openedLogs.Close()
+			}
+			return nil, stacktrace.Propagate(err, "An error occurred streaming the %v logs of service ID %v", stream, serviceId)
+		}
+		streamLogs[stream] = logs
+	}
+
+	follower := &ServiceLogFollower{
+		cancelFunc: cancelFunc,
+		waitGroup:  &sync.WaitGroup{},
+		mutex:      &sync.Mutex{},
+		copyErr:    nil,
+	}
+	writeMutex := &sync.Mutex{}
+	streamWriters := map[docker.LogStream]io.Writer{
+		docker.STDOUT_LOG_STREAM: serializedWriter{mutex: writeMutex, underlying: stdout},
+		docker.STDERR_LOG_STREAM: serializedWriter{mutex: writeMutex, underlying: stderr},
+	}
+	for stream, logs := range streamLogs {
+		follower.waitGroup.Add(1)
+		go func(stream docker.LogStream, logs io.ReadCloser) {
+			defer follower.waitGroup.Done()
+			defer logs.Close()
+			_, err := io.Copy(streamWriters[stream], logs)
+			// Stopping the follower cancels the streams, which isn't a failure
+			if err != nil && ctx.Err() == nil {
+				follower.mutex.Lock()
+				defer follower.mutex.Unlock()
+				if follower.copyErr == nil {
+					follower.copyErr = stacktrace.Propagate(err, "An error occurred copying the %v logs of service ID %v", stream, serviceId)
+				}
+			}
+		}(stream, logs)
+	}
+	return follower, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Writer whose writes are serialized with those of other writers sharing the same mutex
+type serializedWriter struct {
+	mutex *sync.Mutex
+
+	underlying io.Writer
+}
+
+func (writer serializedWriter) Write(bytesToWrite []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	return writer.underlying.Write(bytesToWrite)
+}
+
+func (network *ServiceNetwork) getServiceStreamLogs(serviceId ServiceID, stream docker.LogStream) (io.ReadCloser, error) {
+	nodeInfo, err := network.GetService(serviceId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Cannot get the logs of service ID %v", serviceId)
+	}
+	return network.getDockerManager(nodeInfo).GetContainerStreamLogs(context.Background(), nodeInfo.ContainerId, stream, false)
+}
+
+/*
+Reads the given logs line-by-line, returning the lines that match the regex.
+
+Args:
+	logs: The logs to scan
+	regex: The regex that lines must match
+	stopAtFirstMatch: If true, stops reading as soon as a matching line is found (which is needed for streamed logs that
+		may never end)
+ */
+func findMatchingLines(logs io.Reader, regex *regexp.Regexp, stopAtFirstMatch bool) ([]string, error) {
+	result := []string{}
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !regex.MatchString(line) {
+			continue
+		}
+		result = append(result, line)
+		if stopAtFirstMatch {
+			return result, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, stacktrace.Propagate(err, "An error occurred scanning the logs")
+	}
+	return result, nil
+}
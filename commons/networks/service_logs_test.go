@@ -0,0 +1,82 @@
+package networks
+
+import (
+	"bytes"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const testLogs = `Starting node...
+Connected to peer 172.23.0.3
+Block 1 committed
+Connected to peer 172.23.0.4
+`
+
+func TestFindAllMatchingLines(t *testing.T) {
+	regex := regexp.MustCompile(`Connected to peer .*`)
+	matchingLines, err := findMatchingLines(strings.NewReader(testLogs), regex, false)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"Connected to peer 172.23.0.3", "Connected to peer 172.23.0.4"}, matchingLines)
+}
+
+func TestFindFirstMatchingLine(t *testing.T) {
+	regex := regexp.MustCompile(`Connected to peer .*`)
+	matchingLines, err := findMatchingLines(strings.NewReader(testLogs), regex, true)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"Connected to peer 172.23.0.3"}, matchingLines)
+}
+
+func TestFindNoMatchingLines(t *testing.T) {
+	regex := regexp.MustCompile(`panic`)
+	matchingLines, err := findMatchingLines(strings.NewReader(testLogs), regex, false)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(matchingLines))
+}
+
+func TestGettingLogsOfNonexistentService(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+	_, err := network.GetLogsMatching(testServiceName, regexp.MustCompile(".*"))
+	assert.Assert(t, err != nil)
+}
+
+func TestGettingSeparateOutputStreams(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:latest", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+	_, err = network.AddService(testConfiguration, testServiceName, map[ServiceID]bool{})
+	assert.NilError(t, err)
+	info, err := network.GetServiceInfo(testServiceName)
+	assert.NilError(t, err)
+	assert.NilError(t, dockerManager.SetContainerOutput(info.ContainerId, "{\"height\": 1}\n", testLogs))
+
+	stdout, err := network.GetServiceOutput(testServiceName, docker.STDOUT_LOG_STREAM)
+	assert.NilError(t, err)
+	assert.Equal(t, "{\"height\": 1}\n", stdout)
+	stderrLines, err := network.GetStreamLogsMatching(testServiceName, docker.STDERR_LOG_STREAM, regexp.MustCompile(`Connected to peer .*`))
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(stderrLines))
+	stdoutLines, err := network.GetStreamLogsMatching(testServiceName, docker.STDOUT_LOG_STREAM, regexp.MustCompile(`Connected to peer .*`))
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(stdoutLines))
+
+	streamedStdout := &bytes.Buffer{}
+	streamedStderr := &bytes.Buffer{}
+	follower, err := network.StreamServiceLogs(testServiceName, streamedStdout, streamedStderr)
+	assert.NilError(t, err)
+	assert.NilError(t, follower.Stop())
+	assert.Equal(t, "{\"height\": 1}\n", streamedStdout.String())
+	assert.Equal(t, testLogs, streamedStderr.String())
+}
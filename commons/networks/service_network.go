@@ -3,16 +3,21 @@ package networks
 import (
 	"context"
 	"fmt"
+	"github.com/docker/go-connections/nat"
 	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/logging"
 	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/kurtosis-tech/kurtosis/commons/tracing"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
 	"net"
+	"sync"
 	"time"
 )
 
 /*
-The identifier used for services with the network.
+The identifier used for services with the network. Every ServiceNetwork API takes service IDs as this type (rather than
+	as a raw string or int), so a service ID can't be mixed up with a port, IP offset, or count without an explicit conversion.
  */
 type ServiceID string
 
@@ -29,6 +34,49 @@ type ServiceNode struct {
 
 	// The Docker container ID of the container running the node
 	ContainerId string
+
+	// The ID of the configuration the node was created from
+	configurationId ConfigurationID
+
+	// The services that the node depends on
+	dependencies []services.Service
+
+	// The IDs of the services that the node depends on
+	dependencyIds []ServiceID
+
+	// The Docker host the node runs on: 0 for the controller's host, or i for the i-th remote host
+	dockerHostIdx int
+
+	// True if the node's container was created with CreateService but hasn't yet been started with StartService
+	awaitingStart bool
+
+	// How long each step of the node's initial startup took (see ServiceInfo)
+	pullDuration   time.Duration
+	createDuration time.Duration
+	startDuration  time.Duration
+}
+
+/*
+A service configuration registered with ServiceNetworkBuilder.AddConfiguration, as returned by
+	ServiceNetworkBuilder.GetConfiguration.
+
+Configurations are immutable once registered: the builder takes a deep copy of the container options it's given, and
+	GetContainerOptions returns a deep copy, so neither the caller's options nor the options it gets back can change a
+	registered configuration. (The initializer & availability checker cores are the user's own implementations, so they're
+	shared rather than copied.)
+ */
+type ServiceConfig interface {
+	// Gets the Docker image that containers launched with the configuration run
+	GetDockerImage() string
+
+	// Gets the user-defined logic for how to launch the Docker container
+	GetInitializerCore() services.ServiceInitializerCore
+
+	// Gets the user-defined logic for how to report services launched with the configuration as available
+	GetAvailabilityCheckerCore() services.ServiceAvailabilityCheckerCore
+
+	// Gets a copy of the settings for the Docker containers launched with the configuration
+	GetContainerOptions() docker.ContainerOptions
 }
 
 /*
@@ -44,19 +92,54 @@ type serviceConfig struct {
 
 	// The implementation that will be used for determining whether a node launched using this configuration is available
 	availabilityCheckerCore services.ServiceAvailabilityCheckerCore
+
+	// Optional settings for the Docker containers of nodes launched using this configuration
+	containerOptions docker.ContainerOptions
+
+	// True if nodes launched using this configuration deliberately misbehave (see AddAdversarialConfiguration)
+	isAdversarial bool
+}
+
+func (config serviceConfig) GetDockerImage() string {
+	return config.dockerImage
+}
+
+func (config serviceConfig) GetInitializerCore() services.ServiceInitializerCore {
+	return config.initializerCore
+}
+
+func (config serviceConfig) GetAvailabilityCheckerCore() services.ServiceAvailabilityCheckerCore {
+	return config.availabilityCheckerCore
+}
+
+func (config serviceConfig) GetContainerOptions() docker.ContainerOptions {
+	return config.containerOptions.Copy()
 }
 
 
 /*
 A struct representing a network of services that will be used for a single test (commonly called the "test network"). This
 	struct is the low-level access point for modifying the test network.
+
+NOTE: This is thread-safe, so the network can be manipulated from several goroutines at once (e.g. the test, a chaos monkey,
+	and a resource watchdog). Each method holds the network's lock while it reads or changes the network, including while
+	it makes its Docker calls, so changes to the network are applied one at a time. Methods that block for a long time
+	(StressService, WaitForConvergence, GetLogsMatching, WaitForLogMatch) only hold the lock while looking up the services
+	they act on, and availability checkers are waited on without the lock, so they don't hold up the rest of the network.
+	Probes passed to WaitForConvergence are run without the lock, so they may call the network's methods.
  */
 type ServiceNetwork struct {
+	// Guards the network's services, configurations, partitions, & NAT proxies (the timeline has its own lock)
+	mutex *sync.Mutex
+
+	// The log entry that all of the network's log messages will be written to, tagged with fields identifying the test
+	log *logrus.Entry
+
 	// The tracker used for doling out new IPs within the subnet being used for this particular test network
 	freeIpTracker *FreeIpAddrTracker
 
 	// The Docker manager used for interacting with the Docker engine during test network manipulation
-	dockerManager *docker.DockerManager
+	dockerManager docker.ContainerManager
 
 	// The ID of the Docker network that this test network is running on
 	dockerNetworkId string
@@ -67,6 +150,19 @@ type ServiceNetwork struct {
 	// A mapping of configuration ID -> configuration details
 	configurations map[ConfigurationID]serviceConfig
 
+	// A "set" of the services that are currently cut off from the rest of the network by a partition
+	partitionedServices map[ServiceID]bool
+
+	// A mapping of service ID -> the NAT proxy in front of the service, for services that have one
+	natProxies map[ServiceID]natProxy
+
+	// The record of everything that has happened to services in the network
+	timeline *Timeline
+
+	// A mapping of container ID -> the result of inspecting the container's host ports & addresses, cached because they
+	//  don't change while the container keeps running (see container_network_infos.go)
+	containerNetworkInfos map[string]docker.ContainerNetworkInfo
+
 	// The name of the Docker volume that will be mounted on:
 	// 	a) every single Docker image launched on this network
 	//  b) the test controller running logic against this test network
@@ -74,12 +170,54 @@ type ServiceNetwork struct {
 
 	// The dirpath where the test volume is mounted on the controller (which is where this code will be running in)
 	testVolumeControllerDirpath string
+
+	// The dirpath on the controller where service data snapshots are stored (empty if snapshots aren't available)
+	snapshotsDirpath string
+
+	// The Docker managers for the other Docker Swarm hosts that services can be scheduled onto (see
+	//  ServiceNetworkBuilder.AddRemoteDockerHost)
+	remoteDockerManagers []docker.ContainerManager
+
+	// True if RemoveAll should only stop each service once every service depending on it has been stopped (see
+	//  ServiceNetworkBuilder.UseDependencyOrderedTeardown)
+	isTeardownDependencyOrdered bool
+
+	// True if every service's container must meet strict policy (see ServiceNetworkBuilder.UseStrictPolicy)
+	isStrictPolicyEnforced bool
+
+	// Mapping of external host -> "set" of ports on it that services can reach (see ServiceNetworkBuilder.AllowEgressTo)
+	egressAllowances map[string]map[int]bool
+
+	// Mapping of external host -> IP of the egress gateway that services reach it through, for gateways started so far
+	egressGatewayIps map[string]net.IP
+
+	// Names the services' containers (see ServiceNetworkBuilder.UseContainerNamer), or nil to let Docker name them
+	containerNamer ContainerNamer
+
+	// Mapping of service ID -> number of containers that have been named for the service, so that re-added services'
+	//  containers get unique names
+	containerNameUses map[ServiceID]int
+
+	// The hooks invoked around the creation & start of each service (see ServiceNetworkBuilder.AddServiceHook)
+	serviceHooks []ServiceHook
+
+	// How dependencies are checked to be available before their dependents start, or nil if they aren't (see
+	//  ServiceNetworkBuilder.UseDependencyAvailabilityPolicy)
+	dependencyAvailabilityPolicy *DependencyAvailabilityPolicy
+
+	// Guards the last probe errors, which availability checkers record without the network's lock
+	probeErrorsMutex *sync.Mutex
+
+	// Mapping of service ID -> why the last availability check of the service failed, for services that haven't become
+	//  available since (see GetAvailabilityDiagnostics)
+	lastProbeErrors map[ServiceID]error
 }
 
 /*
 Creates a new ServiceNetwork object with the given parameters.
 
 Args:
+	log: The log entry that the network will write its log messages to.
 	freeIpTracker: The IP tracker that will be used to provide IPs for new nodes added to the network.
 	dockerManager: The Docker manager that will be used for manipulating the Docker engine during test network modification.
 	dockerNetworkName: The name of the Docker network this test network is running on.
@@ -87,30 +225,114 @@ Args:
 	testVolume: The name of the Docker volume that will be mounted on all the nodes in the network.
 	testVolumeControllerDirpath: The dirpath that the test Docker volume is mounted on in the controller image (which will
 		be running all the code here).
+	snapshotsDirpath: The dirpath on the controller image where service data snapshots are stored, which should persist
+		across tests (or empty if snapshots aren't available).
+	remoteDockerManagers: The Docker managers for the other Docker Swarm hosts that services can be scheduled onto (empty
+		if every service should run on the controller's host).
+	isTeardownDependencyOrdered: True if RemoveAll should only stop each service once every service depending on it has
+		been stopped, rather than stopping every service at once.
+	isStrictPolicyEnforced: True if creating a service should fail if its container doesn't meet strict policy (see
+		docker.ContainerOptions.EnforceStrictPolicy), regardless of its configuration's options.
+	egressAllowances: Mapping of external host -> "set" of ports on it that services can reach through egress gateways,
+		which are started when the first service is added (see ServiceNetworkBuilder.AllowEgressTo).
+	containerNamer: Names the services' containers, or nil to let Docker name them.
+	serviceHooks: The hooks to invoke around the creation & start of each service, in order (see ServiceHook).
+	dependencyAvailabilityPolicy: How to check that each service's dependencies are available before starting the
+		service, or nil to not check them (see DependencyAvailabilityPolicy).
  */
 func NewServiceNetwork(
+			log *logrus.Entry,
 			freeIpTracker *FreeIpAddrTracker,
-			dockerManager *docker.DockerManager,
+			dockerManager docker.ContainerManager,
 			dockerNetworkId string,
 			configurations map[ConfigurationID]serviceConfig,
 			testVolume string,
-			testVolumeControllerDirpath string) *ServiceNetwork {
+			testVolumeControllerDirpath string,
+			snapshotsDirpath string,
+			remoteDockerManagers []docker.ContainerManager,
+			isTeardownDependencyOrdered bool,
+			isStrictPolicyEnforced bool,
+			egressAllowances map[string]map[int]bool,
+			containerNamer ContainerNamer,
+			serviceHooks []ServiceHook,
+			dependencyAvailabilityPolicy *DependencyAvailabilityPolicy) *ServiceNetwork {
 	return &ServiceNetwork{
-		freeIpTracker:               freeIpTracker,
-		dockerManager:               dockerManager,
-		dockerNetworkId:             dockerNetworkId,
-		serviceNodes:                make(map[ServiceID]ServiceNode),
-		configurations:              configurations,
-		testVolume:                  testVolume,
-		testVolumeControllerDirpath: testVolumeControllerDirpath,
+		mutex:                        &sync.Mutex{},
+		log:                          log,
+		freeIpTracker:                freeIpTracker,
+		dockerManager:                dockerManager,
+		dockerNetworkId:              dockerNetworkId,
+		serviceNodes:                 make(map[ServiceID]ServiceNode),
+		configurations:               configurations,
+		partitionedServices:          make(map[ServiceID]bool),
+		natProxies:                   make(map[ServiceID]natProxy),
+		timeline:                     newTimeline(),
+		containerNetworkInfos:        make(map[string]docker.ContainerNetworkInfo),
+		testVolume:                   testVolume,
+		testVolumeControllerDirpath:  testVolumeControllerDirpath,
+		snapshotsDirpath:             snapshotsDirpath,
+		remoteDockerManagers:         remoteDockerManagers,
+		isTeardownDependencyOrdered:  isTeardownDependencyOrdered,
+		isStrictPolicyEnforced:       isStrictPolicyEnforced,
+		egressAllowances:             egressAllowances,
+		egressGatewayIps:             make(map[string]net.IP),
+		containerNamer:               containerNamer,
+		containerNameUses:            make(map[ServiceID]int),
+		serviceHooks:                 serviceHooks,
+		dependencyAvailabilityPolicy: dependencyAvailabilityPolicy,
+		probeErrorsMutex:             &sync.Mutex{},
+		lastProbeErrors:              make(map[ServiceID]error),
 	}
 }
 
 // Gets the number of nodes in the network
 func (network *ServiceNetwork) GetSize() int {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
 	return len(network.serviceNodes)
 }
 
+/*
+Gets the ID of the test's own Docker network, which the initializer creates for the test (named after the test & the
+	execution's UUID, so that parallel tests don't collide) & removes when the test finishes.
+ */
+func (network *ServiceNetwork) GetDockerNetworkId() string {
+	return network.dockerNetworkId
+}
+
+/*
+Registers a service configuration on the running network, so that services can be added with it (e.g. a node running an
+	upgraded image, for upgrade tests) without it having been registered on the builder.
+
+Args:
+	configurationId: The ID by which this configuration will be referenced later
+	config: The configuration's Docker image, cores, & container options
+ */
+func (network *ServiceNetwork) AddServiceConfig(configurationId ConfigurationID, config ServiceConfig) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	if _, found := network.configurations[configurationId]; found {
+		return stacktrace.NewError("Configuration ID %v is already registered", configurationId)
+	}
+	newConfig, err := newServiceConfig(
+		config.GetDockerImage(),
+		config.GetInitializerCore(),
+		config.GetAvailabilityCheckerCore(),
+		config.GetContainerOptions())
+	if err != nil {
+		return stacktrace.Propagate(err, "Configuration ID %v is invalid", configurationId)
+	}
+	// The configurations may be shared with the builder (and other networks it built), so they're copied rather than
+	//  changed in place
+	configurationsCopy := make(map[ConfigurationID]serviceConfig, len(network.configurations) + 1)
+	for existingId, existingConfig := range network.configurations {
+		configurationsCopy[existingId] = existingConfig
+	}
+	configurationsCopy[configurationId] = newConfig
+	network.configurations = configurationsCopy
+	return nil
+}
+
 /*
 Adds a service to the network with the given service ID, created using the given configuration ID.
 
@@ -125,111 +347,940 @@ Return:
 	An AvailabilityChecker for checking when the new service is available and ready for use.
  */
 func (network *ServiceNetwork) AddService(configurationId ConfigurationID, serviceId ServiceID, dependencies map[ServiceID]bool) (*services.ServiceAvailabilityChecker, error) {
-	// Maybe one day we'll make this flow from somewhere up above (e.g. make the entire network live inside a single context)
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	return network.addService(configurationId, serviceId, nil, dependencies, []docker.ContainerArchive{})
+}
+
+/*
+Adds a service to the network like AddService, but with the given IP rather than the next free one, for tests that need
+	a service at a known address (e.g. a bootstrap node whose IP is baked into other nodes' configs). The IP is passed to
+	the service's start command & dependents' start commands as usual.
+
+Args:
+	configurationId: The ID of the service configuration to use for creating the service.
+	serviceId: The service ID that will be used to identify this node in the network.
+	ipAddr: The IP the service will have, which must be a free IP in the test network's subnet.
+	dependencies: A "set" of service IDs that the node being created will depend on (empty, not nil, for none).
+
+Return:
+	An AvailabilityChecker for checking when the new service is available and ready for use.
+ */
+func (network *ServiceNetwork) AddServiceWithIp(
+			configurationId ConfigurationID,
+			serviceId ServiceID,
+			ipAddr net.IP,
+			dependencies map[ServiceID]bool) (*services.ServiceAvailabilityChecker, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	if ipAddr == nil {
+		return nil, stacktrace.NewError("IP for service %v must not be nil; use AddService to have an IP picked", serviceId)
+	}
+	return network.addService(configurationId, serviceId, ipAddr, dependencies, []docker.ContainerArchive{})
+}
+
+/*
+Creates the container of a service without starting it, so that the caller can control when the service starts with
+	StartService (e.g. to start a group of services as close together as possible, or to leave some unstarted). The
+	service is registered in the network as soon as it's created, so it can be depended on & removed like any other.
+
+Args:
+	configurationId: The ID of the service configuration to use for creating the service.
+	serviceId: The service ID that will be used to identify this node in the network.
+	dependencies: A "set" of service IDs that the node being created will depend on (empty, not nil, for none).
+ */
+func (network *ServiceNetwork) CreateService(configurationId ConfigurationID, serviceId ServiceID, dependencies map[ServiceID]bool) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	return network.createService(configurationId, serviceId, nil, dependencies, []docker.ContainerArchive{})
+}
+
+/*
+Starts the container of a service that was created with CreateService.
+
+Return:
+	An AvailabilityChecker for checking when the service is available and ready for use, whose WaitForStartupWithTimeout
+		can be used to wait with a timeout other than the configuration's.
+ */
+func (network *ServiceNetwork) StartService(serviceId ServiceID) (*services.ServiceAvailabilityChecker, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	return network.startService(serviceId)
+}
+
+/*
+Gets the node information for the service with the given service ID.
+ */
+func (network *ServiceNetwork) GetService(serviceId ServiceID) (ServiceNode, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	node, found := network.serviceNodes[serviceId]
+	if !found {
+		return ServiceNode{}, stacktrace.NewError("No service with ID %v exists in the network", serviceId)
+	}
+
+	return node, nil
+}
+
+/*
+Gets a "set" of the IDs of the services currently in the network.
+ */
+func (network *ServiceNetwork) GetServiceIds() map[ServiceID]bool {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	result := make(map[ServiceID]bool)
+	for serviceId, _ := range network.serviceNodes {
+		result[serviceId] = true
+	}
+	return result
+}
+
+// Starts the container of a service that was created with createService; must be called with the network's lock held
+func (network *ServiceNetwork) startService(serviceId ServiceID) (*services.ServiceAvailabilityChecker, error) {
 	parentCtx := context.Background()
 
-	config, found := network.configurations[configurationId]
+	nodeInfo, found := network.serviceNodes[serviceId]
 	if !found {
-		return nil, stacktrace.NewError("No service configuration with ID '%v' has been registered", configurationId)
+		return nil, stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	if !nodeInfo.awaitingStart {
+		return nil, stacktrace.NewError("Service ID %v has already been started", serviceId)
 	}
 
-	if _, exists := network.serviceNodes[serviceId]; exists {
-		return nil, stacktrace.NewError("Service ID %s already exists in the network", serviceId)
+	if err := network.waitForDependencies(serviceId, nodeInfo); err != nil {
+		return nil, stacktrace.Propagate(err, "Can't start service ID %v until its dependencies are available", serviceId)
 	}
 
-	if dependencies == nil {
-		return nil, stacktrace.NewError("Dependencies map was nil; use an empty map to specify no dependencies")
+	network.serviceLog(serviceId).Debugf("Starting service ID %v...", serviceId)
+	startStartTime := time.Now()
+	if err := network.getDockerManager(nodeInfo).StartContainer(parentCtx, nodeInfo.ContainerId); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred starting service ID %v", serviceId)
+	}
+	nodeInfo.startDuration = time.Since(startStartTime)
+	nodeInfo.awaitingStart = false
+	network.serviceNodes[serviceId] = nodeInfo
+	network.forgetContainerNetworkInfo(nodeInfo.ContainerId)
+	network.timeline.record(SERVICE_STARTED, serviceId)
+	if err := network.runAfterServiceStartHooks(serviceId, nodeInfo); err != nil {
+		return nil, stacktrace.Propagate(err, "A service hook failed after starting service ID %v", serviceId)
 	}
 
-	// Golang maps are passed by-ref, so we do a defensive copy here so user can't change their input and mess
-	// with our internal data structure
-	dependencyServices := make([]services.Service, 0, len(dependencies))
-	for dependencyId, _ := range dependencies  {
-		dependencyNode, found := network.serviceNodes[dependencyId]
-		if !found {
-			return nil, stacktrace.NewError("Declared a dependency on %v but no service with this ID has been registered", dependencyId)
-		}
-		dependencyServices = append(dependencyServices, dependencyNode.Service)
+	config := network.configurations[nodeInfo.configurationId]
+	availabilityChecker := network.newAvailabilityChecker(parentCtx, serviceId, config, nodeInfo)
+	return availabilityChecker, nil
+}
+
+/*
+Restarts the service with the given ID by replacing its container with a fresh one that keeps the same static IP,
+	hostname, and volumes, so that peers see the same node come back (as they would after a real crash and recovery).
+
+Args:
+	serviceId: The ID of the service to restart
+	containerStopTimeout: How long to wait for the old container to stop before force-killing it
+
+Return:
+	An AvailabilityChecker for checking when the restarted service is available again.
+ */
+func (network *ServiceNetwork) RestartService(serviceId ServiceID, containerStopTimeout time.Duration) (*services.ServiceAvailabilityChecker, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	parentCtx := context.Background()
+
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return nil, stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	config, found := network.configurations[nodeInfo.configurationId]
+	if !found {
+		return nil, stacktrace.NewError("Service ID %v was created with configuration %v, which no longer exists", serviceId, nodeInfo.configurationId)
 	}
 
-	staticIp, err := network.freeIpTracker.GetFreeIpAddr()
+	network.serviceLog(serviceId).Debugf("Restarting service ID %v...", serviceId)
+	newContainerId, err := network.getDockerManager(nodeInfo).RecreateContainer(parentCtx, nodeInfo.ContainerId, containerStopTimeout)
 	if err != nil {
-		return nil, stacktrace.Propagate(err, "Failed to allocate static IP for service %s", serviceId)
+		return nil, stacktrace.Propagate(err, "An error occurred recreating the container for service ID %v", serviceId)
 	}
+	network.forgetContainerNetworkInfo(nodeInfo.ContainerId)
+	nodeInfo.ContainerId = newContainerId
+	network.serviceNodes[serviceId] = nodeInfo
+	network.serviceLog(serviceId).Debugf("Successfully restarted service ID %v in new container %v", serviceId, newContainerId)
 
-	initializer := services.NewServiceInitializer(config.initializerCore, network.dockerNetworkId, network.testVolumeControllerDirpath)
-	service, containerId, err := initializer.CreateService(
-			parentCtx,
-			network.testVolume,
-			config.dockerImage,
-			staticIp,
-			network.dockerManager,
-			dependencyServices)
+	network.timeline.record(SERVICE_RESTARTED, serviceId)
+	if err := network.runAfterServiceStartHooks(serviceId, nodeInfo); err != nil {
+		return nil, stacktrace.Propagate(err, "A service hook failed after restarting service ID %v", serviceId)
+	}
+
+	availabilityChecker := network.newAvailabilityChecker(parentCtx, serviceId, config, nodeInfo)
+	return availabilityChecker, nil
+}
+
+/*
+Takes a snapshot of how much CPU & memory the service with the given ID is currently using.
+ */
+func (network *ServiceNetwork) GetServiceResourceUsage(serviceId ServiceID) (docker.ContainerResourceUsage, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return docker.ContainerResourceUsage{}, stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+
+	usage, err := network.getDockerManager(nodeInfo).GetContainerResourceUsage(context.Background(), nodeInfo.ContainerId)
 	if err != nil {
-		return nil, stacktrace.Propagate(err, "An error occurred creating service %v from configuration %v", serviceId, configurationId)
+		return docker.ContainerResourceUsage{}, stacktrace.Propagate(err, "An error occurred getting the resource usage of service ID %v", serviceId)
 	}
+	return usage, nil
+}
 
-	network.serviceNodes[serviceId] = ServiceNode{
-		IpAddr:      staticIp,
-		Service:     service,
-		ContainerId: containerId,
+/*
+Runs the given command in the container of the service with the given ID (e.g. to trigger the service's admin CLI or
+	inspect its data directory); see DockerManager.RunExecCommand. The network isn't locked while the command runs, so
+	other calls on the network don't wait for it.
+
+Returns:
+	exitCode: The command's exit code (where a nonzero exit code isn't an error)
+	logs: The command's STDOUT & STDERR, interleaved in the order they were written
+ */
+func (network *ServiceNetwork) RunExecCommand(serviceId ServiceID, cmd []string, timeout time.Duration) (exitCode int, logs string, err error) {
+	network.mutex.Lock()
+	nodeInfo, found := network.serviceNodes[serviceId]
+	network.mutex.Unlock()
+	if !found {
+		return 0, "", stacktrace.NewError("No service with ID %v found", serviceId)
 	}
 
-	availabilityChecker := services.NewServiceAvailabilityChecker(parentCtx, config.availabilityCheckerCore, service, dependencyServices)
-	return availabilityChecker, nil
+	exitCode, logs, err = network.getDockerManager(nodeInfo).RunExecCommand(context.Background(), nodeInfo.ContainerId, cmd, timeout)
+	if err != nil {
+		return 0, logs, stacktrace.Propagate(err, "An error occurred running command %v in service ID %v", cmd, serviceId)
+	}
+	return exitCode, logs, nil
 }
 
 /*
-Gets the node information for the service with the given service ID.
+Gets the IP address of the service with the given ID within the test network.
  */
-func (network *ServiceNetwork) GetService(serviceId ServiceID) (ServiceNode, error) {
-	node, found := network.serviceNodes[serviceId]
+func (network *ServiceNetwork) GetServiceIp(serviceId ServiceID) (net.IP, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	nodeInfo, found := network.serviceNodes[serviceId]
 	if !found {
-		return ServiceNode{}, stacktrace.NewError("No service with ID %v exists in the network", serviceId)
+		return nil, stacktrace.NewError("No service with ID %v found", serviceId)
 	}
+	return nodeInfo.IpAddr, nil
+}
 
-	return node, nil
+/*
+Gets a mapping of the ID of every service in the network -> its IP address within the test network (see GetServiceIp).
+	The IPs are statically assigned from the network's subnet, lowest-free-first, so the same sequence of service
+	additions always gets the same IPs.
+ */
+func (network *ServiceNetwork) GetServiceIps() map[ServiceID]net.IP {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	result := make(map[ServiceID]net.IP)
+	for serviceId, nodeInfo := range network.serviceNodes {
+		result[serviceId] = nodeInfo.IpAddr
+	}
+	return result
+}
+
+/*
+Gets the port on the Docker host that the given port of the service with the given ID can be reached at from the host,
+	which requires the service's configuration to publish the port (see docker.ContainerOptions.PublishPorts &
+	PublishedPorts) or use the host's network.
+ */
+func (network *ServiceNetwork) GetServiceHostPort(serviceId ServiceID, port nat.Port) (int, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return 0, stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	if network.isHostNetworked(nodeInfo) {
+		return port.Int(), nil
+	}
+
+	containerNetworkInfo, err := network.getContainerNetworkInfo(nodeInfo)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "An error occurred getting the host port of port %v of service ID %v", port, serviceId)
+	}
+	hostPort, found := containerNetworkInfo.HostPorts[port]
+	if !found {
+		return 0, stacktrace.NewError("Port %v of service ID %v isn't published on the host", port, serviceId)
+	}
+	return hostPort, nil
+}
+
+/*
+Gets the IPv6 address of the service with the given ID, which services have on dual-stack test networks in addition to
+	their IPv4 address (on IPv6-only test networks, this is the same as the node's IpAddr).
+
+Returns:
+	The service's IPv6 address, or nil if the test network has no IPv6 subnet
+ */
+func (network *ServiceNetwork) GetServiceIpv6Addr(serviceId ServiceID) (net.IP, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return nil, stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	if nodeInfo.IpAddr.To4() == nil {
+		return nodeInfo.IpAddr, nil
+	}
+
+	containerNetworkInfo, err := network.getContainerNetworkInfo(nodeInfo)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the IPv6 address of service ID %v", serviceId)
+	}
+	return containerNetworkInfo.Ipv6Addrs[network.dockerNetworkId], nil
+}
+
+/*
+Gets the IP address of the service with the given ID on the given Docker network, for services attached to additional
+	networks (see ServiceNetworkBuilder.CreateNetwork).
+
+Returns:
+	The service's IP on the network, or an error if the service isn't attached to it
+ */
+func (network *ServiceNetwork) GetServiceIpOnNetwork(serviceId ServiceID, dockerNetworkId string) (net.IP, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return nil, stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	if dockerNetworkId == network.dockerNetworkId {
+		return nodeInfo.IpAddr, nil
+	}
+
+	containerNetworkInfo, err := network.getContainerNetworkInfo(nodeInfo)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the IP of service ID %v on network %v", serviceId, dockerNetworkId)
+	}
+	ipAddr, found := containerNetworkInfo.IpAddrs[dockerNetworkId]
+	if !found {
+		return nil, stacktrace.NewError("Service ID %v isn't attached to network %v", serviceId, dockerNetworkId)
+	}
+	return ipAddr, nil
 }
 
 /*
-Stops the container with the given service ID, and removes it from the network.
+Gets the hostname that other services in the network can reach the service with the given ID by, which is stable across
+	restarts (unlike the container ID).
+ */
+func (network *ServiceNetwork) GetServiceHostname(serviceId ServiceID) (string, error) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return "", stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	if network.isHostNetworked(nodeInfo) {
+		return "", stacktrace.NewError("Service ID %v runs on the host's network, so it has no hostname; use its IP instead", serviceId)
+	}
+	return string(serviceId), nil
+}
+
+/*
+Gets a mapping of every hostname that services in the network can be reached by (service IDs, plus any aliases from the
+	services' configurations) -> the IP it resolves to, mirroring what Docker's DNS server will answer inside the network.
+	Services running on the host's network have no hostnames.
+
+NOTE: A configuration alias shared by several services resolves to any one of them (Docker round-robins between them), so
+	the IP it maps to here is arbitrary.
+ */
+func (network *ServiceNetwork) GetResolverMap() map[string]net.IP {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	result := make(map[string]net.IP)
+	for serviceId, nodeInfo := range network.serviceNodes {
+		config := network.configurations[nodeInfo.configurationId]
+		if config.containerOptions.UseHostNetwork {
+			// Host-networked services aren't on the Docker network, so Docker's DNS server doesn't know about them
+			continue
+		}
+		for _, alias := range getNetworkAliases(serviceId, config) {
+			result[alias] = nodeInfo.IpAddr
+		}
+	}
+	return result
+}
+
+/*
+Gets the timeline of every lifecycle event that has happened to services in this network so far.
+ */
+func (network *ServiceNetwork) GetTimeline() *Timeline {
+	return network.timeline
+}
+
+/*
+Stops the container with the given service ID, and removes it from the network, freeing its IP to be given to a later
+	service.
  */
 func (network *ServiceNetwork) RemoveService(serviceId ServiceID, containerStopTimeout time.Duration) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	return network.removeService(serviceId, containerStopTimeout)
+}
+
+// Stops the container of the given service & removes it from the network; must be called with the network's lock held
+func (network *ServiceNetwork) removeService(serviceId ServiceID, containerStopTimeout time.Duration) error {
+	if _, found := network.serviceNodes[serviceId]; !found {
+		return stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+	network.removeServices(map[ServiceID]bool{serviceId: true}, containerStopTimeout)
+	return nil
+}
+
+/*
+Removes the given services (which must exist) from the network, making a best-effort attempt to stop their containers
+	(& NAT proxies) all at once, since stopping each container can take up to the stop timeout. The IPs of the containers
+	that stop are released, so that later services can be given them. Must be called with the network's lock held.
+ */
+func (network *ServiceNetwork) removeServices(serviceIds map[ServiceID]bool, containerStopTimeout time.Duration) {
 	// Maybe one day we'll store this on the ServiceNetwork itself, to represent the test context that the ServiceNetwork
 	//  was created in
 	parentCtx := context.Background()
 
+	// The network's state is updated up front, so that only the Docker calls happen in parallel
+	removedNodes := make(map[ServiceID]ServiceNode)
+	removedNatProxies := make(map[ServiceID]natProxy)
+	for serviceId, _ := range serviceIds {
+		nodeInfo := network.serviceNodes[serviceId]
+		network.serviceLog(serviceId).Debugf("Removing service ID %v...", serviceId)
+		delete(network.serviceNodes, serviceId)
+		network.forgetContainerNetworkInfo(nodeInfo.ContainerId)
+		network.timeline.record(SERVICE_REMOVED, serviceId)
+		removedNodes[serviceId] = nodeInfo
+		if proxy, found := network.natProxies[serviceId]; found {
+			delete(network.natProxies, serviceId)
+			removedNatProxies[serviceId] = proxy
+		}
+	}
+
+	// The IPs of containers that failed to stop may still be in use, so only those of stopped containers are released
+	stoppedIpsMutex := &sync.Mutex{}
+	stoppedIps := []net.IP{}
+	waitGroup := &sync.WaitGroup{}
+	for serviceId, proxy := range removedNatProxies {
+		waitGroup.Add(1)
+		go func(serviceId ServiceID, proxy natProxy) {
+			defer waitGroup.Done()
+			// Make a best-effort attempt to stop the service's NAT proxy
+			if err := network.dockerManager.StopContainer(parentCtx, proxy.containerId, &containerStopTimeout); err != nil {
+				network.serviceLog(serviceId).Errorf("The following error occurred removing the NAT proxy of service ID %v:", serviceId)
+				fmt.Fprintln(network.log.Logger.Out, err)
+				return
+			}
+			stoppedIpsMutex.Lock()
+			defer stoppedIpsMutex.Unlock()
+			stoppedIps = append(stoppedIps, proxy.ipAddr)
+		}(serviceId, proxy)
+	}
+	for serviceId, nodeInfo := range removedNodes {
+		waitGroup.Add(1)
+		go func(serviceId ServiceID, nodeInfo ServiceNode) {
+			defer waitGroup.Done()
+			// Make a best-effort attempt to stop the container
+			err := network.getDockerManager(nodeInfo).StopContainer(parentCtx, nodeInfo.ContainerId, &containerStopTimeout)
+			if err != nil {
+				network.serviceLog(serviceId).Errorf(
+					"The following error occurred stopping service ID %v with container ID %v; proceeding to stop other containers:",
+					serviceId,
+					nodeInfo.ContainerId)
+				fmt.Fprintln(network.log.Logger.Out, err)
+				return
+			}
+			network.serviceLog(serviceId).Debugf("Successfully removed service ID %v", serviceId)
+			// Host-networked services use the host's IP, which was never taken from the tracker
+			if !network.isHostNetworked(nodeInfo) {
+				stoppedIpsMutex.Lock()
+				defer stoppedIpsMutex.Unlock()
+				stoppedIps = append(stoppedIps, nodeInfo.IpAddr)
+			}
+		}(serviceId, nodeInfo)
+	}
+	waitGroup.Wait()
+	if len(stoppedIps) > 0 {
+		network.freeIpTracker.ReleaseIpAddrs(stoppedIps)
+	}
+}
+
+/*
+Abruptly kills the container running the service with the given ID, simulating a crash. Unlike RemoveService, the service
+	stays registered in the network so that it can later be brought back with ReviveService.
+ */
+func (network *ServiceNetwork) KillService(serviceId ServiceID) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	parentCtx := context.Background()
+
 	nodeInfo, found := network.serviceNodes[serviceId]
 	if !found {
 		return stacktrace.NewError("No service with ID %v found", serviceId)
 	}
 
-	logrus.Debugf("Removing service ID %v...", serviceId)
-	delete(network.serviceNodes, serviceId)
+	network.serviceLog(serviceId).Debugf("Killing service ID %v...", serviceId)
+	if err := network.getDockerManager(nodeInfo).KillContainer(parentCtx, nodeInfo.ContainerId); err != nil {
+		return stacktrace.Propagate(err, "An error occurred killing service ID %v", serviceId)
+	}
+	network.forgetContainerNetworkInfo(nodeInfo.ContainerId)
+	network.timeline.record(SERVICE_KILLED, serviceId)
+	network.serviceLog(serviceId).Debugf("Successfully killed service ID %v", serviceId)
+	return nil
+}
+
+/*
+Starts the container of a service that was previously killed with KillService. The same container is reused, so the
+	service comes back with the same IP, hostname, and volumes.
 
-	// Make a best-effort attempt to stop the container
-	err := network.dockerManager.StopContainer(parentCtx, nodeInfo.ContainerId, &containerStopTimeout)
+NOTE: This does not wait for the service to become available again; that's up to the caller.
+ */
+func (network *ServiceNetwork) ReviveService(serviceId ServiceID) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	parentCtx := context.Background()
+
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+
+	network.serviceLog(serviceId).Debugf("Reviving service ID %v...", serviceId)
+	if err := network.getDockerManager(nodeInfo).StartContainer(parentCtx, nodeInfo.ContainerId); err != nil {
+		return stacktrace.Propagate(err, "An error occurred reviving service ID %v", serviceId)
+	}
+	network.forgetContainerNetworkInfo(nodeInfo.ContainerId)
+	network.timeline.record(SERVICE_REVIVED, serviceId)
+	network.serviceLog(serviceId).Debugf("Successfully revived service ID %v", serviceId)
+	return nil
+}
+
+/*
+Freezes every process in the container of the service with the given ID, simulating a node that hangs (e.g. on a long GC
+	pause) rather than crashing: its connections stay open, but it stops responding until UnpauseService is called.
+ */
+func (network *ServiceNetwork) PauseService(serviceId ServiceID) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	parentCtx := context.Background()
+
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+
+	network.serviceLog(serviceId).Debugf("Pausing service ID %v...", serviceId)
+	if err := network.getDockerManager(nodeInfo).PauseContainer(parentCtx, nodeInfo.ContainerId); err != nil {
+		return stacktrace.Propagate(err, "An error occurred pausing service ID %v", serviceId)
+	}
+	network.timeline.record(SERVICE_PAUSED, serviceId)
+	network.serviceLog(serviceId).Debugf("Successfully paused service ID %v", serviceId)
+	return nil
+}
+
+/*
+Resumes the processes of a service that was previously paused with PauseService, which carry on from where they were
+	frozen.
+ */
+func (network *ServiceNetwork) UnpauseService(serviceId ServiceID) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	parentCtx := context.Background()
+
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+
+	network.serviceLog(serviceId).Debugf("Unpausing service ID %v...", serviceId)
+	if err := network.getDockerManager(nodeInfo).UnpauseContainer(parentCtx, nodeInfo.ContainerId); err != nil {
+		return stacktrace.Propagate(err, "An error occurred unpausing service ID %v", serviceId)
+	}
+	network.timeline.record(SERVICE_UNPAUSED, serviceId)
+	network.serviceLog(serviceId).Debugf("Successfully unpaused service ID %v", serviceId)
+	return nil
+}
+
+/*
+Starves the service with the given ID of CPU for the given duration by tightening its container's CPU cgroup, then
+	restores the CPU limit that the service had before (e.g. its configuration's ContainerOptions.CpuPercent), if any. This call blocks for the duration of the stress, so run it in a separate goroutine
+	if the test needs to act on the network while the service is starved.
+
+Args:
+	serviceId: The ID of the service to starve
+	cpuPercent: The percentage of a single CPU core that the service will be limited to during the stress
+	duration: How long the service should be starved for
+ */
+func (network *ServiceNetwork) StressService(serviceId ServiceID, cpuPercent uint, duration time.Duration) error {
+	parentCtx := context.Background()
+
+	// We don't hold the network's lock while the service is starved, so the rest of the network can be used meanwhile
+	nodeInfo, err := network.GetService(serviceId)
+	if err != nil {
+		return stacktrace.Propagate(err, "Cannot stress service ID %v", serviceId)
+	}
+
+	originalCpuPercent, err := network.getDockerManager(nodeInfo).GetContainerCpuLimit(parentCtx, nodeInfo.ContainerId)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred getting the CPU limit of service ID %v before stressing it", serviceId)
+	}
+
+	network.serviceLog(serviceId).Debugf("Limiting service ID %v to %v%% of a CPU for %v...", serviceId, cpuPercent, duration)
+	if err := network.getDockerManager(nodeInfo).LimitContainerCpu(parentCtx, nodeInfo.ContainerId, cpuPercent); err != nil {
+		return stacktrace.Propagate(err, "An error occurred limiting the CPU of service ID %v", serviceId)
+	}
+	network.timeline.record(SERVICE_STRESSED, serviceId)
+	time.Sleep(duration)
+	if originalCpuPercent > 0 {
+		err = network.getDockerManager(nodeInfo).LimitContainerCpu(parentCtx, nodeInfo.ContainerId, originalCpuPercent)
+	} else {
+		err = network.getDockerManager(nodeInfo).UnlimitContainerCpu(parentCtx, nodeInfo.ContainerId)
+	}
 	if err != nil {
-		logrus.Errorf(
-			"The following error occurred stopping service ID %v with container ID %v; proceeding to stop other containers:",
-			serviceId,
-			nodeInfo.ContainerId)
-		fmt.Fprintln(logrus.StandardLogger().Out, err)
+		return stacktrace.Propagate(err, "An error occurred restoring the CPU of service ID %v after stressing it", serviceId)
 	}
-	logrus.Debugf("Successfully removed service ID %v", serviceId)
+	network.timeline.record(SERVICE_UNSTRESSED, serviceId)
+	network.serviceLog(serviceId).Debugf("Restored CPU of service ID %v", serviceId)
 	return nil
 }
 
+/*
+Creates an availability checker for the given service which records the service's availability on the timeline, & whose
+	WaitForStartup fails fast if the service's container stops running
+ */
+func (network *ServiceNetwork) newAvailabilityChecker(
+			ctx context.Context,
+			serviceId ServiceID,
+			config serviceConfig,
+			nodeInfo ServiceNode) *services.ServiceAvailabilityChecker {
+	availabilityChecker := services.NewServiceAvailabilityChecker(
+			ctx,
+			string(serviceId),
+			config.availabilityCheckerCore,
+			nodeInfo.Service,
+			nodeInfo.dependencies)
+	availabilityChecker.OnAvailable(func() {
+		network.setLastProbeError(serviceId, nil)
+		network.timeline.record(SERVICE_AVAILABLE, serviceId)
+	})
+	availabilityChecker.OnProbeFailed(func(probeErr error) {
+		network.setLastProbeError(serviceId, probeErr)
+	})
+	availabilityChecker.UseCrashCheck(func() error {
+		return network.checkForStartupCrash(serviceId, nodeInfo)
+	})
+	return availabilityChecker
+}
+
+/*
+Gets a "set" of the IDs of the services in the network that no other service in the network depends on (or of every
+	service, if every service is depended on, which dependencies being declared at creation time should prevent). Must be
+	called with the network's lock held.
+ */
+func (network *ServiceNetwork) getUndependedServiceIds() map[ServiceID]bool {
+	dependedServiceIds := make(map[ServiceID]bool)
+	for _, nodeInfo := range network.serviceNodes {
+		for _, dependencyId := range nodeInfo.dependencyIds {
+			dependedServiceIds[dependencyId] = true
+		}
+	}
+	result := make(map[ServiceID]bool)
+	for serviceId, _ := range network.serviceNodes {
+		if !dependedServiceIds[serviceId] {
+			result[serviceId] = true
+		}
+	}
+	if len(result) == 0 {
+		for serviceId, _ := range network.serviceNodes {
+			result[serviceId] = true
+		}
+	}
+	return result
+}
+
+// Gets a log entry tagged with the given service ID
+func (network *ServiceNetwork) serviceLog(serviceId ServiceID) *logrus.Entry {
+	return network.log.WithField(logging.SERVICE_ID_FIELD, serviceId)
+}
+
 /*
 Makes a best-effort attempt to remove all the containers in the network, waiting for the given timeout and returning
-	an error if the timeout is reached.
+	an error if the timeout is reached. The containers are all stopped at once, unless the network was built to be torn
+	down in dependency order (see ServiceNetworkBuilder.UseDependencyOrderedTeardown), in which case each service is only
+	stopped once every service depending on it has been.
 
 Args:
 	containerStopTimeout: How long to wait for each container to stop before force-killing it
 */
 func (network *ServiceNetwork) RemoveAll(containerStopTimeout time.Duration) error {
-	for serviceId, _ := range network.serviceNodes {
-		network.RemoveService(serviceId, containerStopTimeout)
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	if network.isTeardownDependencyOrdered {
+		for len(network.serviceNodes) > 0 {
+			network.removeServices(network.getUndependedServiceIds(), containerStopTimeout)
+		}
+	} else {
+		allServiceIds := make(map[ServiceID]bool)
+		for serviceId, _ := range network.serviceNodes {
+			allServiceIds[serviceId] = true
+		}
+		network.removeServices(allServiceIds, containerStopTimeout)
+	}
+
+	// Docker creates the test volume on remote hosts when their containers mount it, and the initializer can only clean
+	//  up the controller's host, so we make a best-effort attempt at cleaning the remote hosts here
+	for _, remoteDockerManager := range network.remoteDockerManagers {
+		if err := remoteDockerManager.RemoveVolume(context.Background(), network.testVolume, true); err != nil {
+			network.log.Errorf("The following error occurred removing test volume %v from a remote Docker host:", network.testVolume)
+			fmt.Fprintln(network.log.Logger.Out, err)
+		}
 	}
 	return nil
 }
+
+/*
+Adds a service to the network with the given IP (or the next free IP, if nil), extracting the given archives into the
+	service's container (after those of its configuration) before it starts.
+ */
+func (network *ServiceNetwork) addService(
+			configurationId ConfigurationID,
+			serviceId ServiceID,
+			requestedIp net.IP,
+			dependencies map[ServiceID]bool,
+			extraArchives []docker.ContainerArchive) (*services.ServiceAvailabilityChecker, error) {
+	if err := network.createService(configurationId, serviceId, requestedIp, dependencies, extraArchives); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating service %v", serviceId)
+	}
+	availabilityChecker, err := network.startService(serviceId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred starting service %v", serviceId)
+	}
+	return availabilityChecker, nil
+}
+
+/*
+Creates the container of a service with the given IP (or the next free IP, if nil) without starting it, extracting the
+	given archives into the container (after those of its configuration).
+ */
+func (network *ServiceNetwork) createService(
+			configurationId ConfigurationID,
+			serviceId ServiceID,
+			requestedIp net.IP,
+			dependencies map[ServiceID]bool,
+			extraArchives []docker.ContainerArchive) (err error) {
+	// Maybe one day we'll make this flow from somewhere up above (e.g. make the entire network live inside a single context)
+	parentCtx := context.Background()
+
+	spanCtx, span := tracing.StartSpan(parentCtx, "CreateService")
+	span.SetAttribute(logging.SERVICE_ID_FIELD, serviceId)
+	span.SetAttribute("configuration_id", configurationId)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	// The service ID is the service's hostname on the network, so the service couldn't be reached without one
+	if serviceId == "" {
+		return stacktrace.NewError("Service ID must not be empty")
+	}
+
+	config, found := network.configurations[configurationId]
+	if !found {
+		return stacktrace.NewError("No service configuration with ID '%v' has been registered", configurationId)
+	}
+
+	if _, exists := network.serviceNodes[serviceId]; exists {
+		return stacktrace.NewError("Service ID %s already exists in the network", serviceId)
+	}
+
+	if dependencies == nil {
+		return stacktrace.NewError("Dependencies map was nil; use an empty map to specify no dependencies")
+	}
+
+	// Golang maps are passed by-ref, so we do a defensive copy here so user can't change their input and mess
+	// with our internal data structure
+	dependencyServices := make([]services.Service, 0, len(dependencies))
+	dependencyIpAddrs := make(map[string]net.IP)
+	dependencyIds := make([]ServiceID, 0, len(dependencies))
+	for dependencyId, _ := range dependencies  {
+		dependencyNode, found := network.serviceNodes[dependencyId]
+		if !found {
+			return stacktrace.NewError("Declared a dependency on %v but no service with this ID has been registered", dependencyId)
+		}
+		dependencyServices = append(dependencyServices, dependencyNode.Service)
+		// Dependencies behind a NAT proxy are only reachable at their external address, as they would be behind a real NAT
+		dependencyIpAddrs[string(dependencyId)] = network.getExternalIp(dependencyId, dependencyNode)
+		dependencyIds = append(dependencyIds, dependencyId)
+	}
+
+	dockerHostIdx := network.pickDockerHost(config)
+	dockerManager := network.getDockerManager(ServiceNode{dockerHostIdx: dockerHostIdx})
+
+	var staticIp net.IP
+	if config.containerOptions.UseHostNetwork {
+		if requestedIp != nil {
+			return stacktrace.NewError("Service %v runs on the host's network, so it can't be given an IP", serviceId)
+		}
+		// Host-networked services bind their ports directly on the host, which the rest of the network reaches via the gateway
+		if err := network.checkHostPortsAvailable(serviceId, config); err != nil {
+			return stacktrace.Propagate(err, "Can't start service %v on the host's network", serviceId)
+		}
+		staticIp, err = network.dockerManager.GetNetworkGatewayIp(spanCtx, network.dockerNetworkId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the host's IP on the network for service %s", serviceId)
+		}
+	} else if requestedIp != nil {
+		if err := network.freeIpTracker.TakeIpAddr(requestedIp); err != nil {
+			return stacktrace.Propagate(err, "Failed to reserve IP %v for service %s", requestedIp, serviceId)
+		}
+		staticIp = requestedIp
+	} else {
+		staticIp, err = network.freeIpTracker.GetFreeIpAddr()
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to allocate static IP for service %s", serviceId)
+		}
+	}
+	if !config.containerOptions.UseHostNetwork {
+		// Failed creations give the IP back, so that they don't use up the network's subnet
+		defer func() {
+			if err != nil {
+				network.freeIpTracker.ReleaseIpAddr(staticIp)
+			}
+		}()
+	}
+
+	// Defensive copy, so the extra archives don't leak into the configuration's options
+	containerOptions := config.containerOptions.Copy()
+	containerOptions.Archives = append(containerOptions.Archives, extraArchives...)
+	containerOptions.NetworkAliases = getNetworkAliases(serviceId, config)
+	containerOptions.Name = network.getNextContainerName(serviceId)
+	containerOptions.EnforceStrictPolicy = containerOptions.EnforceStrictPolicy || network.isStrictPolicyEnforced
+	if containerOptions.Labels == nil {
+		containerOptions.Labels = map[string]string{}
+	}
+	// These let tools outside the test (e.g. the CLI's "inspect" command) find the test's services
+	containerOptions.Labels[docker.TEST_VOLUME_LABEL] = network.testVolume
+	containerOptions.Labels[docker.SERVICE_ID_LABEL] = string(serviceId)
+	if config.isAdversarial {
+		containerOptions.Labels[docker.ADVERSARIAL_LABEL] = docker.ADVERSARIAL_LABEL_VALUE
+	}
+	if len(network.serviceHooks) > 0 {
+		containerOptions.Customizer = network.getHookedCustomizer(serviceId, containerOptions.Customizer)
+	}
+	if !containerOptions.UseHostNetwork && len(network.egressAllowances) > 0 {
+		egressExtraHosts, err := network.ensureEgressGateways(spanCtx)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred starting the network's egress gateways for service %v", serviceId)
+		}
+		containerOptions.ExtraHosts = append(containerOptions.ExtraHosts, egressExtraHosts...)
+	}
+
+	// Creating the container would pull the image anyway, but we pull it separately so the two can be timed apart
+	pullStartTime := time.Now()
+	if err := dockerManager.PullImageIfMissing(spanCtx, config.dockerImage); err != nil {
+		return stacktrace.Propagate(err, "An error occurred pulling image %v for service %v", config.dockerImage, serviceId)
+	}
+	pullDuration := time.Since(pullStartTime)
+
+	createStartTime := time.Now()
+	initializer := services.NewServiceInitializer(config.initializerCore, network.dockerNetworkId, network.testVolumeControllerDirpath)
+	service, containerId, err := initializer.CreateUnstartedService(
+			spanCtx,
+			network.testVolume,
+			config.dockerImage,
+			staticIp,
+			dockerManager,
+			dependencyServices,
+			dependencyIpAddrs,
+			containerOptions)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating service %v from configuration %v", serviceId, configurationId)
+	}
+
+	network.serviceNodes[serviceId] = ServiceNode{
+		IpAddr:          staticIp,
+		Service:         service,
+		ContainerId:     containerId,
+		configurationId: configurationId,
+		dependencies:    dependencyServices,
+		dependencyIds:   dependencyIds,
+		dockerHostIdx:   dockerHostIdx,
+		awaitingStart:   true,
+		pullDuration:    pullDuration,
+		createDuration:  time.Since(createStartTime),
+	}
+	return nil
+}
+
+/*
+Gets the DNS aliases that the service with the given ID will be reachable by on the network: its service ID, followed by
+	any aliases from its configuration.
+ */
+func getNetworkAliases(serviceId ServiceID, config serviceConfig) []string {
+	return append([]string{string(serviceId)}, config.containerOptions.NetworkAliases...)
+}
+
+/*
+Checks that none of the ports used by the given configuration are already bound on the host by another host-networked
+	service in the network.
+
+NOTE: This can't see ports bound on the host by anything outside this network (e.g. host-networked services of tests
+	running in parallel), so tests using host networking should use ports that nothing else will.
+ */
+func (network *ServiceNetwork) checkHostPortsAvailable(serviceId ServiceID, config serviceConfig) error {
+	usedPorts := config.initializerCore.GetUsedPorts()
+	for otherServiceId, nodeInfo := range network.serviceNodes {
+		if !network.isHostNetworked(nodeInfo) {
+			continue
+		}
+		for port, _ := range network.configurations[nodeInfo.configurationId].initializerCore.GetUsedPorts() {
+			if usedPorts[port] {
+				return stacktrace.NewError(
+					"Service %v needs host port %v, which is already used by host-networked service %v",
+					serviceId,
+					port,
+					otherServiceId)
+			}
+		}
+	}
+	return nil
+}
+
+// Gets whether the given node runs on the host's network, rather than being attached to the test network
+func (network *ServiceNetwork) isHostNetworked(nodeInfo ServiceNode) bool {
+	return network.configurations[nodeInfo.configurationId].containerOptions.UseHostNetwork
+}
+
+// Gets the Docker manager for the host that the given node runs on
+func (network *ServiceNetwork) getDockerManager(nodeInfo ServiceNode) docker.ContainerManager {
+	if nodeInfo.dockerHostIdx == 0 {
+		return network.dockerManager
+	}
+	return network.remoteDockerManagers[nodeInfo.dockerHostIdx - 1]
+}
+
+/*
+Picks the Docker host that a new service with the given configuration will run on: the host running the fewest services
+	(preferring the controller's host on ties), or the controller's host if the service can't run anywhere else.
+ */
+func (network *ServiceNetwork) pickDockerHost(config serviceConfig) int {
+	if len(network.remoteDockerManagers) == 0 ||
+			config.containerOptions.UseHostNetwork ||
+			len(config.initializerCore.GetFilesToMount()) > 0 {
+		return 0
+	}
+
+	numServicesPerHost := make([]int, len(network.remoteDockerManagers) + 1)
+	for _, nodeInfo := range network.serviceNodes {
+		numServicesPerHost[nodeInfo.dockerHostIdx]++
+	}
+	result := 0
+	for hostIdx, numServices := range numServicesPerHost {
+		if numServices < numServicesPerHost[result] {
+			result = hostIdx
+		}
+	}
+	return result
+}
@@ -1,20 +1,44 @@
 package networks
 
 import (
+	"context"
 	"github.com/kurtosis-tech/kurtosis/commons/docker"
 	"github.com/kurtosis-tech/kurtosis/commons/services"
 	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
 )
 
 // Identifier used for service configurations
 type ConfigurationID string
 
+/*
+Changes to an existing service configuration, for use with ServiceNetworkBuilder.DeriveWith. Fields left at their zero
+	value keep the base configuration's setting.
+ */
+type ConfigurationOverride struct {
+	// The Docker image that containers launched with the configuration will run with
+	DockerImage string
+
+	// The user-defined logic for how to launch the Docker container
+	InitializerCore services.ServiceInitializerCore
+
+	// The user-defined logic for how to report services launched with the configuration as available
+	AvailabilityCheckerCore services.ServiceAvailabilityCheckerCore
+
+	// Settings for the Docker containers launched with the configuration, which replace the base configuration's
+	//  settings entirely
+	ContainerOptions *docker.ContainerOptions
+}
+
 /*
 A builder for configuring & constructing a test ServiceNetwork.
  */
 type ServiceNetworkBuilder struct {
+	// The log entry that the built network will write its log messages to
+	log *logrus.Entry
+
 	// The Docker manager that will be used for manipulating the Docker engine during the test
-	dockerManager *docker.DockerManager
+	dockerManager docker.ContainerManager
 
 	// The ID of the Docker network that the test network runs in
 	dockerNetworkId string
@@ -23,45 +47,91 @@ type ServiceNetworkBuilder struct {
 	freeIpTracker *FreeIpAddrTracker
 
 	// Mapping of configuration ID -> factories used to construct new nodes
+	// NOTE: This map is shared (rather than copied) with the builder's clones & the networks it builds, so it must only be
+	//  written to through getWritableConfigurations
 	configurations map[ConfigurationID]serviceConfig
 
+	// True if the configurations map may be shared with a clone or a built network, meaning it must be copied before
+	//  it's next written to
+	isConfigurationsShared bool
+
 	// Name of the Docker volume that will be mounted on each new service
 	testVolume string
 
 	// Directory path where the test Docker volume is mounted on the controller
 	testVolumeControllerDirpath string
+
+	// Directory path on the controller where service data snapshots are stored (empty if snapshots aren't available)
+	snapshotsDirpath string
+
+	// Docker managers for the other hosts in the Docker Swarm that services can be scheduled onto
+	remoteDockerManagers []docker.ContainerManager
+
+	// True if the built network should be torn down in dependency order (see UseDependencyOrderedTeardown)
+	isTeardownDependencyOrdered bool
+
+	// True if the built network's services must meet strict policy (see UseStrictPolicy)
+	isStrictPolicyEnforced bool
+
+	// Mapping of external host -> "set" of ports on it that the built network's services can reach (see AllowEgressTo)
+	egressAllowances map[string]map[int]bool
+
+	// Names the built network's service containers (see UseContainerNamer), or nil to let Docker name them
+	containerNamer ContainerNamer
+
+	// The hooks that the built network invokes around the creation & start of each service (see AddServiceHook)
+	serviceHooks []ServiceHook
+
+	// Injects faults into the built network's calls to Docker (see UseFaultInjector), or nil to inject none
+	faultInjector *docker.FaultInjector
+
+	// How the built network checks dependencies are available before starting their dependents (see
+	//  UseDependencyAvailabilityPolicy), or nil if it doesn't
+	dependencyAvailabilityPolicy *DependencyAvailabilityPolicy
 }
 
 /*
 Creates a new builder for configuring a ServiceNetwork.
 
 Args:
+	log: The log entry that the built network will write its log messages to
 	dockerManager: Docker manager that will be used to manipulate the Docker engine when adding services
 	dockerNetworkName: Name of the Docker network that the test network is running in
 	freeIpTracker: IP tracker for doling out IPs to new services that will be added to the network
 	testVolume: Name of the Docker volume mounted on the controller, that will be mounted on every service
 	testVolumeControllerDirpath: The dirpath where the test volume is mounted on the controller (which is where this code
 		will be executing)
+	snapshotsDirpath: The dirpath on the controller where service data snapshots are stored, which should persist across
+		tests (or empty if snapshots aren't available)
  */
 func NewServiceNetworkBuilder(
-			dockerManager *docker.DockerManager,
+			log *logrus.Entry,
+			dockerManager docker.ContainerManager,
 			dockerNetworkId string,
 			freeIpTracker *FreeIpAddrTracker,
 			testVolume string,
-			testVolumeContrllerDirpath string) *ServiceNetworkBuilder {
+			testVolumeContrllerDirpath string,
+			snapshotsDirpath string) *ServiceNetworkBuilder {
 	configurations := make(map[ConfigurationID]serviceConfig)
 	return &ServiceNetworkBuilder{
+		log:                         log,
 		dockerManager:               dockerManager,
 		dockerNetworkId:             dockerNetworkId,
 		freeIpTracker:               freeIpTracker,
 		configurations:              configurations,
 		testVolume:                  testVolume,
 		testVolumeControllerDirpath: testVolumeContrllerDirpath,
+		snapshotsDirpath:            snapshotsDirpath,
+		remoteDockerManagers:        []docker.ContainerManager{},
+		egressAllowances:            map[string]map[int]bool{},
+		containerNamer:              DefaultContainerNamer,
+		serviceHooks:                []ServiceHook{},
 	}
 }
 
 /*
-Defines a new service configuration to the network that can later be used to launch Docker containers
+Defines a new service configuration to the network that can later be used to launch Docker containers. The configuration
+	is validated (see services.ValidateInitializerCore) so that mistakes surface here, rather than when a service is added.
 
 Args:
 	configurationId: The ID by which this configuration will be referenced later
@@ -75,34 +145,344 @@ func (builder *ServiceNetworkBuilder) AddConfiguration(
 			dockerImage string,
 			initializerCore services.ServiceInitializerCore,
 			availabilityCheckerCore services.ServiceAvailabilityCheckerCore) error {
+	return builder.AddConfigurationWithOptions(configurationId, dockerImage, initializerCore, availabilityCheckerCore, docker.ContainerOptions{})
+}
+
+/*
+Identical to AddConfiguration, but allows for customizing the Docker containers launched with this configuration (e.g.
+	selecting a log driver so that high-volume services don't fill the disk, or so that logs get shipped to an aggregator)
+
+Args:
+	configurationId: The ID by which this configuration will be referenced later
+	dockerImage: The Docker image that containers launched with this configuration will run with
+	initializerCore: The user-defined logic for how to launch the Docker container
+	availabilityCheckerCore: The user-defined logic for how to report services launched with this configuration
+		as available
+	containerOptions: Optional settings for the Docker containers launched with this configuration
+ */
+func (builder *ServiceNetworkBuilder) AddConfigurationWithOptions(
+			configurationId ConfigurationID,
+			dockerImage string,
+			initializerCore services.ServiceInitializerCore,
+			availabilityCheckerCore services.ServiceAvailabilityCheckerCore,
+			containerOptions docker.ContainerOptions) error {
 	if _, found := builder.configurations[configurationId]; found {
 		return stacktrace.NewError("Configuration ID %v is already registered", configurationId)
 	}
+	serviceConfig, err := newServiceConfig(dockerImage, initializerCore, availabilityCheckerCore, containerOptions)
+	if err != nil {
+		return stacktrace.Propagate(err, "Configuration ID %v is invalid", configurationId)
+	}
+	builder.getWritableConfigurations()[configurationId] = serviceConfig
+	return nil
+}
+
+/*
+Identical to AddConfigurationWithOptions, but takes all of the configuration's details from the given ServiceConfig,
+	e.g. one returned by GetConfiguration or built by an adapter of another framework's container definitions (like
+	testcontainers.NewServiceConfig).
+
+Args:
+	configurationId: The ID by which this configuration will be referenced later
+	config: The configuration's Docker image, cores, & container options
+ */
+func (builder *ServiceNetworkBuilder) AddServiceConfig(configurationId ConfigurationID, config ServiceConfig) error {
+	return builder.AddConfigurationWithOptions(
+		configurationId,
+		config.GetDockerImage(),
+		config.GetInitializerCore(),
+		config.GetAvailabilityCheckerCore(),
+		config.GetContainerOptions())
+}
+
+/*
+Gets a "set" of the IDs of the service configurations registered so far.
+ */
+func (builder *ServiceNetworkBuilder) GetConfigurationIds() map[ConfigurationID]bool {
+	result := make(map[ConfigurationID]bool)
+	for configurationId, _ := range builder.configurations {
+		result[configurationId] = true
+	}
+	return result
+}
 
-	serviceConfig := serviceConfig{
-		dockerImage: dockerImage,
-		availabilityCheckerCore: availabilityCheckerCore,
-		initializerCore:         initializerCore,
+/*
+Gets the service configuration with the given ID, which can't be changed through the returned value (see ServiceConfig).
+ */
+func (builder *ServiceNetworkBuilder) GetConfiguration(configurationId ConfigurationID) (ServiceConfig, error) {
+	config, found := builder.configurations[configurationId]
+	if !found {
+		return nil, stacktrace.NewError("No configuration with ID %v is registered", configurationId)
 	}
-	builder.configurations[configurationId] = serviceConfig
+	return config, nil
+}
+
+/*
+Creates a Docker volume managed by Kurtosis, which service configurations can mount via docker.ContainerOptions.ExtraVolumeMounts
+	(e.g. so that several services share a dataset). The volume is labelled with the test's volume, so it's removed along
+	with the test volume when the test finishes.
+
+Args:
+	volumeName: A name for the volume that's unique within the test
+
+Returns:
+	The name of the created Docker volume, to use in docker.VolumeMount
+ */
+func (builder *ServiceNetworkBuilder) CreateVolume(volumeName string) (string, error) {
+	if volumeName == "" {
+		return "", stacktrace.NewError("Volume name must not be empty")
+	}
+	dockerVolumeName := builder.testVolume + "-" + volumeName
+	labels := map[string]string{
+		docker.TEST_VOLUME_LABEL: builder.testVolume,
+	}
+	if err := builder.dockerManager.CreateVolume(context.Background(), dockerVolumeName, labels); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred creating volume %v", volumeName)
+	}
+	return dockerVolumeName, nil
+}
+
+/*
+Creates a Docker network for the test, in addition to the test network, which service configurations can attach their
+	containers to via docker.ContainerOptions.AdditionalNetworkIds (e.g. to give validators a "private" network that only
+	their sentries share). Services get Docker-assigned IPs on such networks, which can be looked up with
+	ServiceNetwork.GetServiceIpOnNetwork. The network is removed when the test finishes.
+
+Args:
+	networkName: The name of the network, unique within the test
+
+Returns:
+	The ID of the Docker network that was created
+ */
+func (builder *ServiceNetworkBuilder) CreateNetwork(networkName string) (string, error) {
+	if networkName == "" {
+		return "", stacktrace.NewError("Network name must not be empty")
+	}
+	dockerNetworkName := builder.testVolume + "-" + networkName
+	labels := map[string]string{
+		docker.TEST_VOLUME_LABEL: builder.testVolume,
+	}
+	dockerNetworkId, err := builder.dockerManager.CreateDynamicSubnetNetwork(context.Background(), dockerNetworkName, labels)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred creating network %v", networkName)
+	}
+	return dockerNetworkId, nil
+}
+
+/*
+Adds another Docker host that services can be scheduled onto, for test networks too large for a single machine. The test
+	network must be an overlay network (see DockerManager.CreateOverlayNetwork) that spans the host, i.e. the host must be
+	a node in the same Docker Swarm.
+
+Each new service is scheduled onto whichever host is running the fewest services, except for services that can only run
+	on the controller's host: those whose configuration mounts files or uses the host's network (because the test volume &
+	host ports are local to the controller's host).
+
+Args:
+	dockerManager: The Docker manager for the host's Docker engine
+ */
+func (builder *ServiceNetworkBuilder) AddRemoteDockerHost(dockerManager docker.ContainerManager) {
+	builder.remoteDockerManagers = append(builder.remoteDockerManagers, dockerManager)
+}
+
+/*
+Makes the built network's RemoveAll stop each service only once every service depending on it has been stopped, for
+	services that misbehave (e.g. crash-loop or log spurious errors) when their dependencies disappear before they do.
+	Otherwise every service is stopped at once, which is much faster for large networks.
+ */
+func (builder *ServiceNetworkBuilder) UseDependencyOrderedTeardown() {
+	builder.isTeardownDependencyOrdered = true
+}
+
+/*
+Makes the built network refuse to create any service whose image isn't pinned to a tag other than "latest" (or to a
+	digest), or which would run as root or privileged (see docker.ContainerOptions.EnforceStrictPolicy), for teams
+	enforcing supply-chain hygiene in their test infrastructure. The network's own helper containers (e.g. NAT proxies)
+	are exempt.
+ */
+func (builder *ServiceNetworkBuilder) UseStrictPolicy() {
+	builder.isStrictPolicyEnforced = true
+}
+
+/*
+Makes the built network inject the given injector's faults (e.g. failed container creations) into its calls to each
+	Docker host, for hardening a test suite against a misbehaving Docker engine (see docker.FaultInjection).
+ */
+func (builder *ServiceNetworkBuilder) UseFaultInjector(injector *docker.FaultInjector) {
+	builder.faultInjector = injector
+}
+
+/*
+Creates a copy of this builder, so that a base topology can be defined once and reused across tests. Configurations added
+	to the copy don't affect this builder, and vice versa.
+ */
+func (builder *ServiceNetworkBuilder) Clone() *ServiceNetworkBuilder {
+	// Copying the configurations is deferred until either builder changes them, because most clones change few or none
+	builder.isConfigurationsShared = true
+	return &ServiceNetworkBuilder{
+		log:                          builder.log,
+		dockerManager:                builder.dockerManager,
+		dockerNetworkId:              builder.dockerNetworkId,
+		freeIpTracker:                builder.freeIpTracker,
+		configurations:               builder.configurations,
+		isConfigurationsShared:       true,
+		testVolume:                   builder.testVolume,
+		testVolumeControllerDirpath:  builder.testVolumeControllerDirpath,
+		snapshotsDirpath:             builder.snapshotsDirpath,
+		remoteDockerManagers:         append([]docker.ContainerManager{}, builder.remoteDockerManagers...),
+		isTeardownDependencyOrdered:  builder.isTeardownDependencyOrdered,
+		isStrictPolicyEnforced:       builder.isStrictPolicyEnforced,
+		egressAllowances:             copyEgressAllowances(builder.egressAllowances),
+		containerNamer:               builder.containerNamer,
+		serviceHooks:                 append([]ServiceHook{}, builder.serviceHooks...),
+		faultInjector:                builder.faultInjector,
+		dependencyAvailabilityPolicy: builder.dependencyAvailabilityPolicy,
+	}
+}
+
+/*
+Creates a copy of this builder (see Clone) with the given changes applied to its configurations, for tests that need a
+	small variation on a base topology (e.g. one configuration running a different image).
+
+Args:
+	overrides: Mapping of configuration ID -> changes to make to that configuration, which must already exist
+
+Returns:
+	The new builder; this builder is left unchanged
+ */
+func (builder *ServiceNetworkBuilder) DeriveWith(overrides map[ConfigurationID]ConfigurationOverride) (*ServiceNetworkBuilder, error) {
+	derived := builder.Clone()
+	for configurationId, override := range overrides {
+		if err := derived.OverrideConfiguration(configurationId, override); err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred overriding configuration ID %v", configurationId)
+		}
+	}
+	return derived, nil
+}
+
+/*
+Makes the given changes to an existing configuration of this builder, e.g. from a network loader's ConfigureNetwork to
+	run one of the configurations it added with a different image version (see testsuite.VersionAssignment).
+
+Args:
+	configurationId: The ID of the configuration to change, which must already exist
+	override: The changes to make to the configuration
+ */
+func (builder *ServiceNetworkBuilder) OverrideConfiguration(configurationId ConfigurationID, override ConfigurationOverride) error {
+	config, found := builder.configurations[configurationId]
+	if !found {
+		return stacktrace.NewError("Cannot override configuration ID %v because it doesn't exist", configurationId)
+	}
+	overriddenConfig, err := applyConfigurationOverride(config, override)
+	if err != nil {
+		return stacktrace.Propagate(err, "The override of configuration ID %v is invalid", configurationId)
+	}
+	builder.getWritableConfigurations()[configurationId] = overriddenConfig
 	return nil
 }
 
 /*
 Constructs a ServiceNetwork with the configurations that were defined for this builder
  */
-func (builder ServiceNetworkBuilder) Build() *ServiceNetwork {
-	// Defensive copy, so user calling functions on the builder after building won't affect the
-	// state of the object we already built
-	configurationsCopy := make(map[ConfigurationID]serviceConfig)
-	for configurationId, config := range builder.configurations {
-		configurationsCopy[configurationId] = config
+func (builder *ServiceNetworkBuilder) Build() *ServiceNetwork {
+	// Networks never change their configurations, so instead of copying them we make the builder copy them before it next
+	//  changes them, so user calling functions on the builder after building won't affect the state of the object we
+	//  already built
+	builder.isConfigurationsShared = true
+	dockerManager := builder.dockerManager
+	remoteDockerManagers := append([]docker.ContainerManager{}, builder.remoteDockerManagers...)
+	if builder.faultInjector != nil {
+		dockerManager = builder.faultInjector.Wrap(dockerManager)
+		for idx, remoteDockerManager := range remoteDockerManagers {
+			remoteDockerManagers[idx] = builder.faultInjector.Wrap(remoteDockerManager)
+		}
 	}
 	return NewServiceNetwork(
+		builder.log,
 		builder.freeIpTracker,
-		builder.dockerManager,
+		dockerManager,
 		builder.dockerNetworkId,
-		configurationsCopy,
+		builder.configurations,
 		builder.testVolume,
-		builder.testVolumeControllerDirpath)
+		builder.testVolumeControllerDirpath,
+		builder.snapshotsDirpath,
+		remoteDockerManagers,
+		builder.isTeardownDependencyOrdered,
+		builder.isStrictPolicyEnforced,
+		copyEgressAllowances(builder.egressAllowances),
+		builder.containerNamer,
+		append([]ServiceHook{}, builder.serviceHooks...),
+		builder.dependencyAvailabilityPolicy)
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Gets the builder's configurations map for writing, first copying it if it's shared with a clone or a built network (see
+	Clone & Build).
+ */
+func (builder *ServiceNetworkBuilder) getWritableConfigurations() map[ConfigurationID]serviceConfig {
+	if builder.isConfigurationsShared {
+		configurationsCopy := make(map[ConfigurationID]serviceConfig, len(builder.configurations))
+		for configurationId, config := range builder.configurations {
+			configurationsCopy[configurationId] = config
+		}
+		builder.configurations = configurationsCopy
+		builder.isConfigurationsShared = false
+	}
+	return builder.configurations
+}
+
+// Validates the given configuration details, returning the configuration they make up
+func newServiceConfig(
+			dockerImage string,
+			initializerCore services.ServiceInitializerCore,
+			availabilityCheckerCore services.ServiceAvailabilityCheckerCore,
+			containerOptions docker.ContainerOptions) (serviceConfig, error) {
+	if dockerImage == "" {
+		return serviceConfig{}, stacktrace.NewError("The configuration has an empty Docker image")
+	}
+	if availabilityCheckerCore == nil {
+		return serviceConfig{}, stacktrace.NewError("The configuration has a nil availability checker core")
+	}
+	if err := services.ValidateInitializerCore(initializerCore); err != nil {
+		return serviceConfig{}, stacktrace.Propagate(err, "The configuration has an invalid initializer core")
+	}
+	return serviceConfig{
+		dockerImage:             dockerImage,
+		availabilityCheckerCore: availabilityCheckerCore,
+		initializerCore:         initializerCore,
+		// Defensive copy, so the caller changing their options afterwards won't affect the configuration
+		containerOptions:        containerOptions.Copy(),
+	}, nil
+}
+
+// Gets a copy of the given configuration with the given changes applied to it
+func applyConfigurationOverride(config serviceConfig, override ConfigurationOverride) (serviceConfig, error) {
+	if override.DockerImage != "" {
+		config.dockerImage = override.DockerImage
+	}
+	if override.InitializerCore != nil {
+		if err := services.ValidateInitializerCore(override.InitializerCore); err != nil {
+			return serviceConfig{}, stacktrace.Propagate(err, "The override has an invalid initializer core")
+		}
+		config.initializerCore = override.InitializerCore
+	}
+	if override.AvailabilityCheckerCore != nil {
+		config.availabilityCheckerCore = override.AvailabilityCheckerCore
+	}
+	if override.ContainerOptions != nil {
+		config.containerOptions = override.ContainerOptions.Copy()
+	}
+	return config, nil
+}
+
+func copyEgressAllowances(egressAllowances map[string]map[int]bool) map[string]map[int]bool {
+	result := make(map[string]map[int]bool, len(egressAllowances))
+	for host, ports := range egressAllowances {
+		result[host] = make(map[int]bool, len(ports))
+		for port, _ := range ports {
+			result[host][port] = true
+		}
+	}
+	return result
 }
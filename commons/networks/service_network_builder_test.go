@@ -1,7 +1,11 @@
 package networks
 
 import (
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
 	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -11,7 +15,7 @@ const (
 )
 
 func TestDisallowingSameIds(t *testing.T) {
-	builder := NewServiceNetworkBuilder(nil, "test-network", nil, "test", "/foo/bar")
+	builder := NewServiceNetworkBuilder(testLog, nil, "test-network", nil, "test", "/foo/bar", "")
 	err := builder.AddConfiguration(testConfigurationId0, "test", getTestInitializerCore(), getTestCheckerCore())
 	if err != nil {
 		t.Fatal("Adding a configuration shouldn't fail here")
@@ -24,7 +28,7 @@ func TestDisallowingSameIds(t *testing.T) {
 }
 
 func TestDefensiveCopies(t *testing.T) {
-	builder := NewServiceNetworkBuilder(nil, "test-network", nil, "test", "/foo/bar")
+	builder := NewServiceNetworkBuilder(testLog, nil, "test-network", nil, "test", "/foo/bar", "")
 	err := builder.AddConfiguration(testConfigurationId0, "test", getTestInitializerCore(), getTestCheckerCore())
 	if err != nil {
 		t.Fatal("Adding a configuration shouldn't fail here")
@@ -40,3 +44,150 @@ func TestDefensiveCopies(t *testing.T) {
 
 	assert.Equal(t, 1, len(network.configurations))
 }
+
+func TestContainerOptionsStoredWithConfiguration(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, "test-network", nil, "test", "/foo/bar", "")
+	containerOptions := docker.ContainerOptions{
+		LogDriver: docker.JSON_FILE_LOG_DRIVER,
+		LogDriverOptions: map[string]string{
+			"max-size": "10m",
+			"max-file": "3",
+		},
+	}
+	err := builder.AddConfigurationWithOptions(testConfigurationId0, "test", getTestInitializerCore(), getTestCheckerCore(), containerOptions)
+	if err != nil {
+		t.Fatal("Adding a configuration shouldn't fail here")
+	}
+	err = builder.AddConfiguration(testConfigurationId1, "test", getTestInitializerCore(), getTestCheckerCore())
+	if err != nil {
+		t.Fatal("Adding a configuration shouldn't fail here")
+	}
+	network := builder.Build()
+
+	assert.DeepEqual(t, containerOptions, network.configurations[testConfigurationId0].containerOptions)
+	assert.Equal(t, "", network.configurations[testConfigurationId1].containerOptions.LogDriver)
+}
+
+func TestCreatingVolumeWithEmptyName(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	if _, err := builder.CreateVolume(""); err == nil {
+		t.Fatal("Expected an error when creating a volume with an empty name")
+	}
+}
+
+func TestCreatingNetworkWithEmptyName(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	if _, err := builder.CreateNetwork(""); err == nil {
+		t.Fatal("Expected an error when creating a network with an empty name")
+	}
+}
+
+func TestClonedBuildersAreIndependent(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, "test-network", nil, "test", "/foo/bar", "")
+	assert.NilError(t, builder.AddConfiguration(testConfigurationId0, "test", getTestInitializerCore(), getTestCheckerCore()))
+
+	clone := builder.Clone()
+	assert.NilError(t, clone.AddConfiguration(testConfigurationId1, "test", getTestInitializerCore(), getTestCheckerCore()))
+	assert.Equal(t, 1, len(builder.configurations))
+	assert.Equal(t, 2, len(clone.configurations))
+
+	// The clones share their configurations until they change them, so the original changing must not affect the clone
+	otherClone := builder.Clone()
+	assert.NilError(t, builder.AddConfiguration(testConfigurationId1, "test", getTestInitializerCore(), getTestCheckerCore()))
+	assert.Equal(t, 2, len(builder.configurations))
+	assert.Equal(t, 1, len(otherClone.configurations))
+}
+
+func TestDerivingBuilderWithOverrides(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, "test-network", nil, "test", "/foo/bar", "")
+	assert.NilError(t, builder.AddConfiguration(testConfigurationId0, "test", getTestInitializerCore(), getTestCheckerCore()))
+	assert.NilError(t, builder.AddConfiguration(testConfigurationId1, "test", getTestInitializerCore(), getTestCheckerCore()))
+
+	derived, err := builder.DeriveWith(map[ConfigurationID]ConfigurationOverride{
+		testConfigurationId0: {
+			DockerImage:      "test-v2",
+			ContainerOptions: &docker.ContainerOptions{UseHostNetwork: true},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, "test-v2", derived.configurations[testConfigurationId0].dockerImage)
+	assert.Assert(t, derived.configurations[testConfigurationId0].containerOptions.UseHostNetwork)
+	assert.Equal(t, "test", derived.configurations[testConfigurationId1].dockerImage)
+
+	// The base builder is unchanged
+	assert.Equal(t, "test", builder.configurations[testConfigurationId0].dockerImage)
+	assert.Assert(t, !builder.configurations[testConfigurationId0].containerOptions.UseHostNetwork)
+
+	_, err = builder.DeriveWith(map[ConfigurationID]ConfigurationOverride{
+		"nonexistent": {DockerImage: "test"},
+	})
+	assert.ErrorContains(t, err, "doesn't exist")
+}
+
+func TestConfigurationsDontAliasContainerOptions(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, "test-network", nil, "test", "/foo/bar", "")
+	containerOptions := docker.ContainerOptions{
+		LogDriverOptions: map[string]string{"max-size": "10m"},
+		NetworkAliases:   []string{"alias"},
+	}
+	assert.NilError(t, builder.AddConfigurationWithOptions(testConfigurationId0, "test", getTestInitializerCore(), getTestCheckerCore(), containerOptions))
+
+	// Changing the caller's options after registering them doesn't change the configuration
+	containerOptions.LogDriverOptions["max-size"] = "20m"
+	containerOptions.NetworkAliases[0] = "other-alias"
+	config, err := builder.GetConfiguration(testConfigurationId0)
+	assert.NilError(t, err)
+	assert.Equal(t, "10m", config.GetContainerOptions().LogDriverOptions["max-size"])
+	assert.Equal(t, "alias", config.GetContainerOptions().NetworkAliases[0])
+
+	// ...and nor does changing the options gotten back from the configuration
+	config.GetContainerOptions().LogDriverOptions["max-size"] = "30m"
+	assert.Equal(t, "10m", config.GetContainerOptions().LogDriverOptions["max-size"])
+
+	_, err = builder.GetConfiguration(testConfigurationId1)
+	assert.ErrorContains(t, err, "No configuration")
+}
+
+func BenchmarkCloningAndBuildingLargeBuilder(b *testing.B) {
+	builder := NewServiceNetworkBuilder(testLog, nil, "test-network", nil, "test", "/foo/bar", "")
+	for i := 0; i < 500; i++ {
+		configurationId := ConfigurationID(fmt.Sprintf("test-configuration-%v", i))
+		assert.NilError(b, builder.AddConfiguration(configurationId, "test", getTestInitializerCore(), getTestCheckerCore()))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		builder.Clone().Build()
+	}
+}
+
+func TestFaultInjectionThroughBuilder(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:latest", getTestInitializerCore(), getTestCheckerCore()))
+	injector := docker.NewFaultInjector(docker.FaultInjection{FailNthContainerCreate: 2, InspectDropProbability: 1}, 0)
+	builder.UseFaultInjector(injector)
+	network := builder.Build()
+
+	_, err = network.AddService(testConfiguration, "node1", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	_, err = network.AddService(testConfiguration, "node2", map[ServiceID]bool{})
+	assert.ErrorContains(t, err, "Injected fault")
+
+	// The failed service is left out of the network, which carries on working
+	assert.DeepEqual(t, map[ServiceID]bool{"node1": true}, network.GetServiceIds())
+	assert.Equal(t, 1, len(dockerManager.GetContainerIds()))
+	_, err = network.AddService(testConfiguration, "node3", map[ServiceID]bool{})
+	assert.NilError(t, err)
+
+	// Diagnostics are best-effort, so they're still gathered when the engine drops inspections
+	diagnostics := network.GetAvailabilityDiagnostics(DEFAULT_DIAGNOSTICS_LOG_LINES)
+	assert.Equal(t, 2, len(diagnostics.Services))
+	assert.Equal(t, unknownContainerState, diagnostics.Services[0].ContainerState)
+	assert.Assert(t, injector.GetNumInjectedFaults() >= 3)
+}
@@ -2,9 +2,16 @@ package networks
 
 import (
 	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
 	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
 	"net"
 	"os"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -15,6 +22,8 @@ const (
 	testConfiguration = "test-configuration"
 )
 
+var testLog = logrus.NewEntry(logrus.StandardLogger())
+
 type TestService struct {}
 
 // ======================== Test Initializer Core ========================
@@ -36,7 +45,7 @@ func (t TestInitializerCore) InitializeMountedFiles(filepathsToMount map[string]
 	return nil
 }
 
-func (t TestInitializerCore) GetStartCommand(mountedFileFilepaths map[string]string, publicIpAddr net.IP, dependencies []services.Service) ([]string, error) {
+func (t TestInitializerCore) GetStartCommand(startCommandContext services.StartCommandContext) ([]string, error) {
 	return make([]string, 0), nil
 }
 
@@ -63,7 +72,7 @@ func getTestCheckerCore() services.ServiceAvailabilityCheckerCore {
 
 // ======================== Tests ========================
 func TestDisallowingNonexistentConfigs(t *testing.T) {
-	builder := NewServiceNetworkBuilder(nil, testNetworkName, nil, "test", "/foo/bar")
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
 	network := builder.Build()
 	_, err := network.AddService(testConfiguration, testServiceName, make(map[ServiceID]bool))
 	if err == nil {
@@ -71,9 +80,17 @@ func TestDisallowingNonexistentConfigs(t *testing.T) {
 	}
 }
 
+func TestDisallowingEmptyServiceIds(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:latest", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+	_, err := network.AddService(testConfiguration, "", map[ServiceID]bool{})
+	assert.ErrorContains(t, err, "Service ID must not be empty")
+}
+
 func TestDisallowingNonexistentDependencies(t *testing.T) {
 	var configId ConfigurationID = testConfiguration
-	builder := NewServiceNetworkBuilder(nil, testNetworkName, nil, "test", "/foo/bar")
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
 	err := builder.AddConfiguration(configId, "test", getTestInitializerCore(), getTestCheckerCore())
 	if err != nil {
 		t.Fatal("Adding a configuration shouldn't fail")
@@ -89,3 +106,579 @@ func TestDisallowingNonexistentDependencies(t *testing.T) {
 		t.Fatal("Expected error when declaring a dependency on a service ID that doesn't exist")
 	}
 }
+
+func TestStressingNonexistentService(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+	err := network.StressService(testServiceName, 50, time.Second)
+	if err == nil {
+		t.Fatal("Expected error when stressing a service that doesn't exist")
+	}
+}
+
+func TestStressingServicesRestoresTheirCpuLimits(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	limitedConfiguration := ConfigurationID("limited")
+	assert.NilError(t, builder.AddConfigurationWithOptions(
+		limitedConfiguration,
+		"test",
+		getTestInitializerCore(),
+		getTestCheckerCore(),
+		docker.ContainerOptions{CpuPercent: 150}))
+	network := builder.Build()
+
+	expectedCpuPercentLimits := map[ServiceID]uint{
+		"unlimited": 0,
+		"limited":   150,
+	}
+	_, err = network.AddService(testConfiguration, "unlimited", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	_, err = network.AddService(limitedConfiguration, "limited", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	for serviceId, expectedCpuPercentLimit := range expectedCpuPercentLimits {
+		assert.NilError(t, network.StressService(serviceId, 10, time.Millisecond))
+		nodeInfo, err := network.GetService(serviceId)
+		assert.NilError(t, err)
+		container, _ := dockerManager.GetContainer(nodeInfo.ContainerId)
+		assert.Equal(t, expectedCpuPercentLimit, container.CpuPercentLimit)
+	}
+}
+
+func TestRestartingNonexistentService(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+	_, err := network.RestartService(testServiceName, time.Second)
+	if err == nil {
+		t.Fatal("Expected error when restarting a service that doesn't exist")
+	}
+}
+
+func TestResolverMap(t *testing.T) {
+	var configId ConfigurationID = testConfiguration
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	err := builder.AddConfigurationWithOptions(
+		configId,
+		"test",
+		getTestInitializerCore(),
+		getTestCheckerCore(),
+		docker.ContainerOptions{NetworkAliases: []string{"shared-alias"}})
+	if err != nil {
+		t.Fatal("Adding a configuration shouldn't fail")
+	}
+	network := builder.Build()
+	network.serviceNodes["node"] = ServiceNode{IpAddr: net.ParseIP("172.23.0.2"), configurationId: configId}
+
+	hostname, err := network.GetServiceHostname("node")
+	assert.NilError(t, err)
+	assert.Equal(t, "node", hostname)
+
+	_, err = network.GetServiceHostname(testServiceName)
+	if err == nil {
+		t.Fatal("Expected error when getting the hostname of a service that doesn't exist")
+	}
+
+	resolverMap := network.GetResolverMap()
+	assert.Equal(t, 2, len(resolverMap))
+	assert.Equal(t, "172.23.0.2", resolverMap["node"].String())
+	assert.Equal(t, "172.23.0.2", resolverMap["shared-alias"].String())
+}
+
+type testPortsInitializerCore struct {
+	TestInitializerCore
+	usedPorts map[nat.Port]bool
+}
+
+func (core testPortsInitializerCore) GetUsedPorts() map[nat.Port]bool {
+	return core.usedPorts
+}
+
+func TestHostNetworkPortConflicts(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	hostNetworkOptions := docker.ContainerOptions{UseHostNetwork: true}
+	err := builder.AddConfigurationWithOptions(
+		testConfigurationId0,
+		"test",
+		testPortsInitializerCore{usedPorts: map[nat.Port]bool{"8080/tcp": true}},
+		getTestCheckerCore(),
+		hostNetworkOptions)
+	assert.NilError(t, err)
+	err = builder.AddConfigurationWithOptions(
+		testConfigurationId1,
+		"test",
+		testPortsInitializerCore{usedPorts: map[nat.Port]bool{"9090/tcp": true}},
+		getTestCheckerCore(),
+		hostNetworkOptions)
+	assert.NilError(t, err)
+	network := builder.Build()
+	network.serviceNodes["host-node"] = ServiceNode{IpAddr: net.ParseIP("172.23.0.1"), configurationId: testConfigurationId0}
+
+	err = network.checkHostPortsAvailable("conflicting", network.configurations[testConfigurationId0])
+	if err == nil {
+		t.Fatal("Expected error when a host-networked service needs a port another host-networked service is using")
+	}
+	err = network.checkHostPortsAvailable("non-conflicting", network.configurations[testConfigurationId1])
+	assert.NilError(t, err)
+
+	assert.Equal(t, 0, len(network.GetResolverMap()))
+	_, err = network.GetServiceHostname("host-node")
+	if err == nil {
+		t.Fatal("Expected error when getting the hostname of a host-networked service")
+	}
+}
+
+func TestDockerHostScheduling(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	builder.AddRemoteDockerHost(nil)
+	builder.AddRemoteDockerHost(nil)
+	err := builder.AddConfiguration(testConfigurationId0, "test", getTestInitializerCore(), getTestCheckerCore())
+	assert.NilError(t, err)
+	err = builder.AddConfigurationWithOptions(
+		testConfigurationId1,
+		"test",
+		getTestInitializerCore(),
+		getTestCheckerCore(),
+		docker.ContainerOptions{UseHostNetwork: true})
+	assert.NilError(t, err)
+	network := builder.Build()
+	config := network.configurations[testConfigurationId0]
+
+	// Ties go to the controller's host
+	assert.Equal(t, 0, network.pickDockerHost(config))
+
+	network.serviceNodes["local"] = ServiceNode{dockerHostIdx: 0}
+	assert.Equal(t, 1, network.pickDockerHost(config))
+
+	network.serviceNodes["remote1"] = ServiceNode{dockerHostIdx: 1}
+	assert.Equal(t, 2, network.pickDockerHost(config))
+
+	network.serviceNodes["remote2"] = ServiceNode{dockerHostIdx: 2}
+	network.serviceNodes["another-local"] = ServiceNode{dockerHostIdx: 0}
+	assert.Equal(t, 1, network.pickDockerHost(config))
+
+	// Host-networked services can only run on the controller's host
+	assert.Equal(t, 0, network.pickDockerHost(network.configurations[testConfigurationId1]))
+}
+
+func TestAddingServiceWithNilIp(t *testing.T) {
+	var configId ConfigurationID = testConfiguration
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	err := builder.AddConfiguration(configId, "test", getTestInitializerCore(), getTestCheckerCore())
+	assert.NilError(t, err)
+	network := builder.Build()
+
+	_, err = network.AddServiceWithIp(configId, testServiceName, nil, make(map[ServiceID]bool))
+	if err == nil {
+		t.Fatal("Expected error when adding a service with a nil IP")
+	}
+}
+
+func TestServiceIpAndHostPort(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	err := builder.AddConfigurationWithOptions(
+		testConfigurationId0,
+		"test",
+		getTestInitializerCore(),
+		getTestCheckerCore(),
+		docker.ContainerOptions{UseHostNetwork: true})
+	assert.NilError(t, err)
+	network := builder.Build()
+	network.serviceNodes["host-node"] = ServiceNode{IpAddr: net.ParseIP("172.23.0.1"), configurationId: testConfigurationId0}
+
+	ipAddr, err := network.GetServiceIp("host-node")
+	assert.NilError(t, err)
+	assert.Equal(t, "172.23.0.1", ipAddr.String())
+
+	// Host-networked services listen directly on the host's ports
+	hostPort, err := network.GetServiceHostPort("host-node", "8545/tcp")
+	assert.NilError(t, err)
+	assert.Equal(t, 8545, hostPort)
+
+	_, err = network.GetServiceHostPort(testServiceName, "8545/tcp")
+	if err == nil {
+		t.Fatal("Expected error when getting the host port of a service that doesn't exist")
+	}
+}
+
+func TestStartingServiceThatWasNotCreated(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+
+	_, err := network.StartService(testServiceName)
+	assert.ErrorContains(t, err, "No service")
+
+	// Nodes are only awaiting a start if they were created with CreateService
+	network.serviceNodes[testServiceName] = ServiceNode{}
+	_, err = network.StartService(testServiceName)
+	assert.ErrorContains(t, err, "already been started")
+}
+
+func TestAddingAndRemovingServiceWithFakeDockerManager(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+
+	_, err = network.AddService(testConfiguration, testServiceName, map[ServiceID]bool{})
+	assert.NilError(t, err)
+	containerIds := dockerManager.GetContainerIds()
+	assert.Equal(t, 1, len(containerIds))
+	container, found := dockerManager.GetContainer(containerIds[0])
+	assert.Assert(t, found)
+	assert.Equal(t, "test", container.Image)
+	assert.Equal(t, docker.FAKE_RUNNING_STATE, container.State)
+	assert.Equal(t, "test", container.Options.Labels[docker.TEST_VOLUME_LABEL])
+	assert.Equal(t, string(testServiceName), container.Options.Labels[docker.SERVICE_ID_LABEL])
+	ipAddr, err := network.GetServiceIp(testServiceName)
+	assert.NilError(t, err)
+	assert.Assert(t, container.NetworkIps[network.GetDockerNetworkId()].Equal(ipAddr))
+
+	assert.NilError(t, network.RemoveService(testServiceName, time.Second))
+	container, _ = dockerManager.GetContainer(containerIds[0])
+	assert.Equal(t, docker.FAKE_EXITED_STATE, container.State)
+}
+
+func TestRemovedServicesFreeTheirIps(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+
+	_, err = network.AddService(testConfiguration, "node1", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	firstIp, err := network.GetServiceIp("node1")
+	assert.NilError(t, err)
+	assert.NilError(t, network.RemoveAll(time.Second))
+
+	// Services whose containers couldn't be created don't hold on to their IPs either
+	dockerManager.FailNext("CreateContainer", stacktrace.NewError("Test failure"))
+	_, err = network.AddService(testConfiguration, "node2", map[ServiceID]bool{})
+	assert.ErrorContains(t, err, "Test failure")
+	// ...nor do services whose images couldn't be pulled
+	dockerManager.FailNext("PullImageIfMissing", stacktrace.NewError("Test pull failure"))
+	_, err = network.AddService(testConfiguration, "node2", map[ServiceID]bool{})
+	assert.ErrorContains(t, err, "Test pull failure")
+
+	_, err = network.AddService(testConfiguration, "node3", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	ipAddr, err := network.GetServiceIp("node3")
+	assert.NilError(t, err)
+	assert.Assert(t, firstIp.Equal(ipAddr))
+}
+
+func TestServiceIpsAreDeterministic(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	for i := 0; i < 2; i++ {
+		freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{"172.23.0.1": true})
+		assert.NilError(t, err)
+		builder := NewServiceNetworkBuilder(testLog, docker.NewFakeDockerManager(), testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+		assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+		network := builder.Build()
+		_, err = network.AddService(testConfiguration, "node1", map[ServiceID]bool{})
+		assert.NilError(t, err)
+		_, err = network.AddService(testConfiguration, "node2", map[ServiceID]bool{"node1": true})
+		assert.NilError(t, err)
+
+		serviceIps := network.GetServiceIps()
+		assert.Equal(t, 2, len(serviceIps))
+		assert.Equal(t, "172.23.0.2", serviceIps["node1"].String())
+		assert.Equal(t, "172.23.0.3", serviceIps["node2"].String())
+	}
+}
+
+func TestAddingConfigurationToRunningNetwork(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "node:1.0", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+	_, err = network.AddService(testConfiguration, "node1", map[ServiceID]bool{})
+	assert.NilError(t, err)
+
+	upgradedConfig := serviceConfig{
+		dockerImage:             "node:2.0",
+		initializerCore:         getTestInitializerCore(),
+		availabilityCheckerCore: getTestCheckerCore(),
+	}
+	assert.NilError(t, network.AddServiceConfig("upgraded", upgradedConfig))
+	assert.ErrorContains(t, network.AddServiceConfig("upgraded", upgradedConfig), "already registered")
+	_, err = network.AddService("upgraded", "node2", map[ServiceID]bool{"node1": true})
+	assert.NilError(t, err)
+	node, err := network.GetService("node2")
+	assert.NilError(t, err)
+	container, _ := dockerManager.GetContainer(node.ContainerId)
+	assert.Equal(t, "node:2.0", container.Image)
+	assert.NilError(t, network.RemoveService("node2", time.Second))
+
+	// The builder (and so any other network it builds) doesn't get the configuration
+	_, err = builder.GetConfiguration("upgraded")
+	assert.ErrorContains(t, err, "No configuration")
+}
+
+func TestPausingAndUnpausingService(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+	_, err = network.AddService(testConfiguration, testServiceName, map[ServiceID]bool{})
+	assert.NilError(t, err)
+	node, err := network.GetService(testServiceName)
+	assert.NilError(t, err)
+
+	assert.NilError(t, network.PauseService(testServiceName))
+	container, _ := dockerManager.GetContainer(node.ContainerId)
+	assert.Equal(t, docker.FAKE_PAUSED_STATE, container.State)
+	assert.NilError(t, network.UnpauseService(testServiceName))
+	container, _ = dockerManager.GetContainer(node.ContainerId)
+	assert.Equal(t, docker.FAKE_RUNNING_STATE, container.State)
+	assert.ErrorContains(t, network.PauseService("nonexistent"), "No service with ID nonexistent found")
+}
+
+func TestRunningExecCommandsInService(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	dockerManager := docker.NewFakeDockerManager()
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+	_, err = network.AddService(testConfiguration, testServiceName, map[ServiceID]bool{})
+	assert.NilError(t, err)
+	node, err := network.GetService(testServiceName)
+	assert.NilError(t, err)
+
+	assert.NilError(t, dockerManager.SetExecResult(node.ContainerId, 2, "ls: /data: No such file or directory\n"))
+	exitCode, logs, err := network.RunExecCommand(testServiceName, []string{"ls", "/data"}, time.Second)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, "ls: /data: No such file or directory\n", logs)
+	container, _ := dockerManager.GetContainer(node.ContainerId)
+	assert.DeepEqual(t, [][]string{{"ls", "/data"}}, container.ExecCmds)
+
+	// Commands can't be run in services that aren't running
+	assert.NilError(t, network.KillService(testServiceName))
+	_, _, err = network.RunExecCommand(testServiceName, []string{"ls"}, time.Second)
+	assert.ErrorContains(t, err, "isn't running")
+	_, _, err = network.RunExecCommand("nonexistent", []string{"ls"}, time.Second)
+	assert.ErrorContains(t, err, "No service with ID nonexistent found")
+}
+
+func TestManipulatingNetworkConcurrently(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, docker.NewFakeDockerManager(), testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+
+	numServices := 20
+	errs := make(chan error, numServices)
+	waitGroup := &sync.WaitGroup{}
+	for i := 0; i < numServices; i++ {
+		serviceId := ServiceID(testServiceName + "-" + strconv.Itoa(i))
+		waitGroup.Add(2)
+		go func() {
+			defer waitGroup.Done()
+			_, err := network.AddService(testConfiguration, serviceId, map[ServiceID]bool{})
+			errs <- err
+		}()
+		// Simulates a monitor reading the network while it's being changed
+		go func() {
+			defer waitGroup.Done()
+			network.GetResolverMap()
+			network.GetServiceIds()
+			network.GetState()
+		}()
+	}
+	waitGroup.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, numServices, network.GetSize())
+	assert.Equal(t, numServices, len(network.GetResolverMap()))
+}
+
+func TestRemovingAllServices(t *testing.T) {
+	for _, isTeardownDependencyOrdered := range []bool{false, true} {
+		testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+		assert.NilError(t, err)
+		defer os.RemoveAll(testVolumeControllerDirpath)
+
+		freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+		assert.NilError(t, err)
+		dockerManager := docker.NewFakeDockerManager()
+		builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+		assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+		if isTeardownDependencyOrdered {
+			builder.UseDependencyOrderedTeardown()
+		}
+		network := builder.Build()
+
+		// A chain of services, each depending on the one before it
+		serviceIds := []ServiceID{"service0", "service1", "service2"}
+		for i, serviceId := range serviceIds {
+			dependencies := map[ServiceID]bool{}
+			if i > 0 {
+				dependencies[serviceIds[i - 1]] = true
+			}
+			_, err := network.AddService(testConfiguration, serviceId, dependencies)
+			assert.NilError(t, err)
+		}
+		serviceIdsByContainerId := make(map[string]ServiceID)
+		for _, serviceId := range serviceIds {
+			nodeInfo, err := network.GetService(serviceId)
+			assert.NilError(t, err)
+			serviceIdsByContainerId[nodeInfo.ContainerId] = serviceId
+		}
+
+		assert.NilError(t, network.RemoveAll(time.Second))
+		assert.Equal(t, 0, network.GetSize())
+		stoppedServiceIds := []ServiceID{}
+		for _, call := range dockerManager.GetCalls() {
+			if call.Method == "StopContainer" {
+				stoppedServiceIds = append(stoppedServiceIds, serviceIdsByContainerId[call.Target])
+			}
+		}
+		assert.Equal(t, len(serviceIds), len(stoppedServiceIds))
+		if isTeardownDependencyOrdered {
+			assert.DeepEqual(t, []ServiceID{"service2", "service1", "service0"}, stoppedServiceIds)
+		}
+	}
+}
+
+/*
+The load scenario that CI benchmarks the startup path with: a network of no-op containers (backed by the fake Docker
+	manager, so that only the orchestration layer's own overhead is measured) where every service depends on a single
+	bootstrap service, which is started, waited on, & torn down.
+ */
+func BenchmarkStartingAndTearingDownNoOpNetwork(b *testing.B) {
+	numServices := 50
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(b, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+		assert.NilError(b, err)
+		builder := NewServiceNetworkBuilder(testLog, docker.NewFakeDockerManager(), testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+		assert.NilError(b, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+		network := builder.Build()
+
+		var bootstrapId ServiceID = "bootstrap"
+		checker, err := network.AddService(testConfiguration, bootstrapId, map[ServiceID]bool{})
+		assert.NilError(b, err)
+		assert.NilError(b, checker.WaitForStartup())
+		for j := 1; j < numServices; j++ {
+			serviceId := ServiceID("service-" + strconv.Itoa(j))
+			checker, err := network.AddService(testConfiguration, serviceId, map[ServiceID]bool{bootstrapId: true})
+			assert.NilError(b, err)
+			assert.NilError(b, checker.WaitForStartup())
+		}
+		assert.NilError(b, network.RemoveAll(time.Second))
+	}
+}
+
+func TestStrictPolicyRejectsUnpinnedImages(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	dockerManager := docker.NewFakeDockerManager()
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfigurationId0, "test-image:1.0", getTestInitializerCore(), getTestCheckerCore()))
+	assert.NilError(t, builder.AddConfiguration(testConfigurationId1, "test-image:latest", getTestInitializerCore(), getTestCheckerCore()))
+	builder.UseStrictPolicy()
+	network := builder.Build()
+
+	_, err = network.AddService(testConfigurationId0, "pinned", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	nodeInfo, err := network.GetService("pinned")
+	assert.NilError(t, err)
+	container, found := dockerManager.GetContainer(nodeInfo.ContainerId)
+	assert.Assert(t, found)
+	assert.Assert(t, container.Options.EnforceStrictPolicy)
+
+	_, err = network.AddService(testConfigurationId1, "unpinned", map[ServiceID]bool{})
+	assert.ErrorContains(t, err, "isn't pinned")
+	assert.Equal(t, 1, network.GetSize())
+}
+
+// An initializer core whose image is configured through its environment, run through a wrapper entrypoint
+type envConfiguredInitializerCore struct {
+	TestInitializerCore
+}
+
+func (core envConfiguredInitializerCore) GetEnvVariables() map[string]string {
+	return map[string]string{"PUBLIC_IP": "{{ .IpAddr }}"}
+}
+
+func (core envConfiguredInitializerCore) GetEntrypoint() []string {
+	return []string{"/wrapper.sh", "--ip={{ .IpAddr }}"}
+}
+
+func TestTemplatedEnvVariablesAndEntrypoint(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	dockerManager := docker.NewFakeDockerManager()
+	builder := NewServiceNetworkBuilder(testLog, dockerManager, testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	// The core's entrypoint takes precedence over the configuration's
+	options := docker.ContainerOptions{Entrypoint: []string{"/bin/sh"}}
+	assert.NilError(t, builder.AddConfigurationWithOptions(testConfiguration, "test", envConfiguredInitializerCore{}, getTestCheckerCore(), options))
+	network := builder.Build()
+
+	_, err = network.AddService(testConfiguration, testServiceName, map[ServiceID]bool{})
+	assert.NilError(t, err)
+	nodeInfo, err := network.GetService(testServiceName)
+	assert.NilError(t, err)
+	container, found := dockerManager.GetContainer(nodeInfo.ContainerId)
+	assert.Assert(t, found)
+	assert.Equal(t, nodeInfo.IpAddr.String(), container.EnvVariables["PUBLIC_IP"])
+	assert.DeepEqual(t, []string{"/wrapper.sh", "--ip=" + nodeInfo.IpAddr.String()}, container.Options.Entrypoint)
+}
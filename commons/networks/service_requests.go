@@ -0,0 +1,161 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"net"
+)
+
+/*
+A handle to a service that was added to a network with ServiceRequest.Add, which later requests can depend on.
+ */
+type ServiceHandle struct {
+	// The ID of the service in the network
+	serviceId ServiceID
+
+	// The user-defined interface for interacting with the service
+	service services.Service
+
+	// The checker for when the service becomes available
+	availabilityChecker *services.ServiceAvailabilityChecker
+}
+
+// Gets the ID of the service in the network
+func (handle ServiceHandle) GetServiceId() ServiceID {
+	return handle.serviceId
+}
+
+// GENERICS TOOD: When Go has generics (and this module targets a Go version that has them), parameterize the handle by
+//  the service interface type so that this returns it directly, without a cast
+/*
+Gets the user-defined interface for interacting with the service, which needs to be casted to the service's interface
+	(e.g. handle.GetService().(MyService)).
+ */
+func (handle ServiceHandle) GetService() services.Service {
+	return handle.service
+}
+
+// Gets the checker for when the service becomes available
+func (handle ServiceHandle) GetAvailabilityChecker() *services.ServiceAvailabilityChecker {
+	return handle.availabilityChecker
+}
+
+/*
+A chainable request for adding a service to a network, created with ServiceNetwork.WithService, e.g.:
+
+	bootstrap, err := network.WithService("bootstrap", "node-config").AddAndWait()
+	...
+	node1, err := network.WithService("node1", "node-config").DependingOn(bootstrap).AddAndWait()
+
+Dependencies are given as the handles of already-added services rather than a "set" of IDs, so a dependency can't be
+	misspelled or declared before it exists. Any error in building the request is returned when the service is added.
+ */
+type ServiceRequest struct {
+	// The network the service will be added to
+	network *ServiceNetwork
+
+	// The ID the service will have in the network
+	serviceId ServiceID
+
+	// The ID of the configuration the service will be created from
+	configurationId ConfigurationID
+
+	// A "set" of the services that the service will depend on
+	dependencies map[ServiceID]bool
+
+	// The IP the service will have, or nil for the next free one
+	ipAddr net.IP
+
+	// The first error that occurred building the request, if any
+	err error
+}
+
+/*
+Starts a chainable request for adding a service with the given ID, created from the given configuration, to the network.
+ */
+func (network *ServiceNetwork) WithService(serviceId ServiceID, configurationId ConfigurationID) *ServiceRequest {
+	return &ServiceRequest{
+		network:         network,
+		serviceId:       serviceId,
+		configurationId: configurationId,
+		dependencies:    make(map[ServiceID]bool),
+		ipAddr:          nil,
+		err:             nil,
+	}
+}
+
+/*
+Declares that the service will depend on the services with the given handles.
+ */
+func (request *ServiceRequest) DependingOn(dependencies ...*ServiceHandle) *ServiceRequest {
+	for _, dependency := range dependencies {
+		if dependency == nil {
+			request.setErr(stacktrace.NewError("Service %v was given a nil dependency handle", request.serviceId))
+			continue
+		}
+		request.dependencies[dependency.serviceId] = true
+	}
+	return request
+}
+
+/*
+Gives the service the given IP rather than the next free one (see ServiceNetwork.AddServiceWithIp).
+ */
+func (request *ServiceRequest) AtIp(ipAddr net.IP) *ServiceRequest {
+	if ipAddr == nil {
+		request.setErr(stacktrace.NewError("Service %v was given a nil IP", request.serviceId))
+	}
+	request.ipAddr = ipAddr
+	return request
+}
+
+/*
+Adds the requested service to the network, without waiting for it to become available.
+ */
+func (request *ServiceRequest) Add() (*ServiceHandle, error) {
+	if request.err != nil {
+		return nil, stacktrace.Propagate(request.err, "Invalid request for service %v", request.serviceId)
+	}
+
+	var availabilityChecker *services.ServiceAvailabilityChecker
+	var err error
+	if request.ipAddr != nil {
+		availabilityChecker, err = request.network.AddServiceWithIp(request.configurationId, request.serviceId, request.ipAddr, request.dependencies)
+	} else {
+		availabilityChecker, err = request.network.AddService(request.configurationId, request.serviceId, request.dependencies)
+	}
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred adding service %v", request.serviceId)
+	}
+	nodeInfo, err := request.network.GetService(request.serviceId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting service %v after adding it", request.serviceId)
+	}
+	return &ServiceHandle{
+		serviceId:           request.serviceId,
+		service:             nodeInfo.Service,
+		availabilityChecker: availabilityChecker,
+	}, nil
+}
+
+/*
+Adds the requested service to the network, and waits for it to become available.
+ */
+func (request *ServiceRequest) AddAndWait() (*ServiceHandle, error) {
+	handle, err := request.Add()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred adding service %v", request.serviceId)
+	}
+	if err := handle.availabilityChecker.WaitForStartup(); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred waiting for service %v to become available", request.serviceId)
+	}
+	return handle, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Records the given error, unless an earlier one was already recorded
+func (request *ServiceRequest) setErr(err error) {
+	if request.err == nil {
+		request.err = err
+	}
+}
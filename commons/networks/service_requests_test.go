@@ -0,0 +1,25 @@
+package networks
+
+import (
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestServiceRequestDependencies(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+	bootstrap := &ServiceHandle{serviceId: "bootstrap"}
+	node1 := &ServiceHandle{serviceId: "node1"}
+
+	request := network.WithService("node2", testConfiguration).DependingOn(bootstrap).DependingOn(node1)
+	assert.NilError(t, request.err)
+	assert.DeepEqual(t, map[ServiceID]bool{"bootstrap": true, "node1": true}, request.dependencies)
+}
+
+func TestServiceRequestErrorsAreDeferredToAdd(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	network := builder.Build()
+
+	_, err := network.WithService("node1", testConfiguration).DependingOn(nil).AtIp(nil).Add()
+	assert.ErrorContains(t, err, "nil dependency handle")
+}
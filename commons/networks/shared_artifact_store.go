@@ -0,0 +1,143 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// The wait between checks for an artifact starts at the initial interval & doubles after each check that doesn't find
+	//  it, up to the max
+	INITIAL_ARTIFACT_POLL_INTERVAL = 50 * time.Millisecond
+	MAX_ARTIFACT_POLL_INTERVAL     = 1 * time.Second
+
+	sharedArtifactDirPerms  = 0777
+	sharedArtifactFilePerms = 0666
+)
+
+/*
+A store of files (e.g. generated keys, configs, and outputs) shared between the test and every service in the network.
+	Services see the store at services.SHARED_ARTIFACT_STORE_DIRPATH, and the test reads & writes it through this API.
+
+Artifacts are identified by relative paths within the store (e.g. "keys/node1.pem").
+ */
+type SharedArtifactStore struct {
+	// The dirpath of the store on the controller (which is where this code runs)
+	controllerDirpath string
+}
+
+/*
+Gets the artifact store shared between the test and all the network's services.
+ */
+func (network *ServiceNetwork) GetSharedArtifactStore() *SharedArtifactStore {
+	return &SharedArtifactStore{
+		controllerDirpath: path.Join(network.testVolumeControllerDirpath, services.SHARED_ARTIFACT_STORE_DIRNAME),
+	}
+}
+
+/*
+Writes an artifact to the store, replacing any existing artifact with the same name.
+ */
+func (store SharedArtifactStore) WriteArtifact(name string, contents []byte) error {
+	artifactFilepath, err := store.getControllerFilepath(name)
+	if err != nil {
+		return stacktrace.Propagate(err, "Invalid artifact name")
+	}
+	if err := os.MkdirAll(path.Dir(artifactFilepath), sharedArtifactDirPerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating the directory for artifact %v", name)
+	}
+	if err := ioutil.WriteFile(artifactFilepath, contents, sharedArtifactFilePerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing artifact %v", name)
+	}
+	return nil
+}
+
+/*
+Reads an artifact from the store.
+ */
+func (store SharedArtifactStore) ReadArtifact(name string) ([]byte, error) {
+	artifactFilepath, err := store.getControllerFilepath(name)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Invalid artifact name")
+	}
+	contents, err := ioutil.ReadFile(artifactFilepath)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading artifact %v", name)
+	}
+	return contents, nil
+}
+
+/*
+Blocks until the artifact with the given name exists in the store (e.g. because a service generates it on startup),
+	returning its contents, or until the timeout is hit.
+ */
+func (store SharedArtifactStore) WaitForArtifact(name string, timeout time.Duration) ([]byte, error) {
+	artifactFilepath, err := store.getControllerFilepath(name)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Invalid artifact name")
+	}
+	deadline := time.Now().Add(timeout)
+	backoff := services.NewPollBackoff(INITIAL_ARTIFACT_POLL_INTERVAL, MAX_ARTIFACT_POLL_INTERVAL)
+	for {
+		if _, err := os.Stat(artifactFilepath); err == nil {
+			return store.ReadArtifact(name)
+		}
+		if time.Now().After(deadline) {
+			return nil, stacktrace.NewError("Artifact %v didn't appear in the shared artifact store within %v", name, timeout)
+		}
+		time.Sleep(backoff.NextInterval())
+	}
+}
+
+/*
+Lists the names of all artifacts in the store, sorted.
+ */
+func (store SharedArtifactStore) ListArtifacts() ([]string, error) {
+	result := []string{}
+	if _, err := os.Stat(store.controllerDirpath); os.IsNotExist(err) {
+		return result, nil
+	}
+	err := filepath.Walk(store.controllerDirpath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fileInfo.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(store.controllerDirpath, filePath)
+		if err != nil {
+			return err
+		}
+		result = append(result, filepath.ToSlash(relativePath))
+		return nil
+	})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred listing the shared artifact store")
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+/*
+Gets the path that the artifact with the given name has inside every service's container (e.g. for passing to a service's
+	start command).
+ */
+func (store SharedArtifactStore) GetContainerFilepath(name string) string {
+	return path.Join(services.SHARED_ARTIFACT_STORE_DIRPATH, name)
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (store SharedArtifactStore) getControllerFilepath(name string) (string, error) {
+	cleanedName := path.Clean(name)
+	if name == "" || path.IsAbs(cleanedName) || cleanedName == ".." || strings.HasPrefix(cleanedName, "../") {
+		return "", stacktrace.NewError("Artifact name '%v' must be a relative path inside the store", name)
+	}
+	return path.Join(store.controllerDirpath, cleanedName), nil
+}
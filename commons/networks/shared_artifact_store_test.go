@@ -0,0 +1,58 @@
+package networks
+
+import (
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSharedArtifactStoreRoundTrip(t *testing.T) {
+	testVolumeDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeDirpath)
+
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", testVolumeDirpath, "")
+	store := builder.Build().GetSharedArtifactStore()
+
+	names, err := store.ListArtifacts()
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(names))
+
+	assert.NilError(t, store.WriteArtifact("keys/node1.pem", []byte("key1")))
+	assert.NilError(t, store.WriteArtifact("genesis.json", []byte("{}")))
+
+	contents, err := store.ReadArtifact("keys/node1.pem")
+	assert.NilError(t, err)
+	assert.Equal(t, "key1", string(contents))
+
+	contents, err = store.WaitForArtifact("genesis.json", time.Second)
+	assert.NilError(t, err)
+	assert.Equal(t, "{}", string(contents))
+
+	names, err = store.ListArtifacts()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"genesis.json", "keys/node1.pem"}, names)
+
+	assert.Equal(t, "/kurtosis-shared/artifact-store/keys/node1.pem", store.GetContainerFilepath("keys/node1.pem"))
+}
+
+func TestSharedArtifactStoreRejectsEscapingNames(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "")
+	store := builder.Build().GetSharedArtifactStore()
+	assert.Assert(t, store.WriteArtifact("../escape", []byte{}) != nil)
+	assert.Assert(t, store.WriteArtifact("/etc/passwd", []byte{}) != nil)
+	_, err := store.ReadArtifact("")
+	assert.Assert(t, err != nil)
+}
+
+func TestWaitingForMissingArtifactTimesOut(t *testing.T) {
+	testVolumeDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeDirpath)
+
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", testVolumeDirpath, "")
+	_, err = builder.Build().GetSharedArtifactStore().WaitForArtifact("missing", 10 * time.Millisecond)
+	assert.Assert(t, err != nil)
+}
@@ -0,0 +1,120 @@
+package networks
+
+import (
+	"context"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	SNAPSHOT_FILE_EXTENSION = ".tar"
+)
+
+/*
+Saves the contents of a directory in a service's container (e.g. the data directory of a node that has just finished an
+	expensive bootstrap, like a chain sync) as a named snapshot. Later networks - including those of later test runs - can
+	start services from the snapshot via ServiceNetworkBuilder.GetSnapshotArchive, turning slow setups into fast ones.
+
+The service is paused while its data is copied, so that the snapshot is consistent. Any existing snapshot with the same
+	name is replaced.
+
+Args:
+	serviceId: The ID of the service whose data should be snapshotted
+	containerDirpath: The absolute path of the directory in the service's container to snapshot (volumes mounted in the
+		container are included)
+	snapshotName: The name that the snapshot will be saved under
+ */
+func (network *ServiceNetwork) SnapshotServiceData(serviceId ServiceID, containerDirpath string, snapshotName string) error {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	snapshotFilepath, err := getSnapshotFilepath(network.snapshotsDirpath, snapshotName)
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not get the filepath for snapshot %v", snapshotName)
+	}
+	network.serviceLog(serviceId).Debugf("Snapshotting directory %v of service ID %v to snapshot %v...", containerDirpath, serviceId, snapshotName)
+	if err := network.archiveServiceData(serviceId, containerDirpath, snapshotFilepath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred saving snapshot %v", snapshotName)
+	}
+	network.serviceLog(serviceId).Debugf("Successfully snapshotted service ID %v to snapshot %v", serviceId, snapshotName)
+	return nil
+}
+
+/*
+Gets an archive that will restore the given snapshot into a directory of the containers launched with a configuration,
+	for use in the configuration's docker.ContainerOptions.
+
+Args:
+	snapshotName: The name the snapshot was saved under with ServiceNetwork.SnapshotServiceData
+	containerDirpath: The absolute path of the directory in the container that the snapshot will be restored into
+
+Returns:
+	The archive, and true if the snapshot exists (false if it doesn't, in which case the caller should do the slow setup
+		and save a snapshot for next time)
+ */
+func (builder ServiceNetworkBuilder) GetSnapshotArchive(snapshotName string, containerDirpath string) (docker.ContainerArchive, bool) {
+	snapshotFilepath, err := getSnapshotFilepath(builder.snapshotsDirpath, snapshotName)
+	if err != nil {
+		builder.log.Debugf("Snapshot %v isn't available: %v", snapshotName, err)
+		return docker.ContainerArchive{}, false
+	}
+	if _, err := os.Stat(snapshotFilepath); err != nil {
+		return docker.ContainerArchive{}, false
+	}
+	return docker.ContainerArchive{
+		ArchiveFilepath:  snapshotFilepath,
+		ContainerDirpath: containerDirpath,
+	}, true
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Archives a directory of a service's container to the given file, pausing the service while its data is copied so that
+	the archive is consistent.
+ */
+func (network *ServiceNetwork) archiveServiceData(serviceId ServiceID, containerDirpath string, archiveFilepath string) error {
+	parentCtx := context.Background()
+
+	nodeInfo, found := network.serviceNodes[serviceId]
+	if !found {
+		return stacktrace.NewError("No service with ID %v found", serviceId)
+	}
+
+	if err := network.getDockerManager(nodeInfo).PauseContainer(parentCtx, nodeInfo.ContainerId); err != nil {
+		return stacktrace.Propagate(err, "An error occurred pausing service ID %v to archive its data", serviceId)
+	}
+	defer func() {
+		if err := network.getDockerManager(nodeInfo).UnpauseContainer(parentCtx, nodeInfo.ContainerId); err != nil {
+			network.serviceLog(serviceId).Errorf("An error occurred unpausing service ID %v after archiving its data: %v", serviceId, err)
+		}
+	}()
+
+	// We write to a tempfile and move it into place so that readers never see a half-written archive
+	tempFp, err := ioutil.TempFile(path.Dir(archiveFilepath), path.Base(archiveFilepath))
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating a temporary file for archive %v", archiveFilepath)
+	}
+	defer os.Remove(tempFp.Name())
+	err = network.getDockerManager(nodeInfo).ArchiveContainerDirectory(parentCtx, nodeInfo.ContainerId, containerDirpath, tempFp)
+	tempFp.Close()
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred archiving directory %v of service ID %v", containerDirpath, serviceId)
+	}
+	if err := os.Rename(tempFp.Name(), archiveFilepath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred moving archive %v into place", archiveFilepath)
+	}
+	return nil
+}
+
+func getSnapshotFilepath(snapshotsDirpath string, snapshotName string) (string, error) {
+	if snapshotsDirpath == "" {
+		return "", stacktrace.NewError("No snapshots directory was configured, so snapshots aren't available")
+	}
+	if snapshotName == "" || strings.ContainsAny(snapshotName, "/\\") || strings.HasPrefix(snapshotName, ".") {
+		return "", stacktrace.NewError("Invalid snapshot name '%v'; names must be non-empty, not contain slashes, and not start with '.'", snapshotName)
+	}
+	return path.Join(snapshotsDirpath, snapshotName + SNAPSHOT_FILE_EXTENSION), nil
+}
@@ -0,0 +1,43 @@
+package networks
+
+import (
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSnapshotNamesValidated(t *testing.T) {
+	_, err := getSnapshotFilepath("/snapshots", "../escape")
+	assert.Assert(t, err != nil)
+	_, err = getSnapshotFilepath("/snapshots", "")
+	assert.Assert(t, err != nil)
+	_, err = getSnapshotFilepath("", "synced-chain")
+	assert.Assert(t, err != nil)
+
+	filepath, err := getSnapshotFilepath("/snapshots", "synced-chain")
+	assert.NilError(t, err)
+	assert.Equal(t, "/snapshots/synced-chain.tar", filepath)
+}
+
+func TestGettingSnapshotArchive(t *testing.T) {
+	snapshotsDirpath, err := ioutil.TempDir("", "snapshots")
+	assert.NilError(t, err)
+	defer os.RemoveAll(snapshotsDirpath)
+
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", snapshotsDirpath)
+	_, found := builder.GetSnapshotArchive("synced-chain", "/data")
+	assert.Assert(t, !found)
+
+	assert.NilError(t, ioutil.WriteFile(path.Join(snapshotsDirpath, "synced-chain.tar"), []byte{}, 0644))
+	archive, found := builder.GetSnapshotArchive("synced-chain", "/data")
+	assert.Assert(t, found)
+	assert.Equal(t, "/data", archive.ContainerDirpath)
+}
+
+func TestSnapshottingNonexistentService(t *testing.T) {
+	builder := NewServiceNetworkBuilder(testLog, nil, testNetworkName, nil, "test", "/foo/bar", "/snapshots")
+	network := builder.Build()
+	assert.Assert(t, network.SnapshotServiceData(testServiceName, "/data", "synced-chain") != nil)
+}
@@ -0,0 +1,65 @@
+package networks
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGettingStartupReport(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	freeIpTracker, err := NewFreeIpAddrTracker(testLog.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := NewServiceNetworkBuilder(testLog, docker.NewFakeDockerManager(), testNetworkName, freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddConfiguration(testConfiguration, "test", getTestInitializerCore(), getTestCheckerCore()))
+	network := builder.Build()
+
+	// Services are reported in start order, followed by the unstarted ones
+	availabilityChecker, err := network.AddService(testConfiguration, "zzz-started", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	assert.NilError(t, availabilityChecker.WaitForStartup())
+	_, err = network.AddService(testConfiguration, "aaa-started", map[ServiceID]bool{})
+	assert.NilError(t, err)
+	assert.NilError(t, network.CreateService(testConfiguration, "unstarted", map[ServiceID]bool{}))
+
+	report, err := network.GetStartupReport()
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(report.Services))
+	assert.Equal(t, ServiceID("zzz-started"), report.Services[0].ServiceId)
+	assert.Equal(t, ServiceID("aaa-started"), report.Services[1].ServiceId)
+	assert.Equal(t, ServiceID("unstarted"), report.Services[2].ServiceId)
+	assert.Assert(t, report.Services[0].CreateDuration > 0)
+
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.Assert(t, strings.HasPrefix(lines[0], "SERVICE"))
+	assert.Assert(t, strings.HasPrefix(lines[1], "zzz-started"))
+	assert.Assert(t, !strings.Contains(lines[1], " - "))
+	assert.Assert(t, strings.Contains(lines[2], " - "))
+	assert.Assert(t, strings.HasPrefix(lines[4], "TOTAL"))
+}
+
+func TestSortingStartupReportBySlowest(t *testing.T) {
+	now := time.Now()
+	report := StartupReport{Services: []ServiceInfo{
+		{ServiceId: "fast", PullDuration: time.Second},
+		{ServiceId: "slow", PullDuration: time.Second, StartTime: now, AvailableTime: now.Add(10 * time.Second)},
+		{ServiceId: "unavailable", CreateDuration: 2 * time.Second, StartTime: now},
+	}}
+
+	sorted := report.SortedBySlowest()
+	assert.Equal(t, ServiceID("slow"), sorted.Services[0].ServiceId)
+	assert.Equal(t, ServiceID("unavailable"), sorted.Services[1].ServiceId)
+	assert.Equal(t, ServiceID("fast"), sorted.Services[2].ServiceId)
+	assert.Equal(t, 11 * time.Second, sorted.Services[0].GetTotalStartupDuration())
+
+	// The original report keeps its start order
+	assert.Equal(t, ServiceID("fast"), report.Services[0].ServiceId)
+}
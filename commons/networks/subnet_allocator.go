@@ -0,0 +1,200 @@
+package networks
+
+import (
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"math/big"
+	"net"
+	"sync"
+)
+
+const (
+	BITS_IN_IP4_ADDR = 32
+
+	BITS_IN_IP6_ADDR = 128
+)
+
+/*
+Carves fixed-size subnets for test networks out of a larger "supernet", so that many test networks can run on one host
+	without their subnets colliding. Subnets are handed out lowest-first, so the same sequence of allocations against
+	the same supernet always yields the same subnets. Both IPv4 and IPv6 supernets are supported.
+
+NOTE: This is thread-safe.
+ */
+type SubnetAllocator struct {
+	// The supernet that subnets are carved out of
+	supernet *net.IPNet
+
+	// The first IP of the supernet, as an integer
+	supernetStart *big.Int
+
+	// The number of bits in each subnet's mask
+	subnetMaskBits int
+
+	// The number of IPs in each subnet
+	subnetSize *big.Int
+
+	// The number of subnets that fit in the supernet
+	numSubnets *big.Int
+
+	// Mutex guarding the taken subnets & reserved ranges
+	mutex *sync.Mutex
+
+	// A "set" of the indexes (as strings) of subnets that have been allocated
+	allocatedSubnetIdxs map[string]bool
+
+	// Inclusive [first, last] ranges of subnet indexes that overlap networks the allocator didn't create
+	reservedIdxRanges [][2]*big.Int
+}
+
+/*
+Creates a new subnet allocator.
+
+Args:
+	supernetCidr: The CIDR that subnets will be carved out of (e.g. "172.23.0.0/16" or "fd00:6b75:7274::/48")
+	subnetWidthBits: Each subnet will contain 2 ^ subnetWidthBits IPs
+ */
+func NewSubnetAllocator(supernetCidr string, subnetWidthBits uint32) (*SubnetAllocator, error) {
+	_, supernet, err := net.ParseCIDR(supernetCidr)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to parse supernet %v as a CIDR", supernetCidr)
+	}
+	supernetMaskBits, addrBits := supernet.Mask.Size()
+	supernetWidthBits := uint32(addrBits - supernetMaskBits)
+	if subnetWidthBits > supernetWidthBits {
+		return nil, stacktrace.NewError(
+			"Subnets with %v bits of width don't fit in supernet %v, which only has %v bits of width",
+			subnetWidthBits,
+			supernetCidr,
+			supernetWidthBits)
+	}
+
+	return &SubnetAllocator{
+		supernet:            supernet,
+		supernetStart:       ipToInt(supernet.IP),
+		subnetMaskBits:      addrBits - int(subnetWidthBits),
+		subnetSize:          new(big.Int).Lsh(big.NewInt(1), uint(subnetWidthBits)),
+		numSubnets:          new(big.Int).Lsh(big.NewInt(1), uint(supernetWidthBits - subnetWidthBits)),
+		mutex:               &sync.Mutex{},
+		allocatedSubnetIdxs: make(map[string]bool),
+		reservedIdxRanges:   [][2]*big.Int{},
+	}, nil
+}
+
+/*
+Marks every subnet that overlaps the given network as taken (e.g. because a Docker network that this allocator didn't
+	create is already using it). Networks of the other IP family are ignored.
+ */
+func (allocator *SubnetAllocator) ReserveOverlapping(network *net.IPNet) {
+	networkMaskBits, networkAddrBits := network.Mask.Size()
+	_, supernetAddrBits := allocator.supernet.Mask.Size()
+	if networkAddrBits != supernetAddrBits {
+		return
+	}
+
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+
+	networkFirst := ipToInt(network.IP.Mask(network.Mask))
+	networkLast := new(big.Int).Add(networkFirst, new(big.Int).Lsh(big.NewInt(1), uint(networkAddrBits - networkMaskBits)))
+	networkLast.Sub(networkLast, big.NewInt(1))
+
+	firstIdx := allocator.getSubnetIdx(networkFirst)
+	lastIdx := allocator.getSubnetIdx(networkLast)
+	maxIdx := new(big.Int).Sub(allocator.numSubnets, big.NewInt(1))
+	if lastIdx.Sign() < 0 || firstIdx.Cmp(maxIdx) > 0 {
+		// The network doesn't overlap the supernet at all
+		return
+	}
+	if firstIdx.Sign() < 0 {
+		firstIdx = big.NewInt(0)
+	}
+	if lastIdx.Cmp(maxIdx) > 0 {
+		lastIdx = maxIdx
+	}
+	allocator.reservedIdxRanges = append(allocator.reservedIdxRanges, [2]*big.Int{firstIdx, lastIdx})
+}
+
+/*
+Allocates the lowest free subnet, returning it in CIDR notation.
+ */
+func (allocator *SubnetAllocator) AllocateSubnet() (string, error) {
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+
+	for idx := big.NewInt(0); idx.Cmp(allocator.numSubnets) < 0; idx.Add(idx, big.NewInt(1)) {
+		if allocator.isSubnetIdxTaken(idx) {
+			continue
+		}
+		allocator.allocatedSubnetIdxs[idx.String()] = true
+		return allocator.getSubnet(idx).String(), nil
+	}
+	return "", stacktrace.Propagate(ErrAddressesExhausted, "All %v subnets in supernet %v are taken", allocator.numSubnets, allocator.supernet)
+}
+
+/*
+Releases a subnet previously returned by AllocateSubnet, so that it can be allocated again.
+ */
+func (allocator *SubnetAllocator) ReleaseSubnet(subnetCidr string) error {
+	_, subnet, err := net.ParseCIDR(subnetCidr)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to parse subnet %v as a CIDR", subnetCidr)
+	}
+
+	allocator.mutex.Lock()
+	defer allocator.mutex.Unlock()
+
+	idx := allocator.getSubnetIdx(ipToInt(subnet.IP))
+	if idx.Sign() < 0 || idx.Cmp(allocator.numSubnets) >= 0 || allocator.getSubnet(idx).String() != subnet.String() {
+		return stacktrace.NewError("Subnet %v isn't one of the subnets of supernet %v", subnetCidr, allocator.supernet)
+	}
+	if !allocator.allocatedSubnetIdxs[idx.String()] {
+		return stacktrace.NewError("Subnet %v isn't allocated", subnetCidr)
+	}
+	delete(allocator.allocatedSubnetIdxs, idx.String())
+	return nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (allocator *SubnetAllocator) isSubnetIdxTaken(idx *big.Int) bool {
+	if allocator.allocatedSubnetIdxs[idx.String()] {
+		return true
+	}
+	for _, reservedRange := range allocator.reservedIdxRanges {
+		if idx.Cmp(reservedRange[0]) >= 0 && idx.Cmp(reservedRange[1]) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Gets the index of the subnet containing the given IP, which may be out of range if the IP isn't in the supernet
+func (allocator *SubnetAllocator) getSubnetIdx(ipInt *big.Int) *big.Int {
+	offset := new(big.Int).Sub(ipInt, allocator.supernetStart)
+	// Div rounds towards negative infinity for a positive divisor, which is what we want for IPs below the supernet
+	return new(big.Int).Div(offset, allocator.subnetSize)
+}
+
+func (allocator *SubnetAllocator) getSubnet(idx *big.Int) *net.IPNet {
+	subnetStart := new(big.Int).Add(allocator.supernetStart, new(big.Int).Mul(idx, allocator.subnetSize))
+	_, addrBits := allocator.supernet.Mask.Size()
+	subnetIp := intToIp(subnetStart, addrBits / 8)
+	_, subnet, _ := net.ParseCIDR(fmt.Sprintf("%v/%v", subnetIp, allocator.subnetMaskBits))
+	return subnet
+}
+
+// Converts an IP to an integer, using the 4-byte form for IPv4 addresses
+func ipToInt(ip net.IP) *big.Int {
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return new(big.Int).SetBytes(ipv4)
+	}
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+// Converts an integer to an IP with the given number of bytes (4 for IPv4, 16 for IPv6)
+func intToIp(ipInt *big.Int, numBytes int) net.IP {
+	ipBytes := ipInt.Bytes()
+	result := make(net.IP, numBytes)
+	copy(result[numBytes - len(ipBytes):], ipBytes)
+	return result
+}
@@ -0,0 +1,81 @@
+package networks
+
+import (
+	"gotest.tools/v3/assert"
+	"net"
+	"testing"
+)
+
+func TestSubnetAllocation(t *testing.T) {
+	allocator, err := NewSubnetAllocator("172.23.0.0/22", 8)
+	assert.NilError(t, err)
+
+	_, existingNetwork, err := net.ParseCIDR("172.23.1.128/25")
+	assert.NilError(t, err)
+	allocator.ReserveOverlapping(existingNetwork)
+
+	subnet, err := allocator.AllocateSubnet()
+	assert.NilError(t, err)
+	assert.Equal(t, "172.23.0.0/24", subnet)
+	subnet, err = allocator.AllocateSubnet()
+	assert.NilError(t, err)
+	assert.Equal(t, "172.23.2.0/24", subnet)
+	subnet, err = allocator.AllocateSubnet()
+	assert.NilError(t, err)
+	assert.Equal(t, "172.23.3.0/24", subnet)
+	_, err = allocator.AllocateSubnet()
+	assert.Assert(t, err != nil)
+
+	assert.NilError(t, allocator.ReleaseSubnet("172.23.2.0/24"))
+	subnet, err = allocator.AllocateSubnet()
+	assert.NilError(t, err)
+	assert.Equal(t, "172.23.2.0/24", subnet)
+
+	assert.Assert(t, allocator.ReleaseSubnet("10.0.0.0/24") != nil)
+}
+
+func TestSubnetsMustFitInSupernet(t *testing.T) {
+	_, err := NewSubnetAllocator("172.23.0.0/24", 9)
+	assert.Assert(t, err != nil)
+	_, err = NewSubnetAllocator("not-a-cidr", 8)
+	assert.Assert(t, err != nil)
+}
+
+func TestIpv6SubnetAllocation(t *testing.T) {
+	allocator, err := NewSubnetAllocator("fd00:6b75:7274::/48", 64)
+	assert.NilError(t, err)
+
+	_, existingNetwork, err := net.ParseCIDR("fd00:6b75:7274::/64")
+	assert.NilError(t, err)
+	allocator.ReserveOverlapping(existingNetwork)
+
+	// IPv4 networks can't collide with IPv6 subnets
+	_, ipv4Network, err := net.ParseCIDR("172.23.0.0/16")
+	assert.NilError(t, err)
+	allocator.ReserveOverlapping(ipv4Network)
+
+	subnet, err := allocator.AllocateSubnet()
+	assert.NilError(t, err)
+	assert.Equal(t, "fd00:6b75:7274:1::/64", subnet)
+	subnet, err = allocator.AllocateSubnet()
+	assert.NilError(t, err)
+	assert.Equal(t, "fd00:6b75:7274:2::/64", subnet)
+	assert.NilError(t, allocator.ReleaseSubnet("fd00:6b75:7274:1::/64"))
+}
+
+func BenchmarkAllocatingAndReleasingSubnets(b *testing.B) {
+	allocator, err := NewSubnetAllocator("172.16.0.0/12", 8)
+	assert.NilError(b, err)
+	subnets := make([]string, 256)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range subnets {
+			subnets[j], err = allocator.AllocateSubnet()
+			assert.NilError(b, err)
+		}
+		for _, subnet := range subnets {
+			assert.NilError(b, allocator.ReleaseSubnet(subnet))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package networks
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================== "enum" for lifecycle event types =========================================
+type LifecycleEventType string
+const (
+	SERVICE_STARTED     LifecycleEventType = "SERVICE_STARTED"
+	SERVICE_AVAILABLE   LifecycleEventType = "SERVICE_AVAILABLE"
+	SERVICE_KILLED      LifecycleEventType = "SERVICE_KILLED"
+	SERVICE_REVIVED     LifecycleEventType = "SERVICE_REVIVED"
+	SERVICE_RESTARTED   LifecycleEventType = "SERVICE_RESTARTED"
+	SERVICE_REMOVED     LifecycleEventType = "SERVICE_REMOVED"
+	SERVICE_STRESSED    LifecycleEventType = "SERVICE_STRESSED"
+	SERVICE_UNSTRESSED  LifecycleEventType = "SERVICE_UNSTRESSED"
+	SERVICE_PARTITIONED LifecycleEventType = "SERVICE_PARTITIONED"
+	SERVICE_RECONNECTED LifecycleEventType = "SERVICE_RECONNECTED"
+	SERVICE_PAUSED      LifecycleEventType = "SERVICE_PAUSED"
+	SERVICE_UNPAUSED    LifecycleEventType = "SERVICE_UNPAUSED"
+	SERVICE_REATTACHED  LifecycleEventType = "SERVICE_REATTACHED" // The network re-attached to an already-running service from persisted state
+	SERVICE_CRASHED     LifecycleEventType = "SERVICE_CRASHED"    // The service's container exited without the test killing or removing it (see DetectCrashedServices)
+)
+
+/*
+A single thing that happened to a service in the test network.
+ */
+type LifecycleEvent struct {
+	// When the event happened
+	Timestamp time.Time
+
+	// What happened
+	EventType LifecycleEventType
+
+	// The service it happened to
+	ServiceId ServiceID
+}
+
+/*
+An ordered record of every lifecycle event that happened in a test network, which makes ordering bugs far easier to
+	reason about after the fact.
+
+NOTE: This is thread-safe!
+ */
+type Timeline struct {
+	mutex *sync.Mutex
+
+	// When the timeline was created, which event times are printed relative to
+	startTime time.Time
+
+	events []LifecycleEvent
+
+	// The "set" of subscriptions that are sent each new event (see Subscribe)
+	subscriptions map[*EventSubscription]bool
+}
+
+func newTimeline() *Timeline {
+	return &Timeline{
+		mutex:         &sync.Mutex{},
+		startTime:     time.Now(),
+		events:        []LifecycleEvent{},
+		subscriptions: map[*EventSubscription]bool{},
+	}
+}
+
+/*
+Gets a copy of all events recorded so far, in the order they happened.
+ */
+func (timeline *Timeline) GetEvents() []LifecycleEvent {
+	timeline.mutex.Lock()
+	defer timeline.mutex.Unlock()
+
+	result := make([]LifecycleEvent, len(timeline.events))
+	copy(result, timeline.events)
+	return result
+}
+
+/*
+Renders the timeline as one line per event, with times relative to when the network was created.
+ */
+func (timeline *Timeline) String() string {
+	builder := &strings.Builder{}
+	for _, event := range timeline.GetEvents() {
+		offset := event.Timestamp.Sub(timeline.startTime)
+		fmt.Fprintf(builder, "+%-12v %-20v %v\n", offset.Round(time.Millisecond), event.EventType, event.ServiceId)
+	}
+	return builder.String()
+}
+
+func (timeline *Timeline) record(eventType LifecycleEventType, serviceId ServiceID) {
+	timeline.mutex.Lock()
+	defer timeline.mutex.Unlock()
+	event := LifecycleEvent{
+		Timestamp: time.Now(),
+		EventType: eventType,
+		ServiceId: serviceId,
+	}
+	timeline.events = append(timeline.events, event)
+	for subscription, _ := range timeline.subscriptions {
+		subscription.publish(event)
+	}
+}
@@ -0,0 +1,25 @@
+package networks
+
+import (
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+)
+
+func TestTimelineRecordsEventsInOrder(t *testing.T) {
+	timeline := newTimeline()
+	timeline.record(SERVICE_STARTED, "node1")
+	timeline.record(SERVICE_AVAILABLE, "node1")
+	timeline.record(SERVICE_KILLED, "node1")
+
+	events := timeline.GetEvents()
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, SERVICE_STARTED, events[0].EventType)
+	assert.Equal(t, SERVICE_AVAILABLE, events[1].EventType)
+	assert.Equal(t, SERVICE_KILLED, events[2].EventType)
+	assert.Assert(t, !events[2].Timestamp.Before(events[0].Timestamp))
+
+	rendered := timeline.String()
+	assert.Equal(t, 3, strings.Count(rendered, "node1"))
+	assert.Assert(t, strings.Index(rendered, string(SERVICE_STARTED)) < strings.Index(rendered, string(SERVICE_KILLED)))
+}
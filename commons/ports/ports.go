@@ -0,0 +1,49 @@
+package ports
+
+import (
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	MAX_PORT_NUM = 65535
+)
+
+// The protocols that Docker can expose ports with
+var validProtocols = map[string]bool{
+	"tcp":  true,
+	"udp":  true,
+	"sctp": true,
+}
+
+/*
+Gets the canonical "number/protocol" form of the given port. Port strings without a protocol default to TCP, so e.g.
+	"8080" & "8080/tcp" both normalize to "8080/tcp".
+ */
+func Normalize(port nat.Port) string {
+	return fmt.Sprintf("%v/%v", port.Int(), port.Proto())
+}
+
+/*
+Checks that every port in the given "set" of ports is in range, has a protocol Docker supports, and is declared only once
+	(after normalization).
+ */
+func ValidateUsedPorts(usedPorts map[nat.Port]bool) error {
+	declaredPorts := make(map[string]bool)
+	for port, _ := range usedPorts {
+		portNum := port.Int()
+		if portNum < 1 || portNum > MAX_PORT_NUM {
+			return stacktrace.NewError("Port '%v' isn't in the range 1-%v", port, MAX_PORT_NUM)
+		}
+		if !validProtocols[port.Proto()] {
+			return stacktrace.NewError("Port '%v' has protocol '%v', which isn't one of tcp, udp, or sctp", port, port.Proto())
+		}
+		normalizedPort := Normalize(port)
+		if declaredPorts[normalizedPort] {
+			return stacktrace.NewError("Port %v is declared more than once", normalizedPort)
+		}
+		declaredPorts[normalizedPort] = true
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package ports
+
+import (
+	"github.com/docker/go-connections/nat"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "8080/tcp", Normalize("8080"))
+	assert.Equal(t, "30303/udp", Normalize("30303/udp"))
+}
+
+func TestValidateUsedPorts(t *testing.T) {
+	assert.NilError(t, ValidateUsedPorts(map[nat.Port]bool{"8080/tcp": true, "8080/udp": true}))
+	assert.ErrorContains(t, ValidateUsedPorts(map[nat.Port]bool{"0/tcp": true}), "range")
+	assert.ErrorContains(t, ValidateUsedPorts(map[nat.Port]bool{"8080/icmp": true}), "protocol")
+	assert.ErrorContains(t, ValidateUsedPorts(map[nat.Port]bool{"8080": true, "8080/tcp": true}), "more than once")
+}
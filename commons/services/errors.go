@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+The root cause of errors returned when a service doesn't become available before its availability checker times out (or
+	is cancelled). Errors in this codebase are wrapped with stacktrace, so check for it with:
+
+	var unavailableErr *services.ErrServiceUnavailable
+	if errors.As(stacktrace.RootCause(err), &unavailableErr) { ... }
+ */
+type ErrServiceUnavailable struct {
+	// The ID of the service that didn't become available
+	ServiceId string
+
+	// How long the service was waited on for
+	Timeout time.Duration
+
+	// Why the last availability check found the service unavailable (see ServiceProber), or nil if it was never checked
+	LastProbeError error
+
+	// The context error that ended the wait (context.DeadlineExceeded or context.Canceled)
+	cause error
+}
+
+func (err *ErrServiceUnavailable) Error() string {
+	if err.LastProbeError != nil {
+		return fmt.Sprintf("service %v didn't become available within %v: %v (last check failed with: %v)", err.ServiceId, err.Timeout, err.cause, err.LastProbeError)
+	}
+	return fmt.Sprintf("service %v didn't become available within %v: %v", err.ServiceId, err.Timeout, err.cause)
+}
+
+// Allows errors.Is to check whether the wait timed out or was cancelled
+func (err *ErrServiceUnavailable) Unwrap() error {
+	return err.cause
+}
+
+/*
+The root cause of errors returned when a service's container stops running while its availability checker is waiting for
+	it to start (see ServiceAvailabilityChecker.UseCrashCheck), e.g. because of bad flags or missing config. Check for it
+	with:
+
+	var crashedErr *services.ErrServiceCrashed
+	if errors.As(stacktrace.RootCause(err), &crashedErr) { ... }
+ */
+type ErrServiceCrashed struct {
+	// The ID of the service that crashed
+	ServiceId string
+
+	// Docker's state for the service's container (e.g. "exited"), & its exit code
+	ContainerState string
+	ExitCode int
+
+	// The last lines of the service's logs, for seeing why it crashed
+	LogTail []string
+}
+
+func (err *ErrServiceCrashed) Error() string {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "service %v stopped running while starting up (container %v with exit code %v)", err.ServiceId, err.ContainerState, err.ExitCode)
+	if len(err.LogTail) > 0 {
+		fmt.Fprintf(builder, "; last %v log lines:", len(err.LogTail))
+		for _, line := range err.LogTail {
+			fmt.Fprintf(builder, "\n    | %v", line)
+		}
+	}
+	return builder.String()
+}
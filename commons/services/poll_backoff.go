@@ -0,0 +1,66 @@
+package services
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	// Each interval is this many times as long as the one before it, until the cap is hit
+	pollBackoffMultiplier = 2
+)
+
+/*
+Hands out the intervals to sleep for between polls of something that's expected to become ready (e.g. a freshly started
+	service), growing exponentially from an initial interval up to a cap. Each interval is randomly jittered to somewhere
+	between half & all of its un-jittered length, so that many pollers started at the same moment (e.g. one per service in
+	a freshly started network) don't all hit the services & the Docker engine in lockstep.
+
+NOTE: This is NOT thread-safe; use one per polling loop.
+ */
+type PollBackoff struct {
+	// The un-jittered length of the next interval
+	nextInterval time.Duration
+
+	// The longest that an un-jittered interval can be
+	maxInterval time.Duration
+
+	// The source of the jitter
+	random *rand.Rand
+}
+
+/*
+Creates a new backoff whose first interval is (a jittered) initialInterval.
+
+Args:
+	initialInterval: The un-jittered length of the first interval
+	maxInterval: The longest that an un-jittered interval can grow to (if less than initialInterval, every interval is
+		based on maxInterval instead)
+ */
+func NewPollBackoff(initialInterval time.Duration, maxInterval time.Duration) *PollBackoff {
+	if initialInterval > maxInterval {
+		initialInterval = maxInterval
+	}
+	return &PollBackoff{
+		nextInterval: initialInterval,
+		maxInterval:  maxInterval,
+		random:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+/*
+Gets how long to sleep for before the next poll, growing the interval for the poll after that.
+ */
+func (backoff *PollBackoff) NextInterval() time.Duration {
+	interval := backoff.nextInterval
+	backoff.nextInterval = interval * pollBackoffMultiplier
+	if backoff.nextInterval > backoff.maxInterval {
+		backoff.nextInterval = backoff.maxInterval
+	}
+
+	halfInterval := interval / 2
+	if halfInterval <= 0 {
+		return interval
+	}
+	return halfInterval + time.Duration(backoff.random.Int63n(int64(interval - halfInterval) + 1))
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"gotest.tools/v3/assert"
+	"testing"
+	"time"
+)
+
+func TestPollBackoffGrowsUpToCap(t *testing.T) {
+	backoff := NewPollBackoff(100 * time.Millisecond, 1 * time.Second)
+	expectedIntervals := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		1 * time.Second,
+		1 * time.Second,
+	}
+	for _, expectedInterval := range expectedIntervals {
+		interval := backoff.NextInterval()
+		assert.Assert(t, interval >= expectedInterval / 2, "Interval %v was less than half of %v", interval, expectedInterval)
+		assert.Assert(t, interval <= expectedInterval, "Interval %v was more than %v", interval, expectedInterval)
+	}
+}
+
+func TestPollBackoffWithInitialIntervalAboveCap(t *testing.T) {
+	backoff := NewPollBackoff(1 * time.Second, 10 * time.Millisecond)
+	assert.Assert(t, backoff.NextInterval() <= 10 * time.Millisecond)
+}
@@ -2,15 +2,27 @@ package services
 
 import (
 	"context"
+	"errors"
+	"github.com/kurtosis-tech/kurtosis/commons/logging"
+	"github.com/kurtosis-tech/kurtosis/commons/metrics"
+	"github.com/kurtosis-tech/kurtosis/commons/tracing"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
 	"time"
 )
 
 const (
-	TIME_BETWEEN_STARTUP_POLLS = 1 * time.Second
+	// How long the availability checker waits after the first failed check; the wait then doubles after each failed check,
+	//  up to the core's cap (see MaxPollIntervalProvider)
+	INITIAL_STARTUP_POLL_INTERVAL = 100 * time.Millisecond
+
+	// The cap on the wait between checks, for cores that don't set their own
+	DEFAULT_MAX_STARTUP_POLL_INTERVAL = 2 * time.Second
 )
 
+// The reason given for failed checks by cores that can't say why the service is unavailable (see ServiceProber)
+var errServiceNotUp = errors.New("the availability checker core reported the service as not up")
+
 /*
 Contains the logic wrapping a ServiceAvailabilityCheckerCore, which is used to make requests against a service and verify
 	if it's actually available (because a Docker container running doesn't necessarily mean that the service is running).
@@ -26,6 +38,12 @@ type ServiceAvailabilityChecker struct {
 	 */
 	context context.Context
 
+	// The ID of the service being checked, used for logging and metrics
+	serviceId string
+
+	// When the checker was created (which is right after the service's container was started)
+	creationTime time.Time
+
 	// The developer-defined criteria for determining if their custom service is available
 	core ServiceAvailabilityCheckerCore
 
@@ -34,6 +52,15 @@ type ServiceAvailabilityChecker struct {
 
 	// The dependencies that the service-to-check depends on (just in case it's useful)
 	dependencies []Service
+
+	// Functions that will be called once the service is found to be available
+	availabilityListeners []func()
+
+	// Functions that will be called with the reason each time a check finds the service unavailable
+	probeFailureListeners []func(probeErr error)
+
+	// If non-nil, called after each failed check to find out whether the service has crashed (see UseCrashCheck)
+	crashCheck func() error
 }
 
 /*
@@ -41,47 +68,147 @@ Creates a new availability checker using the given core.
 
 Args:
 	context: The context that availability-checking will happen in, which can be used to check availability checking
+	serviceId: The ID of the service being checked, used for logging and metrics
 	core: The user-defined criteria for whether their custom service is up
 	toCheck: The service to check
 	dependencies: The dependencies of the service being checked
  */
-func NewServiceAvailabilityChecker(context context.Context, core ServiceAvailabilityCheckerCore, toCheck Service, dependencies []Service) *ServiceAvailabilityChecker {
+func NewServiceAvailabilityChecker(context context.Context, serviceId string, core ServiceAvailabilityCheckerCore, toCheck Service, dependencies []Service) *ServiceAvailabilityChecker {
 	// Defensive copy
 	dependenciesCopy := make([]Service, 0, len(dependencies))
 	copy(dependenciesCopy, dependencies)
 
 	return &ServiceAvailabilityChecker{
 		context: context,
+		serviceId: serviceId,
+		creationTime: time.Now(),
 		core: core,
 		toCheck: toCheck,
 		dependencies: dependenciesCopy,
+		availabilityListeners: []func(){},
+		probeFailureListeners: []func(probeErr error){},
+		crashCheck: nil,
 	}
 }
 
+/*
+Registers a function that will be called when WaitForStartup finds the service to be available.
+ */
+func (checker *ServiceAvailabilityChecker) OnAvailable(listener func()) {
+	checker.availabilityListeners = append(checker.availabilityListeners, listener)
+}
+
+/*
+Registers a function that will be called, with the reason, each time WaitForStartup finds the service to be unavailable.
+ */
+func (checker *ServiceAvailabilityChecker) OnProbeFailed(listener func(probeErr error)) {
+	checker.probeFailureListeners = append(checker.probeFailureListeners, listener)
+}
+
+/*
+Makes WaitForStartup fail fast, rather than waiting out its timeout, once the given function reports that the service
+	has crashed by returning an error (e.g. an ErrServiceCrashed), which WaitForStartup then returns. The function is
+	called after each failed check.
+ */
+func (checker *ServiceAvailabilityChecker) UseCrashCheck(crashCheck func() error) {
+	checker.crashCheck = crashCheck
+}
+
 /*
 Waits for the service that was passed in at construction time to start up by making requests to the service until
 	the availability checker core's criteria are met or the timeout is reached.
  */
 func (checker ServiceAvailabilityChecker) WaitForStartup() error {
-	startupTimeout := checker.core.GetTimeout()
+	return checker.WaitForStartupWithTimeout(checker.core.GetTimeout())
+}
 
-	timeoutContext, cancel := context.WithTimeout(checker.context, startupTimeout)
+/*
+Waits for the service to start up like WaitForStartup, but with the given timeout in place of the availability checker
+	core's (e.g. for a test that knows its service starts slowly under load).
+ */
+func (checker ServiceAvailabilityChecker) WaitForStartupWithTimeout(startupTimeout time.Duration) (err error) {
+	spanContext, span := tracing.StartSpan(checker.context, "WaitForStartup")
+	span.SetAttribute(logging.SERVICE_ID_FIELD, checker.serviceId)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	timeoutContext, cancel := context.WithTimeout(spanContext, startupTimeout)
 	defer cancel()
 
+	maxPollInterval := DEFAULT_MAX_STARTUP_POLL_INTERVAL
+	if maxPollIntervalProvider, ok := checker.core.(MaxPollIntervalProvider); ok {
+		maxPollInterval = maxPollIntervalProvider.GetMaxPollInterval()
+	}
+	backoff := NewPollBackoff(INITIAL_STARTUP_POLL_INTERVAL, maxPollInterval)
+	var lastProbeErr error
 	for timeoutContext.Err() == nil {
-		if checker.core.IsServiceUp(checker.toCheck, checker.dependencies) {
+		lastProbeErr = checker.CheckAvailability()
+		if lastProbeErr == nil {
+			metrics.ServiceStartupSeconds.Observe(time.Since(checker.creationTime).Seconds(), checker.serviceId)
 			return nil
 		}
-		logrus.Tracef("Service is not yet available; sleeping for %v before retrying...", TIME_BETWEEN_STARTUP_POLLS)
-		time.Sleep(TIME_BETWEEN_STARTUP_POLLS)
+		if checker.crashCheck != nil {
+			if crashErr := checker.crashCheck(); crashErr != nil {
+				return stacktrace.Propagate(crashErr, "Service crashed while waiting for it to start")
+			}
+		}
+		pollInterval := backoff.NextInterval()
+		logrus.WithField(logging.SERVICE_ID_FIELD, checker.serviceId).Tracef("Service is not yet available; sleeping for %v before retrying...", pollInterval)
+		select {
+		case <-time.After(pollInterval):
+		case <-timeoutContext.Done():
+		}
 	}
 
 	contextErr := timeoutContext.Err()
+	unavailableErr := &ErrServiceUnavailable{
+		ServiceId:      checker.serviceId,
+		Timeout:        startupTimeout,
+		LastProbeError: lastProbeErr,
+		cause:          contextErr,
+	}
 	if (contextErr == context.Canceled) {
-		return stacktrace.Propagate(contextErr, "Context was cancelled while waiting for service to startFailed to Hit timeout (%v) while waiting for service to start", startupTimeout)
+		return stacktrace.Propagate(unavailableErr, "Context was cancelled while waiting for service to startFailed to Hit timeout (%v) while waiting for service to start", startupTimeout)
 	} else if (contextErr == context.DeadlineExceeded) {
-		return stacktrace.Propagate(contextErr, "Hit timeout (%v) while waiting for service to start", startupTimeout)
+		return stacktrace.Propagate(unavailableErr, "Hit timeout (%v) while waiting for service to start", startupTimeout)
 	} else {
-		return stacktrace.Propagate(contextErr, "Hit an unknown context error while waiting for service to start")
+		return stacktrace.Propagate(unavailableErr, "Hit an unknown context error while waiting for service to start")
+	}
+}
+
+/*
+Checks once whether the service is available, notifying the checker's listeners of the result as WaitForStartup does,
+	for callers that poll the service on their own schedule.
+
+Returns:
+	Why the service isn't available, or nil if it is
+ */
+func (checker ServiceAvailabilityChecker) CheckAvailability() error {
+	metrics.AvailabilityProbes.Inc(checker.serviceId)
+	probeErr := checker.probe()
+	if probeErr == nil {
+		for _, listener := range checker.availabilityListeners {
+			listener()
+		}
+		return nil
+	}
+	metrics.AvailabilityProbeFailures.Inc(checker.serviceId)
+	for _, listener := range checker.probeFailureListeners {
+		listener(probeErr)
+	}
+	return probeErr
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Checks whether the service is available, returning why it isn't (if the core can say, per ServiceProber)
+func (checker ServiceAvailabilityChecker) probe() error {
+	if prober, ok := checker.core.(ServiceProber); ok {
+		return prober.ProbeService(checker.toCheck, checker.dependencies)
+	}
+	if !checker.core.IsServiceUp(checker.toCheck, checker.dependencies) {
+		return errServiceNotUp
 	}
+	return nil
 }
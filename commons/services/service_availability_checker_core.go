@@ -26,3 +26,28 @@ type ServiceAvailabilityCheckerCore interface {
 	// How long to keep checking for the service to be available before giving up
 	GetTimeout() time.Duration
 }
+
+/*
+An optional interface that a ServiceAvailabilityCheckerCore can implement to cap how long its availability checker waits
+	between checks (e.g. a lower cap for a service whose tests need to notice it's up quickly, or a higher one for a service
+	that checks are expensive for). Cores that don't implement it are capped at DEFAULT_MAX_STARTUP_POLL_INTERVAL.
+ */
+type MaxPollIntervalProvider interface {
+	// Gets the longest that the availability checker should wait between checks (before jitter)
+	GetMaxPollInterval() time.Duration
+}
+
+/*
+An optional interface that a ServiceAvailabilityCheckerCore can implement to say why a service isn't available yet (e.g.
+	"connection refused" or an unexpected HTTP status), which is reported when the service doesn't become available in
+	time. The availability checker calls it in place of IsServiceUp.
+ */
+type ServiceProber interface {
+	/*
+	Performs the same check as IsServiceUp.
+
+	Returns:
+		Nil if the service is available, or why it isn't
+	 */
+	ProbeService(toCheck Service, dependencies []Service) error
+}
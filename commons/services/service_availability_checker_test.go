@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"testing"
+	"time"
+)
+
+type neverUpCheckerCore struct {}
+func (core neverUpCheckerCore) IsServiceUp(toCheck Service, dependencies []Service) bool {
+	return false
+}
+func (core neverUpCheckerCore) GetTimeout() time.Duration {
+	return time.Minute
+}
+
+func TestUnavailableServiceError(t *testing.T) {
+	checker := NewServiceAvailabilityChecker(context.Background(), "node1", neverUpCheckerCore{}, nil, []Service{})
+	err := checker.WaitForStartupWithTimeout(10 * time.Millisecond)
+
+	var unavailableErr *ErrServiceUnavailable
+	assert.Assert(t, errors.As(stacktrace.RootCause(err), &unavailableErr))
+	assert.Equal(t, "node1", unavailableErr.ServiceId)
+	assert.Equal(t, 10 * time.Millisecond, unavailableErr.Timeout)
+	assert.Assert(t, errors.Is(unavailableErr, context.DeadlineExceeded))
+}
+
+type slowStartingCheckerCore struct {
+	numChecks int
+}
+func (core *slowStartingCheckerCore) IsServiceUp(toCheck Service, dependencies []Service) bool {
+	core.numChecks++
+	return core.numChecks > 5
+}
+func (core *slowStartingCheckerCore) GetTimeout() time.Duration {
+	return time.Minute
+}
+func (core *slowStartingCheckerCore) GetMaxPollInterval() time.Duration {
+	return time.Millisecond
+}
+
+func TestMaxPollIntervalIsRespected(t *testing.T) {
+	core := &slowStartingCheckerCore{}
+	checker := NewServiceAvailabilityChecker(context.Background(), "node1", core, nil, []Service{})
+
+	// With the default cap, five failed checks would take well over a second
+	startTime := time.Now()
+	assert.NilError(t, checker.WaitForStartup())
+	assert.Assert(t, time.Since(startTime) < 500 * time.Millisecond)
+	assert.Equal(t, 6, core.numChecks)
+}
+
+type refusingCheckerCore struct {
+	neverUpCheckerCore
+}
+func (core refusingCheckerCore) ProbeService(toCheck Service, dependencies []Service) error {
+	return errors.New("connection refused")
+}
+
+func TestUnavailableServiceErrorHasLastProbeError(t *testing.T) {
+	checker := NewServiceAvailabilityChecker(context.Background(), "node1", refusingCheckerCore{}, nil, []Service{})
+	numProbeFailures := 0
+	checker.OnProbeFailed(func(probeErr error) {
+		numProbeFailures++
+	})
+	err := checker.WaitForStartupWithTimeout(10 * time.Millisecond)
+
+	var unavailableErr *ErrServiceUnavailable
+	assert.Assert(t, errors.As(stacktrace.RootCause(err), &unavailableErr))
+	assert.Error(t, unavailableErr.LastProbeError, "connection refused")
+	assert.ErrorContains(t, unavailableErr, "last check failed with: connection refused")
+	assert.Assert(t, numProbeFailures > 0)
+}
+
+func TestCrashCheckFailsFast(t *testing.T) {
+	checker := NewServiceAvailabilityChecker(context.Background(), "node1", neverUpCheckerCore{}, nil, []Service{})
+	numCrashChecks := 0
+	checker.UseCrashCheck(func() error {
+		numCrashChecks++
+		if numCrashChecks < 3 {
+			return nil
+		}
+		return &ErrServiceCrashed{ServiceId: "node1", ContainerState: "exited", ExitCode: 1, LogTail: []string{"bad config"}}
+	})
+
+	startTime := time.Now()
+	err := checker.WaitForStartup()
+	assert.Assert(t, time.Since(startTime) < 10 * time.Second)
+	var crashedErr *ErrServiceCrashed
+	assert.Assert(t, errors.As(stacktrace.RootCause(err), &crashedErr))
+	assert.Equal(t, 3, numCrashChecks)
+	assert.ErrorContains(t, err, "exit code 1); last 1 log lines:\n    | bad config")
+}
@@ -11,6 +11,24 @@ import (
 	"path/filepath"
 )
 
+const (
+	// Every service gets the test volume mounted here, in addition to its image-specific test volume mountpoint, so that
+	//  the shared artifact store is at the same path in every service
+	SHARED_VOLUME_MOUNTPOINT = "/kurtosis-shared"
+
+	// The name of the directory, inside the test volume, that holds artifacts shared between all services and the test
+	SHARED_ARTIFACT_STORE_DIRNAME = "artifact-store"
+
+	// The path in every service's container where the artifact store shared between all services and the test can be found
+	SHARED_ARTIFACT_STORE_DIRPATH = SHARED_VOLUME_MOUNTPOINT + "/" + SHARED_ARTIFACT_STORE_DIRNAME
+
+	worldWritableDirPerms = 0777
+
+	// The name of the directory, inside a service's directory in the test volume, that its coverage is written to (see
+	//  CoverageProvider)
+	COVERAGE_DIRNAME = "coverage"
+)
+
 /*
 A struct that wraps a user-defined ServiceInitializerCore, which will instruct the initializer how to launch a new instance
 	of the user's service.
@@ -54,6 +72,8 @@ Args:
 	staticIp: The IP the new service will be given
 	manager: The DockerManager used to launch the container running the service
 	dependencies: The services that the service-to-be-started depends on
+	dependencyIpAddrs: Mapping of service_id -> IP address for the services that the service-to-be-started depends on
+	containerOptions: Optional settings for the Docker container the service will run in
 
 Returns:
 	Service: The interface which should be used to access the newly-created service (which, because Go doesn't have generics,
@@ -65,8 +85,70 @@ func (initializer ServiceInitializer) CreateService(
 			testVolumeName string,
 			dockerImage string,
 			staticIp net.IP,
-			manager *docker.DockerManager,
-			dependencies []Service) (Service, string, error) {
+			manager docker.ContainerManager,
+			dependencies []Service,
+			dependencyIpAddrs map[string]net.IP,
+			containerOptions docker.ContainerOptions) (Service, string, error) {
+	return initializer.createService(
+			context,
+			testVolumeName,
+			dockerImage,
+			staticIp,
+			manager,
+			dependencies,
+			dependencyIpAddrs,
+			containerOptions,
+			true)
+}
+
+/*
+Creates a service like CreateService, but without starting its container, so that the caller can start it later with
+	DockerManager.StartContainer (e.g. to start several services at once).
+
+Returns:
+	Service: The interface which should be used to access the service once it's started
+	string: The ID of the Docker container the service will run in
+ */
+func (initializer ServiceInitializer) CreateUnstartedService(
+			context context.Context,
+			testVolumeName string,
+			dockerImage string,
+			staticIp net.IP,
+			manager docker.ContainerManager,
+			dependencies []Service,
+			dependencyIpAddrs map[string]net.IP,
+			containerOptions docker.ContainerOptions) (Service, string, error) {
+	return initializer.createService(
+			context,
+			testVolumeName,
+			dockerImage,
+			staticIp,
+			manager,
+			dependencies,
+			dependencyIpAddrs,
+			containerOptions,
+			false)
+}
+
+/*
+Calls down to the initializer core to get an instance of the user-defined interface that is used for interacting with
+	the user's service. The core will do the instantiation of the actual interface implementation.
+ */
+func (initializer ServiceInitializer) GetServiceFromIp(ipAddr net.IP) Service {
+	return initializer.core.GetServiceFromIp(ipAddr.String())
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (initializer ServiceInitializer) createService(
+			context context.Context,
+			testVolumeName string,
+			dockerImage string,
+			staticIp net.IP,
+			manager docker.ContainerManager,
+			dependencies []Service,
+			dependencyIpAddrs map[string]net.IP,
+			containerOptions docker.ContainerOptions,
+			startContainer bool) (Service, string, error) {
 	initializerCore := initializer.core
 	usedPorts := initializerCore.GetUsedPorts()
 
@@ -78,6 +160,11 @@ func (initializer ServiceInitializer) CreateService(
 	}
 	mountServiceDirpath := filepath.Join(initializerCore.GetTestVolumeMountpoint(), serviceDirname)
 
+	controllerArtifactStoreDirpath := filepath.Join(initializer.testVolumeControllerDirpath, SHARED_ARTIFACT_STORE_DIRNAME)
+	if err := createWorldWritableDirectory(controllerArtifactStoreDirpath); err != nil {
+		return nil, "", stacktrace.Propagate(err, "An error occurred creating the shared artifact store")
+	}
+
 	requestedFiles := initializerCore.GetFilesToMount()
 	osFiles := make(map[string]*os.File)
 	mountFilepaths := make(map[string]string)
@@ -93,25 +180,82 @@ func (initializer ServiceInitializer) CreateService(
 		mountFilepaths[fileId] = filepath.Join(mountServiceDirpath, filename)
 	}
 	err = initializerCore.InitializeMountedFiles(osFiles, dependencies)
-	startCmdArgs, err := initializerCore.GetStartCommand(mountFilepaths, staticIp, dependencies)
+	if err != nil {
+		return nil, "", stacktrace.Propagate(err, "An error occurred initializing the service's mounted files")
+	}
+
+	dependencyIpAddrStrs := make(map[string]string)
+	for dependencyId, dependencyIpAddr := range dependencyIpAddrs {
+		dependencyIpAddrStrs[dependencyId] = dependencyIpAddr.String()
+	}
+	startCommandContext := StartCommandContext{
+		IpAddr:                     staticIp.String(),
+		Ports:                      getNamedPortNumbers(initializerCore),
+		MountedFilepaths:           mountFilepaths,
+		DependencyIpAddrs:          dependencyIpAddrStrs,
+		Dependencies:               dependencies,
+		SharedArtifactStoreDirpath: SHARED_ARTIFACT_STORE_DIRPATH,
+	}
+	startCmdTemplates, err := initializerCore.GetStartCommand(startCommandContext)
 	if err != nil {
 		return nil, "", stacktrace.Propagate(err, "Failed to create start command.")
 	}
+	startCmdArgs, err := RenderStartCommand(startCmdTemplates, startCommandContext)
+	if err != nil {
+		return nil, "", stacktrace.Propagate(err, "An error occurred rendering the start command")
+	}
+
+	envVariables, err := getEnvVariables(initializerCore, startCommandContext)
+	if err != nil {
+		return nil, "", stacktrace.Propagate(err, "An error occurred getting the service's env variables")
+	}
+	if entrypointProvider, ok := initializerCore.(EntrypointProvider); ok {
+		if entrypointTemplates := entrypointProvider.GetEntrypoint(); len(entrypointTemplates) > 0 {
+			entrypoint, err := RenderStartCommand(entrypointTemplates, startCommandContext)
+			if err != nil {
+				return nil, "", stacktrace.Propagate(err, "An error occurred rendering the entrypoint")
+			}
+			containerOptions.Entrypoint = entrypoint
+		}
+	}
+	if coverageProvider, ok := initializerCore.(CoverageProvider); ok {
+		if err := createWorldWritableDirectory(filepath.Join(controllerServiceDirpath, COVERAGE_DIRNAME)); err != nil {
+			return nil, "", stacktrace.Propagate(err, "An error occurred creating the service's coverage directory")
+		}
+		mountCoverageDirpath := filepath.Join(mountServiceDirpath, COVERAGE_DIRNAME)
+		envVariables[coverageProvider.GetCoverageDirEnvVariable()] = mountCoverageDirpath
+		// Lets the network find the directory when collecting the coverage, without knowing the service's directory
+		labels := map[string]string{}
+		for key, value := range containerOptions.Labels {
+			labels[key] = value
+		}
+		labels[docker.COVERAGE_DIRPATH_LABEL] = mountCoverageDirpath
+		containerOptions.Labels = labels
+	}
 
 	volumeMounts := map[string]string{
 		testVolumeName: initializerCore.GetTestVolumeMountpoint(),
 	}
+	// The volume mounts map is keyed by volume, so it can't hold a second mount of the same volume
+	containerOptions.ExtraVolumeMounts = append(
+		append([]docker.VolumeMount{}, containerOptions.ExtraVolumeMounts...),
+		docker.VolumeMount{VolumeName: testVolumeName, ContainerDirpath: SHARED_VOLUME_MOUNTPOINT})
 
-	containerId, err := manager.CreateAndStartContainer(
+	createContainerFunc := manager.CreateContainer
+	if startContainer {
+		createContainerFunc = manager.CreateAndStartContainer
+	}
+	containerId, err := createContainerFunc(
 			context,
 			dockerImage,
 			initializer.networkId,
 			staticIp,
 			usedPorts,
 			startCmdArgs,
+			envVariables,
 			make(map[string]string),
-			make(map[string]string),
-			volumeMounts)
+			volumeMounts,
+			containerOptions)
 	if err != nil {
 		return nil, "", stacktrace.Propagate(err, "Could not start docker service for image %v", dockerImage)
 	}
@@ -119,9 +263,32 @@ func (initializer ServiceInitializer) CreateService(
 }
 
 /*
-Calls down to the initializer core to get an instance of the user-defined interface that is used for interacting with
-	the user's service. The core will do the instantiation of the actual interface implementation.
+Gets the env variables that the given core wants set in its service's container (see EnvVariablesProvider), rendered
+	against the given context.
  */
-func (initializer ServiceInitializer) GetServiceFromIp(ipAddr net.IP) Service {
-	return initializer.core.GetServiceFromIp(ipAddr.String())
+func getEnvVariables(core ServiceInitializerCore, startCommandContext StartCommandContext) (map[string]string, error) {
+	envVariablesProvider, ok := core.(EnvVariablesProvider)
+	if !ok {
+		return make(map[string]string), nil
+	}
+	result, err := RenderEnvVariables(envVariablesProvider.GetEnvVariables(), startCommandContext)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred rendering the env variables")
+	}
+	return result, nil
+}
+
+/*
+Creates a world-writable directory (e.g. the artifact store shared between all services & the test), if it doesn't already
+	exist. Directories that services write to must be world-writable because services may not run as root.
+ */
+func createWorldWritableDirectory(dirpath string) error {
+	if err := os.MkdirAll(dirpath, worldWritableDirPerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating directory %v", dirpath)
+	}
+	// MkdirAll's permissions are subject to the umask, so we set them explicitly
+	if err := os.Chmod(dirpath, worldWritableDirPerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred setting the permissions of directory %v", dirpath)
+	}
+	return nil
 }
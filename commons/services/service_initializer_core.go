@@ -2,7 +2,6 @@ package services
 
 import (
 	"github.com/docker/go-connections/nat"
-	"net"
 	"os"
 )
 
@@ -67,23 +66,57 @@ type ServiceInitializerCore interface {
 	 */
 	GetTestVolumeMountpoint() string
 
-	// GENERICS TOOD: when Go gets generics, make the type of 'dependencies' to be []N
-	// If Go had generics, dependencies should be of type []T
 	/*
-	Uses the given arguments to build the command that the Docker container running this service will be launched with.
+	Builds the command that the Docker container running this service will be launched with. Each returned fragment is
+	rendered as a Go text/template against the given context before use, so fragments can refer to network-derived values
+	declaratively (e.g. "--bootstrap-ip={{ .DependencyIpAddrs.bootstrap }}") rather than computing them by hand.
 
 	Args:
-		mountedFileFilepaths: Mapping of developer_key -> initialized_file_filepath where developer_key corresponds to the keys returned
-			in the `GetFilesToMount` function, and initialized_file_filepath is the path *on the Docker container* of where the
-			file has been mounted. The files will have already been initialized via the `InitializeMountedFiles` function.
-		publicIpAddr: The IP address of the Docker image running the service
-		dependencies: The services that this service depends on (for use in case the command line to the service changes based on dependencies)
+		startCommandContext: The network-derived values available to the start command, including the service's own IP,
+			its named ports, the filepaths of the files requested in `GetFilesToMount` (already initialized via
+			`InitializeMountedFiles`), and the addresses of its dependencies
 
 	Returns:
-		The command fragments which will be used to construct the run command which will be used to launch the Docker container
-			running the service
+		The command fragments which will be rendered and then used to construct the run command which will be used to launch
+			the Docker container running the service
 	 */
-	GetStartCommand(mountedFileFilepaths map[string]string, publicIpAddr net.IP, dependencies []Service) ([]string, error)
+	GetStartCommand(startCommandContext StartCommandContext) ([]string, error)
 
 }
 
+
+/*
+An optional interface that a ServiceInitializerCore can implement to set environment variables in the Docker container
+	running the service (e.g. for images that are configured through their environment rather than their command).
+ */
+type EnvVariablesProvider interface {
+	// Gets a mapping of env_variable_name -> value to set in the service's container. Like start command fragments, each
+	//  value is rendered as a Go text/template against the service's StartCommandContext before use (see RenderEnvVariables).
+	GetEnvVariables() map[string]string
+}
+
+/*
+An optional interface that a ServiceInitializerCore can implement to override the entrypoint of the service's image (e.g.
+	to run the image's binary through a wrapper script), taking precedence over the configuration's
+	docker.ContainerOptions.Entrypoint.
+ */
+type EntrypointProvider interface {
+	// Gets the entrypoint that the service's container runs the start command with (the image's own if empty). Like start
+	//  command fragments, each fragment is rendered as a Go text/template against the service's StartCommandContext.
+	GetEntrypoint() []string
+}
+
+/*
+An optional interface that a ServiceInitializerCore can implement for services whose binaries are instrumented for
+	coverage (e.g. Go binaries built with "-cover"), so that integration tests can measure the coverage of the services
+	under test. Kurtosis gives each such service a coverage output directory in the test volume, passed in the given env
+	variable, and at teardown signals the service to flush its coverage & collects the directory's files into the test's
+	artifacts (see networks.ServiceNetwork.CollectCoverage).
+ */
+type CoverageProvider interface {
+	// Gets the name of the env variable that the instrumented binary reads its coverage output directory from (e.g. "GOCOVERDIR")
+	GetCoverageDirEnvVariable() string
+
+	// Gets the name of the signal that makes the binary write out its coverage & exit (e.g. "SIGTERM" for Go binaries)
+	GetCoverageFlushSignal() string
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bytes"
+	"github.com/docker/go-connections/nat"
+	"github.com/palantir/stacktrace"
+	"text/template"
+)
+
+/*
+An optional interface that a ServiceInitializerCore can implement to give its ports names, so that start command templates
+	can refer to them (e.g. "--rpc-port={{ .Ports.rpc }}").
+ */
+type NamedPortsProvider interface {
+	// Gets a mapping of port_name -> port, with every port also being in the core's GetUsedPorts
+	GetNamedPorts() map[string]nat.Port
+}
+
+/*
+The network-derived values that a service's start command is rendered with. Every fragment that a ServiceInitializerCore
+	returns from GetStartCommand is rendered as a Go text/template against this context, so fragments can be written
+	declaratively, e.g.:
+
+		"--public-ip={{ .IpAddr }}"
+		"--bootstrap-node={{ .DependencyIpAddrs.bootstrap }}:{{ .Ports.staking }}"
+		"--config={{ .MountedFilepaths.config }}"
+ */
+type StartCommandContext struct {
+	// The IP address of the Docker container running the service
+	IpAddr string
+
+	// Mapping of port_name -> port number, for initializer cores that implement NamedPortsProvider
+	Ports map[string]int
+
+	// Mapping of developer_key -> filepath *on the Docker container* of the file requested in GetFilesToMount, which will
+	//  have already been initialized via InitializeMountedFiles
+	MountedFilepaths map[string]string
+
+	// Mapping of service_id -> IP address for each of the services that this service depends on
+	DependencyIpAddrs map[string]string
+
+	// The services that this service depends on, for cores that need more than their dependencies' addresses
+	Dependencies []Service
+
+	// The path in the service's container where the artifact store shared between all services can be found
+	SharedArtifactStoreDirpath string
+}
+
+/*
+Renders each of the given start command fragments as a Go text/template against the given context. Referencing a value
+	that doesn't exist in the context (e.g. a dependency that wasn't declared) is an error, rather than rendering as empty.
+
+Args:
+	fragments: The start command fragments, which may contain template actions
+	startCommandContext: The values to render the fragments with
+
+Returns:
+	The rendered start command fragments
+ */
+func RenderStartCommand(fragments []string, startCommandContext StartCommandContext) ([]string, error) {
+	result := make([]string, 0, len(fragments))
+	for i, fragment := range fragments {
+		rendered, err := renderTemplate(fragment, startCommandContext)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred rendering start command fragment #%v, '%v'", i, fragment)
+		}
+		result = append(result, rendered)
+	}
+	return result, nil
+}
+
+/*
+Renders each of the given env variables' values as a Go text/template against the given context, as with
+	RenderStartCommand, so values can refer to network-derived values (e.g. "{{ .DependencyIpAddrs.bootstrap }}:9650").
+
+Args:
+	envVariables: Mapping of env_variable_name -> value, which may contain template actions
+	startCommandContext: The values to render the env variables' values with
+
+Returns:
+	A mapping of env_variable_name -> rendered value
+ */
+func RenderEnvVariables(envVariables map[string]string, startCommandContext StartCommandContext) (map[string]string, error) {
+	result := make(map[string]string)
+	for name, value := range envVariables {
+		rendered, err := renderTemplate(value, startCommandContext)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred rendering the value '%v' of env variable %v", value, name)
+		}
+		result[name] = rendered
+	}
+	return result, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Renders the given Go text/template against the given context, failing on references to values that don't exist
+func renderTemplate(templateStr string, startCommandContext StartCommandContext) (string, error) {
+	parsedTemplate, err := template.New("start-command").Option("missingkey=error").Parse(templateStr)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred parsing the template")
+	}
+	buffer := &bytes.Buffer{}
+	if err := parsedTemplate.Execute(buffer, startCommandContext); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred executing the template")
+	}
+	return buffer.String(), nil
+}
+
+func getNamedPortNumbers(core ServiceInitializerCore) map[string]int {
+	result := make(map[string]int)
+	namedPortsProvider, ok := core.(NamedPortsProvider)
+	if !ok {
+		return result
+	}
+	for name, port := range namedPortsProvider.GetNamedPorts() {
+		result[name] = port.Int()
+	}
+	return result
+}
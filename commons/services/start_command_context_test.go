@@ -0,0 +1,74 @@
+package services
+
+import (
+	"github.com/docker/go-connections/nat"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+type namedPortsCore struct {
+	ServiceInitializerCore
+}
+
+func (core namedPortsCore) GetNamedPorts() map[string]nat.Port {
+	return map[string]nat.Port{
+		"rpc": nat.Port("9650/tcp"),
+	}
+}
+
+func TestRenderStartCommand(t *testing.T) {
+	startCommandContext := StartCommandContext{
+		IpAddr:            "172.23.0.3",
+		Ports:             getNamedPortNumbers(namedPortsCore{}),
+		MountedFilepaths:  map[string]string{"config": "/volume/service-1/abc"},
+		DependencyIpAddrs: map[string]string{"bootstrap": "172.23.0.2"},
+	}
+	rendered, err := RenderStartCommand(
+		[]string{
+			"/node",
+			"--public-ip={{ .IpAddr }}",
+			"--bootstrap={{ .DependencyIpAddrs.bootstrap }}:{{ .Ports.rpc }}",
+			"--config={{ .MountedFilepaths.config }}",
+		},
+		startCommandContext)
+	assert.NilError(t, err)
+	assert.DeepEqual(
+		t,
+		[]string{"/node", "--public-ip=172.23.0.3", "--bootstrap=172.23.0.2:9650", "--config=/volume/service-1/abc"},
+		rendered)
+}
+
+func TestRenderStartCommandWithMissingValue(t *testing.T) {
+	startCommandContext := StartCommandContext{
+		DependencyIpAddrs: map[string]string{},
+	}
+	_, err := RenderStartCommand([]string{"--bootstrap={{ .DependencyIpAddrs.bootstrap }}"}, startCommandContext)
+	assert.Assert(t, err != nil)
+}
+
+func TestCoresWithoutNamedPortsGetNoPorts(t *testing.T) {
+	var core ServiceInitializerCore
+	assert.Equal(t, 0, len(getNamedPortNumbers(core)))
+}
+
+func TestRenderEnvVariables(t *testing.T) {
+	startCommandContext := StartCommandContext{
+		IpAddr:            "172.23.0.3",
+		DependencyIpAddrs: map[string]string{"bootstrap": "172.23.0.2"},
+	}
+	rendered, err := RenderEnvVariables(
+		map[string]string{
+			"PUBLIC_IP":      "{{ .IpAddr }}",
+			"BOOTSTRAP_ADDR": "{{ .DependencyIpAddrs.bootstrap }}:9650",
+			"LOG_LEVEL":      "debug",
+		},
+		startCommandContext)
+	assert.NilError(t, err)
+	assert.DeepEqual(
+		t,
+		map[string]string{"PUBLIC_IP": "172.23.0.3", "BOOTSTRAP_ADDR": "172.23.0.2:9650", "LOG_LEVEL": "debug"},
+		rendered)
+
+	_, err = RenderEnvVariables(map[string]string{"PEER": "{{ .DependencyIpAddrs.missing }}"}, startCommandContext)
+	assert.Assert(t, err != nil)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/ports"
+	"github.com/palantir/stacktrace"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// The IP that the start command is generated with during validation
+	validationSampleIpAddr = "172.23.0.2"
+
+	// The directory that the sample filepaths of mounted files are in during validation
+	validationSampleFilesDirpath = "/validation"
+)
+
+/*
+Checks that the given initializer core describes a service that can be started, so that mistakes are reported when the
+	service's configuration is registered rather than partway through a test:
+	- every used port is in range, has a valid protocol, and is declared only once
+	- every named port (see NamedPortsProvider) is a used port
+	- the test volume mountpoint is an absolute path that doesn't collide with SHARED_VOLUME_MOUNTPOINT
+	- the start command can be generated from a sample context without panicking
+ */
+func ValidateInitializerCore(core ServiceInitializerCore) error {
+	if core == nil {
+		return stacktrace.NewError("Initializer core must not be nil")
+	}
+
+	usedPorts := core.GetUsedPorts()
+	if err := ports.ValidateUsedPorts(usedPorts); err != nil {
+		return stacktrace.Propagate(err, "The used ports are invalid")
+	}
+
+	if namedPortsProvider, ok := core.(NamedPortsProvider); ok {
+		declaredPorts := make(map[string]bool)
+		for port, _ := range usedPorts {
+			declaredPorts[ports.Normalize(port)] = true
+		}
+		for name, port := range namedPortsProvider.GetNamedPorts() {
+			if !declaredPorts[ports.Normalize(port)] {
+				return stacktrace.NewError("Named port '%v' is port %v, which isn't one of the used ports", name, port)
+			}
+		}
+	}
+
+	mountpoint := core.GetTestVolumeMountpoint()
+	if !filepath.IsAbs(mountpoint) {
+		return stacktrace.NewError("Test volume mountpoint '%v' isn't an absolute path", mountpoint)
+	}
+	if filepath.Clean(mountpoint) == SHARED_VOLUME_MOUNTPOINT {
+		return stacktrace.NewError("Test volume mountpoint can't be %v, which is reserved for the shared volume", SHARED_VOLUME_MOUNTPOINT)
+	}
+
+	if err := checkStartCommandGeneration(core); err != nil {
+		return stacktrace.Propagate(err, "The start command couldn't be generated from a sample context")
+	}
+	return nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Generates the start command from a sample context, turning a panic into an error. An error returned by the core isn't a
+	validation failure, because the core may legitimately need values that the sample context doesn't have (e.g. dependencies).
+ */
+func checkStartCommandGeneration(core ServiceInitializerCore) (err error) {
+	fileKeys := []string{}
+	for fileKey, _ := range core.GetFilesToMount() {
+		fileKeys = append(fileKeys, fileKey)
+	}
+	sort.Strings(fileKeys)
+	mountedFilepaths := make(map[string]string)
+	for i, fileKey := range fileKeys {
+		mountedFilepaths[fileKey] = filepath.Join(validationSampleFilesDirpath, fmt.Sprintf("file-%v", i))
+	}
+	sampleContext := StartCommandContext{
+		IpAddr:                     validationSampleIpAddr,
+		Ports:                      getNamedPortNumbers(core),
+		MountedFilepaths:           mountedFilepaths,
+		DependencyIpAddrs:          map[string]string{},
+		Dependencies:               []Service{},
+		SharedArtifactStoreDirpath: SHARED_ARTIFACT_STORE_DIRPATH,
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = stacktrace.NewError("Generating the start command panicked: %v", recovered)
+		}
+	}()
+	core.GetStartCommand(sampleContext)
+	return nil
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"github.com/docker/go-connections/nat"
+	"gotest.tools/v3/assert"
+	"os"
+	"testing"
+)
+
+type validationTestCore struct {
+	usedPorts map[nat.Port]bool
+	mountpoint string
+	panicOnStartCommand bool
+}
+func (core validationTestCore) GetUsedPorts() map[nat.Port]bool {
+	return core.usedPorts
+}
+func (core validationTestCore) GetServiceFromIp(ipAddr string) Service {
+	return nil
+}
+func (core validationTestCore) GetFilesToMount() map[string]bool {
+	return map[string]bool{"config": true}
+}
+func (core validationTestCore) InitializeMountedFiles(mountedFiles map[string]*os.File, dependencies []Service) error {
+	return nil
+}
+func (core validationTestCore) GetTestVolumeMountpoint() string {
+	return core.mountpoint
+}
+func (core validationTestCore) GetStartCommand(startCommandContext StartCommandContext) ([]string, error) {
+	if core.panicOnStartCommand {
+		panic("no bootstrap node")
+	}
+	return []string{"--config=" + startCommandContext.MountedFilepaths["config"]}, nil
+}
+
+func TestValidInitializerCore(t *testing.T) {
+	core := validationTestCore{
+		usedPorts:  map[nat.Port]bool{"8080/tcp": true, "8080/udp": true},
+		mountpoint: "/data",
+	}
+	assert.NilError(t, ValidateInitializerCore(core))
+}
+
+func TestInvalidInitializerCores(t *testing.T) {
+	validPorts := map[nat.Port]bool{"8080/tcp": true}
+	assert.ErrorContains(t, ValidateInitializerCore(nil), "nil")
+	assert.ErrorContains(
+		t,
+		ValidateInitializerCore(validationTestCore{usedPorts: map[nat.Port]bool{"70000/tcp": true}, mountpoint: "/data"}),
+		"range")
+	assert.ErrorContains(
+		t,
+		ValidateInitializerCore(validationTestCore{usedPorts: map[nat.Port]bool{"8080": true, "8080/tcp": true}, mountpoint: "/data"}),
+		"more than once")
+	assert.ErrorContains(t, ValidateInitializerCore(validationTestCore{usedPorts: validPorts, mountpoint: "data"}), "absolute")
+	assert.ErrorContains(
+		t,
+		ValidateInitializerCore(validationTestCore{usedPorts: validPorts, mountpoint: SHARED_VOLUME_MOUNTPOINT}),
+		"reserved")
+	assert.ErrorContains(
+		t,
+		ValidateInitializerCore(validationTestCore{usedPorts: validPorts, mountpoint: "/data", panicOnStartCommand: true}),
+		"panicked")
+}
@@ -0,0 +1,246 @@
+package testcontainers
+
+import (
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/ports"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/palantir/stacktrace"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// How long a container has to become ready if its request doesn't say, which is testcontainers-go's default
+	DEFAULT_STARTUP_TIMEOUT = 60 * time.Second
+
+	// Where containers get the test volume mounted, which testcontainers-go modules don't know about
+	testVolumeMountpoint = "/kurtosis-test-volume"
+)
+
+/*
+The definition of a container, with the same fields (& meanings) as testcontainers-go's ContainerRequest, so that the
+	requests built by testcontainers-go modules (e.g. a module's Postgres or Kafka request, with its image, env, command, &
+	wait strategy) can be dropped into Kurtosis topologies by copying their fields over. Only the fields that make sense for
+	a service in a Kurtosis test network are supported; e.g. there are no host port bindings, as services are reached at
+	their IPs on the test network.
+ */
+type ContainerRequest struct {
+	Image string
+
+	// The platform ("os/arch[/variant]", e.g. "linux/arm64") whose variant of a multi-arch image the container runs, or
+	//  empty for the Docker engine's own platform
+	ImagePlatform string
+
+	// Overrides the image's ENTRYPOINT if non-empty
+	Entrypoint []string
+
+	// Overrides the image's CMD if non-empty
+	Cmd []string
+
+	Env map[string]string
+
+	// The ports the container listens on, in Docker's "port[/protocol]" form (e.g. "5432/tcp" or "6379")
+	ExposedPorts []string
+
+	Labels map[string]string
+
+	// Mapping of container dirpath -> tmpfs mount options (empty for Docker's defaults)
+	Tmpfs map[string]string
+
+	// How to tell that the container is ready (see ForListeningPort, ForHTTP, ForExec, & ForAll); if nil, the container is ready
+	//  once all its exposed TCP ports accept connections
+	WaitingFor WaitStrategy
+
+	// How long the container has to become ready (DEFAULT_STARTUP_TIMEOUT if zero), which testcontainers-go sets on the
+	//  wait strategy instead
+	StartupTimeout time.Duration
+}
+
+/*
+The service that the network returns for containers started from a ContainerRequest (see ServiceNetwork.GetService).
+ */
+type ContainerNode struct {
+	IpAddr string
+
+	// The "set" of the container's exposed ports
+	exposedPorts map[nat.Port]bool
+}
+
+/*
+Gets the "IP:port" address that the given exposed port of the container can be reached at from the test network, the
+	equivalent of testcontainers-go's PortEndpoint.
+
+Args:
+	port: The exposed port, in the same form as in ContainerRequest.ExposedPorts
+ */
+func (node ContainerNode) GetPortEndpoint(port string) (string, error) {
+	normalizedPort := ports.Normalize(nat.Port(port))
+	for exposedPort, _ := range node.exposedPorts {
+		if ports.Normalize(exposedPort) == normalizedPort {
+			return net.JoinHostPort(node.IpAddr, strconv.Itoa(exposedPort.Int())), nil
+		}
+	}
+	return "", stacktrace.NewError("Port %v isn't one of the container's exposed ports", port)
+}
+
+/*
+Wraps the given container request as a service configuration, to register with ServiceNetworkBuilder.AddServiceConfig.
+
+Args:
+	request: The definition of the containers launched with the configuration
+ */
+func NewServiceConfig(request ContainerRequest) (networks.ServiceConfig, error) {
+	if request.Image == "" {
+		return nil, stacktrace.NewError("The container request has no image")
+	}
+	if request.ImagePlatform != "" {
+		if err := docker.ValidatePlatform(request.ImagePlatform); err != nil {
+			return nil, stacktrace.Propagate(err, "The container request's image platform is invalid")
+		}
+	}
+	exposedPorts := make(map[nat.Port]bool)
+	for _, portSpec := range request.ExposedPorts {
+		if strings.Contains(portSpec, ":") {
+			return nil, stacktrace.NewError("Exposed port '%v' binds a host port, which isn't supported; containers are reached at their IPs on the test network", portSpec)
+		}
+		exposedPorts[nat.Port(portSpec)] = true
+	}
+	if err := ports.ValidateUsedPorts(exposedPorts); err != nil {
+		return nil, stacktrace.Propagate(err, "The container request's exposed ports are invalid")
+	}
+
+	waitStrategy := request.WaitingFor
+	if waitStrategy == nil {
+		tcpPortStrategies := []WaitStrategy{}
+		for port, _ := range exposedPorts {
+			if port.Proto() == tcpProtocol {
+				tcpPortStrategies = append(tcpPortStrategies, ForListeningPort(string(port)))
+			}
+		}
+		waitStrategy = ForAll(tcpPortStrategies...)
+	}
+	startupTimeout := request.StartupTimeout
+	if startupTimeout == 0 {
+		startupTimeout = DEFAULT_STARTUP_TIMEOUT
+	}
+
+	return containerRequestConfig{
+		dockerImage: request.Image,
+		initializerCore: containerRequestInitializerCore{
+			exposedPorts: exposedPorts,
+			cmd:          request.Cmd,
+			env:          request.Env,
+		},
+		availabilityCheckerCore: containerRequestAvailabilityCheckerCore{
+			waitStrategy:   waitStrategy,
+			startupTimeout: startupTimeout,
+		},
+		containerOptions: docker.ContainerOptions{
+			Entrypoint:  request.Entrypoint,
+			Labels:      request.Labels,
+			TmpfsMounts: request.Tmpfs,
+			Platform:    request.ImagePlatform,
+		},
+	}, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+type containerRequestConfig struct {
+	dockerImage string
+
+	initializerCore containerRequestInitializerCore
+
+	availabilityCheckerCore containerRequestAvailabilityCheckerCore
+
+	containerOptions docker.ContainerOptions
+}
+
+func (config containerRequestConfig) GetDockerImage() string {
+	return config.dockerImage
+}
+
+func (config containerRequestConfig) GetInitializerCore() services.ServiceInitializerCore {
+	return config.initializerCore
+}
+
+func (config containerRequestConfig) GetAvailabilityCheckerCore() services.ServiceAvailabilityCheckerCore {
+	return config.availabilityCheckerCore
+}
+
+func (config containerRequestConfig) GetContainerOptions() docker.ContainerOptions {
+	return config.containerOptions.Copy()
+}
+
+type containerRequestInitializerCore struct {
+	exposedPorts map[nat.Port]bool
+
+	cmd []string
+
+	env map[string]string
+}
+
+func (core containerRequestInitializerCore) GetUsedPorts() map[nat.Port]bool {
+	return core.exposedPorts
+}
+
+func (core containerRequestInitializerCore) GetServiceFromIp(ipAddr string) services.Service {
+	return ContainerNode{
+		IpAddr:       ipAddr,
+		exposedPorts: core.exposedPorts,
+	}
+}
+
+func (core containerRequestInitializerCore) GetFilesToMount() map[string]bool {
+	return map[string]bool{}
+}
+
+func (core containerRequestInitializerCore) InitializeMountedFiles(mountedFiles map[string]*os.File, dependencies []services.Service) error {
+	return nil
+}
+
+func (core containerRequestInitializerCore) GetTestVolumeMountpoint() string {
+	return testVolumeMountpoint
+}
+
+func (core containerRequestInitializerCore) GetStartCommand(startCommandContext services.StartCommandContext) ([]string, error) {
+	result := make([]string, 0, len(core.cmd))
+	for _, fragment := range core.cmd {
+		// Start command fragments are rendered as templates, but testcontainers-go commands aren't templates so must come out as-is
+		if strings.Contains(fragment, "{{") {
+			fragment = "{{ " + strconv.Quote(fragment) + " }}"
+		}
+		result = append(result, fragment)
+	}
+	return result, nil
+}
+
+func (core containerRequestInitializerCore) GetEnvVariables() map[string]string {
+	result := make(map[string]string)
+	for name, value := range core.env {
+		// Env variable values are rendered as templates too, but testcontainers-go env variables aren't templates so must come out as-is
+		if strings.Contains(value, "{{") {
+			value = "{{ " + strconv.Quote(value) + " }}"
+		}
+		result[name] = value
+	}
+	return result
+}
+
+type containerRequestAvailabilityCheckerCore struct {
+	waitStrategy WaitStrategy
+
+	startupTimeout time.Duration
+}
+
+func (core containerRequestAvailabilityCheckerCore) IsServiceUp(toCheck services.Service, dependencies []services.Service) bool {
+	return core.waitStrategy.IsReady(toCheck.(ContainerNode))
+}
+
+func (core containerRequestAvailabilityCheckerCore) GetTimeout() time.Duration {
+	return core.startupTimeout
+}
@@ -0,0 +1,127 @@
+package testcontainers
+
+import (
+	"context"
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestAddingContainerRequestToNetwork(t *testing.T) {
+	testVolumeControllerDirpath, err := ioutil.TempDir("", "test-volume")
+	assert.NilError(t, err)
+	defer os.RemoveAll(testVolumeControllerDirpath)
+
+	config, err := NewServiceConfig(ContainerRequest{
+		Image:        "postgres:13",
+		Cmd:          []string{"postgres", "-c", "fsync=off", "{{ not a template }}"},
+		Env:          map[string]string{"POSTGRES_PASSWORD": "password"},
+		ExposedPorts: []string{"5432/tcp"},
+		Tmpfs:        map[string]string{"/var/lib/postgresql/data": ""},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, DEFAULT_STARTUP_TIMEOUT, config.GetAvailabilityCheckerCore().GetTimeout())
+
+	dockerManager := docker.NewFakeDockerManager()
+	log := logrus.NewEntry(logrus.StandardLogger())
+	freeIpTracker, err := networks.NewFreeIpAddrTracker(log.Logger, "172.23.0.0/24", map[string]bool{})
+	assert.NilError(t, err)
+	builder := networks.NewServiceNetworkBuilder(log, dockerManager, "test-network", freeIpTracker, "test", testVolumeControllerDirpath, "")
+	assert.NilError(t, builder.AddServiceConfig("postgres", config))
+	network := builder.Build()
+	_, err = network.AddService("postgres", "db", map[networks.ServiceID]bool{})
+	assert.NilError(t, err)
+
+	node, err := network.GetService("db")
+	assert.NilError(t, err)
+	container, found := dockerManager.GetContainer(node.ContainerId)
+	assert.Assert(t, found)
+	assert.Equal(t, "postgres:13", container.Image)
+	assert.DeepEqual(t, []string{"postgres", "-c", "fsync=off", "{{ not a template }}"}, container.StartCmdArgs)
+	assert.Equal(t, "password", container.EnvVariables["POSTGRES_PASSWORD"])
+	_, found = container.Options.TmpfsMounts["/var/lib/postgresql/data"]
+	assert.Assert(t, found)
+
+	endpoint, err := node.Service.(ContainerNode).GetPortEndpoint("5432")
+	assert.NilError(t, err)
+	assert.Equal(t, net.JoinHostPort(node.IpAddr.String(), "5432"), endpoint)
+}
+
+func TestRejectingInvalidContainerRequests(t *testing.T) {
+	_, err := NewServiceConfig(ContainerRequest{ExposedPorts: []string{"5432/tcp"}})
+	assert.ErrorContains(t, err, "no image")
+	_, err = NewServiceConfig(ContainerRequest{Image: "postgres:13", ExposedPorts: []string{"15432:5432/tcp"}})
+	assert.ErrorContains(t, err, "binds a host port")
+	_, err = NewServiceConfig(ContainerRequest{Image: "postgres:13", ExposedPorts: []string{"5432/icmp"}})
+	assert.ErrorContains(t, err, "exposed ports are invalid")
+}
+
+func TestWaitStrategies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/health" {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NilError(t, err)
+	node := ContainerNode{IpAddr: host, exposedPorts: map[nat.Port]bool{nat.Port(port + "/tcp"): true}}
+
+	assert.Assert(t, ForListeningPort(port).IsReady(node))
+	assert.Assert(t, ForHTTP(port, "/health").IsReady(node))
+	assert.Assert(t, !ForHTTP(port, "/other").IsReady(node))
+	assert.Assert(t, !ForAll(ForListeningPort(port), ForHTTP(port, "/other")).IsReady(node))
+	// Ports that the container doesn't expose are never ready
+	assert.Assert(t, !ForListeningPort("1").IsReady(node))
+}
+
+func TestExecWaitStrategy(t *testing.T) {
+	fake := docker.NewFakeDockerManager()
+	parentCtx := context.Background()
+	_, err := fake.CreateAndStartContainer(parentCtx, "redis:6", "test-network", net.ParseIP("172.23.0.2"), nil, nil, nil, nil, nil, docker.ContainerOptions{})
+	assert.NilError(t, err)
+	readyContainerId, err := fake.CreateAndStartContainer(parentCtx, "redis:6", "test-network", net.ParseIP("172.23.0.3"), nil, nil, nil, nil, nil, docker.ContainerOptions{})
+	assert.NilError(t, err)
+	executor := &readyContainerExecutor{FakeDockerManager: fake, readyContainerId: readyContainerId}
+
+	strategy := ForExec(executor, "test-network", "redis-cli", "ping")
+	assert.Assert(t, strategy.IsReady(ContainerNode{IpAddr: "172.23.0.3"}))
+	assert.DeepEqual(t, []string{"redis-cli", "ping"}, executor.lastCmd)
+	assert.Assert(t, !strategy.IsReady(ContainerNode{IpAddr: "172.23.0.2"}))
+	// Containers that aren't on the network are never ready
+	assert.Assert(t, !strategy.IsReady(ContainerNode{IpAddr: "172.23.0.4"}))
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Executor whose commands only succeed in one container
+type readyContainerExecutor struct {
+	*docker.FakeDockerManager
+
+	readyContainerId string
+
+	lastCmd []string
+}
+
+func (executor *readyContainerExecutor) ExecInteractive(
+			context context.Context,
+			containerId string,
+			cmd []string,
+			terminal *docker.ExecTerminal,
+			stdin io.Reader,
+			stdout io.Writer,
+			stderr io.Writer) (exitCode int, err error) {
+	executor.lastCmd = cmd
+	if containerId != executor.readyContainerId {
+		return 1, nil
+	}
+	return 0, nil
+}
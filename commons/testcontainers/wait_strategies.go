@@ -0,0 +1,180 @@
+package testcontainers
+
+import (
+	"context"
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	tcpProtocol = "tcp"
+
+	// How long each check of a wait strategy waits for a connection or response
+	checkTimeout = 1 * time.Second
+
+	// How long each check of an exec strategy's command can run for, which is longer than checkTimeout as readiness
+	//  commands (e.g. pg_isready) often make a connection of their own
+	execCheckTimeout = 10 * time.Second
+)
+
+// Compile-time check that the Docker manager can run exec strategies
+var _ ContainerExecutor = &docker.DockerManager{}
+
+/*
+Tells whether a container is ready, like testcontainers-go's wait strategies. The container is checked repeatedly until
+	the strategy reports it ready or the request's startup timeout passes, so strategies only check once.
+ */
+type WaitStrategy interface {
+	IsReady(node ContainerNode) bool
+}
+
+/*
+Gets a strategy that waits for the given exposed port to accept TCP connections, like testcontainers-go's
+	wait.ForListeningPort.
+
+Args:
+	port: The exposed port, in the same form as in ContainerRequest.ExposedPorts
+ */
+func ForListeningPort(port string) WaitStrategy {
+	return listeningPortStrategy{port: port}
+}
+
+/*
+Gets a strategy that waits for an HTTP GET of the given path on the given exposed port to get a 2xx response, like
+	testcontainers-go's wait.ForHTTP.
+
+Args:
+	port: The exposed port, in the same form as in ContainerRequest.ExposedPorts
+	path: The path to GET (e.g. "/health")
+ */
+func ForHTTP(port string, path string) WaitStrategy {
+	return httpStrategy{port: port, path: path}
+}
+
+/*
+Runs commands in containers for ForExec, as implemented by docker.DockerManager.
+ */
+type ContainerExecutor interface {
+	GetContainersOnNetwork(context context.Context, networkId string) (map[string]bool, error)
+	GetContainerIpAddr(context context.Context, containerId string, networkId string) (net.IP, error)
+	ExecInteractive(
+			context context.Context,
+			containerId string,
+			cmd []string,
+			terminal *docker.ExecTerminal,
+			stdin io.Reader,
+			stdout io.Writer,
+			stderr io.Writer) (exitCode int, err error)
+}
+
+/*
+Gets a strategy that waits for the given command to exit with code 0 when run inside the container, like
+	testcontainers-go's wait.ForExec, for services whose readiness is best checked with their own tooling (e.g.
+	pg_isready or redis-cli ping).
+
+Args:
+	executor: What runs the command, e.g. the DockerManager that the network uses
+	networkId: The ID of the test network that the container is on (the one passed to networks.NewServiceNetworkBuilder),
+		which its container is found on by its IP
+	cmd: The command & its args (e.g. ["pg_isready", "-U", "postgres"])
+ */
+func ForExec(executor ContainerExecutor, networkId string, cmd ...string) WaitStrategy {
+	return execStrategy{executor: executor, networkId: networkId, cmd: cmd}
+}
+
+/*
+Gets a strategy that waits for all of the given strategies, like testcontainers-go's wait.ForAll.
+ */
+func ForAll(strategies ...WaitStrategy) WaitStrategy {
+	return allStrategy{strategies: strategies}
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+type listeningPortStrategy struct {
+	port string
+}
+
+func (strategy listeningPortStrategy) IsReady(node ContainerNode) bool {
+	endpoint, err := node.GetPortEndpoint(strategy.port)
+	if err != nil {
+		return false
+	}
+	conn, err := net.DialTimeout(tcpProtocol, endpoint, checkTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+type httpStrategy struct {
+	port string
+
+	path string
+}
+
+func (strategy httpStrategy) IsReady(node ContainerNode) bool {
+	endpoint, err := node.GetPortEndpoint(strategy.port)
+	if err != nil {
+		return false
+	}
+	client := &http.Client{Timeout: checkTimeout}
+	resp, err := client.Get(fmt.Sprintf("http://%v%v", endpoint, strategy.path))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices
+}
+
+type execStrategy struct {
+	executor ContainerExecutor
+
+	networkId string
+
+	cmd []string
+}
+
+func (strategy execStrategy) IsReady(node ContainerNode) bool {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), execCheckTimeout)
+	defer cancelFunc()
+	containerId, found := strategy.findContainerId(ctx, node.IpAddr)
+	if !found {
+		return false
+	}
+	exitCode, err := strategy.executor.ExecInteractive(ctx, containerId, strategy.cmd, nil, nil, ioutil.Discard, ioutil.Discard)
+	return err == nil && exitCode == 0
+}
+
+// Finds the container on the strategy's network that has the given IP
+func (strategy execStrategy) findContainerId(ctx context.Context, ipAddr string) (string, bool) {
+	containerIds, err := strategy.executor.GetContainersOnNetwork(ctx, strategy.networkId)
+	if err != nil {
+		return "", false
+	}
+	for containerId, _ := range containerIds {
+		containerIp, err := strategy.executor.GetContainerIpAddr(ctx, containerId, strategy.networkId)
+		if err == nil && containerIp.String() == ipAddr {
+			return containerId, true
+		}
+	}
+	return "", false
+}
+
+type allStrategy struct {
+	strategies []WaitStrategy
+}
+
+func (strategy allStrategy) IsReady(node ContainerNode) bool {
+	for _, childStrategy := range strategy.strategies {
+		if !childStrategy.IsReady(node) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,9 +1,42 @@
 package testsuite
 
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/assertions"
+	"github.com/kurtosis-tech/kurtosis/commons/metrics"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"regexp"
+	"time"
+)
+
 /*
 An object that will be passed in to every test, which the user can use to manipulate the results of the test
  */
-type TestContext struct {}
+type TestContext struct {
+	// The log entry that the test should log to, tagged with fields identifying the test
+	log *logrus.Entry
+}
+
+/*
+Creates a new test context whose logger is the given log entry.
+ */
+func NewTestContext(log *logrus.Entry) TestContext {
+	return TestContext{
+		log: log,
+	}
+}
+
+/*
+Gets the logger that the test should write its log messages to, which tags each line with the test's identifying fields
+	so it can be attributed to the test. Falls back to the system-level logger if the context wasn't given one.
+ */
+func (context TestContext) Log() *logrus.Entry {
+	if context.log == nil {
+		return logrus.NewEntry(logrus.StandardLogger())
+	}
+	return context.log
+}
 
 /*
 Fails the test with the given error
@@ -22,6 +55,106 @@ func (context TestContext) AssertTrue(condition bool, err error) {
 	}
 }
 
+/*
+Asserts that every service in the network that hasn't been removed is available & not partitioned, failing the test with
+	the network's availability diagnostics if not
+ */
+func (context TestContext) AssertAllHealthy(network *networks.ServiceNetwork) {
+	if err := assertions.AssertAllHealthy(network); err != nil {
+		failTest(err)
+	}
+}
+
+/*
+Asserts that the network has exactly the given number of services, failing the test if it doesn't
+ */
+func (context TestContext) AssertServiceCount(network *networks.ServiceNetwork, expected int) {
+	if err := assertions.AssertServiceCount(network, expected); err != nil {
+		failTest(err)
+	}
+}
+
+/*
+Asserts that the two given services can reach each other over the test network, failing the test if they can't
+ */
+func (context TestContext) AssertReachable(network *networks.ServiceNetwork, serviceIdA networks.ServiceID, serviceIdB networks.ServiceID) {
+	if err := assertions.AssertReachable(network, serviceIdA, serviceIdB); err != nil {
+		failTest(err)
+	}
+}
+
+/*
+Asserts that the response to the given RPC against the given service satisfies the matcher within the timeout, failing
+	the test if it doesn't (see assertions.EventuallyRPC)
+ */
+func (context TestContext) AssertEventuallyRPC(
+			network *networks.ServiceNetwork,
+			serviceId networks.ServiceID,
+			rpc networks.ServiceProbe,
+			matcher assertions.ResponseMatcher,
+			timeout time.Duration) {
+	if err := assertions.EventuallyRPC(network, serviceId, rpc, matcher, timeout); err != nil {
+		failTest(stacktrace.Propagate(err, "RPC assertion failed"))
+	}
+}
+
+/*
+Asserts that the two given services can't reach each other over the test network (e.g. because one of them has been
+	partitioned off), failing the test if they can
+ */
+func (context TestContext) AssertUnreachable(network *networks.ServiceNetwork, serviceIdA networks.ServiceID, serviceIdB networks.ServiceID) {
+	reachable, err := network.IsReachable(serviceIdA, serviceIdB)
+	if err != nil {
+		failTest(stacktrace.Propagate(err, "An error occurred checking reachability between services %v and %v", serviceIdA, serviceIdB))
+	}
+	if reachable {
+		failTest(stacktrace.NewError("Expected services %v and %v to be unreachable from each other, but they were reachable", serviceIdA, serviceIdB))
+	}
+}
+
+/*
+Asserts that the given services all converge on the same state (as extracted by the given probe) within the timeout,
+	failing the test if they don't
+ */
+func (context TestContext) AssertConverged(network *networks.ServiceNetwork, serviceIds map[networks.ServiceID]bool, probe networks.ServiceProbe, timeout time.Duration) {
+	if err := network.WaitForConvergence(serviceIds, probe, timeout); err != nil {
+		failTest(stacktrace.Propagate(err, "Services failed to converge"))
+	}
+}
+
+/*
+Asserts that the Prometheus metric with the given name and labels, exposed by a service at the given endpoint URL, satisfies
+	the matcher within the timeout (e.g. "node reports at least 4 peers"), failing the test if it doesn't
+ */
+func (context TestContext) AssertMetric(endpointUrl string, name string, labels map[string]string, matcher metrics.ValueMatcher, timeout time.Duration) {
+	if err := metrics.WaitForMetric(endpointUrl, name, labels, matcher, timeout); err != nil {
+		failTest(stacktrace.Propagate(err, "Metric assertion failed"))
+	}
+}
+
+/*
+Asserts that the given resource watchdog hasn't seen any service exceed its resource thresholds, failing the test if it has
+ */
+func (context TestContext) AssertNoResourceViolations(watchdog *networks.ResourceWatchdog) {
+	violations := watchdog.GetViolations()
+	if len(violations) > 0 {
+		failTest(stacktrace.NewError(
+			"Expected no resource threshold violations, but found %v; first violation: %v",
+			len(violations),
+			violations[0]))
+	}
+}
+
+/*
+Asserts that the service with the given ID logs a line matching the given regex within the timeout (lines logged before
+	this was called count too), failing the test if it doesn't
+ */
+func (context TestContext) AssertLogContains(network *networks.ServiceNetwork, serviceId networks.ServiceID, regex *regexp.Regexp, timeout time.Duration) {
+	if _, err := network.WaitForLogMatch(serviceId, regex, timeout); err != nil {
+		failTest(stacktrace.Propagate(err, "Log assertion failed"))
+	}
+}
+
 func failTest(err error) {
 	panic(err)
 }
@@ -0,0 +1,163 @@
+package testsuite
+
+import (
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/palantir/stacktrace"
+	"sort"
+	"strings"
+)
+
+const (
+	// Separates the base name of a test expanded with ExpandTests from its version assignment
+	VERSIONED_TEST_NAME_SEPARATOR = "@"
+
+	versionAssignmentSeparator = "+"
+)
+
+/*
+The image versions that a mixed-version network runs, as a mapping of configuration ID -> the tag of the image that the
+	configuration's services run (e.g. {"old-node": "v1.0.0", "new-node": "v1.1.0"}), for upgrade-compatibility testing.
+ */
+type VersionAssignment map[networks.ConfigurationID]string
+
+/*
+Makes the configurations of the given builder run the assigned image versions, by replacing the tags of their images;
+	meant to be called from a network loader's ConfigureNetwork after the configurations are added.
+ */
+func (assignment VersionAssignment) ApplyTo(builder *networks.ServiceNetworkBuilder) error {
+	for configurationId, tag := range assignment {
+		config, err := builder.GetConfiguration(configurationId)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting configuration ID %v to assign version '%v' to", configurationId, tag)
+		}
+		versionedImage, err := docker.WithImageTag(config.GetDockerImage(), tag)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred assigning version '%v' to configuration ID %v", tag, configurationId)
+		}
+		if err := builder.OverrideConfiguration(configurationId, networks.ConfigurationOverride{DockerImage: versionedImage}); err != nil {
+			return stacktrace.Propagate(err, "An error occurred assigning version '%v' to configuration ID %v", tag, configurationId)
+		}
+	}
+	return nil
+}
+
+/*
+Gets the assignment as "<configuration ID>=<version>" pairs sorted by configuration ID & joined by "+", e.g.
+	"new-node=v1.1.0+old-node=v1.0.0".
+ */
+func (assignment VersionAssignment) String() string {
+	pairs := make([]string, 0, len(assignment))
+	for configurationId, tag := range assignment {
+		pairs = append(pairs, string(configurationId) + "=" + tag)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, versionAssignmentSeparator)
+}
+
+/*
+A declaration of the image versions that each configuration of a network can run, which is expanded into every
+	combination of versions (see GetAssignments).
+ */
+type VersionMatrix struct {
+	// The configurations in the order they were added, so that assignments come out in a predictable order
+	configurationIds []networks.ConfigurationID
+
+	// Mapping of configuration ID -> versions the configuration can run
+	versions map[networks.ConfigurationID][]string
+}
+
+/*
+Creates an empty version matrix.
+ */
+func NewVersionMatrix() *VersionMatrix {
+	return &VersionMatrix{
+		configurationIds: []networks.ConfigurationID{},
+		versions:         map[networks.ConfigurationID][]string{},
+	}
+}
+
+/*
+Declares the versions that the given configuration can run.
+
+Args:
+	configurationId: The ID of the configuration
+	versions: The image tags that the configuration can run, of which there must be at least one
+ */
+func (matrix *VersionMatrix) AddConfiguration(configurationId networks.ConfigurationID, versions ...string) error {
+	if _, found := matrix.versions[configurationId]; found {
+		return stacktrace.NewError("Configuration ID %v is already in the version matrix", configurationId)
+	}
+	if len(versions) == 0 {
+		return stacktrace.NewError("Configuration ID %v must have at least one version", configurationId)
+	}
+	matrix.configurationIds = append(matrix.configurationIds, configurationId)
+	matrix.versions[configurationId] = append([]string{}, versions...)
+	return nil
+}
+
+/*
+Expands the matrix into every combination of the configurations' versions, with the versions of the configuration added
+	last varying fastest.
+ */
+func (matrix *VersionMatrix) GetAssignments() []VersionAssignment {
+	result := []VersionAssignment{{}}
+	for _, configurationId := range matrix.configurationIds {
+		expanded := make([]VersionAssignment, 0, len(result) * len(matrix.versions[configurationId]))
+		for _, partialAssignment := range result {
+			for _, version := range matrix.versions[configurationId] {
+				assignment := VersionAssignment{configurationId: version}
+				for otherConfigurationId, otherVersion := range partialAssignment {
+					assignment[otherConfigurationId] = otherVersion
+				}
+				expanded = append(expanded, assignment)
+			}
+		}
+		result = expanded
+	}
+	return result
+}
+
+/*
+Gets the assignments that pair each version with the version after it (i.e. N-1 & N), for testing that each release is
+	compatible with the one before it.
+
+Args:
+	oldConfigurationId: The configuration that runs the older version of each pair
+	newConfigurationId: The configuration that runs the newer version of each pair
+	versions: The versions, from oldest to newest
+ */
+func GetAdjacentVersionPairs(oldConfigurationId networks.ConfigurationID, newConfigurationId networks.ConfigurationID, versions []string) []VersionAssignment {
+	result := []VersionAssignment{}
+	for i := 1; i < len(versions); i++ {
+		result = append(result, VersionAssignment{
+			oldConfigurationId: versions[i - 1],
+			newConfigurationId: versions[i],
+		})
+	}
+	return result
+}
+
+/*
+Creates a test per version assignment, for registering in a TestSuite's GetTests. Each test is named
+	"<base name>@<assignment>" (see VersionAssignment.String), e.g. "upgradeTest@new-node=v1.1.0+old-node=v1.0.0".
+
+Args:
+	baseName: The name that the tests' names start with
+	assignments: The version assignments to create a test for
+	testFactory: Creates the test for an assignment, whose network loader should apply the assignment with ApplyTo
+
+Returns:
+	Mapping of test name -> test
+ */
+func ExpandTests(baseName string, assignments []VersionAssignment, testFactory func(assignment VersionAssignment) Test) (map[string]Test, error) {
+	result := make(map[string]Test)
+	for _, assignment := range assignments {
+		testName := baseName + VERSIONED_TEST_NAME_SEPARATOR + assignment.String()
+		if _, found := result[testName]; found {
+			return nil, stacktrace.NewError("Version assignment %v is given more than once", assignment.String())
+		}
+		result[testName] = testFactory(assignment)
+	}
+	return result, nil
+}
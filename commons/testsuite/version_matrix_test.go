@@ -0,0 +1,109 @@
+package testsuite
+
+import (
+	"github.com/docker/go-connections/nat"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/commons/services"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+type versionedTest struct {
+	Test
+
+	assignment VersionAssignment
+}
+
+type testInitializerCore struct{}
+
+func (core testInitializerCore) GetUsedPorts() map[nat.Port]bool {
+	return map[nat.Port]bool{}
+}
+
+func (core testInitializerCore) GetServiceFromIp(ipAddr string) services.Service {
+	return nil
+}
+
+func (core testInitializerCore) GetFilesToMount() map[string]bool {
+	return map[string]bool{}
+}
+
+func (core testInitializerCore) InitializeMountedFiles(mountedFiles map[string]*os.File, dependencies []services.Service) error {
+	return nil
+}
+
+func (core testInitializerCore) GetTestVolumeMountpoint() string {
+	return "/foo/bar"
+}
+
+func (core testInitializerCore) GetStartCommand(startCommandContext services.StartCommandContext) ([]string, error) {
+	return []string{}, nil
+}
+
+type testAvailabilityCheckerCore struct{}
+
+func (core testAvailabilityCheckerCore) IsServiceUp(toCheck services.Service, dependencies []services.Service) bool {
+	return true
+}
+
+func (core testAvailabilityCheckerCore) GetTimeout() time.Duration {
+	return time.Second
+}
+
+func TestVersionMatrix(t *testing.T) {
+	matrix := NewVersionMatrix()
+	assert.NilError(t, matrix.AddConfiguration("old-node", "v1", "v2"))
+	assert.NilError(t, matrix.AddConfiguration("new-node", "v2", "v3"))
+	assert.ErrorContains(t, matrix.AddConfiguration("new-node", "v4"), "already in the version matrix")
+	assert.ErrorContains(t, matrix.AddConfiguration("other-node"), "at least one version")
+
+	assert.DeepEqual(t, []VersionAssignment{
+		{"old-node": "v1", "new-node": "v2"},
+		{"old-node": "v1", "new-node": "v3"},
+		{"old-node": "v2", "new-node": "v2"},
+		{"old-node": "v2", "new-node": "v3"},
+	}, matrix.GetAssignments())
+
+	assert.DeepEqual(t, []VersionAssignment{
+		{"old-node": "v1", "new-node": "v2"},
+		{"old-node": "v2", "new-node": "v3"},
+	}, GetAdjacentVersionPairs("old-node", "new-node", []string{"v1", "v2", "v3"}))
+}
+
+func TestExpandTests(t *testing.T) {
+	assignments := GetAdjacentVersionPairs("old-node", "new-node", []string{"v1", "v2", "v3"})
+	tests, err := ExpandTests("upgradeTest", assignments, func(assignment VersionAssignment) Test {
+		return versionedTest{assignment: assignment}
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(tests))
+	assert.DeepEqual(
+		t,
+		VersionAssignment{"old-node": "v2", "new-node": "v3"},
+		tests["upgradeTest@new-node=v3+old-node=v2"].(versionedTest).assignment)
+
+	_, err = ExpandTests("upgradeTest", append(assignments, assignments[0]), func(assignment VersionAssignment) Test {
+		return versionedTest{assignment: assignment}
+	})
+	assert.ErrorContains(t, err, "more than once")
+}
+
+func TestApplyVersionAssignment(t *testing.T) {
+	builder := networks.NewServiceNetworkBuilder(logrus.NewEntry(logrus.StandardLogger()), nil, "test-network", nil, "test", "/foo/bar", "")
+	assert.NilError(t, builder.AddConfiguration("old-node", "myorg/node:dev", testInitializerCore{}, testAvailabilityCheckerCore{}))
+	assert.NilError(t, builder.AddConfiguration("new-node", "myorg/node:dev", testInitializerCore{}, testAvailabilityCheckerCore{}))
+
+	assert.NilError(t, VersionAssignment{"old-node": "v1", "new-node": "v2"}.ApplyTo(builder))
+	config, err := builder.GetConfiguration("old-node")
+	assert.NilError(t, err)
+	assert.Equal(t, "myorg/node:v1", config.GetDockerImage())
+	config, err = builder.GetConfiguration("new-node")
+	assert.NilError(t, err)
+	assert.Equal(t, "myorg/node:v2", config.GetDockerImage())
+
+	err = VersionAssignment{"missing-node": "v1"}.ApplyTo(builder)
+	assert.ErrorContains(t, err, "No configuration with ID missing-node")
+}
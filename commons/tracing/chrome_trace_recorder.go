@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// "Complete" events in the Chrome trace event format, which carry both a start time and a duration
+	chromeCompleteEventPhase = "X"
+
+	chromeTracePid = 1
+	chromeTraceTid = 1
+
+	errorAttributeKey = "error"
+)
+
+/*
+A Tracer that records finished spans in memory and can write them out in the Chrome trace event format, which can be
+	opened in chrome://tracing or https://ui.perfetto.dev to see exactly where the time went during a network boot.
+
+NOTE: This is thread-safe!
+ */
+type ChromeTraceRecorder struct {
+	mutex *sync.Mutex
+
+	// The spans that have been ended so far
+	finishedSpans []*recordedSpan
+}
+
+/*
+Creates a new recorder with no recorded spans.
+ */
+func NewChromeTraceRecorder() *ChromeTraceRecorder {
+	return &ChromeTraceRecorder{
+		mutex:         &sync.Mutex{},
+		finishedSpans: []*recordedSpan{},
+	}
+}
+
+func (recorder *ChromeTraceRecorder) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	span := &recordedSpan{
+		recorder:   recorder,
+		name:       spanName,
+		startTime:  time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+	return ctx, span
+}
+
+/*
+Writes all spans ended so far to the given writer as a Chrome trace event JSON document.
+ */
+func (recorder *ChromeTraceRecorder) WriteJson(writer io.Writer) error {
+	recorder.mutex.Lock()
+	events := make([]chromeTraceEvent, 0, len(recorder.finishedSpans))
+	for _, span := range recorder.finishedSpans {
+		events = append(events, chromeTraceEvent{
+			Name:      span.name,
+			Phase:     chromeCompleteEventPhase,
+			Timestamp: span.startTime.UnixNano() / int64(time.Microsecond),
+			Duration:  span.duration.Nanoseconds() / int64(time.Microsecond),
+			Pid:       chromeTracePid,
+			Tid:       chromeTraceTid,
+			Args:      span.attributes,
+		})
+	}
+	recorder.mutex.Unlock()
+
+	document := chromeTraceDocument{TraceEvents: events}
+	if err := json.NewEncoder(writer).Encode(document); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the Chrome trace JSON")
+	}
+	return nil
+}
+
+// =============================== Private helpers =========================================
+type chromeTraceDocument struct {
+	TraceEvents []chromeTraceEvent `json:"traceEvents"`
+}
+
+type chromeTraceEvent struct {
+	Name      string                 `json:"name"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur"`
+	Pid       int                    `json:"pid"`
+	Tid       int                    `json:"tid"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+type recordedSpan struct {
+	recorder *ChromeTraceRecorder
+
+	name      string
+	startTime time.Time
+	duration  time.Duration
+
+	// Only modified before the span is ended, and only read after
+	attributes map[string]interface{}
+}
+
+func (span *recordedSpan) SetAttribute(key string, value interface{}) {
+	span.attributes[key] = fmt.Sprintf("%v", value)
+}
+
+func (span *recordedSpan) RecordError(err error) {
+	if err != nil {
+		span.attributes[errorAttributeKey] = err.Error()
+	}
+}
+
+func (span *recordedSpan) End() {
+	span.duration = time.Since(span.startTime)
+	span.recorder.mutex.Lock()
+	defer span.recorder.mutex.Unlock()
+	span.recorder.finishedSpans = append(span.recorder.finishedSpans, span)
+}
@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestChromeTraceRecorderOutput(t *testing.T) {
+	recorder := NewChromeTraceRecorder()
+	SetTracer(recorder)
+	defer SetTracer(nil)
+
+	ctx, outerSpan := StartSpan(context.Background(), "outer")
+	_, innerSpan := StartSpan(ctx, "inner")
+	innerSpan.SetAttribute("image", "test-image")
+	innerSpan.RecordError(stacktrace.NewError("Test error"))
+	innerSpan.End()
+	outerSpan.End()
+
+	// Spans that haven't ended shouldn't be written
+	_, unendedSpan := StartSpan(context.Background(), "unended")
+	_ = unendedSpan
+
+	buffer := &bytes.Buffer{}
+	assert.NilError(t, recorder.WriteJson(buffer))
+
+	document := chromeTraceDocument{}
+	assert.NilError(t, json.Unmarshal(buffer.Bytes(), &document))
+	assert.Equal(t, 2, len(document.TraceEvents))
+	assert.Equal(t, "inner", document.TraceEvents[0].Name)
+	assert.Equal(t, "test-image", document.TraceEvents[0].Args["image"])
+	assert.Assert(t, document.TraceEvents[0].Args["error"] != nil)
+	assert.Equal(t, "outer", document.TraceEvents[1].Name)
+	assert.Assert(t, document.TraceEvents[1].Timestamp <= document.TraceEvents[0].Timestamp)
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+A single timed operation in a trace.
+ */
+type Span interface {
+	// Attaches a key-value attribute to the span (e.g. the image being pulled)
+	SetAttribute(key string, value interface{})
+
+	// Marks the span as having failed with the given error
+	RecordError(err error)
+
+	// Marks the end of the operation the span represents
+	End()
+}
+
+/*
+Creates spans for the orchestration steps that Kurtosis performs (adding services, creating containers, pulling images,
+	waiting for availability, etc.).
+
+This interface is intentionally shaped like the OpenTelemetry tracer, so that plugging Kurtosis into an OpenTelemetry
+	pipeline only requires a few lines of adapter code in the user's controller, e.g.:
+
+	type otelTracer struct { tracer trace.Tracer }
+	func (t otelTracer) Start(ctx context.Context, spanName string) (context.Context, tracing.Span) {
+		ctx, span := t.tracer.Start(ctx, spanName)
+		return ctx, otelSpan{span}
+	}
+
+For users who just want to look at a boot in a trace viewer without running any tracing infrastructure, see ChromeTraceRecorder.
+ */
+type Tracer interface {
+	/*
+	Starts a new span with the given name, as a child of any span in the given context.
+
+	Returns:
+		A context containing the new span, which should be passed to any nested operations
+		The new span, which the caller must End
+	 */
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+var (
+	globalTracerMutex = &sync.RWMutex{}
+
+	// Tracing is a no-op until the user sets a tracer
+	globalTracer Tracer = noopTracer{}
+)
+
+/*
+Sets the tracer that Kurtosis will create all its spans with.
+ */
+func SetTracer(tracer Tracer) {
+	globalTracerMutex.Lock()
+	defer globalTracerMutex.Unlock()
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+	globalTracer = tracer
+}
+
+/*
+Starts a span with the given name using the tracer set via SetTracer.
+ */
+func StartSpan(ctx context.Context, spanName string) (context.Context, Span) {
+	globalTracerMutex.RLock()
+	tracer := globalTracer
+	globalTracerMutex.RUnlock()
+	return tracer.Start(ctx, spanName)
+}
+
+// =============================== No-op implementation =========================================
+type noopTracer struct{}
+
+func (tracer noopTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (span noopSpan) SetAttribute(key string, value interface{}) {}
+func (span noopSpan) RecordError(err error) {}
+func (span noopSpan) End() {}
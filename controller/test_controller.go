@@ -1,19 +1,27 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"github.com/docker/docker/client"
 	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/logging"
 	"github.com/kurtosis-tech/kurtosis/commons/networks"
 	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+	"github.com/kurtosis-tech/kurtosis/commons/tracing"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
+	"os"
+	"path"
 	"time"
 )
 
 const (
 	// How long to wait before force-killing a container
 	CONTAINER_STOP_TIMEOUT = 30 * time.Second
+
+	// How long to give services instrumented for coverage to write out their coverage & exit at the end of a test
+	COVERAGE_FLUSH_TIMEOUT = 30 * time.Second
 )
 
 /*
@@ -46,6 +54,34 @@ type TestController struct {
 	// The name of the specific test this controller is responsible for running (since there's a 1:1 mapping between controller
 	// 	and test to execute
 	testName string
+
+	// The directory on the controller container where service artifacts will be exported at the end of the test (empty
+	//  if artifacts shouldn't be exported)
+	artifactsDirpath string
+
+	// How much information about each service will be exported at the end of the test
+	artifactVerbosity networks.ArtifactVerbosity
+
+	// The address (e.g. ":9091") that the health of the test network will be served on while the test runs (empty if
+	//  network health shouldn't be served)
+	healthListenAddr string
+
+	// The directory on the controller container, shared across tests, where service data snapshots are stored (empty if
+	//  snapshots aren't available)
+	snapshotsDirpath string
+
+	// The Docker host URLs of the other Docker Swarm nodes that services will be spread across (empty if every service
+	//  should run on the controller's host)
+	swarmDockerHosts []string
+
+	// The TLS settings for connecting to the Docker engines of the other Docker Swarm nodes (nil to use the environment's)
+	swarmDockerTlsOptions *docker.TlsOptions
+
+	// The UUID of the test suite execution that the test is part of
+	executionId string
+
+	// The name of the test suite that the test is part of
+	testSuiteName string
 }
 
 /*
@@ -65,6 +101,22 @@ Args:
 	testControllerIp: The IP address of the controller container itself
 	testSuite: A pre-defined set of tests that the user will choose to run a single test from
 	testName: The name of the test to run in the test suite
+	artifactsDirpath: The directory on the controller container that the initializer will have mounted for service artifacts
+		(logs, inspect JSON) to be exported to at the end of the test, or empty if artifacts shouldn't be exported
+	artifactVerbosity: How much information about each service should be exported at the end of the test
+	healthListenAddr: The address (e.g. ":9091") that the controller will serve the test network's health on as JSON,
+		at the /health path, while the test runs; leave empty to not serve network health
+	snapshotsDirpath: The directory on the controller container that the initializer will have mounted for storing service
+		data snapshots across tests, or empty if snapshots aren't available
+	swarmDockerHosts: The Docker host URLs of the other Docker Swarm nodes that services should be spread across (passed
+		by the initializer as a comma-separated list), or empty if every service should run on the controller's host
+	swarmDockerTlsOptions: The TLS material & verification settings for connecting to the Docker engines of the other
+		Docker Swarm nodes, whose files must be available on the controller container (e.g. baked into the controller
+		image); nil to use the DOCKER_CERT_PATH & DOCKER_TLS_VERIFY environment variables
+	executionId: The UUID of the test suite execution that the test is part of, which every container, network, & volume
+		the controller creates is labelled with (along with the test suite & test names) so that they can be cleaned up if
+		the run crashes (see docker.DockerManager.CleanUpOrphans)
+	testSuiteName: The name of the test suite that the test is part of
  */
 func NewTestController(
 			testVolumeName string,
@@ -74,16 +126,32 @@ func NewTestController(
 			gatewayIp string,
 			testControllerIp string,
 			testSuite testsuite.TestSuite,
-			testName string) *TestController {
+			testName string,
+			artifactsDirpath string,
+			artifactVerbosity networks.ArtifactVerbosity,
+			healthListenAddr string,
+			snapshotsDirpath string,
+			swarmDockerHosts []string,
+			swarmDockerTlsOptions *docker.TlsOptions,
+			executionId string,
+			testSuiteName string) *TestController {
 	return &TestController{
-		testVolumeName:     testVolumeName,
-		testVolumeFilepath: testVolumeFilepath,
-		networkId:          networkId,
-		subnetMask:         subnetMask,
-		gatewayIp:          gatewayIp,
-		testControllerIp:   testControllerIp,
-		testSuite:          testSuite,
-		testName:           testName,
+		testVolumeName:        testVolumeName,
+		testVolumeFilepath:    testVolumeFilepath,
+		networkId:             networkId,
+		subnetMask:            subnetMask,
+		gatewayIp:             gatewayIp,
+		testControllerIp:      testControllerIp,
+		testSuite:             testSuite,
+		testName:              testName,
+		artifactsDirpath:      artifactsDirpath,
+		artifactVerbosity:     artifactVerbosity,
+		healthListenAddr:      healthListenAddr,
+		snapshotsDirpath:      snapshotsDirpath,
+		swarmDockerHosts:      swarmDockerHosts,
+		swarmDockerTlsOptions: swarmDockerTlsOptions,
+		executionId:           executionId,
+		testSuiteName:         testSuiteName,
 	}
 }
 
@@ -95,6 +163,13 @@ Returns:
 	testErr: Indicates an error in the test itself, indicating a test failure
  */
 func (controller TestController) RunTest() (setupErr error, testErr error) {
+	// Secrets injected into services must not leak through the controller's logs or the errors it returns
+	logrus.AddHook(docker.NewSecretRedactingHook())
+	defer func() {
+		setupErr = docker.RedactSecretsFromError(setupErr)
+		testErr = docker.RedactSecretsFromError(testErr)
+	}()
+
 	tests := controller.testSuite.GetTests()
 	logrus.Debugf("Test configs: %v", tests)
 	test, found := tests[controller.testName]
@@ -108,15 +183,47 @@ func (controller TestController) RunTest() (setupErr error, testErr error) {
 	}
 
 	logrus.Info("Connecting to Docker environment...")
+	connectionOpts := []client.Opt{
+		client.FromEnv,
+		client.WithAPIVersionNegotiation(),
+	}
+	// These wrap the client's transport, so must come after the options configuring its connection
+	transportWrappingOpts := []client.Opt{
+		docker.WithRateLimiting(docker.DEFAULT_MAX_CONCURRENT_DOCKER_REQUESTS, docker.DEFAULT_MAX_DOCKER_REQUEST_RETRIES),
+	}
+	if controller.artifactsDirpath != "" && controller.artifactVerbosity == networks.ALL_ARTIFACTS {
+		auditLogFilepath := path.Join(controller.artifactsDirpath, networks.DOCKER_AUDIT_LOG_FILENAME)
+		auditLogFp, err := os.Create(auditLogFilepath)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to create Docker audit log file at %v", auditLogFilepath), nil
+		}
+		defer auditLogFp.Close()
+		transportWrappingOpts = append(transportWrappingOpts, docker.WithAuditLog(docker.NewAuditLog(auditLogFp)))
+	}
 	// Initialize a Docker client
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	dockerClient, err := client.NewClientWithOpts(append(connectionOpts, transportWrappingOpts...)...)
 	if err != nil {
 		return stacktrace.Propagate(err,"Failed to initialize Docker client from environment."), nil
 	}
-	dockerManager, err := docker.NewDockerManager(logrus.StandardLogger(), dockerClient)
+	// Every log line from the framework is tagged with the test name, so it can be attributed even when logs are aggregated
+	testLog := logrus.WithField(logging.TEST_NAME_FIELD, controller.testName)
+	dockerManager, err := docker.NewDockerManager(testLog, dockerClient)
 	if err != nil {
 		return stacktrace.Propagate(err, "An error occurred when constructing the Docker manager"), nil
 	}
+	serviceResourceLabels := map[string]string{
+		docker.EXECUTION_ID_LABEL: controller.executionId,
+		docker.TEST_SUITE_LABEL:   controller.testSuiteName,
+		docker.TEST_NAME_LABEL:    controller.testName,
+	}
+	dockerManager.UseResourceLabels(serviceResourceLabels)
+	isWindowsEngine, err := dockerManager.DetectWindowsContainers(context.Background())
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred detecting whether the Docker engine runs Windows containers"), nil
+	}
+	if isWindowsEngine {
+		logrus.Info("The Docker engine runs Windows containers, so the test network will use Windows containers")
+	}
 	logrus.Info("Connected to Docker environment")
 
 	logrus.Infof("Configuring test network in Docker network %v...", controller.networkId)
@@ -130,16 +237,55 @@ func (controller TestController) RunTest() (setupErr error, testErr error) {
 	}
 
 	builder := networks.NewServiceNetworkBuilder(
+			testLog,
 			dockerManager,
 			controller.networkId,
 			freeIpTracker,
 			controller.testVolumeName,
-			controller.testVolumeFilepath)
+			controller.testVolumeFilepath,
+			controller.snapshotsDirpath)
+	for _, swarmDockerHost := range controller.swarmDockerHosts {
+		// Options later in the list override earlier ones, so these replace the host & TLS settings from the environment
+		remoteConnectionOpts := append([]client.Opt{}, connectionOpts...)
+		remoteConnectionOpts = append(remoteConnectionOpts, client.WithHost(swarmDockerHost))
+		if controller.swarmDockerTlsOptions != nil {
+			remoteConnectionOpts = append(remoteConnectionOpts, docker.WithTls(*controller.swarmDockerTlsOptions))
+		}
+		remoteDockerClient, err := client.NewClientWithOpts(append(remoteConnectionOpts, transportWrappingOpts...)...)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to initialize Docker client for Swarm host %v.", swarmDockerHost), nil
+		}
+		remoteDockerManager, err := docker.NewDockerManager(testLog, remoteDockerClient)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred when constructing the Docker manager for Swarm host %v", swarmDockerHost), nil
+		}
+		remoteDockerManager.UseResourceLabels(serviceResourceLabels)
+		builder.AddRemoteDockerHost(remoteDockerManager)
+	}
 	if err := networkLoader.ConfigureNetwork(builder); err != nil {
 		return stacktrace.Propagate(err, "Could not configure test network in Docker network %v", controller.networkId), nil
 	}
 	network := builder.Build()
 	defer func() {
+		logrus.Info("Test network lifecycle timeline:")
+		fmt.Fprint(logrus.StandardLogger().Out, network.GetTimeline())
+
+		// We export artifacts regardless of whether the test passed, because they're useful either way
+		if controller.artifactsDirpath != "" {
+			// Coverage is collected first, since services flush their coverage on their way out (which the logs should show)
+			if err := network.CollectCoverage(controller.artifactsDirpath, COVERAGE_FLUSH_TIMEOUT); err != nil {
+				logrus.Error("An error occurred collecting service coverage; some coverage may be missing")
+				fmt.Fprintln(logrus.StandardLogger().Out, err)
+			}
+			logrus.Infof("Exporting service artifacts to %v...", controller.artifactsDirpath)
+			if err := network.ExportServiceArtifacts(controller.artifactsDirpath, controller.artifactVerbosity); err != nil {
+				logrus.Error("An error occurred exporting service artifacts; some artifacts may be missing")
+				fmt.Fprintln(logrus.StandardLogger().Out, err)
+			} else {
+				logrus.Info("Successfully exported service artifacts")
+			}
+		}
+
 		logrus.Info("Stopping test network...")
 		err := network.RemoveAll(CONTAINER_STOP_TIMEOUT)
 		if err != nil {
@@ -151,8 +297,25 @@ func (controller TestController) RunTest() (setupErr error, testErr error) {
 	}()
 	logrus.Info("Test network configured")
 
+	if controller.healthListenAddr != "" {
+		healthServer, err := networks.StartHealthServer(network, controller.healthListenAddr)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred starting the network health server"), nil
+		}
+		defer healthServer.Close()
+		logrus.Infof("Serving test network health on %v%v", controller.healthListenAddr, networks.HEALTH_PATH)
+	}
+
+	// Pulling every image up front, in parallel, is much faster than pulling them one at a time as services are created
+	if err := network.PrePullImages(); err != nil {
+		return stacktrace.Propagate(err, "An error occurred pre-pulling the test network's images"), nil
+	}
+
 	logrus.Info("Initializing test network...")
+	_, initializeSpan := tracing.StartSpan(context.Background(), "InitializeNetwork")
 	availabilityCheckers, err := networkLoader.InitializeNetwork(network);
+	initializeSpan.RecordError(err)
+	initializeSpan.End()
 	if err != nil {
 		return stacktrace.Propagate(err, "An error occurred initialized the network to its starting state"), nil
 	}
@@ -160,14 +323,41 @@ func (controller TestController) RunTest() (setupErr error, testErr error) {
 
 	// Second pass: wait for all services to come up
 	logrus.Info("Waiting for test network to become available...")
+	_, availabilitySpan := tracing.StartSpan(context.Background(), "WaitForNetworkAvailability")
 	for serviceId, availabilityChecker := range availabilityCheckers {
 		logrus.Debugf("Waiting for service %v to become available...", serviceId)
 		if err := availabilityChecker.WaitForStartup(); err != nil {
+			availabilitySpan.RecordError(err)
+			availabilitySpan.End()
+			logrus.Errorf("Service %v didn't become available; test network diagnostics:", serviceId)
+			fmt.Fprint(logrus.StandardLogger().Out, network.GetAvailabilityDiagnostics(networks.DEFAULT_DIAGNOSTICS_LOG_LINES))
 			return stacktrace.Propagate(err, "An error occurred waiting for service with ID %v to start up", serviceId), nil
 		}
 		logrus.Debugf("Service %v is available", serviceId)
 	}
+	availabilitySpan.End()
 	logrus.Info("Test network is available")
+	if startupReport, err := network.GetStartupReport(); err != nil {
+		logrus.Warnf("Couldn't get the startup times of the test network's services: %v", err)
+	} else {
+		logrus.Info("Test network startup breakdown:")
+		fmt.Fprint(logrus.StandardLogger().Out, startupReport)
+		// The slowest services are what's worth tuning, but the start order is what's needed to read the report above
+		if logrus.IsLevelEnabled(logrus.DebugLevel) {
+			logrus.Debug("Test network startup breakdown, slowest services first:")
+			fmt.Fprint(logrus.StandardLogger().Out, startupReport.SortedBySlowest())
+		}
+		for _, serviceInfo := range startupReport.Services {
+			if startupDuration, isAvailable := serviceInfo.GetStartupDuration(); isAvailable {
+				logrus.Debugf(
+					"Service %v (container %v at %v) became available %v after starting",
+					serviceInfo.ServiceId,
+					serviceInfo.ContainerId,
+					serviceInfo.IpAddr,
+					startupDuration)
+			}
+		}
+	}
 
 	logrus.Info("Executing test...")
 	untypedNetwork, err := networkLoader.WrapNetwork(network)
@@ -177,8 +367,10 @@ func (controller TestController) RunTest() (setupErr error, testErr error) {
 
 	testResultChan := make(chan error)
 
+	_, executionSpan := tracing.StartSpan(context.Background(), "ExecuteTest")
+	defer executionSpan.End()
 	go func() {
-		testResultChan <- runTest(test, untypedNetwork)
+		testResultChan <- runTest(test, untypedNetwork, testsuite.NewTestContext(testLog))
 	}()
 
 	// Time out the test so a poorly-written test doesn't run forever
@@ -210,7 +402,7 @@ func (controller TestController) RunTest() (setupErr error, testErr error) {
 }
 
 // Little helper function meant to be run inside a goroutine that runs the test
-func runTest(test testsuite.Test, untypedNetwork interface{}) (resultErr error) {
+func runTest(test testsuite.Test, untypedNetwork interface{}, testContext testsuite.TestContext) (resultErr error) {
 	// See https://medium.com/@hussachai/error-handling-in-go-a-quick-opinionated-guide-9199dd7c7f76 for details
 	defer func() {
 		if recoverResult := recover(); recoverResult != nil {
@@ -218,7 +410,7 @@ func runTest(test testsuite.Test, untypedNetwork interface{}) (resultErr error)
 			resultErr = recoverResult.(error)
 		}
 	}()
-	test.Run(untypedNetwork, testsuite.TestContext{})
+	test.Run(untypedNetwork, testContext)
 	logrus.Tracef("Test completed successfully")
 	return
 }
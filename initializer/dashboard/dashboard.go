@@ -0,0 +1,288 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/client"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!
+
+The dashboard runs while the system-level logger is being intercepted during parallel test execution, so nothing in this
+	file should log to the system-level logger! Everything is written directly to the dashboard's output.
+
+!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!
+ */
+
+const (
+	// How often the dashboard polls Docker and redraws
+	REFRESH_INTERVAL = 1 * time.Second
+
+	// How many of the most recent log lines to show for each service
+	NUM_RECENT_LOG_LINES = 3
+
+	// Status shown for tests that have started but not yet finished
+	RUNNING_STATUS = "RUNNING"
+
+	// ANSI escape sequence to move the cursor to the top-left & clear the terminal
+	clearScreenSequence = "\033[H\033[2J"
+
+	// How long we'll wait for Docker to respond when gathering the status of a single container
+	containerStatusTimeout = 5 * time.Second
+)
+
+/*
+A snapshot of a single container in a test network, as shown on the dashboard.
+ */
+type serviceSnapshot struct {
+	containerId string
+
+	// The container's status (undefined if statusErr is non-nil)
+	status docker.ContainerStatus
+
+	// Non-nil if an error occurred getting the container's status
+	statusErr error
+
+	// The container's most recent log lines
+	recentLogs []string
+}
+
+/*
+A snapshot of a single test, as shown on the dashboard.
+ */
+type testSnapshot struct {
+	testName string
+
+	status string
+
+	// The containers in the test's network (only populated while the test is running)
+	services []serviceSnapshot
+
+	// Non-nil if an error occurred listing the containers in the test's network
+	networkErr error
+}
+
+/*
+An optional live terminal view of every test in a run, showing each test's services with their health, restart counts,
+	and recent log lines. This is invaluable for debugging big topologies locally.
+
+The dashboard repeatedly clears & redraws its output, so it should be given a terminal that nothing else is writing to
+	(e.g. STDERR, with STDOUT redirected to a file).
+
+NOTE: This is thread-safe!
+ */
+type Dashboard struct {
+	// The Docker manager used to inspect test networks
+	dockerManager *docker.DockerManager
+
+	// Where the dashboard will be drawn to
+	output io.Writer
+
+	// Mutex guarding the test maps
+	mutex *sync.Mutex
+
+	// Mapping of test name -> ID of the Docker network the test is running in
+	testNetworks map[string]string
+
+	// Mapping of test name -> status string
+	testStatuses map[string]string
+
+	// Closed to tell the dashboard goroutine to stop
+	stopChan chan struct{}
+
+	// Closed by the dashboard goroutine once it has fully stopped
+	doneChan chan struct{}
+}
+
+/*
+Creates a new dashboard which will inspect tests using the given Docker client.
+
+Args:
+	dockerClient: The Docker client used to inspect the containers in each test's network
+	output: Where the dashboard will be drawn to, which should be a terminal
+ */
+func NewDashboard(dockerClient *client.Client, output io.Writer) (*Dashboard, error) {
+	// The Docker manager requires a log, but we can't write to the system-level logger so we throw its output away
+	discardingLog := logrus.New()
+	discardingLog.SetOutput(ioutil.Discard)
+	dockerManager, err := docker.NewDockerManager(logrus.NewEntry(discardingLog), dockerClient)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the Docker manager for the dashboard")
+	}
+	return &Dashboard{
+		dockerManager: dockerManager,
+		output:        output,
+		mutex:         &sync.Mutex{},
+		testNetworks:  make(map[string]string),
+		testStatuses:  make(map[string]string),
+		stopChan:      nil,
+		doneChan:      nil,
+	}, nil
+}
+
+/*
+Starts redrawing the dashboard in a background goroutine. A dashboard can only be started once.
+ */
+func (dashboard *Dashboard) Start() error {
+	if dashboard.stopChan != nil {
+		return stacktrace.NewError("Dashboard has already been started")
+	}
+	dashboard.stopChan = make(chan struct{})
+	dashboard.doneChan = make(chan struct{})
+	go dashboard.run()
+	return nil
+}
+
+/*
+Stops redrawing the dashboard, drawing one final frame, and blocks until this is done.
+ */
+func (dashboard *Dashboard) Stop() {
+	if dashboard.stopChan == nil {
+		return
+	}
+	close(dashboard.stopChan)
+	<-dashboard.doneChan
+}
+
+/*
+Records that the given test has started running in the Docker network with the given ID.
+ */
+func (dashboard *Dashboard) SetTestRunning(testName string, networkId string) {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+	dashboard.testNetworks[testName] = networkId
+	dashboard.testStatuses[testName] = RUNNING_STATUS
+}
+
+/*
+Records that the given test has finished with the given status (e.g. "PASSED"), after which its network won't be inspected.
+ */
+func (dashboard *Dashboard) SetTestFinished(testName string, status string) {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+	delete(dashboard.testNetworks, testName)
+	dashboard.testStatuses[testName] = status
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (dashboard *Dashboard) run() {
+	defer close(dashboard.doneChan)
+
+	for {
+		render(dashboard.output, dashboard.takeSnapshots(), time.Now())
+		select {
+		case <-dashboard.stopChan:
+			render(dashboard.output, dashboard.takeSnapshots(), time.Now())
+			return
+		case <-time.After(REFRESH_INTERVAL):
+		}
+	}
+}
+
+/*
+Gathers the current state of every test, sorted by test name so the dashboard doesn't jump around between redraws.
+ */
+func (dashboard *Dashboard) takeSnapshots() []testSnapshot {
+	dashboard.mutex.Lock()
+	testStatuses := make(map[string]string)
+	for testName, status := range dashboard.testStatuses {
+		testStatuses[testName] = status
+	}
+	testNetworks := make(map[string]string)
+	for testName, networkId := range dashboard.testNetworks {
+		testNetworks[testName] = networkId
+	}
+	dashboard.mutex.Unlock()
+
+	result := []testSnapshot{}
+	for testName, status := range testStatuses {
+		snapshot := testSnapshot{
+			testName: testName,
+			status:   status,
+			services: []serviceSnapshot{},
+		}
+		if networkId, found := testNetworks[testName]; found {
+			snapshot.services, snapshot.networkErr = dashboard.takeServiceSnapshots(networkId)
+		}
+		result = append(result, snapshot)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].testName < result[j].testName
+	})
+	return result
+}
+
+func (dashboard *Dashboard) takeServiceSnapshots(networkId string) ([]serviceSnapshot, error) {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), containerStatusTimeout)
+	defer cancelFunc()
+
+	containerIds, err := dashboard.dockerManager.GetContainersOnNetwork(ctx, networkId)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the containers on network %v", networkId)
+	}
+
+	result := []serviceSnapshot{}
+	for containerId, _ := range containerIds {
+		snapshot := serviceSnapshot{
+			containerId: containerId,
+			recentLogs:  []string{},
+		}
+		snapshot.status, snapshot.statusErr = dashboard.dockerManager.GetContainerStatus(ctx, containerId)
+		if recentLogs, err := dashboard.dockerManager.GetContainerLogTail(ctx, containerId, NUM_RECENT_LOG_LINES); err == nil {
+			snapshot.recentLogs = recentLogs
+		}
+		result = append(result, snapshot)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].containerId < result[j].containerId
+	})
+	return result, nil
+}
+
+/*
+Draws a single frame of the dashboard.
+ */
+func render(output io.Writer, snapshots []testSnapshot, now time.Time) {
+	builder := &strings.Builder{}
+	builder.WriteString(clearScreenSequence)
+	fmt.Fprintf(builder, "Kurtosis test dashboard - %v\n", now.Format("15:04:05"))
+	for _, test := range snapshots {
+		fmt.Fprintf(builder, "\n%v [%v]\n", test.testName, test.status)
+		if test.networkErr != nil {
+			fmt.Fprintf(builder, "    Couldn't inspect test network: %v\n", test.networkErr)
+			continue
+		}
+		for _, service := range test.services {
+			shortId := service.containerId
+			if len(shortId) > 12 {
+				shortId = shortId[:12]
+			}
+			if service.statusErr != nil {
+				fmt.Fprintf(builder, "  - %v  couldn't get status: %v\n", shortId, service.statusErr)
+				continue
+			}
+			fmt.Fprintf(
+				builder,
+				"  - %v  %-30v state=%v health=%v restarts=%v\n",
+				shortId,
+				service.status.Image,
+				service.status.State,
+				service.status.HealthStatus,
+				service.status.RestartCount)
+			for _, line := range service.recentLogs {
+				fmt.Fprintf(builder, "        | %v\n", line)
+			}
+		}
+	}
+	io.WriteString(output, builder.String())
+}
@@ -0,0 +1,63 @@
+package dashboard
+
+import (
+	"bytes"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/palantir/stacktrace"
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderShowsServicesAndLogs(t *testing.T) {
+	snapshots := []testSnapshot{
+		{
+			testName: "myTest",
+			status:   RUNNING_STATUS,
+			services: []serviceSnapshot{
+				{
+					containerId: "0123456789abcdef",
+					status: docker.ContainerStatus{
+						Image:        "my-node-image",
+						State:        "running",
+						HealthStatus: docker.NO_HEALTHCHECK_STATUS,
+						RestartCount: 2,
+					},
+					recentLogs: []string{"Block 12 committed"},
+				},
+			},
+		},
+		{
+			testName: "otherTest",
+			status:   "PASSED",
+			services: []serviceSnapshot{},
+		},
+	}
+
+	output := &bytes.Buffer{}
+	render(output, snapshots, time.Now())
+	rendered := output.String()
+	assert.Assert(t, strings.HasPrefix(rendered, clearScreenSequence))
+	assert.Assert(t, strings.Contains(rendered, "myTest [RUNNING]"))
+	assert.Assert(t, strings.Contains(rendered, "0123456789ab "))
+	assert.Assert(t, !strings.Contains(rendered, "0123456789abcdef"))
+	assert.Assert(t, strings.Contains(rendered, "restarts=2"))
+	assert.Assert(t, strings.Contains(rendered, "| Block 12 committed"))
+	assert.Assert(t, strings.Contains(rendered, "otherTest [PASSED]"))
+}
+
+func TestRenderShowsNetworkErrors(t *testing.T) {
+	snapshots := []testSnapshot{
+		{
+			testName:   "myTest",
+			status:     RUNNING_STATUS,
+			services:   []serviceSnapshot{},
+			networkErr: stacktrace.NewError("network went away"),
+		},
+	}
+
+	output := &bytes.Buffer{}
+	render(output, snapshots, time.Now())
+	assert.Assert(t, strings.Contains(output.String(), "network went away"))
+}
@@ -0,0 +1,232 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!
+
+Events are emitted while the system-level logger is being intercepted during parallel test execution, so nothing in this
+	file should log to the system-level logger! Everything is written directly to the stream's output.
+
+!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!
+ */
+
+// =============================== "enum" for event type =========================================
+type EventType string
+const (
+	// The tests are about to start
+	RUN_STARTED EventType = "RUN_STARTED"
+
+	// A test has started running
+	TEST_STARTED EventType = "TEST_STARTED"
+
+	// A test has finished, with the status & (if it errored) error of the test
+	TEST_FINISHED EventType = "TEST_FINISHED"
+
+	// The run received a signal & is cleaning up its tests
+	RUN_INTERRUPTED EventType = "RUN_INTERRUPTED"
+
+	// The final event of every run, with the result document
+	RUN_FINISHED EventType = "RUN_FINISHED"
+)
+
+/*
+A single line of the event stream. Fields that don't apply to the event's type are omitted.
+ */
+type Event struct {
+	Type EventType `json:"type"`
+
+	Time time.Time `json:"time"`
+
+	// The ID of the test suite execution (RUN_STARTED & RUN_FINISHED only)
+	ExecutionId string `json:"executionId,omitempty"`
+
+	// The name of the test (TEST_STARTED & TEST_FINISHED only)
+	TestName string `json:"testName,omitempty"`
+
+	// The test's status, e.g. PASSED (TEST_FINISHED only)
+	Status string `json:"status,omitempty"`
+
+	// How long the test took to run (TEST_FINISHED only)
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+
+	// The error that prevented the test (TEST_FINISHED) or the whole run (RUN_FINISHED) from running
+	Error string `json:"error,omitempty"`
+
+	// The names of the tests that will be run (RUN_STARTED only)
+	TestNames []string `json:"testNames,omitempty"`
+
+	// The result document (RUN_FINISHED only)
+	Result *RunResult `json:"result,omitempty"`
+}
+
+/*
+The final result of a test suite execution.
+ */
+type RunResult struct {
+	// False if any test failed or errored, or if the run couldn't complete
+	AllTestsPassed bool `json:"allTestsPassed"`
+
+	// The outcome of each test that finished, in test name order
+	Tests []TestResult `json:"tests"`
+}
+
+type TestResult struct {
+	Name string `json:"name"`
+
+	Status string `json:"status"`
+
+	// The error that prevented the test from running (empty if it ran)
+	Error string `json:"error,omitempty"`
+
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+/*
+Writes the events of a test suite execution to an output as newline-delimited JSON, for consumption by other tools. The
+	stream also collects the outcome of each test, for the result document of the final RUN_FINISHED event.
+
+NOTE: This is thread-safe!
+ */
+type EventStream struct {
+	mutex *sync.Mutex
+
+	// Nil if the stream only collects the tests' outcomes, without writing events anywhere
+	encoder *json.Encoder
+
+	// Mapping of test name -> outcome, for every test that has finished
+	testResults map[string]TestResult
+}
+
+/*
+Creates a stream that writes its events to the given output, or (if the output is nil) that only collects the tests'
+	outcomes for GetRunResult, e.g. to notify others of a run that's reported in human-readable form.
+ */
+func NewEventStream(output io.Writer) *EventStream {
+	var encoder *json.Encoder = nil
+	if output != nil {
+		encoder = json.NewEncoder(output)
+	}
+	return &EventStream{
+		mutex:       &sync.Mutex{},
+		encoder:     encoder,
+		testResults: map[string]TestResult{},
+	}
+}
+
+/*
+Returns true if the stream writes its events to an output, which is then the run's only output.
+ */
+func (stream *EventStream) HasOutput() bool {
+	return stream.encoder != nil
+}
+
+func (stream *EventStream) EmitRunStarted(executionId string, testNames []string) {
+	sortedTestNames := append([]string{}, testNames...)
+	sort.Strings(sortedTestNames)
+	stream.emit(Event{Type: RUN_STARTED, ExecutionId: executionId, TestNames: sortedTestNames})
+}
+
+func (stream *EventStream) EmitTestStarted(testName string) {
+	stream.emit(Event{Type: TEST_STARTED, TestName: testName})
+}
+
+/*
+Emits the outcome of a test, which is also recorded for the result document.
+
+Args:
+	testName: The test that finished
+	status: The test's status, e.g. PASSED
+	executionErr: The error that prevented the test from running, or nil if it ran
+	duration: How long the test took to run
+ */
+func (stream *EventStream) EmitTestFinished(testName string, status string, executionErr error, duration time.Duration) {
+	errorStr := ""
+	if executionErr != nil {
+		errorStr = executionErr.Error()
+	}
+	stream.mutex.Lock()
+	stream.testResults[testName] = TestResult{
+		Name:            testName,
+		Status:          status,
+		Error:           errorStr,
+		DurationSeconds: duration.Seconds(),
+	}
+	stream.mutex.Unlock()
+	stream.emit(Event{
+		Type:            TEST_FINISHED,
+		TestName:        testName,
+		Status:          status,
+		DurationSeconds: duration.Seconds(),
+		Error:           errorStr,
+	})
+}
+
+func (stream *EventStream) EmitRunInterrupted(signal string) {
+	stream.emit(Event{Type: RUN_INTERRUPTED, Error: "Received signal " + signal})
+}
+
+/*
+Emits the final event of the run, with the result document of every test that finished.
+
+Args:
+	executionId: The ID of the test suite execution
+	allTestsPassed: Whether every test passed
+	runErr: The error that prevented the run from completing, or nil if it completed
+ */
+func (stream *EventStream) EmitRunFinished(executionId string, allTestsPassed bool, runErr error) {
+	errorStr := ""
+	if runErr != nil {
+		errorStr = runErr.Error()
+	}
+	stream.emit(Event{
+		Type:        RUN_FINISHED,
+		ExecutionId: executionId,
+		Error:       errorStr,
+		Result:      stream.GetRunResult(allTestsPassed, runErr),
+	})
+}
+
+/*
+Gets the result document of the run, with every test that has finished so far.
+
+Args:
+	allTestsPassed: Whether every test passed
+	runErr: The error that prevented the run from completing, or nil if it completed
+ */
+func (stream *EventStream) GetRunResult(allTestsPassed bool, runErr error) *RunResult {
+	stream.mutex.Lock()
+	testNames := make([]string, 0, len(stream.testResults))
+	for testName, _ := range stream.testResults {
+		testNames = append(testNames, testName)
+	}
+	sort.Strings(testNames)
+	testResults := make([]TestResult, 0, len(testNames))
+	for _, testName := range testNames {
+		testResults = append(testResults, stream.testResults[testName])
+	}
+	stream.mutex.Unlock()
+
+	return &RunResult{
+		AllTestsPassed: allTestsPassed && runErr == nil,
+		Tests:          testResults,
+	}
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func (stream *EventStream) emit(event Event) {
+	event.Time = time.Now().UTC()
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.encoder == nil {
+		return
+	}
+	// There's nowhere to report a broken output to, since the event stream is the only output
+	stream.encoder.Encode(event)
+}
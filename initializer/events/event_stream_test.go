@@ -0,0 +1,75 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"gotest.tools/v3/assert"
+	"testing"
+	"time"
+)
+
+func TestEventStreamEmitsNewlineDelimitedEvents(t *testing.T) {
+	output := &bytes.Buffer{}
+	stream := NewEventStream(output)
+	stream.EmitRunStarted("execution", []string{"testB", "testA"})
+	stream.EmitTestStarted("testB")
+	stream.EmitTestFinished("testB", "ERRORED", errors.New("setup failed"), 2 * time.Second)
+	stream.EmitTestStarted("testA")
+	stream.EmitTestFinished("testA", "PASSED", nil, time.Second)
+	stream.EmitRunFinished("execution", true, nil)
+
+	emittedEvents := []Event{}
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		event := Event{}
+		assert.NilError(t, json.Unmarshal(scanner.Bytes(), &event))
+		emittedEvents = append(emittedEvents, event)
+	}
+	assert.Equal(t, 6, len(emittedEvents))
+
+	assert.Equal(t, RUN_STARTED, emittedEvents[0].Type)
+	assert.DeepEqual(t, []string{"testA", "testB"}, emittedEvents[0].TestNames)
+	assert.Equal(t, TEST_FINISHED, emittedEvents[2].Type)
+	assert.Equal(t, "setup failed", emittedEvents[2].Error)
+	assert.Equal(t, 2.0, emittedEvents[2].DurationSeconds)
+
+	// The result document has every finished test, in name order
+	finishedEvent := emittedEvents[5]
+	assert.Equal(t, RUN_FINISHED, finishedEvent.Type)
+	assert.Equal(t, "execution", finishedEvent.ExecutionId)
+	assert.DeepEqual(t, &RunResult{
+		AllTestsPassed: true,
+		Tests: []TestResult{
+			{Name: "testA", Status: "PASSED", DurationSeconds: 1},
+			{Name: "testB", Status: "ERRORED", Error: "setup failed", DurationSeconds: 2},
+		},
+	}, finishedEvent.Result)
+}
+
+func TestRunErrorFailsResult(t *testing.T) {
+	output := &bytes.Buffer{}
+	NewEventStream(output).EmitRunFinished("execution", true, errors.New("Docker unreachable"))
+
+	event := Event{}
+	assert.NilError(t, json.Unmarshal(output.Bytes(), &event))
+	assert.Equal(t, "Docker unreachable", event.Error)
+	assert.Equal(t, false, event.Result.AllTestsPassed)
+	assert.Equal(t, 0, len(event.Result.Tests))
+}
+
+func TestStreamWithoutOutputCollectsResults(t *testing.T) {
+	stream := NewEventStream(nil)
+	assert.Assert(t, !stream.HasOutput())
+	stream.EmitTestStarted("testA")
+	stream.EmitTestFinished("testA", "FAILED", errors.New("assertion failed"), time.Second)
+	stream.EmitRunFinished("execution", false, nil)
+
+	assert.DeepEqual(t, &RunResult{
+		AllTestsPassed: false,
+		Tests: []TestResult{
+			{Name: "testA", Status: "FAILED", Error: "assertion failed", DurationSeconds: 1},
+		},
+	}, stream.GetRunResult(false, nil))
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"io"
+)
+
+const (
+	// The statuses of tests that didn't pass, as reported in TestResult.Status
+	failedTestStatus  = "FAILED"
+	erroredTestStatus = "ERRORED"
+
+	junitFailedTestMessage = "Test failed"
+)
+
+// The JUnit XML elements that CI systems (e.g. Jenkins, GitLab, & CircleCI) read test reports from
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name        string          `xml:"name,attr"`
+	NumTests    int             `xml:"tests,attr"`
+	NumFailures int             `xml:"failures,attr"`
+	NumErrors   int             `xml:"errors,attr"`
+	TimeSeconds string          `xml:"time,attr"`
+	TestCases   []junitTestCase `xml:"testcase"`
+	SystemErr   string          `xml:"system-err,omitempty"`
+}
+
+type junitTestCase struct {
+	Name        string        `xml:"name,attr"`
+	ClassName   string        `xml:"classname,attr"`
+	TimeSeconds string        `xml:"time,attr"`
+	Failure     *junitProblem `xml:"failure,omitempty"`
+	Error       *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Details string `xml:",chardata"`
+}
+
+/*
+Writes the given result document as a JUnit XML report, which most CI systems can display test-by-test.
+
+Args:
+	suiteName: The name of the test suite that was run, which the report's single test suite is named after
+	result: The result document of the run (see EventStream.GetRunResult)
+	runErr: The error that prevented the run from completing, or nil if it completed, which is reported as the suite's
+		STDERR
+	output: Where the report will be written
+ */
+func WriteJunitReport(suiteName string, result *RunResult, runErr error, output io.Writer) error {
+	suite := junitTestSuite{
+		Name:      suiteName,
+		NumTests:  len(result.Tests),
+		TestCases: []junitTestCase{},
+	}
+	totalSeconds := 0.0
+	for _, test := range result.Tests {
+		testCase := junitTestCase{
+			Name:        test.Name,
+			ClassName:   suiteName,
+			TimeSeconds: formatJunitSeconds(test.DurationSeconds),
+		}
+		switch test.Status {
+		case failedTestStatus:
+			suite.NumFailures++
+			testCase.Failure = &junitProblem{Message: junitFailedTestMessage}
+		case erroredTestStatus:
+			suite.NumErrors++
+			testCase.Error = &junitProblem{Message: test.Error, Details: test.Error}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+		totalSeconds += test.DurationSeconds
+	}
+	suite.TimeSeconds = formatJunitSeconds(totalSeconds)
+	if runErr != nil {
+		suite.SystemErr = runErr.Error()
+	}
+
+	if _, err := io.WriteString(output, xml.Header); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the JUnit report's XML header")
+	}
+	encoder := xml.NewEncoder(output)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the JUnit report")
+	}
+	if _, err := io.WriteString(output, "\n"); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the end of the JUnit report")
+	}
+	return nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func formatJunitSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
@@ -0,0 +1,37 @@
+package events
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"gotest.tools/v3/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritingJunitReport(t *testing.T) {
+	stream := NewEventStream(nil)
+	stream.EmitTestFinished("testA", "PASSED", nil, time.Second)
+	stream.EmitTestFinished("testB", "FAILED", nil, 2 * time.Second)
+	stream.EmitTestFinished("testC", "ERRORED", errors.New("network setup failed"), 500 * time.Millisecond)
+
+	output := &bytes.Buffer{}
+	assert.NilError(t, WriteJunitReport("my-suite", stream.GetRunResult(false, nil), nil, output))
+	assert.Assert(t, strings.HasPrefix(output.String(), xml.Header))
+
+	report := junitTestSuites{}
+	assert.NilError(t, xml.Unmarshal(output.Bytes(), &report))
+	assert.Equal(t, 1, len(report.Suites))
+	suite := report.Suites[0]
+	assert.Equal(t, "my-suite", suite.Name)
+	assert.Equal(t, 3, suite.NumTests)
+	assert.Equal(t, 1, suite.NumFailures)
+	assert.Equal(t, 1, suite.NumErrors)
+	assert.Equal(t, "3.500", suite.TimeSeconds)
+
+	assert.Equal(t, "testA", suite.TestCases[0].Name)
+	assert.Assert(t, suite.TestCases[0].Failure == nil && suite.TestCases[0].Error == nil)
+	assert.Assert(t, suite.TestCases[1].Failure != nil)
+	assert.Equal(t, "network setup failed", suite.TestCases[2].Error.Message)
+}
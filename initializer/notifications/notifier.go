@@ -0,0 +1,180 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/kurtosis-tech/kurtosis/initializer/events"
+	"github.com/kurtosis-tech/kurtosis/initializer/parallelism"
+	"github.com/palantir/stacktrace"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// Sends the run summary as-is, as JSON
+	DEFAULT_WEBHOOK_PAYLOAD_TEMPLATE = "{{ json . }}"
+
+	// Sends the run summary's text as a Slack incoming webhook message
+	SLACK_WEBHOOK_PAYLOAD_TEMPLATE = `{"text": {{ json .Text }}}`
+
+	webhookTimeout = 30 * time.Second
+
+	jsonContentType = "application/json"
+)
+
+/*
+Tells someone (e.g. a chat channel) how a test suite execution went, once it's finished.
+ */
+type Notifier interface {
+	Notify(summary RunSummary) error
+}
+
+/*
+What a notifier is told about a finished test suite execution.
+ */
+type RunSummary struct {
+	ExecutionId string `json:"executionId"`
+
+	// False if any test failed or errored, or if the run couldn't complete
+	AllTestsPassed bool `json:"allTestsPassed"`
+
+	// The error that prevented the run from completing (empty if it completed)
+	Error string `json:"error,omitempty"`
+
+	// The outcome of each test that finished, in test name order
+	Tests []events.TestResult `json:"tests"`
+
+	// The names of the tests that passed, in order
+	PassedTestNames []string `json:"passedTestNames"`
+
+	// The names of the tests that finished but failed or errored, in order
+	FailedTestNames []string `json:"failedTestNames"`
+
+	// A one-line, human-readable description of the run's outcome, e.g. for chat messages
+	Text string `json:"text"`
+}
+
+/*
+Creates the summary of a finished test suite execution.
+
+Args:
+	executionId: The ID of the test suite execution
+	result: The result document of the run (see events.EventStream.GetRunResult)
+	runErr: The error that prevented the run from completing, or nil if it completed
+ */
+func NewRunSummary(executionId string, result *events.RunResult, runErr error) RunSummary {
+	summary := RunSummary{
+		ExecutionId:     executionId,
+		AllTestsPassed:  result.AllTestsPassed,
+		Tests:           result.Tests,
+		PassedTestNames: []string{},
+		FailedTestNames: []string{},
+	}
+	for _, testResult := range result.Tests {
+		if testResult.Status == string(parallelism.PASSED) {
+			summary.PassedTestNames = append(summary.PassedTestNames, testResult.Name)
+		} else {
+			summary.FailedTestNames = append(summary.FailedTestNames, testResult.Name)
+		}
+	}
+
+	switch {
+	case runErr != nil:
+		summary.Error = runErr.Error()
+		summary.Text = fmt.Sprintf("Kurtosis run %v couldn't complete: %v", executionId, firstLine(summary.Error))
+	case len(summary.FailedTestNames) > 0:
+		summary.Text = fmt.Sprintf(
+			"Kurtosis run %v: %v of %v tests failed: %v",
+			executionId,
+			len(summary.FailedTestNames),
+			len(result.Tests),
+			strings.Join(summary.FailedTestNames, ", "))
+	case !result.AllTestsPassed:
+		summary.Text = fmt.Sprintf("Kurtosis run %v didn't pass (%v tests finished)", executionId, len(result.Tests))
+	default:
+		summary.Text = fmt.Sprintf("Kurtosis run %v: all %v tests passed", executionId, len(result.Tests))
+	}
+	return summary
+}
+
+/*
+A notifier that POSTs the run summary to a webhook (e.g. a Slack incoming webhook, or an alerting system's), as a payload
+	rendered from a Go text/template against the RunSummary. Templates can use a "json" function that renders a value as
+	JSON (e.g. `{"status": {{ json .Text }}}`), so that values are escaped properly.
+ */
+type WebhookNotifier struct {
+	url string
+
+	payloadTemplate *template.Template
+
+	// True if the webhook is only called for runs that didn't pass, e.g. for nightly suites that should only report failures
+	onlyOnFailure bool
+
+	httpClient *http.Client
+}
+
+/*
+Creates a notifier that calls the given webhook.
+
+Args:
+	url: The URL to POST the payload to
+	payloadTemplate: The template of the JSON payload (e.g. DEFAULT_WEBHOOK_PAYLOAD_TEMPLATE or SLACK_WEBHOOK_PAYLOAD_TEMPLATE)
+	onlyOnFailure: True to only call the webhook for runs that didn't pass
+ */
+func NewWebhookNotifier(url string, payloadTemplate string, onlyOnFailure bool) (*WebhookNotifier, error) {
+	if url == "" {
+		return nil, stacktrace.NewError("The webhook URL must not be empty")
+	}
+	parsedTemplate, err := template.New("webhook-payload").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"json": renderJson}).
+		Parse(payloadTemplate)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing the webhook payload template")
+	}
+	return &WebhookNotifier{
+		url:             url,
+		payloadTemplate: parsedTemplate,
+		onlyOnFailure:   onlyOnFailure,
+		httpClient:      &http.Client{Timeout: webhookTimeout},
+	}, nil
+}
+
+func (notifier WebhookNotifier) Notify(summary RunSummary) error {
+	if notifier.onlyOnFailure && summary.AllTestsPassed {
+		return nil
+	}
+	payload := &bytes.Buffer{}
+	if err := notifier.payloadTemplate.Execute(payload, summary); err != nil {
+		return stacktrace.Propagate(err, "An error occurred rendering the webhook payload")
+	}
+	resp, err := notifier.httpClient.Post(notifier.url, jsonContentType, payload)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred calling the webhook")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return stacktrace.NewError("The webhook responded with status %v", resp.Status)
+	}
+	return nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func renderJson(value interface{}) (string, error) {
+	result, err := json.Marshal(value)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred rendering '%v' as JSON", value)
+	}
+	return string(result), nil
+}
+
+// Gets the first line of the given string, since errors' stack traces are too noisy for one-line summaries
+func firstLine(str string) string {
+	if newlineIdx := strings.Index(str, "\n"); newlineIdx != -1 {
+		return str[:newlineIdx]
+	}
+	return str
+}
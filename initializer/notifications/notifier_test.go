@@ -0,0 +1,68 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kurtosis-tech/kurtosis/initializer/events"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var failedRunResult = &events.RunResult{
+	AllTestsPassed: false,
+	Tests: []events.TestResult{
+		{Name: "testA", Status: "PASSED", DurationSeconds: 1},
+		{Name: "testB", Status: "FAILED", Error: "assertion failed", DurationSeconds: 2},
+		{Name: "testC", Status: "ERRORED", Error: "setup failed", DurationSeconds: 3},
+	},
+}
+
+func TestRunSummary(t *testing.T) {
+	summary := NewRunSummary("execution", failedRunResult, nil)
+	assert.DeepEqual(t, []string{"testA"}, summary.PassedTestNames)
+	assert.DeepEqual(t, []string{"testB", "testC"}, summary.FailedTestNames)
+	assert.Equal(t, "Kurtosis run execution: 2 of 3 tests failed: testB, testC", summary.Text)
+
+	errSummary := NewRunSummary("execution", &events.RunResult{}, errors.New("Docker unreachable\nstack trace"))
+	assert.Equal(t, "Kurtosis run execution couldn't complete: Docker unreachable", errSummary.Text)
+	assert.Equal(t, "Docker unreachable\nstack trace", errSummary.Error)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	requestBodies := [][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, err := ioutil.ReadAll(request.Body)
+		assert.NilError(t, err)
+		assert.Equal(t, jsonContentType, request.Header.Get("Content-Type"))
+		requestBodies = append(requestBodies, body)
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(server.URL, SLACK_WEBHOOK_PAYLOAD_TEMPLATE, true)
+	assert.NilError(t, err)
+	assert.NilError(t, notifier.Notify(NewRunSummary("execution", &events.RunResult{AllTestsPassed: true}, nil)))
+	assert.Equal(t, 0, len(requestBodies))
+
+	assert.NilError(t, notifier.Notify(NewRunSummary("execution", failedRunResult, nil)))
+	assert.Equal(t, 1, len(requestBodies))
+	payload := map[string]string{}
+	assert.NilError(t, json.Unmarshal(requestBodies[0], &payload))
+	assert.DeepEqual(t, map[string]string{"text": "Kurtosis run execution: 2 of 3 tests failed: testB, testC"}, payload)
+}
+
+func TestWebhookNotifierErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(server.URL, DEFAULT_WEBHOOK_PAYLOAD_TEMPLATE, false)
+	assert.NilError(t, err)
+	assert.ErrorContains(t, notifier.Notify(NewRunSummary("execution", failedRunResult, nil)), "403")
+
+	_, err = NewWebhookNotifier(server.URL, "{{ json .Text ", false)
+	assert.ErrorContains(t, err, "parsing the webhook payload template")
+}
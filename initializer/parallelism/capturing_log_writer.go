@@ -1,16 +1,50 @@
 package parallelism
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"runtime"
 	"sync"
 )
 
+const (
+	// The most erroneous messages that are kept between drains, unless configured otherwise (see
+	//  SystemLogCaptureOptions); once there are more, the oldest are dropped, so that code that logs to the system logger
+	//  in a loop can't make a long-running suite (e.g. a soak test) run out of memory
+	DEFAULT_MAX_CAPTURED_ERRONEOUS_MESSAGES = 100
+
+	// The most bytes of each message & stacktrace that are kept, with anything past that being replaced by a marker
+	maxCapturedErroneousMessageBytes    = 4 * 1024
+	maxCapturedErroneousStacktraceBytes = 16 * 1024
+
+	truncationMarkerFormat = "... [%v more bytes truncated]"
+)
+
 /*
-Package struct encapsulating information about where an erroneous system logger event came from
+A message that was logged to the system-level logger during parallel test execution, and where it came from.
  */
-type erroneousSystemLogInfo struct {
-	message    []byte
-	stacktrace []byte
+type ErroneousSystemLog struct {
+	// The message that was logged (truncated if it was very long)
+	Message []byte
+
+	// The stacktrace of the goroutine that logged the message (truncated if it was very long)
+	Stacktrace []byte
+}
+
+/*
+How messages logged to the system-level logger during parallel test execution are captured (see
+	TestExecutorParallelizer.UseSystemLogCaptureOptions).
+ */
+type SystemLogCaptureOptions struct {
+	// The most messages kept between drains (i.e. between tests finishing), after which the oldest are dropped; 0 for
+	//  DEFAULT_MAX_CAPTURED_ERRONEOUS_MESSAGES
+	MaxMessages int
+
+	// Prefixes of the packages (e.g. "github.com/docker/docker/") of known noisy code that's expected to log to the
+	//  system-level logger; messages logged from these packages are passed through to the output instead of being
+	//  flagged as erroneous
+	AllowedCallerPackages []string
 }
 
 /*
@@ -25,23 +59,56 @@ however, so we need a way to:
 Thus, we have this special writer that we plug in which doesn't actually write to STDOUT but captures the input for
  later logging in the form of a really loud error message.
 
+To keep memory bounded no matter how much gets logged, only the most recent messages are kept (with a count of how many
+ were dropped) and each message & stacktrace is truncated.
+
 NOTE: This is thread-safe!
  */
 type erroneousSystemLogCaptureWriter struct {
-	logMessages []erroneousSystemLogInfo
+	logMessages []ErroneousSystemLog
 	mutex *sync.Mutex
+
+	// The number of messages that were dropped to make room for newer ones
+	numDroppedMessages int
+
+	// The most messages that are kept
+	maxMessages int
+
+	// Prefixes of the packages whose messages are passed through rather than captured
+	allowedCallerPackages []string
+
+	// Where the messages of allowed callers are passed through to (nil to discard them)
+	passthroughOutput io.Writer
 }
 
 /*
-Creates a new writer for capturing erroneous system log events
+Creates a new writer for capturing erroneous system log events with the given options
  */
-func newErroneousSystemLogCaptureWriter() *erroneousSystemLogCaptureWriter {
+func newErroneousSystemLogCaptureWriter(options SystemLogCaptureOptions) *erroneousSystemLogCaptureWriter {
+	maxMessages := options.MaxMessages
+	if maxMessages <= 0 {
+		maxMessages = DEFAULT_MAX_CAPTURED_ERRONEOUS_MESSAGES
+	}
 	return &erroneousSystemLogCaptureWriter{
-		logMessages: []erroneousSystemLogInfo{},
+		logMessages: []ErroneousSystemLog{},
 		mutex: &sync.Mutex{},
+		numDroppedMessages: 0,
+		maxMessages: maxMessages,
+		allowedCallerPackages: append([]string{}, options.AllowedCallerPackages...),
+		passthroughOutput: nil,
 	}
 }
 
+/*
+Sets where the messages logged by allowed callers are passed through to (e.g. the system-level logger's output from
+	before it was intercepted)
+ */
+func (writer *erroneousSystemLogCaptureWriter) setPassthroughOutput(output io.Writer) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	writer.passthroughOutput = output
+}
+
 /*
 This write function (which comes from the Writer interface) will capture:
 		a) the message that was intended for logging and
@@ -52,30 +119,51 @@ func (writer *erroneousSystemLogCaptureWriter) Write(data []byte) (n int, err er
 	writer.mutex.Lock()
 	defer writer.mutex.Unlock()
 
-	dataCopy := make([]byte, len(data))
-	copy(dataCopy, data)
-	stacktraceBytes := getStacktraceBytes()
-	logInfo := erroneousSystemLogInfo{
-		message:    dataCopy,
-		stacktrace: stacktraceBytes,
+	stacktrace := getStacktraceBytes()
+	if writer.isFromAllowedCaller(stacktrace) {
+		if writer.passthroughOutput == nil {
+			return len(data), nil
+		}
+		return writer.passthroughOutput.Write(data)
+	}
+
+	logInfo := ErroneousSystemLog{
+		Message:    truncateBytes(data, maxCapturedErroneousMessageBytes),
+		Stacktrace: truncateBytes(stacktrace, maxCapturedErroneousStacktraceBytes),
+	}
+	if len(writer.logMessages) >= writer.maxMessages {
+		// We copy rather than reslice so that the dropped message's memory can be reclaimed
+		writer.logMessages = append([]ErroneousSystemLog{}, writer.logMessages[1:]...)
+		writer.numDroppedMessages++
 	}
 	writer.logMessages = append(writer.logMessages, logInfo)
 	return len(data), nil
 }
 
 /*
-Retrieves the erroneous system-level logger messages that were captured
+Retrieves the erroneous system-level logger messages that were captured since the last drain, along with the number of
+	earlier messages that were dropped to keep memory bounded, and forgets them so that they're only reported once
  */
-func (writer *erroneousSystemLogCaptureWriter) getCapturedMessages() []erroneousSystemLogInfo {
+func (writer *erroneousSystemLogCaptureWriter) drainCapturedMessages() ([]ErroneousSystemLog, int) {
 	writer.mutex.Lock()
 	defer writer.mutex.Unlock()
 
-	// Defensive copy
-	result := []erroneousSystemLogInfo{}
-	for _, logInfo := range writer.logMessages {
-		result = append(result, logInfo)
+	result := writer.logMessages
+	numDroppedMessages := writer.numDroppedMessages
+	writer.logMessages = []ErroneousSystemLog{}
+	writer.numDroppedMessages = 0
+	return result, numDroppedMessages
+}
+
+// Returns true if the given stacktrace has a frame in one of the allowed callers' packages
+func (writer *erroneousSystemLogCaptureWriter) isFromAllowedCaller(stacktrace []byte) bool {
+	for _, packagePrefix := range writer.allowedCallerPackages {
+		// Each frame's function is on its own line, starting with the function's package path
+		if packagePrefix != "" && bytes.Contains(stacktrace, []byte("\n" + packagePrefix)) {
+			return true
+		}
 	}
-	return result
+	return false
 }
 
 /*
@@ -93,3 +181,17 @@ func getStacktraceBytes() []byte {
 		buf = make([]byte, 2*len(buf))
 	}
 }
+
+/*
+Copies the given bytes, replacing everything past the first maxBytes with a marker saying how much was cut.
+ */
+func truncateBytes(data []byte, maxBytes int) []byte {
+	if len(data) <= maxBytes {
+		result := make([]byte, len(data))
+		copy(result, data)
+		return result
+	}
+	result := make([]byte, maxBytes, maxBytes + len(truncationMarkerFormat) + 20)
+	copy(result, data[:maxBytes])
+	return append(result, fmt.Sprintf(truncationMarkerFormat, len(data) - maxBytes)...)
+}
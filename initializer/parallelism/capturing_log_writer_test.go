@@ -0,0 +1,66 @@
+package parallelism
+
+import (
+	"bytes"
+	"fmt"
+	"gotest.tools/assert"
+	"testing"
+)
+
+func TestCapturedMessagesAreBounded(t *testing.T) {
+	writer := newErroneousSystemLogCaptureWriter(SystemLogCaptureOptions{})
+	numMessages := DEFAULT_MAX_CAPTURED_ERRONEOUS_MESSAGES + 5
+	for i := 0; i < numMessages; i++ {
+		_, err := writer.Write([]byte(fmt.Sprintf("message %v", i)))
+		assert.NilError(t, err)
+	}
+
+	messages, numDropped := writer.drainCapturedMessages()
+	assert.Equal(t, DEFAULT_MAX_CAPTURED_ERRONEOUS_MESSAGES, len(messages))
+	assert.Equal(t, 5, numDropped)
+	assert.Equal(t, "message 5", string(messages[0].Message))
+	assert.Equal(t, fmt.Sprintf("message %v", numMessages - 1), string(messages[len(messages) - 1].Message))
+}
+
+func TestLongCapturedMessagesAreTruncated(t *testing.T) {
+	writer := newErroneousSystemLogCaptureWriter(SystemLogCaptureOptions{})
+	longMessage := bytes.Repeat([]byte("a"), maxCapturedErroneousMessageBytes + 10)
+	_, err := writer.Write(longMessage)
+	assert.NilError(t, err)
+
+	messages, _ := writer.drainCapturedMessages()
+	expected := string(longMessage[:maxCapturedErroneousMessageBytes]) + "... [10 more bytes truncated]"
+	assert.Equal(t, expected, string(messages[0].Message))
+}
+
+func TestDrainingCapturedMessages(t *testing.T) {
+	writer := newErroneousSystemLogCaptureWriter(SystemLogCaptureOptions{MaxMessages: 2})
+	for i := 0; i < 3; i++ {
+		_, err := writer.Write([]byte(fmt.Sprintf("message %v", i)))
+		assert.NilError(t, err)
+	}
+
+	messages, numDropped := writer.drainCapturedMessages()
+	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, 1, numDropped)
+	assert.Assert(t, bytes.Contains(messages[0].Stacktrace, []byte("TestDrainingCapturedMessages")))
+
+	// Drained messages are only reported once
+	messages, numDropped = writer.drainCapturedMessages()
+	assert.Equal(t, 0, len(messages))
+	assert.Equal(t, 0, numDropped)
+}
+
+func TestAllowedCallersArePassedThrough(t *testing.T) {
+	writer := newErroneousSystemLogCaptureWriter(SystemLogCaptureOptions{
+		AllowedCallerPackages: []string{"github.com/kurtosis-tech/kurtosis/initializer/parallelism.TestAllowedCallers"},
+	})
+	passthroughOutput := &bytes.Buffer{}
+	writer.setPassthroughOutput(passthroughOutput)
+	_, err := writer.Write([]byte("expected noise"))
+	assert.NilError(t, err)
+
+	assert.Equal(t, "expected noise", passthroughOutput.String())
+	messages, _ := writer.drainCapturedMessages()
+	assert.Equal(t, 0, len(messages))
+}
@@ -0,0 +1,103 @@
+package parallelism
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// =============================== "enum" for CI log format =========================================
+/*
+How each test's output is marked up, so that the suite's output is navigable in a CI system's UI.
+ */
+type CiLogFormat string
+const (
+	// Each test's output is set off with banners
+	PLAIN_LOG_FORMAT CiLogFormat = "plain"
+
+	// Each test's output is a collapsible group, and failures are error annotations on the workflow run
+	GITHUB_ACTIONS_LOG_FORMAT CiLogFormat = "github-actions"
+
+	// Each test's output is a collapsible group, which is expanded for failed tests
+	// NOTE: Buildkite annotations can only be made with the buildkite-agent binary, so failures aren't annotated
+	BUILDKITE_LOG_FORMAT CiLogFormat = "buildkite"
+)
+
+const (
+	// The environment variables that GitHub Actions & Buildkite set to "true" in every job
+	githubActionsEnvVar = "GITHUB_ACTIONS"
+	buildkiteEnvVar = "BUILDKITE"
+)
+
+/*
+Gets the log format for the CI system that this process is running in, according to the environment variables the CI
+	system sets, or PLAIN_LOG_FORMAT if it isn't running in a known CI system.
+ */
+func DetectCiLogFormat() CiLogFormat {
+	if os.Getenv(githubActionsEnvVar) == "true" {
+		return GITHUB_ACTIONS_LOG_FORMAT
+	}
+	if os.Getenv(buildkiteEnvVar) == "true" {
+		return BUILDKITE_LOG_FORMAT
+	}
+	return PLAIN_LOG_FORMAT
+}
+
+/*
+Returns true if the given string is one of the log formats.
+ */
+func IsValidCiLogFormat(format string) bool {
+	switch CiLogFormat(format) {
+	case PLAIN_LOG_FORMAT, GITHUB_ACTIONS_LOG_FORMAT, BUILDKITE_LOG_FORMAT:
+		return true
+	}
+	return false
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+/*
+Writes the marker that starts a collapsible group with the given title, which a failed test's group shouldn't be collapsed
+	in if the CI system supports that.
+
+NOTE: The markers must be whole lines, so they're written to the output directly rather than through a log formatter.
+ */
+func (format CiLogFormat) writeGroupStart(output io.Writer, title string, isFailure bool) {
+	switch format {
+	case GITHUB_ACTIONS_LOG_FORMAT:
+		fmt.Fprintf(output, "::group::%v\n", escapeGithubActionsCommandData(title))
+	case BUILDKITE_LOG_FORMAT:
+		if isFailure {
+			fmt.Fprintf(output, "+++ %v\n", title)
+		} else {
+			fmt.Fprintf(output, "--- %v\n", title)
+		}
+	}
+}
+
+func (format CiLogFormat) writeGroupEnd(output io.Writer) {
+	if format == GITHUB_ACTIONS_LOG_FORMAT {
+		fmt.Fprintln(output, "::endgroup::")
+	}
+}
+
+// Writes an error annotation for the given test, if the CI system supports them
+func (format CiLogFormat) writeErrorAnnotation(output io.Writer, testName string, message string) {
+	if format == GITHUB_ACTIONS_LOG_FORMAT {
+		fmt.Fprintf(
+			output,
+			"::error title=%v::%v\n",
+			escapeGithubActionsCommandProperty("Test " + testName),
+			escapeGithubActionsCommandData(message))
+	}
+}
+
+// Escapes a GitHub Actions workflow command's data, which can't contain raw newlines
+func escapeGithubActionsCommandData(data string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(data)
+}
+
+// Escapes a GitHub Actions workflow command's property, which additionally can't contain the property separators
+func escapeGithubActionsCommandProperty(property string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(property)
+}
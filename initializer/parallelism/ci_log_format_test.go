@@ -0,0 +1,74 @@
+package parallelism
+
+import (
+	"bytes"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGithubActionsGroupsAndAnnotations(t *testing.T) {
+	output := captureTestOutputs(GITHUB_ACTIONS_LOG_FORMAT, func(manager *ParallelTestOutputManager) {
+		manager.logTestOutput("passingTest", nil, true, strings.NewReader("passing logs\n"))
+		manager.logTestOutput("erroringTest", stacktrace.NewError("line one\nline two"), false, strings.NewReader("erroring logs\n"))
+	})
+
+	assert.Assert(t, strings.Contains(output, "::group::passingTest: PASSED\npassing logs\n"))
+	assert.Assert(t, strings.Contains(output, "::group::erroringTest: ERRORED\nerroring logs\n"))
+	assert.Equal(t, strings.Count(output, "::endgroup::\n"), 2)
+	assert.Equal(t, strings.Count(output, "::error "), 1)
+	// Annotations must be a single line
+	assert.Assert(t, strings.Contains(output, "::error title=Test erroringTest::Test erroringTest ERRORED: line one%0Aline two"))
+}
+
+func TestBuildkiteExpandsFailedGroups(t *testing.T) {
+	output := captureTestOutputs(BUILDKITE_LOG_FORMAT, func(manager *ParallelTestOutputManager) {
+		manager.logTestOutput("passingTest", nil, true, strings.NewReader(""))
+		manager.logTestOutput("failingTest", nil, false, strings.NewReader(""))
+	})
+	assert.Assert(t, strings.Contains(output, "--- passingTest: PASSED\n"))
+	assert.Assert(t, strings.Contains(output, "+++ failingTest: FAILED\n"))
+}
+
+func TestPlainFormatHasNoMarkers(t *testing.T) {
+	output := captureTestOutputs(PLAIN_LOG_FORMAT, func(manager *ParallelTestOutputManager) {
+		manager.logTestOutput("failingTest", nil, false, strings.NewReader(""))
+	})
+	assert.Assert(t, !strings.Contains(output, "::"))
+	assert.Assert(t, !strings.Contains(output, "+++"))
+	assert.Assert(t, strings.Contains(output, "failingTest"))
+}
+
+func TestDetectingCiLogFormat(t *testing.T) {
+	for _, envVar := range []string{githubActionsEnvVar, buildkiteEnvVar} {
+		originalValue, isSet := os.LookupEnv(envVar)
+		if isSet {
+			defer os.Setenv(envVar, originalValue)
+		} else {
+			defer os.Unsetenv(envVar)
+		}
+		os.Unsetenv(envVar)
+	}
+	assert.Equal(t, DetectCiLogFormat(), PLAIN_LOG_FORMAT)
+	os.Setenv(buildkiteEnvVar, "true")
+	assert.Equal(t, DetectCiLogFormat(), BUILDKITE_LOG_FORMAT)
+	os.Setenv(githubActionsEnvVar, "true")
+	assert.Equal(t, DetectCiLogFormat(), GITHUB_ACTIONS_LOG_FORMAT)
+
+	assert.Assert(t, IsValidCiLogFormat("github-actions"))
+	assert.Assert(t, !IsValidCiLogFormat("jenkins"))
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Gets what the given function logs through an output manager with the given format
+func captureTestOutputs(format CiLogFormat, logOutputs func(manager *ParallelTestOutputManager)) string {
+	output := &bytes.Buffer{}
+	originalOutput := logrus.StandardLogger().Out
+	logrus.SetOutput(output)
+	defer logrus.SetOutput(originalOutput)
+	logOutputs(newParallelTestOutputManager(format, SystemLogCaptureOptions{}))
+	return output.String()
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
 	"io"
+	"sort"
 	"sync"
 )
 
@@ -66,25 +67,65 @@ type ParallelTestOutputManager struct {
 
 	// Captures all test output sent through the output manager
 	testOutputs  		   map[string]parallelTestOutput
+
+	// How each test's output is marked up for the CI system's UI
+	ciLogFormat            CiLogFormat
+
+	// Mapping of test name -> (artifact filepath -> URL), for tests whose artifacts were uploaded
+	artifactUrls           map[string]map[string]string
+
+	// Mapping of test name -> the error uploading its artifacts, for tests whose artifacts couldn't all be uploaded
+	artifactUploadErrs     map[string]error
 }
 
 /*
-Creates a new output manager to handle the display of parallel test results.
+Creates a new output manager to handle the display of parallel test results, with each test's output marked up in the
+	given format & system-level log messages captured per the given options.
  */
-func newParallelTestOutputManager() *ParallelTestOutputManager {
+func newParallelTestOutputManager(ciLogFormat CiLogFormat, captureOptions SystemLogCaptureOptions) *ParallelTestOutputManager {
 	return &ParallelTestOutputManager{
-		interceptor:             newErroneousSystemLogCaptureWriter(),
+		interceptor:             newErroneousSystemLogCaptureWriter(captureOptions),
 		writerBeforeManagement:  nil,
 		isInterceptingStdLogger: false,
 		mutex:                   &sync.Mutex{},
 		sideChannelLogger:       nil,
 		testOutputs:             make(map[string]parallelTestOutput),
+		ciLogFormat:             ciLogFormat,
+		artifactUrls:            make(map[string]map[string]string),
+		artifactUploadErrs:      make(map[string]error),
 	}
 }
 
+/*
+Thread-safe method to record the URLs of a test's uploaded artifacts, to be printed in the summary.
+
+Args:
+	testName: The name of the test whose artifacts were uploaded
+	artifactUrls: Mapping of artifact filepath -> URL, for every artifact that was uploaded
+	uploadErr: The error that prevented some or all of the artifacts from being uploaded, or nil
+ */
+func (manager *ParallelTestOutputManager) recordArtifactUpload(testName string, artifactUrls map[string]string, uploadErr error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	manager.artifactUrls[testName] = artifactUrls
+	if uploadErr != nil {
+		manager.artifactUploadErrs[testName] = uploadErr
+	}
+}
+
+/*
+Thread-safe method to get the messages that were logged to the system-level logger since the last drain (which can't be
+	attributed to any one test, as tests run in parallel), along with how many earlier messages were dropped to bound
+	memory. Each message is only returned once.
+ */
+func (manager *ParallelTestOutputManager) DrainErroneousSystemLogs() ([]ErroneousSystemLog, int) {
+	return manager.interceptor.drainCapturedMessages()
+}
+
 /*
 Thread-safe method to log test output, to provide parallel tests a way to print their log messages in real time as
-	they finish.
+	they finish. Any messages logged to the system-level logger since the last test finished are printed as a warning in
+	the test's output, as they may have come from the test.
  */
 func (manager *ParallelTestOutputManager) logTestOutput(
 			testName string,
@@ -114,14 +155,20 @@ func (manager *ParallelTestOutputManager) logTestOutput(
 		outputLogger = manager.sideChannelLogger
 	}
 
-	printBanner(outputLogger, testName, logTestNameBannerAsError)
+	status := getTestStatusFromResult(executionErr, testPassed)
+	if manager.ciLogFormat == PLAIN_LOG_FORMAT {
+		printBanner(outputLogger, testName, logTestNameBannerAsError)
+	} else {
+		manager.ciLogFormat.writeGroupStart(outputLogger.Out, fmt.Sprintf("%v: %v", testName, status), status != PASSED)
+	}
 	_, err := io.Copy(outputLogger.Out, testLogs)
 	if err != nil {
 		outputLogger.Error("An error occurred copying the test's logfile to STDOUT; the logs above may not be complete!")
 		fmt.Fprintln(outputLogger.Out, err) // Logrus will escape newlines so we don't actually log this
 	}
+	erroneousSystemLogs, numDroppedErroneousSystemLogs := manager.DrainErroneousSystemLogs()
+	logErroneousSystemLogging(outputLogger, erroneousSystemLogs, numDroppedErroneousSystemLogs)
 
-	status := getTestStatusFromResult(executionErr, testPassed)
 	switch status {
 	case ERRORED:
 		outputLogger.Errorf("Test %v %v", testName, status)
@@ -131,6 +178,14 @@ func (manager *ParallelTestOutputManager) logTestOutput(
 	case FAILED:
 		outputLogger.Errorf("Test %v %v", testName, status)
 	}
+
+	manager.ciLogFormat.writeGroupEnd(outputLogger.Out)
+	switch status {
+	case ERRORED:
+		manager.ciLogFormat.writeErrorAnnotation(outputLogger.Out, testName, fmt.Sprintf("Test %v %v: %v", testName, status, executionErr))
+	case FAILED:
+		manager.ciLogFormat.writeErrorAnnotation(outputLogger.Out, testName, fmt.Sprintf("Test %v %v", testName, status))
+	}
 }
 
 /*
@@ -154,6 +209,8 @@ func (manager *ParallelTestOutputManager) startInterceptingStdLogger() {
 	manager.sideChannelLogger.SetLevel(stdLogger.Level)
 	// NOTE: we don't copy hooks here because we don't use them - if we ever use hooks, copy them here!
 
+	// Known noisy callers' messages still go to the original output
+	manager.interceptor.setPassthroughOutput(stdLogger.Out)
 	logrus.SetOutput(manager.interceptor)
 	manager.isInterceptingStdLogger = true
 }
@@ -176,7 +233,8 @@ func (manager *ParallelTestOutputManager) stopInterceptingStdLogger() {
 /*
 Prints a summary of:
 1) the status of all the tests that have been logged to the logger so far
-2) any erroneous log messages that were captured while the standard logger was being intercepted
+2) any erroneous log messages that were captured while the standard logger was being intercepted, since the last test
+	finished
  */
 func (manager *ParallelTestOutputManager) printSummary() {
 	manager.mutex.Lock()
@@ -208,10 +266,22 @@ func (manager *ParallelTestOutputManager) printSummary() {
 		} else {
 			outputLogger.Info(logStr)
 		}
+		artifactUrls := manager.artifactUrls[testName]
+		artifactFilepaths := make([]string, 0, len(artifactUrls))
+		for artifactFilepath, _ := range artifactUrls {
+			artifactFilepaths = append(artifactFilepaths, artifactFilepath)
+		}
+		sort.Strings(artifactFilepaths)
+		for _, artifactFilepath := range artifactFilepaths {
+			outputLogger.Infof("    %v: %v", artifactFilepath, artifactUrls[artifactFilepath])
+		}
+		if uploadErr, found := manager.artifactUploadErrs[testName]; found {
+			outputLogger.Warnf("    Not all artifacts could be uploaded: %v", uploadErr)
+		}
 	}
 
-	erroneousSystemLogs := manager.interceptor.getCapturedMessages()
-	logErroneousSystemLogging(outputLogger, erroneousSystemLogs)
+	erroneousSystemLogs, numDroppedErroneousSystemLogs := manager.DrainErroneousSystemLogs()
+	logErroneousSystemLogging(outputLogger, erroneousSystemLogs, numDroppedErroneousSystemLogs)
 }
 
 /*
@@ -264,8 +334,8 @@ func getTestStatusFromResult(executionErr error, testPassed bool) testStatus {
 Helper function to print a big warning if there was logging to the system-level logging when there should only have been
  logging to the test-specific logger
 */
-func logErroneousSystemLogging(log *logrus.Logger, capturedErroneousMessages []erroneousSystemLogInfo) {
-	if len(capturedErroneousMessages) == 0 {
+func logErroneousSystemLogging(log *logrus.Logger, capturedErroneousMessages []ErroneousSystemLog, numDroppedMessages int) {
+	if len(capturedErroneousMessages) == 0 && numDroppedMessages == 0 {
 		return
 	}
 
@@ -279,15 +349,19 @@ func logErroneousSystemLogging(log *logrus.Logger, capturedErroneousMessages []e
 	log.Error("")
 	log.Error("The log message(s) attempted, and the stacktrace(s) of origination, are as follows in the order they were logged:")
 	log.Error("")
+	if numDroppedMessages > 0 {
+		log.Errorf("[%d earlier erroneous message(s) were dropped to bound memory usage; only the most recent are shown]", numDroppedMessages)
+		log.Error("")
+	}
 
 	for i, messageInfo := range capturedErroneousMessages {
-		log.Errorf("----------------- Erroneous Message #%d -------------------", i+1)
+		log.Errorf("----------------- Erroneous Message #%d -------------------", numDroppedMessages+i+1)
 		log.Error("Message:")
-		log.Out.Write(messageInfo.message)
+		log.Out.Write(messageInfo.Message)
 		log.Out.Write([]byte("\n")) // The message likely won't come with a newline so we add it
 		log.Error("")
 		log.Error("Stacktrace:")
-		log.Out.Write(messageInfo.stacktrace)
+		log.Out.Write(messageInfo.Stacktrace)
 		log.Out.Write([]byte("\n")) // The stacktrace likely won't end with a newline so we add it
 	}
 }
@@ -2,7 +2,9 @@ package parallelism
 
 import (
 	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
 	"gotest.tools/assert"
+	"strings"
 	"testing"
 )
 
@@ -13,3 +15,19 @@ func TestLogTestResult(t *testing.T) {
 	assert.Equal(t, getTestStatusFromResult(stacktrace.NewError("Test"), false), ERRORED, "Expected errored test")
 	assert.Equal(t, getTestStatusFromResult(stacktrace.NewError("Test"), true), ERRORED, "Expected errored test")
 }
+
+func TestErroneousSystemLogsAreFlushedIntoTestOutput(t *testing.T) {
+	output := captureTestOutputs(PLAIN_LOG_FORMAT, func(manager *ParallelTestOutputManager) {
+		manager.startInterceptingStdLogger()
+		defer manager.stopInterceptingStdLogger()
+		logrus.Info("stray system log message")
+		manager.logTestOutput("testA", nil, true, strings.NewReader("testA's logs\n"))
+		manager.logTestOutput("testB", nil, true, strings.NewReader("testB's logs\n"))
+	})
+
+	// The message is printed once, in the output of the first test to finish after it was logged
+	testBIdx := strings.Index(output, "testB's logs")
+	strayIdx := strings.Index(output, "stray system log message")
+	assert.Assert(t, strayIdx > strings.Index(output, "testA's logs") && strayIdx < testBIdx)
+	assert.Equal(t, 1, strings.Count(output, "stray system log message"))
+}
@@ -18,10 +18,13 @@ type ParallelTestParams struct {
 	// Subnet mask that should be used for the Docker network that the test controller & network will run in
 	SubnetMask          string
 
+	// IPv6 subnet mask that the test's Docker network will additionally have, making it dual-stack (empty for none)
+	Ipv6SubnetMask      string
+
 	// UUID representing an a single execution of one or more tests from the test suite, to which this test execution belongs
 	ExecutionInstanceId uuid.UUID
 }
 
-func NewParallelTestParams(testName string, test testsuite.Test, subnetMask string, executionInstanceId uuid.UUID) *ParallelTestParams {
-	return &ParallelTestParams{TestName: testName, Test: test, SubnetMask: subnetMask, ExecutionInstanceId: executionInstanceId}
+func NewParallelTestParams(testName string, test testsuite.Test, subnetMask string, ipv6SubnetMask string, executionInstanceId uuid.UUID) *ParallelTestParams {
+	return &ParallelTestParams{TestName: testName, Test: test, SubnetMask: subnetMask, Ipv6SubnetMask: ipv6SubnetMask, ExecutionInstanceId: executionInstanceId}
 }
@@ -7,14 +7,19 @@ import (
 	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
 	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/logging"
 	"github.com/kurtosis-tech/kurtosis/commons/networks"
 	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+	"github.com/kurtosis-tech/kurtosis/initializer/dashboard"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
 	"io"
 	"io/ioutil"
 	"net"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -33,11 +38,27 @@ const (
 	containerSuccessExitCode = 0
 
 	// TODO Make this configurable based on the controller image the user defines!
-	controllerLogMountFilepath = "/test-controller.log"
+	// The controller's log file is mounted via its directory, as Windows containers can't bind-mount single files
+	controllerLogMountDirpath = "/test-controller-logs"
+	controllerLogFilename     = "test-controller.log"
+
+	// Where the Docker engine is reached from the controller, which needs to spin up new containers
+	dockerSocketFilepath = "/var/run/docker.sock"
 
 	// TODO Make this configurable based on the controller image the user defines!
 	testVolumeMountpoint = "/shared"
 
+	// Appended to the execution UUID & test name to name the controller's container
+	controllerContainerNameSuffix = "--controller"
+
+	// Where the test's artifacts directory on the host will be mounted on the controller
+	artifactsMountpoint = "/artifacts"
+
+	artifactsDirPerms = 0755
+
+	// Where the shared snapshots directory on the host will be mounted on the controller
+	snapshotsMountpoint = "/snapshots"
+
 	// These are an "API" of sorts - environment variables that are agreed to be set in the test controller's Docker environment
 	testVolumeArg           = "TEST_VOLUME"
 	testNameArg             = "TEST_NAME"
@@ -48,6 +69,15 @@ const (
 	logLevelArg             = "LOG_LEVEL"
 	testControllerIpArg     = "TEST_CONTROLLER_IP"
 	testVolumeMountpointArg = "TEST_VOLUME_MOUNTPOINT"
+	artifactsDirpathArg     = "ARTIFACTS_DIRPATH"
+	artifactVerbosityArg    = "ARTIFACT_VERBOSITY"
+	snapshotsDirpathArg     = "SNAPSHOTS_DIRPATH"
+	swarmDockerHostsArg     = "SWARM_DOCKER_HOSTS"
+	executionIdArg          = "EXECUTION_ID"
+	testSuiteArg            = "TEST_SUITE"
+
+	// The separator between the Docker host URLs in the swarmDockerHostsArg environment variable
+	swarmDockerHostsSeparator = ","
 
 	// After we hard-timeout a test, how long we'll give the test to clean itself up (namely the Docker network & containers)
 	//  before we call it lost and continue on
@@ -86,6 +116,9 @@ type testExecutor struct {
 	// The mask of the subnet that the test should run in
 	subnetMask string
 
+	// The mask of the IPv6 subnet that the test's network should additionally have (empty for none)
+	ipv6SubnetMask string
+
 	// The name of the Docker image of the test controller to run
 	testControllerImageName string
 
@@ -100,6 +133,24 @@ type testExecutor struct {
 
 	// The actual test object to run
 	test testsuite.Test
+
+	// The live dashboard that the test's progress will be reported to (nil if the dashboard is disabled)
+	dashboard *dashboard.Dashboard
+
+	// The absolute path of the host directory that service artifacts will be exported to (empty if disabled)
+	artifactsDirpath string
+
+	// How much information about each service will be exported at the end of the test
+	artifactVerbosity networks.ArtifactVerbosity
+
+	// The absolute path of the host directory where service data snapshots are stored (empty if disabled)
+	snapshotsDirpath string
+
+	// The Docker host URLs of the other Docker Swarm nodes that the test's services will be spread across (empty if disabled)
+	swarmDockerHosts []string
+
+	// True if the test's Docker network is created without outbound connectivity
+	isEgressBlocked bool
 }
 
 /*
@@ -112,6 +163,8 @@ Args:
 	executionInstanceId: The UUID representing an execution of the user's test suite, to which this test execution belongs
 	dockerClient: The Docker client to use to manipulate the Docker engine
 	subnetMask: The subnet mask of the Docker network that has been spun up for this test
+	ipv6SubnetMask: The IPv6 subnet mask that the test's Docker network should additionally have, making it dual-stack
+		(empty for a single-stack network)
 	testControllerImageName: The name of the Docker image of the test controller that will orchestrate execution of this test
 	testControllerLogLevel: A string representing the log level that the test controller should set for itself; this string
 		should be meaningful to the user-defined controller code
@@ -119,27 +172,50 @@ Args:
 		controller image (as a method for the user to pass their own custom params between initializer and controller)
 	testName: The name of the test the executor should execute
 	test: The logic of the test being executed
+	dashboard: The live dashboard that the test's progress should be reported to, or nil if there's no dashboard
+	artifactsDirpath: The absolute path of the host directory under which this test's service artifacts will be exported,
+		or empty if artifacts shouldn't be exported
+	artifactVerbosity: How much information about each service to export
+	snapshotsDirpath: The absolute path of the host directory, shared by all tests, where service data snapshots are
+		stored, or empty if snapshots aren't available
+	swarmDockerHosts: The Docker host URLs of the other Docker Swarm nodes that the test's services will be spread across,
+		or empty if every service should run on this host
+	isEgressBlocked: True if the test's Docker network should be created without outbound connectivity
  */
 func newTestExecutor(
 			log *logrus.Logger,
 			executionInstanceId uuid.UUID,
 			dockerClient *client.Client,
 			subnetMask string,
+			ipv6SubnetMask string,
 			testControllerImageName string,
 			testControllerLogLevel string,
 			customTestControllerEnvVars map[string]string,
 			testName string,
-			test testsuite.Test) *testExecutor {
+			test testsuite.Test,
+			dashboard *dashboard.Dashboard,
+			artifactsDirpath string,
+			artifactVerbosity networks.ArtifactVerbosity,
+			snapshotsDirpath string,
+			swarmDockerHosts []string,
+			isEgressBlocked bool) *testExecutor {
 	return &testExecutor{
 		log:                         log,
 		executionInstanceId:         executionInstanceId,
 		dockerClient:                dockerClient,
 		subnetMask:                  subnetMask,
+		ipv6SubnetMask:              ipv6SubnetMask,
 		testControllerImageName:     testControllerImageName,
 		testControllerLogLevel:      testControllerLogLevel,
 		customTestControllerEnvVars: customTestControllerEnvVars,
 		testName:                    testName,
 		test:                        test,
+		dashboard:                   dashboard,
+		artifactsDirpath:            artifactsDirpath,
+		artifactVerbosity:           artifactVerbosity,
+		snapshotsDirpath:            snapshotsDirpath,
+		swarmDockerHosts:            swarmDockerHosts,
+		isEgressBlocked:             isEgressBlocked,
 	}
 }
 
@@ -219,10 +295,24 @@ func (executor testExecutor) runTestGoroutine(context context.Context) (bool, er
 	executor.log.Info("Creating Docker manager from environment settings...")
 	// NOTE: at this point, all Docker commands from here forward will be bound by the Context that we pass in here - we'll
 	//  only need to cancel this context once
-	dockerManager, err := docker.NewDockerManager(executor.log, executor.dockerClient)
+	dockerManagerLog := logging.NewTestLogEntry(executor.log, executor.executionInstanceId.String(), executor.testName)
+	dockerManager, err := docker.NewDockerManager(dockerManagerLog, executor.dockerClient)
 	if err != nil {
 		return false, stacktrace.Propagate(err, "An error occurred getting the Docker manager for test %v", executor.testName)
 	}
+	// Everything the test creates is labelled, so that it can be traced back to this test & cleaned up if the run crashes
+	dockerManager.UseResourceLabels(map[string]string{
+		docker.EXECUTION_ID_LABEL: executor.executionInstanceId.String(),
+		docker.TEST_SUITE_LABEL:   executor.testControllerImageName,
+		docker.TEST_NAME_LABEL:    executor.testName,
+	})
+	isWindowsEngine, err := dockerManager.DetectWindowsContainers(context)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred detecting whether the Docker engine runs Windows containers")
+	}
+	if isWindowsEngine {
+		executor.log.Info("The Docker engine runs Windows containers, so the test will use Windows containers")
+	}
 	executor.log.Info("Docker manager created successfully")
 
 	executor.log.Infof("Creating Docker network for test with subnet mask %v...", executor.subnetMask)
@@ -235,12 +325,19 @@ func (executor testExecutor) runTestGoroutine(context context.Context) (bool, er
 	if err != nil {
 		return false, stacktrace.Propagate(err, "An error occurred getting the gateway IP")
 	}
-	networkId, err := dockerManager.CreateNetwork(context, networkName, executor.subnetMask, gatewayIp)
+	networkId, err := executor.createNetwork(context, dockerManager, networkName, gatewayIp)
 	if err != nil {
 		return false, stacktrace.Propagate(err, "Error occurred creating Docker network %v for test %v", networkName, executor.testName)
 	}
+	// Deferred before the network removal so that these run after, once the test's containers have stopped
+	// NOTE: The test volume has the same name as the network (see runControllerContainer)
+	defer removeTestVolumesDeferredFunc(executor.log, dockerManager, networkName)
+	defer removeAdditionalNetworksDeferredFunc(executor.log, dockerManager, networkName)
 	defer removeNetworkDeferredFunc(executor.log, dockerManager, networkId)
 	executor.log.Infof("Docker network %v created successfully", networkId)
+	if executor.dashboard != nil {
+		executor.dashboard.SetTestRunning(executor.testName, networkId)
+	}
 
 	executor.log.Info("Running test controller...")
 	controllerIp, err := publicIpProvider.GetFreeIpAddr()
@@ -261,6 +358,41 @@ func (executor testExecutor) runTestGoroutine(context context.Context) (bool, er
 	return testPassed, nil
 }
 
+/*
+Helper function to create the test's Docker network, which is dual-stack if the test has an IPv6 subnet, or an overlay
+	network spanning the Docker Swarm if the test's services will be spread across the Swarm, and is internal (i.e. has no
+	outbound connectivity) if egress is blocked.
+*/
+func (executor testExecutor) createNetwork(
+			context context.Context,
+			manager *docker.DockerManager,
+			networkName string,
+			gatewayIp net.IP) (string, error) {
+	if len(executor.swarmDockerHosts) > 0 {
+		return manager.CreateOverlayNetwork(context, networkName, executor.subnetMask, gatewayIp, executor.isEgressBlocked)
+	}
+	if executor.ipv6SubnetMask == "" {
+		return manager.CreateNetwork(context, networkName, executor.subnetMask, gatewayIp, executor.isEgressBlocked)
+	}
+
+	ipv6IpProvider, err := networks.NewFreeIpAddrTracker(executor.log, executor.ipv6SubnetMask, map[string]bool{})
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Could not create the free IPv6 address tracker")
+	}
+	ipv6GatewayIp, err := ipv6IpProvider.GetFreeIpAddr()
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the IPv6 gateway IP")
+	}
+	return manager.CreateDualStackNetwork(
+		context,
+		networkName,
+		executor.subnetMask,
+		gatewayIp,
+		executor.ipv6SubnetMask,
+		ipv6GatewayIp,
+		executor.isEgressBlocked)
+}
+
 /*
 Helper function to run the controller container against the given test network.
 
@@ -285,19 +417,54 @@ func (executor testExecutor) runControllerContainer(
 
 	volumeName := uniqueTestIdentifier
 	executor.log.Debugf("Creating Docker volume %v which will be shared with the test network...", volumeName)
-	if err := manager.CreateVolume(context, volumeName); err != nil {
+	volumeLabels := map[string]string{
+		docker.TEST_VOLUME_LABEL: volumeName,
+	}
+	if err := manager.CreateVolume(context, volumeName, volumeLabels); err != nil {
 		return false, stacktrace.Propagate(err, "Error creating Docker volume to share amongst test nodes")
 	}
 	executor.log.Debugf("Docker volume %v created successfully", volumeName)
 
-	testControllerLogFilename := fmt.Sprintf("%v-controller-logs", uniqueTestIdentifier)
-	executor.log.Debugf("Creating temporary file with name %v to store controller logs...", testControllerLogFilename)
-	logTmpFile, err := ioutil.TempFile("", testControllerLogFilename)
+	testControllerLogDirname := fmt.Sprintf("%v-controller-logs", uniqueTestIdentifier)
+	executor.log.Debugf("Creating temporary directory with name %v to store controller logs...", testControllerLogDirname)
+	logTmpDirpath, err := ioutil.TempDir("", testControllerLogDirname)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "Could not create temporary directory to store log info for passing to test controller")
+	}
+	defer os.RemoveAll(logTmpDirpath) // We're responsible for removing the temporary directory we created
+	logTmpFilepath := filepath.Join(logTmpDirpath, controllerLogFilename)
+	logTmpFile, err := os.Create(logTmpFilepath)
 	if err != nil {
 		return false, stacktrace.Propagate(err, "Could not create tempfile to store log info for passing to test controller")
 	}
 	logTmpFile.Close()
-	executor.log.Debugf("Successfully created temporary file to store controller logs at path %v", logTmpFile.Name())
+	executor.log.Debugf("Successfully created temporary file to store controller logs at path %v", logTmpFilepath)
+
+	// Because the test controller will need to spin up new images, we need to bind-mount the host Docker engine into the test controller
+	dockerEnginePath := dockerSocketFilepath
+	if manager.IsUsingWindowsContainers() {
+		dockerEnginePath = docker.WINDOWS_DOCKER_ENGINE_PIPEPATH
+	}
+	bindMounts := map[string]string{
+		dockerEnginePath: dockerEnginePath,
+		logTmpDirpath:    controllerLogMountDirpath,
+	}
+
+	controllerArtifactsDirpath := ""
+	if executor.artifactsDirpath != "" {
+		testArtifactsDirpath := path.Join(executor.artifactsDirpath, executor.testName)
+		if err := os.MkdirAll(testArtifactsDirpath, artifactsDirPerms); err != nil {
+			return false, stacktrace.Propagate(err, "Could not create artifacts directory %v for the test", testArtifactsDirpath)
+		}
+		bindMounts[testArtifactsDirpath] = artifactsMountpoint
+		controllerArtifactsDirpath = artifactsMountpoint
+	}
+
+	controllerSnapshotsDirpath := ""
+	if executor.snapshotsDirpath != "" {
+		bindMounts[executor.snapshotsDirpath] = snapshotsMountpoint
+		controllerSnapshotsDirpath = snapshotsMountpoint
+	}
 
 	envVariables, err := generateTestControllerEnvVariables(
 		networkId,
@@ -307,18 +474,18 @@ func (executor testExecutor) runControllerContainer(
 		executor.testName,
 		executor.testControllerLogLevel,
 		volumeName,
+		controllerArtifactsDirpath,
+		executor.artifactVerbosity,
+		controllerSnapshotsDirpath,
+		executor.swarmDockerHosts,
+		executor.executionInstanceId.String(),
+		executor.testControllerImageName,
 		executor.customTestControllerEnvVars)
 	if err != nil {
 		return false, stacktrace.Propagate(err, "Failed to map test controller environment variables.")
 	}
 	executor.log.Debugf("Environment variables that are being passed to the controller: %v", envVariables)
 
-	bindMounts := map[string]string{
-		// Because the test controller will need to spin up new images, we need to bind-mount the host Docker engine into the test controller
-		"/var/run/docker.sock": "/var/run/docker.sock",
-		logTmpFile.Name():      controllerLogMountFilepath,
-	}
-
 	volumeMounts := map[string]string{
 		volumeName: testVolumeMountpoint,
 	}
@@ -332,7 +499,11 @@ func (executor testExecutor) runControllerContainer(
 		nil, // The controller image's CMD should be parameterized, so we don't specify a start command here
 		envVariables,
 		bindMounts,
-		volumeMounts)
+		volumeMounts,
+		docker.ContainerOptions{
+			// Named like the test's service containers, so the test's containers can be told apart in `docker ps`
+			Name: docker.SanitizeContainerName(uniqueTestIdentifier + controllerContainerNameSuffix),
+		})
 	if err != nil {
 		return false, stacktrace.Propagate(err, "Failed to run test controller container")
 	}
@@ -347,14 +518,13 @@ func (executor testExecutor) runControllerContainer(
 
 	// We open a new fp for reading because our original FP is only for writing
 	executor.log.Info("- - - - - - - - - - - - - - - - - - - CONTROLLER LOGS - - - - - - - - - - - - - - - - - -")
-	logReadFp, err := os.Open(logTmpFile.Name())
+	logReadFp, err := os.Open(logTmpFilepath)
 	if err != nil {
 		return false, stacktrace.Propagate(err, "Failed to open controller log file for reading")
 	}
 	io.Copy(executor.log.Out, logReadFp)
 	executor.log.Info("- - - - - - - - - - - - - - - - - - END CONTROLLER LOGS - - - - - - - - - - - - - - - - - -")
 	logReadFp.Close()
-	os.Remove(logTmpFile.Name()) // We're responsible for removing the tempfile we created
 
 	return exitCode == containerSuccessExitCode, nil
 }
@@ -379,6 +549,50 @@ func removeNetworkDeferredFunc(log *logrus.Logger, dockerManager *docker.DockerM
 	}
 }
 
+/*
+Helper function for making a best-effort attempt at removing the additional networks created for the test (which are
+	labelled with the test volume's name), logging any error states; intended to be run as a deferred function.
+*/
+func removeAdditionalNetworksDeferredFunc(log *logrus.Logger, dockerManager *docker.DockerManager, testVolumeName string) {
+	// As with the test network, we use the background context so that we try to clean up even if the test was cancelled
+	networkIds, err := dockerManager.ListNetworks(context.Background(), map[string]string{docker.TEST_VOLUME_LABEL: testVolumeName})
+	if err != nil {
+		log.Errorf("An error occurred listing the additional Docker networks of test volume %v:", testVolumeName)
+		log.Error(err.Error())
+		log.Error("NOTE: This means you will need to clean up the test's additional Docker networks manually!!")
+		return
+	}
+	for _, networkId := range networkIds {
+		removeNetworkDeferredFunc(log, dockerManager, networkId)
+	}
+}
+
+/*
+Helper function for making a best-effort attempt at removing the test volume and any other volumes created for the test
+	(which are all labelled with the test volume's name), logging any error states; intended to be run as a deferred
+	function.
+*/
+func removeTestVolumesDeferredFunc(log *logrus.Logger, dockerManager *docker.DockerManager, testVolumeName string) {
+	// As with the network, we use the background context so that we try to clean up even if the test was cancelled
+	volumeNames, err := dockerManager.ListVolumes(context.Background(), map[string]string{docker.TEST_VOLUME_LABEL: testVolumeName})
+	if err != nil {
+		log.Errorf("An error occurred listing the Docker volumes of test volume %v:", testVolumeName)
+		log.Error(err.Error())
+		log.Error("NOTE: This means you will need to clean up the test's Docker volumes manually!!")
+		return
+	}
+	for _, volumeName := range volumeNames {
+		log.Debugf("Attempting to remove Docker volume %v...", volumeName)
+		if err := dockerManager.RemoveVolume(context.Background(), volumeName, true); err != nil {
+			log.Errorf("An error occurred removing Docker volume %v:", volumeName)
+			log.Error(err.Error())
+			log.Error("NOTE: This means you will need to clean up the Docker volume manually!!")
+		} else {
+			log.Debugf("Docker volume %v successfully removed", volumeName)
+		}
+	}
+}
+
 /*
 NOTE: This is a separate function because it provides a nice documentation reference point, where we can say to users,
 "to see the latest special environment variables that will be passed to the test controller, see this function". Do not
@@ -394,6 +608,16 @@ Args:
 		initializer will not know what to do with this!)
 	testVolumeName: The name of the Docker volume that has been created for this particular test execution, and that the
 		test controller can share with the services that it spins up to read and write data to them
+	artifactsDirpath: The directory on the controller container that service artifacts should be exported to at the end
+		of the test, or empty if artifacts shouldn't be exported
+	artifactVerbosity: How much information about each service the controller should export
+	snapshotsDirpath: The directory on the controller container where service data snapshots are stored, or empty if
+		snapshots aren't available
+	swarmDockerHosts: The Docker host URLs of the other Docker Swarm nodes that the controller should spread services
+		across, or empty if every service should run on this host
+	executionId: The UUID of the test suite execution, which the controller labels the Docker resources it creates with
+	testSuiteName: The name of the test suite (its controller image), which the controller labels the Docker resources it
+		creates with
 	customEnvVars: A custom user-defined map from <env variable name> -> <env variable value> that will be set for test controller
 */
 func generateTestControllerEnvVariables(
@@ -404,17 +628,29 @@ func generateTestControllerEnvVariables(
 			testName string,
 			logLevel string,
 			testVolumeName string,
+			artifactsDirpath string,
+			artifactVerbosity networks.ArtifactVerbosity,
+			snapshotsDirpath string,
+			swarmDockerHosts []string,
+			executionId string,
+			testSuiteName string,
 			customEnvVars map[string]string) (map[string]string, error) {
 	standardVars := map[string]string{
 		testNameArg:             testName,
 		subnetMaskArg:           subnetMask,
 		networkIdArg:            networkId,
 		gatewayIpArg:            gatewayIp.String(),
-		logFilepathArg:          controllerLogMountFilepath,
+		logFilepathArg:          path.Join(controllerLogMountDirpath, controllerLogFilename),
 		logLevelArg:             logLevel,
 		testControllerIpArg:     controllerIpAddr.String(),
 		testVolumeArg:           testVolumeName,
 		testVolumeMountpointArg: testVolumeMountpoint,
+		artifactsDirpathArg:     artifactsDirpath,
+		artifactVerbosityArg:    string(artifactVerbosity),
+		snapshotsDirpathArg:     snapshotsDirpath,
+		swarmDockerHostsArg:     strings.Join(swarmDockerHosts, swarmDockerHostsSeparator),
+		executionIdArg:          executionId,
+		testSuiteArg:            testSuiteName,
 	}
 	for key, val := range customEnvVars {
 		if _, ok := standardVars[key]; ok {
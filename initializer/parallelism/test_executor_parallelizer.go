@@ -5,15 +5,29 @@ import (
 	"fmt"
 	"github.com/docker/distribution/uuid"
 	"github.com/docker/docker/client"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/initializer/dashboard"
+	"github.com/kurtosis-tech/kurtosis/initializer/events"
+	"github.com/kurtosis-tech/kurtosis/initializer/timings"
+	"github.com/kurtosis-tech/kurtosis/initializer/uploads"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/signal"
+	"path"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
+)
+
+const (
+	// The name of the file in each test's artifacts directory that the test's log is written to, when artifacts are uploaded
+	TEST_LOG_ARTIFACT_FILENAME = "test.log"
+
+	testLogArtifactPerms = 0644
 )
 
 /*
@@ -37,6 +51,39 @@ type TestExecutorParallelizer struct {
 
 	// The number of tests to run in parallel
 	parallelism                 uint
+
+	// The live dashboard that tests will report their progress to (nil if the dashboard is disabled)
+	dashboard                   *dashboard.Dashboard
+
+	// The stream that tests' starts & outcomes will be emitted to as machine-readable events (nil if disabled)
+	eventStream                 *events.EventStream
+
+	// How each test's output is marked up for the CI system's UI
+	ciLogFormat                 CiLogFormat
+
+	// The absolute path of the host directory that service artifacts will be exported to (empty if disabled)
+	artifactsDirpath            string
+
+	// How much information about each service will be exported at the end of each test
+	artifactVerbosity           networks.ArtifactVerbosity
+
+	// The absolute path of the host directory where service data snapshots are stored (empty if disabled)
+	snapshotsDirpath            string
+
+	// The Docker host URLs of the other Docker Swarm nodes that tests' services will be spread across (empty if disabled)
+	swarmDockerHosts            []string
+
+	// True if tests' Docker networks are created without outbound connectivity
+	isEgressBlocked             bool
+
+	// Where tests' durations are read from to schedule the longest tests first, and recorded to afterwards (nil if disabled)
+	timingStore                 timings.TimingStore
+
+	// Where each test's artifacts are uploaded to once the test finishes (nil if disabled)
+	artifactUploader            uploads.ArtifactUploader
+
+	// How messages logged to the system-level logger while the tests run are captured
+	systemLogCaptureOptions     SystemLogCaptureOptions
 }
 
 /*
@@ -50,6 +97,23 @@ Args:
 	customTestControllerEnvVars: A custom user-defined map from <env variable name> -> <env variable value> that will be
 		passed via Docker environment variables to the test controller
 	parallelism: The number of tests to run concurrently
+	dashboard: The live dashboard that tests will report their progress to, or nil if no dashboard should be shown
+	eventStream: The stream that each test's start & outcome will be emitted to, or nil if no events should be emitted
+	ciLogFormat: How each test's output should be marked up, so the output is navigable in the CI system's UI
+	artifactsDirpath: The absolute path of the host directory that each test's service artifacts will be exported
+		to, or empty if artifacts shouldn't be exported
+	artifactVerbosity: How much information about each service to export
+	snapshotsDirpath: The absolute path of the host directory, shared by all tests, where service data snapshots are
+		stored, or empty if snapshots aren't available
+	swarmDockerHosts: The Docker host URLs of the other Docker Swarm nodes that each test's services will be spread across,
+		or empty if every service should run on this host
+	isEgressBlocked: True if each test's Docker network should be created without outbound connectivity, so that only the
+		external endpoints each test allows (see networks.ServiceNetworkBuilder.AllowEgressTo) are reachable
+	timingStore: The store of tests' durations from earlier runs, which are used to start the longest tests first and
+		which this run's durations are recorded to, or nil if tests should be started in no particular order
+	artifactUploader: The uploader that, once each test finishes, the test's log & service artifacts are uploaded with
+		(with keys under "<execution ID>/<test name>/"), so that the summary can link to them; nil if artifacts shouldn't
+		be uploaded. Requires the artifacts directory.
  */
 func NewTestExecutorParallelizer(
 			executionId uuid.UUID,
@@ -57,7 +121,17 @@ func NewTestExecutorParallelizer(
 			testControllerImageName string,
 			testControllerLogLevel string,
 			customTestControllerEnvVars map[string]string,
-			parallelism uint) *TestExecutorParallelizer {
+			parallelism uint,
+			dashboard *dashboard.Dashboard,
+			eventStream *events.EventStream,
+			ciLogFormat CiLogFormat,
+			artifactsDirpath string,
+			artifactVerbosity networks.ArtifactVerbosity,
+			snapshotsDirpath string,
+			swarmDockerHosts []string,
+			isEgressBlocked bool,
+			timingStore timings.TimingStore,
+			artifactUploader uploads.ArtifactUploader) *TestExecutorParallelizer {
 	return &TestExecutorParallelizer{
 		executionId:                 executionId,
 		dockerClient:                dockerClient,
@@ -65,9 +139,28 @@ func NewTestExecutorParallelizer(
 		testControllerLogLevel:      testControllerLogLevel,
 		customTestControllerEnvVars: customTestControllerEnvVars,
 		parallelism:                 parallelism,
+		dashboard:                   dashboard,
+		eventStream:                 eventStream,
+		ciLogFormat:                 ciLogFormat,
+		artifactsDirpath:            artifactsDirpath,
+		artifactVerbosity:           artifactVerbosity,
+		snapshotsDirpath:            snapshotsDirpath,
+		swarmDockerHosts:            swarmDockerHosts,
+		isEgressBlocked:             isEgressBlocked,
+		timingStore:                 timingStore,
+		artifactUploader:            artifactUploader,
+		systemLogCaptureOptions:     SystemLogCaptureOptions{},
 	}
 }
 
+/*
+Sets how messages logged to the system-level logger while the tests run are captured, e.g. to keep more of them or to
+	pass known noisy callers' messages through.
+ */
+func (executor *TestExecutorParallelizer) UseSystemLogCaptureOptions(options SystemLogCaptureOptions) {
+	executor.systemLogCaptureOptions = options
+}
+
 /*
 Runs the given tests in parallel, printing:
 1) the output of tests as they finish
@@ -92,26 +185,39 @@ func (executor TestExecutorParallelizer) RunInParallelAndPrintResults(allTestPar
 		sig, ok := <-sigs
 		// signal channel was closed with no syscall signal
 		if !ok { return }
-		fmt.Printf("\nReceived signal: %v. Cleaning up tests and exiting gracefully...\n", sig)
+		if executor.eventStream != nil {
+			executor.eventStream.EmitRunInterrupted(sig.String())
+		}
+		// When the event stream has an output, it's the run's only output
+		if executor.eventStream == nil || !executor.eventStream.HasOutput() {
+			fmt.Printf("\nReceived signal: %v. Cleaning up tests and exiting gracefully...\n", sig)
+		}
 		cancelFunc()
 	}()
 	// These need to be buffered else sending to the channel will be blocking
 	testParamsChan := make(chan ParallelTestParams, len(allTestParams))
 
 	logrus.Info("Loading test params into work queue...")
-	for _, testParams := range allTestParams {
-		testParamsChan <- testParams
+	for _, testName := range executor.getTestOrder(allTestParams) {
+		testParamsChan <- allTestParams[testName]
 	}
 	close(testParamsChan) // We close the channel so that when all params are consumed, the worker threads won't block on waiting for more params
 	logrus.Info("All test params loaded into work queue")
 
-	outputManager := newParallelTestOutputManager()
+	outputManager := newParallelTestOutputManager(executor.ciLogFormat, executor.systemLogCaptureOptions)
 
 	logrus.Infof("Launching %v tests with parallelism %v...", len(allTestParams), executor.parallelism)
 
-	executor.disableSystemLogAndRunTestThreads(&ctx, outputManager, testParamsChan)
+	testDurations := newTestDurationRecorder()
+	executor.disableSystemLogAndRunTestThreads(&ctx, outputManager, testParamsChan, testDurations)
 
 	logrus.Info("All tests exited")
+	// An interrupted run's durations are cut short, so they'd throw off later runs' scheduling
+	if executor.timingStore != nil && ctx.Err() == nil {
+		if err := executor.timingStore.RecordDurations(testDurations.getDurations()); err != nil {
+			logrus.Warnf("Couldn't record the tests' durations for scheduling later runs: %v", err)
+		}
+	}
 
 	outputManager.printSummary()
 	return outputManager.getAllTestsPassed()
@@ -121,7 +227,8 @@ func (executor TestExecutorParallelizer) RunInParallelAndPrintResults(allTestPar
 func (executor TestExecutorParallelizer) disableSystemLogAndRunTestThreads(
 		parentContext *context.Context,
 		outputManager *ParallelTestOutputManager,
-		testParamsChan chan ParallelTestParams) {
+		testParamsChan chan ParallelTestParams,
+		testDurations *testDurationRecorder) {
 	/*
     Because each test needs to have its logs written to an independent file to avoid getting logs all mixed up, we need to make
     sure that all code below this point uses the per-test logger rather than the systemwide logger. However, it's very difficult for
@@ -131,10 +238,17 @@ func (executor TestExecutorParallelizer) disableSystemLogAndRunTestThreads(
 	outputManager.startInterceptingStdLogger()
 	defer outputManager.stopInterceptingStdLogger()
 
+	if executor.dashboard != nil {
+		if err := executor.dashboard.Start(); err != nil {
+			outputManager.sideChannelLogger.Warnf("The test dashboard couldn't be started: %v", err)
+		}
+		defer executor.dashboard.Stop()
+	}
+
 	var waitGroup sync.WaitGroup
 	for i := uint(0); i < executor.parallelism; i++ {
 		waitGroup.Add(1)
-		go executor.runTestWorkerGoroutine(parentContext, outputManager, &waitGroup, testParamsChan)
+		go executor.runTestWorkerGoroutine(parentContext, outputManager, &waitGroup, testParamsChan, testDurations)
 	}
 	waitGroup.Wait()
 }
@@ -147,7 +261,8 @@ func (executor TestExecutorParallelizer) runTestWorkerGoroutine(
 			parentContext *context.Context,
 			outputManager *ParallelTestOutputManager,
 			waitGroup *sync.WaitGroup,
-			testParamsChan chan ParallelTestParams) {
+			testParamsChan chan ParallelTestParams,
+			testDurations *testDurationRecorder) {
 	// IMPORTANT: make sure that we mark a thread as done!
 	defer waitGroup.Done()
 
@@ -175,15 +290,42 @@ func (executor TestExecutorParallelizer) runTestWorkerGoroutine(
 			executor.executionId,
 			executor.dockerClient,
 			testParams.SubnetMask,
+			testParams.Ipv6SubnetMask,
 			executor.testControllerImageName,
 			executor.testControllerLogLevel,
 			executor.customTestControllerEnvVars,
 			testName,
-			testParams.Test)
+			testParams.Test,
+			executor.dashboard,
+			executor.artifactsDirpath,
+			executor.artifactVerbosity,
+			executor.snapshotsDirpath,
+			executor.swarmDockerHosts,
+			executor.isEgressBlocked)
 
 
+		if executor.eventStream != nil {
+			executor.eventStream.EmitTestStarted(testName)
+		}
+		testStartTime := time.Now()
 		passed, executionErr := testExecutor.runTest(parentContext)
+		testDuration := time.Since(testStartTime)
 		writingTempFp.Close() // Close to flush out anything remaining in the buffer
+		// Tests that errored may have stopped early, so only the durations of tests that ran to completion are kept
+		if executionErr == nil {
+			testDurations.record(testName, testDuration)
+		}
+		status := string(getTestStatusFromResult(executionErr, passed))
+		if executor.dashboard != nil {
+			executor.dashboard.SetTestFinished(testName, status)
+		}
+		if executor.eventStream != nil {
+			executor.eventStream.EmitTestFinished(testName, status, executionErr, testDuration)
+		}
+		if executor.artifactUploader != nil && executor.artifactsDirpath != "" {
+			artifactUrls, err := executor.uploadTestArtifacts(testName, writingTempFp.Name())
+			outputManager.recordArtifactUpload(testName, artifactUrls, err)
+		}
 
 		// Create a new FP to read the logfile from the start
 		var testOutputReader io.Reader
@@ -198,3 +340,84 @@ func (executor TestExecutorParallelizer) runTestWorkerGoroutine(
 		outputManager.logTestOutput(testName, executionErr, passed, testOutputReader)
 	}
 }
+
+/*
+Uploads the given test's log & service artifacts with the artifact uploader.
+
+Returns:
+	Mapping of the artifact's filepath, relative to the test's artifacts directory -> URL of the uploaded artifact
+ */
+func (executor TestExecutorParallelizer) uploadTestArtifacts(testName string, testLogFilepath string) (map[string]string, error) {
+	testArtifactsDirpath := path.Join(executor.artifactsDirpath, testName)
+	// The test's log only otherwise goes to STDOUT, which CI machines with ephemeral disks won't keep either
+	if err := os.MkdirAll(testArtifactsDirpath, artifactsDirPerms); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating artifacts directory %v", testArtifactsDirpath)
+	}
+	testLogContents, err := ioutil.ReadFile(testLogFilepath)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading the test's log")
+	}
+	if err := ioutil.WriteFile(path.Join(testArtifactsDirpath, TEST_LOG_ARTIFACT_FILENAME), testLogContents, testLogArtifactPerms); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred writing the test's log to its artifacts directory")
+	}
+	artifactUrls, err := uploads.UploadDirectory(executor.artifactUploader, testArtifactsDirpath, path.Join(executor.executionId.String(), testName))
+	if err != nil {
+		return artifactUrls, stacktrace.Propagate(err, "An error occurred uploading the test's artifacts")
+	}
+	return artifactUrls, nil
+}
+
+/*
+Gets the order to start the given tests in: longest-first according to the timing store's durations (see
+	timings.OrderLongestFirst), or by name if there's no timing store or its durations can't be read.
+ */
+func (executor TestExecutorParallelizer) getTestOrder(allTestParams map[string]ParallelTestParams) []string {
+	testNames := make(map[string]bool)
+	for testName, _ := range allTestParams {
+		testNames[testName] = true
+	}
+	recordedDurations := map[string]time.Duration{}
+	if executor.timingStore != nil {
+		durations, err := executor.timingStore.GetDurations()
+		if err != nil {
+			logrus.Warnf("Couldn't get the tests' durations from earlier runs, so tests won't be started longest-first: %v", err)
+		} else {
+			recordedDurations = durations
+		}
+	}
+	return timings.OrderLongestFirst(timings.EstimateDurations(testNames, recordedDurations))
+}
+
+/*
+Collects the durations of the tests that the worker goroutines run.
+
+NOTE: This is thread-safe!
+ */
+type testDurationRecorder struct {
+	mutex *sync.Mutex
+
+	durations map[string]time.Duration
+}
+
+func newTestDurationRecorder() *testDurationRecorder {
+	return &testDurationRecorder{
+		mutex:     &sync.Mutex{},
+		durations: make(map[string]time.Duration),
+	}
+}
+
+func (recorder *testDurationRecorder) record(testName string, duration time.Duration) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.durations[testName] = duration
+}
+
+func (recorder *testDurationRecorder) getDurations() map[string]time.Duration {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	result := make(map[string]time.Duration)
+	for testName, duration := range recorder.durations {
+		result[testName] = duration
+	}
+	return result
+}
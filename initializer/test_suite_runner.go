@@ -1,25 +1,44 @@
 package initializer
 
 import (
-	"encoding/binary"
-	"fmt"
+	"context"
 	"github.com/docker/distribution/uuid"
 	"github.com/docker/docker/client"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
 	"github.com/kurtosis-tech/kurtosis/commons/testsuite"
+	"github.com/kurtosis-tech/kurtosis/initializer/dashboard"
+	"github.com/kurtosis-tech/kurtosis/initializer/events"
+	"github.com/kurtosis-tech/kurtosis/initializer/notifications"
 	"github.com/kurtosis-tech/kurtosis/initializer/parallelism"
+	"github.com/kurtosis-tech/kurtosis/initializer/timings"
+	"github.com/kurtosis-tech/kurtosis/initializer/uploads"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
-	"math"
-	"net"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
 )
 
 // =============================== Test Suite Runner =========================================
 const (
-	// This is the IP address that the first Docker subnet will be doled out from, with subsequent Docker networks doled out with
-	//  increasing IPs corresponding to the NETWORK_WIDTH_BITS
-	SUBNET_START_ADDR = "172.23.0.0"
+	// The range of IPs that test networks' subnets are carved out of, if the user doesn't specify one
+	DEFAULT_SUPERNET_CIDR = "172.23.0.0/16"
 
-	BITS_IN_IP4_ADDR = 32
+	// The number of bits of width of each test network's IPv6 subnet, which gives every test a standard /64
+	IPV6_SUBNET_WIDTH_BITS = 64
+
+	ARTIFACTS_DIR_PERMS = 0755
+
+	SNAPSHOTS_DIR_PERMS = 0755
+
+	// The value of the registry mirror arg that makes the runner start its own registry mirror on this host (see
+	//  DockerManager.StartLocalRegistryMirror)
+	LOCAL_REGISTRY_MIRROR = "local"
 )
 
 /*
@@ -43,6 +62,103 @@ type TestSuiteRunner struct {
 	// The number of bits in a test network's subnet mask, such that 2 ^ this_value will be the maximum number of allowed
 	//  services in any given test network
 	networkWidthBits uint32
+
+	// The settings that tests are run with
+	options TestSuiteRunnerOptions
+}
+
+/*
+Optional settings for TestSuiteRunners. The zero value runs every service of a test on this host, in a network carved out
+	of DEFAULT_SUPERNET_CIDR with outbound connectivity, and reports the run to STDOUT without exporting anything.
+ */
+type TestSuiteRunnerOptions struct {
+	// The CIDR that each test network's subnet will be carved out of, avoiding the subnets of any Docker networks that
+	//  already exist on the host (DEFAULT_SUPERNET_CIDR if empty). This may be an IPv6 CIDR, for IPv6-only test networks.
+	SupernetCidr string
+
+	// An IPv6 CIDR that each test network will additionally get a /64 subnet from, making the test networks dual-stack so
+	//  that services' IPv6 code paths can be exercised (empty for single-stack networks)
+	Ipv6SupernetCidr string
+
+	// Whether to draw a live dashboard of each test's services to STDERR while the tests run (useful for local
+	//  debugging, with STDOUT redirected to a file so the two don't interleave)
+	ShowDashboard bool
+
+	// The directory where, at the end of each test, every service's STDOUT/STDERR (and, depending on the verbosity,
+	//  container inspect JSON) will be written under <test name>/<service ID>/; empty to disable
+	ArtifactsDirpath string
+
+	// How much information about each service to export to the artifacts directory
+	ArtifactVerbosity networks.ArtifactVerbosity
+
+	// The directory where service data snapshots (see ServiceNetwork.SnapshotServiceData) will be stored, which should be
+	//  kept between runs so later runs can start from earlier runs' snapshots; empty to disable
+	SnapshotsDirpath string
+
+	// The Docker host URLs (e.g. "tcp://10.0.0.5:2376") of the other nodes in the Docker Swarm that this host's Docker
+	//  engine is a manager of, which must be reachable from the test controller. If non-empty, each test network is
+	//  created as an overlay network spanning the Swarm and the test's services are spread across these hosts as well as
+	//  this one, for topologies too large for a single machine. Empty to run every service on this host.
+	SwarmDockerHosts []string
+
+	// The host (e.g. "mirror.internal:5000") of a pull-through registry mirror of Docker Hub that the images used by the
+	//  tests should be pulled through, for CI machines behind slow links or rate-limited by Docker Hub; or
+	//  LOCAL_REGISTRY_MIRROR to start (or reuse) a mirror on this host, whose cache is kept between runs. Empty to pull
+	//  images from their registries directly.
+	// NOTE: Only the images pulled before the tests start (every configured service image, and the controller image) go
+	//  through the mirror.
+	RegistryMirror string
+
+	// The TLS material & verification settings for connecting to the Docker engine (e.g. a remote engine at DOCKER_HOST),
+	//  for when they can't come from the DOCKER_CERT_PATH & DOCKER_TLS_VERIFY environment variables; nil to use the
+	//  environment's
+	DockerTlsOptions *docker.TlsOptions
+
+	// True if each test's Docker network should be created without outbound connectivity (e.g. to the internet), so that
+	//  tests prove their services don't depend on external endpoints other than those the test explicitly allows (see
+	//  networks.ServiceNetworkBuilder.AllowEgressTo)
+	IsEgressBlocked bool
+
+	// If non-nil, all human-readable output is suppressed & the run is instead reported to this output as a stream of
+	//  newline-delimited JSON events (see the events package), ending with a RUN_FINISHED event holding the result
+	//  document, for wrapping by other tools. Can't be combined with the dashboard.
+	EventsOutput io.Writer
+
+	// How each test's output is marked up so that the suite's output is navigable in a CI system's UI, e.g. as GitHub
+	//  Actions collapsible groups with error annotations for failures; empty to detect the CI system from the
+	//  environment (see parallelism.DetectCiLogFormat)
+	CiLogFormat parallelism.CiLogFormat
+
+	// The store that each test's duration is recorded to, so that later runs start their longest tests first & finish
+	//  sooner (see the timings package); nil to start tests in no particular order & not record durations
+	TimingStore timings.TimingStore
+
+	// The notifiers (e.g. a notifications.WebhookNotifier) that are given the run's summary once it's finished, so that
+	//  e.g. nightly suites can report failures to a chat channel. A notifier failing doesn't change the run's result;
+	//  it's only logged.
+	Notifiers []notifications.Notifier
+
+	// The uploader (e.g. an uploads.HttpPutUploader) that each test's log & service artifacts are uploaded to object
+	//  storage with once the test finishes, under "<execution ID>/<test name>/", with the uploaded artifacts' URLs
+	//  printed in the summary; for CI machines with ephemeral disks. Requires the artifacts directory; nil to not upload
+	//  artifacts.
+	ArtifactUploader uploads.ArtifactUploader
+
+	// The file that a JUnit XML report of the run, with a test case per test (in a test suite named after the controller
+	//  image), is written to once the run is finished, for CI systems that display test results test-by-test; empty to
+	//  not write a report
+	JunitReportFilepath string
+
+	// If non-zero, the containers, networks, & volumes that Kurtosis created more than this long ago (e.g. left behind by
+	//  an earlier run whose initializer crashed) are removed before the tests start (see
+	//  docker.DockerManager.CleanUpOrphans). This should be longer than any run sharing the Docker engine takes, since
+	//  their resources would be removed from under them otherwise. A failure to clean up is only logged.
+	OrphanMaxAge time.Duration
+
+	// How messages logged to the system-level logger while the tests run (which would otherwise be interleaved with the
+	//  tests' output) are captured & then printed in the output of the next test to finish, e.g. how many are kept &
+	//  which noisy callers' packages are passed through instead; the zero value for the defaults
+	SystemLogCaptureOptions parallelism.SystemLogCaptureOptions
 }
 
 /*
@@ -55,19 +171,22 @@ Args:
 		to parse this, so this should be meaningful to the controller image)
 	networkWidthBits: Each test will get a Docker network with a number of available IP addresses = 2^network_width_bits.
 		This parameter should be set high enough so that each test can fit all the services they want.
+	options: The optional settings that the tests are run with
  */
 func NewTestSuiteRunner(
 			testSuite testsuite.TestSuite,
 			testControllerImageName string,
 			testControllerLogLevel string,
 			testControllerEnvVars map[string]string,
-			networkWidthBits uint32) *TestSuiteRunner {
+			networkWidthBits uint32,
+			options TestSuiteRunnerOptions) *TestSuiteRunner {
 	return &TestSuiteRunner{
 		testSuite:                   testSuite,
 		testControllerImageName:     testControllerImageName,
 		testControllerLogLevel:      testControllerLogLevel,
 		customTestControllerEnvVars: testControllerEnvVars,
 		networkWidthBits:            networkWidthBits,
+		options:                     options,
 	}
 }
 
@@ -84,6 +203,70 @@ Returns:
 		being retrieved. If this is non-nil, the allTestsPassed value is undefined!
  */
 func (runner TestSuiteRunner) RunTests(testNamesToRun map[string]bool, testParallelism uint) (allTestsPassed bool, executionErr error) {
+	executionInstanceId := uuid.Generate()
+	if runner.options.EventsOutput == nil && len(runner.options.Notifiers) == 0 && runner.options.JunitReportFilepath == "" {
+		return runner.runTests(executionInstanceId, testNamesToRun, testParallelism, nil)
+	}
+
+	// Without an events output, the stream only collects the tests' outcomes for the notifiers & the JUnit report
+	eventStream := events.NewEventStream(runner.options.EventsOutput)
+	if runner.options.EventsOutput != nil {
+		if runner.options.ShowDashboard {
+			return false, stacktrace.NewError("The dashboard can't be shown when the run is reported as machine-readable events")
+		}
+		// Only the events should be output, so the human-readable logging is discarded
+		originalLogOutput := logrus.StandardLogger().Out
+		logrus.SetOutput(ioutil.Discard)
+		defer logrus.SetOutput(originalLogOutput)
+	}
+	allTestsPassed, executionErr = runner.runTests(executionInstanceId, testNamesToRun, testParallelism, eventStream)
+	eventStream.EmitRunFinished(executionInstanceId.String(), allTestsPassed, executionErr)
+
+	if runner.options.JunitReportFilepath != "" {
+		if err := runner.writeJunitReport(eventStream.GetRunResult(allTestsPassed, executionErr), executionErr); err != nil {
+			// CI would otherwise show a stale report, or none, so the run fails
+			if executionErr == nil {
+				executionErr = stacktrace.Propagate(err, "An error occurred writing the JUnit report")
+			} else {
+				logrus.Errorf("An error occurred writing the JUnit report: %v", err)
+			}
+		}
+	}
+
+	summary := notifications.NewRunSummary(
+		executionInstanceId.String(),
+		eventStream.GetRunResult(allTestsPassed, executionErr),
+		executionErr)
+	for _, notifier := range runner.options.Notifiers {
+		if err := notifier.Notify(summary); err != nil {
+			logrus.Warnf("An error occurred notifying of the run's result: %v", err)
+		}
+	}
+	return allTestsPassed, executionErr
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+// Writes the given result document to the runner's JUnit report file
+func (runner TestSuiteRunner) writeJunitReport(result *events.RunResult, runErr error) error {
+	reportFp, err := os.Create(runner.options.JunitReportFilepath)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating JUnit report file %v", runner.options.JunitReportFilepath)
+	}
+	defer reportFp.Close()
+	if err := events.WriteJunitReport(runner.testControllerImageName, result, runErr, reportFp); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing JUnit report file %v", runner.options.JunitReportFilepath)
+	}
+	return nil
+}
+
+/*
+Runs the tests as described in RunTests, emitting the tests' starts & outcomes to the given event stream (if non-nil)
+ */
+func (runner TestSuiteRunner) runTests(
+			executionInstanceId uuid.UUID,
+			testNamesToRun map[string]bool,
+			testParallelism uint,
+			eventStream *events.EventStream) (bool, error) {
 	allTests := runner.testSuite.GetTests()
 
 	// If the user doesn't specify any test names to run, run all of them
@@ -104,66 +287,256 @@ func (runner TestSuiteRunner) RunTests(testNamesToRun map[string]bool, testParal
 		testsToRun[testName] = test
 	}
 
-	executionInstanceId := uuid.Generate()
-	testParams, err := buildTestParams(executionInstanceId, testsToRun, runner.networkWidthBits)
-	if err != nil {
-		return false, stacktrace.Propagate(err, "An error occurred building the test params map")
+	if runner.options.ArtifactUploader != nil && runner.options.ArtifactsDirpath == "" {
+		return false, stacktrace.NewError("Artifacts can only be uploaded when an artifacts directory is given")
+	}
+
+	if len(runner.options.SwarmDockerHosts) > 0 && runner.options.Ipv6SupernetCidr != "" {
+		return false, stacktrace.NewError("Dual-stack test networks aren't supported when spreading tests across a Docker Swarm")
+	}
+
+	var err error
+	// Docker requires bind-mounted paths to be absolute
+	absArtifactsDirpath := ""
+	if runner.options.ArtifactsDirpath != "" {
+		absArtifactsDirpath, err = filepath.Abs(runner.options.ArtifactsDirpath)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred getting the absolute path of artifacts directory %v", runner.options.ArtifactsDirpath)
+		}
+	}
+
+	absSnapshotsDirpath := ""
+	if runner.options.SnapshotsDirpath != "" {
+		absSnapshotsDirpath, err = filepath.Abs(runner.options.SnapshotsDirpath)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred getting the absolute path of snapshots directory %v", runner.options.SnapshotsDirpath)
+		}
+		if err := os.MkdirAll(absSnapshotsDirpath, SNAPSHOTS_DIR_PERMS); err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred creating snapshots directory %v", absSnapshotsDirpath)
+		}
+	}
+
+	dockerClientOpts := []client.Opt{
+		client.FromEnv,
+		client.WithAPIVersionNegotiation(),
+	}
+	if runner.options.DockerTlsOptions != nil {
+		dockerClientOpts = append(dockerClientOpts, docker.WithTls(*runner.options.DockerTlsOptions))
+	}
+	dockerClientOpts = append(
+		dockerClientOpts,
+		docker.WithRateLimiting(docker.DEFAULT_MAX_CONCURRENT_DOCKER_REQUESTS, docker.DEFAULT_MAX_DOCKER_REQUEST_RETRIES))
+	if absArtifactsDirpath != "" && runner.options.ArtifactVerbosity == networks.ALL_ARTIFACTS {
+		if err := os.MkdirAll(absArtifactsDirpath, ARTIFACTS_DIR_PERMS); err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred creating artifacts directory %v", absArtifactsDirpath)
+		}
+		auditLogFilepath := path.Join(absArtifactsDirpath, networks.DOCKER_AUDIT_LOG_FILENAME)
+		auditLogFp, err := os.Create(auditLogFilepath)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred creating Docker audit log file %v", auditLogFilepath)
+		}
+		defer auditLogFp.Close()
+		dockerClientOpts = append(dockerClientOpts, docker.WithAuditLog(docker.NewAuditLog(auditLogFp)))
 	}
 
 	// Initialize a Docker client
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	dockerClient, err := client.NewClientWithOpts(dockerClientOpts...)
 	if err != nil {
 		return false, stacktrace.Propagate(err,"Failed to initialize Docker client from environment.")
 	}
 
+	supernetCidr := runner.options.SupernetCidr
+	if supernetCidr == "" {
+		supernetCidr = DEFAULT_SUPERNET_CIDR
+	}
+	subnetAllocator, err := networks.NewSubnetAllocator(supernetCidr, runner.networkWidthBits)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred creating the allocator for test network subnets")
+	}
+	dockerManager, err := docker.NewDockerManager(logrus.NewEntry(logrus.StandardLogger()), dockerClient)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred creating the Docker manager")
+	}
+	// An unsuitable Docker environment should fail the run here, rather than with Docker errors midway through the tests
+	if err := dockerManager.RunPreflightChecks(context.Background(), docker.DEFAULT_PREFLIGHT_REQUIREMENTS); err != nil {
+		return false, stacktrace.Propagate(err, "The Docker environment failed the preflight checks")
+	}
+	// Orphans are cleaned up before the existing subnets are looked at, so their networks' subnets are free for this run
+	if runner.options.OrphanMaxAge > 0 {
+		removedOrphans, err := dockerManager.CleanUpOrphans(context.Background(), runner.options.OrphanMaxAge)
+		if err != nil {
+			logrus.Errorf("An error occurred cleaning up the Docker resources left behind by earlier runs: %v", err)
+		}
+		logrus.Infof(
+			"Cleaned up %v containers, %v networks, & %v volumes left behind by earlier runs",
+			len(removedOrphans.ContainerIds),
+			len(removedOrphans.NetworkIds),
+			len(removedOrphans.VolumeNames))
+	}
+	existingSubnets, err := dockerManager.GetNetworkSubnets(context.Background())
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred getting the subnets of existing Docker networks")
+	}
+	var ipv6SubnetAllocator *networks.SubnetAllocator = nil
+	if runner.options.Ipv6SupernetCidr != "" {
+		ipv6SubnetAllocator, err = networks.NewSubnetAllocator(runner.options.Ipv6SupernetCidr, IPV6_SUBNET_WIDTH_BITS)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred creating the allocator for test network IPv6 subnets")
+		}
+	}
+	for _, existingSubnet := range existingSubnets {
+		subnetAllocator.ReserveOverlapping(existingSubnet)
+		if ipv6SubnetAllocator != nil {
+			ipv6SubnetAllocator.ReserveOverlapping(existingSubnet)
+		}
+	}
+
+	if runner.options.RegistryMirror != "" {
+		registryMirror := runner.options.RegistryMirror
+		if registryMirror == LOCAL_REGISTRY_MIRROR {
+			registryMirror, err = dockerManager.StartLocalRegistryMirror(context.Background())
+			if err != nil {
+				return false, stacktrace.Propagate(err, "An error occurred starting the local registry mirror")
+			}
+		}
+		logrus.Infof("Pulling Docker Hub images through registry mirror %v", registryMirror)
+		dockerManager.UseRegistryMirror(registryMirror)
+	}
+
+	// Tests running in parallel would otherwise race to pull the same images, so we pull them all once up front
+	suiteImages := getTestImages(testsToRun)
+	suiteImages[runner.testControllerImageName] = true
+	logrus.Infof("Pre-warming the image cache with %v images used by the tests...", len(suiteImages))
+	if err := docker.PullImagesInParallel(context.Background(), []docker.ContainerManager{dockerManager}, suiteImages); err != nil {
+		// Each test's controller will pull (and report errors for) whatever images are still missing
+		logrus.Warnf("Couldn't pre-warm the image cache; tests will pull missing images themselves: %v", err)
+	} else {
+		logrus.Info("Successfully pre-warmed the image cache")
+	}
+
+	testParams, err := buildTestParams(executionInstanceId, testsToRun, subnetAllocator, ipv6SubnetAllocator)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "An error occurred building the test params map")
+	}
+
+	var testDashboard *dashboard.Dashboard = nil
+	if runner.options.ShowDashboard {
+		testDashboard, err = dashboard.NewDashboard(dockerClient, os.Stderr)
+		if err != nil {
+			return false, stacktrace.Propagate(err, "An error occurred creating the test dashboard")
+		}
+	}
+
+	ciLogFormat := runner.options.CiLogFormat
+	if ciLogFormat == "" {
+		ciLogFormat = parallelism.DetectCiLogFormat()
+	}
 	testExecutor := parallelism.NewTestExecutorParallelizer(
 		executionInstanceId,
 		dockerClient,
 		runner.testControllerImageName,
 		runner.testControllerLogLevel,
 		runner.customTestControllerEnvVars,
-		testParallelism)
+		testParallelism,
+		testDashboard,
+		eventStream,
+		ciLogFormat,
+		absArtifactsDirpath,
+		runner.options.ArtifactVerbosity,
+		absSnapshotsDirpath,
+		runner.options.SwarmDockerHosts,
+		runner.options.IsEgressBlocked,
+		runner.options.TimingStore,
+		runner.options.ArtifactUploader)
+	testExecutor.UseSystemLogCaptureOptions(runner.options.SystemLogCaptureOptions)
 
 	logrus.Infof("Running %v tests with execution ID %v...", len(testsToRun), executionInstanceId.String())
-	allTestsPassed = testExecutor.RunInParallelAndPrintResults(testParams)
+	if eventStream != nil {
+		testNames := make([]string, 0, len(testsToRun))
+		for testName, _ := range testsToRun {
+			testNames = append(testNames, testName)
+		}
+		eventStream.EmitRunStarted(executionInstanceId.String(), testNames)
+	}
+	allTestsPassed := testExecutor.RunInParallelAndPrintResults(testParams)
 	return allTestsPassed, nil
 }
 
 /*
-Helper function to build, from the set of tests to run, the map of test params that we'll pass to the TestExecutorParallelizer
+Gets a "set" of the Docker images used by the service configurations of the given tests, by running each test's network
+	configuration against a builder that doesn't touch Docker. Tests whose networks can't be configured this way are skipped,
+	as their controllers will report the error when they run.
 
 Args:
-	testsToRun: A "set" of test names to run in parallel
+	testsToRun: Mapping of test name -> test
  */
-func buildTestParams(executionInstanceId uuid.UUID, testsToRun map[string]testsuite.Test, networkWidthBits uint32) (map[string]parallelism.ParallelTestParams, error) {
-	subnetMaskBits := BITS_IN_IP4_ADDR - networkWidthBits
-
-	subnetStartIp := net.ParseIP(SUBNET_START_ADDR)
-	if subnetStartIp == nil {
-		return nil, stacktrace.NewError("Subnet start IP %v was not a valid IP address; this is a code problem", SUBNET_START_ADDR)
+func getTestImages(testsToRun map[string]testsuite.Test) map[string]bool {
+	result := make(map[string]bool)
+	for testName, test := range testsToRun {
+		networkLoader, err := test.GetNetworkLoader()
+		if err != nil {
+			logrus.Debugf("Couldn't get the network loader of test %v to find its images: %v", testName, err)
+			continue
+		}
+		builder := networks.NewServiceNetworkBuilder(
+			logrus.NewEntry(logrus.StandardLogger()),
+			docker.NewFakeDockerManager(),
+			"",
+			nil,
+			"",
+			"",
+			"")
+		if err := networkLoader.ConfigureNetwork(builder); err != nil {
+			logrus.Debugf("Couldn't configure the network of test %v to find its images: %v", testName, err)
+			continue
+		}
+		for configurationId, _ := range builder.GetConfigurationIds() {
+			config, err := builder.GetConfiguration(configurationId)
+			if err != nil {
+				logrus.Debugf("Couldn't get configuration %v of test %v to find its image: %v", configurationId, testName, err)
+				continue
+			}
+			result[config.GetDockerImage()] = true
+		}
 	}
+	return result
+}
 
-	// The IP can be either 4 bytes or 16 bytes long; we need to handle both
-	//  else we'll get a silent 0 value for the int!
-	// See https://gist.github.com/ammario/649d4c0da650162efd404af23e25b86b
-	var subnetStartIpInt uint32
-	if len(subnetStartIp) == 16 {
-		subnetStartIpInt = binary.BigEndian.Uint32(subnetStartIp[12:16])
-	} else {
-		subnetStartIpInt = binary.BigEndian.Uint32(subnetStartIp)
+/*
+Helper function to build, from the set of tests to run, the map of test params that we'll pass to the TestExecutorParallelizer
+
+Args:
+	testsToRun: A "set" of test names to run in parallel
+	subnetAllocator: The allocator that each test's subnet will be allocated from
+	ipv6SubnetAllocator: The allocator that each test's additional IPv6 subnet will be allocated from, or nil if test
+		networks should be single-stack
+ */
+func buildTestParams(
+			executionInstanceId uuid.UUID,
+			testsToRun map[string]testsuite.Test,
+			subnetAllocator *networks.SubnetAllocator,
+			ipv6SubnetAllocator *networks.SubnetAllocator) (map[string]parallelism.ParallelTestParams, error) {
+	// We allocate in test name order so that a given set of tests always gets the same subnets
+	testNames := []string{}
+	for testName, _ := range testsToRun {
+		testNames = append(testNames, testName)
 	}
+	sort.Strings(testNames)
 
-	testIndex := 0
 	testParams := make(map[string]parallelism.ParallelTestParams)
-	for testName, test := range testsToRun {
-		// Pick the next free available subnet IP, considering all the tests we've started previously
-		subnetIpInt := subnetStartIpInt + uint32(testIndex) * uint32(math.Pow(2, float64(networkWidthBits)))
-		subnetIp := make(net.IP, 4)
-		binary.BigEndian.PutUint32(subnetIp, subnetIpInt)
-		subnetCidrStr := fmt.Sprintf("%v/%v", subnetIp.String(), subnetMaskBits)
-
-		testParams[testName] = *parallelism.NewParallelTestParams(testName, test, subnetCidrStr, executionInstanceId)
-		testIndex++
+	for _, testName := range testNames {
+		subnetCidrStr, err := subnetAllocator.AllocateSubnet()
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred allocating a subnet for test %v", testName)
+		}
+		ipv6SubnetCidrStr := ""
+		if ipv6SubnetAllocator != nil {
+			ipv6SubnetCidrStr, err = ipv6SubnetAllocator.AllocateSubnet()
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "An error occurred allocating an IPv6 subnet for test %v", testName)
+			}
+		}
+		testParams[testName] = *parallelism.NewParallelTestParams(testName, testsToRun[testName], subnetCidrStr, ipv6SubnetCidrStr, executionInstanceId)
 	}
 	return testParams, nil
 }
@@ -0,0 +1,220 @@
+package timings
+
+import (
+	"encoding/json"
+	"github.com/palantir/stacktrace"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	/*
+	How much a test's latest duration counts towards its recorded duration, with the rest coming from its earlier runs, so
+		that one unusually slow (or fast) run doesn't throw off scheduling
+	 */
+	DURATION_SMOOTHING_FACTOR = 0.5
+
+	// The duration estimated for tests that have never been timed, if no test has been timed either
+	DEFAULT_ESTIMATED_DURATION = 1 * time.Minute
+
+	timingsFilePerms = 0644
+	timingsDirPerms  = 0755
+)
+
+/*
+Persists how long each test took across runs, so that runs can schedule their longest tests first and CI jobs can split
+	a suite into shards that take about as long as each other. Implementations other than FileTimingStore can keep the
+	durations somewhere shared (e.g. a CI cache or a database).
+ */
+type TimingStore interface {
+	// Gets a mapping of test_name -> recorded duration, for every test with a recorded duration
+	GetDurations() (map[string]time.Duration, error)
+
+	// Records the given mapping of test_name -> how long the test took in the latest run
+	RecordDurations(durations map[string]time.Duration) error
+}
+
+/*
+A TimingStore that keeps the durations in a local JSON file, smoothing each test's recorded duration across runs (see
+	DURATION_SMOOTHING_FACTOR).
+
+NOTE: This is thread-safe!
+ */
+type FileTimingStore struct {
+	filepath string
+
+	mutex *sync.Mutex
+}
+
+/*
+Creates a store backed by the given file, which is created when durations are first recorded.
+ */
+func NewFileTimingStore(filepath string) *FileTimingStore {
+	return &FileTimingStore{
+		filepath: filepath,
+		mutex:    &sync.Mutex{},
+	}
+}
+
+func (store *FileTimingStore) GetDurations() (map[string]time.Duration, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.readDurations()
+}
+
+func (store *FileTimingStore) RecordDurations(durations map[string]time.Duration) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	recordedDurations, err := store.readDurations()
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred reading the recorded durations")
+	}
+	for testName, duration := range durations {
+		if recordedDuration, found := recordedDurations[testName]; found {
+			duration = time.Duration(DURATION_SMOOTHING_FACTOR * float64(duration) + (1 - DURATION_SMOOTHING_FACTOR) * float64(recordedDuration))
+		}
+		recordedDurations[testName] = duration
+	}
+
+	timingsFile := timingsFileContents{TestDurationMillis: make(map[string]int64)}
+	for testName, duration := range recordedDurations {
+		timingsFile.TestDurationMillis[testName] = int64(duration / time.Millisecond)
+	}
+	contents, err := json.MarshalIndent(timingsFile, "", "  ")
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred serializing the durations")
+	}
+	if err := os.MkdirAll(filepath.Dir(store.filepath), timingsDirPerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating the directory of timings file %v", store.filepath)
+	}
+	// Written to a temporary file first, so that a run dying midway through writing doesn't corrupt the timings
+	tempFilepath := store.filepath + ".tmp"
+	if err := ioutil.WriteFile(tempFilepath, contents, timingsFilePerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing temporary timings file %v", tempFilepath)
+	}
+	if err := os.Rename(tempFilepath, store.filepath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred replacing timings file %v", store.filepath)
+	}
+	return nil
+}
+
+/*
+Estimates how long each of the given tests will take: the recorded duration for tests that have one, or else the average
+	recorded duration of the other tests (DEFAULT_ESTIMATED_DURATION if none have one).
+
+Args:
+	testNames: A "set" of the names of the tests to estimate the durations of
+	recordedDurations: Mapping of test_name -> recorded duration (see TimingStore.GetDurations)
+
+Returns:
+	Mapping of test_name -> estimated duration, for each of the given tests
+ */
+func EstimateDurations(testNames map[string]bool, recordedDurations map[string]time.Duration) map[string]time.Duration {
+	defaultDuration := DEFAULT_ESTIMATED_DURATION
+	if len(recordedDurations) > 0 {
+		totalDuration := time.Duration(0)
+		for _, duration := range recordedDurations {
+			totalDuration += duration
+		}
+		defaultDuration = totalDuration / time.Duration(len(recordedDurations))
+	}
+
+	result := make(map[string]time.Duration)
+	for testName, _ := range testNames {
+		duration, found := recordedDurations[testName]
+		if !found {
+			duration = defaultDuration
+		}
+		result[testName] = duration
+	}
+	return result
+}
+
+/*
+Orders the given tests longest-first, which is the order that gets a parallel run finished soonest (as the short tests
+	fill in around the long ones at the end, rather than a long test starting last). Ties are broken by name, so the order
+	is stable.
+
+Args:
+	estimatedDurations: Mapping of test_name -> estimated duration (see EstimateDurations)
+ */
+func OrderLongestFirst(estimatedDurations map[string]time.Duration) []string {
+	result := make([]string, 0, len(estimatedDurations))
+	for testName, _ := range estimatedDurations {
+		result = append(result, testName)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		iDuration := estimatedDurations[result[i]]
+		jDuration := estimatedDurations[result[j]]
+		if iDuration != jDuration {
+			return iDuration > jDuration
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+/*
+Splits the given tests into the given number of shards that take about as long as each other to run, for CI jobs that each
+	run one shard. The split only depends on its arguments, so every job computes the same split as long as they all use
+	the same recorded durations.
+
+Args:
+	estimatedDurations: Mapping of test_name -> estimated duration (see EstimateDurations)
+	numShards: The number of shards to split the tests into
+
+Returns:
+	A "set" of test names per shard
+ */
+func AssignShards(estimatedDurations map[string]time.Duration, numShards int) ([]map[string]bool, error) {
+	if numShards < 1 {
+		return nil, stacktrace.NewError("The number of shards must be at least 1, but was %v", numShards)
+	}
+	result := make([]map[string]bool, numShards)
+	shardDurations := make([]time.Duration, numShards)
+	for i := 0; i < numShards; i++ {
+		result[i] = make(map[string]bool)
+	}
+	// Each test goes to the shard with the least work so far, longest test first
+	for _, testName := range OrderLongestFirst(estimatedDurations) {
+		shortestShardIdx := 0
+		for i := 1; i < numShards; i++ {
+			if shardDurations[i] < shardDurations[shortestShardIdx] {
+				shortestShardIdx = i
+			}
+		}
+		result[shortestShardIdx][testName] = true
+		shardDurations[shortestShardIdx] += estimatedDurations[testName]
+	}
+	return result, nil
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+type timingsFileContents struct {
+	// Mapping of test_name -> recorded duration in milliseconds
+	TestDurationMillis map[string]int64 `json:"testDurationMillis"`
+}
+
+// Must be called with the store's lock held
+func (store *FileTimingStore) readDurations() (map[string]time.Duration, error) {
+	result := make(map[string]time.Duration)
+	contents, err := ioutil.ReadFile(store.filepath)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading timings file %v", store.filepath)
+	}
+	timingsFile := timingsFileContents{}
+	if err := json.Unmarshal(contents, &timingsFile); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing timings file %v", store.filepath)
+	}
+	for testName, durationMillis := range timingsFile.TestDurationMillis {
+		result[testName] = time.Duration(durationMillis) * time.Millisecond
+	}
+	return result, nil
+}
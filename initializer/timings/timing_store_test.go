@@ -0,0 +1,70 @@
+package timings
+
+import (
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordingDurations(t *testing.T) {
+	tempDirpath, err := ioutil.TempDir("", "timings")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tempDirpath)
+	store := NewFileTimingStore(filepath.Join(tempDirpath, "nested", "timings.json"))
+
+	durations, err := store.GetDurations()
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(durations))
+
+	assert.NilError(t, store.RecordDurations(map[string]time.Duration{"test1": 10 * time.Second, "test2": 4 * time.Second}))
+	assert.NilError(t, store.RecordDurations(map[string]time.Duration{"test1": 20 * time.Second}))
+	durations, err = store.GetDurations()
+	assert.NilError(t, err)
+	// The latest duration is smoothed with the earlier ones, while unrun tests keep theirs
+	assert.DeepEqual(t, map[string]time.Duration{"test1": 15 * time.Second, "test2": 4 * time.Second}, durations)
+}
+
+func TestEstimatingDurations(t *testing.T) {
+	recordedDurations := map[string]time.Duration{"test1": 10 * time.Second, "test2": 20 * time.Second, "removedTest": 30 * time.Second}
+	estimatedDurations := EstimateDurations(map[string]bool{"test1": true, "newTest": true}, recordedDurations)
+	assert.DeepEqual(t, map[string]time.Duration{"test1": 10 * time.Second, "newTest": 20 * time.Second}, estimatedDurations)
+
+	estimatedDurations = EstimateDurations(map[string]bool{"newTest": true}, map[string]time.Duration{})
+	assert.DeepEqual(t, map[string]time.Duration{"newTest": DEFAULT_ESTIMATED_DURATION}, estimatedDurations)
+}
+
+func TestOrderingLongestFirst(t *testing.T) {
+	order := OrderLongestFirst(map[string]time.Duration{
+		"short": 1 * time.Second,
+		"long":  1 * time.Minute,
+		"b":     10 * time.Second,
+		"a":     10 * time.Second,
+	})
+	assert.DeepEqual(t, []string{"long", "a", "b", "short"}, order)
+}
+
+func TestAssigningShards(t *testing.T) {
+	shards, err := AssignShards(map[string]time.Duration{
+		"test1": 60 * time.Second,
+		"test2": 30 * time.Second,
+		"test3": 20 * time.Second,
+		"test4": 10 * time.Second,
+		"test5": 5 * time.Second,
+	}, 2)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []map[string]bool{
+		{"test1": true, "test5": true},
+		{"test2": true, "test3": true, "test4": true},
+	}, shards)
+
+	shards, err = AssignShards(map[string]time.Duration{"test1": time.Second}, 3)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(shards[0]))
+	assert.Equal(t, 0, len(shards[2]))
+
+	_, err = AssignShards(map[string]time.Duration{}, 0)
+	assert.ErrorContains(t, err, "at least 1")
+}
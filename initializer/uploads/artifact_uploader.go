@@ -0,0 +1,211 @@
+package uploads
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/palantir/stacktrace"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// The env variables that a CommandUploader's command gets the file to upload & its key in
+	FILEPATH_ENV_VARIABLE = "ARTIFACT_FILEPATH"
+	KEY_ENV_VARIABLE      = "ARTIFACT_KEY"
+
+	uploadTimeout = 5 * time.Minute
+
+	shellBinary = "sh"
+)
+
+/*
+Pushes artifact files to object storage (e.g. S3, GCS, or Azure Blob Storage), so that tests' logs & reports outlive
+	CI machines with ephemeral disks. Implementations for storage services whose SDKs can't be used here can be built on
+	HttpPutUploader or CommandUploader.
+ */
+type ArtifactUploader interface {
+	/*
+	Uploads the given file.
+
+	Args:
+		localFilepath: The path of the file to upload
+		key: The key (i.e. object name) to upload the file as, with "/"-separated components (e.g. "<execution ID>/<test>/test.log")
+
+	Returns:
+		The URL that the uploaded file can be found at
+	 */
+	Upload(localFilepath string, key string) (string, error)
+}
+
+/*
+Uploads each file of the given directory (recursively), keyed by the given prefix followed by the file's path relative to
+	the directory.
+
+Args:
+	uploader: The uploader to upload the files with
+	dirpath: The directory whose files to upload
+	keyPrefix: The prefix of the files' keys (e.g. "<execution ID>/<test>")
+
+Returns:
+	Mapping of relative filepath (with "/" separators) -> URL of the uploaded file, for every file that was uploaded
+	An error if any file couldn't be uploaded; the upload is best-effort, so the other files will still be uploaded
+ */
+func UploadDirectory(uploader ArtifactUploader, dirpath string, keyPrefix string) (map[string]string, error) {
+	result := make(map[string]string)
+	var resultErr error = nil
+	walkErr := filepath.Walk(dirpath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fileInfo.Mode().IsRegular() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(dirpath, filePath)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		fileUrl, err := uploader.Upload(filePath, path.Join(keyPrefix, relativePath))
+		if err != nil {
+			resultErr = stacktrace.Propagate(err, "An error occurred uploading artifact %v", relativePath)
+			return nil
+		}
+		result[relativePath] = fileUrl
+		return nil
+	})
+	if walkErr != nil {
+		return result, stacktrace.Propagate(walkErr, "An error occurred walking artifacts directory %v", dirpath)
+	}
+	return result, resultErr
+}
+
+/*
+An uploader that PUTs each file to <base URL>/<key>, which object storage services accept given the right credentials:
+	e.g. an Azure Blob Storage container URL with a SAS token (and an "x-ms-blob-type: BlockBlob" header), a GCS bucket URL
+	with an "Authorization: Bearer <token>" header, or an S3-compatible bucket or upload proxy that accepts PUTs.
+ */
+type HttpPutUploader struct {
+	baseUrl *url.URL
+
+	// Headers sent with every upload (e.g. for authorization)
+	headers map[string]string
+
+	httpClient *http.Client
+}
+
+/*
+Creates an uploader that PUTs files under the given URL.
+
+Args:
+	baseUrl: The URL to upload files under, whose query string (e.g. a SAS token) is sent with every upload but left out
+		of the uploaded files' URLs
+	headers: Mapping of header name -> value, sent with every upload
+ */
+func NewHttpPutUploader(baseUrl string, headers map[string]string) (*HttpPutUploader, error) {
+	parsedUrl, err := url.Parse(baseUrl)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing upload URL '%v'", baseUrl)
+	}
+	if parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https" {
+		return nil, stacktrace.NewError("Upload URL '%v' isn't an HTTP or HTTPS URL", baseUrl)
+	}
+	return &HttpPutUploader{
+		baseUrl:    parsedUrl,
+		headers:    headers,
+		httpClient: &http.Client{Timeout: uploadTimeout},
+	}, nil
+}
+
+func (uploader HttpPutUploader) Upload(localFilepath string, key string) (string, error) {
+	fp, err := os.Open(localFilepath)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred opening file %v", localFilepath)
+	}
+	defer fp.Close()
+	fileInfo, err := fp.Stat()
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting the size of file %v", localFilepath)
+	}
+
+	objectUrl := *uploader.baseUrl
+	objectUrl.Path = strings.TrimSuffix(objectUrl.Path, "/") + "/" + key
+	objectUrl.RawPath = ""
+	request, err := http.NewRequest(http.MethodPut, objectUrl.String(), fp)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred creating the upload request")
+	}
+	request.ContentLength = fileInfo.Size()
+	for name, value := range uploader.headers {
+		request.Header.Set(name, value)
+	}
+	resp, err := uploader.httpClient.Do(request)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred uploading file %v", localFilepath)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return "", stacktrace.NewError("Uploading file %v was rejected with status %v", localFilepath, resp.Status)
+	}
+
+	// The query string may hold credentials, so isn't part of the URL that's reported
+	objectUrl.RawQuery = ""
+	return objectUrl.String(), nil
+}
+
+/*
+An uploader that runs a shell command per file, for storage services whose CLIs handle authentication (e.g.
+	`aws s3 cp "$ARTIFACT_FILEPATH" "s3://my-bucket/$ARTIFACT_KEY"`, or the gsutil & az equivalents). The command gets the
+	file to upload & its key in the FILEPATH_ENV_VARIABLE & KEY_ENV_VARIABLE env variables.
+ */
+type CommandUploader struct {
+	command string
+
+	urlTemplate *template.Template
+}
+
+/*
+Creates an uploader that runs the given command.
+
+Args:
+	command: The shell command that uploads a file
+	urlTemplate: A Go text/template of the URL that an uploaded file can be found at, rendered against a struct with a
+		"Key" field (e.g. "https://my-bucket.s3.amazonaws.com/{{ .Key }}")
+ */
+func NewCommandUploader(command string, urlTemplate string) (*CommandUploader, error) {
+	if command == "" {
+		return nil, stacktrace.NewError("The upload command must not be empty")
+	}
+	parsedTemplate, err := template.New("artifact-url").Option("missingkey=error").Parse(urlTemplate)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing the artifact URL template")
+	}
+	return &CommandUploader{
+		command:     command,
+		urlTemplate: parsedTemplate,
+	}, nil
+}
+
+func (uploader CommandUploader) Upload(localFilepath string, key string) (string, error) {
+	fileUrl := &bytes.Buffer{}
+	if err := uploader.urlTemplate.Execute(fileUrl, struct{ Key string }{Key: key}); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred rendering the URL of artifact %v", key)
+	}
+
+	cmd := exec.Command(shellBinary, "-c", uploader.command)
+	cmd.Env = append(
+		os.Environ(),
+		fmt.Sprintf("%v=%v", FILEPATH_ENV_VARIABLE, localFilepath),
+		fmt.Sprintf("%v=%v", KEY_ENV_VARIABLE, key))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", stacktrace.Propagate(err, "The upload command failed for file %v with output:\n%v", localFilepath, string(output))
+	}
+	return fileUrl.String(), nil
+}
@@ -0,0 +1,86 @@
+package uploads
+
+import (
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestHttpPutUploader(t *testing.T) {
+	uploadedBodies := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, err := ioutil.ReadAll(request.Body)
+		assert.NilError(t, err)
+		assert.Equal(t, http.MethodPut, request.Method)
+		assert.Equal(t, "BlockBlob", request.Header.Get("x-ms-blob-type"))
+		assert.Equal(t, "sig=secret", request.URL.RawQuery)
+		uploadedBodies[request.URL.Path] = string(body)
+	}))
+	defer server.Close()
+
+	dirpath, err := ioutil.TempDir("", "artifacts")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dirpath)
+	assert.NilError(t, os.MkdirAll(path.Join(dirpath, "service1"), 0755))
+	assert.NilError(t, ioutil.WriteFile(path.Join(dirpath, "test.log"), []byte("test log"), 0644))
+	assert.NilError(t, ioutil.WriteFile(path.Join(dirpath, "service1", "logs.txt"), []byte("service log"), 0644))
+
+	uploader, err := NewHttpPutUploader(server.URL + "/container/?sig=secret", map[string]string{"x-ms-blob-type": "BlockBlob"})
+	assert.NilError(t, err)
+	urls, err := UploadDirectory(uploader, dirpath, "execution/testA")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"test.log":          server.URL + "/container/execution/testA/test.log",
+		"service1/logs.txt": server.URL + "/container/execution/testA/service1/logs.txt",
+	}, urls)
+	assert.DeepEqual(t, map[string]string{
+		"/container/execution/testA/test.log":          "test log",
+		"/container/execution/testA/service1/logs.txt": "service log",
+	}, uploadedBodies)
+}
+
+func TestHttpPutUploaderRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	fp, err := ioutil.TempFile("", "artifact")
+	assert.NilError(t, err)
+	fp.Close()
+	defer os.Remove(fp.Name())
+
+	uploader, err := NewHttpPutUploader(server.URL, map[string]string{})
+	assert.NilError(t, err)
+	_, err = uploader.Upload(fp.Name(), "key")
+	assert.ErrorContains(t, err, "403")
+
+	_, err = NewHttpPutUploader("s3://bucket", map[string]string{})
+	assert.ErrorContains(t, err, "isn't an HTTP or HTTPS URL")
+}
+
+func TestCommandUploader(t *testing.T) {
+	dirpath, err := ioutil.TempDir("", "bucket")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dirpath)
+	srcFilepath := path.Join(dirpath, "test.log")
+	assert.NilError(t, ioutil.WriteFile(srcFilepath, []byte("test log"), 0644))
+
+	uploader, err := NewCommandUploader(`cp "$ARTIFACT_FILEPATH" "` + dirpath + `/$(basename "$ARTIFACT_KEY").uploaded"`, "file://bucket/{{ .Key }}")
+	assert.NilError(t, err)
+	fileUrl, err := uploader.Upload(srcFilepath, "execution/testA/test.log")
+	assert.NilError(t, err)
+	assert.Equal(t, "file://bucket/execution/testA/test.log", fileUrl)
+	uploaded, err := ioutil.ReadFile(path.Join(dirpath, "test.log.uploaded"))
+	assert.NilError(t, err)
+	assert.Equal(t, "test log", string(uploaded))
+
+	failingUploader, err := NewCommandUploader("echo denied && exit 1", "{{ .Key }}")
+	assert.NilError(t, err)
+	_, err = failingUploader.Upload(srcFilepath, "key")
+	assert.ErrorContains(t, err, "denied")
+}
@@ -0,0 +1,271 @@
+package kurtosistest
+
+import (
+	"context"
+	"github.com/docker/distribution/uuid"
+	"github.com/docker/docker/client"
+	"github.com/kurtosis-tech/kurtosis/commons/docker"
+	"github.com/kurtosis-tech/kurtosis/commons/logging"
+	"github.com/kurtosis-tech/kurtosis/commons/networks"
+	"github.com/kurtosis-tech/kurtosis/initializer"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Gives each network room for 256 IPs, as the CLI does by default
+	NETWORK_WIDTH_BITS = 8
+
+	// How long each of the network's containers gets to stop when the network is torn down
+	CONTAINER_STOP_TIMEOUT = 10 * time.Second
+
+	// How many subnets are tried when creating the network, in case networks created by other processes (e.g. the tests
+	//  of other packages, which "go test" runs in parallel) take the subnets that were free when they were listed
+	maxNetworkCreationAttempts = 5
+
+	hostDirectoryVolumePrefix = "kurtosistest-"
+)
+
+// The characters that can't be in Docker network & volume names, which test names may contain (e.g. the "/" of subtests)
+var invalidDockerNameCharsRegex = regexp.MustCompile("[^a-zA-Z0-9_.-]")
+
+/*
+The parts of *testing.T that StartNetwork uses (which *testing.T has from Go 1.14, when Cleanup was added).
+ */
+type T interface {
+	Helper()
+	Name() string
+	Logf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Cleanup(cleanupFunc func())
+}
+
+/*
+Starts the network of the given loader for an ordinary Go test, so that a test network can be used without a test suite,
+	initializer, or controller image:
+
+	func TestMyService(t *testing.T) {
+		network := kurtosistest.StartNetwork(t, MyNetworkLoader{}).(MyNetwork)
+		...
+	}
+
+The network's Docker network & test volume are created, the loader configures & initializes the network, and the services
+	it returns availability checkers for are waited on, failing the test if any of this fails. The network is torn down
+	(and its Docker resources removed) when the test & its subtests finish, and the framework's logs are written through
+	t.Logf, so they're shown for failed tests or with "go test -v".
+
+The network's Docker resources are named & labelled as those of a test of a test suite run, so the CLI's commands that
+	take a run ID (e.g. inspect, shell, & logs) work on them, with the run ID that's logged when the network starts.
+
+NOTE: The test volume is a directory on this machine, so the Docker engine must be on this machine too.
+
+Args:
+	t: The test, e.g. a *testing.T
+	networkLoader: The loader of the network to start
+
+Returns:
+	The network, as wrapped by the loader's WrapNetwork
+ */
+func StartNetwork(t T, networkLoader networks.NetworkLoader) networks.Network {
+	t.Helper()
+	network, err := startNetwork(t, networkLoader)
+	if err != nil {
+		t.Fatalf("An error occurred starting the test network:\n%v", err)
+	}
+	return network
+}
+
+// =========================== PRIVATE HELPER FUNCTIONS =========================================
+func startNetwork(t T, networkLoader networks.NetworkLoader) (networks.Network, error) {
+	log := logrus.New()
+	log.SetLevel(logrus.GetLevel())
+	log.SetOutput(newTestLogWriter(t))
+	log.AddHook(docker.NewSecretRedactingHook())
+
+	executionId := uuid.Generate().String()
+	testName := invalidDockerNameCharsRegex.ReplaceAllString(t.Name(), "-")
+	testLog := logging.NewTestLogEntry(log, executionId, testName)
+	// Test networks & volumes are named <execution ID>-<test name>, as the initializer names them
+	resourceName := executionId + "-" + testName
+
+	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to initialize Docker client from environment.")
+	}
+	dockerManager, err := docker.NewDockerManager(testLog, dockerClient)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the Docker manager")
+	}
+
+	networkId, subnetMask, gatewayIp, err := createNetwork(dockerManager, resourceName)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating Docker network %v", resourceName)
+	}
+	t.Cleanup(func() {
+		removeNetworkResources(testLog, dockerManager, networkId, resourceName)
+	})
+	t.Logf("Started Docker network %v for run ID %v", resourceName, executionId)
+
+	testVolumeDirpath, err := ioutil.TempDir("", hostDirectoryVolumePrefix)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the test volume's directory")
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(testVolumeDirpath); err != nil {
+			// Files that containers wrote as root may not be removable by this process
+			testLog.Warnf("Couldn't remove test volume directory %v: %v", testVolumeDirpath, err)
+		}
+	})
+	volumeLabels := map[string]string{
+		docker.EXECUTION_ID_LABEL: executionId,
+		docker.TEST_VOLUME_LABEL:  resourceName,
+	}
+	if err := dockerManager.CreateHostDirectoryVolume(context.Background(), resourceName, testVolumeDirpath, volumeLabels); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating test volume %v", resourceName)
+	}
+
+	freeIpTracker, err := networks.NewFreeIpAddrTracker(log, subnetMask, map[string]bool{gatewayIp.String(): true})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the free IP address tracker")
+	}
+	builder := networks.NewServiceNetworkBuilder(testLog, dockerManager, networkId, freeIpTracker, resourceName, testVolumeDirpath, "")
+	if err := networkLoader.ConfigureNetwork(builder); err != nil {
+		return nil, stacktrace.Propagate(err, "Could not configure the test network")
+	}
+	network := builder.Build()
+	// Registered after the network resources' cleanup, so that it runs first
+	t.Cleanup(func() {
+		if err := network.RemoveAll(CONTAINER_STOP_TIMEOUT); err != nil {
+			testLog.Errorf("An error occurred stopping the test network: %v", err)
+		}
+	})
+
+	if err := network.PrePullImages(); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred pre-pulling the test network's images")
+	}
+	availabilityCheckers, err := networkLoader.InitializeNetwork(network)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred initializing the network to its starting state")
+	}
+	for serviceId, availabilityChecker := range availabilityCheckers {
+		if err := availabilityChecker.WaitForStartup(); err != nil {
+			testLog.Errorf(
+				"Service %v didn't start up; the test network's diagnostics are:\n%v",
+				serviceId,
+				network.GetAvailabilityDiagnostics(networks.DEFAULT_DIAGNOSTICS_LOG_LINES))
+			return nil, stacktrace.Propagate(err, "An error occurred waiting for service %v to start up", serviceId)
+		}
+	}
+	wrappedNetwork, err := networkLoader.WrapNetwork(network)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred wrapping the network in the loader's network type")
+	}
+	return wrappedNetwork, nil
+}
+
+/*
+Creates a Docker network with the given name in a subnet (of the initializer's default supernet) that no other Docker
+	network is using.
+
+Returns:
+	networkId: The ID of the created network
+	subnetMask: The network's subnet
+	gatewayIp: The network's gateway, which services can't be given
+ */
+func createNetwork(dockerManager *docker.DockerManager, name string) (networkId string, subnetMask string, gatewayIp net.IP, err error) {
+	subnetAllocator, err := networks.NewSubnetAllocator(initializer.DEFAULT_SUPERNET_CIDR, NETWORK_WIDTH_BITS)
+	if err != nil {
+		return "", "", nil, stacktrace.Propagate(err, "An error occurred creating the subnet allocator")
+	}
+	existingSubnets, err := dockerManager.GetNetworkSubnets(context.Background())
+	if err != nil {
+		return "", "", nil, stacktrace.Propagate(err, "An error occurred getting the subnets of existing Docker networks")
+	}
+	for _, existingSubnet := range existingSubnets {
+		subnetAllocator.ReserveOverlapping(existingSubnet)
+	}
+
+	var lastErr error = nil
+	for attempt := 0; attempt < maxNetworkCreationAttempts; attempt++ {
+		subnetMask, err := subnetAllocator.AllocateSubnet()
+		if err != nil {
+			return "", "", nil, stacktrace.Propagate(err, "An error occurred allocating a subnet for the network")
+		}
+		freeIpTracker, err := networks.NewFreeIpAddrTracker(logrus.StandardLogger(), subnetMask, map[string]bool{})
+		if err != nil {
+			return "", "", nil, stacktrace.Propagate(err, "An error occurred creating the free IP address tracker for subnet %v", subnetMask)
+		}
+		gatewayIp, err := freeIpTracker.GetFreeIpAddr()
+		if err != nil {
+			return "", "", nil, stacktrace.Propagate(err, "An error occurred getting the gateway IP of subnet %v", subnetMask)
+		}
+		networkId, err := dockerManager.CreateNetwork(context.Background(), name, subnetMask, gatewayIp, false)
+		if err == nil {
+			return networkId, subnetMask, gatewayIp, nil
+		}
+		lastErr = err
+	}
+	return "", "", nil, stacktrace.Propagate(lastErr, "Couldn't create the network in any of %v subnets", maxNetworkCreationAttempts)
+}
+
+// Makes a best-effort attempt at removing the test's Docker network, and the volumes & additional networks created for it
+func removeNetworkResources(log *logrus.Entry, dockerManager *docker.DockerManager, networkId string, testVolumeName string) {
+	// The background context is used so that cleanup happens even if the test's context was cancelled
+	ctx := context.Background()
+	testVolumeLabels := map[string]string{docker.TEST_VOLUME_LABEL: testVolumeName}
+	if err := dockerManager.RemoveNetwork(ctx, networkId, CONTAINER_STOP_TIMEOUT); err != nil {
+		log.Errorf("An error occurred removing Docker network %v, which must be cleaned up manually: %v", networkId, err)
+	}
+	additionalNetworkIds, err := dockerManager.ListNetworks(ctx, testVolumeLabels)
+	if err != nil {
+		log.Errorf("An error occurred listing the additional Docker networks, which must be cleaned up manually: %v", err)
+	}
+	for _, additionalNetworkId := range additionalNetworkIds {
+		if err := dockerManager.RemoveNetwork(ctx, additionalNetworkId, CONTAINER_STOP_TIMEOUT); err != nil {
+			log.Errorf("An error occurred removing Docker network %v, which must be cleaned up manually: %v", additionalNetworkId, err)
+		}
+	}
+	volumeNames, err := dockerManager.ListVolumes(ctx, testVolumeLabels)
+	if err != nil {
+		log.Errorf("An error occurred listing the Docker volumes, which must be cleaned up manually: %v", err)
+	}
+	for _, volumeName := range volumeNames {
+		if err := dockerManager.RemoveVolume(ctx, volumeName, true); err != nil {
+			log.Errorf("An error occurred removing Docker volume %v, which must be cleaned up manually: %v", volumeName, err)
+		}
+	}
+}
+
+// =========================== TEST LOG WRITER =========================================
+/*
+Writes each log entry to a test's log with t.Logf. Logrus writes each entry with a single call to Write, so each entry
+	becomes one test log line.
+
+NOTE: This is thread-safe!
+ */
+type testLogWriter struct {
+	mutex *sync.Mutex
+
+	t T
+}
+
+func newTestLogWriter(t T) *testLogWriter {
+	return &testLogWriter{
+		mutex: &sync.Mutex{},
+		t:     t,
+	}
+}
+
+func (writer *testLogWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	writer.t.Logf("%v", strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
@@ -0,0 +1,35 @@
+package kurtosistest
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+// Records what's logged to it, in place of a *testing.T
+type recordingT struct {
+	*testing.T
+
+	logLines []string
+}
+
+func (t *recordingT) Logf(format string, args ...interface{}) {
+	t.logLines = append(t.logLines, fmt.Sprintf(format, args...))
+}
+
+func TestFrameworkLogsGoThroughTestLog(t *testing.T) {
+	recorder := &recordingT{T: t, logLines: []string{}}
+	log := logrus.New()
+	log.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true, DisableColors: true})
+	log.SetOutput(newTestLogWriter(recorder))
+
+	log.Info("first")
+	log.Warn("second")
+	assert.DeepEqual(t, []string{`level=info msg=first`, `level=warning msg=second`}, recorder.logLines)
+}
+
+func TestTestNamesAreValidDockerNames(t *testing.T) {
+	assert.Equal(t, "TestParent-sub_test.1", invalidDockerNameCharsRegex.ReplaceAllString("TestParent/sub_test.1", "-"))
+	assert.Equal(t, "TestParent-with-spaces-", invalidDockerNameCharsRegex.ReplaceAllString("TestParent/with spaces!", "-"))
+}
@@ -0,0 +1,25 @@
+#!/bin/bash
+
+# Runs the orchestration layer's benchmarks (builders, IP & subnet allocation, and the no-op network load scenario),
+#  printing results in the format that benchstat reads so that runs can be compared to spot regressions, e.g.:
+#
+#    scripts/benchmark.sh > old.txt; git checkout my-branch; scripts/benchmark.sh > new.txt; benchstat old.txt new.txt
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+
+SCRIPTS_PATH=$(cd $(dirname "${BASH_SOURCE[0]}"); pwd)
+KURTOSIS_PATH=$(dirname "${SCRIPTS_PATH}")
+
+# A fixed number of iterations (rather than a fixed time) keeps the work done identical between runs
+BENCHMARK_ITERATIONS="${BENCHMARK_ITERATIONS:-20x}"
+BENCHMARK_COUNT="${BENCHMARK_COUNT:-5}"
+
+echo "Running benchmarks..." >&2
+if ! go test -run '^$' -bench . -benchmem -benchtime "${BENCHMARK_ITERATIONS}" -count "${BENCHMARK_COUNT}" "${KURTOSIS_PATH}/..."; then
+    echo "Benchmarks failed!" >&2
+    exit 1
+fi
+echo "Benchmarks succeeded" >&2